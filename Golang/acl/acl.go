@@ -0,0 +1,135 @@
+// Package acl 은 클라이언트 IP 를 CIDR 허용/거부 목록과 비교하는 미들웨어를 제공한다.
+//
+// 클라이언트 IP 는 realip.ClientIP 다. 프록시 뒤에서는 realip 미들웨어가 앞에 있어야 한다.
+package acl
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sync/atomic"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/realip"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Config 는 접근 제어 설정이다. 항목은 CIDR("10.0.0.0/8") 또는 단일 IP 다.
+type Config struct {
+	// Allow 가 비어 있지 않으면 목록에 있는 클라이언트만 허용한다.
+	Allow []string
+	// Deny 에 있는 클라이언트는 Allow 와 관계없이 거부한다.
+	Deny []string
+	// Logger 는 거부 기록을 남길 곳이다. nil 이면 요청 로거(logging.From)를 사용한다.
+	Logger logging.Logger
+}
+
+// ACL 은 파싱된 접근 제어 목록이다. Update 로 실행 중에 목록을 바꿀 수 있다.
+type ACL struct {
+	rules  atomic.Pointer[rules]
+	logger logging.Logger
+}
+
+// rules 는 한 시점의 목록이다. 요청 하나는 처음 읽은 rules 만 사용한다.
+type rules struct {
+	allow, deny []netip.Prefix
+}
+
+// New 는 cfg 의 주소 목록을 파싱한다.
+func New(cfg Config) (*ACL, error) {
+	a := &ACL{logger: cfg.Logger}
+	if err := a.Update(cfg); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Update 는 cfg 의 목록을 파싱해 한 번에 바꾼다. 파싱에 실패하면 기존 목록을 유지한다.
+// cfg.Logger 는 무시한다.
+func (a *ACL) Update(cfg Config) error {
+	rs, err := parseRules(cfg)
+	if err != nil {
+		return err
+	}
+	a.rules.Store(rs)
+	return nil
+}
+
+// Enabled 는 허용 또는 거부 목록이 있는지 확인한다. 없으면 Middleware 는 모든 요청을 통과시킨다.
+func (a *ACL) Enabled() bool {
+	rs := a.rules.Load()
+	return len(rs.allow) > 0 || len(rs.deny) > 0
+}
+
+func parseRules(cfg Config) (*rules, error) {
+	rs := &rules{}
+	var err error
+	if rs.allow, err = parsePrefixes(cfg.Allow); err != nil {
+		return nil, err
+	}
+	if rs.deny, err = parsePrefixes(cfg.Deny); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+func parsePrefixes(list []string) ([]netip.Prefix, error) {
+	out, err := realip.ParsePrefixes(list)
+	if err != nil {
+		return nil, fmt.Errorf("acl: %w", err)
+	}
+	return out, nil
+}
+
+func contains(list []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range list {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed 는 addr 가 허용되는지와 거부 이유를 돌려준다.
+func (a *ACL) Allowed(addr netip.Addr) (bool, string) {
+	return a.rules.Load().allowed(addr)
+}
+
+func (rs *rules) allowed(addr netip.Addr) (bool, string) {
+	switch {
+	case !addr.IsValid():
+		return false, "unknown client address"
+	case contains(rs.deny, addr):
+		return false, "deny list"
+	case len(rs.allow) > 0 && !contains(rs.allow, addr):
+		return false, "not in allow list"
+	}
+	return true, ""
+}
+
+// Middleware 는 허용되지 않은 클라이언트를 403 으로 거부하고 기록하는 미들웨어를 만든다.
+// 목록이 모두 비어 있으면 검사하지 않는다.
+func (a *ACL) Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rs := a.rules.Load()
+			if len(rs.allow) == 0 && len(rs.deny) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ip := realip.ClientIP(r)
+			if ok, reason := rs.allowed(ip); !ok {
+				logger := a.logger
+				if logger == nil {
+					logger = logging.From(r.Context())
+				}
+				logger.Warn("acl: denied", "client", ip.String(), "remote", r.RemoteAddr,
+					"forwarded", r.Header.Get("X-Forwarded-For"), "method", r.Method, "path", r.URL.Path, "reason", reason)
+				api.WriteError(w, api.NewError(http.StatusForbidden, "forbidden", "access denied"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// Package admin 은 운영자용 /admin 화면을 제공한다.
+//
+// 등록된 라우트, 설정(비밀 값은 가림), 활성 세션, 최근 에러, 요청 통계를 보여 준다.
+// 페이지는 render 의 기본 엔진으로 그리며 다음 템플릿이 필요하다.
+//
+//	admin.html admin_routes.html admin_config.html admin_sessions.html admin_errors.html
+//
+// Accept 헤더가 JSON 이나 XML 이면 같은 내용을 해당 형식으로 돌려준다.
+// 이 패키지는 인가를 하지 않으므로 Mount 에 rbac 미들웨어를 넘긴다.
+package admin
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hgsong234/_stack/Golang/config"
+	"github.com/hgsong234/_stack/Golang/i18n"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/render"
+	"github.com/hgsong234/_stack/Golang/router"
+	"github.com/hgsong234/_stack/Golang/session"
+)
+
+// Dashboard 는 /admin 화면에 보여 줄 정보의 출처다. nil 인 항목은 빈 화면이다.
+type Dashboard struct {
+	// Routes 는 등록된 라우트 목록을 돌려준다. (보통 (*router.Router).Routes)
+	Routes func() []router.RouteInfo
+	// Config 는 화면에 보여 줄 현재 설정을 돌려준다. Settings 로 비밀 값을 가린다.
+	Config func() *config.Config
+	// Sessions 는 활성 세션을 나열할 세션 Manager 다.
+	Sessions *session.Manager
+	// Recorder 는 요청 통계와 최근 에러를 모으는 Recorder 다.
+	Recorder *Recorder
+
+	prefix string
+}
+
+// Mount 는 prefix 아래에 관리 화면을 등록한다. mws 는 모든 화면에 적용된다.
+func (d *Dashboard) Mount(r router.Routes, prefix string, mws ...router.Middleware) {
+	d.prefix = strings.TrimSuffix(prefix, "/")
+	g := r.Group(prefix, mws...)
+	g.GET("", d.overview)
+	g.GET("/routes", d.routes)
+	g.GET("/config", d.config)
+	g.GET("/sessions", d.sessions)
+	g.GET("/errors", d.errors)
+}
+
+// page 는 모든 관리 화면이 공유하는 레이아웃 값이다. Prefix 는 화면 사이의 링크에 쓴다.
+type page struct {
+	Lang   string `json:"-" xml:"-"`
+	Prefix string `json:"-" xml:"-"`
+}
+
+func (d *Dashboard) newPage(r *http.Request) page {
+	return page{Lang: i18n.Lang(r.Context()), Prefix: d.prefix}
+}
+
+// overviewView 는 요청 통계 화면이다. 브라우저에서는 몇 초마다 새로 고친다.
+type overviewView struct {
+	XMLName xml.Name `json:"-" xml:"stats"`
+	page
+	Stats
+}
+
+func (overviewView) Template() string { return "admin.html" }
+
+func (d *Dashboard) overview(w http.ResponseWriter, r *http.Request) {
+	v := overviewView{page: d.newPage(r)}
+	if d.Recorder != nil {
+		v.Stats = d.Recorder.Stats()
+	}
+	render.Negotiate(w, r, v)
+}
+
+// routesView 는 등록된 라우트 목록이다.
+type routesView struct {
+	XMLName xml.Name `json:"-" xml:"routes"`
+	page
+	Routes []router.RouteInfo `json:"routes" xml:"route"`
+}
+
+func (routesView) Template() string { return "admin_routes.html" }
+func (v routesView) String() string {
+	var s string
+	for _, rt := range v.Routes {
+		s += fmt.Sprintf("%-7s %s\n", rt.Method, rt.Pattern)
+	}
+	return s
+}
+
+func (d *Dashboard) routes(w http.ResponseWriter, r *http.Request) {
+	v := routesView{page: d.newPage(r)}
+	if d.Routes != nil {
+		v.Routes = d.Routes()
+	}
+	render.Negotiate(w, r, v)
+}
+
+// configView 는 비밀 값을 가린 설정이다.
+type configView struct {
+	XMLName xml.Name `json:"-" xml:"config"`
+	page
+	Settings []config.Setting `json:"settings" xml:"setting"`
+}
+
+func (configView) Template() string { return "admin_config.html" }
+func (v configView) String() string {
+	var s string
+	for _, st := range v.Settings {
+		s += st.Key + "=" + st.Value + "\n"
+	}
+	return s
+}
+
+func (d *Dashboard) config(w http.ResponseWriter, r *http.Request) {
+	v := configView{page: d.newPage(r)}
+	if d.Config != nil {
+		v.Settings = d.Config().Settings()
+	}
+	render.Negotiate(w, r, v)
+}
+
+// sessionsView 는 활성 세션 목록이다. 저장소가 나열을 지원하지 않으면 Error 에 이유를 담는다.
+type sessionsView struct {
+	XMLName xml.Name `json:"-" xml:"sessions"`
+	page
+	Sessions []session.Info `json:"sessions" xml:"session"`
+	Error    string         `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+func (sessionsView) Template() string { return "admin_sessions.html" }
+
+func (d *Dashboard) sessions(w http.ResponseWriter, r *http.Request) {
+	v := sessionsView{page: d.newPage(r)}
+	if d.Sessions != nil {
+		var err error
+		v.Sessions, err = d.Sessions.Active(r.Context())
+		if err != nil {
+			if !errors.Is(err, session.ErrNotListable) {
+				logging.From(r.Context()).Error("admin: list sessions", "err", err)
+			}
+			v.Error = err.Error()
+		}
+	}
+	render.Negotiate(w, r, v)
+}
+
+// errorsView 는 최근 5xx 응답 목록이다.
+type errorsView struct {
+	XMLName xml.Name `json:"-" xml:"errors"`
+	page
+	Errors []RequestError `json:"errors" xml:"error"`
+}
+
+func (errorsView) Template() string { return "admin_errors.html" }
+
+func (d *Dashboard) errors(w http.ResponseWriter, r *http.Request) {
+	v := errorsView{page: d.newPage(r)}
+	if d.Recorder != nil {
+		v.Errors = d.Recorder.Errors()
+	}
+	render.Negotiate(w, r, v)
+}
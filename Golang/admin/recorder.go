@@ -0,0 +1,175 @@
+package admin
+
+import (
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/middleware"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// RequestError 는 5xx 로 끝난 요청 하나의 기록이다.
+type RequestError struct {
+	Time      time.Time `json:"time" xml:"time,attr"`
+	Method    string    `json:"method" xml:"method,attr"`
+	Path      string    `json:"path" xml:"path,attr"`
+	Route     string    `json:"route,omitempty" xml:"route,attr,omitempty"`
+	Status    int       `json:"status" xml:"status,attr"`
+	LatencyMS float64   `json:"latency_ms" xml:"latency_ms,attr"`
+	RequestID string    `json:"request_id,omitempty" xml:"request_id,attr,omitempty"`
+}
+
+// Stats 는 Recorder 가 모은 요청 통계와 런타임 상태다.
+type Stats struct {
+	Uptime        string  `json:"uptime" xml:"uptime"`
+	Requests      int64   `json:"requests" xml:"requests"`
+	InFlight      int64   `json:"in_flight" xml:"in_flight"`
+	ClientErrors  int64   `json:"client_errors" xml:"client_errors"`
+	ServerErrors  int64   `json:"server_errors" xml:"server_errors"`
+	AvgLatencyMS  float64 `json:"avg_latency_ms" xml:"avg_latency_ms"`
+	RatePerSecond float64 `json:"rate_per_second" xml:"rate_per_second"`
+	Goroutines    int     `json:"goroutines" xml:"goroutines"`
+	HeapBytes     uint64  `json:"heap_bytes" xml:"heap_bytes"`
+}
+
+// rateWindow 는 RatePerSecond 를 계산하는 구간이다.
+const rateWindow = time.Minute
+
+// Recorder 는 요청 통계와 최근 에러를 메모리에 모은다.
+type Recorder struct {
+	start time.Time
+
+	mu       sync.Mutex
+	requests int64
+	inFlight int64
+	client   int64
+	server   int64
+	latency  time.Duration
+	errors   []RequestError // 순환 버퍼
+	next     int
+	seconds  [60]int64 // 초 단위 요청 수 (순환)
+	lastSec  int64
+}
+
+// NewRecorder 는 최근 에러를 n 개까지 보관하는 Recorder 를 만든다.
+func NewRecorder(n int) *Recorder {
+	if n <= 0 {
+		n = 50
+	}
+	return &Recorder{start: time.Now(), errors: make([]RequestError, 0, n)}
+}
+
+// Middleware 는 모든 요청을 집계하고 5xx 응답을 최근 에러로 남긴다.
+// 패닉으로 끝난 요청도 기록하려면 Recover 보다 앞에 등록한다.
+func (rec *Recorder) Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec.mu.Lock()
+			rec.inFlight++
+			rec.mu.Unlock()
+
+			sw := middleware.NewStatusWriter(w)
+			defer func() {
+				rec.done(r, sw.Code(), start)
+			}()
+			next.ServeHTTP(sw, r)
+		})
+	}
+}
+
+// done 은 끝난 요청 하나를 집계한다.
+func (rec *Recorder) done(r *http.Request, status int, start time.Time) {
+	now := time.Now()
+	d := now.Sub(start)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.inFlight--
+	rec.requests++
+	rec.latency += d
+	rec.tick(now.Unix())
+	rec.seconds[now.Unix()%int64(len(rec.seconds))]++
+	switch {
+	case status >= 500:
+		rec.server++
+		rec.push(RequestError{
+			Time:      start.UTC(),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Route:     router.Pattern(r),
+			Status:    status,
+			LatencyMS: float64(d.Microseconds()) / 1000,
+			RequestID: middleware.RequestIDFrom(r.Context()),
+		})
+	case status >= 400:
+		rec.client++
+	}
+}
+
+// tick 은 마지막 집계 이후 지나간 초의 칸을 비운다.
+func (rec *Recorder) tick(sec int64) {
+	n := int64(len(rec.seconds))
+	if gap := sec - rec.lastSec; gap >= n || rec.lastSec == 0 {
+		rec.seconds = [60]int64{}
+	} else {
+		for s := rec.lastSec + 1; s <= sec; s++ {
+			rec.seconds[s%n] = 0
+		}
+	}
+	if sec > rec.lastSec {
+		rec.lastSec = sec
+	}
+}
+
+// push 는 에러를 순환 버퍼에 넣는다.
+func (rec *Recorder) push(e RequestError) {
+	if len(rec.errors) < cap(rec.errors) {
+		rec.errors = append(rec.errors, e)
+		return
+	}
+	rec.errors[rec.next] = e
+	rec.next = (rec.next + 1) % len(rec.errors)
+}
+
+// Errors 는 최근 에러를 최신 순으로 돌려준다.
+func (rec *Recorder) Errors() []RequestError {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	out := make([]RequestError, 0, len(rec.errors))
+	for i := range rec.errors {
+		idx := (rec.next - 1 - i + 2*len(rec.errors)) % len(rec.errors)
+		out = append(out, rec.errors[idx])
+	}
+	return out
+}
+
+// Stats 는 현재 통계를 돌려준다.
+func (rec *Recorder) Stats() Stats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	now := time.Now()
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.tick(now.Unix())
+	var recent int64
+	for _, n := range rec.seconds {
+		recent += n
+	}
+	s := Stats{
+		Uptime:        now.Sub(rec.start).Round(time.Second).String(),
+		Requests:      rec.requests,
+		InFlight:      rec.inFlight,
+		ClientErrors:  rec.client,
+		ServerErrors:  rec.server,
+		RatePerSecond: float64(recent) / rateWindow.Seconds(),
+		Goroutines:    runtime.NumGoroutine(),
+		HeapBytes:     ms.HeapAlloc,
+	}
+	if rec.requests > 0 {
+		s.AvgLatencyMS = float64((rec.latency / time.Duration(rec.requests)).Microseconds()) / 1000
+	}
+	return s
+}
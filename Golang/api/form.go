@@ -0,0 +1,300 @@
+package api
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/validate"
+)
+
+const (
+	// MaxFormBytes 는 BindForm 이 읽는 multipart 본문의 최대 크기다. 큰 파일은 upload 패키지로 스트리밍한다.
+	MaxFormBytes = 32 << 20
+	// maxFormMemory 를 넘는 multipart 파일은 임시 파일에 쓴다.
+	maxFormMemory = 8 << 20
+)
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	durationType   = reflect.TypeFor[time.Duration]()
+	fileType       = reflect.TypeFor[*multipart.FileHeader]()
+	filesType      = reflect.TypeFor[[]*multipart.FileHeader]()
+	unmarshalerPtr = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// BindForm 은 쿼리 문자열과 urlencoded 또는 multipart 폼을 dst(구조체 포인터)에 채우고 validate 태그로 검사한다.
+//
+//	type signupForm struct {
+//		Name    string                `form:"name" validate:"required,max=64"`
+//		Age     int                   `form:"age" default:"20" validate:"min=14"`
+//		Tags    []string              `form:"tag"`
+//		Agree   bool                  `form:"agree"`          // 체크박스: "on" 이면 true
+//		Born    time.Time             `form:"born"`           // 2006-01-02 또는 datetime-local
+//		Avatar  *multipart.FileHeader `form:"avatar"`
+//	}
+//
+// 필드 이름은 form 태그, 없으면 json 태그, 둘 다 없으면 Go 필드 이름이다. default 태그는 값이 없거나 빈
+// 문자열일 때 쓴다. 값을 바꿀 수 없거나 검사에 실패하면 필드별 Details(form 이름)를 담은 422 *Error 이고,
+// 본문을 읽을 수 없으면 400, 413, 415 *Error 다. FieldErrors 로 템플릿에 넘길 메시지를 만든다.
+func BindForm(r *http.Request, dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		panic("api: BindForm needs a pointer to a struct")
+	}
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	var files map[string][]*multipart.FileHeader
+	if r.MultipartForm != nil {
+		files = r.MultipartForm.File
+	}
+	names := map[string]string{}
+	var errs validate.Errors
+	bindStruct(rv.Elem(), r.Form, files, names, &errs)
+	if len(errs) > 0 {
+		e := NewError(http.StatusUnprocessableEntity, "validation_failed", "request validation failed")
+		e.Details = errs
+		return e
+	}
+	err := Validate(dst)
+	// 검사 에러의 필드 이름은 json 이름이므로 폼 이름으로 바꾼다.
+	var e *Error
+	if errors.As(err, &e) {
+		if fields, ok := e.Details.(validate.Errors); ok {
+			for i, f := range fields {
+				if n, ok := names[f.Field]; ok {
+					fields[i].Field = n
+				}
+			}
+		}
+	}
+	return err
+}
+
+func parseForm(r *http.Request) error {
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var err error
+	switch {
+	case mt == "multipart/form-data":
+		r.Body = http.MaxBytesReader(nil, r.Body, MaxFormBytes)
+		err = r.ParseMultipartForm(maxFormMemory)
+	case mt == "" || mt == "application/x-www-form-urlencoded" || r.Method == http.MethodGet || r.Method == http.MethodHead:
+		r.Body = http.MaxBytesReader(nil, r.Body, MaxBodyBytes)
+		err = r.ParseForm()
+	default:
+		return NewError(http.StatusUnsupportedMediaType, "unsupported_media_type",
+			"Content-Type must be application/x-www-form-urlencoded or multipart/form-data")
+	}
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		return tooLarge(maxErr.Limit)
+	case err != nil:
+		return BadRequest("malformed form body")
+	}
+	return nil
+}
+
+// bindStruct 는 v 의 필드를 채운다. names 에는 검사 에러의 이름(json)과 폼 이름의 짝을 모은다.
+func bindStruct(v reflect.Value, form url.Values, files map[string][]*multipart.FileHeader, names map[string]string, errs *validate.Errors) {
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && sf.Tag.Get("form") == "" {
+			bindStruct(fv, form, files, names, errs)
+			continue
+		}
+		name := formName(sf)
+		if name == "-" {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if jsonName == "" {
+			jsonName = sf.Name
+		}
+		names[jsonName] = name
+		switch sf.Type {
+		case fileType:
+			if fs := files[name]; len(fs) > 0 {
+				fv.Set(reflect.ValueOf(fs[0]))
+			}
+			continue
+		case filesType:
+			fv.Set(reflect.ValueOf(files[name]))
+			continue
+		}
+		vals := form[name]
+		if def, ok := sf.Tag.Lookup("default"); ok && (len(vals) == 0 || len(vals) == 1 && vals[0] == "") {
+			vals = []string{def}
+		}
+		if len(vals) == 0 {
+			continue
+		}
+		if msg := setField(fv, vals); msg != "" {
+			*errs = append(*errs, validate.FieldError{Field: name, Rule: "type", Message: msg})
+		}
+	}
+}
+
+func formName(sf reflect.StructField) string {
+	if n := sf.Tag.Get("form"); n != "" {
+		return n
+	}
+	if n, _, _ := strings.Cut(sf.Tag.Get("json"), ","); n != "" {
+		return n
+	}
+	return sf.Name
+}
+
+// setField 는 vals 를 v 의 타입으로 바꿔 넣는다. 실패하면 메시지를 돌려준다.
+func setField(v reflect.Value, vals []string) string {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if msg := setValue(s.Index(i), val); msg != "" {
+				return msg
+			}
+		}
+		v.Set(s)
+		return ""
+	}
+	return setValue(v, vals[0])
+}
+
+func setValue(v reflect.Value, s string) string {
+	if v.Kind() == reflect.Pointer {
+		if s == "" {
+			return ""
+		}
+		p := reflect.New(v.Type().Elem())
+		if msg := setValue(p.Elem(), s); msg != "" {
+			return msg
+		}
+		v.Set(p)
+		return ""
+	}
+	// 빈 칸으로 보낸 숫자, 날짜 입력은 값이 없는 것으로 본다.
+	if s == "" && v.Kind() != reflect.String {
+		return ""
+	}
+	switch v.Type() {
+	case timeType:
+		for _, layout := range []string{time.DateOnly, "2006-01-02T15:04", "2006-01-02T15:04:05", time.RFC3339} {
+			if t, err := time.Parse(layout, s); err == nil {
+				v.Set(reflect.ValueOf(t))
+				return ""
+			}
+		}
+		return "must be a date (2006-01-02) or time (2006-01-02T15:04)"
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return "must be a duration (e.g. 30s, 5m)"
+		}
+		v.SetInt(int64(d))
+		return ""
+	}
+	if v.Addr().Type().Implements(unmarshalerPtr) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return "is invalid"
+		}
+		return ""
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		switch strings.ToLower(s) {
+		case "on", "yes", "1", "true":
+			v.SetBool(true)
+		case "off", "no", "0", "false":
+			v.SetBool(false)
+		default:
+			return "must be true or false"
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return "must be an integer"
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return "must be a non-negative integer"
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return "must be a number"
+		}
+		v.SetFloat(n)
+	default:
+		panic(fmt.Sprintf("api: BindForm cannot bind %s", v.Type()))
+	}
+	return ""
+}
+
+// FieldErrors 는 BindForm 또는 Validate 에러의 필드별 첫 메시지다. 검사 에러가 아니면 nil 이다.
+func FieldErrors(err error) map[string]string {
+	var e *Error
+	if !errors.As(err, &e) {
+		return nil
+	}
+	fields, ok := e.Details.(validate.Errors)
+	if !ok {
+		return nil
+	}
+	out := map[string]string{}
+	for _, f := range fields {
+		if _, dup := out[f.Field]; !dup {
+			out[f.Field] = f.Message
+		}
+	}
+	return out
+}
+
+// FormFuncMap 은 폼을 다시 보여 줄 때 쓰는 템플릿 함수다.
+//
+//	<input name="age" value="{{.Form.Age}}" {{invalid .Errors "age"}}>
+//	{{with fieldError .Errors "age"}}<p class="error">{{.}}</p>{{end}}
+//	<input type="checkbox" name="agree" {{checked .Form.Agree}}>
+//	<option value="ko" {{selected .Form.Lang "ko"}}>
+func FormFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"fieldError": func(errs map[string]string, name string) string { return errs[name] },
+		"invalid": func(errs map[string]string, name string) template.HTMLAttr {
+			if _, ok := errs[name]; ok {
+				return `aria-invalid="true"`
+			}
+			return ""
+		},
+		"checked": func(on bool) template.HTMLAttr {
+			if on {
+				return "checked"
+			}
+			return ""
+		},
+		"selected": func(value, option any) template.HTMLAttr {
+			if fmt.Sprint(value) == fmt.Sprint(option) {
+				return "selected"
+			}
+			return ""
+		},
+	}
+}
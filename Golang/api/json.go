@@ -0,0 +1,124 @@
+// Package api 는 JSON API 핸들러에서 쓰는 요청/응답 헬퍼와 공통 에러 형식을 제공한다.
+//
+// 모든 에러 응답은 RFC 7807 application/problem+json 형식이다. (apperror)
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/hgsong234/_stack/Golang/apperror"
+	"github.com/hgsong234/_stack/Golang/validate"
+)
+
+// MaxBodyBytes 는 ReadJSON 이 읽는 요청 본문의 기본 최대 크기다.
+const MaxBodyBytes = 1 << 20
+
+// Error 는 API 에러다. apperror.Error 와 같은 타입이다.
+type Error = apperror.Error
+
+// NewError 는 상태 코드와 메시지로 Error 를 만든다.
+func NewError(status int, code, message string) *Error {
+	return apperror.New(status, code, message)
+}
+
+// 자주 쓰는 에러 생성 함수
+func BadRequest(msg string) *Error { return apperror.BadRequest(msg) }
+func NotFound(msg string) *Error   { return apperror.NotFound(msg) }
+func Internal() *Error             { return apperror.Internal(nil) }
+
+// WriteJSON 은 v 를 JSON 으로 인코딩해 status 와 함께 쓴다.
+func WriteJSON(w http.ResponseWriter, status int, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		WriteError(w, Internal())
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// WriteError 는 err 를 problem+json 으로 쓴다. (apperror.Write) 요청 기한 초과는 504, 그 밖에 *Error 가
+// 아니면 500 으로 처리한다.
+func WriteError(w http.ResponseWriter, err error) {
+	apperror.Write(w, nil, err)
+}
+
+// ReadJSON 은 요청 본문을 dst 로 디코딩하고 validate 태그로 검사한다.
+// Content-Type 이 application/json 이 아니거나, 본문이 MaxBodyBytes 를 넘거나,
+// 알 수 없는 필드가 있으면 *Error 를, 검사에 실패하면 422 *Error 를 돌려준다.
+func ReadJSON(r *http.Request, dst any) error {
+	return ReadJSONLimit(r, dst, MaxBodyBytes)
+}
+
+// ReadJSONLimit 은 최대 크기를 지정하는 ReadJSON 이다.
+func ReadJSONLimit(r *http.Request, dst any, limit int64) error {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mt != "application/json" && !strings.HasSuffix(mt, "+json")) {
+		return NewError(http.StatusUnsupportedMediaType, "unsupported_media_type",
+			"Content-Type must be application/json")
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, limit))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	// 본문에 JSON 값이 두 개 이상이면 거부한다.
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		if err == nil {
+			return BadRequest("request body must contain a single JSON value")
+		}
+		return decodeError(err)
+	}
+	return Validate(dst)
+}
+
+// Validate 는 v 를 validate 태그로 검사한다. 실패하면 필드별 Details 를 담은 422 *Error 다.
+// 폼이나 쿼리에서 직접 채운 값을 검사할 때 사용한다.
+func Validate(v any) error {
+	err := validate.Struct(v)
+	var fields validate.Errors
+	if !errors.As(err, &fields) {
+		return err
+	}
+	e := NewError(http.StatusUnprocessableEntity, "validation_failed", "request validation failed")
+	e.Details = fields
+	return e
+}
+
+// decodeError 는 json 디코딩 에러를 클라이언트가 이해할 수 있는 메시지로 바꾼다.
+func decodeError(err error) *Error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		maxErr    *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &maxErr):
+		return tooLarge(maxErr.Limit)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return BadRequest("request body is truncated")
+	case errors.As(err, &syntaxErr):
+		return BadRequest(fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset))
+	case errors.As(err, &typeErr):
+		return BadRequest(fmt.Sprintf("field %q must be %s", typeErr.Field, typeErr.Type))
+	case errors.Is(err, io.EOF):
+		return BadRequest("request body must not be empty")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return BadRequest("unknown field " + strings.TrimPrefix(err.Error(), "json: unknown field "))
+	}
+	return BadRequest(err.Error())
+}
+
+func tooLarge(limit int64) *Error {
+	return NewError(http.StatusRequestEntityTooLarge, "body_too_large",
+		fmt.Sprintf("request body must not exceed %d bytes", limit))
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/hgsong234/_stack/Golang/validate"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type signup struct {
+	Name    string   `json:"name" validate:"required,max=20"`
+	Email   string   `json:"email" validate:"required,email"`
+	Site    *string  `json:"site" validate:"omitempty,url"`
+	Age     int      `json:"age" validate:"min=0,max=150"`
+	Role    string   `json:"role" validate:"omitempty,oneof=admin member"`
+	Tags    []string `json:"tags" validate:"max=3"`
+	Address *address `json:"address"`
+}
+
+const testLimit = 256
+
+func readJSON(contentType string, body []byte, dst any) error {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", contentType)
+	return ReadJSONLimit(r, dst, testLimit)
+}
+
+func TestReadJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"ok", "application/json", `{"name":"kim","email":"kim@example.com"}`, 0},
+		{"problem json", "application/problem+json; charset=utf-8", `{"name":"kim","email":"kim@example.com"}`, 0},
+		{"text", "text/plain", `{}`, http.StatusUnsupportedMediaType},
+		{"no content type", "", `{}`, http.StatusUnsupportedMediaType},
+		{"empty", "application/json", ``, http.StatusBadRequest},
+		{"truncated", "application/json", `{"name":"kim"`, http.StatusBadRequest},
+		{"syntax", "application/json", `{"name":}`, http.StatusBadRequest},
+		{"wrong type", "application/json", `{"age":"ten"}`, http.StatusBadRequest},
+		{"unknown field", "application/json", `{"admin":true}`, http.StatusBadRequest},
+		{"two values", "application/json", `{} {}`, http.StatusBadRequest},
+		{"too large", "application/json", `{"name":"` + strings.Repeat("a", testLimit) + `"}`, http.StatusRequestEntityTooLarge},
+		{"invalid", "application/json", `{"name":" ","email":"nope","age":-1}`, http.StatusUnprocessableEntity},
+		{"nested invalid", "application/json", `{"name":"kim","email":"kim@example.com","address":{}}`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		var dst signup
+		err := readJSON(tt.contentType, []byte(tt.body), &dst)
+		if tt.status == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		var e *Error
+		if !errors.As(err, &e) || e.Status != tt.status {
+			t.Errorf("%s: error %v, want status %d", tt.name, err, tt.status)
+		}
+	}
+}
+
+func TestValidateDetails(t *testing.T) {
+	err := Validate(&signup{Email: "kim@example.com", Age: 200, Address: &address{}})
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusUnprocessableEntity || e.Code != "validation_failed" {
+		t.Fatalf("error %v, want 422 validation_failed", err)
+	}
+	got := map[string]string{}
+	for _, f := range e.Details.(validate.Errors) {
+		got[f.Field] = f.Rule
+	}
+	want := map[string]string{"name": "required", "age": "max", "address.city": "required"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("details %v, want %v", got, want)
+	}
+}
+
+// FuzzReadJSON 은 임의의 Content-Type 과 본문을 디코딩한다. 에러는 언제나 정해진 상태 코드의 *Error 이고,
+// 성공한 값은 검사를 통과하며 다시 인코딩해 읽어도 같은 값이다.
+func FuzzReadJSON(f *testing.F) {
+	for _, s := range [][2]string{
+		{"application/json", `{"name":"kim","email":"kim@example.com","site":"https://example.com","tags":["a"]}`},
+		{"application/json", `{"name":"kim","email":"kim@example.com","address":{"city":"Seoul"}}`},
+		{"application/json; charset=utf-8", `{"name":"\ud800","email":"a@b.c"}`},
+		{"application/vnd.api+json", `{"name":"kim","email":"kim@example.com","role":"admin"}`},
+		{"application/json", `{"name":"kim","email":"kim@example.com","unknown":1}`},
+		{"application/json", `{"name":"kim"} {"name":"lee"}`},
+		{"application/json", `{"age":1e400}`},
+		{"application/json", `{"tags":[1,2]}`},
+		{"application/json", `[`},
+		{"application/json", ``},
+		{"application/json", `null`},
+		{"text/html", `{}`},
+		{"application/json;;", `{}`},
+		{"application/json", `{"name":"` + strings.Repeat("가", testLimit) + `"}`},
+	} {
+		f.Add(s[0], []byte(s[1]))
+	}
+	f.Fuzz(func(t *testing.T, contentType string, body []byte) {
+		var dst signup
+		err := readJSON(contentType, body, &dst)
+		if err != nil {
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("error %T %v is not *Error", err, err)
+			}
+			switch e.Status {
+			case http.StatusBadRequest, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity:
+			case http.StatusRequestEntityTooLarge:
+				if len(body) <= testLimit {
+					t.Fatalf("413 for a %d byte body", len(body))
+				}
+			default:
+				t.Fatalf("unexpected status %d: %v", e.Status, e)
+			}
+			return
+		}
+		if err := validate.Struct(&dst); err != nil {
+			t.Fatalf("ReadJSON accepted a value that fails validation: %v", err)
+		}
+		b, err := json.Marshal(&dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var again signup
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(b)))
+		r.Header.Set("Content-Type", "application/json")
+		if err := ReadJSON(r, &again); err != nil {
+			t.Fatalf("re-reading %s: %v", b, err)
+		}
+		if !reflect.DeepEqual(dst, again) {
+			t.Fatalf("round trip changed %+v to %+v", dst, again)
+		}
+	})
+}
+
+// FuzzValidate 는 임의의 값으로 채운 구조체를 검사한다. 실패는 언제나 422 이고,
+// 보고된 필드는 실제로 규칙을 어긴 필드이며, 같은 값은 같은 결과를 낸다.
+func FuzzValidate(f *testing.F) {
+	f.Add("kim", "kim@example.com", "https://example.com", 30, "admin", uint8(1), "Seoul")
+	f.Add("", "", "", 0, "", uint8(0), "")
+	f.Add("   ", "Kim <kim@example.com>", "ftp://example.com", -1, "root", uint8(4), " ")
+	f.Add(strings.Repeat("가", 21), "@", "http://", 151, "member", uint8(3), "\x00")
+	f.Fuzz(func(t *testing.T, name, email, site string, age int, role string, ntags uint8, city string) {
+		v := signup{Name: name, Email: email, Age: age, Role: role, Tags: make([]string, ntags%8)}
+		if site != "" {
+			v.Site = &site
+		}
+		if city != "" {
+			v.Address = &address{City: city}
+		}
+		err := Validate(&v)
+		if again := Validate(&v); (err == nil) != (again == nil) || err != nil && err.Error() != again.Error() {
+			t.Fatalf("Validate is not deterministic: %v, then %v", err, again)
+		}
+		failed := map[string]string{}
+		if err != nil {
+			var e *Error
+			if !errors.As(err, &e) || e.Status != http.StatusUnprocessableEntity {
+				t.Fatalf("error %v, want 422", err)
+			}
+			fields, _ := e.Details.(validate.Errors)
+			if len(fields) == 0 {
+				t.Fatalf("422 without field details: %v", err)
+			}
+			for _, fe := range fields {
+				if _, dup := failed[fe.Field]; dup || fe.Rule == "" || fe.Message == "" {
+					t.Fatalf("bad field error %+v in %v", fe, fields)
+				}
+				failed[fe.Field] = fe.Rule
+			}
+		}
+		wantName := ""
+		if strings.TrimSpace(name) == "" {
+			wantName = "required"
+		} else if utf8.RuneCountInString(name) > 20 {
+			wantName = "max"
+		}
+		if failed["name"] != wantName {
+			t.Fatalf("name %q: rule %q, want %q", name, failed["name"], wantName)
+		}
+		if (age < 0 || age > 150) != (failed["age"] != "") {
+			t.Fatalf("age %d: rule %q", age, failed["age"])
+		}
+		if (strings.TrimSpace(role) != "" && role != "admin" && role != "member") != (failed["role"] == "oneof") {
+			t.Fatalf("role %q: rule %q", role, failed["role"])
+		}
+		if (ntags%8 > 3) != (failed["tags"] == "max") {
+			t.Fatalf("%d tags: rule %q", ntags%8, failed["tags"])
+		}
+		if (city != "" && strings.TrimSpace(city) == "") != (failed["address.city"] == "required") {
+			t.Fatalf("city %q: rule %q", city, failed["address.city"])
+		}
+		for field := range failed {
+			switch field {
+			case "name", "email", "site", "age", "role", "tags", "address.city":
+			default:
+				t.Fatalf("unexpected field %q in %v", field, failed)
+			}
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/quota"
+	"github.com/hgsong234/_stack/Golang/router"
+	"github.com/hgsong234/_stack/Golang/store"
+)
+
+// Admin 은 키 관리 엔드포인트다.
+//
+//	GET    /keys              목록
+//	POST   /keys              발급 {"name", "scopes", "rate_limit", "burst", "daily_quota", "monthly_quota"} → 원문 포함
+//	POST   /keys/{id}/rotate  같은 설정으로 새 키를 발급하고 기존 키를 폐기
+//	DELETE /keys/{id}         폐기
+//	PUT    /keys/{id}/quota   할당량 재정의 {"daily_quota", "monthly_quota"} (null 은 기본값)
+//	GET    /keys/{id}/usage   현재 기간의 사용량 (Quotas 가 있을 때)
+type Admin struct {
+	Keys *store.APIKeys
+	// Quotas 가 있으면 사용량 라우트를 등록한다.
+	Quotas *quota.Tracker
+}
+
+// Issued 는 발급 응답이다. Key 는 이 응답에서만 볼 수 있다.
+type Issued struct {
+	store.APIKey
+	Key string `json:"key"`
+}
+
+// Mount 는 r 의 prefix 아래에 키 관리 라우트를 등록한다. mws 로 관리자 인증을 건다.
+func (a *Admin) Mount(r router.Routes, prefix string, mws ...router.Middleware) {
+	g := r.Group(prefix, mws...)
+	g.GET("/keys", a.list)
+	g.POST("/keys", a.create)
+	g.POST("/keys/{id}/rotate", a.rotate)
+	g.DELETE("/keys/{id}", a.revoke)
+	g.PUT("/keys/{id}/quota", a.setQuota)
+	if a.Quotas != nil {
+		g.GET("/keys/{id}/usage", a.usage)
+	}
+}
+
+func (a *Admin) list(w http.ResponseWriter, r *http.Request) {
+	keys, err := a.Keys.List(r.Context())
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	api.WriteJSON(w, http.StatusOK, map[string]any{"keys": keys})
+}
+
+func (a *Admin) create(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name      string   `json:"name" validate:"required,max=100"`
+		Scopes    []string `json:"scopes"`
+		RateLimit float64  `json:"rate_limit" validate:"min=0"`
+		Burst     int      `json:"burst" validate:"min=0"`
+		quotas
+	}
+	if err := api.ReadJSON(r, &req); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+	if err := req.quotas.check(); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+	a.issue(w, r, store.APIKey{
+		Name: req.Name, Scopes: req.Scopes, RateLimit: req.RateLimit, Burst: req.Burst,
+		DailyQuota: req.Daily, MonthlyQuota: req.Monthly,
+	})
+}
+
+func (a *Admin) rotate(w http.ResponseWriter, r *http.Request) {
+	old, ok := a.load(w, r)
+	if !ok {
+		return
+	}
+	if old.Revoked() {
+		api.WriteError(w, api.NewError(http.StatusConflict, "revoked", "API key is already revoked"))
+		return
+	}
+	// 사용량은 키마다 세므로 새 키는 0 에서 시작한다.
+	if !a.issue(w, r, store.APIKey{
+		Name: old.Name, Scopes: old.Scopes, RateLimit: old.RateLimit, Burst: old.Burst,
+		DailyQuota: old.DailyQuota, MonthlyQuota: old.MonthlyQuota,
+	}) {
+		return
+	}
+	if err := a.Keys.Revoke(r.Context(), old.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		logging.From(r.Context()).Error("apikey: revoke after rotation", "id", old.ID, "err", err)
+	}
+}
+
+func (a *Admin) revoke(w http.ResponseWriter, r *http.Request) {
+	k, ok := a.load(w, r)
+	if !ok {
+		return
+	}
+	if err := a.Keys.Revoke(r.Context(), k.ID); err != nil {
+		fail(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// quotas 는 할당량 재정의 요청이다. null(생략)은 기본값, 0 은 제한 없음이다.
+type quotas struct {
+	Daily   *int64 `json:"daily_quota"`
+	Monthly *int64 `json:"monthly_quota"`
+}
+
+func (q quotas) check() error {
+	if (q.Daily != nil && *q.Daily < 0) || (q.Monthly != nil && *q.Monthly < 0) {
+		return api.BadRequest("quotas must not be negative")
+	}
+	return nil
+}
+
+func (a *Admin) setQuota(w http.ResponseWriter, r *http.Request) {
+	k, ok := a.load(w, r)
+	if !ok {
+		return
+	}
+	var req quotas
+	if err := api.ReadJSON(r, &req); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+	if err := req.check(); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+	if err := a.Keys.SetQuotas(r.Context(), k.ID, req.Daily, req.Monthly); err != nil {
+		fail(w, r, err)
+		return
+	}
+	k.DailyQuota, k.MonthlyQuota = req.Daily, req.Monthly
+	api.WriteJSON(w, http.StatusOK, k)
+}
+
+func (a *Admin) usage(w http.ResponseWriter, r *http.Request) {
+	k, ok := a.load(w, r)
+	if !ok {
+		return
+	}
+	usages, err := a.Quotas.Usage(r.Context(), k)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	api.WriteJSON(w, http.StatusOK, map[string]any{"quotas": usages})
+}
+
+// issue 는 새 키를 저장하고 원문과 함께 201 로 응답한다.
+func (a *Admin) issue(w http.ResponseWriter, r *http.Request, k store.APIKey) bool {
+	key, prefix, hash := Generate()
+	k.Prefix, k.Hash = prefix, hash
+	if k.Scopes == nil {
+		k.Scopes = []string{}
+	}
+	if err := a.Keys.Create(r.Context(), &k); err != nil {
+		fail(w, r, err)
+		return false
+	}
+	api.WriteJSON(w, http.StatusCreated, Issued{APIKey: k, Key: key})
+	return true
+}
+
+// load 는 경로의 {id} 키를 읽는다.
+func (a *Admin) load(w http.ResponseWriter, r *http.Request) (*store.APIKey, bool) {
+	id, err := strconv.ParseInt(router.Param(r, "id"), 10, 64)
+	if err != nil {
+		api.WriteError(w, api.BadRequest("id must be an integer"))
+		return nil, false
+	}
+	k, err := a.Keys.Get(r.Context(), id)
+	if err != nil {
+		fail(w, r, err)
+		return nil, false
+	}
+	return k, true
+}
+
+func fail(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		api.WriteError(w, api.NotFound("API key not found"))
+		return
+	}
+	logging.From(r.Context()).Error("apikey: store", "err", err)
+	api.WriteError(w, err)
+}
@@ -0,0 +1,255 @@
+// Package apikey 는 X-API-Key 헤더 인증, 키 발급과 교체, 키별 scope 와 요청 제한, 할당량을 제공한다.
+//
+// 키는 "ak_<prefix>_<secret>" 형식이다. prefix 로 행을 찾고 전체 키의 SHA-256 해시를 비교하므로
+// 데이터베이스가 유출되어도 키 원문은 알 수 없다. 원문은 발급할 때 한 번만 보여준다.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/audit"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/quota"
+	"github.com/hgsong234/_stack/Golang/ratelimit"
+	"github.com/hgsong234/_stack/Golang/router"
+	"github.com/hgsong234/_stack/Golang/store"
+	"github.com/hgsong234/_stack/Golang/timing"
+)
+
+// Header 는 API 키를 담는 요청 헤더다.
+const Header = "X-API-Key"
+
+// Generate 는 새 키 원문과 저장할 prefix, 해시를 만든다.
+func Generate() (key, prefix, hash string) {
+	p := make([]byte, 6)
+	s := make([]byte, 32)
+	rand.Read(p)
+	rand.Read(s)
+	prefix = hex.EncodeToString(p)
+	key = "ak_" + prefix + "_" + base64.RawURLEncoding.EncodeToString(s)
+	return key, prefix, Hash(key)
+}
+
+// Hash 는 키 원문의 해시다.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// parse 는 키에서 prefix 를 꺼낸다.
+func parse(key string) (prefix string, ok bool) {
+	rest, ok := strings.CutPrefix(key, "ak_")
+	if !ok {
+		return "", false
+	}
+	prefix, _, ok = strings.Cut(rest, "_")
+	return prefix, ok && prefix != ""
+}
+
+// Authenticator 는 API 키를 검증한다.
+type Authenticator struct {
+	Keys *store.APIKeys
+	// Limits 는 키별 요청 제한 상태를 보관한다. nil 이면 키별 제한을 적용하지 않는다.
+	Limits ratelimit.Backend
+	// Quotas 가 있으면 키별 일간·월간 할당량을 센다.
+	Quotas *quota.Tracker
+}
+
+// 컨텍스트 키
+type ctxKey struct{}
+
+// From 은 요청을 인증한 API 키를 돌려준다. API 키로 인증하지 않았으면 nil 이다.
+func From(ctx context.Context) *store.APIKey {
+	k, _ := ctx.Value(ctxKey{}).(*store.APIKey)
+	return k
+}
+
+// HasScope 는 키에 scope 가 있는지 확인한다. "*" 는 모든 scope 다.
+func HasScope(k *store.APIKey, scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify 는 키 원문을 확인하고 저장된 키를 돌려준다.
+func (a *Authenticator) Verify(ctx context.Context, key string) (*store.APIKey, error) {
+	prefix, ok := parse(key)
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	k, err := a.Keys.ByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(k.Hash), []byte(Hash(key))) != 1 || k.Revoked() {
+		return nil, store.ErrNotFound
+	}
+	return k, nil
+}
+
+// Middleware 는 X-API-Key 헤더가 있으면 검증해서 컨텍스트에 저장하는 미들웨어를 만든다.
+// 헤더가 없으면 그대로 통과시키므로 다른 인증(JWT, 세션)과 함께 쓸 수 있다.
+// 잘못되거나 폐기된 키는 401, 키별 한도나 할당량을 넘으면 429 다.
+func (a *Authenticator) Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw := r.Header.Get(Header)
+			if raw == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			done := timing.Start(r.Context(), "auth")
+			k, err := a.Verify(r.Context(), raw)
+			done()
+			if err != nil {
+				if !errors.Is(err, store.ErrNotFound) {
+					logging.From(r.Context()).Error("apikey: verify", "err", err)
+					api.WriteError(w, err)
+					return
+				}
+				prefix, _ := parse(raw)
+				audit.RecordRequest(r, audit.Event{
+					Type: "apikey.rejected", Outcome: audit.Failure, Actor: "anonymous", Target: r.Method + " " + r.URL.Path,
+					Fields: map[string]any{"prefix": prefix},
+				})
+				api.WriteError(w, api.NewError(http.StatusUnauthorized, "unauthorized", "invalid API key"))
+				return
+			}
+			if !a.allow(w, r, k) || !a.consume(w, r, k) {
+				return
+			}
+			audit.RecordRequest(r, audit.Event{
+				Type: "apikey.used", Actor: "apikey:" + strconv.FormatInt(k.ID, 10), Target: r.Method + " " + r.URL.Path,
+				Fields: map[string]any{"name": k.Name},
+			})
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, k)))
+		})
+	}
+}
+
+// allow 는 키별 요청 제한을 적용한다. 백엔드 장애 때는 통과시킨다.
+func (a *Authenticator) allow(w http.ResponseWriter, r *http.Request, k *store.APIKey) bool {
+	if a.Limits == nil || k.RateLimit <= 0 {
+		return true
+	}
+	burst := k.Burst
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(k.RateLimit)))
+	}
+	res, err := a.Limits.Take(r.Context(), "apikey:"+strconv.FormatInt(k.ID, 10), k.RateLimit, burst)
+	if err != nil {
+		logging.From(r.Context()).Error("apikey: rate limit", "err", err)
+		return true
+	}
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(burst))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(float64(burst-res.Remaining)/k.RateLimit))))
+	if res.Allowed {
+		return true
+	}
+	// 0 이면 클라이언트가 곧바로 다시 보내므로 최소 1 초로 알린다. (ratelimit.Middleware 와 같다)
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(res.RetryAfter.Seconds())))))
+	api.WriteError(w, api.NewError(http.StatusTooManyRequests, "rate_limited", "API key rate limit exceeded"))
+	return false
+}
+
+// consume 은 요청을 할당량에 센다. 기간마다 X-RateLimit-{Daily,Monthly}-{Limit,Remaining,Reset} 헤더를 쓰고
+// (Reset 은 Unix 초), 할당량을 넘으면 다음 기간까지 Retry-After 와 함께 429 로 거부한다. 백엔드 장애 때는 통과시킨다.
+func (a *Authenticator) consume(w http.ResponseWriter, r *http.Request, k *store.APIKey) bool {
+	if a.Quotas == nil {
+		return true
+	}
+	usages, ok, err := a.Quotas.Consume(r.Context(), k)
+	if err != nil {
+		logging.From(r.Context()).Error("apikey: quota", "err", err)
+		return true
+	}
+	var exceeded *quota.Usage
+	for i, u := range usages {
+		if u.Limit == 0 {
+			continue
+		}
+		name := "X-RateLimit-" + periodHeader[u.Period]
+		w.Header().Set(name+"-Limit", strconv.FormatInt(u.Limit, 10))
+		w.Header().Set(name+"-Remaining", strconv.FormatInt(*u.Remaining, 10))
+		w.Header().Set(name+"-Reset", strconv.FormatInt(u.Reset.Unix(), 10))
+		if u.Exceeded() && exceeded == nil {
+			exceeded = &usages[i]
+		}
+	}
+	if ok || exceeded == nil {
+		return true
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(exceeded.Reset).Seconds()))))
+	api.WriteError(w, api.NewError(http.StatusTooManyRequests, "quota_exceeded",
+		strings.ToLower(periodHeader[exceeded.Period])+" API key quota exceeded"))
+	return false
+}
+
+var periodHeader = map[quota.Period]string{quota.Day: "Daily", quota.Month: "Monthly"}
+
+// UsageHandler 는 요청한 API 키의 현재 할당량과 사용량을 보여준다. Require 뒤에 건다.
+//
+//	{"key": {"id", "name", "prefix"}, "quotas": [{"period": "day", "limit", "used", "remaining", "reset"}, ...]}
+func UsageHandler(t *quota.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		k := From(r.Context())
+		if k == nil {
+			api.WriteError(w, api.NewError(http.StatusUnauthorized, "unauthorized", "API key required"))
+			return
+		}
+		usages, err := t.Usage(r.Context(), k)
+		if err != nil {
+			logging.From(r.Context()).Error("apikey: quota usage", "err", err)
+			api.WriteError(w, err)
+			return
+		}
+		api.WriteJSON(w, http.StatusOK, map[string]any{
+			"key":    map[string]any{"id": k.ID, "name": k.Name, "prefix": k.Prefix},
+			"quotas": usages,
+		})
+	}
+}
+
+// RequireScope 는 API 키로 인증한 요청에 scope 가 있는지 검사하는 미들웨어를 만든다.
+// API 키 없이 온 요청은 통과시키므로, 키 인증을 필수로 하려면 Require 와 함께 쓴다.
+func RequireScope(scope string) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if k := From(r.Context()); k != nil && !HasScope(k, scope) {
+				api.WriteError(w, api.NewError(http.StatusForbidden, "insufficient_scope",
+					"API key lacks scope "+strconv.Quote(scope)))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Require 는 유효한 API 키가 없는 요청을 401 로 거부하는 미들웨어를 만든다.
+func Require() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if From(r.Context()) == nil {
+				api.WriteError(w, api.NewError(http.StatusUnauthorized, "unauthorized", "API key required"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Package apperror 는 서비스 전체에서 쓰는 에러 타입과, 에러를 RFC 7807 application/problem+json 으로
+// 쓰는 공통 렌더러다.
+//
+// 핸들러는 에러 형식을 직접 만들지 않고 *Error 를 돌려주거나 Write 에 넘긴다. 원인(Cause)은 로그와
+// errors.Is/As 용이고 응답에는 싣지 않는다. *Error 가 아닌 에러는 500 internal 로 감춘다.
+//
+//	{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "user not found",
+//	 "code": "not_found", "request_id": "..."}
+package apperror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ContentType 은 에러 응답의 미디어 타입이다.
+const ContentType = "application/problem+json"
+
+// Error 는 클라이언트에게 보여 줄 에러다. Code 는 클라이언트가 분기할 때 쓰는 안정된 snake_case 값이다.
+type Error struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Details 는 필드별 검사 결과처럼 에러에 딸린 구조화된 값이다.
+	Details any `json:"details,omitempty"`
+	// Cause 는 감싼 원인이다. 응답에는 싣지 않는다.
+	Cause error `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Code + ": " + e.Message + ": " + e.Cause.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error { return e.Cause }
+
+// New 는 상태 코드, 코드, 메시지로 Error 를 만든다.
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Wrap 은 cause 를 감싼 Error 를 만든다.
+func Wrap(cause error, status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message, Cause: cause}
+}
+
+// WithDetails 는 Details 를 붙인 복사본이다.
+func (e *Error) WithDetails(details any) *Error {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+// 자주 쓰는 에러 생성 함수
+func BadRequest(msg string) *Error { return New(http.StatusBadRequest, "bad_request", msg) }
+func NotFound(msg string) *Error   { return New(http.StatusNotFound, "not_found", msg) }
+func Forbidden(msg string) *Error  { return New(http.StatusForbidden, "forbidden", msg) }
+func Conflict(msg string) *Error   { return New(http.StatusConflict, "conflict", msg) }
+
+// Internal 은 원인을 감춘 500 에러다. cause 는 nil 이어도 된다.
+func Internal(cause error) *Error {
+	return Wrap(cause, http.StatusInternalServerError, "internal", "internal server error")
+}
+
+// From 은 err 를 응답할 Error 로 바꾼다. 체인에 *Error 가 있으면 그것을, 요청 기한 초과는 504 를,
+// 그 밖에는 err 를 원인으로 감싼 Internal 을 돌려준다.
+func From(err error) *Error {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, context.DeadlineExceeded):
+		return Wrap(err, http.StatusGatewayTimeout, "deadline_exceeded", "request deadline exceeded")
+	}
+	return Internal(err)
+}
+
+// Problem 은 RFC 7807 문제 상세 문서다. Code, Details, RequestID 는 확장 멤버다.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	// Extra 는 개발 모드의 스택 트레이스처럼 특정 응답에만 붙는 확장 멤버다.
+	Extra map[string]any `json:"-"`
+}
+
+// MarshalJSON 은 Extra 를 최상위 멤버로 펼친다.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type plain Problem
+	b, err := json.Marshal(plain(p))
+	if err != nil || len(p.Extra) == 0 {
+		return b, err
+	}
+	m := map[string]any{}
+	for k, v := range p.Extra {
+		m[k] = v
+	}
+	var base map[string]any
+	json.Unmarshal(b, &base)
+	for k, v := range base {
+		m[k] = v
+	}
+	return json.Marshal(m)
+}
+
+// Problem 은 e 의 문제 상세 문서다.
+func (e *Error) Problem() Problem {
+	return Problem{
+		Type:    "about:blank",
+		Title:   http.StatusText(e.Status),
+		Status:  e.Status,
+		Detail:  e.Message,
+		Code:    e.Code,
+		Details: e.Details,
+	}
+}
+
+// Write 는 err 를 problem+json 으로 쓴다. r 이 있으면 요청 경로를 instance 로 싣는다.
+// 요청 ID 는 RequestID 미들웨어가 응답 헤더에 넣어 둔 값을 쓴다.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	p := From(err).Problem()
+	if r != nil {
+		p.Instance = r.URL.Path
+	}
+	WriteProblem(w, p)
+}
+
+// WriteProblem 은 p 를 그대로 쓴다.
+func WriteProblem(w http.ResponseWriter, p Problem) {
+	if p.RequestID == "" {
+		p.RequestID = w.Header().Get("X-Request-ID")
+	}
+	b, _ := json.Marshal(p)
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	w.Write(append(b, '\n'))
+}
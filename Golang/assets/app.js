@@ -0,0 +1,6 @@
+// 현재 페이지를 가리키는 머리말 링크에 aria-current 를 붙인다.
+document.querySelectorAll("header a").forEach((a) => {
+  if (a.pathname === location.pathname) {
+    a.setAttribute("aria-current", "page");
+  }
+});
@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// maxLimit 는 한 번에 조회할 수 있는 최대 이벤트 수다.
+const maxLimit = 1000
+
+// Mount 는 r 의 prefix 에 감사 로그 조회 라우트를 등록한다. mws 로 관리자 인가를 건다.
+//
+//	GET ?type=auth.&actor=alice&outcome=failure&since=2024-01-01T00:00:00Z&until=...&limit=100
+//
+// 최근 이벤트부터 돌려주고, limit 의 기본값은 100, 최댓값은 1000 이다.
+func (l *Logger) Mount(r router.Routes, prefix string, mws ...router.Middleware) {
+	r.GET(prefix, l.query, mws...)
+}
+
+func (l *Logger) query(w http.ResponseWriter, r *http.Request) {
+	if l.Store == nil {
+		api.WriteError(w, api.NewError(http.StatusNotFound, "audit_disabled", "audit log is not enabled"))
+		return
+	}
+	q := r.URL.Query()
+	f := Filter{Type: q.Get("type"), Actor: q.Get("actor"), Outcome: q.Get("outcome"), Limit: 100}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				api.WriteError(w, api.BadRequest(p.name+" must be an RFC 3339 time"))
+				return
+			}
+			*p.dst = t
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			api.WriteError(w, api.BadRequest("limit must be between 1 and "+strconv.Itoa(maxLimit)))
+			return
+		}
+		f.Limit = n
+	}
+	events, err := l.Store.Query(r.Context(), f)
+	if err != nil {
+		logging.From(r.Context()).Error("audit: query", "err", err)
+		api.WriteError(w, api.Internal())
+		return
+	}
+	if events == nil {
+		events = []Event{}
+	}
+	api.WriteJSON(w, http.StatusOK, map[string]any{"events": events})
+}
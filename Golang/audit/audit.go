@@ -0,0 +1,176 @@
+// Package audit 는 보안 관련 이벤트(로그인, 권한 거부, 관리 작업, API 키 사용)를 접근 로그와 별도로
+// 추가 전용 저장소(파일 또는 DB)에 남긴다.
+//
+// 이벤트 종류는 "영역.동작" 이름이다.
+//
+//	auth.login, auth.logout, auth.lockout, auth.signup,          로그인과 계정
+//	auth.email_verified, auth.password_reset, auth.2fa
+//	authz.denied                                                 역할·권한 거부
+//	admin.change, config.reload                                  관리 API 변경
+//	apikey.used, apikey.rejected                                 API 키 사용
+//
+// 저장에 실패해도 요청은 막지 않고, 에러 로그와 audit_write_errors_total 메트릭으로 알린다.
+package audit
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/metrics"
+	"github.com/hgsong234/_stack/Golang/middleware"
+	"github.com/hgsong234/_stack/Golang/realip"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// 결과
+const (
+	Success = "success"
+	Failure = "failure"
+	Denied  = "denied"
+)
+
+var (
+	recorded    = metrics.NewCounterVec("audit_events_total", "Audit events recorded by type and outcome.", "type", "outcome")
+	writeErrors = metrics.NewCounterVec("audit_write_errors_total", "Audit events that could not be stored.", "store")
+)
+
+// Event 는 감사 이벤트 하나다.
+type Event struct {
+	ID   int64     `json:"id"`
+	Time time.Time `json:"time"`
+	Type string    `json:"type"`
+	// Outcome 은 Success, Failure, Denied 중 하나다.
+	Outcome string `json:"outcome"`
+	// Actor 는 행위자다. (예: "alice", "apikey:3", "local:12") 요청 밖의 이벤트는 "system" 이다.
+	Actor string `json:"actor,omitempty"`
+	// Target 은 대상이다. (예: 경로, 계정 이메일)
+	Target    string         `json:"target,omitempty"`
+	RemoteIP  string         `json:"remote_ip,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	Fields    map[string]any `json:"fields,omitempty"`
+}
+
+// Filter 는 조회 조건이다. 빈 값은 조건이 없는 것이다.
+type Filter struct {
+	// Type 이 "." 으로 끝나면 접두사로 비교한다. (예: "auth.")
+	Type    string
+	Actor   string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+	// Limit 은 돌려줄 최대 개수이고, 최근 이벤트부터 돌려준다.
+	Limit int
+}
+
+func (f Filter) match(e *Event) bool {
+	if f.Type != "" {
+		if f.Type[len(f.Type)-1] == '.' {
+			if len(e.Type) < len(f.Type) || e.Type[:len(f.Type)] != f.Type {
+				return false
+			}
+		} else if e.Type != f.Type {
+			return false
+		}
+	}
+	return (f.Actor == "" || e.Actor == f.Actor) &&
+		(f.Outcome == "" || e.Outcome == f.Outcome) &&
+		(f.Since.IsZero() || !e.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || e.Time.Before(f.Until))
+}
+
+// Store 는 추가 전용 이벤트 저장소다.
+type Store interface {
+	// Append 는 e 를 저장하고 e.ID 를 채운다.
+	Append(ctx context.Context, e *Event) error
+	Query(ctx context.Context, f Filter) ([]Event, error)
+	// Name 은 메트릭과 로그에 쓰는 저장소 이름이다.
+	Name() string
+}
+
+// Logger 는 이벤트에 요청 정보를 채워 Store 에 남긴다. 필드는 서버를 시작하기 전에 정한다.
+type Logger struct {
+	// Store 가 nil 이면 기록하지 않는다.
+	Store Store
+	// Actor 는 요청의 행위자를 정한다. Event.Actor 가 비어 있을 때만 쓴다.
+	Actor func(r *http.Request) string
+}
+
+// Default 는 패키지 수준 함수가 사용하는 Logger 다. 저장소를 정하기 전에는 기록하지 않는다.
+var Default = &Logger{}
+
+// Record 는 요청 밖의 이벤트(시그널, 주기 작업)를 남긴다. Actor 가 비어 있으면 "system" 이다.
+func Record(ctx context.Context, e Event) { Default.Record(ctx, e) }
+
+// RecordRequest 는 r 의 행위자, 클라이언트 IP, 요청 ID 를 채워 이벤트를 남긴다.
+func RecordRequest(r *http.Request, e Event) { Default.RecordRequest(r, e) }
+
+// Record 는 요청 밖의 이벤트를 남긴다.
+func (l *Logger) Record(ctx context.Context, e Event) {
+	if e.Actor == "" {
+		e.Actor = "system"
+	}
+	l.append(ctx, e)
+}
+
+// RecordRequest 는 r 의 정보를 채워 이벤트를 남긴다.
+func (l *Logger) RecordRequest(r *http.Request, e Event) {
+	if l.Store == nil {
+		return
+	}
+	if e.Actor == "" && l.Actor != nil {
+		e.Actor = l.Actor(r)
+	}
+	if e.RemoteIP == "" {
+		e.RemoteIP = realip.Host(r)
+	}
+	if e.RequestID == "" {
+		e.RequestID = middleware.RequestIDFrom(r.Context())
+	}
+	l.append(r.Context(), e)
+}
+
+func (l *Logger) append(ctx context.Context, e Event) {
+	s := l.Store
+	if s == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if e.Outcome == "" {
+		e.Outcome = Success
+	}
+	// 요청이 취소되어도 기록은 남아야 한다.
+	if err := s.Append(context.WithoutCancel(ctx), &e); err != nil {
+		writeErrors.Inc(s.Name())
+		logging.From(ctx).Error("audit: store event", "store", s.Name(), "type", e.Type, "err", err)
+		return
+	}
+	recorded.Inc(e.Type, e.Outcome)
+}
+
+// Changes 는 GET, HEAD, OPTIONS 가 아닌 요청을 admin.change 이벤트로 남기는 미들웨어다.
+// 관리 API 의 인가 미들웨어 뒤에 걸면 행위자는 인가된 주체다.
+func Changes() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+			sw := middleware.NewStatusWriter(w)
+			next.ServeHTTP(sw, r)
+			outcome := Success
+			if sw.Code() >= 400 {
+				outcome = Failure
+			}
+			RecordRequest(r, Event{
+				Type: "admin.change", Outcome: outcome, Target: r.URL.Path,
+				Fields: map[string]any{"method": r.Method, "status": sw.Code()},
+			})
+		})
+	}
+}
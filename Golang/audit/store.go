@@ -0,0 +1,191 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hgsong234/_stack/Golang/store"
+)
+
+// FileStore 는 이벤트를 JSON 한 줄씩 파일 끝에 추가한다. 파일은 O_APPEND 로만 연다.
+// 이벤트 ID 는 파일의 줄 번호다. 조회는 파일 전체를 읽으므로 관리용 조회에만 쓴다.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	next int64
+	// Sync 이면 이벤트마다 fsync 한다.
+	Sync bool
+}
+
+// OpenFile 은 path 를 열고(없으면 만들고) 마지막 이벤트 ID 를 읽는다.
+func OpenFile(path string) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: open %s: %w", path, err)
+	}
+	s := &FileStore{path: path, f: f}
+	if err := s.scan(func(e *Event) bool { s.next = e.ID; return true }); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) Name() string { return "file" }
+
+func (s *FileStore) Append(_ context.Context, e *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.ID = s.next + 1
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := s.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	s.next = e.ID
+	if s.Sync {
+		return s.f.Sync()
+	}
+	return nil
+}
+
+func (s *FileStore) Query(ctx context.Context, f Filter) ([]Event, error) {
+	var out []Event
+	err := s.scan(func(e *Event) bool {
+		if f.match(e) {
+			out = append(out, *e)
+			// 최근 Limit 개만 남긴다.
+			if f.Limit > 0 && len(out) > f.Limit {
+				out = out[1:]
+			}
+		}
+		return ctx.Err() == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, ctx.Err()
+}
+
+// scan 은 파일의 이벤트를 처음부터 읽는다. 읽을 수 없는 줄(쓰다 끊긴 마지막 줄 등)은 건너뛴다.
+func (s *FileStore) scan(fn func(*Event) bool) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("audit: read %s: %w", s.path, err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		var e Event
+		if json.Unmarshal(sc.Bytes(), &e) != nil {
+			continue
+		}
+		if !fn(&e) {
+			break
+		}
+	}
+	return sc.Err()
+}
+
+// Close 는 파일을 닫는다.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+// DBStore 는 이벤트를 audit_events 테이블에 추가한다. 테이블의 트리거가 수정과 삭제를 막는다.
+type DBStore struct {
+	db store.DB
+}
+
+// NewDBStore 는 db 를 사용하는 DBStore 를 만든다.
+func NewDBStore(db store.DB) *DBStore { return &DBStore{db: db} }
+
+func (s *DBStore) Name() string { return "db" }
+
+func (s *DBStore) Append(ctx context.Context, e *Event) error {
+	fields := []byte("{}")
+	if len(e.Fields) > 0 {
+		var err error
+		if fields, err = json.Marshal(e.Fields); err != nil {
+			return err
+		}
+	}
+	return s.db.QueryRowContext(ctx, s.db.Rebind(
+		`INSERT INTO audit_events (time, type, outcome, actor, target, remote_ip, request_id, fields)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		e.Time, e.Type, e.Outcome, e.Actor, e.Target, e.RemoteIP, e.RequestID, string(fields),
+	).Scan(&e.ID)
+}
+
+func (s *DBStore) Query(ctx context.Context, f Filter) ([]Event, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, v any) {
+		where = append(where, cond)
+		args = append(args, v)
+	}
+	if f.Type != "" {
+		if strings.HasSuffix(f.Type, ".") {
+			add("type LIKE ?", f.Type+"%")
+		} else {
+			add("type = ?", f.Type)
+		}
+	}
+	if f.Actor != "" {
+		add("actor = ?", f.Actor)
+	}
+	if f.Outcome != "" {
+		add("outcome = ?", f.Outcome)
+	}
+	if !f.Since.IsZero() {
+		add("time >= ?", f.Since.UTC())
+	}
+	if !f.Until.IsZero() {
+		add("time < ?", f.Until.UTC())
+	}
+	q := `SELECT id, time, type, outcome, actor, target, remote_ip, request_id, fields FROM audit_events`
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	q += " ORDER BY id DESC"
+	if f.Limit > 0 {
+		q += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(q), args...)
+	if err != nil {
+		return nil, fmt.Errorf("audit: query: %w", err)
+	}
+	defer rows.Close()
+	var out []Event
+	for rows.Next() {
+		var (
+			e      Event
+			fields sql.NullString
+		)
+		if err := rows.Scan(&e.ID, &e.Time, &e.Type, &e.Outcome, &e.Actor, &e.Target, &e.RemoteIP, &e.RequestID, &fields); err != nil {
+			return nil, fmt.Errorf("audit: query: %w", err)
+		}
+		if fields.String != "" && fields.String != "{}" {
+			json.Unmarshal([]byte(fields.String), &e.Fields)
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,219 @@
+// Package auth 는 Bearer JWT(HS256/RS256) 인증 미들웨어와 토큰 발급을 제공한다.
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 검증 에러
+var (
+	ErrMalformed    = errors.New("auth: malformed token")
+	ErrSignature    = errors.New("auth: invalid signature")
+	ErrUnknownKey   = errors.New("auth: unknown signing key")
+	ErrExpired      = errors.New("auth: token expired")
+	ErrNotYetValid  = errors.New("auth: token not yet valid")
+	ErrClaimInvalid = errors.New("auth: invalid claims")
+)
+
+// Claims 는 토큰에 담기는 클레임이다.
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	// AMR 은 사용자가 로그인한 방식이다. (RFC 8176, 예: "pwd", "otp", "mfa")
+	AMR []string `json:"amr,omitempty"`
+}
+
+// Audience 는 문자열 하나 또는 문자열 배열로 표현되는 aud 클레임이다.
+type Audience []string
+
+// UnmarshalJSON 은 두 형식을 모두 받아들인다.
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = Audience{s}
+		return nil
+	}
+	var ss []string
+	if err := json.Unmarshal(b, &ss); err != nil {
+		return err
+	}
+	*a = ss
+	return nil
+}
+
+// Contains 는 aud 에 v 가 포함되어 있는지 확인한다.
+func (a Audience) Contains(v string) bool {
+	for _, x := range a {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+var b64 = base64.RawURLEncoding
+
+// Sign 은 서명 키로 클레임에 서명한 토큰을 만든다.
+func (ks *KeySet) Sign(c Claims) (string, error) {
+	k := ks.signer()
+	if k == nil {
+		return "", errors.New("auth: no signing key configured")
+	}
+	h, _ := json.Marshal(header{Alg: k.Alg, Typ: "JWT", Kid: k.ID})
+	p, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	input := b64.EncodeToString(h) + "." + b64.EncodeToString(p)
+	sig, err := k.sign([]byte(input))
+	if err != nil {
+		return "", err
+	}
+	return input + "." + b64.EncodeToString(sig), nil
+}
+
+// Verify 는 토큰의 서명과 시간 클레임, 발급자·대상을 검사한다.
+func (ks *KeySet) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+	hb, err := b64.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	var h header
+	if err := json.Unmarshal(hb, &h); err != nil {
+		return nil, ErrMalformed
+	}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	input := []byte(parts[0] + "." + parts[1])
+
+	// kid 가 있으면 그 키만, 없으면 알고리즘이 같은 모든 키로 검증한다. (키 교체 기간 지원)
+	candidates := ks.verifiers(h.Kid, h.Alg)
+	if len(candidates) == 0 {
+		return nil, ErrUnknownKey
+	}
+	ok := false
+	for _, k := range candidates {
+		if k.verify(input, sig) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return nil, ErrSignature
+	}
+
+	pb, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	var c Claims
+	if err := json.Unmarshal(pb, &c); err != nil {
+		return nil, ErrMalformed
+	}
+	if err := ks.validate(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// validate 는 시간과 발급자·대상 클레임을 검사한다.
+func (ks *KeySet) validate(c *Claims) error {
+	now := ks.now().Unix()
+	leeway := int64(ks.Leeway / time.Second)
+	if c.ExpiresAt != 0 && now > c.ExpiresAt+leeway {
+		return ErrExpired
+	}
+	if c.NotBefore != 0 && now+leeway < c.NotBefore {
+		return ErrNotYetValid
+	}
+	if ks.Issuer != "" && c.Issuer != ks.Issuer {
+		return fmt.Errorf("%w: issuer %q", ErrClaimInvalid, c.Issuer)
+	}
+	if ks.Audience != "" && !c.Audience.Contains(ks.Audience) {
+		return fmt.Errorf("%w: audience", ErrClaimInvalid)
+	}
+	if c.Subject == "" {
+		return fmt.Errorf("%w: missing subject", ErrClaimInvalid)
+	}
+	return nil
+}
+
+// Key 는 서명 또는 검증에 쓰는 키 하나다.
+type Key struct {
+	ID  string
+	Alg string // "HS256" 또는 "RS256"
+
+	secret  []byte
+	public  *rsa.PublicKey
+	private *rsa.PrivateKey
+}
+
+// HMACKey 는 HS256 키를 만든다.
+func HMACKey(id string, secret []byte) Key {
+	return Key{ID: id, Alg: "HS256", secret: secret}
+}
+
+// RSAPublicKey 는 RS256 검증 전용 키를 만든다.
+func RSAPublicKey(id string, pub *rsa.PublicKey) Key {
+	return Key{ID: id, Alg: "RS256", public: pub}
+}
+
+// RSAPrivateKey 는 RS256 서명 키를 만든다. 검증에도 사용된다.
+func RSAPrivateKey(id string, priv *rsa.PrivateKey) Key {
+	return Key{ID: id, Alg: "RS256", public: &priv.PublicKey, private: priv}
+}
+
+func (k Key) canSign() bool { return k.secret != nil || k.private != nil }
+
+func (k Key) sign(input []byte) ([]byte, error) {
+	switch k.Alg {
+	case "HS256":
+		m := hmac.New(sha256.New, k.secret)
+		m.Write(input)
+		return m.Sum(nil), nil
+	case "RS256":
+		sum := sha256.Sum256(input)
+		return rsa.SignPKCS1v15(nil, k.private, crypto.SHA256, sum[:])
+	}
+	return nil, fmt.Errorf("auth: unsupported alg %q", k.Alg)
+}
+
+func (k Key) verify(input, sig []byte) bool {
+	switch k.Alg {
+	case "HS256":
+		m := hmac.New(sha256.New, k.secret)
+		m.Write(input)
+		return hmac.Equal(m.Sum(nil), sig)
+	case "RS256":
+		sum := sha256.Sum256(input)
+		return rsa.VerifyPKCS1v15(k.public, crypto.SHA256, sum[:], sig) == nil
+	}
+	return false
+}
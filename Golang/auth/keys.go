@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// KeySet 은 토큰 서명·검증 키 모음이다.
+// 첫 번째 서명 가능한 키로 새 토큰에 서명하고, 등록된 모든 키로 검증하므로
+// 새 키를 앞에 추가하고 예전 키를 잠시 남겨 두는 방식으로 키를 교체할 수 있다.
+type KeySet struct {
+	// Issuer, Audience 가 비어 있지 않으면 토큰의 iss, aud 와 일치해야 한다.
+	Issuer   string
+	Audience string
+	// Leeway 는 exp/nbf 검사 시 허용하는 시계 오차다.
+	Leeway time.Duration
+
+	mu   sync.RWMutex
+	keys []Key
+	now  func() time.Time
+}
+
+// NewKeySet 은 keys 로 KeySet 을 만든다.
+func NewKeySet(keys ...Key) *KeySet {
+	return &KeySet{keys: keys, Leeway: 30 * time.Second, now: time.Now}
+}
+
+// SetKeys 는 키 목록을 통째로 교체한다. (설정 재적용 시 사용)
+func (ks *KeySet) SetKeys(keys ...Key) {
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.mu.Unlock()
+}
+
+func (ks *KeySet) signer() *Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	for i := range ks.keys {
+		if ks.keys[i].canSign() {
+			k := ks.keys[i]
+			return &k
+		}
+	}
+	return nil
+}
+
+func (ks *KeySet) verifiers(kid, alg string) []Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	var out []Key
+	for _, k := range ks.keys {
+		// 헤더의 alg 와 키의 알고리즘이 다르면 사용하지 않는다. (알고리즘 혼동 공격 방지)
+		if k.Alg != alg {
+			continue
+		}
+		if kid != "" && k.ID != kid {
+			continue
+		}
+		out = append(out, k)
+	}
+	return out
+}
+
+// LoadRSAPrivateKey 는 PEM(PKCS#1 또는 PKCS#8) 파일에서 RSA 개인 키를 읽는다.
+func LoadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %s: %w", path, err)
+	}
+	rk, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("auth: %s: not an RSA key", path)
+	}
+	return rk, nil
+}
+
+// LoadRSAPublicKey 는 PEM(PKIX 또는 PKCS#1) 파일에서 RSA 공개 키를 읽는다.
+func LoadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if k, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	k, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %s: %w", path, err)
+	}
+	rk, ok := k.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("auth: %s: not an RSA key", path)
+	}
+	return rk, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("auth: " + path + ": no PEM data")
+	}
+	return block, nil
+}
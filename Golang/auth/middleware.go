@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/router"
+	"github.com/hgsong234/_stack/Golang/timing"
+)
+
+type claimsKey struct{}
+
+// ClaimsFrom 은 인증된 요청의 클레임을 꺼낸다. 인증되지 않았으면 nil 이다.
+func ClaimsFrom(ctx context.Context) *Claims {
+	c, _ := ctx.Value(claimsKey{}).(*Claims)
+	return c
+}
+
+// WithClaims 는 클레임을 담은 컨텍스트를 만든다.
+func WithClaims(ctx context.Context, c *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, c)
+}
+
+// bearer 는 Authorization 헤더에서 Bearer 토큰을 꺼낸다.
+func bearer(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
+
+// Authenticate 는 Bearer 토큰이 있으면 검증해 클레임을 컨텍스트에 넣는다.
+// 토큰이 없으면 그대로 통과시키고, 잘못된 토큰이면 401 을 돌려준다.
+func (ks *KeySet) Authenticate() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tok := bearer(r)
+			if tok == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			done := timing.Start(r.Context(), "auth")
+			c, err := ks.Verify(tok)
+			done()
+			if err != nil {
+				unauthorized(w, err.Error())
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), c)))
+		})
+	}
+}
+
+// Require 는 유효한 Bearer 토큰이 없는 요청을 401 로 거부한다. 보호할 라우트에 적용한다.
+func (ks *KeySet) Require() router.Middleware {
+	authn := ks.Authenticate()
+	return func(next http.Handler) http.Handler {
+		return authn(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ClaimsFrom(r.Context()) == nil {
+				unauthorized(w, "authentication required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	api.WriteError(w, api.NewError(http.StatusUnauthorized, "unauthorized", msg))
+}
+
+// TokenHandler 는 테스트용 토큰 발급 엔드포인트다.
+// {"subject": "alice", "roles": ["admin"]} 본문을 받아 ttl 동안 유효한 토큰을 돌려준다.
+// 누구나 토큰을 받을 수 있으므로 운영 환경에서는 등록하지 않는다.
+func (ks *KeySet) TokenHandler(ttl time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Subject string   `json:"subject"`
+			Roles   []string `json:"roles"`
+			AMR     []string `json:"amr"`
+		}
+		if err := api.ReadJSON(r, &req); err != nil {
+			api.WriteError(w, err)
+			return
+		}
+		if req.Subject == "" {
+			api.WriteError(w, api.BadRequest("subject is required"))
+			return
+		}
+		now := ks.now()
+		c := Claims{
+			Subject:   req.Subject,
+			Issuer:    ks.Issuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+			Roles:     req.Roles,
+			AMR:       req.AMR,
+		}
+		if ks.Audience != "" {
+			c.Audience = Audience{ks.Audience}
+		}
+		tok, err := ks.Sign(c)
+		if err != nil {
+			api.WriteError(w, err)
+			return
+		}
+		api.WriteJSON(w, http.StatusOK, map[string]any{
+			"access_token": tok,
+			"token_type":   "Bearer",
+			"expires_in":   int(ttl.Seconds()),
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Registry 는 이름으로 찾는 Set 목록이다.
+type Registry struct {
+	sets []Set
+}
+
+// Add 는 s 를 등록한다. 이름이 같은 Set 이 있으면 바꾼다.
+func (g *Registry) Add(s Set) {
+	for i, old := range g.sets {
+		if old.Name() == s.Name() {
+			g.sets[i] = s
+			return
+		}
+	}
+	g.sets = append(g.sets, s)
+}
+
+// Get 은 이름이 name 인 Set 이다. 없으면 nil 이다.
+func (g *Registry) Get(name string) Set {
+	for _, s := range g.sets {
+		if s.Name() == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Names 는 등록한 순서의 이름 목록이다.
+func (g *Registry) Names() []string {
+	out := make([]string, len(g.sets))
+	for i, s := range g.sets {
+		out[i] = s.Name()
+	}
+	return out
+}
+
+// Mount 는 r 의 prefix 아래에 내보내기·들여오기 라우트를 등록한다. mws 로 관리자 인증을 건다.
+//
+//	GET  prefix                                  등록한 데이터 묶음 이름
+//	GET  prefix/{name}?format=csv                내보내기 (첨부 파일로 스트리밍)
+//	POST prefix/{name}?dry_run=1&conflict=skip   들여오기. 본문이 text/csv 이면 CSV, 아니면 JSON
+func (g *Registry) Mount(r router.Routes, prefix string, mws ...router.Middleware) {
+	rg := r.Group(prefix, mws...)
+	rg.GET("", func(w http.ResponseWriter, r *http.Request) {
+		api.WriteJSON(w, http.StatusOK, map[string][]string{"datasets": g.Names()})
+	})
+	rg.GET("/{name}", g.export)
+	rg.POST("/{name}", g.importHandler)
+}
+
+func (g *Registry) export(w http.ResponseWriter, r *http.Request) {
+	s := g.Get(router.Param(r, "name"))
+	if s == nil {
+		api.WriteError(w, api.NotFound("dataset not found"))
+		return
+	}
+	f, err := ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		api.WriteError(w, api.BadRequest(err.Error()))
+		return
+	}
+	ctype := "application/json"
+	if f == CSV {
+		ctype = "text/csv; charset=utf-8"
+	}
+	name := fmt.Sprintf("%s-%s.%s", s.Name(), time.Now().UTC().Format("20060102-150405"), f)
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	w.Header().Set("Cache-Control", "no-store")
+	n, err := s.Export(r.Context(), w, f)
+	if err != nil {
+		// 이미 본문을 쓰기 시작했으므로 상태 코드는 바꿀 수 없다. 받은 쪽은 잘린 파일을 받는다.
+		logging.From(r.Context()).Error("backup: export", "dataset", s.Name(), "rows", n, "err", err)
+		return
+	}
+	logging.From(r.Context()).Info("backup: exported", "dataset", s.Name(), "format", string(f), "rows", n)
+}
+
+func (g *Registry) importHandler(w http.ResponseWriter, r *http.Request) {
+	s := g.Get(router.Param(r, "name"))
+	if s == nil {
+		api.WriteError(w, api.NotFound("dataset not found"))
+		return
+	}
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" && strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		format = string(CSV)
+	}
+	f, err := ParseFormat(format)
+	if err != nil {
+		api.WriteError(w, api.BadRequest(err.Error()))
+		return
+	}
+	var opts Options
+	if opts.Conflict, err = ParseConflict(q.Get("conflict")); err != nil {
+		api.WriteError(w, api.BadRequest(err.Error()))
+		return
+	}
+	if v := q.Get("dry_run"); v != "" {
+		if opts.DryRun, err = strconv.ParseBool(v); err != nil {
+			api.WriteError(w, api.BadRequest("dry_run must be a boolean"))
+			return
+		}
+	}
+	rep, err := s.Import(r.Context(), r.Body, f, opts)
+	var (
+		inputErr *InputError
+		maxErr   *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &maxErr):
+		api.WriteError(w, api.NewError(http.StatusRequestEntityTooLarge, "body_too_large",
+			fmt.Sprintf("request body must not exceed %d bytes; use the import command for large files", maxErr.Limit)))
+		return
+	case errors.Is(err, ErrRejected):
+		api.WriteError(w, api.NewError(http.StatusUnprocessableEntity, "import_rejected",
+			fmt.Sprintf("%d of %d rows are invalid; nothing was written", rep.Failed, rep.Total)).WithDetails(rep))
+		return
+	case errors.As(err, &inputErr):
+		api.WriteError(w, api.BadRequest(inputErr.Error()))
+		return
+	case err != nil:
+		logging.From(r.Context()).Error("backup: import", "dataset", s.Name(), "err", err)
+		api.WriteError(w, err)
+		return
+	}
+	logging.From(r.Context()).Warn("backup: imported", "dataset", s.Name(), "dry_run", rep.DryRun,
+		"created", rep.Created, "replaced", rep.Replaced, "skipped", rep.Skipped)
+	api.WriteJSON(w, http.StatusOK, rep)
+}
@@ -0,0 +1,341 @@
+// Package backup 은 저장소의 데이터를 JSON 또는 CSV 로 내보내고 다시 들여온다. 백업과 환경 복제에 쓴다.
+//
+// 내보내기는 행을 읽는 대로 쓰므로 데이터가 커도 메모리를 쓰지 않는다. JSON 은 배열 하나이고,
+// 들여올 때는 배열과 줄마다 객체 하나(NDJSON)를 모두 받는다.
+//
+// 들여오기는 먼저 모든 행을 검사하고(형식, 검증 규칙, 파일 안의 중복, 충돌) 하나라도 틀리면 아무것도 쓰지 않는다.
+// DryRun 이면 검사와 집계만 한다. 이미 있는 행은 Conflict 에 따라 건너뛰거나, 덮어쓰거나, 전체를 실패시킨다.
+package backup
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/hgsong234/_stack/Golang/validate"
+)
+
+// Format 은 파일 형식이다.
+type Format string
+
+const (
+	JSON Format = "json"
+	CSV  Format = "csv"
+)
+
+// ParseFormat 은 "json" 또는 "csv" 를 Format 으로 바꾼다. 비어 있으면 JSON 이다.
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", JSON:
+		return JSON, nil
+	case CSV:
+		return CSV, nil
+	}
+	return "", fmt.Errorf("backup: unknown format %q (json or csv)", s)
+}
+
+// Conflict 는 들여오는 행이 이미 있을 때의 처리다.
+type Conflict string
+
+const (
+	// Skip 은 있는 행을 그대로 둔다. (기본값)
+	Skip Conflict = "skip"
+	// Overwrite 는 있는 행을 들여오는 값으로 바꾼다.
+	Overwrite Conflict = "overwrite"
+	// Fail 은 있는 행이 하나라도 있으면 아무것도 쓰지 않는다.
+	Fail Conflict = "fail"
+)
+
+// ParseConflict 는 문자열을 Conflict 로 바꾼다. 비어 있으면 Skip 이다.
+func ParseConflict(s string) (Conflict, error) {
+	switch c := Conflict(s); c {
+	case "":
+		return Skip, nil
+	case Skip, Overwrite, Fail:
+		return c, nil
+	}
+	return "", fmt.Errorf("backup: unknown conflict strategy %q (skip, overwrite or fail)", s)
+}
+
+// ErrRejected 는 검사에서 틀린 행이 있어 아무것도 쓰지 않았다는 에러다. 자세한 내용은 Report.Errors 에 있다.
+var ErrRejected = errors.New("backup: import rejected")
+
+// InputError 는 파일 자체를 읽을 수 없을 때(JSON 문법, CSV 따옴표나 열 수, 빠진 열)의 에러다.
+type InputError struct{ Err error }
+
+func (e *InputError) Error() string { return "backup: " + e.Err.Error() }
+func (e *InputError) Unwrap() error { return e.Err }
+
+// maxErrors 는 Report 에 담을 행 에러의 최대 개수다. Failed 는 모두 센다.
+const maxErrors = 100
+
+// Options 는 들여오기 설정이다.
+type Options struct {
+	DryRun   bool
+	Conflict Conflict
+}
+
+// Report 는 들여오기 결과다. DryRun 이면 쓸 예정이었던 개수다.
+type Report struct {
+	Dataset  string     `json:"dataset"`
+	DryRun   bool       `json:"dry_run"`
+	Conflict Conflict   `json:"conflict"`
+	Total    int        `json:"total"`
+	Created  int        `json:"created"`
+	Replaced int        `json:"replaced"`
+	Skipped  int        `json:"skipped"`
+	Failed   int        `json:"failed"`
+	Errors   []RowError `json:"errors,omitempty"`
+}
+
+// RowError 는 틀린 행 하나다. Row 는 1부터 센 데이터 행 번호다. (CSV 머리글은 세지 않는다)
+type RowError struct {
+	Row   int    `json:"row"`
+	Key   string `json:"key,omitempty"`
+	Error string `json:"error"`
+}
+
+func (r *Report) fail(row int, key string, err error) {
+	r.Failed++
+	if len(r.Errors) < maxErrors {
+		r.Errors = append(r.Errors, RowError{Row: row, Key: key, Error: err.Error()})
+	}
+}
+
+// Set 은 내보내고 들여올 수 있는 데이터 묶음이다.
+type Set interface {
+	Name() string
+	// Export 는 모든 행을 f 형식으로 w 에 쓰고 쓴 행 수를 돌려준다.
+	Export(ctx context.Context, w io.Writer, f Format) (int, error)
+	// Import 는 r 의 행을 들여온다. 검사에서 틀린 행이 있으면 Report 와 ErrRejected 를 돌려준다.
+	Import(ctx context.Context, r io.Reader, f Format, opts Options) (*Report, error)
+}
+
+// Dataset 은 T 타입 행의 Set 이다. JSON 은 T 의 json 태그를 따르고, CSV 는 Columns 와 Row, Parse 를 쓴다.
+type Dataset[T any] struct {
+	Label   string
+	Columns []string
+	// Row 는 v 의 CSV 값이고(Columns 순서), Parse 는 CSV 한 줄(열 이름 → 값)을 되돌린다.
+	Row   func(v *T) []string
+	Parse func(row map[string]string) (*T, error)
+	// Key 는 파일 안의 중복을 찾고 에러를 보고할 때 쓰는 행 식별자다.
+	Key func(v *T) string
+	// Each 는 모든 행을 차례로 fn 에 넘긴다.
+	Each func(ctx context.Context, fn func(*T) error) error
+	// Validate 는 들여오는 행을 검사한다. nil 이면 validate 태그를 검사한다.
+	Validate func(v *T) error
+	// Exists 는 같은 행이 이미 있는지, Save 는 행을 넣거나 덮어쓴다.
+	Exists func(ctx context.Context, v *T) (bool, error)
+	Save   func(ctx context.Context, v *T) error
+	// Stale 이 true 인 행은 쓰지 않고 Skipped 로 센다. (예: 그사이 만료된 세션)
+	Stale func(v *T) bool
+	// Done 이 있으면 행을 하나 이상 쓴 뒤에 부른다. (예: id 시퀀스 맞추기)
+	Done func(ctx context.Context) error
+}
+
+// Name 은 Set 구현이다.
+func (d *Dataset[T]) Name() string { return d.Label }
+
+// Export 는 Set 구현이다.
+func (d *Dataset[T]) Export(ctx context.Context, w io.Writer, f Format) (int, error) {
+	n := 0
+	if f == CSV {
+		cw := csv.NewWriter(w)
+		if err := cw.Write(d.Columns); err != nil {
+			return 0, err
+		}
+		err := d.Each(ctx, func(v *T) error {
+			n++
+			return cw.Write(d.Row(v))
+		})
+		cw.Flush()
+		return n, errors.Join(err, cw.Error())
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString("[")
+	err := d.Each(ctx, func(v *T) error {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			bw.WriteString(",")
+		}
+		n++
+		bw.WriteString("\n")
+		_, err = bw.Write(b)
+		return err
+	})
+	if err != nil {
+		bw.Flush()
+		return n, err
+	}
+	bw.WriteString("\n]\n")
+	return n, bw.Flush()
+}
+
+// Import 는 Set 구현이다.
+func (d *Dataset[T]) Import(ctx context.Context, r io.Reader, f Format, opts Options) (*Report, error) {
+	if opts.Conflict == "" {
+		opts.Conflict = Skip
+	}
+	rep := &Report{Dataset: d.Label, DryRun: opts.DryRun, Conflict: opts.Conflict}
+	type planned struct {
+		v      *T
+		exists bool
+	}
+	var plan []planned
+	seen := map[string]int{}
+	err := d.decode(r, f, func(row int, v *T, err error) error {
+		rep.Total++
+		if err != nil {
+			rep.fail(row, "", err)
+			return nil
+		}
+		key := d.Key(v)
+		if err := d.validate(v); err != nil {
+			rep.fail(row, key, err)
+			return nil
+		}
+		if prev, dup := seen[key]; dup {
+			rep.fail(row, key, fmt.Errorf("duplicate of row %d", prev))
+			return nil
+		}
+		seen[key] = row
+		exists, err := d.Exists(ctx, v)
+		if err != nil {
+			return err
+		}
+		if exists && opts.Conflict == Fail {
+			rep.fail(row, key, errors.New("already exists"))
+			return nil
+		}
+		plan = append(plan, planned{v, exists})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rep.Failed > 0 {
+		return rep, ErrRejected
+	}
+	written := 0
+	for _, p := range plan {
+		switch {
+		case d.Stale != nil && d.Stale(p.v):
+			rep.Skipped++
+			continue
+		case !p.exists:
+			rep.Created++
+		case opts.Conflict == Skip:
+			rep.Skipped++
+			continue
+		default:
+			rep.Replaced++
+		}
+		if opts.DryRun {
+			continue
+		}
+		if err := d.Save(ctx, p.v); err != nil {
+			return rep, fmt.Errorf("backup: %s %s: %w", d.Label, d.Key(p.v), err)
+		}
+		written++
+	}
+	if written > 0 && d.Done != nil {
+		if err := d.Done(ctx); err != nil {
+			return rep, err
+		}
+	}
+	return rep, nil
+}
+
+func (d *Dataset[T]) validate(v *T) error {
+	if d.Validate != nil {
+		return d.Validate(v)
+	}
+	return validate.Struct(v)
+}
+
+// decode 는 r 의 행을 차례로 fn 에 넘긴다. 한 행의 값이 틀리면 그 에러와 함께 넘기고 계속하지만,
+// 파일 자체를 읽을 수 없으면 멈추고 InputError 를 돌려준다.
+func (d *Dataset[T]) decode(r io.Reader, f Format, fn func(row int, v *T, err error) error) error {
+	if f == CSV {
+		cr := csv.NewReader(r)
+		header, err := cr.Read()
+		if err != nil {
+			return &InputError{fmt.Errorf("read csv header: %w", err)}
+		}
+		for _, c := range d.Columns {
+			if !slices.Contains(header, c) {
+				return &InputError{fmt.Errorf("csv header has no %q column", c)}
+			}
+		}
+		for row := 1; ; row++ {
+			rec, err := cr.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return &InputError{fmt.Errorf("read csv: %w", err)}
+			}
+			fields := make(map[string]string, len(header))
+			for i, name := range header {
+				fields[name] = rec[i]
+			}
+			v, err := d.Parse(fields)
+			if err := fn(row, v, err); err != nil {
+				return err
+			}
+		}
+	}
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+	array := false
+	if b, err := peekNonSpace(br); err == nil && b == '[' {
+		if _, err := dec.Token(); err != nil {
+			return &InputError{fmt.Errorf("read json: %w", err)}
+		}
+		array = true
+	}
+	for row := 1; ; row++ {
+		if array && !dec.More() {
+			if _, err := dec.Token(); err != nil {
+				return &InputError{fmt.Errorf("read json: %w", err)}
+			}
+			return nil
+		}
+		v := new(T)
+		err := dec.Decode(v)
+		if err == io.EOF && !array {
+			return nil
+		}
+		var typeErr *json.UnmarshalTypeError
+		if err != nil && !errors.As(err, &typeErr) {
+			return &InputError{fmt.Errorf("read json: %w", err)}
+		}
+		if err := fn(row, v, err); err != nil {
+			return err
+		}
+	}
+}
+
+// peekNonSpace 는 앞쪽 공백을 건너뛰고 다음 바이트를 읽지 않은 채로 돌려준다.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			br.ReadByte()
+			continue
+		}
+		return b[0], nil
+	}
+}
@@ -0,0 +1,92 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Usage 는 export, import 하위 명령 사용법이다.
+const Usage = "usage: export NAME [FILE] | import NAME FILE [dry-run] [skip|overwrite|fail]"
+
+// Run 은 "export" 또는 "import" 하위 명령을 실행한다. args[0] 이 명령 이름이다.
+// 형식은 파일 확장자로 정하고(.csv 이면 CSV, 아니면 JSON) FILE 이 없거나 "-" 이면 표준 입출력을 쓴다.
+// 들여오기 결과는 out 에 JSON 으로 쓴다.
+func Run(ctx context.Context, reg *Registry, args []string, out io.Writer) error {
+	if len(args) < 2 {
+		return errors.New(Usage)
+	}
+	s := reg.Get(args[1])
+	if s == nil {
+		return fmt.Errorf("backup: unknown dataset %q (%s)", args[1], strings.Join(reg.Names(), ", "))
+	}
+	file := "-"
+	if len(args) > 2 {
+		file = args[2]
+	}
+	f := JSON
+	if strings.EqualFold(filepath.Ext(file), ".csv") {
+		f = CSV
+	}
+	switch args[0] {
+	case "export":
+		if len(args) > 3 {
+			return errors.New(Usage)
+		}
+		w := out
+		if file != "-" {
+			fw, err := os.Create(file)
+			if err != nil {
+				return err
+			}
+			defer fw.Close()
+			w = fw
+		}
+		n, err := s.Export(ctx, w, f)
+		if err != nil {
+			return err
+		}
+		if file != "-" {
+			fmt.Fprintf(out, "exported %d %s rows to %s\n", n, s.Name(), file)
+		}
+		return nil
+	case "import":
+		if len(args) < 3 {
+			return errors.New(Usage)
+		}
+		var opts Options
+		for _, a := range args[3:] {
+			if a == "dry-run" {
+				opts.DryRun = true
+				continue
+			}
+			c, err := ParseConflict(a)
+			if err != nil {
+				return err
+			}
+			opts.Conflict = c
+		}
+		r := io.Reader(os.Stdin)
+		if file != "-" {
+			fr, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer fr.Close()
+			r = fr
+		}
+		rep, err := s.Import(ctx, r, f, opts)
+		if rep != nil {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			enc.Encode(rep)
+		}
+		return err
+	}
+	return errors.New(Usage)
+}
@@ -0,0 +1,187 @@
+package backup
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/session"
+	"github.com/hgsong234/_stack/Golang/store"
+	"github.com/hgsong234/_stack/Golang/validate"
+)
+
+// Users 는 users 테이블이다. id 와 생성 시각을 그대로 옮기므로 다른 환경에서도 같은 id 를 쓴다.
+func Users(users *store.Users) *Dataset[store.User] {
+	return &Dataset[store.User]{
+		Label:   "users",
+		Columns: []string{"id", "name", "email", "created_at"},
+		Row: func(u *store.User) []string {
+			return []string{strconv.FormatInt(u.ID, 10), u.Name, u.Email, u.CreatedAt.UTC().Format(time.RFC3339Nano)}
+		},
+		Parse: func(row map[string]string) (*store.User, error) {
+			u := &store.User{Name: row["name"], Email: row["email"]}
+			var err error
+			if u.ID, err = strconv.ParseInt(row["id"], 10, 64); err != nil {
+				return nil, fmt.Errorf("id %q is not an integer", row["id"])
+			}
+			if u.CreatedAt, err = time.Parse(time.RFC3339Nano, row["created_at"]); err != nil {
+				return nil, fmt.Errorf("created_at %q is not an RFC 3339 time", row["created_at"])
+			}
+			return u, nil
+		},
+		Key: func(u *store.User) string { return strconv.FormatInt(u.ID, 10) },
+		Each: func(ctx context.Context, fn func(*store.User) error) error {
+			const batch = 500
+			for offset := 0; ; offset += batch {
+				list, err := users.List(ctx, batch, offset)
+				if err != nil {
+					return err
+				}
+				for i := range list {
+					if err := fn(&list[i]); err != nil {
+						return err
+					}
+				}
+				if len(list) < batch {
+					return nil
+				}
+			}
+		},
+		Validate: func(u *store.User) error {
+			if u.ID <= 0 {
+				return errors.New("id must be positive")
+			}
+			if u.CreatedAt.IsZero() {
+				return errors.New("created_at is required")
+			}
+			return validate.Struct(u)
+		},
+		Exists: func(ctx context.Context, u *store.User) (bool, error) {
+			_, err := users.Get(ctx, u.ID)
+			if errors.Is(err, store.ErrNotFound) {
+				return false, nil
+			}
+			return err == nil, err
+		},
+		Save: users.Restore,
+		Done: users.SyncIDs,
+	}
+}
+
+// Sessions 는 세션 저장소다. 세션 ID 가 그대로 들어 있으므로 내보낸 파일을 가진 사람은 그 세션으로 로그인할 수 있다.
+// 저장소가 나열을 지원해야 내보낼 수 있다. (memory, redis)
+func Sessions(m *session.Manager) *Dataset[session.Record] {
+	return &Dataset[session.Record]{
+		Label:   "sessions",
+		Columns: []string{"id", "expiry", "data"},
+		Row: func(s *session.Record) []string {
+			return []string{s.ID, s.Expiry.UTC().Format(time.RFC3339Nano), base64.StdEncoding.EncodeToString(s.Data)}
+		},
+		Parse: func(row map[string]string) (*session.Record, error) {
+			s := &session.Record{ID: row["id"]}
+			var err error
+			if s.Expiry, err = time.Parse(time.RFC3339Nano, row["expiry"]); err != nil {
+				return nil, fmt.Errorf("expiry %q is not an RFC 3339 time", row["expiry"])
+			}
+			if s.Data, err = base64.StdEncoding.DecodeString(row["data"]); err != nil {
+				return nil, errors.New("data is not base64")
+			}
+			return s, nil
+		},
+		Key:  func(s *session.Record) string { return s.ID },
+		Each: m.Export,
+		Validate: func(s *session.Record) error {
+			if s.ID == "" {
+				return errors.New("id is required")
+			}
+			return nil
+		},
+		Exists: func(ctx context.Context, s *session.Record) (bool, error) { return m.Exists(ctx, s.ID) },
+		Save:   m.Restore,
+		Stale:  func(s *session.Record) bool { return !s.Expiry.After(time.Now()) },
+	}
+}
+
+// PageFile 은 pages.dir 의 파일 하나다. Source 는 머리말을 포함한 원문이다.
+type PageFile struct {
+	File   string `json:"file"`
+	Source string `json:"source"`
+}
+
+// Pages 는 파일 기반 페이지 디렉터리(.md, .html)다. 들여온 페이지는 pages.live 가 아니면 재시작한 뒤에 보인다.
+func Pages(dir string) *Dataset[PageFile] {
+	return &Dataset[PageFile]{
+		Label:   "content",
+		Columns: []string{"file", "source"},
+		Row:     func(p *PageFile) []string { return []string{p.File, p.Source} },
+		Parse: func(row map[string]string) (*PageFile, error) {
+			return &PageFile{File: row["file"], Source: row["source"]}, nil
+		},
+		Key: func(p *PageFile) string { return p.File },
+		Each: func(ctx context.Context, fn func(*PageFile) error) error {
+			fsys := os.DirFS(dir)
+			return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if name != "." && strings.HasPrefix(d.Name(), ".") {
+					if d.IsDir() {
+						return fs.SkipDir
+					}
+					return nil
+				}
+				if d.IsDir() || !pageFile(name) {
+					return nil
+				}
+				src, err := fs.ReadFile(fsys, name)
+				if err != nil {
+					return err
+				}
+				return fn(&PageFile{File: name, Source: string(src)})
+			})
+		},
+		Validate: func(p *PageFile) error {
+			if !fs.ValidPath(p.File) || p.File == "." || !pageFile(p.File) {
+				return fmt.Errorf("file %q must be a relative .md or .html path", p.File)
+			}
+			for _, part := range strings.Split(p.File, "/") {
+				if strings.HasPrefix(part, ".") {
+					return fmt.Errorf("file %q must not contain hidden names", p.File)
+				}
+			}
+			return nil
+		},
+		Exists: func(_ context.Context, p *PageFile) (bool, error) {
+			_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(p.File)))
+			if errors.Is(err, fs.ErrNotExist) {
+				return false, nil
+			}
+			return err == nil, err
+		},
+		Save: func(_ context.Context, p *PageFile) error {
+			name := filepath.Join(dir, filepath.FromSlash(p.File))
+			if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+				return err
+			}
+			// 실행 중인 서버가 반쯤 쓴 파일을 읽지 않도록 옆에 쓰고 바꾼다.
+			tmp := name + ".import"
+			if err := os.WriteFile(tmp, []byte(p.Source), 0o644); err != nil {
+				return err
+			}
+			return os.Rename(tmp, name)
+		},
+	}
+}
+
+func pageFile(name string) bool {
+	ext := path.Ext(name)
+	return ext == ".md" || ext == ".html"
+}
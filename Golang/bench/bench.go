@@ -0,0 +1,291 @@
+// Package bench 는 서버 핸들러에 프로세스 안에서 부하를 주고 지연 백분위와 요청당 할당을 잰다.
+//
+// 요청은 소켓을 거치지 않고 http.Handler 에 직접 넣으므로 결과는 미들웨어와 라우팅, 핸들러의 비용이다.
+// 결과를 파일로 남겨 다음 실행(또는 다른 빌드)과 비교하면 배포 전에 성능 회귀를 찾을 수 있다.
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Route 는 부하에 섞을 요청 하나와 그 비중이다.
+type Route struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Weight int    `json:"weight"`
+}
+
+func (r Route) String() string { return r.Method + " " + r.Path }
+
+// ParseRoute 는 "[METHOD ]PATH[ *WEIGHT]" 를 읽는다. 메서드가 없으면 GET, 비중이 없으면 1 이다.
+// (예: "/healthz", "GET /api/v1/users *5")
+func ParseRoute(s string) (Route, error) {
+	f := strings.Fields(s)
+	r := Route{Method: http.MethodGet, Weight: 1}
+	if n := len(f); n > 0 && strings.HasPrefix(f[n-1], "*") {
+		w, err := strconv.Atoi(f[n-1][1:])
+		if err != nil || w < 1 {
+			return Route{}, fmt.Errorf("bench: route %q: weight must be a positive integer", s)
+		}
+		r.Weight, f = w, f[:n-1]
+	}
+	switch len(f) {
+	case 1:
+		r.Path = f[0]
+	case 2:
+		r.Method, r.Path = strings.ToUpper(f[0]), f[1]
+	default:
+		return Route{}, fmt.Errorf("bench: route %q must be [METHOD ]PATH[ *WEIGHT]", s)
+	}
+	if !strings.HasPrefix(r.Path, "/") {
+		return Route{}, fmt.Errorf("bench: route %q: path must start with /", s)
+	}
+	return r, nil
+}
+
+// Options 는 부하 설정이다.
+type Options struct {
+	// Concurrency 는 동시에 요청을 보내는 작업자 수다.
+	Concurrency int
+	// Duration 동안 잰다. 그 앞의 Warmup 동안 보낸 요청은 결과에 넣지 않는다.
+	Duration time.Duration
+	Warmup   time.Duration
+	// Routes 는 비중에 따라 무작위로 섞는다.
+	Routes []Route
+	// Header 는 모든 요청에 붙인다. (예: Authorization)
+	Header http.Header
+	// AllocRuns 는 경로마다 할당을 재는 순차 요청 수다. 0 이면 100 이다.
+	AllocRuns int
+}
+
+// Stats 는 경로 하나(또는 전체)의 결과다. Errors 는 5xx 응답 수다.
+type Stats struct {
+	Route    string      `json:"route"`
+	Requests int         `json:"requests"`
+	Errors   int         `json:"errors"`
+	Status   map[int]int `json:"status"`
+	RPS      float64     `json:"rps"`
+	MeanMS   float64     `json:"mean_ms"`
+	P50MS    float64     `json:"p50_ms"`
+	P90MS    float64     `json:"p90_ms"`
+	P99MS    float64     `json:"p99_ms"`
+	P999MS   float64     `json:"p999_ms"`
+	MaxMS    float64     `json:"max_ms"`
+	// AllocsPerOp, BytesPerOp 는 부하가 끝난 뒤 이 경로만 순차로 보내 잰 값이다. 전체에서는 부하 중의 평균이다.
+	AllocsPerOp float64 `json:"allocs_per_op"`
+	BytesPerOp  float64 `json:"bytes_per_op"`
+}
+
+// Result 는 한 번의 실행 결과다. Save 로 저장하고 Load 로 읽어 Compare 에 넘긴다.
+type Result struct {
+	Time        time.Time `json:"time"`
+	GoVersion   string    `json:"go_version"`
+	GOMAXPROCS  int       `json:"gomaxprocs"`
+	Concurrency int       `json:"concurrency"`
+	Seconds     float64   `json:"seconds"`
+	GCCycles    uint32    `json:"gc_cycles"`
+	Total       Stats     `json:"total"`
+	Routes      []Stats   `json:"routes"`
+}
+
+// Route 는 이름이 name 인 경로의 결과다. 없으면 nil 이다.
+func (r *Result) Route(name string) *Stats {
+	for i := range r.Routes {
+		if r.Routes[i].Route == name {
+			return &r.Routes[i]
+		}
+	}
+	return nil
+}
+
+// samples 는 작업자 하나가 경로마다 모은 지연과 상태 코드다.
+type samples struct {
+	latency [][]time.Duration
+	status  []map[int]int
+}
+
+// Run 은 h 에 부하를 주고 결과를 돌려준다. ctx 가 취소되면 그때까지 모은 결과를 돌려준다.
+func Run(ctx context.Context, h http.Handler, opts Options) (*Result, error) {
+	if len(opts.Routes) == 0 {
+		return nil, errors.New("bench: no routes")
+	}
+	if opts.Concurrency < 1 || opts.Duration <= 0 {
+		return nil, errors.New("bench: concurrency and duration must be positive")
+	}
+	if opts.AllocRuns <= 0 {
+		opts.AllocRuns = 100
+	}
+	// 비중만큼 경로 번호를 되풀이한 표에서 고르면 고르는 비용이 경로 수와 상관없다.
+	var table []int
+	for i, r := range opts.Routes {
+		for range r.Weight {
+			table = append(table, i)
+		}
+	}
+	if opts.Warmup > 0 {
+		drive(ctx, h, opts, table, opts.Warmup)
+	}
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	workers := drive(ctx, h, opts, table, opts.Duration)
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	res := &Result{
+		Time:        start.UTC(),
+		GoVersion:   runtime.Version(),
+		GOMAXPROCS:  runtime.GOMAXPROCS(0),
+		Concurrency: opts.Concurrency,
+		Seconds:     elapsed.Seconds(),
+		GCCycles:    after.NumGC - before.NumGC,
+	}
+	var all []time.Duration
+	allStatus := map[int]int{}
+	for i, rt := range opts.Routes {
+		var lat []time.Duration
+		status := map[int]int{}
+		for _, w := range workers {
+			lat = append(lat, w.latency[i]...)
+			for code, n := range w.status[i] {
+				status[code] += n
+				allStatus[code] += n
+			}
+		}
+		all = append(all, lat...)
+		st := summarize(rt.String(), lat, status, elapsed)
+		if ctx.Err() == nil {
+			st.AllocsPerOp, st.BytesPerOp = allocs(ctx, h, rt, opts.Header, opts.AllocRuns)
+		}
+		res.Routes = append(res.Routes, st)
+	}
+	res.Total = summarize("total", all, allStatus, elapsed)
+	if n := float64(res.Total.Requests); n > 0 {
+		res.Total.AllocsPerOp = float64(after.Mallocs-before.Mallocs) / n
+		res.Total.BytesPerOp = float64(after.TotalAlloc-before.TotalAlloc) / n
+	}
+	return res, nil
+}
+
+// drive 는 작업자들이 d 동안 요청을 보내게 하고 모은 결과를 돌려준다.
+func drive(ctx context.Context, h http.Handler, opts Options, table []int, d time.Duration) []*samples {
+	// 요청의 context 를 끊으면 진행 중이던 요청이 에러로 끝나므로 시간은 작업자가 직접 본다.
+	deadline := time.Now().Add(d)
+	workers := make([]*samples, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i := range workers {
+		s := &samples{latency: make([][]time.Duration, len(opts.Routes)), status: make([]map[int]int, len(opts.Routes))}
+		for j := range s.status {
+			s.status[j] = map[int]int{}
+		}
+		workers[i] = s
+		wg.Go(func() {
+			rng := rand.New(rand.NewPCG(uint64(i), uint64(time.Now().UnixNano())))
+			for t := time.Now(); t.Before(deadline) && ctx.Err() == nil; t = time.Now() {
+				ri := table[rng.IntN(len(table))]
+				code := serve(ctx, h, opts.Routes[ri], opts.Header)
+				s.latency[ri] = append(s.latency[ri], time.Since(t))
+				s.status[ri][code]++
+			}
+		})
+	}
+	wg.Wait()
+	return workers
+}
+
+// allocs 는 rt 를 n 번 순차로 보내며 요청당 할당 횟수와 바이트를 잰다. 다른 고루틴의 할당도 조금 섞인다.
+func allocs(ctx context.Context, h http.Handler, rt Route, header http.Header, n int) (float64, float64) {
+	// 첫 요청은 캐시나 지연 초기화를 채울 수 있으므로 빼고 잰다.
+	serve(ctx, h, rt, header)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for range n {
+		serve(ctx, h, rt, header)
+	}
+	runtime.ReadMemStats(&after)
+	return float64(after.Mallocs-before.Mallocs) / float64(n), float64(after.TotalAlloc-before.TotalAlloc) / float64(n)
+}
+
+// serve 는 rt 요청 하나를 h 에 넣고 상태 코드를 돌려준다. 본문은 버린다.
+func serve(ctx context.Context, h http.Handler, rt Route, header http.Header) int {
+	req, err := http.NewRequestWithContext(ctx, rt.Method, rt.Path, nil)
+	if err != nil {
+		return http.StatusBadRequest
+	}
+	req.Host = "localhost"
+	req.RequestURI = rt.Path
+	req.RemoteAddr = "127.0.0.1:0"
+	if header != nil {
+		req.Header = header.Clone()
+	}
+	w := &discardWriter{header: http.Header{}, status: http.StatusOK}
+	h.ServeHTTP(w, req)
+	return w.status
+}
+
+// discardWriter 는 상태 코드만 기억하고 본문은 버리는 ResponseWriter 다.
+type discardWriter struct {
+	header http.Header
+	status int
+	wrote  bool
+}
+
+func (w *discardWriter) Header() http.Header { return w.header }
+
+func (w *discardWriter) WriteHeader(code int) {
+	if !w.wrote {
+		w.status, w.wrote = code, true
+	}
+}
+
+func (w *discardWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return len(b), nil
+}
+
+func (w *discardWriter) Flush() {}
+
+func summarize(name string, lat []time.Duration, status map[int]int, elapsed time.Duration) Stats {
+	st := Stats{Route: name, Requests: len(lat), Status: status}
+	for code, n := range status {
+		if code >= 500 {
+			st.Errors += n
+		}
+	}
+	if len(lat) == 0 {
+		return st
+	}
+	slices.Sort(lat)
+	var sum time.Duration
+	for _, d := range lat {
+		sum += d
+	}
+	st.RPS = float64(len(lat)) / elapsed.Seconds()
+	st.MeanMS = ms(sum / time.Duration(len(lat)))
+	st.P50MS = ms(percentile(lat, 0.50))
+	st.P90MS = ms(percentile(lat, 0.90))
+	st.P99MS = ms(percentile(lat, 0.99))
+	st.P999MS = ms(percentile(lat, 0.999))
+	st.MaxMS = ms(lat[len(lat)-1])
+	return st
+}
+
+// percentile 은 정렬한 sorted 의 q 백분위(가장 가까운 순위)다.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	i := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(0, min(i, len(sorted)-1))]
+}
+
+func ms(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
@@ -0,0 +1,126 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"text/tabwriter"
+)
+
+// Save 는 res 를 path 에 JSON 으로 쓴다.
+func Save(path string, res *Result) error {
+	b, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// Load 는 Save 로 쓴 결과를 읽는다.
+func Load(path string) (*Result, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var res Result
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, fmt.Errorf("bench: %s: %w", path, err)
+	}
+	return &res, nil
+}
+
+// Write 는 res 를 경로별 표로 쓴다. base 가 있으면 p50, p99, allocs/op 옆에 base 대비 변화율을 붙인다.
+func Write(w io.Writer, res, base *Result) {
+	fmt.Fprintf(w, "%s, GOMAXPROCS=%d, concurrency=%d, %.1fs, %d GC cycles\n",
+		res.GoVersion, res.GOMAXPROCS, res.Concurrency, res.Seconds, res.GCCycles)
+	if base != nil {
+		fmt.Fprintf(w, "compared with %s (%s)\n", base.Time.Format("2006-01-02 15:04:05"), base.GoVersion)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "ROUTE\tREQUESTS\t5XX\tRPS\tMEAN\tP50\tP90\tP99\tP99.9\tMAX\tALLOCS/OP\tB/OP\t")
+	for _, st := range append(slices.Clip(res.Routes), res.Total) {
+		var old *Stats
+		if base != nil {
+			if st.Route == "total" {
+				old = &base.Total
+			} else {
+				old = base.Route(st.Route)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.0f\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%.0f\t\n",
+			st.Route, st.Requests, st.Errors, st.RPS,
+			duration(st.MeanMS), withDelta(duration(st.P50MS), st.P50MS, old, func(s *Stats) float64 { return s.P50MS }),
+			duration(st.P90MS), withDelta(duration(st.P99MS), st.P99MS, old, func(s *Stats) float64 { return s.P99MS }),
+			duration(st.P999MS), duration(st.MaxMS),
+			withDelta(fmt.Sprintf("%.1f", st.AllocsPerOp), st.AllocsPerOp, old, func(s *Stats) float64 { return s.AllocsPerOp }),
+			st.BytesPerOp)
+	}
+	tw.Flush()
+}
+
+// Regression 은 base 보다 나빠진 값 하나다.
+type Regression struct {
+	Route  string
+	Metric string
+	Base   float64
+	Now    float64
+}
+
+// Percent 는 base 대비 증가율(%)이다.
+func (r Regression) Percent() float64 { return change(r.Base, r.Now) }
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s %s %.4g -> %.4g (%+.1f%%)", r.Route, r.Metric, r.Base, r.Now, r.Percent())
+}
+
+// Compare 는 cur 가 base 보다 maxPercent 넘게 나빠진 값이다. 경로마다 p50 지연과 요청당 할당을 본다.
+// p99 는 실행마다 흔들림이 커서 표에만 보여 준다. 한쪽에만 있는 경로는 건너뛴다.
+func Compare(base, cur *Result, maxPercent float64) []Regression {
+	var out []Regression
+	for _, st := range cur.Routes {
+		old := base.Route(st.Route)
+		if old == nil || old.Requests == 0 || st.Requests == 0 {
+			continue
+		}
+		for _, m := range []struct {
+			name     string
+			old, now float64
+		}{
+			{"p50_ms", old.P50MS, st.P50MS},
+			{"allocs/op", old.AllocsPerOp, st.AllocsPerOp},
+		} {
+			// 할당이 0 에서 1 로 늘어난 것도 회귀다.
+			if change(m.old, m.now) > maxPercent || (m.old == 0 && m.now >= 1) {
+				out = append(out, Regression{Route: st.Route, Metric: m.name, Base: m.old, Now: m.now})
+			}
+		}
+	}
+	return out
+}
+
+func change(old, now float64) float64 {
+	if old == 0 {
+		return 0
+	}
+	return (now - old) / old * 100
+}
+
+func withDelta(s string, now float64, old *Stats, get func(*Stats) float64) string {
+	if old == nil || old.Requests == 0 {
+		return s
+	}
+	return fmt.Sprintf("%s (%+.0f%%)", s, change(get(old), now))
+}
+
+// duration 은 밀리초 값을 읽기 쉬운 단위로 쓴다.
+func duration(ms float64) string {
+	switch {
+	case ms < 1:
+		return fmt.Sprintf("%.0fµs", ms*1000)
+	case ms < 1000:
+		return fmt.Sprintf("%.2fms", ms)
+	}
+	return fmt.Sprintf("%.2fs", ms/1000)
+}
@@ -0,0 +1,420 @@
+// Package botguard 는 요청이 봇처럼 보이는 정도를 점수로 매기고, 의심스러운 클라이언트가 비싼 경로에
+// 들어오기 전에 작업 증명(또는 hCaptcha) 확인을 거치게 한다.
+//
+// 점수는 User-Agent, 빠진 브라우저 헤더, IP 별 요청 빈도로 매긴다. Threshold 이상인 요청이 Paths 아래로
+// 들어오면 브라우저에는 challenge.html 을, API 클라이언트에는 challenge_required 에러를 보낸다.
+// 확인을 통과하면 클라이언트 IP 에 묶인 서명된 통행증을 쿠키(API 는 X-Bot-Pass 헤더)로 쓴다.
+//
+// 작업 증명은 sha256(challenge + nonce) 의 앞 Difficulty 비트가 0 이 되는 10진수 nonce 를 찾는 것이다.
+package botguard
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/apperror"
+	"github.com/hgsong234/_stack/Golang/cookies"
+	"github.com/hgsong234/_stack/Golang/i18n"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/metrics"
+	"github.com/hgsong234/_stack/Golang/realip"
+	"github.com/hgsong234/_stack/Golang/render"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+const (
+	// PassCookie 는 통행증 쿠키 이름이고, PassHeader 는 쿠키를 쓰지 않는 클라이언트가 통행증을 보내는 헤더다.
+	PassCookie = "bot_pass"
+	PassHeader = "X-Bot-Pass"
+
+	challengeName = "bot_challenge"
+	hcaptchaURL   = "https://api.hcaptcha.com/siteverify"
+	hcaptchaCSP   = "default-src 'self'; script-src 'self' https://hcaptcha.com https://*.hcaptcha.com; " +
+		"frame-src https://hcaptcha.com https://*.hcaptcha.com; style-src 'self' https://hcaptcha.com https://*.hcaptcha.com; " +
+		"connect-src 'self' https://hcaptcha.com https://*.hcaptcha.com; img-src 'self' data:; object-src 'none'; " +
+		"frame-ancestors 'none'; base-uri 'self'"
+)
+
+var (
+	decisions = metrics.NewCounterVec("botguard_requests_total",
+		"Requests to protected paths by decision (allowed, passed, challenged).", "decision")
+	verifications = metrics.NewCounterVec("botguard_verifications_total",
+		"Challenge answers by method (pow, hcaptcha) and result (ok, failed).", "method", "result")
+)
+
+// toolAgents 는 브라우저가 아닌 HTTP 클라이언트와 자동화 도구의 User-Agent 조각이다. 소문자로 비교한다.
+var toolAgents = []string{
+	"curl", "wget", "httpie", "python-requests", "python-urllib", "aiohttp", "go-http-client", "java/",
+	"okhttp", "libwww", "scrapy", "bot", "crawler", "spider", "headless", "phantomjs", "selenium",
+	"puppeteer", "playwright",
+}
+
+// Options 는 Guard 설정이다.
+type Options struct {
+	// Secrets 는 문제와 통행증을 서명할 키다. 첫 번째 값으로 서명하고 나머지는 읽을 때만 쓴다.
+	Secrets [][]byte
+	// Insecure 이면 통행증 쿠키를 HTTP 로도 보낸다. (개발용)
+	Insecure bool
+	// Paths 는 확인을 거쳐야 하는 경로다. 하위 경로를 포함한다.
+	Paths []string
+	// Threshold 는 확인을 요구할 점수(0~100)다. 0 이면 50.
+	Threshold int
+	// Difficulty 는 작업 증명에서 0 이어야 하는 앞 비트 수다. 0 이면 16.
+	Difficulty int
+	// ChallengeTTL 은 문제의 유효 시간이고 PassTTL 은 통행증의 유효 시간이다. 0 이면 5분, 1시간.
+	ChallengeTTL time.Duration
+	PassTTL      time.Duration
+	// RateWindow 동안 한 IP 에서 RateLimit 번보다 많이 들어오면 점수를 더한다. RateLimit 이 0 이면 세지 않는다.
+	RateWindow time.Duration
+	RateLimit  int
+	// VerifyPath 는 답을 받는 경로다. 비어 있으면 "/_bot/verify".
+	VerifyPath string
+	// HCaptchaSiteKey 와 HCaptchaSecret 이 있으면 작업 증명 대신 hCaptcha 로 확인한다.
+	HCaptchaSiteKey string
+	HCaptchaSecret  string
+	// Client 는 hCaptcha 확인 요청에 쓴다. nil 이면 http.DefaultClient.
+	Client *http.Client
+}
+
+// Guard 는 봇 점수 매기기와 확인 흐름이다. 여러 요청에서 동시에 써도 안전하다.
+type Guard struct {
+	opts       Options
+	challenges *cookies.Jar
+	passes     *cookies.Jar
+	rates      rates
+}
+
+// New 는 opts 로 Guard 를 만든다.
+func New(opts Options) (*Guard, error) {
+	if opts.Threshold <= 0 {
+		opts.Threshold = 50
+	}
+	if opts.Difficulty <= 0 {
+		opts.Difficulty = 16
+	}
+	if opts.Difficulty > 32 {
+		return nil, errors.New("botguard: difficulty must be at most 32 bits")
+	}
+	if opts.ChallengeTTL <= 0 {
+		opts.ChallengeTTL = 5 * time.Minute
+	}
+	if opts.PassTTL <= 0 {
+		opts.PassTTL = time.Hour
+	}
+	if opts.RateWindow <= 0 {
+		opts.RateWindow = 10 * time.Second
+	}
+	if opts.VerifyPath == "" {
+		opts.VerifyPath = "/_bot/verify"
+	}
+	if (opts.HCaptchaSiteKey == "") != (opts.HCaptchaSecret == "") {
+		return nil, errors.New("botguard: hcaptcha site key and secret must be set together")
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	challenges, err := cookies.New(cookies.Options{Secrets: opts.Secrets, MaxAge: opts.ChallengeTTL})
+	if err != nil {
+		return nil, err
+	}
+	passes, err := cookies.New(cookies.Options{Secrets: opts.Secrets, MaxAge: opts.PassTTL, Insecure: opts.Insecure})
+	if err != nil {
+		return nil, err
+	}
+	return &Guard{
+		opts:       opts,
+		challenges: challenges,
+		passes:     passes,
+		rates:      rates{window: opts.RateWindow, seen: map[string]*window{}},
+	}, nil
+}
+
+// Score 는 r 이 봇처럼 보이는 정도(0~100)와 그 이유다. 요청 빈도는 Middleware 가 센 값을 쓰므로 여기서는 보지 않는다.
+func (g *Guard) Score(r *http.Request) (int, []string) {
+	score, reasons := 0, []string{}
+	add := func(n int, reason string) {
+		score += n
+		reasons = append(reasons, reason)
+	}
+	ua := strings.ToLower(r.UserAgent())
+	switch {
+	case ua == "":
+		add(40, "no_user_agent")
+	case containsAny(ua, toolAgents):
+		add(40, "tool_user_agent")
+	}
+	if r.Header.Get("Accept") == "" {
+		add(20, "no_accept")
+	}
+	if r.Header.Get("Accept-Language") == "" {
+		add(15, "no_accept_language")
+	}
+	if r.Header.Get("Accept-Encoding") == "" {
+		add(10, "no_accept_encoding")
+	}
+	return min(score, 100), reasons
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// protected 는 path 가 확인을 거쳐야 하는 경로인지 확인한다.
+func (g *Guard) protected(path string) bool {
+	for _, p := range g.opts.Paths {
+		p = strings.TrimSuffix(p, "/")
+		if p == "" || path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// passed 는 r 에 이 클라이언트 IP 로 발급한 유효한 통행증이 있는지 확인한다.
+func (g *Guard) passed(r *http.Request, ip string) bool {
+	token := r.Header.Get(PassHeader)
+	if token == "" {
+		c, err := r.Cookie(PassCookie)
+		if err != nil {
+			return false
+		}
+		token = c.Value
+	}
+	v, err := g.passes.Decode(PassCookie, token)
+	return err == nil && v == ip
+}
+
+// Middleware 는 보호 경로로 들어오는 의심스러운 요청에 확인을 요구하고, VerifyPath 로 오는 답을 처리한다.
+// 세션과 CSRF 미들웨어보다 앞에(i18n 안쪽에) 등록한다.
+func (g *Guard) Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == g.opts.VerifyPath {
+				g.verify(w, r)
+				return
+			}
+			if !g.protected(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ip := realip.ClientIP(r).String()
+			if g.passed(r, ip) {
+				decisions.Inc("passed")
+				next.ServeHTTP(w, r)
+				return
+			}
+			score, reasons := g.Score(r)
+			if g.opts.RateLimit > 0 && g.rates.hit(ip, time.Now()) > g.opts.RateLimit {
+				score, reasons = min(score+50, 100), append(reasons, "request_rate")
+			}
+			if score < g.opts.Threshold {
+				decisions.Inc("allowed")
+				next.ServeHTTP(w, r)
+				return
+			}
+			decisions.Inc("challenged")
+			logging.From(r.Context()).Debug("bot challenge", "ip", ip, "score", score, "reasons", reasons)
+			g.challenge(w, r, ip, http.StatusForbidden, false)
+		})
+	}
+}
+
+// challenge 는 새 문제를 담은 확인 페이지나 에러 응답을 쓴다. failed 이면 앞선 답이 틀렸다는 안내를 붙인다.
+func (g *Guard) challenge(w http.ResponseWriter, r *http.Request, ip string, status int, failed bool) {
+	ctx := r.Context()
+	w.Header().Set("Cache-Control", "no-store")
+	token := g.newChallenge(ip)
+	if wantsJSON(r) {
+		code, msg := "challenge_required", i18n.T(ctx, "challenge.message")
+		if failed {
+			code, msg = "challenge_failed", i18n.T(ctx, "challenge.failed")
+		}
+		details := map[string]any{"verify_url": g.opts.VerifyPath}
+		if g.opts.HCaptchaSiteKey != "" {
+			details["method"], details["site_key"] = "hcaptcha", g.opts.HCaptchaSiteKey
+		} else {
+			details["method"], details["challenge"], details["difficulty"] = "pow", token, g.opts.Difficulty
+		}
+		apperror.Write(w, r, apperror.New(status, code, msg).WithDetails(details))
+		return
+	}
+	back := r.URL.RequestURI()
+	if r.URL.Path == g.opts.VerifyPath {
+		back = safeReturn(r.PostFormValue("return"))
+	}
+	if g.opts.HCaptchaSiteKey != "" {
+		w.Header().Set("Content-Security-Policy", hcaptchaCSP)
+	}
+	render.Default().RenderStatus(w, status, "challenge.html", map[string]any{
+		"VerifyURL": g.opts.VerifyPath, "Return": back, "Challenge": token, "Difficulty": g.opts.Difficulty,
+		"SiteKey": g.opts.HCaptchaSiteKey, "Failed": failed, "Lang": i18n.Lang(ctx),
+	})
+}
+
+// newChallenge 는 ip 에 묶인 새 문제다. 같은 IP 에서 ChallengeTTL 안에만 풀 수 있다.
+func (g *Guard) newChallenge(ip string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return g.challenges.Encode(challengeName, ip+" "+hex.EncodeToString(b))
+}
+
+// answer 는 확인 요청의 본문이다. 폼은 challenge, nonce, h-captcha-response, return 필드로 보낸다.
+type answer struct {
+	Challenge string `json:"challenge"`
+	Nonce     string `json:"nonce"`
+	Response  string `json:"response"`
+}
+
+// verify 는 답을 확인하고 통행증을 준다. 폼으로 보낸 브라우저는 원래 경로로 돌려보내고,
+// JSON 으로 보낸 API 클라이언트에는 {"pass": "...", "expires_in": 초} 를 돌려준다.
+func (g *Guard) verify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		api.WriteError(w, api.NewError(http.StatusMethodNotAllowed, "method_not_allowed", "use POST"))
+		return
+	}
+	ip := realip.ClientIP(r).String()
+	var a answer
+	form := !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+	if form {
+		a = answer{Challenge: r.PostFormValue("challenge"), Nonce: r.PostFormValue("nonce"), Response: r.PostFormValue("h-captcha-response")}
+	} else if err := api.ReadJSON(r, &a); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+	method, ok := "pow", false
+	if g.opts.HCaptchaSiteKey != "" {
+		method = "hcaptcha"
+		var err error
+		if ok, err = g.hcaptcha(r.Context(), a.Response, ip); err != nil {
+			logging.From(r.Context()).Warn("hcaptcha verification failed", "err", err)
+		}
+	} else {
+		ok = g.solved(a.Challenge, a.Nonce, ip)
+	}
+	if !ok {
+		verifications.Inc(method, "failed")
+		g.challenge(w, r, ip, http.StatusForbidden, true)
+		return
+	}
+	verifications.Inc(method, "ok")
+	cookie := g.passes.Cookie(PassCookie, ip)
+	http.SetCookie(w, cookie)
+	w.Header().Set("Cache-Control", "no-store")
+	if form {
+		http.Redirect(w, r, safeReturn(r.PostFormValue("return")), http.StatusSeeOther)
+		return
+	}
+	api.WriteJSON(w, http.StatusOK, map[string]any{"pass": cookie.Value, "expires_in": int(g.opts.PassTTL.Seconds())})
+}
+
+// solved 는 challenge 가 ip 에 발급한 유효한 문제이고 nonce 가 그 답인지 확인한다.
+func (g *Guard) solved(challenge, nonce, ip string) bool {
+	v, err := g.challenges.Decode(challengeName, challenge)
+	if err != nil || !strings.HasPrefix(v, ip+" ") {
+		return false
+	}
+	if _, err := strconv.ParseUint(nonce, 10, 64); err != nil {
+		return false
+	}
+	sum := sha256.Sum256([]byte(challenge + nonce))
+	for i := range g.opts.Difficulty {
+		if sum[i/8]&(0x80>>(i%8)) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hcaptcha 는 위젯이 준 응답 토큰을 hCaptcha 에 확인한다.
+func (g *Guard) hcaptcha(ctx context.Context, response, ip string) (bool, error) {
+	if response == "" {
+		return false, nil
+	}
+	form := url.Values{"secret": {g.opts.HCaptchaSecret}, "response": {response}, "remoteip": {ip}, "sitekey": {g.opts.HCaptchaSiteKey}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hcaptchaURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := g.opts.Client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Success bool     `json:"success"`
+		Errors  []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return false, err
+	}
+	if !out.Success && len(out.Errors) > 0 {
+		return false, errors.New("hcaptcha: " + strings.Join(out.Errors, ", "))
+	}
+	return out.Success, nil
+}
+
+// safeReturn 은 확인 뒤 돌아갈 경로다. 다른 사이트로 보내지 않도록 이 서버의 경로만 받는다.
+func safeReturn(s string) string {
+	if !strings.HasPrefix(s, "/") || strings.HasPrefix(s, "//") || strings.HasPrefix(s, "/\\") {
+		return "/"
+	}
+	return s
+}
+
+func wantsJSON(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/") ||
+		strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") ||
+		render.PreferredType(r.Header.Get("Accept"), "text/html", "application/json") == "application/json"
+}
+
+// rates 는 IP 별 고정 창 요청 수다. 오래된 창은 창 길이마다 한 번씩 지운다.
+type rates struct {
+	mu     sync.Mutex
+	window time.Duration
+	seen   map[string]*window
+	swept  time.Time
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+// hit 은 key 의 요청을 하나 세고 현재 창의 요청 수를 돌려준다.
+func (rt *rates) hit(key string, now time.Time) int {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if now.Sub(rt.swept) > rt.window {
+		for k, w := range rt.seen {
+			if now.Sub(w.start) > rt.window {
+				delete(rt.seen, k)
+			}
+		}
+		rt.swept = now
+	}
+	w := rt.seen[key]
+	if w == nil || now.Sub(w.start) > rt.window {
+		w = &window{start: now}
+		rt.seen[key] = w
+	}
+	w.count++
+	return w.count
+}
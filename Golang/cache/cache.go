@@ -0,0 +1,172 @@
+// Package cache 는 세션, 요청 제한, 응답 캐시, Idempotency-Key 의 redis 백엔드가 함께 쓰는
+// Redis 클라이언트를 만든다. 단일 노드, sentinel, 클러스터를 같은 redis.UniversalClient 로 다룬다.
+package cache
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Config 는 Redis 연결 설정이다.
+type Config struct {
+	// Addrs 는 "host:port" 목록이다. MasterName 이 있으면 sentinel 주소, Cluster 이면 클러스터 노드 주소다.
+	Addrs []string
+	// MasterName 이 있으면 sentinel 에 물어 마스터에 연결한다.
+	MasterName       string
+	SentinelPassword string
+	// Cluster 이면 Redis Cluster 로 연결한다. 주소가 하나뿐인 설정 엔드포인트에도 쓴다.
+	Cluster  bool
+	Username string
+	Password string
+	// DB 는 단일 노드와 sentinel 에서만 쓴다.
+	DB int
+
+	// PoolSize 는 노드마다의 최대 연결 수다. 0 이면 go-redis 기본값(CPU 당 10개)이다.
+	PoolSize        int
+	MinIdleConns    int
+	ConnMaxIdleTime time.Duration
+	DialTimeout     time.Duration
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+
+	// TLS 가 nil 이 아니면 TLS 로 연결한다. (TLSConfig 참고)
+	TLS *tls.Config
+}
+
+// New 는 cfg 로 클라이언트를 만든다. 연결은 첫 명령에서 맺으므로 주소를 확인하려면 Ping 을 부른다.
+func New(cfg Config) (redis.UniversalClient, error) {
+	switch {
+	case len(cfg.Addrs) == 0:
+		return nil, errors.New("cache: no redis address")
+	case cfg.Cluster && cfg.MasterName != "":
+		return nil, errors.New("cache: cluster and sentinel master name are mutually exclusive")
+	case len(cfg.Addrs) > 1 && !cfg.Cluster && cfg.MasterName == "":
+		// go-redis 는 주소가 여럿이면 클러스터로 본다. 설정 실수를 조용히 클러스터로 만들지 않게 막는다.
+		return nil, errors.New("cache: multiple redis addresses need cluster or a sentinel master name")
+	}
+	opts := &redis.UniversalOptions{
+		Addrs:            cfg.Addrs,
+		MasterName:       cfg.MasterName,
+		SentinelPassword: cfg.SentinelPassword,
+		IsClusterMode:    cfg.Cluster,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		PoolSize:         cfg.PoolSize,
+		MinIdleConns:     cfg.MinIdleConns,
+		ConnMaxIdleTime:  cfg.ConnMaxIdleTime,
+		DialTimeout:      cfg.DialTimeout,
+		ReadTimeout:      cfg.ReadTimeout,
+		WriteTimeout:     cfg.WriteTimeout,
+		TLSConfig:        cfg.TLS,
+	}
+	if !cfg.Cluster {
+		opts.DB = cfg.DB
+	}
+	return redis.NewUniversalClient(opts), nil
+}
+
+// TLSConfig 는 Redis 연결용 TLS 설정을 만든다. caFile 이 비어 있으면 시스템 인증서를 쓰고,
+// certFile 과 keyFile 이 있으면 클라이언트 인증서를 보낸다.
+func TLSConfig(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	c := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: serverName}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("cache: %w", err)
+		}
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("cache: no certificates in %s", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cache: %w", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
+
+// Pool 은 기능들이 같은 연결 풀을 쓰도록 주소별 클라이언트를 하나씩만 만든다.
+type Pool struct {
+	base Config
+
+	mu      sync.Mutex
+	clients map[string]redis.UniversalClient
+}
+
+// NewPool 은 base 를 기본 연결로 쓰는 Pool 을 만든다.
+func NewPool(base Config) *Pool {
+	return &Pool{base: base, clients: map[string]redis.UniversalClient{}}
+}
+
+// Client 는 addr 의 클라이언트를 돌려준다. addr 가 비어 있으면 기본 연결이고, 아니면 기본 설정의 인증과
+// TLS 로 그 주소(쉼표로 여럿)에 연결한다. 같은 addr 는 같은 클라이언트를 받는다.
+func (p *Pool) Client(addr string) (redis.UniversalClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.clients[addr]; ok {
+		return c, nil
+	}
+	cfg := p.base
+	if addr != "" {
+		cfg.Addrs = strings.Split(addr, ",")
+		cfg.MasterName, cfg.Cluster = "", false
+	}
+	c, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	p.clients[addr] = c
+	return c, nil
+}
+
+// Ping 은 만든 클라이언트 모두에 PING 을 보낸다. 준비 검사로 쓴다.
+func (p *Pool) Ping(ctx context.Context) error {
+	p.mu.Lock()
+	clients := make(map[string]redis.UniversalClient, len(p.clients))
+	for addr, c := range p.clients {
+		clients[addr] = c
+	}
+	p.mu.Unlock()
+	var errs []error
+	for addr, c := range clients {
+		if err := c.Ping(ctx).Err(); err != nil {
+			if addr == "" {
+				addr = strings.Join(p.base.Addrs, ",")
+			}
+			errs = append(errs, fmt.Errorf("redis %s: %w", addr, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Len 은 만든 클라이언트 수다.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.clients)
+}
+
+// Close 는 모든 클라이언트의 연결을 닫는다.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var errs []error
+	for addr, c := range p.clients {
+		errs = append(errs, c.Close())
+		delete(p.clients, addr)
+	}
+	return errors.Join(errs...)
+}
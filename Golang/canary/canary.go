@@ -0,0 +1,253 @@
+// Package canary 는 두 구현(핸들러 또는 프록시 백엔드) 사이에 요청을 가중치대로 나누는 카나리 배포 도구다.
+//
+// 요청마다 0~99 의 버킷을 정하고 버킷이 Weight 보다 작으면 canary, 아니면 stable 로 보낸다. 버킷은 쿠키에
+// 남기므로 같은 클라이언트는 계속 같은 쪽으로 가고, 가중치를 올리면 이미 canary 를 받던 클라이언트는 그대로
+// 둔 채 대상이 늘어난다. 쿠키를 쓰지 않는 클라이언트는 Header 에 고정 키(클라이언트 ID 등)를 보내면 된다.
+// Header 값이 "stable" 이나 "canary" 이면 그쪽으로 강제로 보낸다. (시험용)
+//
+// 가중치는 관리 API(Set.Mount)로 실행 중에 바꿀 수 있고, 쪽마다 요청 수와 지연 시간을 메트릭으로 남긴다.
+package canary
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/metrics"
+	"github.com/hgsong234/_stack/Golang/middleware"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// 요청을 보낼 쪽
+const (
+	Stable = "stable"
+	Canary = "canary"
+)
+
+// DefaultHeader 는 Split.Header 가 비어 있을 때 쓰는 요청 헤더다. 응답에도 같은 이름으로 받은 쪽을 알린다.
+const DefaultHeader = "X-Canary"
+
+var (
+	splitRequests = metrics.NewCounterVec("canary_requests_total",
+		"Total number of requests by traffic split, variant and status.", "split", "variant", "status")
+	splitDuration = metrics.NewHistogramVec("canary_request_duration_seconds",
+		"Request latency by traffic split and variant.", nil, "split", "variant")
+	splitWeight = metrics.NewGaugeVec("canary_weight_percent",
+		"Percentage of traffic sent to the canary variant.", "split")
+)
+
+// Split 은 요청을 stable 과 canary 로 나누는 규칙 하나다.
+type Split struct {
+	Name string
+	// Cookie 는 버킷을 남길 쿠키 이름이다. 비어 있으면 "canary_" + Name.
+	Cookie string
+	// Header 는 고정 키나 강제할 쪽을 받는 요청 헤더다. 비어 있으면 DefaultHeader.
+	Header string
+	// MaxAge 는 쿠키 유효 기간이다. 0 이면 30일.
+	MaxAge time.Duration
+
+	weight atomic.Int32
+}
+
+// New 는 weight(0~100)% 를 canary 로 보내는 Split 을 만든다.
+func New(name string, weight int) (*Split, error) {
+	if name == "" {
+		return nil, fmt.Errorf("canary: split name is required")
+	}
+	s := &Split{Name: name}
+	if err := s.SetWeight(weight); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Weight 는 canary 로 보내는 비율(%)이다.
+func (s *Split) Weight() int { return int(s.weight.Load()) }
+
+// SetWeight 는 canary 로 보내는 비율을 바꾼다. 0 이면 모두 stable, 100 이면 모두 canary 다.
+func (s *Split) SetWeight(weight int) error {
+	if weight < 0 || weight > 100 {
+		return fmt.Errorf("canary: weight %d is not between 0 and 100", weight)
+	}
+	s.weight.Store(int32(weight))
+	splitWeight.Set(float64(weight), s.Name)
+	return nil
+}
+
+func (s *Split) cookieName() string {
+	if s.Cookie != "" {
+		return s.Cookie
+	}
+	return "canary_" + s.Name
+}
+
+func (s *Split) header() string {
+	if s.Header != "" {
+		return s.Header
+	}
+	return DefaultHeader
+}
+
+// Assign 은 r 을 보낼 쪽을 정한다. 처음 온 클라이언트에게는 버킷을 쿠키로 남긴다.
+func (s *Split) Assign(w http.ResponseWriter, r *http.Request) string {
+	key := r.Header.Get(s.header())
+	switch key {
+	case Stable, Canary:
+		return key
+	case "":
+	default:
+		return s.variant(bucket(s.Name, key))
+	}
+	if c, err := r.Cookie(s.cookieName()); err == nil {
+		if b, err := strconv.Atoi(c.Value); err == nil && b >= 0 && b < 100 {
+			return s.variant(b)
+		}
+	}
+	b := rand.IntN(100)
+	maxAge := s.MaxAge
+	if maxAge <= 0 {
+		maxAge = 30 * 24 * time.Hour
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name: s.cookieName(), Value: strconv.Itoa(b), Path: "/", MaxAge: int(maxAge.Seconds()),
+		HttpOnly: true, Secure: r.TLS != nil, SameSite: http.SameSiteLaxMode,
+	})
+	return s.variant(b)
+}
+
+func (s *Split) variant(bucket int) string {
+	if bucket < s.Weight() {
+		return Canary
+	}
+	return Stable
+}
+
+// bucket 은 split 과 고정 키로 정해지는 0~99 의 값이다.
+func bucket(name, key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name + "\x00" + key))
+	return int(h.Sum32() % 100)
+}
+
+type ctxKey struct{}
+
+// From 은 요청이 받은 쪽이다. Split.Handler 를 거치지 않은 요청이면 빈 문자열이다.
+func From(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKey{}).(string)
+	return v
+}
+
+// Handler 는 요청을 나눠 stable 이나 canary 로 보내고 쪽별 메트릭을 남긴다.
+func (s *Split) Handler(stable, canary http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := s.Assign(w, r)
+		next := stable
+		if v == Canary {
+			next = canary
+		}
+		w.Header().Set(s.header(), v)
+		r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, v))
+		start := time.Now()
+		sw := middleware.NewStatusWriter(w)
+		defer func() {
+			splitDuration.Observe(time.Since(start).Seconds(), s.Name, v)
+			splitRequests.Inc(s.Name, v, strconv.Itoa(sw.Code()))
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// Set 은 이름으로 찾는 Split 모음이다. 관리 API 가 가중치를 바꿀 대상이다.
+type Set struct {
+	mu     sync.RWMutex
+	splits map[string]*Split
+}
+
+// NewSet 은 빈 Set 을 만든다.
+func NewSet() *Set { return &Set{splits: map[string]*Split{}} }
+
+// Add 는 s 를 등록한다. 같은 이름이 이미 있으면 에러다.
+func (set *Set) Add(s *Split) error {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	if _, ok := set.splits[s.Name]; ok {
+		return fmt.Errorf("canary: duplicate split %q", s.Name)
+	}
+	set.splits[s.Name] = s
+	return nil
+}
+
+// Get 은 이름이 name 인 Split 이다. 없으면 nil 이다.
+func (set *Set) Get(name string) *Split {
+	set.mu.RLock()
+	defer set.mu.RUnlock()
+	return set.splits[name]
+}
+
+// Status 는 관리 API 가 보여 주는 Split 의 상태다.
+type Status struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+// All 은 이름순으로 정렬한 모든 Split 의 상태다.
+func (set *Set) All() []Status {
+	set.mu.RLock()
+	defer set.mu.RUnlock()
+	out := make([]Status, 0, len(set.splits))
+	for _, s := range set.splits {
+		out = append(out, Status{Name: s.Name, Weight: s.Weight()})
+	}
+	slices.SortFunc(out, func(a, b Status) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+// Mount 는 r 의 prefix 아래에 가중치 관리 라우트를 등록한다. mws 로 관리자 인증을 건다.
+//
+//	GET prefix         모든 Split 과 가중치
+//	PUT prefix/{name}  {"weight": 25} 로 실행 중 변경
+func (set *Set) Mount(r router.Routes, prefix string, mws ...router.Middleware) {
+	g := r.Group(prefix, mws...)
+	g.GET("", set.list)
+	g.PUT("/{name}", set.put)
+}
+
+func (set *Set) list(w http.ResponseWriter, r *http.Request) {
+	api.WriteJSON(w, http.StatusOK, map[string][]Status{"splits": set.All()})
+}
+
+func (set *Set) put(w http.ResponseWriter, r *http.Request) {
+	s := set.Get(router.Param(r, "name"))
+	if s == nil {
+		api.WriteError(w, api.NotFound("traffic split not found"))
+		return
+	}
+	var body struct {
+		Weight *int `json:"weight"`
+	}
+	if err := api.ReadJSON(r, &body); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+	if body.Weight == nil {
+		api.WriteError(w, api.BadRequest("weight is required"))
+		return
+	}
+	prev := s.Weight()
+	if err := s.SetWeight(*body.Weight); err != nil {
+		api.WriteError(w, api.BadRequest("weight must be between 0 and 100"))
+		return
+	}
+	logging.From(r.Context()).Warn("canary weight changed", "split", s.Name, "from", prev, "to", *body.Weight)
+	api.WriteJSON(w, http.StatusOK, Status{Name: s.Name, Weight: s.Weight()})
+}
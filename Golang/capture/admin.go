@@ -0,0 +1,22 @@
+package capture
+
+import (
+	"net/http"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Mount 는 r 의 prefix 에 기록 조회 라우트를 등록한다. mws 로 관리자 인가를 건다.
+//
+//	GET    prefix   최근 기록 ({"captures": [...]}, replay 명령이 이 응답도 읽는다)
+//	DELETE prefix   보관 중인 기록 삭제
+func (rec *Recorder) Mount(r router.Routes, prefix string, mws ...router.Middleware) {
+	r.GET(prefix, func(w http.ResponseWriter, _ *http.Request) {
+		api.WriteJSON(w, http.StatusOK, map[string]any{"captures": rec.Recent()})
+	}, mws...)
+	r.DELETE(prefix, func(w http.ResponseWriter, _ *http.Request) {
+		rec.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	}, mws...)
+}
@@ -0,0 +1,301 @@
+// Package capture 는 버그를 재현하려고 요청·응답 쌍을 기록하고(Recorder), 기록한 요청을 다른 서버에
+// 다시 보낸다(Replay).
+//
+// 인증 헤더, 쿠키와 비밀번호·토큰 같은 본문 필드는 기록하기 전에 가리고, 본문은 앞부분만 남긴다.
+// 필드를 가릴 수 없는 본문(JSON, 폼이 아닌 것)은 길이만 남긴다.
+// 가린 값은 다시 보낼 수 없으므로 재현하려면 대상 서버에서 따로 인증해야 한다.
+// 기록에는 여전히 개인 정보가 남을 수 있으므로 필요할 때만 켜고 경로를 좁혀서 쓴다.
+package capture
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/middleware"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Redacted 는 가린 값 자리에 넣는 문자열이다.
+const Redacted = "[REDACTED]"
+
+// 기본으로 가리는 헤더와 본문 필드. Config 의 목록은 여기에 더해진다.
+var (
+	defaultHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-API-Key", "X-CSRF-Token"}
+	defaultFields  = []string{"password", "new_password", "token", "secret", "code", "csrf_token", "client_secret", "access_token", "refresh_token", "key", "backup_codes"}
+)
+
+// Message 는 기록한 요청이나 응답의 헤더와 본문이다.
+type Message struct {
+	Header http.Header `json:"header,omitempty"`
+	// Body 는 UTF-8 이 아니면 base64 로 인코딩하고 Base64 를 켠다.
+	Body   string `json:"body,omitempty"`
+	Base64 bool   `json:"base64,omitempty"`
+	// Truncated 이면 Body 는 앞부분만이다.
+	Truncated bool `json:"truncated,omitempty"`
+	// Omitted 는 본문을 남기지 않은 이유다. (예: 가릴 수 없는 JSON)
+	Omitted string `json:"omitted,omitempty"`
+}
+
+// Exchange 는 기록한 요청·응답 쌍 하나다.
+type Exchange struct {
+	ID        int64     `json:"id"`
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	Method    string    `json:"method"`
+	Host      string    `json:"host"`
+	// URL 은 경로와 쿼리다. (예: /api/hello?name=a)
+	URL        string  `json:"url"`
+	Request    Message `json:"request"`
+	Status     int     `json:"status"`
+	Response   Message `json:"response"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Config 는 Recorder 설정이다.
+type Config struct {
+	// Paths 는 기록할 경로 접두사다. 비어 있으면 모든 요청을 기록한다.
+	Paths []string
+	// MaxBody 는 요청과 응답 본문을 각각 몇 바이트까지 남길지다. 0 이면 64KiB.
+	MaxBody int
+	// Buffer 는 메모리에 보관할 최근 기록 수다. 0 이면 100.
+	Buffer int
+	// RedactHeaders, RedactFields 는 기본 목록에 더해 가릴 헤더와 본문 필드(JSON 키, 폼 필드)다.
+	RedactHeaders []string
+	RedactFields  []string
+	// Output 이 있으면 기록을 JSON 한 줄씩 추가로 쓴다. replay 명령이 이 형식을 읽는다.
+	Output io.Writer
+}
+
+// Recorder 는 기록을 순환 버퍼에 모으고 Output 에도 쓴다.
+type Recorder struct {
+	cfg     Config
+	headers []string
+	fields  map[string]bool
+
+	mu     sync.Mutex
+	ring   []Exchange // 순환 버퍼
+	next   int
+	lastID int64
+}
+
+// New 는 cfg 로 Recorder 를 만든다.
+func New(cfg Config) *Recorder {
+	if cfg.MaxBody <= 0 {
+		cfg.MaxBody = 64 << 10
+	}
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = 100
+	}
+	rec := &Recorder{cfg: cfg, fields: map[string]bool{}, ring: make([]Exchange, 0, cfg.Buffer)}
+	for _, h := range append(append([]string{}, defaultHeaders...), cfg.RedactHeaders...) {
+		rec.headers = append(rec.headers, http.CanonicalHeaderKey(h))
+	}
+	for _, f := range append(append([]string{}, defaultFields...), cfg.RedactFields...) {
+		rec.fields[strings.ToLower(f)] = true
+	}
+	return rec
+}
+
+// Middleware 는 Paths 에 맞는 요청과 그 응답을 기록한다. 압축 미들웨어보다 안쪽에 걸어야 응답 본문을
+// 압축하기 전에 남긴다. WebSocket 업그레이드 요청은 기록하지 않는다.
+func (rec *Recorder) Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !rec.matches(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			start := time.Now()
+			reqBody := &limitBuffer{max: rec.cfg.MaxBody}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = readCloser{io.TeeReader(r.Body, reqBody), r.Body}
+			}
+			bw := &bodyWriter{StatusWriter: middleware.NewStatusWriter(w), buf: limitBuffer{max: rec.cfg.MaxBody}}
+			defer func() {
+				rec.add(Exchange{
+					Time:       start.UTC(),
+					RequestID:  middleware.RequestIDFrom(r.Context()),
+					Method:     r.Method,
+					Host:       r.Host,
+					URL:        r.URL.RequestURI(),
+					Request:    rec.message(r.Header, reqBody),
+					Status:     bw.Code(),
+					Response:   rec.message(bw.Header(), &bw.buf),
+					DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+				})
+			}()
+			next.ServeHTTP(bw, r)
+		})
+	}
+}
+
+func (rec *Recorder) matches(r *http.Request) bool {
+	if r.Header.Get("Upgrade") != "" {
+		return false
+	}
+	if len(rec.cfg.Paths) == 0 {
+		return true
+	}
+	for _, p := range rec.cfg.Paths {
+		if strings.HasPrefix(r.URL.Path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// message 는 헤더와 본문을 가려서 Message 로 만든다.
+func (rec *Recorder) message(h http.Header, body *limitBuffer) Message {
+	m := Message{Header: h.Clone(), Truncated: body.truncated}
+	for _, k := range rec.headers {
+		if _, ok := m.Header[k]; ok {
+			m.Header[k] = []string{Redacted}
+		}
+	}
+	b := body.Bytes()
+	if len(b) == 0 {
+		return m
+	}
+	ct := h.Get("Content-Type")
+	switch {
+	case strings.Contains(ct, "json"):
+		var v any
+		if err := json.Unmarshal(b, &v); err != nil {
+			// 잘린 JSON 은 필드를 가릴 수 없으므로 남기지 않는다.
+			m.Omitted = "json body could not be parsed for redaction"
+			return m
+		}
+		b, _ = json.Marshal(rec.redact(v))
+	case strings.HasPrefix(ct, "application/x-www-form-urlencoded"):
+		q, err := url.ParseQuery(string(b))
+		if err != nil {
+			m.Omitted = "form body could not be parsed for redaction"
+			return m
+		}
+		for k := range q {
+			if rec.fields[strings.ToLower(k)] {
+				q[k] = []string{Redacted}
+			}
+		}
+		b = []byte(q.Encode())
+	default:
+		// HTML 이나 평문은 가릴 필드를 찾을 수 없다. (예: TOTP 비밀이 담긴 2단계 인증 설정 페이지)
+		m.Omitted = fmt.Sprintf("%d byte %s body is not recorded because it cannot be redacted", len(b), mediaType(ct))
+		return m
+	}
+	if utf8.Valid(b) {
+		m.Body = string(b)
+	} else {
+		m.Body, m.Base64 = base64.StdEncoding.EncodeToString(b), true
+	}
+	return m
+}
+
+// mediaType 은 Content-Type 의 매개변수를 뺀 값이다. 비어 있으면 "untyped" 다.
+func mediaType(ct string) string {
+	mt, _, _ := strings.Cut(ct, ";")
+	if mt = strings.TrimSpace(mt); mt == "" {
+		return "untyped"
+	}
+	return mt
+}
+
+// redact 는 JSON 값에서 가릴 필드의 값을 바꾼다.
+func (rec *Recorder) redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, x := range v {
+			if rec.fields[strings.ToLower(k)] {
+				v[k] = Redacted
+			} else {
+				v[k] = rec.redact(x)
+			}
+		}
+	case []any:
+		for i, x := range v {
+			v[i] = rec.redact(x)
+		}
+	}
+	return v
+}
+
+func (rec *Recorder) add(e Exchange) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.lastID++
+	e.ID = rec.lastID
+	if len(rec.ring) < cap(rec.ring) {
+		rec.ring = append(rec.ring, e)
+	} else {
+		rec.ring[rec.next] = e
+	}
+	rec.next = (rec.next + 1) % cap(rec.ring)
+	if rec.cfg.Output != nil {
+		b, _ := json.Marshal(e)
+		if _, err := rec.cfg.Output.Write(append(b, '\n')); err != nil {
+			logging.Default().Error("capture: write", "err", err)
+		}
+	}
+}
+
+// Recent 는 보관 중인 기록을 최근 것부터 돌려준다.
+func (rec *Recorder) Recent() []Exchange {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	out := make([]Exchange, 0, len(rec.ring))
+	for i := range len(rec.ring) {
+		out = append(out, rec.ring[(rec.next-1-i+len(rec.ring))%len(rec.ring)])
+	}
+	return out
+}
+
+// Reset 은 보관 중인 기록을 지운다. Output 에 쓴 기록은 그대로 둔다.
+func (rec *Recorder) Reset() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.ring, rec.next = rec.ring[:0], 0
+}
+
+// limitBuffer 는 처음 max 바이트만 남기는 Writer 다.
+type limitBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyWriter 는 응답 본문의 앞부분을 함께 남기는 ResponseWriter 래퍼다.
+type bodyWriter struct {
+	*middleware.StatusWriter
+	buf limitBuffer
+}
+
+func (w *bodyWriter) Write(b []byte) (int, error) {
+	w.buf.Write(b)
+	return w.StatusWriter.Write(b)
+}
@@ -0,0 +1,133 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Load 는 r 에서 기록을 읽는다. Output 파일(JSON 한 줄씩)과 관리 API 응답({"captures": [...]})을 모두 읽는다.
+func Load(r io.Reader) ([]Exchange, error) {
+	var out []Exchange
+	dec := json.NewDecoder(r)
+	for {
+		var v struct {
+			Exchange
+			Captures []Exchange `json:"captures"`
+		}
+		err := dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("capture: load: %w", err)
+		}
+		if v.Captures != nil {
+			out = append(out, v.Captures...)
+		} else {
+			out = append(out, v.Exchange)
+		}
+	}
+}
+
+// Result 는 다시 보낸 요청 하나의 결과다.
+type Result struct {
+	Status   int
+	Body     []byte
+	Duration time.Duration
+	// SameBody 는 기록한 응답 본문과 같은지다. 잘린 본문은 앞부분만 비교하고, JSON 은 가린 필드를 빼고
+	// 값으로 비교한다.
+	SameBody bool
+}
+
+// Replay 는 e 의 요청을 target(예: http://localhost:8080)에 다시 보낸다.
+// 가린 헤더는 보내지 않고, 본문이 잘렸거나 남지 않은 요청은 에러를 돌려준다.
+func Replay(ctx context.Context, client *http.Client, target string, e Exchange) (*Result, error) {
+	if e.Request.Truncated || e.Request.Omitted != "" {
+		return nil, errors.New("request body was not captured in full")
+	}
+	body, err := e.Request.bytes()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, e.Method, strings.TrimSuffix(target, "/")+e.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range e.Request.Header {
+		if len(vs) == 1 && vs[0] == Redacted {
+			continue
+		}
+		req.Header[k] = vs
+	}
+	req.Header.Del("Content-Length")
+	req.Header.Del("Accept-Encoding")
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Status: resp.StatusCode, Body: got, Duration: time.Since(start)}
+	want, err := e.Response.bytes()
+	switch {
+	case err != nil || e.Response.Omitted != "":
+	case e.Response.Truncated:
+		res.SameBody = bytes.HasPrefix(got, want)
+	case strings.Contains(e.Response.Header.Get("Content-Type"), "json"):
+		var a, b any
+		res.SameBody = json.Unmarshal(want, &a) == nil && json.Unmarshal(got, &b) == nil && sameJSON(a, b)
+	default:
+		res.SameBody = bytes.Equal(got, want)
+	}
+	return res, nil
+}
+
+// sameJSON 은 기록한 값 want 와 새 값 got 을 비교한다. want 에서 가린 값은 무엇이든 같은 것으로 본다.
+func sameJSON(want, got any) bool {
+	switch w := want.(type) {
+	case string:
+		return w == Redacted || w == got
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for k, v := range w {
+			if x, ok := g[k]; !ok || !sameJSON(v, x) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		g, ok := got.([]any)
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for i := range w {
+			if !sameJSON(w[i], g[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return want == got
+}
+
+func (m Message) bytes() ([]byte, error) {
+	if m.Base64 {
+		return base64.StdEncoding.DecodeString(m.Body)
+	}
+	return []byte(m.Body), nil
+}
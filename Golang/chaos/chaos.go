@@ -0,0 +1,285 @@
+// Package chaos 는 회복력 시험용 장애 주입 미들웨어다. 개발·스테이징 전용이며 운영에서는 켜지 않는다.
+//
+// 규칙은 메서드와 경로 접두사로 요청을 고르고, 그중 Percent 만큼의 요청에 지연, 에러 응답, 연결 끊기,
+// 응답 속도 제한을 넣는다. 클라이언트의 재시도와 타임아웃이 제대로 동작하는지 확인하는 데 쓴다.
+// 장애를 넣은 응답에는 X-Chaos 헤더가 붙는다. 규칙은 관리 API(Handler)로 실행 중에 바꿀 수 있다.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Header 는 장애를 넣은 응답에 붙는 헤더다. 값은 넣은 장애 목록이다. (예: "latency=500ms, status=503")
+const Header = "X-Chaos"
+
+// Rule 은 장애 주입 규칙 하나다.
+type Rule struct {
+	// Method 가 비어 있으면 모든 메서드와 일치한다.
+	Method string `json:"method,omitempty"`
+	// Path 는 경로 접두사다. 그 자체와 하위 경로 전체와 일치한다. 비어 있으면 모든 경로다.
+	Path string `json:"path,omitempty"`
+	// Percent 는 일치한 요청 중 장애를 넣을 비율(0~100)이다.
+	Percent float64 `json:"percent"`
+	// Latency 만큼 기다린 뒤 처리하고, Jitter 가 있으면 0~Jitter 를 더 기다린다. (예: "500ms")
+	Latency string `json:"latency,omitempty"`
+	Jitter  string `json:"jitter,omitempty"`
+	// Status 가 있으면 핸들러를 부르지 않고 이 상태 코드의 에러 응답을 보낸다.
+	Status int `json:"status,omitempty"`
+	// Drop 이면 응답 없이 연결을 끊는다.
+	Drop bool `json:"drop,omitempty"`
+	// BytesPerSecond 가 있으면 응답 본문을 이 속도로 보낸다.
+	BytesPerSecond int64 `json:"bytes_per_second,omitempty"`
+}
+
+// rule 은 검사를 마친 Rule 이다.
+type rule struct {
+	Rule
+	latency, jitter time.Duration
+}
+
+// Injector 는 장애 주입 규칙의 집합이다. 여러 요청에서 동시에 읽어도 안전하다.
+type Injector struct {
+	exempt []string
+	rules  atomic.Pointer[[]rule]
+}
+
+// New 는 rules 로 Injector 를 만든다. exempt 경로(관리 API, 상태 검사 등)에는 장애를 넣지 않는다.
+func New(rules []Rule, exempt ...string) (*Injector, error) {
+	in := &Injector{exempt: exempt}
+	if err := in.SetRules(rules); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// Rules 는 현재 규칙이다.
+func (in *Injector) Rules() []Rule {
+	rs := *in.rules.Load()
+	out := make([]Rule, len(rs))
+	for i, r := range rs {
+		out[i] = r.Rule
+	}
+	return out
+}
+
+// SetRules 는 규칙을 검사해 바꾼다. 하나라도 잘못되면 아무것도 바꾸지 않는다.
+func (in *Injector) SetRules(rules []Rule) error {
+	rs, err := compile(rules)
+	if err != nil {
+		return err
+	}
+	in.rules.Store(&rs)
+	return nil
+}
+
+func compile(rules []Rule) ([]rule, error) {
+	out := make([]rule, 0, len(rules))
+	var errs []error
+	for i, r := range rules {
+		c := rule{Rule: r}
+		bad := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("chaos: rule %d: "+format, append([]any{i}, args...)...))
+		}
+		if r.Percent < 0 || r.Percent > 100 {
+			bad("percent %v is not between 0 and 100", r.Percent)
+		}
+		for _, d := range []struct {
+			name string
+			s    string
+			dst  *time.Duration
+		}{{"latency", r.Latency, &c.latency}, {"jitter", r.Jitter, &c.jitter}} {
+			if d.s == "" {
+				continue
+			}
+			v, err := time.ParseDuration(d.s)
+			if err != nil || v < 0 {
+				bad("invalid %s %q", d.name, d.s)
+			}
+			*d.dst = v
+		}
+		if r.Status != 0 && (r.Status < 400 || r.Status > 599) {
+			bad("status %d is not an error status", r.Status)
+		}
+		if r.Status != 0 && r.Drop {
+			bad("status and drop cannot be combined")
+		}
+		if r.BytesPerSecond < 0 {
+			bad("bytes_per_second must not be negative")
+		}
+		if c.latency == 0 && c.jitter == 0 && r.Status == 0 && !r.Drop && r.BytesPerSecond == 0 {
+			bad("no fault is set")
+		}
+		if r.Path != "" && !strings.HasPrefix(r.Path, "/") {
+			bad("path %q must start with /", r.Path)
+		}
+		out = append(out, c)
+	}
+	return out, errors.Join(errs...)
+}
+
+// match 는 r 에 일치하는 첫 규칙을 돌려준다.
+func (in *Injector) match(r *http.Request) (rule, bool) {
+	if hasPrefix(r.URL.Path, in.exempt) {
+		return rule{}, false
+	}
+	for _, c := range *in.rules.Load() {
+		if c.Method != "" && !strings.EqualFold(c.Method, r.Method) {
+			continue
+		}
+		if c.Path != "" && !hasPrefix(r.URL.Path, []string{c.Path}) {
+			continue
+		}
+		return c, true
+	}
+	return rule{}, false
+}
+
+func hasPrefix(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		p = strings.TrimSuffix(p, "/")
+		if p == "" || path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware 는 규칙에 따라 요청에 장애를 넣는다. 요청 ID 와 접근 로그 안쪽에 등록해야 지연과 에러가 기록된다.
+func (in *Injector) Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c, ok := in.match(r)
+			if !ok || rand.Float64()*100 >= c.Percent {
+				next.ServeHTTP(w, r)
+				return
+			}
+			var faults []string
+			if d := c.latency + jitter(c.jitter); d > 0 {
+				faults = append(faults, "latency="+d.String())
+				if err := sleep(r.Context(), d); err != nil {
+					return
+				}
+			}
+			l := logging.From(r.Context())
+			switch {
+			case c.Drop:
+				l.Debug("chaos: dropping connection", "path", r.URL.Path)
+				// net/http 가 응답 없이 연결(HTTP/2 는 스트림)을 끊는다.
+				panic(http.ErrAbortHandler)
+			case c.Status != 0:
+				faults = append(faults, fmt.Sprintf("status=%d", c.Status))
+				w.Header().Set(Header, strings.Join(faults, ", "))
+				l.Debug("chaos: injected error", "path", r.URL.Path, "status", c.Status)
+				api.WriteError(w, api.NewError(c.Status, "chaos_injected", "fault injected for resilience testing"))
+				return
+			}
+			if c.BytesPerSecond > 0 {
+				faults = append(faults, fmt.Sprintf("bandwidth=%d", c.BytesPerSecond))
+				w = newThrottled(r.Context(), w, c.BytesPerSecond)
+			}
+			w.Header().Set(Header, strings.Join(faults, ", "))
+			l.Debug("chaos: injected faults", "path", r.URL.Path, "faults", faults)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return rand.N(max)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// throttled 는 응답 본문을 조각으로 나눠 보내 속도를 제한한다.
+type throttled struct {
+	http.ResponseWriter
+	ctx   context.Context
+	rc    *http.ResponseController
+	bps   int64
+	chunk int
+	start time.Time
+	sent  int64
+}
+
+func newThrottled(ctx context.Context, w http.ResponseWriter, bps int64) *throttled {
+	// 초당 10 번 정도 나눠 보낸다.
+	chunk := int(min(max(bps/10, 1), 32<<10))
+	return &throttled{ResponseWriter: w, ctx: ctx, rc: http.NewResponseController(w), bps: bps, chunk: chunk}
+}
+
+func (w *throttled) Write(b []byte) (int, error) {
+	if w.start.IsZero() {
+		w.start = time.Now()
+	}
+	n := 0
+	for len(b) > 0 {
+		due := w.start.Add(time.Duration(float64(w.sent) / float64(w.bps) * float64(time.Second)))
+		if d := time.Until(due); d > 0 {
+			if err := sleep(w.ctx, d); err != nil {
+				return n, err
+			}
+		}
+		m, err := w.ResponseWriter.Write(b[:min(len(b), w.chunk)])
+		n += m
+		w.sent += int64(m)
+		if err != nil {
+			return n, err
+		}
+		// 조각마다 내보내야 클라이언트가 느린 속도를 본다.
+		w.rc.Flush()
+		b = b[m:]
+	}
+	return n, nil
+}
+
+func (w *throttled) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Handler 는 GET 으로 규칙을 돌려주고 PUT {"rules": [...]} 로 바꾸는 관리 API 핸들러다. DELETE 는 모든 규칙을 지운다.
+// 관리자 인가 미들웨어 뒤에 등록한다.
+func (in *Injector) Handler() http.HandlerFunc {
+	type body struct {
+		Rules []Rule `json:"rules"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			var b body
+			if err := api.ReadJSON(r, &b); err != nil {
+				api.WriteError(w, err)
+				return
+			}
+			if err := in.SetRules(b.Rules); err != nil {
+				api.WriteError(w, api.BadRequest(err.Error()))
+				return
+			}
+			logging.From(r.Context()).Warn("chaos rules changed", "rules", len(b.Rules))
+		case http.MethodDelete:
+			in.SetRules(nil)
+			logging.From(r.Context()).Warn("chaos rules cleared")
+		}
+		api.WriteJSON(w, http.StatusOK, body{Rules: in.Rules()})
+	}
+}
@@ -0,0 +1,72 @@
+// Package coalesce 는 같은 키의 동시 작업을 하나로 합친다(singleflight). 인기 있는 키에 요청이 한꺼번에
+// 몰려도 실제 계산은 한 번만 하고, 기다리던 호출은 모두 그 결과를 나눠 받는다.
+//
+// Group 은 함수 호출을, Requests 는 GET/HEAD 요청을 합친다. 결과는 계산이 끝나면 버리므로 캐시가 아니다.
+// 응답을 저장해 두려면 respcache 와 함께 쓴다.
+package coalesce
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Group 은 키별로 진행 중인 호출을 추적한다. 빈 값으로 쓸 수 있다.
+type Group[V any] struct {
+	mu    sync.Mutex
+	calls map[string]*call[V]
+}
+
+type call[V any] struct {
+	done    chan struct{}
+	val     V
+	err     error
+	waiters int
+}
+
+// Do 는 key 로 진행 중인 호출이 없으면 fn 을 실행하고, 있으면 그 호출이 끝날 때까지 기다려 같은 결과를 돌려준다.
+// shared 는 결과를 다른 호출과 나눠 가졌는지다. ctx 가 먼저 끝나면 기다리지 않고 ctx.Err() 를 돌려준다.
+// fn 은 ctx 와 상관없이 끝까지 실행되므로, 필요하면 fn 안에서 별도의 제한 시간을 건다.
+func (g *Group[V]) Do(ctx context.Context, key string, fn func() (V, error)) (v V, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*call[V]{}
+	}
+	if c, ok := g.calls[key]; ok {
+		c.waiters++
+		g.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.val, true, c.err
+		case <-ctx.Done():
+			return v, false, ctx.Err()
+		}
+	}
+	c := &call[V]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	finished := false
+	defer func() {
+		// fn 이 패닉하면 기다리던 호출에는 에러를 주고 패닉은 그대로 올려 보낸다.
+		if !finished {
+			p := recover()
+			c.err = fmt.Errorf("coalesce: panic: %v", p)
+			g.finish(key, c)
+			panic(p)
+		}
+	}()
+	c.val, c.err = fn()
+	finished = true
+	return c.val, g.finish(key, c) > 0, c.err
+}
+
+// finish 는 호출을 목록에서 지우고 기다리던 호출을 깨운다. 기다리던 호출 수를 돌려준다.
+func (g *Group[V]) finish(key string, c *call[V]) int {
+	g.mu.Lock()
+	delete(g.calls, key)
+	n := c.waiters
+	g.mu.Unlock()
+	close(c.done)
+	return n
+}
@@ -0,0 +1,118 @@
+package coalesce
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/hgsong234/_stack/Golang/metrics"
+	"github.com/hgsong234/_stack/Golang/middleware"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Header 는 응답을 다른 요청과 나눠 받았는지 알려 주는 헤더다. 나눠 받은 응답에만 "shared" 로 붙는다.
+const Header = "X-Coalesced"
+
+var coalesced = metrics.NewCounterVec("coalesce_requests_total",
+	"Coalesced GET requests by result (leader, shared, bypass).", "result")
+
+// Config 는 Requests 설정이다.
+type Config struct {
+	// Key 는 같은 응답을 받아도 되는 요청을 묶는 키다. nil 이면 DefaultKey.
+	Key func(*http.Request) string
+	// MaxBody 는 나눠 줄 응답 본문의 최대 크기다. 넘으면 기다리던 요청은 각자 핸들러를 실행한다. 0 이면 1MB.
+	MaxBody int
+}
+
+// DefaultKey 는 메서드, 경로와 쿼리, 그리고 응답을 바꿀 수 있는 요청 헤더(인증, 쿠키, Accept 계열) 다.
+// 인증 정보가 키에 들어가므로 다른 사용자의 응답을 나눠 받지 않는다.
+func DefaultKey(r *http.Request) string {
+	h := sha256.New()
+	for _, name := range []string{"Authorization", "Cookie", "X-Api-Key", "Accept", "Accept-Language"} {
+		for _, v := range r.Header.Values(name) {
+			h.Write([]byte(name + ":" + v + "\n"))
+		}
+	}
+	return r.Method + " " + r.URL.RequestURI() + " " + hex.EncodeToString(h.Sum(nil)[:12])
+}
+
+// result 는 먼저 온 요청(leader)이 기록해 기다리던 요청에 나눠 주는 응답이다.
+type result struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+var errNotShareable = errors.New("coalesce: response is not shareable")
+
+// Requests 는 같은 키의 동시 GET/HEAD 요청 중 먼저 온 요청만 핸들러를 실행하고,
+// 나머지는 그 응답을 복사해 받게 하는 라우트 미들웨어다. Set-Cookie 가 붙었거나 MaxBody 를 넘는 응답은
+// 나누지 않고, 기다리던 요청이 각자 핸들러를 실행한다.
+func Requests(cfg Config) router.Middleware {
+	if cfg.Key == nil {
+		cfg.Key = DefaultKey
+	}
+	if cfg.MaxBody <= 0 {
+		cfg.MaxBody = 1 << 20
+	}
+	var g Group[*result]
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				coalesced.Inc("bypass")
+				next.ServeHTTP(w, r)
+				return
+			}
+			leader := false
+			res, shared, err := g.Do(r.Context(), cfg.Key(r), func() (*result, error) {
+				leader = true
+				pre := w.Header().Clone()
+				rec := middleware.NewBodyRecorder(w, cfg.MaxBody)
+				next.ServeHTTP(rec, r)
+				if res := shareable(rec, pre); res != nil {
+					return res, nil
+				}
+				return nil, errNotShareable
+			})
+			switch {
+			case leader:
+				coalesced.Inc("leader")
+			case err == nil && shared:
+				coalesced.Inc("shared")
+				res.replay(w, r)
+			case r.Context().Err() != nil:
+				// 기다리는 동안 클라이언트가 끊었다.
+			default:
+				coalesced.Inc("bypass")
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// replay 는 나눠 받은 응답을 쓴다. 본문은 요청마다 쓰기만 하고 바꾸지 않으므로 복사하지 않는다.
+func (res *result) replay(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	for k, v := range res.header {
+		h[k] = slices.Clone(v)
+	}
+	h.Set(Header, "shared")
+	h.Set("Content-Length", strconv.Itoa(len(res.body)))
+	w.WriteHeader(res.status)
+	if r.Method != http.MethodHead {
+		w.Write(res.body)
+	}
+}
+
+// shareable 은 나눠 줄 응답을 만든다. 요청 ID 처럼 바깥 미들웨어가 붙인 헤더는 요청마다 새로 붙으므로
+// 핸들러가 더하거나 바꾼 헤더만 남긴다. pre 는 핸들러 실행 전의 헤더다.
+func shareable(rec *middleware.BodyRecorder, pre http.Header) *result {
+	body, ok := rec.Body()
+	if !ok || rec.Sent().Get("Set-Cookie") != "" {
+		return nil
+	}
+	return &result{status: rec.Status(), header: rec.Changed(pre), body: body}
+}
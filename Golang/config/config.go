@@ -0,0 +1,1862 @@
+// Package config 는 서버 설정을 기본값 → 설정 파일(JSON) → 환경 변수 → 명령행 플래그 순서로 읽어 들인다.
+//
+// 각 필드의 환경 변수 이름과 플래그 이름은 json 태그에서 만들어진다.
+// 예를 들어 Server.Addr 는 환경 변수 APP_SERVER_ADDR, 플래그 -server.addr 로 덮어쓸 수 있다.
+// secret:"true" 태그가 붙은 필드는 Settings 에서 값이 가려진다.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix 는 설정을 덮어쓰는 환경 변수의 접두사다.
+const EnvPrefix = "APP_"
+
+// Config 는 서버 전체 설정이다.
+type Config struct {
+	Server    ServerConfig    `json:"server"`
+	TLS       TLSConfig       `json:"tls"`
+	Log       LogConfig       `json:"log"`
+	Static    StaticConfig    `json:"static"`
+	Templates TemplatesConfig `json:"templates"`
+	Session   SessionConfig   `json:"session"`
+	Auth      AuthConfig      `json:"auth"`
+	OAuth     OAuthConfig     `json:"oauth"`
+	RateLimit RateLimitConfig `json:"rate_limit"`
+	Quota     QuotaConfig     `json:"quota"`
+	CORS      CORSConfig      `json:"cors"`
+	Compress  CompressConfig  `json:"compress"`
+	Upload    UploadConfig    `json:"upload"`
+	Download  DownloadConfig  `json:"download"`
+	Security  SecurityConfig  `json:"security"`
+	CSRF      CSRFConfig      `json:"csrf"`
+	Database  DatabaseConfig  `json:"database"`
+	Redis     RedisConfig     `json:"redis"`
+	API       APIConfig       `json:"api"`
+	ACL       ACLConfig       `json:"acl"`
+	RBAC      RBACConfig      `json:"rbac"`
+	Admin     AdminConfig     `json:"admin"`
+	Debug     DebugConfig     `json:"debug"`
+	Tracing   TracingConfig   `json:"tracing"`
+	Proxy     ProxyConfig     `json:"proxy"`
+	Client    ClientConfig    `json:"client"`
+	// ResponseCache 는 응답 캐시 설정이다.
+	ResponseCache ResponseCacheConfig `json:"response_cache"`
+	VHosts        VHostsConfig        `json:"vhosts"`
+	Maintenance   MaintenanceConfig   `json:"maintenance"`
+	Chaos         ChaosConfig         `json:"chaos"`
+	Canary        CanaryConfig        `json:"canary"`
+	Deadline      DeadlineConfig      `json:"deadline"`
+	Bot           BotConfig           `json:"bot"`
+	Search        SearchConfig        `json:"search"`
+	Bench         BenchConfig         `json:"bench"`
+	Flags         FlagsConfig         `json:"flags"`
+	Jobs          JobsConfig          `json:"jobs"`
+	Cron          CronConfig          `json:"cron"`
+	Webhooks      WebhooksConfig      `json:"webhooks"`
+	Mail          MailConfig          `json:"mail"`
+	LocalAuth     LocalAuthConfig     `json:"local_auth"`
+	Audit         AuditConfig         `json:"audit"`
+	Capture       CaptureConfig       `json:"capture"`
+	Mock          MockConfig          `json:"mock"`
+	Shed          ShedConfig          `json:"shed"`
+	Idempotency   IdempotencyConfig   `json:"idempotency"`
+	Pages         PagesConfig         `json:"pages"`
+	Markdown      MarkdownConfig      `json:"markdown"`
+	Feed          FeedConfig          `json:"feed"`
+	WellKnown     WellKnownConfig     `json:"well_known"`
+	RealIP        RealIPConfig        `json:"real_ip"`
+	Tenancy       TenancyConfig       `json:"tenancy"`
+	GraphQL       GraphQLConfig       `json:"graphql"`
+	GRPC          GRPCConfig          `json:"grpc"`
+	Dev           DevConfig           `json:"dev"`
+	Modules       ModulesConfig       `json:"modules"`
+
+	// file 은 읽은 설정 파일 경로다.
+	file string
+}
+
+// File 은 읽은 설정 파일 경로다. 파일 없이 시작했으면 비어 있다.
+func (c *Config) File() string { return c.file }
+
+// ModulesConfig 는 module 패키지로 등록된 기능 모듈 설정이다. 등록된 모듈은 기본으로 켜진다.
+type ModulesConfig struct {
+	// Disabled 는 끌 모듈 이름이다.
+	Disabled []string `json:"disabled"`
+	// Settings 는 모듈 이름별 설정이다. 모듈이 스스로 읽으므로 설정 파일에서만 지정할 수 있다.
+	Settings map[string]json.RawMessage `json:"settings"`
+}
+
+// DevConfig 는 개발 모드 설정이다. --dev 는 -dev.enabled true 와 같다.
+//
+// 개발 모드에서는 템플릿과 설정 파일이 바뀌면 다시 읽고, 로그는 debug 레벨의 pretty 형식이며, 패닉
+// 에러 페이지에 스택 트레이스를 보여준다. 현재 디렉터리에 templates/, assets/ 가 있으면 내장 파일 대신
+// 그 디렉터리를 읽는다. 설정 파일, 환경 변수, 플래그로 명시한 값이 개발 모드 기본값보다 우선한다.
+type DevConfig struct {
+	Enabled bool `json:"enabled"`
+	// Watch 가 true 이면 이 프로세스는 감독만 한다. Dirs 의 Exts 파일이 바뀔 때마다 Build 로 다시 빌드하고
+	// 성공하면 Binary 로 서버를 재시작한다. 빌드가 실패하면 이전 서버를 그대로 둔다.
+	Watch  bool     `json:"watch"`
+	Build  []string `json:"build"`
+	Binary string   `json:"binary"`
+	Dirs   []string `json:"dirs"`
+	Exts   []string `json:"exts"`
+	// Interval 은 파일 변경을 확인하는 주기다.
+	Interval Duration `json:"interval"`
+}
+
+// devDefaults 는 개발 모드의 기본값을 c 에 적용한다.
+func (c *Config) devDefaults() {
+	c.Dev.Enabled = true
+	c.Log.Level, c.Log.Format = "debug", "pretty"
+	c.Templates.Reload = true
+	c.Pages.Live = true
+	c.Server.TimingHeader = true
+	if dirExists("templates") {
+		c.Templates.Dir = "templates"
+	}
+	if dirExists("assets") {
+		c.Static.Dir = "assets"
+		c.Static.MaxAge = 0
+	}
+}
+
+func dirExists(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && fi.IsDir()
+}
+
+// ServerConfig 는 HTTP 리스너 설정이다.
+type ServerConfig struct {
+	// Addr 는 TCP "host:port", 유닉스 소켓 "unix:/경로", systemd 소켓 활성화 "systemd" 또는
+	// "systemd:이름" 중 하나다.
+	Addr         string   `json:"addr"`
+	DrainTimeout Duration `json:"drain_timeout"`
+	// RealtimeDrainTimeout 은 종료할 때 WebSocket, SSE 클라이언트에 종료를 알리고 연결이 끊기기를 기다리는 시간이다.
+	// 지나면 남은 연결을 끊는다. drain_timeout 안에 들어가야 한다.
+	RealtimeDrainTimeout Duration `json:"realtime_drain_timeout"`
+	// 연결 단위 타임아웃 (0 이면 제한 없음)
+	ReadHeaderTimeout Duration `json:"read_header_timeout"`
+	ReadTimeout       Duration `json:"read_timeout"`
+	WriteTimeout      Duration `json:"write_timeout"`
+	IdleTimeout       Duration `json:"idle_timeout"`
+	// HandlerTimeout 은 API 핸들러 하나가 실행될 수 있는 시간이다. 넘으면 503 을 돌려준다.
+	HandlerTimeout Duration `json:"handler_timeout"`
+	// MaxBodyBytes 는 요청 본문의 기본 최대 크기다.
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+	// MaxHeaderBytes 는 요청 줄과 헤더의 최대 크기다.
+	MaxHeaderBytes int `json:"max_header_bytes"`
+	// KeepAlives 가 false 이면 응답마다 HTTP 연결을 닫는다.
+	KeepAlives bool `json:"keep_alives"`
+	// TCPKeepAlive 는 TCP keep-alive 탐침 주기다. 0 이면 15초, 음수이면 끈다.
+	TCPKeepAlive Duration `json:"tcp_keep_alive"`
+	// TCPNoDelay 가 false 이면 Nagle 알고리즘으로 작은 쓰기를 모아 보낸다.
+	TCPNoDelay bool `json:"tcp_no_delay"`
+	// SocketReadBuffer, SocketWriteBuffer 는 연결의 커널 버퍼 크기(SO_RCVBUF, SO_SNDBUF)다. 0 이면 커널 기본값.
+	SocketReadBuffer  int `json:"socket_read_buffer"`
+	SocketWriteBuffer int `json:"socket_write_buffer"`
+	// ListenBacklog 는 accept 를 기다리는 연결 큐의 길이다. 0 이면 커널 기본값(somaxconn). 유닉스에서만 적용한다.
+	ListenBacklog int `json:"listen_backlog"`
+	// MaxConnections 는 리스너마다 동시에 열어 둘 연결 수다. 가득 차면 accept 를 멈춰 새 연결은 커널 큐에서
+	// 기다린다. 0 이면 제한하지 않는다.
+	MaxConnections int `json:"max_connections"`
+	// HTTP2 는 TLS 에서 HTTP/2 를 협상할지, H2C 는 평문 HTTP/2 (prior knowledge) 를 받을지 정한다.
+	HTTP2 bool `json:"http2"`
+	H2C   bool `json:"h2c"`
+	// TimingHeader 가 true 이면 응답에 단계별 처리 시간(인증, DB, 렌더링)을 Server-Timing 헤더로 보낸다.
+	// 내부 구조가 드러나므로 운영에서는 켜지 않는다. 접근 로그에는 항상 남는다.
+	TimingHeader bool `json:"timing_header"`
+	// HookTimeout 은 서브시스템의 시작·준비·종료 훅 하나가 실행될 수 있는 시간이다.
+	HookTimeout Duration `json:"hook_timeout"`
+	// Listeners 는 Addr 외에 함께 띄울 리스너 목록이다. 설정 파일에서만 지정할 수 있다.
+	Listeners []ListenerConfig `json:"listeners"`
+}
+
+// ListenerConfig 는 추가 리스너 하나다. 타임아웃과 프로토콜은 주 리스너와 같다.
+type ListenerConfig struct {
+	// Name 은 로그에 쓰는 이름이다. 비어 있으면 Addr 를 쓴다.
+	Name string `json:"name"`
+	// Addr 는 ServerConfig.Addr 와 같은 형식이다.
+	Addr string `json:"addr"`
+	// TLS 이면 tls 절의 인증서로 HTTPS 를 서비스한다.
+	TLS bool `json:"tls"`
+	// Handler 는 "app" (기본값, 주 리스너와 같은 라우터) 또는 "admin" (관리 화면, 설정 다시 읽기,
+	// 디버그, 메트릭, 상태 검사만 있는 라우터) 이다. admin 리스너가 있으면 관리 경로는 그 리스너에만 있다.
+	Handler string `json:"handler"`
+	// Allow 가 비어 있지 않으면 이 리스너에서는 목록의 IP/CIDR 클라이언트만 받는다.
+	Allow []string `json:"allow"`
+}
+
+// AdminListener 는 관리 전용 리스너가 설정되었는지 확인한다.
+func (c ServerConfig) AdminListener() bool {
+	for _, l := range c.Listeners {
+		if l.Handler == "admin" {
+			return true
+		}
+	}
+	return false
+}
+
+// VHostsConfig 는 Host 헤더에 따른 라우트 트리 선택이다. Hosts 가 비어 있으면 모든 호스트가 주 라우터로 간다.
+type VHostsConfig struct {
+	// Hosts 는 설정 파일에서만 지정할 수 있다.
+	Hosts []VHost `json:"hosts"`
+	// Default 는 일치하는 호스트가 없을 때 쓸 Hosts 항목의 host 값이다. 비어 있으면 주 라우터가 처리한다.
+	Default string `json:"default"`
+}
+
+// VHost 는 호스트 하나와 그 호스트에서 서비스할 라우트 트리다.
+type VHost struct {
+	// Host 는 포트 없는 호스트 이름이나 "*.example.com" 같은 와일드카드다.
+	Host string `json:"host"`
+	// Handler 는 "app" (주 라우터), "admin" (관리 라우터), "static" (Dir 의 파일), "redirect" (RedirectTo 로 이동) 중 하나다.
+	Handler string `json:"handler"`
+	Dir     string `json:"dir"`
+	// RedirectTo 는 "https://www.example.com" 처럼 경로 없는 주소다. 요청 경로와 쿼리는 그대로 붙인다.
+	RedirectTo string `json:"redirect_to"`
+}
+
+// Uses 는 handler 를 쓰는 호스트가 있는지 확인한다.
+func (c VHostsConfig) Uses(handler string) bool {
+	for _, h := range c.Hosts {
+		if h.Handler == handler {
+			return true
+		}
+	}
+	return false
+}
+
+// TLSConfig 는 HTTPS 설정이다. 인증서 파일과 ACME 호스트가 모두 비어 있으면 평문 HTTP 로 동작한다.
+type TLSConfig struct {
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	// ACMEHosts 를 지정하면 Let's Encrypt 에서 인증서를 자동으로 발급·갱신한다.
+	ACMEHosts    []string `json:"acme_hosts"`
+	ACMECacheDir string   `json:"acme_cache_dir"`
+	ACMEEmail    string   `json:"acme_email"`
+	// RedirectAddr 이 비어 있지 않으면 이 주소에서 HTTP 요청을 HTTPS 로 리다이렉트한다.
+	RedirectAddr string `json:"redirect_addr"`
+	// HTTP3Addr 가 있으면 이 UDP 주소에서 HTTP/3 (QUIC) 도 서비스한다. (실험적)
+	// 보통 서버 주소와 같은 포트(":443")를 쓴다.
+	HTTP3Addr string `json:"http3_addr"`
+}
+
+// Enabled 는 TLS 인증서가 설정되었는지 확인한다.
+func (c TLSConfig) Enabled() bool { return c.CertFile != "" && c.KeyFile != "" }
+
+// ACME 는 자동 인증서 발급을 사용하는지 확인한다.
+func (c TLSConfig) ACME() bool { return len(c.ACMEHosts) > 0 }
+
+// LogConfig 는 로그 설정이다.
+type LogConfig struct {
+	// Level 은 debug, info, warn, error 중 하나다. SIGHUP 또는 PUT /api/admin/loglevel 로 실행 중에 바꿀 수 있다.
+	Level string `json:"level"`
+	// Format 은 "json", "console", "pretty"(개발용) 중 하나다.
+	Format string `json:"format"`
+	// Outputs 는 "stdout", "stderr", "file", "syslog" 중 하나 이상이다. 접근 로그도 같은 곳에 쓴다.
+	Outputs []string `json:"outputs"`
+	// File 은 file 출력 경로다. 파일이 FileMaxSizeMB 를 넘으면 회전하고,
+	// 회전된 파일은 FileMaxBackups 개, FileMaxAge 동안만 보관한다.
+	File           string   `json:"file"`
+	FileMaxSizeMB  int      `json:"file_max_size_mb"`
+	FileMaxAge     Duration `json:"file_max_age"`
+	FileMaxBackups int      `json:"file_max_backups"`
+	// SyslogAddr 가 비어 있으면 로컬 syslog 데몬에 보낸다. (예: network "udp", addr "logs:514")
+	SyslogNetwork string `json:"syslog_network"`
+	SyslogAddr    string `json:"syslog_addr"`
+	SyslogTag     string `json:"syslog_tag"`
+	// AccessSample 은 접근 로그에 남길 성공 응답의 비율(0~1)이다. 에러 응답은 항상 남긴다.
+	AccessSample float64 `json:"access_sample"`
+	// SlowRequest 보다 오래 걸린 요청은 헤더와 단계별 시간을 담아 warn 으로 남긴다. 0 이면 끈다.
+	SlowRequest Duration `json:"slow_request"`
+}
+
+// StaticConfig 는 /static/ 정적 파일 설정이다.
+type StaticConfig struct {
+	// Dir 이 비어 있으면 내장 자산(assets/)을 사용한다. 파일은 내용 해시가 붙은 이름(/static/app.3a7bd3e2360a.css)으로
+	// 1년 동안 캐시되고, 템플릿에서는 {{asset "app.css"}} 로 그 이름을 얻는다.
+	Dir string `json:"dir"`
+	// MaxAge 는 해시 없는 이름으로 요청한 파일의 캐시 시간이다.
+	MaxAge Duration `json:"max_age"`
+	// Preload 는 HTML 페이지 요청에 103 Early Hints 로 먼저 보낼 Link 헤더 값들이다. /static/ 경로는
+	// 해시가 붙은 이름으로 바뀐다. (예: "</static/app.css>; rel=preload; as=style")
+	Preload []string `json:"preload"`
+}
+
+// TemplatesConfig 는 HTML 템플릿 설정이다. Dir 이 비어 있으면 내장 템플릿을 사용한다.
+type TemplatesConfig struct {
+	Dir string `json:"dir"`
+	// Reload 가 true 이면 요청마다 템플릿을 다시 읽는다. (개발 모드)
+	Reload bool `json:"reload"`
+}
+
+// PagesConfig 는 파일 기반 페이지 설정이다. Dir 의 .md 와 .html 파일이 경로에 맞춰 페이지가 된다.
+// Dir 이 비어 있으면 비활성화된다.
+type PagesConfig struct {
+	Dir string `json:"dir"`
+	// Live 가 true 이면 요청마다 파일을 다시 읽고, 파일이 바뀌면 열린 페이지를 새로고침한다. (개발 모드)
+	// 레이아웃 변경도 바로 보려면 templates.reload 를 함께 켠다.
+	Live bool `json:"live"`
+}
+
+// MarkdownConfig 는 Markdown 렌더링의 확장 문법과 POST /api/markdown 설정이다.
+type MarkdownConfig struct {
+	// Tables 는 GitHub 형식의 표, Strikethrough 는 ~~취소선~~, Highlight 는 코드 블록 강조(hl-* 클래스)다.
+	Tables        bool `json:"tables"`
+	Strikethrough bool `json:"strikethrough"`
+	Highlight     bool `json:"highlight"`
+	// HTML 이 true 이면 사용자가 쓴 HTML 중 허용 목록의 태그를 남기고, false 이면 글자로 이스케이프한다.
+	// 파일 기반 페이지의 HTML 은 언제나 그대로 내보낸다.
+	HTML bool `json:"html"`
+	// MaxBytes 는 API 요청 본문의 최대 크기다.
+	MaxBytes int64 `json:"max_bytes"`
+}
+
+// FeedConfig 는 /feed.xml 과 /sitemap.xml 설정이다. 페이지(pages.dir)가 있을 때 켜진다.
+type FeedConfig struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Author      string `json:"author"`
+	// BaseURL 은 항목 주소의 앞부분이다. (예: https://example.com) 비어 있으면 요청의 Host 를 쓴다.
+	BaseURL string `json:"base_url"`
+	// Format 은 /feed.xml 의 형식이다. (atom, rss)
+	Format string `json:"format"`
+	// Limit 은 피드에 넣을 최근 글 수다.
+	Limit  int      `json:"limit"`
+	MaxAge Duration `json:"max_age"`
+}
+
+// WellKnownConfig 는 /robots.txt, /favicon.ico, /.well-known/ 설정이다.
+type WellKnownConfig struct {
+	// RobotsFile 이 있으면 그 파일을 /robots.txt 로 내보낸다. 없으면 RobotsDisallow 와 사이트맵 주소로 만든다.
+	RobotsFile     string   `json:"robots_file"`
+	RobotsDisallow []string `json:"robots_disallow"`
+	// FaviconFile 이 비어 있으면 /favicon.ico 는 204 다.
+	FaviconFile string `json:"favicon_file"`
+	// SecurityContact 가 있으면 /.well-known/security.txt 를 내보낸다. (예: "mailto:security@example.com")
+	SecurityContact []string `json:"security_contact"`
+	// SecurityExpires 는 security.txt 의 만료 시각(RFC 3339)이다. 비어 있으면 1년 뒤로 적는다.
+	SecurityExpires   string   `json:"security_expires"`
+	SecurityPolicy    string   `json:"security_policy"`
+	SecurityLanguages []string `json:"security_languages"`
+	// ACMEChallengeDir 은 외부 ACME 클라이언트(certbot --webroot 등)가 챌린지 파일을 쓰는 디렉터리다.
+	// tls.acme_hosts 의 자동 발급은 tls.redirect_addr 리스너에서 챌린지를 따로 처리한다.
+	ACMEChallengeDir string `json:"acme_challenge_dir"`
+	// ChangePasswordURL 은 /.well-known/change-password 가 보낼 주소다. 비어 있으면 로컬 계정이 켜져 있을 때
+	// /auth/forgot 이다.
+	ChangePasswordURL string   `json:"change_password_url"`
+	MaxAge            Duration `json:"max_age"`
+}
+
+// RealIPConfig 는 프록시 뒤에서 실제 클라이언트 IP 를 찾는 설정이다. 접근 로그, 요청 제한, ACL, 감사 로그가
+// 모두 이 주소를 쓴다.
+type RealIPConfig struct {
+	// TrustedProxies 는 전달 헤더를 믿을 수 있는 프록시의 CIDR 또는 IP 다. 비어 있으면 연결 주소만 쓴다.
+	TrustedProxies []string `json:"trusted_proxies"`
+	// ProxyDepth 는 클라이언트 앞에 있는 신뢰 프록시의 최대 개수다.
+	ProxyDepth int `json:"proxy_depth"`
+	// Headers 는 읽을 헤더(Forwarded, X-Forwarded-For, X-Real-IP)와 그 순서다. 비어 있으면 셋 다 이 순서로 읽는다.
+	Headers []string `json:"headers"`
+}
+
+// TenancyConfig 는 멀티 테넌시 설정이다. 켜면 세션, 요청 제한, 응답 캐시, Idempotency-Key 가 테넌트별로
+// 나뉘고, schema 나 dsn 이 있는 테넌트의 데이터는 그 데이터베이스에 있다. 테넌트 목록은 설정 파일에서만
+// 지정할 수 있고, 바꾸려면 재시작해야 한다.
+type TenancyConfig struct {
+	Enabled bool `json:"enabled"`
+	// Sources 는 테넌트를 찾는 방법(subdomain, header, path)과 순서다.
+	Sources []string `json:"sources"`
+	// Domain 이 "example.com" 이면 acme.example.com 은 테넌트 acme 다.
+	Domain string `json:"domain"`
+	// Header 는 header 방법에서 읽는 헤더다. 헤더를 덮어쓰는 게이트웨이 뒤에서만 쓴다.
+	Header string `json:"header"`
+	// PathPrefix 가 "/t/" 이면 /t/acme/hello 는 테넌트 acme 의 /hello 다.
+	PathPrefix string `json:"path_prefix"`
+	// Default 는 테넌트를 찾지 못한 요청의 테넌트다. 비어 있으면 Required 가 아닐 때 테넌트 없이 처리한다.
+	Default  string   `json:"default"`
+	Required bool     `json:"required"`
+	Tenants  []Tenant `json:"tenants"`
+}
+
+// GraphQLConfig 는 /graphql 설정이다. 데이터베이스가 있을 때 사용자 스키마를 제공한다.
+type GraphQLConfig struct {
+	Enabled bool `json:"enabled"`
+	// Playground 이면 브라우저로 GET /graphql 에 GraphiQL 을 보여준다. 개발 환경에서만 켠다.
+	Playground bool `json:"playground"`
+	// MaxDepth 는 쿼리의 최대 중첩 깊이다.
+	MaxDepth int `json:"max_depth"`
+	// MaxBytes 는 요청 본문의 최대 크기다.
+	MaxBytes int64 `json:"max_bytes"`
+}
+
+// GRPCConfig 는 사용자 gRPC 서비스(usersv1.UserService) 설정이다. 데이터베이스가 있을 때 주 리스너에서
+// HTTP/2 gRPC 요청을 받으므로 TLS 를 쓰지 않으면 server.h2c 를 켜야 한다.
+type GRPCConfig struct {
+	Enabled bool `json:"enabled"`
+	// Gateway 이면 서비스의 HTTP 규칙으로 /api/v2/users JSON 게이트웨이를 연다. h2c 없이도 동작한다.
+	Gateway bool `json:"gateway"`
+}
+
+// Tenant 는 테넌트 하나다. Schema 는 Postgres 스키마 이름이고(없으면 만든다), DSN 은 따로 쓰는 데이터베이스다.
+// 둘 다 비어 있으면 기본 데이터베이스를 같이 쓴다.
+type Tenant struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Hosts    []string          `json:"hosts"`
+	Schema   string            `json:"schema"`
+	DSN      string            `json:"dsn" secret:"true"`
+	Settings map[string]string `json:"settings"`
+}
+
+// SessionConfig 는 세션 쿠키와 저장소 설정이다.
+type SessionConfig struct {
+	CookieName string   `json:"cookie_name"`
+	TTL        Duration `json:"ttl"`
+	Secure     bool     `json:"secure"`
+	// Secret 이 비어 있으면 시작할 때마다 임의로 만들어지므로 재시작하면 세션이 사라진다.
+	Secret string `json:"secret" secret:"true"`
+	// OldSecrets 는 바꾸기 전의 secret 들이다. 키를 바꾼 뒤에도 이전 키로 만든 쿠키를 만료될 때까지 읽는다.
+	OldSecrets []string `json:"old_secrets" secret:"true"`
+	// Store 는 "memory" 또는 "redis" 다.
+	Store     string `json:"store"`
+	RedisAddr string `json:"redis_addr"`
+}
+
+// AuthConfig 는 JWT 인증 설정이다. 키는 "kid=값" 형식이다.
+type AuthConfig struct {
+	// HMACKeys 는 HS256 키 목록이다. RSA 개인 키가 없으면 첫 번째 키로 서명한다.
+	HMACKeys []string `json:"hmac_keys" secret:"true"`
+	// RSAPrivateKey 는 RS256 서명 키 PEM 파일이다. ("kid=path")
+	RSAPrivateKey string `json:"rsa_private_key"`
+	// RSAPublicKeys 는 검증 전용 RS256 공개 키 PEM 파일 목록이다.
+	RSAPublicKeys []string `json:"rsa_public_keys"`
+	Issuer        string   `json:"issuer"`
+	Audience      string   `json:"audience"`
+	TokenTTL      Duration `json:"token_ttl"`
+	// TokenEndpoint 가 true 이면 테스트용 POST /auth/token 을 등록한다.
+	TokenEndpoint bool `json:"token_endpoint"`
+}
+
+// OAuthConfig 는 OAuth2 로그인 공급자 설정이다. 클라이언트 ID 가 있는 공급자만 활성화된다.
+type OAuthConfig struct {
+	// RedirectURL 은 공급자에 등록한 콜백 주소다. (예: https://example.com/auth/callback)
+	RedirectURL        string `json:"redirect_url"`
+	GoogleClientID     string `json:"google_client_id"`
+	GoogleClientSecret string `json:"google_client_secret" secret:"true"`
+	GitHubClientID     string `json:"github_client_id"`
+	GitHubClientSecret string `json:"github_client_secret" secret:"true"`
+}
+
+// Enabled 는 공급자가 하나라도 설정되었는지 확인한다.
+func (c OAuthConfig) Enabled() bool { return c.GoogleClientID != "" || c.GitHubClientID != "" }
+
+// RateLimitConfig 는 클라이언트별 요청 제한 설정이다.
+type RateLimitConfig struct {
+	Enabled bool `json:"enabled"`
+	// Rate 는 초당 허용 요청 수, Burst 는 순간적으로 허용하는 최대 요청 수다.
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+	// KeyHeader 가 있으면 그 헤더(예: X-API-Key)의 API 키가 확인될 때 키 ID 로, 그 밖에는 클라이언트 IP 로 구분한다.
+	KeyHeader string `json:"key_header"`
+	// Backend 는 "memory" 또는 "redis" 다.
+	Backend   string `json:"backend"`
+	RedisAddr string `json:"redis_addr"`
+}
+
+// QuotaConfig 는 API 키별 일간·월간 요청 할당량 설정이다. 기간은 UTC 로 나누고, 키마다
+// PUT /api/admin/keys/{id}/quota 로 재정의할 수 있다. 키는 GET /api/usage 로 자기 사용량을 본다.
+type QuotaConfig struct {
+	Enabled bool `json:"enabled"`
+	// Backend 는 "memory", "redis", "db" 다. 인스턴스가 여럿이면 redis 나 db 를 쓴다.
+	Backend   string `json:"backend"`
+	RedisAddr string `json:"redis_addr"`
+	// Daily 와 Monthly 는 기본 할당량이다. 0 이면 제한하지 않고 사용량만 센다.
+	Daily   int64 `json:"daily"`
+	Monthly int64 `json:"monthly"`
+}
+
+// CORSConfig 는 전역 CORS 정책이다. AllowedOrigins 가 비어 있으면 비활성화된다.
+type CORSConfig struct {
+	AllowedOrigins   []string `json:"allowed_origins"`
+	AllowedMethods   []string `json:"allowed_methods"`
+	AllowedHeaders   []string `json:"allowed_headers"`
+	ExposedHeaders   []string `json:"exposed_headers"`
+	AllowCredentials bool     `json:"allow_credentials"`
+	MaxAge           Duration `json:"max_age"`
+}
+
+// CompressConfig 는 응답 압축(gzip/br) 설정이다.
+type CompressConfig struct {
+	Enabled      bool     `json:"enabled"`
+	MinSize      int      `json:"min_size"`
+	ContentTypes []string `json:"content_types"`
+}
+
+// UploadConfig 는 /upload 엔드포인트 설정이다. Dir 이 비어 있으면 비활성화된다.
+type UploadConfig struct {
+	Dir      string `json:"dir"`
+	MaxBytes int64  `json:"max_bytes"`
+	// ThumbnailSize 가 0 보다 크면 올라온 이미지의 썸네일을 백그라운드 작업으로 Dir/thumbs 에 만든다.
+	ThumbnailSize int `json:"thumbnail_size"`
+}
+
+// DownloadConfig 는 Range 를 지원하는 파일 다운로드 설정이다. Dir 이 있으면 /downloads/ 에서 서비스한다.
+type DownloadConfig struct {
+	Dir string `json:"dir"`
+	// BytesPerSecond 는 요청 하나의 전송 속도 제한이다. 0 이면 제한하지 않는다.
+	BytesPerSecond int64 `json:"bytes_per_second"`
+	// Routes 는 경로별로 다른 디렉터리나 속도 제한을 쓰는 추가 규칙이다. 설정 파일에서만 지정할 수 있다.
+	Routes []DownloadRoute `json:"routes"`
+}
+
+// DownloadRoute 는 Prefix 아래에서 Dir 의 파일을 내려주는 규칙이다.
+type DownloadRoute struct {
+	Prefix         string `json:"prefix"`
+	Dir            string `json:"dir"`
+	BytesPerSecond int64  `json:"bytes_per_second"`
+	Inline         bool   `json:"inline"`
+}
+
+// SecurityConfig 는 보안 헤더 설정이다. 빈 값은 기본값, "-" 는 헤더를 보내지 않음을 뜻한다.
+type SecurityConfig struct {
+	HSTS               string `json:"hsts"`
+	CSP                string `json:"csp"`
+	ContentTypeOptions string `json:"content_type_options"`
+	FrameOptions       string `json:"frame_options"`
+	ReferrerPolicy     string `json:"referrer_policy"`
+}
+
+// CSRFConfig 는 폼 POST 의 CSRF 보호 설정이다.
+type CSRFConfig struct {
+	Enabled bool `json:"enabled"`
+	// Storage 는 토큰을 둘 곳이다. "session" 은 세션에, "cookie" 는 session.secret 으로 서명한 쿠키에 둔다.
+	// (double submit cookie, 세션 저장소를 쓰지 않는다)
+	Storage string `json:"storage"`
+	// ExemptPaths 는 검사하지 않을 경로 접두사다.
+	// 기본값은 쿠키가 아니라 Authorization/X-API-Key 헤더로 인증하는 경로들이다.
+	ExemptPaths []string `json:"exempt_paths"`
+}
+
+// DatabaseConfig 는 데이터베이스 연결과 커넥션 풀 설정이다. Driver 가 비어 있으면 사용하지 않는다.
+type DatabaseConfig struct {
+	// Driver 는 "sqlite" 또는 "postgres" 다.
+	Driver string `json:"driver"`
+	// DSN 은 SQLite 파일 경로 또는 Postgres 연결 문자열이다.
+	DSN             string   `json:"dsn" secret:"true"`
+	MaxOpenConns    int      `json:"max_open_conns"`
+	MaxIdleConns    int      `json:"max_idle_conns"`
+	ConnMaxLifetime Duration `json:"conn_max_lifetime"`
+	ConnMaxIdleTime Duration `json:"conn_max_idle_time"`
+	// AutoMigrate 가 true 이면 시작할 때 적용되지 않은 마이그레이션을 적용한다.
+	AutoMigrate bool `json:"auto_migrate"`
+}
+
+// RedisConfig 는 redis 백엔드(session.store, rate_limit.backend, response_cache.backend,
+// idempotency.backend)가 함께 쓰는 연결 설정이다. 기능의 redis_addr 가 있으면 그 기능만 이 설정의
+// 인증과 TLS 로 그 주소에 따로 연결한다. 모든 백엔드가 memory 이면 연결하지 않는다.
+type RedisConfig struct {
+	// Addrs 는 "host:port" 목록이다. MasterName 이 있으면 sentinel 주소, Cluster 이면 클러스터 노드 주소다.
+	Addrs            []string `json:"addrs"`
+	MasterName       string   `json:"master_name"`
+	SentinelPassword string   `json:"sentinel_password" secret:"true"`
+	Cluster          bool     `json:"cluster"`
+	Username         string   `json:"username"`
+	Password         string   `json:"password" secret:"true"`
+	DB               int      `json:"db"`
+	// PoolSize 가 0 이면 go-redis 기본값(CPU 당 10개)이다.
+	PoolSize        int      `json:"pool_size"`
+	MinIdleConns    int      `json:"min_idle_conns"`
+	ConnMaxIdleTime Duration `json:"conn_max_idle_time"`
+	DialTimeout     Duration `json:"dial_timeout"`
+	ReadTimeout     Duration `json:"read_timeout"`
+	WriteTimeout    Duration `json:"write_timeout"`
+	// TLS 가 true 이면 TLS 로 연결한다. TLSCAFile 이 비어 있으면 시스템 인증서로 검증한다.
+	TLS           bool   `json:"tls"`
+	TLSCAFile     string `json:"tls_ca_file"`
+	TLSCertFile   string `json:"tls_cert_file"`
+	TLSKeyFile    string `json:"tls_key_file"`
+	TLSServerName string `json:"tls_server_name"`
+}
+
+// APIConfig 는 버전별 API 설정이다.
+// V1Deprecated 가 설정되면 /api/v1 응답에 Deprecation, Sunset, Link 헤더를 붙인다.
+type APIConfig struct {
+	// V1Deprecated 와 V1Sunset 은 "2006-01-02" 형식의 날짜다.
+	V1Deprecated string `json:"v1_deprecated"`
+	V1Sunset     string `json:"v1_sunset"`
+	// V1Successor 는 대체 API 의 URL 이다. (예: "/api/v2")
+	V1Successor string `json:"v1_successor"`
+}
+
+// V1Deprecation 은 v1 폐기 날짜와 제거 날짜를 돌려준다. 설정되지 않은 날짜는 0 이다.
+func (c APIConfig) V1Deprecation() (since, sunset time.Time, err error) {
+	if c.V1Deprecated != "" {
+		if since, err = time.Parse(time.DateOnly, c.V1Deprecated); err != nil {
+			return since, sunset, fmt.Errorf("api.v1_deprecated: %w", err)
+		}
+	}
+	if c.V1Sunset != "" {
+		if sunset, err = time.Parse(time.DateOnly, c.V1Sunset); err != nil {
+			return since, sunset, fmt.Errorf("api.v1_sunset: %w", err)
+		}
+	}
+	return since, sunset, nil
+}
+
+// ACLConfig 는 IP 접근 제어 설정이다. Allow 와 Deny 가 모두 비어 있으면 비활성화된다.
+type ACLConfig struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+	// TrustedProxies 와 ProxyDepth 는 real_ip 로 옮겼다. real_ip.trusted_proxies 가 비어 있을 때만 쓴다.
+	TrustedProxies []string `json:"trusted_proxies"`
+	ProxyDepth     int      `json:"proxy_depth"`
+}
+
+// Enabled 는 허용 또는 거부 목록이 있는지 확인한다.
+func (c ACLConfig) Enabled() bool { return len(c.Allow) > 0 || len(c.Deny) > 0 }
+
+// RBACConfig 는 역할별 권한 정의다. 각 항목은 "역할=권한 권한 ..." 이다.
+type RBACConfig struct {
+	Roles []string `json:"roles"`
+	// RequireMFA 의 역할을 가진 주체는 2단계 인증으로 로그인했어야 한다. (JWT amr 클레임에 "mfa" 또는 "otp")
+	// 아니면 역할 검사를 하는 경로에서 403 mfa_required 로 거부한다.
+	RequireMFA []string `json:"require_mfa"`
+}
+
+// AdminConfig 는 /admin 관리 화면 설정이다. 화면은 admin 역할만 볼 수 있다.
+type AdminConfig struct {
+	Enabled bool `json:"enabled"`
+	// RecentErrors 는 화면에 보관할 최근 5xx 응답 수다.
+	RecentErrors int `json:"recent_errors"`
+}
+
+// MaintenanceConfig 는 점검 모드 설정이다. 실행 중에는 PUT /api/admin/maintenance 로도 바꿀 수 있으며,
+// 설정을 다시 읽을 때는 Enabled 가 바뀐 경우에만 적용한다.
+type MaintenanceConfig struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"`
+	// RetryAfter 는 Retry-After 헤더로 알릴 예상 점검 시간이다.
+	RetryAfter Duration `json:"retry_after"`
+	// ExemptPaths 는 점검 중에도 서비스할 경로다. 하위 경로를 포함한다.
+	ExemptPaths []string `json:"exempt_paths"`
+}
+
+// ChaosConfig 는 장애 주입 설정이다. 개발·스테이징에서 회복력을 시험할 때만 켜고 운영에서는 켜지 않는다.
+// 켜져 있으면 실행 중에 PUT /api/admin/chaos 로 규칙을 바꿀 수 있다.
+type ChaosConfig struct {
+	Enabled bool        `json:"enabled"`
+	Rules   []ChaosRule `json:"rules"`
+	// ExemptPaths 에는 장애를 넣지 않는다. 하위 경로를 포함한다.
+	ExemptPaths []string `json:"exempt_paths"`
+}
+
+// ChaosRule 은 장애 주입 규칙이다. 메서드와 경로 접두사가 일치하는 요청 중 Percent 만큼에 장애를 넣는다.
+type ChaosRule struct {
+	Method  string  `json:"method"`
+	Path    string  `json:"path"`
+	Percent float64 `json:"percent"`
+	// Latency 에 0~Jitter 를 더한 만큼 기다린다.
+	Latency Duration `json:"latency"`
+	Jitter  Duration `json:"jitter"`
+	// Status 가 있으면 이 상태 코드로 에러 응답을 보내고, Drop 이면 응답 없이 연결을 끊는다.
+	Status int  `json:"status"`
+	Drop   bool `json:"drop"`
+	// BytesPerSecond 가 있으면 응답 본문을 이 속도로 보낸다.
+	BytesPerSecond int64 `json:"bytes_per_second"`
+}
+
+// CanaryConfig 는 카나리 배포의 트래픽 분할 설정이다. 가중치는 실행 중에 PUT /api/admin/canary/{name} 으로 바꾼다.
+type CanaryConfig struct {
+	// Splits 는 두 핸들러 구현 사이의 분할이다. 모듈이 이름으로 찾아 쓴다. 프록시 분할은 proxy.routes[].canary 에 둔다.
+	Splits []CanarySplit `json:"splits"`
+}
+
+// CanarySplit 은 요청의 Weight% 를 canary 쪽으로 보내는 분할이다.
+type CanarySplit struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+	// Cookie 는 고정 배정을 남길 쿠키 이름이고 Header 는 고정 키 헤더다. 비어 있으면 canary_<name>, X-Canary.
+	Cookie string `json:"cookie"`
+	Header string `json:"header"`
+}
+
+// DeadlineConfig 는 클라이언트가 보낸 남은 시간을 요청 기한으로 삼는 설정이다. 기한은 DB 조회와 외부 호출에도 적용된다.
+type DeadlineConfig struct {
+	Enabled bool `json:"enabled"`
+	// Header 는 남은 시간("1500" 밀리초 또는 "1.5s")을 받는 요청 헤더다. 외부 호출에도 같은 헤더로 남은 시간을 보낸다.
+	Header string `json:"header"`
+	// Default 는 헤더가 없는 요청의 기한이다. 0 이면 기한을 걸지 않는다.
+	Default Duration `json:"default"`
+	// Max 는 클라이언트가 요청할 수 있는 가장 긴 기한이다.
+	Max Duration `json:"max"`
+}
+
+// BotConfig 는 봇 점수와 확인 설정이다. 점수가 Threshold 이상인 클라이언트는 Paths 에 들어오기 전에
+// 작업 증명(또는 hCaptcha)을 풀어야 한다.
+type BotConfig struct {
+	Enabled bool `json:"enabled"`
+	// Paths 는 확인을 거쳐야 하는 비싼 경로다. 하위 경로를 포함한다.
+	Paths     []string `json:"paths"`
+	Threshold int      `json:"threshold"`
+	// Difficulty 는 작업 증명에서 0 이어야 하는 해시 앞 비트 수(1~32)다. 1 늘 때마다 풀이 시간이 두 배가 된다.
+	Difficulty int `json:"difficulty"`
+	// PassTTL 은 확인을 통과한 뒤 다시 묻지 않는 시간이다.
+	PassTTL Duration `json:"pass_ttl"`
+	// RateWindow 동안 한 IP 가 보호 경로에 RateLimit 번보다 많이 요청하면 점수를 더한다. 0 이면 세지 않는다.
+	RateWindow Duration `json:"rate_window"`
+	RateLimit  int      `json:"rate_limit"`
+	VerifyPath string   `json:"verify_path"`
+	// HCaptchaSiteKey 와 HCaptchaSecret 을 함께 두면 작업 증명 대신 hCaptcha 위젯을 보여 준다.
+	HCaptchaSiteKey string `json:"hcaptcha_site_key"`
+	HCaptchaSecret  string `json:"hcaptcha_secret" secret:"true"`
+}
+
+// SearchConfig 는 GET /api/v1/search 의 전문 검색 색인 설정이다. 사용자와 페이지를 색인한다.
+type SearchConfig struct {
+	Enabled bool `json:"enabled"`
+	// Backend 는 "memory"(시작할 때마다 다시 채움) 또는 "sqlite"(FTS5) 다.
+	Backend string `json:"backend"`
+	// DSN 은 sqlite 색인 파일이다. 비어 있으면 앱 DB(database.driver 가 sqlite 일 때)에 색인 테이블을 둔다.
+	DSN string `json:"dsn"`
+}
+
+// BenchConfig 는 "bench" 하위 명령의 부하 설정이다. 서버를 프로세스 안에서 띄우고 리스너 없이 핸들러에 요청을 넣는다.
+type BenchConfig struct {
+	Concurrency int      `json:"concurrency"`
+	Duration    Duration `json:"duration"`
+	// Warmup 동안 보낸 요청은 결과에 넣지 않는다.
+	Warmup Duration `json:"warmup"`
+	// Routes 는 "[METHOD ]PATH[ *WEIGHT]" 목록이다. (예: "GET /api/v1/users *5")
+	Routes []string `json:"routes"`
+	// Headers 는 모든 요청에 붙일 "Name: value" 목록이다. (예: "Authorization: Bearer ...")
+	Headers []string `json:"headers" secret:"true"`
+	// Output 이 있으면 결과를 JSON 으로 저장하고, Baseline 이 있으면 그 결과와 비교한다.
+	Output   string `json:"output"`
+	Baseline string `json:"baseline"`
+	// MaxRegression 은 Baseline 보다 p50 지연이나 요청당 할당이 이 비율(%)보다 더 나빠지면 명령을 실패시킨다.
+	// 0 이면 비교해서 보여 주기만 한다.
+	MaxRegression float64 `json:"max_regression"`
+}
+
+// JobsConfig 는 백그라운드 작업 큐 설정이다.
+type JobsConfig struct {
+	Concurrency int `json:"concurrency"`
+	QueueSize   int `json:"queue_size"`
+	// MaxAttempts 번 실패한 작업은 실패 목록(GET /api/admin/jobs)에 남는다.
+	MaxAttempts    int      `json:"max_attempts"`
+	RetryBaseDelay Duration `json:"retry_base_delay"`
+	RetryMaxDelay  Duration `json:"retry_max_delay"`
+	DeadLetters    int      `json:"dead_letters"`
+	// SpoolFile 이 있으면 종료 기한 안에 끝내지 못한 작업을 저장해 다음 시작 때 다시 처리한다.
+	SpoolFile string `json:"spool_file"`
+}
+
+// CronConfig 는 주기 작업의 실행 시각이다. cron 식("*/5 * * * *") 이나 "@every 1m" 을 쓰며, 비우면 그 작업을 끈다.
+// 최근 실행 상태는 GET /api/admin/cron 에서 볼 수 있다.
+type CronConfig struct {
+	// SessionCleanup 은 메모리 세션 저장소에서 만료된 세션을 지우는 주기다.
+	SessionCleanup string `json:"session_cleanup"`
+	// CacheWarmup 마다 WarmupPaths 를 내부에서 요청해 응답 캐시를 채운다.
+	CacheWarmup string   `json:"cache_warmup"`
+	WarmupPaths []string `json:"warmup_paths"`
+	// StatsRollup 마다 직전 구간의 요청 수·에러 수·평균 지연을 로그로 남긴다.
+	StatsRollup string `json:"stats_rollup"`
+	// TokenCleanup 마다 만료된 이메일 확인, 비밀번호 재설정 토큰을 지운다. local_auth 를 켰을 때만 쓴다.
+	TokenCleanup string `json:"token_cleanup"`
+	// QuotaCleanup 마다 지난달보다 오래된 할당량 사용량을 지운다. quota.backend 가 db 일 때만 쓴다.
+	QuotaCleanup string `json:"quota_cleanup"`
+	// Timeout 은 한 번의 실행 제한 시간이고, Jitter 는 실행 시각에 더하는 무작위 지연의 최댓값이다.
+	Timeout Duration `json:"timeout"`
+	Jitter  Duration `json:"jitter"`
+}
+
+// MailConfig 는 메일 발송 설정이다. Backend 는 "log"(보내지 않고 로그만), "smtp", "sendgrid", "ses" 중 하나다.
+type MailConfig struct {
+	Backend string `json:"backend"`
+	// From 은 기본 발신자다. (예: "Hello <noreply@example.com>")
+	From         string `json:"from"`
+	SMTPAddr     string `json:"smtp_addr"`
+	SMTPUsername string `json:"smtp_username"`
+	SMTPPassword string `json:"smtp_password" secret:"true"`
+	SendGridKey  string `json:"sendgrid_key" secret:"true"`
+	// SES 자격 증명이 비어 있으면 AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN 환경 변수를 쓴다.
+	SESRegion    string `json:"ses_region"`
+	SESAccessKey string `json:"ses_access_key"`
+	SESSecretKey string `json:"ses_secret_key" secret:"true"`
+	// Welcome 이면 사용자를 만들 때 환영 메일(templates/email_welcome.html)을 보낸다.
+	Welcome bool `json:"welcome"`
+}
+
+// LocalAuthConfig 는 이메일과 비밀번호 가입, 로그인 설정이다. 데이터베이스가 필요하다.
+type LocalAuthConfig struct {
+	Enabled bool `json:"enabled"`
+	// Hash 는 비밀번호 해시 방식이다. "argon2id" 또는 "bcrypt".
+	Hash              string `json:"hash"`
+	MinPasswordLength int    `json:"min_password_length"`
+	// RequireVerified 이면 메일의 확인 링크를 열어야 로그인할 수 있다.
+	RequireVerified bool `json:"require_verified"`
+	// LockAfter 번 연속으로 틀리면 LockFor 동안 로그인을 막는다. 0 이면 잠그지 않는다.
+	LockAfter int      `json:"lock_after"`
+	LockFor   Duration `json:"lock_for"`
+	// VerifyTTL, ResetTTL 은 확인 링크와 비밀번호 재설정 링크의 유효 기간이다.
+	VerifyTTL Duration `json:"verify_ttl"`
+	ResetTTL  Duration `json:"reset_ttl"`
+	// BaseURL 은 메일 링크의 앞부분이다. (예: https://example.com) 로컬 인증을 켜면 반드시 정해야 한다.
+	BaseURL string `json:"base_url"`
+	// TOTPIssuer 는 2단계 인증 앱에 표시되는 서비스 이름이다.
+	TOTPIssuer string `json:"totp_issuer"`
+}
+
+// AuditConfig 는 보안 감사 로그 설정이다. 이벤트는 GET /api/admin/audit 로 조회한다.
+type AuditConfig struct {
+	Enabled bool `json:"enabled"`
+	// Store 는 저장소다. "file" 은 File 에 JSON 한 줄씩 추가하고, "db" 는 audit_events 테이블에 쓴다.
+	Store string `json:"store"`
+	File  string `json:"file"`
+	// Sync 이면 파일 저장소가 이벤트마다 fsync 한다.
+	Sync bool `json:"sync"`
+}
+
+// CaptureConfig 는 디버깅용 요청·응답 기록 설정이다. 기록은 GET /api/admin/captures 로 보고,
+// "replay FILE TARGET" 명령으로 다른 서버에 다시 보낸다.
+type CaptureConfig struct {
+	Enabled bool `json:"enabled"`
+	// Paths 는 기록할 경로 접두사다. 비어 있으면 모든 요청을 기록한다.
+	Paths []string `json:"paths"`
+	// MaxBody 는 요청과 응답 본문을 각각 몇 바이트까지 남길지다.
+	MaxBody int `json:"max_body"`
+	// Buffer 는 메모리에 보관할 최근 기록 수다.
+	Buffer int `json:"buffer"`
+	// File 이 있으면 기록을 JSON 한 줄씩 이 파일에도 추가한다.
+	File string `json:"file"`
+	// RedactHeaders, RedactFields 는 인증 헤더와 비밀번호·토큰 필드 외에 더 가릴 헤더와 본문 필드다.
+	RedactHeaders []string `json:"redact_headers"`
+	RedactFields  []string `json:"redact_fields"`
+}
+
+// MockConfig 는 개발용 가짜 응답 설정이다. File 이 있으면 픽스처에 선언한 라우트가 실제 핸들러와 프록시보다
+// 먼저 미리 정한 응답을 돌려준다. 설정을 다시 읽으면 픽스처도 다시 읽는다. 운영에서는 켜지 않는다.
+type MockConfig struct {
+	File string `json:"file"`
+	// Latency 는 latency 를 정하지 않은 라우트가 응답 전에 기다리는 시간이다.
+	Latency Duration `json:"latency"`
+}
+
+// ShedConfig 는 동시 처리 요청 수 제한(load shedding) 설정이다. 자리가 없는 요청은 QueueTimeout 동안 기다리고,
+// 그래도 자리가 나지 않으면 503 과 Retry-After 로 거절한다. 재시작해야 바뀐다.
+type ShedConfig struct {
+	Enabled bool `json:"enabled"`
+	// MaxInFlight 는 전역 한도다. 0 이면 라우트별 한도만 건다.
+	MaxInFlight int `json:"max_in_flight"`
+	// MinInFlight 는 TargetLatency 로 한도를 줄일 때의 하한이다.
+	MinInFlight int `json:"min_in_flight"`
+	// Queue 는 한도마다 기다릴 수 있는 요청 수다.
+	Queue        int      `json:"queue"`
+	QueueTimeout Duration `json:"queue_timeout"`
+	// TargetLatency 가 있으면 응답 시간이 이보다 길어질 때 한도를 줄이고, 짧으면 MaxInFlight 까지 늘린다.
+	TargetLatency Duration `json:"target_latency"`
+	// Routes 는 "패턴=한도" 또는 "METHOD 패턴=한도" 형식의 라우트별 한도다. (예: "POST /api/export=4")
+	Routes []string `json:"routes"`
+	// ExemptPaths 는 제한하지 않는 경로 접두사다.
+	ExemptPaths []string `json:"exempt_paths"`
+}
+
+// IdempotencyConfig 는 API 의 POST/PATCH 요청에 붙은 Idempotency-Key 처리 설정이다.
+type IdempotencyConfig struct {
+	Enabled bool `json:"enabled"`
+	// TTL 은 첫 응답을 보관해 재시도에 돌려주는 시간이다.
+	TTL Duration `json:"ttl"`
+	// Backend 는 "memory" 또는 "redis" 다. 인스턴스가 여럿이면 redis 를 쓴다.
+	Backend   string `json:"backend"`
+	RedisAddr string `json:"redis_addr"`
+	// MaxBody 보다 큰 응답은 보관하지 않는다.
+	MaxBody int `json:"max_body"`
+}
+
+// WebhooksConfig 는 이벤트를 보낼 웹훅 구독자다. 구독자 목록은 설정 파일에서만 지정할 수 있고,
+// 실행 중에는 /api/admin/webhooks 로 추가·삭제한다. (재시작하면 설정 파일의 목록으로 돌아간다)
+type WebhooksConfig struct {
+	Subscribers []WebhookSubscriber `json:"subscribers"`
+	// Timeout 은 발송 요청 하나의 제한 시간이다. 실패한 발송은 jobs 설정에 따라 다시 시도한다.
+	Timeout Duration `json:"timeout"`
+	// History 는 GET /api/admin/webhooks/deliveries 에 보관할 발송 기록 수다.
+	History int `json:"history"`
+	// Receivers 는 POST /webhooks/{name} 으로 받을 공급자다. 설정 파일에서만 지정할 수 있다.
+	Receivers []WebhookReceiver `json:"receivers"`
+	// Tolerance 는 서명 시각과 서버 시각의 최대 차이다. 이보다 오래된 요청은 재전송으로 보고 거절한다.
+	Tolerance Duration `json:"tolerance"`
+}
+
+// WebhookReceiver 는 받을 웹훅 공급자 하나다. Type 은 서명 형식으로 "github", "stripe", "signed"(이 서버의 발송 형식) 중 하나다.
+type WebhookReceiver struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Secret string `json:"secret" secret:"true"`
+}
+
+// WebhookSubscriber 는 구독자 하나다. Events 가 비어 있으면 모든 이벤트를 받고, "user.*" 처럼 접두사로 고를 수 있다.
+type WebhookSubscriber struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret" secret:"true"`
+	Events []string `json:"events"`
+}
+
+// FlagsConfig 는 기능 플래그를 읽어 올 곳이다. 파일 → 원격 → 환경 변수 순서로 합치며 뒤의 것이 이긴다.
+type FlagsConfig struct {
+	// File 은 {"이름": {"enabled": true, "percentage": 25, "users": [...]}} 형식의 JSON 파일이다.
+	File string `json:"file"`
+	// RemoteURL 은 같은 형식의 JSON 을 돌려주는 주소다.
+	RemoteURL string `json:"remote_url"`
+	// Env 이면 APP_FLAG_이름=on|off|25% 환경 변수도 읽는다.
+	Env bool `json:"env"`
+	// RefreshInterval 마다 파일과 원격 주소를 다시 읽는다.
+	RefreshInterval Duration `json:"refresh_interval"`
+}
+
+// DebugConfig 는 /debug/ 의 pprof·expvar 엔드포인트 설정이다. 켜면 admin 역할만 볼 수 있다.
+type DebugConfig struct {
+	Enabled bool `json:"enabled"`
+	// Addr 가 있으면 공개 리스너 대신 이 주소(예: "127.0.0.1:6060")에서만 서비스한다.
+	// 루프백 주소만 허용한다.
+	Addr string `json:"addr"`
+}
+
+// TracingConfig 는 OpenTelemetry 추적 설정이다. Exporter 가 비어 있으면 trace context 전파만 한다.
+type TracingConfig struct {
+	// Exporter 는 "otlp", "jaeger", "stdout" 중 하나다.
+	Exporter string `json:"exporter"`
+	// Endpoint 는 OTLP/HTTP 수집기 주소다. 비어 있으면 http://localhost:4318.
+	Endpoint string `json:"endpoint"`
+	// Headers 는 수집기에 보낼 "이름=값" 헤더 목록이다.
+	Headers     []string `json:"headers" secret:"true"`
+	ServiceName string   `json:"service_name"`
+	// SampleRatio 는 새 추적을 기록할 비율(0~1)이다.
+	SampleRatio float64 `json:"sample_ratio"`
+}
+
+// ProxyConfig 는 리버스 프록시 설정이다. Routes 가 비어 있으면 비활성화된다.
+type ProxyConfig struct {
+	// Routes 는 설정 파일에서만 지정할 수 있다.
+	Routes                []ProxyRoute `json:"routes"`
+	MaxIdleConnsPerHost   int          `json:"max_idle_conns_per_host"`
+	IdleConnTimeout       Duration     `json:"idle_conn_timeout"`
+	DialTimeout           Duration     `json:"dial_timeout"`
+	ResponseHeaderTimeout Duration     `json:"response_header_timeout"`
+}
+
+// ProxyRoute 는 접두사나 호스트 하나를 백엔드로 보내는 규칙이다.
+type ProxyRoute struct {
+	Host      string   `json:"host"`
+	Prefix    string   `json:"prefix"`
+	Upstream  string   `json:"upstream"`
+	Upstreams []string `json:"upstreams"`
+	// Balance 는 "round_robin" 또는 "least_conn" 이다.
+	Balance         string            `json:"balance"`
+	HealthCheck     *ProxyHealthCheck `json:"health_check"`
+	StripPrefix     bool              `json:"strip_prefix"`
+	SetHeaders      map[string]string `json:"set_headers"`
+	RemoveHeaders   []string          `json:"remove_headers"`
+	ResponseHeaders map[string]string `json:"response_headers"`
+	MaxBodyBytes    int64             `json:"max_body_bytes"`
+	Canary          *ProxyCanary      `json:"canary"`
+	Transform       *ProxyTransform   `json:"transform"`
+}
+
+// ProxyTransform 은 프록시 응답 변환이다. 본문은 스트리밍으로 바꾼다.
+type ProxyTransform struct {
+	// Headers 는 응답 헤더 값의 문자열 치환이다.
+	Headers []ProxyHeaderRewrite `json:"headers"`
+	// Links 는 HTML 응답의 href, src 등이 from 으로 시작하면 to 로 바꾼다.
+	Links []ProxyRewrite `json:"links"`
+	// RedactFields 는 JSON 응답에서 값을 가릴 필드 이름이다. 깊이와 관계없이 적용한다.
+	RedactFields []string `json:"redact_fields"`
+}
+
+// ProxyHeaderRewrite 는 응답 헤더 Header 의 값에서 From 을 To 로 바꾼다.
+type ProxyHeaderRewrite struct {
+	Header string `json:"header"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// ProxyRewrite 는 From 으로 시작하는 값을 To 로 시작하도록 바꾼다.
+type ProxyRewrite struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ProxyCanary 는 프록시 규칙의 카나리 백엔드다. 요청의 Weight% 를 Upstreams 로 보낸다.
+// Split 은 가중치를 바꿀 때 쓰는 이름이다. 비어 있으면 호스트와 접두사를 이은 값이다.
+type ProxyCanary struct {
+	Split     string   `json:"split"`
+	Upstreams []string `json:"upstreams"`
+	Weight    int      `json:"weight"`
+}
+
+// ProxyHealthCheck 는 백엔드 상태 검사 설정이다. 0 인 값은 기본값을 쓴다.
+type ProxyHealthCheck struct {
+	Path               string   `json:"path"`
+	Interval           Duration `json:"interval"`
+	Timeout            Duration `json:"timeout"`
+	UnhealthyThreshold int      `json:"unhealthy_threshold"`
+	HealthyThreshold   int      `json:"healthy_threshold"`
+}
+
+// ClientConfig 는 프록시와 OAuth 등 외부 호출에 쓰는 HTTP 클라이언트 설정이다.
+type ClientConfig struct {
+	// Timeout 은 재시도를 포함한 요청 하나의 전체 시간 제한이다. (프록시에는 적용하지 않는다)
+	Timeout Duration `json:"timeout"`
+	// RetryMax 는 멱등 요청의 최대 재시도 횟수다. 0 이면 재시도하지 않는다.
+	RetryMax       int      `json:"retry_max"`
+	RetryBaseDelay Duration `json:"retry_base_delay"`
+	RetryMaxDelay  Duration `json:"retry_max_delay"`
+	// BreakerFailures 번 연속 실패하면 해당 호스트로의 호출을 BreakerOpenTimeout 동안 막는다.
+	// 0 이면 차단기를 쓰지 않는다.
+	BreakerFailures         int      `json:"breaker_failures"`
+	BreakerOpenTimeout      Duration `json:"breaker_open_timeout"`
+	BreakerHalfOpenRequests int      `json:"breaker_half_open_requests"`
+}
+
+// ResponseCacheConfig 는 라우트별 응답 캐시 설정이다. 라우트마다의 TTL 은 코드에서 정한다.
+type ResponseCacheConfig struct {
+	Enabled bool `json:"enabled"`
+	// Backend 는 "memory" 또는 "redis" 다.
+	Backend   string `json:"backend"`
+	RedisAddr string `json:"redis_addr"`
+	// MaxBytes 는 memory 백엔드의 전체 크기 제한이다.
+	MaxBytes int64 `json:"max_bytes"`
+	// MaxEntryBytes 보다 큰 응답은 캐시하지 않는다.
+	MaxEntryBytes int `json:"max_entry_bytes"`
+}
+
+// Default 는 기본 설정을 반환한다.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:                 ":8080",
+			DrainTimeout:         Duration(15 * time.Second),
+			RealtimeDrainTimeout: Duration(5 * time.Second),
+			HookTimeout:          Duration(10 * time.Second),
+			ReadHeaderTimeout:    Duration(5 * time.Second),
+			ReadTimeout:          Duration(30 * time.Second),
+			WriteTimeout:         Duration(60 * time.Second),
+			IdleTimeout:          Duration(120 * time.Second),
+			HandlerTimeout:       Duration(30 * time.Second),
+			MaxBodyBytes:         1 << 20,
+			MaxHeaderBytes:       1 << 20,
+			KeepAlives:           true,
+			TCPNoDelay:           true,
+			HTTP2:                true,
+		},
+		TLS: TLSConfig{ACMECacheDir: "acme-cache"},
+		Log: LogConfig{
+			Level:          "info",
+			Format:         "json",
+			Outputs:        []string{"stdout"},
+			FileMaxSizeMB:  100,
+			FileMaxAge:     Duration(7 * 24 * time.Hour),
+			FileMaxBackups: 7,
+			SyslogTag:      "hello-server",
+			AccessSample:   1,
+			SlowRequest:    Duration(2 * time.Second),
+		},
+		Static:        StaticConfig{MaxAge: Duration(time.Hour)},
+		Session:       SessionConfig{CookieName: "session", TTL: Duration(24 * time.Hour), Store: "memory"},
+		Auth:          AuthConfig{TokenTTL: Duration(time.Hour)},
+		RateLimit:     RateLimitConfig{Rate: 10, Burst: 20, Backend: "memory"},
+		Quota:         QuotaConfig{Backend: "memory"},
+		Compress:      CompressConfig{Enabled: true, MinSize: 1024},
+		Upload:        UploadConfig{MaxBytes: 100 << 20},
+		ACL:           ACLConfig{ProxyDepth: 1},
+		RBAC:          RBACConfig{Roles: []string{"admin=*", "editor=users:read users:write", "viewer=users:read"}},
+		Admin:         AdminConfig{Enabled: true, RecentErrors: 50},
+		Tracing:       TracingConfig{ServiceName: "hello-server", SampleRatio: 1},
+		ResponseCache: ResponseCacheConfig{Enabled: true, Backend: "memory", MaxBytes: 64 << 20, MaxEntryBytes: 1 << 20},
+		Flags:         FlagsConfig{Env: true, RefreshInterval: Duration(30 * time.Second)},
+		Mail:          MailConfig{Backend: "log", From: "hello-server <noreply@localhost>", Welcome: true},
+		LocalAuth: LocalAuthConfig{
+			Hash: "argon2id", MinPasswordLength: 10, RequireVerified: true, LockAfter: 5, TOTPIssuer: "hello-server",
+			LockFor: Duration(15 * time.Minute), VerifyTTL: Duration(24 * time.Hour), ResetTTL: Duration(time.Hour),
+		},
+		Audit:   AuditConfig{Store: "file", File: "audit.log"},
+		Capture: CaptureConfig{MaxBody: 64 << 10, Buffer: 100},
+		Shed: ShedConfig{
+			MaxInFlight: 512, MinInFlight: 16, Queue: 128, QueueTimeout: Duration(500 * time.Millisecond),
+			ExemptPaths: []string{"/healthz", "/readyz", "/livez", "/metrics"},
+		},
+		Idempotency: IdempotencyConfig{Enabled: true, TTL: Duration(24 * time.Hour), Backend: "memory", MaxBody: 1 << 20},
+		Markdown:    MarkdownConfig{Tables: true, Strikethrough: true, Highlight: true, HTML: true, MaxBytes: 256 << 10},
+		Feed:        FeedConfig{Title: "hello server", Format: "atom", Limit: 20, MaxAge: Duration(time.Hour)},
+		WellKnown:   WellKnownConfig{RobotsDisallow: []string{"/admin/", "/api/", "/auth/"}, MaxAge: Duration(24 * time.Hour)},
+		RealIP:      RealIPConfig{ProxyDepth: 1},
+		Tenancy:     TenancyConfig{Sources: []string{"subdomain"}, Header: "X-Tenant-ID", PathPrefix: "/t/"},
+		GraphQL:     GraphQLConfig{Enabled: true, MaxDepth: 10, MaxBytes: 1 << 20},
+		GRPC:        GRPCConfig{Gateway: true},
+		Dev: DevConfig{
+			Build:    []string{"go", "build", "-o", ".dev/server", "web_server.go"},
+			Binary:   ".dev/server",
+			Dirs:     []string{"."},
+			Exts:     []string{".go", ".html", ".json", ".sql", ".css", ".js"},
+			Interval: Duration(500 * time.Millisecond),
+		},
+		Webhooks: WebhooksConfig{Timeout: Duration(10 * time.Second), History: 200, Tolerance: Duration(5 * time.Minute)},
+		Cron: CronConfig{
+			SessionCleanup: "@every 1m",
+			WarmupPaths:    []string{"/"},
+			StatsRollup:    "@hourly",
+			TokenCleanup:   "@hourly",
+			QuotaCleanup:   "@daily",
+			Timeout:        Duration(time.Minute),
+		},
+		Jobs: JobsConfig{
+			Concurrency:    4,
+			QueueSize:      1000,
+			MaxAttempts:    5,
+			RetryBaseDelay: Duration(time.Second),
+			RetryMaxDelay:  Duration(5 * time.Minute),
+			DeadLetters:    100,
+		},
+		Maintenance: MaintenanceConfig{
+			RetryAfter:  Duration(5 * time.Minute),
+			ExemptPaths: []string{"/healthz", "/readyz", "/livez", "/metrics", "/admin", "/api/admin", "/auth", "/static"},
+		},
+		Deadline: DeadlineConfig{
+			Header: "X-Request-Timeout",
+			Max:    Duration(time.Minute),
+		},
+		Search: SearchConfig{Backend: "memory"},
+		Bench: BenchConfig{
+			Concurrency: 8,
+			Duration:    Duration(10 * time.Second),
+			Warmup:      Duration(time.Second),
+			Routes:      []string{"/healthz", "/"},
+		},
+		Bot: BotConfig{
+			Threshold:  50,
+			Difficulty: 16,
+			PassTTL:    Duration(time.Hour),
+			RateWindow: Duration(10 * time.Second),
+			RateLimit:  30,
+			VerifyPath: "/_bot/verify",
+		},
+		Chaos: ChaosConfig{
+			ExemptPaths: []string{"/healthz", "/readyz", "/livez", "/metrics", "/admin", "/api/admin"},
+		},
+		Client: ClientConfig{
+			Timeout:                 Duration(30 * time.Second),
+			RetryMax:                2,
+			RetryBaseDelay:          Duration(100 * time.Millisecond),
+			RetryMaxDelay:           Duration(2 * time.Second),
+			BreakerFailures:         5,
+			BreakerOpenTimeout:      Duration(30 * time.Second),
+			BreakerHalfOpenRequests: 1,
+		},
+		Proxy: ProxyConfig{
+			MaxIdleConnsPerHost:   32,
+			IdleConnTimeout:       Duration(90 * time.Second),
+			DialTimeout:           Duration(5 * time.Second),
+			ResponseHeaderTimeout: Duration(30 * time.Second),
+		},
+		CSRF: CSRFConfig{Enabled: true, Storage: "session", ExemptPaths: []string{"/api/", "/admin/", "/auth/token", "/upload", "/webhooks/", "/graphql"}},
+		Database: DatabaseConfig{
+			Driver:          "sqlite",
+			DSN:             "app.db",
+			MaxIdleConns:    2,
+			ConnMaxLifetime: Duration(30 * time.Minute),
+			ConnMaxIdleTime: Duration(5 * time.Minute),
+			AutoMigrate:     true,
+		},
+	}
+}
+
+// Load 는 args(보통 os.Args[1:])와 환경 변수를 읽어 설정을 만든다.
+// 설정 파일 경로는 -config 플래그 또는 APP_CONFIG 환경 변수로 지정한다.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv(EnvPrefix+"CONFIG"), "path to JSON config file")
+	// 플래그 값은 파일과 환경 변수를 적용한 뒤에 덮어써야 하므로 일단 모아 둔다.
+	set := map[string]string{}
+	for _, f := range walk(Default()) {
+		name := f.flag
+		fs.Func(name, fmt.Sprintf("%s (env %s, default %q)", f.path, f.env, f.String()), func(s string) error {
+			set[name] = s
+			return nil
+		})
+	}
+	fs.BoolFunc("dev", "development mode (same as -dev.enabled true)", func(s string) error {
+		set["dev.enabled"] = s
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if err := apply(cfg, *path, set); err != nil {
+		return nil, err
+	}
+	if cfg.Dev.Enabled {
+		// 명시한 값이 이기도록 개발 모드 기본값 위에 같은 설정을 다시 적용한다.
+		cfg = Default()
+		cfg.devDefaults()
+		if err := apply(cfg, *path, set); err != nil {
+			return nil, err
+		}
+	}
+	cfg.file = *path
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// apply 는 설정 파일, 환경 변수, 플래그 값(set)을 차례로 cfg 에 덮어쓴다.
+func apply(cfg *Config, path string, set map[string]string) error {
+	if path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return err
+		}
+	}
+	for _, f := range walk(cfg) {
+		if v, ok := os.LookupEnv(f.env); ok {
+			if err := f.Set(v); err != nil {
+				return fmt.Errorf("config: %s: %w", f.env, err)
+			}
+		}
+		if v, ok := set[f.flag]; ok {
+			if err := f.Set(v); err != nil {
+				return fmt.Errorf("config: -%s: %w", f.flag, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadFile 은 JSON 설정 파일을 cfg 위에 덮어쓴다. 알 수 없는 키는 오류로 처리한다.
+func loadFile(cfg *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate 는 설정 값의 일관성을 검사한다.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr is required"))
+	}
+	if c.Server.DrainTimeout <= 0 {
+		errs = append(errs, errors.New("server.drain_timeout must be positive"))
+	}
+	if c.Server.RealtimeDrainTimeout <= 0 || c.Server.RealtimeDrainTimeout > c.Server.DrainTimeout {
+		errs = append(errs, errors.New("server.realtime_drain_timeout must be positive and not exceed server.drain_timeout"))
+	}
+	if c.Server.HookTimeout <= 0 {
+		errs = append(errs, errors.New("server.hook_timeout must be positive"))
+	}
+	if c.Server.ReadHeaderTimeout < 0 || c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 ||
+		c.Server.IdleTimeout < 0 || c.Server.HandlerTimeout < 0 {
+		errs = append(errs, errors.New("server timeouts must not be negative"))
+	}
+	if c.Server.MaxBodyBytes <= 0 || c.Upload.MaxBytes <= 0 {
+		errs = append(errs, errors.New("server.max_body_bytes and upload.max_bytes must be positive"))
+	}
+	if c.Server.MaxHeaderBytes < 0 || c.Server.SocketReadBuffer < 0 || c.Server.SocketWriteBuffer < 0 ||
+		c.Server.ListenBacklog < 0 || c.Server.MaxConnections < 0 {
+		errs = append(errs, errors.New("server socket sizes, listen_backlog and max_connections must not be negative"))
+	}
+	if c.Server.WriteTimeout > 0 && c.Server.HandlerTimeout > c.Server.WriteTimeout {
+		errs = append(errs, errors.New("server.handler_timeout must not exceed server.write_timeout"))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls.cert_file and tls.key_file must be set together"))
+	}
+	if c.TLS.Enabled() && c.TLS.ACME() {
+		errs = append(errs, errors.New("tls.cert_file and tls.acme_hosts are mutually exclusive"))
+	}
+	if c.TLS.RedirectAddr != "" && !c.TLS.Enabled() && !c.TLS.ACME() {
+		errs = append(errs, errors.New("tls.redirect_addr requires TLS to be enabled"))
+	}
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level %q is not one of debug, info, warn, error", c.Log.Level))
+	}
+	switch c.Log.Format {
+	case "json", "console", "pretty":
+	default:
+		errs = append(errs, fmt.Errorf("log.format %q is not one of json, console, pretty", c.Log.Format))
+	}
+	for _, o := range c.Log.Outputs {
+		switch o {
+		case "stdout", "stderr", "syslog":
+		case "file":
+			if c.Log.File == "" {
+				errs = append(errs, errors.New("log.file is required for the file output"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("log.outputs %q is not one of stdout, stderr, file, syslog", o))
+		}
+	}
+	if c.Log.FileMaxSizeMB < 0 || c.Log.FileMaxBackups < 0 || c.Log.FileMaxAge < 0 {
+		errs = append(errs, errors.New("log file rotation limits must not be negative"))
+	}
+	if c.Log.AccessSample < 0 || c.Log.AccessSample > 1 {
+		errs = append(errs, errors.New("log.access_sample must be between 0 and 1"))
+	}
+	if c.Log.SlowRequest < 0 {
+		errs = append(errs, errors.New("log.slow_request must not be negative"))
+	}
+	switch c.Session.Store {
+	case "memory":
+	case "redis":
+		if c.Session.RedisAddr == "" && len(c.Redis.Addrs) == 0 {
+			errs = append(errs, errors.New("session.redis_addr or redis.addrs is required for the redis store"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("session.store %q is not one of memory, redis", c.Session.Store))
+	}
+	if c.Session.Secret != "" && len(c.Session.Secret) < 16 {
+		errs = append(errs, errors.New("session.secret must be at least 16 bytes"))
+	}
+	for i, old := range c.Session.OldSecrets {
+		if len(old) < 16 {
+			errs = append(errs, fmt.Errorf("session.old_secrets[%d] must be at least 16 bytes", i))
+		}
+	}
+	if s := c.CSRF.Storage; s != "session" && s != "cookie" {
+		errs = append(errs, fmt.Errorf("csrf.storage %q is not one of session, cookie", s))
+	}
+	for _, kv := range append(append([]string{c.Auth.RSAPrivateKey}, c.Auth.HMACKeys...), c.Auth.RSAPublicKeys...) {
+		if kv != "" && !strings.Contains(kv, "=") {
+			errs = append(errs, fmt.Errorf("auth key %q must have the form kid=value", kv))
+		}
+	}
+	if c.OAuth.Enabled() && c.OAuth.RedirectURL == "" {
+		errs = append(errs, errors.New("oauth.redirect_url is required when a provider is configured"))
+	}
+	if c.RateLimit.Enabled {
+		if c.RateLimit.Rate <= 0 || c.RateLimit.Burst < 1 {
+			errs = append(errs, errors.New("rate_limit.rate and rate_limit.burst must be positive"))
+		}
+		switch c.RateLimit.Backend {
+		case "memory":
+		case "redis":
+			if c.RateLimit.RedisAddr == "" && len(c.Redis.Addrs) == 0 {
+				errs = append(errs, errors.New("rate_limit.redis_addr or redis.addrs is required for the redis backend"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("rate_limit.backend %q is not one of memory, redis", c.RateLimit.Backend))
+		}
+	}
+	if q := c.Quota; q.Enabled {
+		if q.Daily < 0 || q.Monthly < 0 {
+			errs = append(errs, errors.New("quota.daily and quota.monthly must not be negative"))
+		}
+		switch q.Backend {
+		case "memory":
+		case "redis":
+			if q.RedisAddr == "" && len(c.Redis.Addrs) == 0 {
+				errs = append(errs, errors.New("quota.redis_addr or redis.addrs is required for the redis backend"))
+			}
+		case "db":
+			if c.Database.Driver == "" {
+				errs = append(errs, errors.New("quota.backend db requires database.driver"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("quota.backend %q is not one of memory, redis, db", q.Backend))
+		}
+	}
+	if c.ResponseCache.Enabled {
+		switch c.ResponseCache.Backend {
+		case "memory":
+			if c.ResponseCache.MaxBytes <= 0 {
+				errs = append(errs, errors.New("response_cache.max_bytes must be positive"))
+			}
+		case "redis":
+			if c.ResponseCache.RedisAddr == "" && len(c.Redis.Addrs) == 0 {
+				errs = append(errs, errors.New("response_cache.redis_addr or redis.addrs is required for the redis backend"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("response_cache.backend %q is not one of memory, redis", c.ResponseCache.Backend))
+		}
+	}
+	switch c.Database.Driver {
+	case "":
+	case "sqlite", "postgres":
+		if c.Database.DSN == "" {
+			errs = append(errs, errors.New("database.dsn is required"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("database.driver %q is not one of sqlite, postgres", c.Database.Driver))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("database pool sizes must not be negative"))
+	}
+	if d := c.Dev; d.Watch && (len(d.Build) == 0 || d.Binary == "" || d.Interval <= 0) {
+		errs = append(errs, errors.New("dev.watch requires dev.build, dev.binary and a positive dev.interval"))
+	}
+	if r := c.Redis; len(r.Addrs) > 0 {
+		switch {
+		case r.Cluster && r.MasterName != "":
+			errs = append(errs, errors.New("redis.cluster and redis.master_name are mutually exclusive"))
+		case len(r.Addrs) > 1 && !r.Cluster && r.MasterName == "":
+			errs = append(errs, errors.New("redis.addrs has several addresses; set redis.cluster or redis.master_name"))
+		}
+		if r.PoolSize < 0 || r.MinIdleConns < 0 || r.DB < 0 {
+			errs = append(errs, errors.New("redis.pool_size, redis.min_idle_conns and redis.db must not be negative"))
+		}
+		if (r.TLSCertFile == "") != (r.TLSKeyFile == "") {
+			errs = append(errs, errors.New("redis.tls_cert_file and redis.tls_key_file must be set together"))
+		}
+	}
+	if _, _, err := c.API.V1Deprecation(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.API.V1Deprecated == "" && (c.API.V1Sunset != "" || c.API.V1Successor != "") {
+		errs = append(errs, errors.New("api.v1_sunset and api.v1_successor require api.v1_deprecated"))
+	}
+	if c.Admin.Enabled && c.Admin.RecentErrors <= 0 {
+		errs = append(errs, errors.New("admin.recent_errors must be positive"))
+	}
+	switch c.Tracing.Exporter {
+	case "", "otlp", "jaeger", "stdout":
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter %q is not one of otlp, jaeger, stdout", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio must be between 0 and 1"))
+	}
+	for _, kv := range c.Tracing.Headers {
+		if !strings.Contains(kv, "=") {
+			errs = append(errs, fmt.Errorf("tracing header %q must have the form name=value", kv))
+		}
+	}
+	if c.Download.BytesPerSecond < 0 {
+		errs = append(errs, errors.New("download.bytes_per_second must not be negative"))
+	}
+	if c.TLS.HTTP3Addr != "" && !c.TLS.Enabled() && !c.TLS.ACME() {
+		errs = append(errs, errors.New("tls.http3_addr requires a TLS certificate or ACME hosts"))
+	}
+	for _, l := range c.Static.Preload {
+		if !strings.HasPrefix(l, "<") || !strings.Contains(l, ">") {
+			errs = append(errs, fmt.Errorf("static.preload %q must be a Link header value like </path>; rel=preload", l))
+		}
+	}
+	for i, rt := range c.Download.Routes {
+		if rt.Dir == "" || !strings.HasPrefix(rt.Prefix, "/") || !strings.HasSuffix(rt.Prefix, "/") {
+			errs = append(errs, fmt.Errorf("download.routes[%d] needs a dir and a prefix of the form /path/", i))
+		}
+		if rt.BytesPerSecond < 0 {
+			errs = append(errs, fmt.Errorf("download.routes[%d].bytes_per_second must not be negative", i))
+		}
+	}
+	for i, rt := range c.Proxy.Routes {
+		if rt.Upstream == "" && len(rt.Upstreams) == 0 {
+			errs = append(errs, fmt.Errorf("proxy.routes[%d] needs an upstream", i))
+		}
+		switch rt.Balance {
+		case "", "round_robin", "least_conn":
+		default:
+			errs = append(errs, fmt.Errorf("proxy.routes[%d].balance %q is not one of round_robin, least_conn", i, rt.Balance))
+		}
+		if rt.Host == "" && rt.Prefix == "" {
+			errs = append(errs, fmt.Errorf("proxy.routes[%d] needs a host or a prefix", i))
+		}
+		if rt.Prefix != "" && !strings.HasPrefix(rt.Prefix, "/") {
+			errs = append(errs, fmt.Errorf("proxy.routes[%d].prefix %q must start with /", i, rt.Prefix))
+		}
+		if t := rt.Transform; t != nil {
+			for j, h := range t.Headers {
+				if h.Header == "" || h.From == "" {
+					errs = append(errs, fmt.Errorf("proxy.routes[%d].transform.headers[%d] needs a header and from", i, j))
+				}
+			}
+			for j, l := range t.Links {
+				if l.From == "" {
+					errs = append(errs, fmt.Errorf("proxy.routes[%d].transform.links[%d].from is required", i, j))
+				}
+			}
+		}
+		if c := rt.Canary; c != nil && (len(c.Upstreams) == 0 || c.Weight < 0 || c.Weight > 100) {
+			errs = append(errs, fmt.Errorf("proxy.routes[%d].canary needs upstreams and a weight between 0 and 100", i))
+		}
+	}
+	if c.Client.RetryMax < 0 || c.Client.BreakerFailures < 0 || c.Client.BreakerHalfOpenRequests < 0 {
+		errs = append(errs, errors.New("client retry and breaker counts must not be negative"))
+	}
+	addrs := map[string]bool{c.Server.Addr: true}
+	for i, l := range c.Server.Listeners {
+		if l.Addr == "" {
+			errs = append(errs, fmt.Errorf("server.listeners[%d].addr is required", i))
+		} else if addrs[l.Addr] {
+			errs = append(errs, fmt.Errorf("server.listeners[%d].addr %q is already in use", i, l.Addr))
+		}
+		addrs[l.Addr] = true
+		switch l.Handler {
+		case "", "app":
+		case "admin":
+			if len(l.Allow) == 0 && !loopback(l.Addr) {
+				errs = append(errs, fmt.Errorf("server.listeners[%d] serves admin routes and needs a loopback addr or an allow list", i))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("server.listeners[%d].handler %q is not one of app, admin", i, l.Handler))
+		}
+		if l.TLS && !c.TLS.Enabled() && !c.TLS.ACME() {
+			errs = append(errs, fmt.Errorf("server.listeners[%d].tls requires a TLS certificate or ACME hosts", i))
+		}
+	}
+	if c.Jobs.Concurrency < 1 || c.Jobs.QueueSize < 1 || c.Jobs.MaxAttempts < 1 || c.Jobs.DeadLetters < 1 {
+		errs = append(errs, errors.New("jobs.concurrency, queue_size, max_attempts and dead_letters must be positive"))
+	}
+	if c.Jobs.RetryBaseDelay <= 0 || c.Jobs.RetryMaxDelay < c.Jobs.RetryBaseDelay {
+		errs = append(errs, errors.New("jobs.retry_base_delay must be positive and not exceed jobs.retry_max_delay"))
+	}
+	if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+		errs = append(errs, fmt.Errorf("mail.from %q: %w", c.Mail.From, err))
+	}
+	switch c.Mail.Backend {
+	case "log":
+	case "smtp":
+		if _, _, err := net.SplitHostPort(c.Mail.SMTPAddr); err != nil {
+			errs = append(errs, errors.New("mail.smtp_addr must be host:port when mail.backend is smtp"))
+		}
+	case "sendgrid":
+		if c.Mail.SendGridKey == "" {
+			errs = append(errs, errors.New("mail.sendgrid_key is required when mail.backend is sendgrid"))
+		}
+	case "ses":
+		if c.Mail.SESRegion == "" {
+			errs = append(errs, errors.New("mail.ses_region is required when mail.backend is ses"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("mail.backend %q is not one of log, smtp, sendgrid, ses", c.Mail.Backend))
+	}
+	if la := c.LocalAuth; la.Enabled {
+		if c.Database.Driver == "" {
+			errs = append(errs, errors.New("local_auth requires database.driver"))
+		}
+		if u, err := url.Parse(la.BaseURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs = append(errs, errors.New("local_auth.base_url must be an absolute http(s) URL such as https://example.com"))
+		}
+		if la.TOTPIssuer == "" || strings.Contains(la.TOTPIssuer, ":") {
+			errs = append(errs, errors.New("local_auth.totp_issuer must be non-empty and must not contain ':'"))
+		}
+		if la.Hash != "argon2id" && la.Hash != "bcrypt" {
+			errs = append(errs, fmt.Errorf("local_auth.hash %q is not one of argon2id, bcrypt", la.Hash))
+		}
+		if la.MinPasswordLength < 8 || la.MinPasswordLength > 72 {
+			errs = append(errs, errors.New("local_auth.min_password_length must be between 8 and 72"))
+		}
+		if la.LockAfter < 0 || (la.LockAfter > 0 && la.LockFor <= 0) || la.VerifyTTL < Duration(time.Hour) || la.ResetTTL < Duration(time.Hour) {
+			errs = append(errs, errors.New("local_auth.lock_after must not be negative, lock_for must be positive, and verify_ttl and reset_ttl must be at least 1h"))
+		}
+	}
+	if a := c.Audit; a.Enabled {
+		switch {
+		case a.Store == "db" && c.Database.Driver == "":
+			errs = append(errs, errors.New("audit.store db requires database.driver"))
+		case a.Store == "file" && a.File == "":
+			errs = append(errs, errors.New("audit.file is required when audit.store is file"))
+		case a.Store != "db" && a.Store != "file":
+			errs = append(errs, fmt.Errorf("audit.store %q is not one of file, db", a.Store))
+		}
+	}
+	if c.Mock.Latency < 0 {
+		errs = append(errs, errors.New("mock.latency must not be negative"))
+	}
+	if c.Capture.Enabled && (c.Capture.MaxBody < 1 || c.Capture.Buffer < 1) {
+		errs = append(errs, errors.New("capture.max_body and capture.buffer must be positive"))
+	}
+	if sh := c.Shed; sh.Enabled {
+		if sh.MaxInFlight < 0 || sh.MinInFlight < 0 || sh.Queue < 0 || sh.QueueTimeout < 0 || sh.TargetLatency < 0 {
+			errs = append(errs, errors.New("shed limits, queue and durations must not be negative"))
+		}
+		if sh.MaxInFlight > 0 && sh.MinInFlight > sh.MaxInFlight {
+			errs = append(errs, errors.New("shed.min_in_flight must not exceed shed.max_in_flight"))
+		}
+		if sh.MaxInFlight == 0 && len(sh.Routes) == 0 {
+			errs = append(errs, errors.New("shed requires max_in_flight or routes"))
+		}
+		for i, rt := range sh.Routes {
+			pattern, n, ok := strings.Cut(rt, "=")
+			if max, err := strconv.Atoi(strings.TrimSpace(n)); !ok || err != nil || max < 1 || !strings.Contains(pattern, "/") {
+				errs = append(errs, fmt.Errorf("shed.routes[%d] %q is not \"[METHOD] pattern=limit\"", i, rt))
+			}
+		}
+	}
+	if id := c.Idempotency; id.Enabled {
+		if id.TTL <= 0 || id.MaxBody < 1 {
+			errs = append(errs, errors.New("idempotency.ttl and idempotency.max_body must be positive"))
+		}
+		switch id.Backend {
+		case "memory":
+		case "redis":
+			if id.RedisAddr == "" && len(c.Redis.Addrs) == 0 {
+				errs = append(errs, errors.New("idempotency.redis_addr or redis.addrs is required for the redis backend"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("idempotency.backend %q is not one of memory, redis", id.Backend))
+		}
+	}
+	if c.Markdown.MaxBytes < 1 {
+		errs = append(errs, errors.New("markdown.max_bytes must be positive"))
+	}
+	if f := c.Feed.Format; f != "atom" && f != "rss" {
+		errs = append(errs, fmt.Errorf("feed.format %q is not one of atom, rss", f))
+	}
+	if c.Feed.Limit < 1 || c.Feed.MaxAge <= 0 {
+		errs = append(errs, errors.New("feed.limit and feed.max_age must be positive"))
+	}
+	if v := c.WellKnown.SecurityExpires; v != "" {
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			errs = append(errs, fmt.Errorf("well_known.security_expires %q is not an RFC 3339 time", v))
+		}
+	}
+	if c.Webhooks.Timeout <= 0 || c.Webhooks.History < 1 || c.Webhooks.Tolerance <= 0 {
+		errs = append(errs, errors.New("webhooks.timeout, webhooks.history and webhooks.tolerance must be positive"))
+	}
+	receivers := map[string]bool{}
+	for i, rc := range c.Webhooks.Receivers {
+		if rc.Name == "" || strings.Contains(rc.Name, "/") || receivers[rc.Name] {
+			errs = append(errs, fmt.Errorf("webhooks.receivers[%d].name must be set, unique and contain no /", i))
+		}
+		receivers[rc.Name] = true
+		if rc.Type != "github" && rc.Type != "stripe" && rc.Type != "signed" {
+			errs = append(errs, fmt.Errorf("webhooks.receivers[%d].type %q is not one of github, stripe, signed", i, rc.Type))
+		}
+		if rc.Secret == "" {
+			errs = append(errs, fmt.Errorf("webhooks.receivers[%d].secret is required", i))
+		}
+	}
+	hookIDs := map[string]bool{}
+	for i, s := range c.Webhooks.Subscribers {
+		if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("webhooks.subscribers[%d].url %q must be an absolute http or https URL", i, s.URL))
+		}
+		if s.ID == "" || hookIDs[s.ID] {
+			errs = append(errs, fmt.Errorf("webhooks.subscribers[%d].id must be set and unique", i))
+		}
+		hookIDs[s.ID] = true
+		if len(s.Secret) < 16 {
+			errs = append(errs, fmt.Errorf("webhooks.subscribers[%d].secret must be at least 16 bytes", i))
+		}
+	}
+	if c.Cron.Timeout < 0 || c.Cron.Jitter < 0 {
+		errs = append(errs, errors.New("cron.timeout and cron.jitter must not be negative"))
+	}
+	for _, p := range c.Cron.WarmupPaths {
+		if !strings.HasPrefix(p, "/") {
+			errs = append(errs, fmt.Errorf("cron.warmup_paths: %q must start with /", p))
+		}
+	}
+	if c.Upload.ThumbnailSize < 0 || (c.Upload.ThumbnailSize > 0 && c.Upload.Dir == "") {
+		errs = append(errs, errors.New("upload.thumbnail_size must not be negative and requires upload.dir"))
+	}
+	if c.Flags.RemoteURL != "" {
+		if u, err := url.Parse(c.Flags.RemoteURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("flags.remote_url %q must be an http(s) URL", c.Flags.RemoteURL))
+		}
+	}
+	if (c.Flags.File != "" || c.Flags.RemoteURL != "") && c.Flags.RefreshInterval <= 0 {
+		errs = append(errs, errors.New("flags.refresh_interval must be positive"))
+	}
+	if c.Maintenance.RetryAfter < 0 {
+		errs = append(errs, errors.New("maintenance.retry_after must not be negative"))
+	}
+	for _, p := range c.Maintenance.ExemptPaths {
+		if !strings.HasPrefix(p, "/") {
+			errs = append(errs, fmt.Errorf("maintenance.exempt_paths %q must start with /", p))
+		}
+	}
+	if c.Deadline.Default < 0 || c.Deadline.Max < 0 {
+		errs = append(errs, errors.New("deadline.default and deadline.max must not be negative"))
+	}
+	switch c.Search.Backend {
+	case "memory":
+	case "sqlite":
+		if c.Search.Enabled && c.Search.DSN == "" && c.Database.Driver != "sqlite" {
+			errs = append(errs, errors.New("search.dsn is required when the database is not sqlite"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("search.backend %q must be memory or sqlite", c.Search.Backend))
+	}
+	if c.Bench.Concurrency < 1 || c.Bench.Duration <= 0 || c.Bench.Warmup < 0 || c.Bench.MaxRegression < 0 {
+		errs = append(errs, errors.New("bench.concurrency and bench.duration must be positive; bench.warmup and bench.max_regression must not be negative"))
+	}
+	for _, h := range c.Bench.Headers {
+		if name, _, ok := strings.Cut(h, ":"); !ok || strings.TrimSpace(name) == "" {
+			errs = append(errs, errors.New(`bench.headers entries must be "Name: value"`))
+		}
+	}
+	if c.Bot.Enabled {
+		if c.Bot.Threshold < 1 || c.Bot.Threshold > 100 {
+			errs = append(errs, errors.New("bot.threshold must be between 1 and 100"))
+		}
+		if c.Bot.Difficulty < 1 || c.Bot.Difficulty > 32 {
+			errs = append(errs, errors.New("bot.difficulty must be between 1 and 32"))
+		}
+		if c.Bot.PassTTL < 0 || c.Bot.RateWindow < 0 || c.Bot.RateLimit < 0 {
+			errs = append(errs, errors.New("bot.pass_ttl, bot.rate_window and bot.rate_limit must not be negative"))
+		}
+		if !strings.HasPrefix(c.Bot.VerifyPath, "/") {
+			errs = append(errs, fmt.Errorf("bot.verify_path %q must start with /", c.Bot.VerifyPath))
+		}
+		for _, p := range c.Bot.Paths {
+			if !strings.HasPrefix(p, "/") {
+				errs = append(errs, fmt.Errorf("bot.paths %q must start with /", p))
+			}
+		}
+		if (c.Bot.HCaptchaSiteKey == "") != (c.Bot.HCaptchaSecret == "") {
+			errs = append(errs, errors.New("bot.hcaptcha_site_key and bot.hcaptcha_secret must be set together"))
+		}
+	}
+	splits := map[string]bool{}
+	for i, sp := range c.Canary.Splits {
+		if sp.Name == "" || splits[sp.Name] {
+			errs = append(errs, fmt.Errorf("canary.splits[%d].name %q is empty or duplicated", i, sp.Name))
+		}
+		splits[sp.Name] = true
+		if sp.Weight < 0 || sp.Weight > 100 {
+			errs = append(errs, fmt.Errorf("canary.splits[%d].weight must be between 0 and 100", i))
+		}
+	}
+	for i, rule := range c.Chaos.Rules {
+		if rule.Percent < 0 || rule.Percent > 100 {
+			errs = append(errs, fmt.Errorf("chaos.rules[%d].percent must be between 0 and 100", i))
+		}
+		if rule.Status != 0 && (rule.Status < 400 || rule.Status > 599) {
+			errs = append(errs, fmt.Errorf("chaos.rules[%d].status %d is not an error status", i, rule.Status))
+		}
+		if rule.Latency < 0 || rule.Jitter < 0 || rule.BytesPerSecond < 0 {
+			errs = append(errs, fmt.Errorf("chaos.rules[%d] latency, jitter and bytes_per_second must not be negative", i))
+		}
+	}
+	for _, p := range c.Chaos.ExemptPaths {
+		if !strings.HasPrefix(p, "/") {
+			errs = append(errs, fmt.Errorf("chaos.exempt_paths %q must start with /", p))
+		}
+	}
+	hosts := map[string]bool{}
+	for i, h := range c.VHosts.Hosts {
+		if h.Host == "" || hosts[strings.ToLower(h.Host)] {
+			errs = append(errs, fmt.Errorf("vhosts.hosts[%d].host %q is empty or duplicated", i, h.Host))
+		}
+		hosts[strings.ToLower(h.Host)] = true
+		switch h.Handler {
+		case "app", "admin":
+		case "static":
+			if h.Dir == "" {
+				errs = append(errs, fmt.Errorf("vhosts.hosts[%d].dir is required for the static handler", i))
+			}
+		case "redirect":
+			if u, err := url.Parse(h.RedirectTo); err != nil || u.Scheme == "" || u.Host == "" || strings.TrimSuffix(u.Path, "/") != "" {
+				errs = append(errs, fmt.Errorf("vhosts.hosts[%d].redirect_to %q must be an absolute URL without a path", i, h.RedirectTo))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("vhosts.hosts[%d].handler %q is not one of app, admin, static, redirect", i, h.Handler))
+		}
+	}
+	if c.VHosts.Default != "" && !hosts[strings.ToLower(c.VHosts.Default)] {
+		errs = append(errs, fmt.Errorf("vhosts.default %q is not one of vhosts.hosts", c.VHosts.Default))
+	}
+	if c.Debug.Addr != "" && !loopback(c.Debug.Addr) {
+		errs = append(errs, fmt.Errorf("debug.addr %q must be a loopback address", c.Debug.Addr))
+	}
+	for _, d := range c.RBAC.Roles {
+		if role, _, ok := strings.Cut(d, "="); !ok || strings.TrimSpace(role) == "" {
+			errs = append(errs, fmt.Errorf("rbac role %q must have the form role=perm ...", d))
+		}
+	}
+	if c.ACL.ProxyDepth < 1 || c.RealIP.ProxyDepth < 1 {
+		errs = append(errs, errors.New("acl.proxy_depth and real_ip.proxy_depth must be at least 1"))
+	}
+	if c.Tenancy.Enabled {
+		errs = append(errs, c.Tenancy.validate(c.Database.Driver)...)
+	}
+	if c.GraphQL.MaxDepth < 1 || c.GraphQL.MaxBytes < 1 {
+		errs = append(errs, errors.New("graphql.max_depth and graphql.max_bytes must be positive"))
+	}
+	for _, h := range c.RealIP.Headers {
+		switch strings.ToLower(h) {
+		case "forwarded", "x-forwarded-for", "x-real-ip":
+		default:
+			errs = append(errs, fmt.Errorf("real_ip.headers %q is not one of Forwarded, X-Forwarded-For, X-Real-IP", h))
+		}
+	}
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level %q is not one of debug, info, warn, error", c.Log.Level))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// loopback 은 addr 의 호스트가 localhost 또는 루프백 IP 인지 확인한다. 빈 호스트(":6060")는 모든 인터페이스라 거부한다.
+// 유닉스 소켓("unix:/경로")은 로컬에서만 접근할 수 있으므로 허용한다.
+func loopback(addr string) bool {
+	if strings.HasPrefix(addr, "unix:") {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// validate 는 켜진 tenancy 설정을 검사한다. driver 는 database.driver 다.
+func (c TenancyConfig) validate(driver string) []error {
+	var errs []error
+	for _, src := range c.Sources {
+		if src != "subdomain" && src != "header" && src != "path" {
+			errs = append(errs, fmt.Errorf("tenancy.sources %q is not one of subdomain, header, path", src))
+		}
+	}
+	ids := map[string]bool{}
+	for i, t := range c.Tenants {
+		if t.ID == "" || ids[t.ID] {
+			errs = append(errs, fmt.Errorf("tenancy.tenants[%d].id must be set and unique", i))
+		}
+		ids[t.ID] = true
+		if t.Schema != "" && driver != "postgres" {
+			errs = append(errs, fmt.Errorf("tenancy.tenants[%d].schema needs database.driver postgres", i))
+		}
+		if t.Schema != "" && strings.Trim(t.Schema, "abcdefghijklmnopqrstuvwxyz0123456789_") != "" {
+			errs = append(errs, fmt.Errorf("tenancy.tenants[%d].schema %q must be lowercase letters, digits and _", i, t.Schema))
+		}
+		if t.DSN != "" && driver == "" {
+			errs = append(errs, fmt.Errorf("tenancy.tenants[%d].dsn needs database.driver", i))
+		}
+	}
+	if c.Default != "" && !ids[c.Default] {
+		errs = append(errs, fmt.Errorf("tenancy.default %q is not a tenant", c.Default))
+	}
+	return errs
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration 은 JSON 에서 "15s" 같은 문자열(또는 나노초 숫자)로 표현되는 time.Duration 이다.
+type Duration time.Duration
+
+// D 는 time.Duration 값을 돌려준다.
+func (d Duration) D() time.Duration { return time.Duration(d) }
+
+func (d Duration) String() string { return time.Duration(d).String() }
+
+// MarshalJSON 은 "15s" 형태의 문자열로 직렬화한다.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON 은 문자열 또는 숫자를 받아들인다.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("invalid duration %s", b)
+		}
+		*d = Duration(n)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+var durationType = reflect.TypeOf(Duration(0))
+
+// field 는 환경 변수나 플래그로 덮어쓸 수 있는 하나의 설정 값이다.
+type field struct {
+	path string // "server.addr"
+	env  string // "APP_SERVER_ADDR"
+	flag string // "server.addr"
+	v    reflect.Value
+	// secret 이 true 이면 값을 화면이나 로그에 노출하지 않는다.
+	secret bool
+}
+
+// walk 는 json 태그를 따라 구조체의 모든 말단 필드를 찾는다.
+func walk(cfg *Config) []field {
+	var out []field
+	var rec func(v reflect.Value, prefix []string)
+	rec = func(v reflect.Value, prefix []string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name := strings.Split(sf.Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" || !sf.IsExported() {
+				continue
+			}
+			fv := v.Field(i)
+			p := append(append([]string(nil), prefix...), name)
+			if fv.Kind() == reflect.Struct && fv.Type() != durationType {
+				rec(fv, p)
+				continue
+			}
+			if !settable(fv) {
+				continue
+			}
+			out = append(out, field{
+				path:   strings.Join(p, "."),
+				env:    EnvPrefix + strings.ToUpper(strings.Join(p, "_")),
+				flag:   strings.Join(p, "."),
+				v:      fv,
+				secret: sf.Tag.Get("secret") == "true",
+			})
+		}
+	}
+	rec(reflect.ValueOf(cfg).Elem(), nil)
+	return out
+}
+
+// settable 은 문자열에서 값을 만들 수 있는 타입인지 확인한다.
+func settable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return v.Type().Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+// Set 은 문자열 값을 필드 타입에 맞게 변환해 저장한다. 슬라이스는 쉼표로 구분한다.
+func (f field) Set(s string) error {
+	v := f.v
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		var items []string
+		for _, it := range strings.Split(s, ",") {
+			if it = strings.TrimSpace(it); it != "" {
+				items = append(items, it)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	}
+	return nil
+}
+
+// String 은 현재 값을 문자열로 표현한다.
+func (f field) String() string {
+	if f.v.Kind() == reflect.Slice {
+		return strings.Join(f.v.Interface().([]string), ",")
+	}
+	return fmt.Sprint(f.v.Interface())
+}
+
+// Redacted 는 Settings 에서 비밀 값 대신 보여 주는 문자열이다.
+const Redacted = "[redacted]"
+
+// Setting 은 설정 값 하나와 그 값을 덮어쓰는 환경 변수 이름이다.
+type Setting struct {
+	Key   string `json:"key" xml:"key,attr"`
+	Env   string `json:"env" xml:"env,attr"`
+	Value string `json:"value" xml:",chardata"`
+}
+
+// Settings 는 모든 설정 값을 구조체 선언 순서대로 돌려준다.
+// secret 태그가 붙은 필드는 값이 비어 있지 않으면 Redacted 로 가린다.
+func (c *Config) Settings() []Setting {
+	fields := walk(c)
+	out := make([]Setting, 0, len(fields))
+	for _, f := range fields {
+		v := f.String()
+		if f.secret && v != "" {
+			v = Redacted
+		}
+		out = append(out, Setting{Key: f.path, Env: f.env, Value: v})
+	}
+	return out
+}
@@ -0,0 +1,232 @@
+// Package cookies 는 서명(또는 암호화)된 쿠키를 보안 기본값(HttpOnly, Secure, SameSite=Lax)으로 읽고 쓴다.
+//
+//	jar, _ := cookies.New(cookies.Options{Secrets: [][]byte{secret}, MaxAge: time.Hour})
+//	cookies.Set(jar, w, "prefs", prefs)
+//	prefs, err := cookies.Get[Prefs](jar, r, "prefs")
+//
+// 값에는 쿠키 이름과 서명한 시각이 묶이므로 다른 이름의 쿠키로 옮기거나 MaxAge 가 지난 값은 읽히지 않는다.
+package cookies
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MaxSize 는 브라우저가 받아 주는 쿠키 하나(이름, 값, 속성)의 크기다.
+const MaxSize = 4096
+
+var (
+	// ErrInvalid 는 서명이 맞지 않거나, 다른 이름의 쿠키이거나, 만료된 값이다.
+	ErrInvalid = errors.New("cookies: invalid or expired value")
+	// ErrTooLarge 는 인코딩한 쿠키가 MaxSize 를 넘을 때다.
+	ErrTooLarge = errors.New("cookies: value is too large")
+)
+
+// Options 는 Jar 의 키와 쿠키 속성이다.
+type Options struct {
+	// Secrets 의 첫 번째 값으로 서명·암호화하고, 나머지는 읽을 때만 쓴다. 키를 바꿀 때 이전 값을 뒤에 두면
+	// 이미 나간 쿠키도 만료될 때까지 읽힌다. 각각 16바이트 이상이어야 한다.
+	Secrets [][]byte
+	// Encrypt 이면 값을 AES-GCM 으로 암호화한다. 아니면 HMAC-SHA256 서명만 하므로 값이 보인다.
+	Encrypt bool
+
+	// Path 의 기본값은 "/" 다.
+	Path   string
+	Domain string
+	// MaxAge 가 0 이면 브라우저를 닫을 때 지워지는 쿠키이고 서명 시각도 검사하지 않는다.
+	MaxAge time.Duration
+	// SameSite 의 기본값은 Lax 다.
+	SameSite http.SameSite
+	// Insecure 이면 HTTP 로도 보낸다. (개발용)
+	Insecure bool
+	// Script 이면 HttpOnly 를 빼서 자바스크립트가 읽을 수 있게 한다.
+	Script bool
+}
+
+// Jar 는 정해진 키와 속성으로 쿠키를 만들고 읽는다.
+type Jar struct {
+	opts Options
+	keys []key
+}
+
+type key struct {
+	mac  []byte
+	aead cipher.AEAD
+}
+
+// New 는 opts 로 Jar 를 만든다.
+func New(opts Options) (*Jar, error) {
+	if len(opts.Secrets) == 0 {
+		return nil, errors.New("cookies: at least one secret is required")
+	}
+	if opts.Path == "" {
+		opts.Path = "/"
+	}
+	if opts.SameSite == 0 {
+		opts.SameSite = http.SameSiteLaxMode
+	}
+	j := &Jar{opts: opts}
+	for i, secret := range opts.Secrets {
+		if len(secret) < 16 {
+			return nil, fmt.Errorf("cookies: secret %d must be at least 16 bytes", i)
+		}
+		var k key
+		var err error
+		if opts.Encrypt {
+			enc, err := hkdf.Key(sha256.New, secret, nil, "cookie encryption", 32)
+			if err != nil {
+				return nil, err
+			}
+			block, err := aes.NewCipher(enc)
+			if err != nil {
+				return nil, err
+			}
+			if k.aead, err = cipher.NewGCM(block); err != nil {
+				return nil, err
+			}
+		} else if k.mac, err = hkdf.Key(sha256.New, secret, nil, "cookie signing", 32); err != nil {
+			return nil, err
+		}
+		j.keys = append(j.keys, k)
+	}
+	return j, nil
+}
+
+// Encode 는 name 쿠키에 넣을 value 의 서명(또는 암호화)된 값이다.
+func (j *Jar) Encode(name, value string) string {
+	payload := binary.BigEndian.AppendUint64(nil, uint64(time.Now().Unix()))
+	payload = append(payload, value...)
+	k := j.keys[0]
+	if k.aead != nil {
+		nonce := make([]byte, k.aead.NonceSize())
+		rand.Read(nonce)
+		return base64.RawURLEncoding.EncodeToString(k.aead.Seal(nonce, nonce, payload, []byte(name)))
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(k.sign(name, payload))
+}
+
+// Decode 는 Encode 한 값을 되돌린다. 어느 키로도 열리지 않거나 MaxAge 가 지났으면 ErrInvalid 다.
+func (j *Jar) Decode(name, encoded string) (string, error) {
+	var payload []byte
+	if j.keys[0].aead != nil {
+		b, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", ErrInvalid
+		}
+		for _, k := range j.keys {
+			n := k.aead.NonceSize()
+			if len(b) < n {
+				break
+			}
+			if p, err := k.aead.Open(nil, b[:n], b[n:], []byte(name)); err == nil {
+				payload = p
+				break
+			}
+		}
+	} else {
+		data, sig, ok := strings.Cut(encoded, ".")
+		p, err1 := base64.RawURLEncoding.DecodeString(data)
+		mac, err2 := base64.RawURLEncoding.DecodeString(sig)
+		if !ok || err1 != nil || err2 != nil {
+			return "", ErrInvalid
+		}
+		for _, k := range j.keys {
+			if hmac.Equal(mac, k.sign(name, p)) {
+				payload = p
+				break
+			}
+		}
+	}
+	if len(payload) < 8 {
+		return "", ErrInvalid
+	}
+	signed := time.Unix(int64(binary.BigEndian.Uint64(payload)), 0)
+	if j.opts.MaxAge > 0 && time.Since(signed) > j.opts.MaxAge {
+		return "", ErrInvalid
+	}
+	return string(payload[8:]), nil
+}
+
+func (k key) sign(name string, payload []byte) []byte {
+	h := hmac.New(sha256.New, k.mac)
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+// Cookie 는 value 를 인코딩하고 Jar 의 속성을 붙인 쿠키다.
+func (j *Jar) Cookie(name, value string) *http.Cookie {
+	return j.cookie(name, j.Encode(name, value), int(j.opts.MaxAge.Seconds()))
+}
+
+func (j *Jar) cookie(name, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     j.opts.Path,
+		Domain:   j.opts.Domain,
+		MaxAge:   maxAge,
+		HttpOnly: !j.opts.Script,
+		Secure:   !j.opts.Insecure,
+		SameSite: j.opts.SameSite,
+	}
+}
+
+// SetValue 는 name 쿠키에 value 를 쓴다. 응답 헤더를 쓰기 전에 불러야 한다.
+func (j *Jar) SetValue(w http.ResponseWriter, name, value string) error {
+	c := j.Cookie(name, value)
+	if len(c.String()) > MaxSize {
+		return ErrTooLarge
+	}
+	http.SetCookie(w, c)
+	return nil
+}
+
+// Value 는 name 쿠키의 값이다. 쿠키가 없으면 http.ErrNoCookie, 값이 맞지 않으면 ErrInvalid 다.
+func (j *Jar) Value(r *http.Request, name string) (string, error) {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return j.Decode(name, c.Value)
+}
+
+// Delete 는 name 쿠키를 지우라고 보낸다.
+func (j *Jar) Delete(w http.ResponseWriter, name string) {
+	http.SetCookie(w, j.cookie(name, "", -1))
+}
+
+// Set 은 v 를 JSON 으로 바꿔 name 쿠키에 쓴다.
+func Set[T any](j *Jar, w http.ResponseWriter, name string, v T) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return j.SetValue(w, name, string(b))
+}
+
+// Get 은 name 쿠키의 JSON 값을 T 로 읽는다. 에러는 Value 와 같고, JSON 이 T 에 맞지 않으면 ErrInvalid 다.
+func Get[T any](j *Jar, r *http.Request, name string) (T, error) {
+	var v T
+	s, err := j.Value(r, name)
+	if err != nil {
+		return v, err
+	}
+	if json.Unmarshal([]byte(s), &v) != nil {
+		return v, ErrInvalid
+	}
+	return v, nil
+}
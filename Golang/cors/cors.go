@@ -0,0 +1,200 @@
+// Package cors 는 교차 출처 요청(CORS) 정책을 적용하고 프리플라이트(OPTIONS) 요청에 응답한다.
+//
+// 기본 정책은 모든 경로에 적용되고, Route 로 경로 접두사(라우트 그룹)마다 다른 정책을 지정할 수 있다.
+// 프리플라이트 요청은 라우트 매칭과 무관하게 처리되어야 하므로 전역 미들웨어로 등록한다.
+package cors
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Policy 는 하나의 CORS 정책이다.
+type Policy struct {
+	// AllowedOrigins 는 허용할 출처 목록이다. "*" 는 모든 출처, "https://*.example.com" 은 하위 도메인 전체다.
+	AllowedOrigins []string
+	// AllowedMethods 가 비어 있으면 GET, HEAD, POST 를 허용한다.
+	AllowedMethods []string
+	// AllowedHeaders 는 요청에 허용할 헤더다. "*" 는 요청한 헤더를 모두 허용한다.
+	AllowedHeaders []string
+	// ExposedHeaders 는 브라우저 스크립트가 읽을 수 있는 응답 헤더다.
+	ExposedHeaders []string
+	// AllowCredentials 가 true 이면 쿠키와 인증 헤더를 허용한다. "*" 출처와 함께 쓸 수 없다.
+	AllowCredentials bool
+	// MaxAge 는 브라우저가 프리플라이트 결과를 캐시하는 시간이다.
+	MaxAge time.Duration
+}
+
+func (p *Policy) validate() error {
+	if p.AllowCredentials {
+		for _, o := range p.AllowedOrigins {
+			if o == "*" {
+				return errors.New(`cors: AllowCredentials cannot be used with the "*" origin`)
+			}
+		}
+	}
+	if len(p.AllowedMethods) == 0 {
+		p.AllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	for i, m := range p.AllowedMethods {
+		p.AllowedMethods[i] = strings.ToUpper(m)
+	}
+	return nil
+}
+
+// allowOrigin 은 origin 에 대해 Access-Control-Allow-Origin 에 쓸 값을 돌려준다.
+func (p *Policy) allowOrigin(origin string) (string, bool) {
+	for _, o := range p.AllowedOrigins {
+		switch {
+		case o == "*":
+			return "*", true
+		case strings.EqualFold(o, origin):
+			return origin, true
+		case strings.Contains(o, "://*."):
+			scheme, suffix, _ := strings.Cut(o, "://*")
+			if strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) &&
+				len(origin) > len(scheme)+3+len(suffix) {
+				return origin, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (p *Policy) allowMethod(m string) bool {
+	for _, x := range p.AllowedMethods {
+		if x == m {
+			return true
+		}
+	}
+	return false
+}
+
+// allowHeaders 는 요청한 헤더가 모두 허용되는지 확인하고 응답에 쓸 값을 돌려준다.
+func (p *Policy) allowHeaders(requested string) (string, bool) {
+	if requested == "" {
+		return "", true
+	}
+	var out []string
+	for _, h := range strings.Split(requested, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		if !p.allowHeader(h) {
+			return "", false
+		}
+		out = append(out, h)
+	}
+	return strings.Join(out, ", "), true
+}
+
+func (p *Policy) allowHeader(h string) bool {
+	for _, x := range p.AllowedHeaders {
+		if x == "*" || strings.EqualFold(x, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// route 는 경로 접두사별 정책이다.
+type route struct {
+	prefix string
+	policy Policy
+}
+
+// CORS 는 기본 정책과 경로별 정책 모음이다.
+type CORS struct {
+	def    Policy
+	routes []route
+}
+
+// New 는 기본 정책으로 CORS 를 만든다.
+func New(def Policy) (*CORS, error) {
+	if err := def.validate(); err != nil {
+		return nil, err
+	}
+	return &CORS{def: def}, nil
+}
+
+// Route 는 prefix 로 시작하는 경로에 기본 정책 대신 p 를 적용한다. 가장 긴 접두사가 우선한다.
+func (c *CORS) Route(prefix string, p Policy) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+	c.routes = append(c.routes, route{prefix: prefix, policy: p})
+	sort.SliceStable(c.routes, func(i, j int) bool { return len(c.routes[i].prefix) > len(c.routes[j].prefix) })
+	return nil
+}
+
+func (c *CORS) policy(path string) *Policy {
+	for i := range c.routes {
+		if strings.HasPrefix(path, c.routes[i].prefix) {
+			return &c.routes[i].policy
+		}
+	}
+	return &c.def
+}
+
+// Middleware 는 CORS 헤더를 붙이고 프리플라이트 요청에 204 로 응답한다.
+func (c *CORS) Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			p := c.policy(r.URL.Path)
+			allowed, ok := p.allowOrigin(origin)
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			if preflight {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+				if !ok || !p.allowMethod(r.Header.Get("Access-Control-Request-Method")) {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				headers, ok := p.allowHeaders(r.Header.Get("Access-Control-Request-Headers"))
+				if !ok {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				h.Set("Access-Control-Allow-Origin", allowed)
+				h.Set("Access-Control-Allow-Methods", strings.Join(p.AllowedMethods, ", "))
+				if headers != "" {
+					h.Set("Access-Control-Allow-Headers", headers)
+				}
+				if p.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+				if p.MaxAge > 0 {
+					h.Set("Access-Control-Max-Age", strconv.Itoa(int(p.MaxAge.Seconds())))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if ok {
+				h.Set("Access-Control-Allow-Origin", allowed)
+				if p.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+				if len(p.ExposedHeaders) > 0 {
+					h.Set("Access-Control-Expose-Headers", strings.Join(p.ExposedHeaders, ", "))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
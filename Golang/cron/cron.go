@@ -0,0 +1,296 @@
+// Package cron 은 등록한 함수를 cron 식에 맞춰 실행하는 스케줄러다.
+//
+// 같은 작업은 겹쳐 실행하지 않는다. 이전 실행이 끝나지 않았으면 이번 차례는 건너뛰고 skipped 로 센다.
+// 여러 인스턴스가 같은 시각에 몰리지 않도록 작업마다 실행 시각에 무작위 지연(Jitter)을 더할 수 있다.
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/lifecycle"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/metrics"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+var (
+	cronRuns = metrics.NewCounterVec("cron_runs_total",
+		"Scheduled task runs by task and result (ok, error, skipped).", "task", "result")
+	cronDuration = metrics.NewHistogramVec("cron_duration_seconds", "Scheduled task duration by task.", nil, "task")
+)
+
+// ErrUnknownTask 는 등록되지 않은 작업 이름이다.
+var ErrUnknownTask = errors.New("cron: unknown task")
+
+// Task 는 주기적으로 실행할 작업이다.
+type Task struct {
+	Name string
+	// Spec 은 Parse 가 받는 cron 식이다. (예: "*/5 * * * *", "@every 1m")
+	Spec string
+	Func func(ctx context.Context) error
+	// Timeout 이 있으면 한 번의 실행을 이 시간으로 제한한다.
+	Timeout time.Duration
+	// Jitter 가 있으면 실행 시각에 [0, Jitter) 의 무작위 지연을 더한다.
+	Jitter time.Duration
+}
+
+// Status 는 작업의 최근 실행 상태다.
+type Status struct {
+	Name      string    `json:"name"`
+	Spec      string    `json:"spec"`
+	Running   bool      `json:"running"`
+	Next      time.Time `json:"next,omitzero"`
+	LastStart time.Time `json:"last_start,omitzero"`
+	LastDurMs float64   `json:"last_duration_ms,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+	Runs      int       `json:"runs"`
+	Failures  int       `json:"failures"`
+	Skipped   int       `json:"skipped"`
+}
+
+type entry struct {
+	task     Task
+	schedule Schedule
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Scheduler 는 작업 목록과 실행 상태다.
+type Scheduler struct {
+	// Location 은 cron 식을 해석할 시간대다. nil 이면 time.Local.
+	Location *time.Location
+
+	mu      sync.Mutex
+	entries []*entry
+	ctx     context.Context // Run 의 컨텍스트. Run 전에는 nil 이다.
+	wg      sync.WaitGroup  // 실행 중인 작업
+}
+
+// New 는 비어 있는 Scheduler 를 만든다.
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add 는 작업을 등록한다. Run 전에 호출한다.
+func (s *Scheduler) Add(t Task) error {
+	sched, err := Parse(t.Spec)
+	if err != nil {
+		return err
+	}
+	if t.Name == "" || t.Func == nil {
+		return errors.New("cron: task needs a name and a func")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if e.task.Name == t.Name {
+			return fmt.Errorf("cron: task %q registered twice", t.Name)
+		}
+	}
+	s.entries = append(s.entries, &entry{task: t, schedule: sched, status: Status{Name: t.Name, Spec: t.Spec}})
+	return nil
+}
+
+// Run 은 ctx 가 끝날 때까지 작업을 실행하고, 끝나면 실행 중인 작업이 돌아올 때까지 기다린다.
+// 실행 중인 작업의 컨텍스트도 함께 취소된다.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	entries := s.entries
+	s.mu.Unlock()
+	var loops sync.WaitGroup
+	for _, e := range entries {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			s.loop(ctx, e)
+		}()
+	}
+	loops.Wait()
+	s.wg.Wait()
+}
+
+// RegisterLifecycle 은 서버가 요청을 받기 시작하면 Run 을 시작하고, 종료할 때 실행 중인 작업이
+// 돌아올 때까지 기다리는 훅을 lc 에 등록한다.
+func (s *Scheduler) RegisterLifecycle(lc *lifecycle.Manager) {
+	var stop context.CancelFunc
+	done := make(chan struct{})
+	lc.OnReady("cron", 0, func(ctx context.Context) error {
+		// 훅의 타임아웃과 관계없이 종료 훅이 멈출 때까지 실행한다.
+		runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		stop = cancel
+		go func() {
+			s.Run(runCtx)
+			close(done)
+		}()
+		return nil
+	})
+	lc.OnShutdown("cron", 0, func(ctx context.Context) error {
+		if stop == nil {
+			return nil
+		}
+		stop()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// loop 는 작업 하나의 실행 시각을 기다렸다가 실행하기를 반복한다.
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	loc := s.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	for {
+		next := e.schedule.Next(time.Now().In(loc))
+		if next.IsZero() {
+			logging.Default().Warn("cron: task will never run", "task", e.task.Name, "spec", e.task.Spec)
+			return
+		}
+		e.mu.Lock()
+		e.status.Next = next
+		e.mu.Unlock()
+		wait := time.Until(next)
+		if e.task.Jitter > 0 {
+			wait += rand.N(e.task.Jitter)
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+			s.start(ctx, e)
+		}
+	}
+}
+
+// start 는 작업이 실행 중이 아니면 새 고루틴에서 실행한다. 실행 중이면 건너뛰고 false 를 돌려준다.
+func (s *Scheduler) start(ctx context.Context, e *entry) bool {
+	e.mu.Lock()
+	if e.status.Running {
+		e.status.Skipped++
+		e.mu.Unlock()
+		cronRuns.Inc(e.task.Name, "skipped")
+		logging.Default().Warn("cron: previous run still in progress, skipping", "task", e.task.Name)
+		return false
+	}
+	e.status.Running = true
+	e.status.LastStart = time.Now()
+	e.mu.Unlock()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.exec(ctx, e)
+	}()
+	return true
+}
+
+// exec 는 작업을 한 번 실행하고 상태를 기록한다. 패닉은 에러로 기록한다.
+func (s *Scheduler) exec(ctx context.Context, e *entry) {
+	if e.task.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.task.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	err := func() (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = fmt.Errorf("panic: %v", v)
+			}
+		}()
+		return e.task.Func(ctx)
+	}()
+	d := time.Since(start)
+	cronDuration.Observe(d.Seconds(), e.task.Name)
+	e.mu.Lock()
+	e.status.Running = false
+	e.status.Runs++
+	e.status.LastDurMs = float64(d.Microseconds()) / 1000
+	e.status.LastError = ""
+	if err != nil {
+		e.status.Failures++
+		e.status.LastError = err.Error()
+	}
+	e.mu.Unlock()
+	if err != nil {
+		cronRuns.Inc(e.task.Name, "error")
+		logging.Default().Error("cron: task failed", "task", e.task.Name, "duration_ms", d.Milliseconds(), "err", err)
+		return
+	}
+	cronRuns.Inc(e.task.Name, "ok")
+	logging.Default().Debug("cron: task done", "task", e.task.Name, "duration_ms", d.Milliseconds())
+}
+
+// Trigger 는 name 작업을 지금 실행한다. Run 중에만 쓸 수 있으며, 이미 실행 중이면 false 다.
+func (s *Scheduler) Trigger(name string) (bool, error) {
+	s.mu.Lock()
+	ctx := s.ctx
+	var found *entry
+	for _, e := range s.entries {
+		if e.task.Name == name {
+			found = e
+		}
+	}
+	s.mu.Unlock()
+	if found == nil {
+		return false, ErrUnknownTask
+	}
+	if ctx == nil || ctx.Err() != nil {
+		return false, errors.New("cron: scheduler is not running")
+	}
+	return s.start(ctx, found), nil
+}
+
+// Status 는 등록 순서대로 모든 작업의 상태다.
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	entries := s.entries
+	s.mu.Unlock()
+	out := make([]Status, 0, len(entries))
+	for _, e := range entries {
+		e.mu.Lock()
+		out = append(out, e.status)
+		e.mu.Unlock()
+	}
+	return out
+}
+
+// Mount 는 r 의 prefix 아래에 작업 관리 라우트를 등록한다. mws 로 관리자 인증을 건다.
+//
+//	GET  prefix             모든 작업의 최근 실행 상태
+//	POST prefix/{name}/run  작업을 지금 실행
+func (s *Scheduler) Mount(r router.Routes, prefix string, mws ...router.Middleware) {
+	g := r.Group(prefix, mws...)
+	g.GET("", func(w http.ResponseWriter, r *http.Request) {
+		api.WriteJSON(w, http.StatusOK, map[string][]Status{"tasks": s.Status()})
+	})
+	g.POST("/{name}/run", func(w http.ResponseWriter, r *http.Request) {
+		name := router.Param(r, "name")
+		started, err := s.Trigger(name)
+		switch {
+		case errors.Is(err, ErrUnknownTask):
+			api.WriteError(w, api.NewError(http.StatusNotFound, "not_found", "no task with this name"))
+		case err != nil:
+			api.WriteError(w, api.NewError(http.StatusServiceUnavailable, "scheduler_stopped", err.Error()))
+		case !started:
+			api.WriteError(w, api.NewError(http.StatusConflict, "already_running", "task is already running"))
+		default:
+			logging.From(r.Context()).Info("cron: task triggered", "task", name)
+			w.WriteHeader(http.StatusAccepted)
+		}
+	})
+}
@@ -0,0 +1,148 @@
+// Package csrf 는 세션에 저장한 토큰(synchronizer token 패턴)으로 폼 POST 의 CSRF 공격을 막는다.
+// Options.Cookie 가 있으면 세션 대신 서명된 쿠키에 토큰을 둔다. (double submit cookie 패턴)
+//
+// 토큰은 폼 필드(FieldName) 또는 요청 헤더(HeaderName)로 보낸다.
+// multipart 본문은 스트리밍 처리를 위해 파싱하지 않으므로 헤더로 보내야 한다.
+package csrf
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/cookies"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+	"github.com/hgsong234/_stack/Golang/session"
+)
+
+const (
+	// FieldName 은 토큰을 담는 폼 필드 이름이다.
+	FieldName = "csrf_token"
+	// HeaderName 은 토큰을 담는 요청 헤더 이름이다.
+	HeaderName = "X-CSRF-Token"
+	// CookieName 은 Options.Cookie 를 쓸 때 토큰을 담는 쿠키 이름이다.
+	CookieName = "csrf"
+
+	sessionKey = "_csrf"
+)
+
+// Options 는 CSRF 미들웨어 설정이다.
+type Options struct {
+	// ExemptPaths 는 검사하지 않을 경로 접두사다. (예: 토큰 인증을 쓰는 "/api/")
+	ExemptPaths []string
+	// Cookie 가 있으면 토큰을 이 Jar 로 서명한 쿠키에 둔다. 세션 미들웨어가 없어도 된다.
+	Cookie *cookies.Jar
+}
+
+// cookieState 는 쿠키에 토큰을 둘 때 Token 이 쿠키를 쓸 수 있도록 요청 컨텍스트에 넣는 값이다.
+type cookieState struct {
+	jar   *cookies.Jar
+	w     http.ResponseWriter
+	token string
+}
+
+type ctxKey struct{}
+
+// Middleware 는 안전하지 않은 메서드(POST, PUT, PATCH, DELETE 등)의 토큰을 검사하는 미들웨어를 만든다.
+// 세션을 사용하므로 session 미들웨어 안쪽에 등록해야 한다.
+func Middleware(opts Options) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// 라우트가 없는 요청(404/405)은 핸들러가 실행되지 않으므로 검사하지 않는다.
+			if opts.Cookie != nil {
+				r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, &cookieState{jar: opts.Cookie, w: w}))
+			}
+			if safeMethod(r.Method) || exempt(r.URL.Path, opts.ExemptPaths) || router.Pattern(r) == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			want := stored(r)
+			got := requestToken(r)
+			if want == "" || subtle.ConstantTimeCompare([]byte(want), []byte(got)) != 1 {
+				logging.From(r.Context()).Warn("csrf: rejected", "method", r.Method, "path", r.URL.Path)
+				api.WriteError(w, api.NewError(http.StatusForbidden, "csrf_invalid", "invalid CSRF token"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Token 은 요청 세션의 CSRF 토큰을 돌려준다. 없으면 새로 만들어 세션에 저장한다.
+// 응답을 쓰기 전에 호출해야 새 세션 쿠키가 전송된다.
+func Token(r *http.Request) string {
+	if t := stored(r); t != "" {
+		return t
+	}
+	b := make([]byte, 32)
+	rand.Read(b)
+	t := base64.RawURLEncoding.EncodeToString(b)
+	if cs, ok := r.Context().Value(ctxKey{}).(*cookieState); ok {
+		if err := cs.jar.SetValue(cs.w, CookieName, t); err != nil {
+			logging.From(r.Context()).Error("csrf: store token", "err", err)
+		}
+		cs.token = t
+		return t
+	}
+	if err := session.Set(r, sessionKey, t); err != nil {
+		logging.From(r.Context()).Error("csrf: store token", "err", err)
+	}
+	return t
+}
+
+// stored 는 세션 또는 쿠키에 있는 토큰이다.
+func stored(r *http.Request) string {
+	cs, ok := r.Context().Value(ctxKey{}).(*cookieState)
+	if !ok {
+		return session.GetString(r, sessionKey)
+	}
+	if cs.token == "" {
+		cs.token, _ = cs.jar.Value(r, CookieName)
+	}
+	return cs.token
+}
+
+// FuncMap 은 템플릿 함수다. {{csrfField .CSRF}} 는 토큰을 담은 hidden input 을 출력한다.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"csrfField": func(token string) template.HTML {
+			return template.HTML(`<input type="hidden" name="` + FieldName + `" value="` +
+				template.HTMLEscapeString(token) + `">`)
+		},
+	}
+}
+
+// requestToken 은 헤더 또는 urlencoded 폼에서 토큰을 꺼낸다.
+func requestToken(r *http.Request) string {
+	if t := r.Header.Get(HeaderName); t != "" {
+		return t
+	}
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "application/x-www-form-urlencoded" {
+		return r.PostFormValue(FieldName)
+	}
+	return ""
+}
+
+func safeMethod(m string) bool {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func exempt(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
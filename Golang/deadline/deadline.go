@@ -0,0 +1,126 @@
+// Package deadline 은 클라이언트가 알려 준 남은 시간(기본 X-Request-Timeout 헤더)을 요청 컨텍스트의
+// 기한으로 삼아 DB 조회와 외부 HTTP 호출까지 전달한다.
+//
+// 헤더 값은 "1500"(밀리초) 또는 "1.5s" 같은 Go 기간이다. Middleware 가 기한을 걸면 ctx 를 쓰는 DB 조회와
+// 외부 호출은 기한에 맞춰 취소되고, Transport 는 백엔드에 남은 시간을 같은 헤더로 알린다.
+// 기한이 지나 핸들러가 응답하지 못했으면 504 deadline_exceeded 로 응답한다.
+package deadline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/middleware"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// DefaultHeader 는 Config.Header 가 비어 있을 때 쓰는 헤더다.
+const DefaultHeader = "X-Request-Timeout"
+
+// Config 는 기한 미들웨어 설정이다.
+type Config struct {
+	// Header 는 클라이언트가 남은 시간을 보내는 헤더다. 비어 있으면 DefaultHeader.
+	Header string
+	// Default 는 헤더가 없을 때의 기한이다. 0 이면 기한을 걸지 않는다.
+	Default time.Duration
+	// Max 는 클라이언트가 요청할 수 있는 가장 긴 기한이다. 더 긴 값은 Max 로 줄인다. 0 이면 제한하지 않는다.
+	Max time.Duration
+}
+
+// Parse 는 헤더 값을 기간으로 읽는다. 단위가 없으면 밀리초다.
+func Parse(v string) (time.Duration, error) {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		ms, err2 := strconv.ParseInt(v, 10, 64)
+		if err2 != nil {
+			return 0, err
+		}
+		d = time.Duration(ms) * time.Millisecond
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("deadline: %q is not positive", v)
+	}
+	return d, nil
+}
+
+type headerKey struct{}
+
+// Middleware 는 요청 컨텍스트에 기한을 건다. 요청 로그가 기한 초과를 기록하도록 접근 로그 안쪽에 등록한다.
+func Middleware(cfg Config) router.Middleware {
+	if cfg.Header == "" {
+		cfg.Header = DefaultHeader
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			budget := cfg.Default
+			if v := r.Header.Get(cfg.Header); v != "" {
+				d, err := Parse(v)
+				if err != nil {
+					api.WriteError(w, api.BadRequest(fmt.Sprintf("%s must be a positive duration such as 1500 or 1.5s", cfg.Header)))
+					return
+				}
+				budget = d
+			}
+			if cfg.Max > 0 && budget > cfg.Max {
+				budget = cfg.Max
+			}
+			if budget <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			parent := r.Context()
+			ctx, cancel := context.WithTimeout(context.WithValue(parent, headerKey{}, cfg.Header), budget)
+			defer cancel()
+			sw := middleware.NewStatusWriter(w)
+			next.ServeHTTP(sw, r.WithContext(ctx))
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) || parent.Err() != nil {
+				return
+			}
+			logging.From(ctx).Warn("request deadline exceeded", "path", r.URL.Path, "budget", budget.String(), "responded", sw.Status != 0)
+			if sw.Status == 0 {
+				api.WriteError(sw, api.NewError(http.StatusGatewayTimeout, "deadline_exceeded",
+					fmt.Sprintf("request deadline of %s exceeded", budget)))
+			}
+		})
+	}
+}
+
+// Transport 는 요청 컨텍스트의 남은 시간을 헤더로 백엔드에 알리는 http.RoundTripper 다.
+// 기한이 이미 지났으면 요청을 보내지 않는다. 헤더 이름은 Middleware 설정을 따르고, 없으면 DefaultHeader 다.
+type Transport struct {
+	// Base 가 nil 이면 http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ctx := req.Context()
+	at, ok := ctx.Deadline()
+	if !ok {
+		return base.RoundTrip(req)
+	}
+	left := time.Until(at)
+	if left <= 0 {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, context.DeadlineExceeded
+	}
+	header, _ := ctx.Value(headerKey{}).(string)
+	if header == "" {
+		header = DefaultHeader
+	}
+	// RoundTripper 는 요청을 바꾸면 안 되므로 헤더만 복사해 바꾼다.
+	req = req.Clone(ctx)
+	req.Header.Set(header, strconv.FormatInt(max(left.Milliseconds(), 1), 10))
+	return base.RoundTrip(req)
+}
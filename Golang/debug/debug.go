@@ -0,0 +1,62 @@
+// Package debug 는 net/http/pprof 프로파일링과 expvar 런타임 변수를 /debug/ 아래에 노출한다.
+//
+// 프로파일은 내부 구현과 메모리 내용을 드러내므로 반드시 관리자 인증 뒤에 두고,
+// 가능하면 Handler 를 localhost 에만 묶인 별도 리스너에서 서비스한다.
+package debug
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Prefix 는 디버그 엔드포인트의 경로 접두사다.
+const Prefix = "/debug/"
+
+// Handler 는 다음 경로를 서비스하는 핸들러를 만든다.
+//
+//	/debug/pprof/              프로파일 목록 (heap, goroutine, block, mutex, ...)
+//	/debug/pprof/profile       CPU 프로파일 (?seconds=30)
+//	/debug/pprof/trace         실행 추적 (?seconds=5)
+//	/debug/pprof/symbol
+//	/debug/vars                expvar (memstats 와 등록된 변수)
+//
+// 명령행에는 플래그로 넘긴 비밀 값이 있을 수 있으므로 cmdline 은 노출하지 않는다.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(Prefix+"pprof/", pprof.Index)
+	mux.HandleFunc(Prefix+"pprof/profile", pprof.Profile)
+	mux.HandleFunc(Prefix+"pprof/symbol", pprof.Symbol)
+	mux.HandleFunc(Prefix+"pprof/trace", pprof.Trace)
+	mux.HandleFunc(Prefix+"vars", vars)
+	return mux
+}
+
+// vars 는 cmdline 을 뺀 expvar 변수들을 JSON 객체로 쓴다.
+func vars(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprintf(w, "{\n")
+	first := true
+	expvar.Do(func(kv expvar.KeyValue) {
+		if kv.Key == "cmdline" {
+			return
+		}
+		if !first {
+			fmt.Fprintf(w, ",\n")
+		}
+		first = false
+		fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+	})
+	fmt.Fprintf(w, "\n}\n")
+}
+
+// Mount 는 라우터의 /debug/ 아래에 Handler 를 등록한다. mws 에는 인증·인가 미들웨어를 넘긴다.
+func Mount(r *router.Router, mws ...router.Middleware) {
+	h := Handler()
+	r.Handle(http.MethodGet, Prefix, h, mws...)
+	// pprof 의 symbol 조회는 POST 로도 들어온다.
+	r.Handle(http.MethodPost, Prefix+"pprof/symbol", h, mws...)
+}
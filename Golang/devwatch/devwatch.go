@@ -0,0 +1,159 @@
+// Package devwatch 는 개발 중에 소스 파일이 바뀌면 서버를 다시 빌드하고 재시작한다.
+//
+// 감독 프로세스가 Build 명령으로 바이너리를 만들고 자식 프로세스로 띄운다. 파일이 바뀌면 다시 빌드하고,
+// 성공했을 때만 이전 서버를 SIGTERM 으로 멈추고(드레인) 새 서버를 띄운다. 빌드가 실패하면 에러를
+// 기록하고 이전 서버를 그대로 둔다.
+package devwatch
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/logging"
+)
+
+// Config 는 감독 설정이다.
+type Config struct {
+	// Build 는 빌드 명령과 인자다. (예: go build -o .dev/server .)
+	Build []string
+	// Binary 는 빌드 결과로 실행할 파일이고, Args 는 그 인자다.
+	Binary string
+	Args   []string
+	// Dirs 아래에서 확장자가 Exts 중 하나인 파일을 지켜본다. 이름이 "." 으로 시작하는 디렉터리는 건너뛴다.
+	Dirs []string
+	Exts []string
+	// Interval 은 변경을 확인하는 주기다. 0 이면 500ms.
+	Interval time.Duration
+	// StopTimeout 은 이전 서버가 SIGTERM 뒤 끝나기를 기다리는 시간이다. 지나면 강제로 끝낸다. 0 이면 20초.
+	StopTimeout time.Duration
+	// Logger 가 nil 이면 logging.Default 다.
+	Logger logging.Logger
+}
+
+// Run 은 ctx 가 끝날 때까지 서버를 빌드하고, 띄우고, 변경마다 재시작한다. ctx 가 끝나면 서버를 멈춘다.
+func Run(ctx context.Context, cfg Config) error {
+	if len(cfg.Build) == 0 || cfg.Binary == "" {
+		return errors.New("devwatch: build command and binary are required")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 500 * time.Millisecond
+	}
+	if cfg.StopTimeout <= 0 {
+		cfg.StopTimeout = 20 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logging.Default()
+	}
+	w := &watcher{cfg: cfg}
+	w.last = w.stamp()
+	w.rebuild(ctx)
+	t := time.NewTicker(cfg.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			w.stop()
+			return nil
+		case err := <-w.exited:
+			// 서버가 스스로 끝났다. 다시 시작해도 같은 이유로 끝날 것이므로 다음 변경을 기다린다.
+			w.child, w.exited = nil, nil
+			cfg.Logger.Error("devwatch: server exited, waiting for changes", "err", err)
+		case <-t.C:
+			if stamp := w.stamp(); stamp != w.last {
+				w.last = stamp
+				w.rebuild(ctx)
+			}
+		}
+	}
+}
+
+type watcher struct {
+	cfg    Config
+	last   string
+	child  *exec.Cmd
+	exited chan error
+}
+
+// rebuild 는 빌드가 성공하면 서버를 재시작한다.
+func (w *watcher) rebuild(ctx context.Context) {
+	l := w.cfg.Logger
+	start := time.Now()
+	build := exec.CommandContext(ctx, w.cfg.Build[0], w.cfg.Build[1:]...)
+	out, err := build.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == nil {
+			l.Error("devwatch: build failed, keeping the running server", "err", err, "output", strings.TrimSpace(string(out)))
+		}
+		return
+	}
+	l.Info("devwatch: build ok", "took", time.Since(start).Round(time.Millisecond).String())
+	w.stop()
+	cmd := exec.Command(w.cfg.Binary, w.cfg.Args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		l.Error("devwatch: start server", "err", err)
+		return
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	w.child, w.exited = cmd, exited
+	l.Info("devwatch: server started", "pid", cmd.Process.Pid)
+}
+
+// stop 은 실행 중인 서버를 SIGTERM 으로 멈추고 StopTimeout 동안 기다린다.
+func (w *watcher) stop() {
+	if w.child == nil {
+		return
+	}
+	cmd, exited := w.child, w.exited
+	w.child, w.exited = nil, nil
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		cmd.Process.Kill()
+	}
+	select {
+	case <-exited:
+	case <-time.After(w.cfg.StopTimeout):
+		w.cfg.Logger.Warn("devwatch: server did not stop in time, killing it", "pid", cmd.Process.Pid)
+		cmd.Process.Kill()
+		<-exited
+	}
+}
+
+// stamp 는 지켜보는 파일의 이름, 크기, 수정 시각을 이어 붙인 값이다.
+func (w *watcher) stamp() string {
+	bin, _ := filepath.Abs(w.cfg.Binary)
+	var b strings.Builder
+	for _, dir := range w.cfg.Dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != dir && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !slices.Contains(w.cfg.Exts, filepath.Ext(path)) {
+				return nil
+			}
+			if abs, _ := filepath.Abs(path); abs == bin {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				b.WriteString(path + " " + strconv.FormatInt(info.Size(), 10) + " " + strconv.FormatInt(info.ModTime().UnixNano(), 10) + "\n")
+			}
+			return nil
+		})
+	}
+	return b.String()
+}
@@ -0,0 +1,142 @@
+// Package download 는 큰 파일을 Range 요청(부분 응답, 여러 구간, If-Range)과 함께 내려주는 핸들러다.
+//
+// 중단된 다운로드는 클라이언트가 Range 와 If-Range 로 이어 받을 수 있다.
+// 파일이 바뀌면 ETag 가 달라지므로 If-Range 가 일치하지 않아 전체 파일을 다시 보낸다.
+package download
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// Options 는 다운로드 핸들러 설정이다.
+type Options struct {
+	// BytesPerSecond 가 0 보다 크면 요청 하나의 전송 속도를 이 값으로 제한한다.
+	BytesPerSecond int64
+	// Inline 이 false 이면 Content-Disposition: attachment 로 저장 대화상자를 띄운다.
+	Inline bool
+	// ChunkTimeout 은 조각 하나를 보내는 쓰기 시간 제한이다. 서버의 WriteTimeout 대신
+	// 조각마다 다시 설정되므로 오래 걸리는 다운로드도 끊기지 않는다. 기본값 30초.
+	ChunkTimeout time.Duration
+}
+
+// Handler 는 fsys 의 파일을 내려준다. 요청 경로는 접두사가 이미 제거되어 있어야 한다.
+// 디렉터리와 숨김 파일은 노출하지 않는다.
+func Handler(fsys fs.FS, opts Options) http.Handler {
+	if opts.ChunkTimeout <= 0 {
+		opts.ChunkTimeout = 30 * time.Second
+	}
+	return &handler{fsys: fsys, opts: opts}
+}
+
+type handler struct {
+	fsys fs.FS
+	opts Options
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" || strings.HasPrefix(path.Base(name), ".") {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		http.Error(w, "file is not seekable", http.StatusInternalServerError)
+		return
+	}
+	hd := w.Header()
+	// 크기와 수정 시각으로 만든 강한 ETag. If-Range 는 강한 비교만 허용한다.
+	hd.Set("ETag", fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size()))
+	hd.Set("Accept-Ranges", "bytes")
+	hd.Set("Cache-Control", "private, no-cache")
+	disposition := "attachment"
+	if h.opts.Inline {
+		disposition = "inline"
+	}
+	hd.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": fi.Name()}))
+	// ServeContent 가 Range, If-Range, If-None-Match, If-Modified-Since 와 multipart/byteranges 를 처리한다.
+	http.ServeContent(newThrottledWriter(r.Context(), w, h.opts), r, fi.Name(), fi.ModTime(), rs)
+}
+
+// throttledWriter 는 조각 단위로 쓰면서 쓰기 시간 제한을 늘리고, 필요하면 속도를 제한한다.
+type throttledWriter struct {
+	http.ResponseWriter
+	ctx   context.Context
+	rc    *http.ResponseController
+	opts  Options
+	chunk int
+	start time.Time
+	sent  int64
+}
+
+func newThrottledWriter(ctx context.Context, w http.ResponseWriter, opts Options) *throttledWriter {
+	chunk := 32 << 10
+	// 초당 10 번 정도 나눠 보내 속도가 고르게 보이도록 한다.
+	if opts.BytesPerSecond > 0 && opts.BytesPerSecond/10 < int64(chunk) {
+		chunk = int(max(opts.BytesPerSecond/10, 512))
+	}
+	return &throttledWriter{ResponseWriter: w, ctx: ctx, rc: http.NewResponseController(w), opts: opts, chunk: chunk}
+}
+
+func (w *throttledWriter) Write(b []byte) (int, error) {
+	if w.start.IsZero() {
+		w.start = time.Now()
+	}
+	n := 0
+	for len(b) > 0 {
+		p := b[:min(len(b), w.chunk)]
+		if err := w.wait(); err != nil {
+			return n, err
+		}
+		w.rc.SetWriteDeadline(time.Now().Add(w.opts.ChunkTimeout))
+		m, err := w.ResponseWriter.Write(p)
+		n += m
+		w.sent += int64(m)
+		if err != nil {
+			return n, err
+		}
+		b = b[m:]
+	}
+	return n, nil
+}
+
+// wait 는 지금까지 보낸 양이 허용 속도를 넘었으면 따라잡을 때까지 기다린다.
+func (w *throttledWriter) wait() error {
+	if w.opts.BytesPerSecond <= 0 {
+		return nil
+	}
+	due := w.start.Add(time.Duration(float64(w.sent) / float64(w.opts.BytesPerSecond) * float64(time.Second)))
+	d := time.Until(due)
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (w *throttledWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
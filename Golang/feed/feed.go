@@ -0,0 +1,194 @@
+// Package feed 는 콘텐츠 목록으로 Atom/RSS 피드(/feed.xml)와 sitemap.xml 을 만든다.
+//
+// 콘텐츠는 Provider 가 준다. 파일 기반 페이지(pages.Site)나 DB 의 글 저장소가 Provider 를 구현하면 된다.
+package feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Entry 는 피드와 사이트맵의 항목 하나다.
+type Entry struct {
+	// Path 는 사이트 안의 경로다. (예: /blog/hello)
+	Path    string
+	Title   string
+	Summary string
+	// Published 가 0 이면 글이 아닌 페이지로 보고 사이트맵에만 넣는다.
+	Published time.Time
+	// Updated 가 0 이면 Published 를 쓴다.
+	Updated time.Time
+}
+
+func (e Entry) updated() time.Time {
+	if e.Updated.IsZero() {
+		return e.Published
+	}
+	return e.Updated
+}
+
+// Provider 는 피드와 사이트맵에 넣을 항목을 준다.
+type Provider interface {
+	Entries(ctx context.Context) ([]Entry, error)
+}
+
+// ProviderFunc 는 함수로 된 Provider 다.
+type ProviderFunc func(ctx context.Context) ([]Entry, error)
+
+func (f ProviderFunc) Entries(ctx context.Context) ([]Entry, error) { return f(ctx) }
+
+// Config 는 피드의 정보다.
+type Config struct {
+	Title       string
+	Description string
+	Author      string
+	// BaseURL 은 항목 주소의 앞부분이다. (예: https://example.com) 비어 있으면 요청의 Host 로 만든다.
+	BaseURL string
+	// Format 은 "atom"(기본값) 또는 "rss" 다.
+	Format string
+	// Limit 은 피드에 넣을 최근 글 수다. 0 이면 20.
+	Limit int
+	// MaxAge 는 Cache-Control 의 max-age 다. 0 이면 1시간.
+	MaxAge time.Duration
+}
+
+// Feeds 는 Provider 들의 항목으로 피드와 사이트맵을 만든다.
+type Feeds struct {
+	Config
+	Providers []Provider
+}
+
+// New 는 Feeds 를 만든다.
+func New(cfg Config, providers ...Provider) *Feeds {
+	if cfg.Format == "" {
+		cfg.Format = "atom"
+	}
+	if cfg.Limit <= 0 {
+		cfg.Limit = 20
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = time.Hour
+	}
+	return &Feeds{Config: cfg, Providers: providers}
+}
+
+// Mount 는 GET /feed.xml 과 /sitemap.xml 을 등록한다.
+func (f *Feeds) Mount(r router.Routes, mws ...router.Middleware) {
+	r.GET("/feed.xml", f.FeedHandler(), mws...)
+	r.GET("/sitemap.xml", f.SitemapHandler(), mws...)
+}
+
+// entries 는 모든 Provider 의 항목을 모은다.
+func (f *Feeds) entries(ctx context.Context) ([]Entry, error) {
+	var all []Entry
+	for _, p := range f.Providers {
+		es, err := p.Entries(ctx)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, es...)
+	}
+	return all, nil
+}
+
+// FeedHandler 는 Format 형식의 피드를 쓴다.
+func (f *Feeds) FeedHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		all, err := f.entries(r.Context())
+		if err != nil {
+			logging.From(r.Context()).Error("feed: entries", "err", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		var posts []Entry
+		for _, e := range all {
+			if !e.Published.IsZero() {
+				posts = append(posts, e)
+			}
+		}
+		sort.SliceStable(posts, func(i, j int) bool { return posts[i].Published.After(posts[j].Published) })
+		if len(posts) > f.Limit {
+			posts = posts[:f.Limit]
+		}
+		base := f.baseURL(r)
+		var doc any
+		contentType := "application/atom+xml; charset=utf-8"
+		if f.Format == "rss" {
+			doc, contentType = f.rss(base, posts), "application/rss+xml; charset=utf-8"
+		} else {
+			doc = f.atom(base, posts)
+		}
+		f.write(w, r, contentType, doc, lastModified(posts))
+	}
+}
+
+// SitemapHandler 는 모든 항목의 sitemap.xml 을 쓴다.
+func (f *Feeds) SitemapHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		all, err := f.entries(r.Context())
+		if err != nil {
+			logging.From(r.Context()).Error("feed: entries", "err", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		sort.SliceStable(all, func(i, j int) bool { return all[i].Path < all[j].Path })
+		// 사이트맵 파일 하나에는 50,000 개까지 넣을 수 있다.
+		if len(all) > 50000 {
+			all = all[:50000]
+		}
+		base := f.baseURL(r)
+		set := urlset{NS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+		for _, e := range all {
+			u := sitemapURL{Loc: base + e.Path}
+			if t := e.updated(); !t.IsZero() {
+				u.LastMod = t.UTC().Format(time.DateOnly)
+			}
+			set.URLs = append(set.URLs, u)
+		}
+		f.write(w, r, "application/xml; charset=utf-8", set, lastModified(all))
+	}
+}
+
+// write 는 doc 을 XML 로 쓴다. 캐시 헤더를 붙이고, If-Modified-Since 와 HEAD 는 http.ServeContent 가 처리한다.
+func (f *Feeds) write(w http.ResponseWriter, r *http.Request, contentType string, doc any, modified time.Time) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(doc); err != nil {
+		logging.From(r.Context()).Error("feed: encode", "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(f.MaxAge.Seconds())))
+	http.ServeContent(w, r, "", modified, bytes.NewReader(buf.Bytes()))
+}
+
+func (f *Feeds) baseURL(r *http.Request) string {
+	if f.BaseURL != "" {
+		return strings.TrimSuffix(f.BaseURL, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func lastModified(es []Entry) time.Time {
+	var t time.Time
+	for _, e := range es {
+		if u := e.updated(); u.After(t) {
+			t = u
+		}
+	}
+	return t
+}
@@ -0,0 +1,118 @@
+package feed
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Atom (RFC 4287)
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"feed"`
+	NS       string      `xml:"xmlns,attr"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	ID       string      `xml:"id"`
+	Links    []atomLink  `xml:"link"`
+	Updated  string      `xml:"updated"`
+	Author   *atomAuthor `xml:"author,omitempty"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title     string   `xml:"title"`
+	ID        string   `xml:"id"`
+	Link      atomLink `xml:"link"`
+	Published string   `xml:"published"`
+	Updated   string   `xml:"updated"`
+	Summary   string   `xml:"summary,omitempty"`
+}
+
+func (f *Feeds) atom(base string, posts []Entry) atomFeed {
+	doc := atomFeed{
+		NS: "http://www.w3.org/2005/Atom", Title: f.Title, Subtitle: f.Description, ID: base + "/",
+		Links: []atomLink{
+			{Href: base + "/"},
+			{Href: base + "/feed.xml", Rel: "self", Type: "application/atom+xml"},
+		},
+		Updated: lastModified(posts).UTC().Format(time.RFC3339),
+	}
+	// 항목마다 author 가 없으므로 피드의 author 는 반드시 있어야 한다.
+	author := f.Author
+	if author == "" {
+		author = f.Title
+	}
+	doc.Author = &atomAuthor{Name: author}
+	for _, e := range posts {
+		u := base + e.Path
+		doc.Entries = append(doc.Entries, atomEntry{
+			Title: e.Title, ID: u, Link: atomLink{Href: u}, Summary: e.Summary,
+			Published: e.Published.UTC().Format(time.RFC3339), Updated: e.updated().UTC().Format(time.RFC3339),
+		})
+	}
+	return doc
+}
+
+// RSS 2.0
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description,omitempty"`
+}
+
+type rssGUID struct {
+	Value     string `xml:",chardata"`
+	PermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+func (f *Feeds) rss(base string, posts []Entry) rssFeed {
+	ch := rssChannel{Title: f.Title, Link: base + "/", Description: f.Description}
+	if t := lastModified(posts); !t.IsZero() {
+		ch.LastBuildDate = t.UTC().Format(time.RFC1123Z)
+	}
+	for _, e := range posts {
+		u := base + e.Path
+		ch.Items = append(ch.Items, rssItem{
+			Title: e.Title, Link: u, GUID: rssGUID{Value: u, PermaLink: true},
+			PubDate: e.Published.UTC().Format(time.RFC1123Z), Description: e.Summary,
+		})
+	}
+	return rssFeed{Version: "2.0", Channel: ch}
+}
+
+// sitemaps.org 0.9
+type urlset struct {
+	XMLName xml.Name     `xml:"urlset"`
+	NS      string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
@@ -0,0 +1,241 @@
+// Package flags 는 재배포 없이 기능을 켜고 끄는 기능 플래그다.
+//
+// 플래그 값은 Source (설정 파일, 환경 변수, 원격 주소) 에서 읽어 뒤의 Source 가 앞의 것을 덮어쓰며,
+// 관리 API 로 바꾼 값(Override)이 그 위에 놓인다. 핸들러는 flags.Enabled(ctx, "new-greeting") 으로 확인한다.
+//
+// 켜진 플래그는 Percentage 로 일부 사용자에게만 켤 수 있고, Users 에 있는 사용자에게는 항상 켜진다.
+// 같은 사용자는 같은 플래그에서 항상 같은 결과를 받는다.
+package flags
+
+import (
+	"context"
+	"hash/fnv"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/auth"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Flag 는 플래그 하나의 규칙이다.
+type Flag struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// Percentage 는 켜진 플래그를 받을 사용자 비율(1~99)이다. 0 이나 100 이면 모든 사용자다.
+	// 사용자를 알 수 없는 요청은 비율 대상에서 빠진다.
+	Percentage int `json:"percentage,omitempty"`
+	// Users 는 Enabled 와 관계없이 플래그가 켜지는 사용자다.
+	Users []string `json:"users,omitempty"`
+}
+
+// On 은 subject 사용자에게 플래그가 켜져 있는지 확인한다. subject 가 비어 있으면 익명 사용자다.
+func (f Flag) On(subject string) bool {
+	if subject != "" && slices.Contains(f.Users, subject) {
+		return true
+	}
+	if !f.Enabled {
+		return false
+	}
+	if f.Percentage <= 0 || f.Percentage >= 100 {
+		return true
+	}
+	if subject == "" {
+		return false
+	}
+	return bucket(f.Name, subject) < f.Percentage
+}
+
+// bucket 은 플래그와 사용자로 정해지는 0~99 의 값이다. 플래그마다 다른 사용자가 먼저 켜지도록 이름을 섞는다.
+func bucket(name, subject string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name + "\x00" + subject))
+	return int(h.Sum32() % 100)
+}
+
+// Set 은 Source 들과 실행 중 변경을 합친 플래그 모음이다.
+type Set struct {
+	// Sources 는 앞에서부터 읽어 합친다. 같은 이름은 뒤의 Source 가 이긴다.
+	Sources []Source
+	// Subject 는 요청 컨텍스트의 사용자 식별자다. nil 이면 JWT 의 subject 를 쓴다.
+	Subject func(ctx context.Context) string
+
+	mu        sync.Mutex
+	loaded    []map[string]Flag // Source 별 마지막으로 읽은 값
+	overrides map[string]Flag
+	current   atomic.Pointer[map[string]Flag]
+}
+
+// New 는 sources 를 읽는 Set 을 만든다. 값은 Refresh 를 호출해야 읽는다.
+func New(sources ...Source) *Set {
+	return &Set{Sources: sources}
+}
+
+// Default 는 패키지 수준 함수가 사용하는 기본 Set 이다.
+var Default = New()
+
+// Enabled 는 Default 에서 요청 사용자에게 name 이 켜져 있는지 확인한다.
+func Enabled(ctx context.Context, name string) bool { return Default.Enabled(ctx, name) }
+
+// Enabled 는 요청 사용자에게 name 이 켜져 있는지 확인한다. 없는 플래그는 꺼져 있다.
+func (s *Set) Enabled(ctx context.Context, name string) bool {
+	f, ok := s.Get(name)
+	return ok && f.On(s.subject(ctx))
+}
+
+func (s *Set) subject(ctx context.Context) string {
+	if s.Subject != nil {
+		return s.Subject(ctx)
+	}
+	if c := auth.ClaimsFrom(ctx); c != nil {
+		return c.Subject
+	}
+	return ""
+}
+
+// Get 은 name 의 현재 규칙이다.
+func (s *Set) Get(name string) (Flag, bool) {
+	m := s.current.Load()
+	if m == nil {
+		return Flag{}, false
+	}
+	f, ok := (*m)[name]
+	return f, ok
+}
+
+// All 은 모든 플래그의 현재 규칙을 이름 순으로 돌려준다.
+func (s *Set) All() []Flag {
+	m := s.current.Load()
+	if m == nil {
+		return []Flag{}
+	}
+	out := make([]Flag, 0, len(*m))
+	for _, name := range slices.Sorted(maps.Keys(*m)) {
+		out = append(out, (*m)[name])
+	}
+	return out
+}
+
+// Refresh 는 모든 Source 를 다시 읽는다. 읽지 못한 Source 는 이전 값을 유지하며, 에러는 마지막 것을 돌려준다.
+func (s *Set) Refresh(ctx context.Context) error {
+	var lastErr error
+	results := make([]map[string]Flag, len(s.Sources))
+	for i, src := range s.Sources {
+		m, err := src.Load(ctx)
+		if err != nil {
+			lastErr = err
+			logging.Default().Warn("flags: load failed, keeping previous values", "source", src.String(), "err", err)
+			continue
+		}
+		results[i] = m
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loaded == nil {
+		s.loaded = make([]map[string]Flag, len(s.Sources))
+	}
+	for i, m := range results {
+		if m != nil {
+			s.loaded[i] = m
+		}
+	}
+	s.publish()
+	return lastErr
+}
+
+// Watch 는 ctx 가 끝날 때까지 interval 마다 Refresh 한다.
+func (s *Set) Watch(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			s.Refresh(ctx)
+		}
+	}
+}
+
+// Override 는 Source 값과 관계없이 f 를 쓴다. Source 를 다시 읽어도 유지된다.
+func (s *Set) Override(f Flag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.overrides == nil {
+		s.overrides = map[string]Flag{}
+	}
+	s.overrides[f.Name] = f
+	s.publish()
+}
+
+// ClearOverride 는 name 의 Override 를 지워 Source 값으로 되돌린다. 지운 것이 있으면 true 다.
+func (s *Set) ClearOverride(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.overrides[name]; !ok {
+		return false
+	}
+	delete(s.overrides, name)
+	s.publish()
+	return true
+}
+
+// publish 는 Source 값과 Override 를 합쳐 새 스냅샷으로 바꾼다. s.mu 를 잡은 채로 호출한다.
+func (s *Set) publish() {
+	m := map[string]Flag{}
+	for _, l := range s.loaded {
+		for name, f := range l {
+			f.Name = name
+			m[name] = f
+		}
+	}
+	maps.Copy(m, s.overrides)
+	s.current.Store(&m)
+}
+
+// Mount 는 r 의 prefix 아래에 플래그 관리 라우트를 등록한다. mws 로 관리자 인증을 건다.
+//
+//	GET    prefix         모든 플래그
+//	PUT    prefix/{name}  {"enabled": true, "percentage": 10} 으로 실행 중 변경
+//	DELETE prefix/{name}  실행 중 변경을 지우고 Source 값으로 되돌림
+func (s *Set) Mount(r router.Routes, prefix string, mws ...router.Middleware) {
+	g := r.Group(prefix, mws...)
+	g.GET("", s.list)
+	g.PUT("/{name}", s.put)
+	g.DELETE("/{name}", s.clear)
+}
+
+func (s *Set) list(w http.ResponseWriter, r *http.Request) {
+	api.WriteJSON(w, http.StatusOK, map[string][]Flag{"flags": s.All()})
+}
+
+func (s *Set) put(w http.ResponseWriter, r *http.Request) {
+	var f Flag
+	if err := api.ReadJSON(r, &f); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+	if f.Percentage < 0 || f.Percentage > 100 {
+		api.WriteError(w, api.BadRequest("percentage must be between 0 and 100"))
+		return
+	}
+	f.Name = router.Param(r, "name")
+	s.Override(f)
+	logging.From(r.Context()).Warn("feature flag changed", "flag", f.Name, "enabled", f.Enabled, "percentage", f.Percentage)
+	api.WriteJSON(w, http.StatusOK, f)
+}
+
+func (s *Set) clear(w http.ResponseWriter, r *http.Request) {
+	name := router.Param(r, "name")
+	if !s.ClearOverride(name) {
+		api.WriteError(w, api.NewError(http.StatusNotFound, "not_found", "flag has no runtime override"))
+		return
+	}
+	logging.From(r.Context()).Warn("feature flag override cleared", "flag", name)
+	w.WriteHeader(http.StatusNoContent)
+}
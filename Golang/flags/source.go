@@ -0,0 +1,123 @@
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Source 는 플래그 규칙을 읽어 오는 곳이다. 돌려준 맵의 키가 플래그 이름이다.
+type Source interface {
+	Load(ctx context.Context) (map[string]Flag, error)
+	// String 은 로그에 쓰는 이름이다.
+	String() string
+}
+
+// File 은 JSON 파일에서 플래그를 읽는다. 형식은 {"new-greeting": {"enabled": true, "percentage": 25}} 이다.
+// 파일이 없으면 플래그가 없는 것으로 본다.
+type File string
+
+func (f File) Load(context.Context) (map[string]Flag, error) {
+	data, err := os.ReadFile(string(f))
+	if os.IsNotExist(err) {
+		return map[string]Flag{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decode(data)
+}
+
+func (f File) String() string { return "file:" + string(f) }
+
+// Env 는 Prefix 로 시작하는 환경 변수에서 플래그를 읽는다. APP_FLAG_NEW_GREETING=on 은 "new-greeting" 이 된다.
+// 값은 "on"/"true", "off"/"false", 또는 비율 "25%" 다.
+type Env struct {
+	Prefix string
+}
+
+func (e Env) Load(context.Context) (map[string]Flag, error) {
+	out := map[string]Flag{}
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		rest, ok := strings.CutPrefix(k, e.Prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		name := strings.ToLower(strings.ReplaceAll(rest, "_", "-"))
+		f := Flag{Name: name}
+		switch v = strings.ToLower(strings.TrimSpace(v)); {
+		case v == "on" || v == "true" || v == "1":
+			f.Enabled = true
+		case v == "off" || v == "false" || v == "0":
+		case strings.HasSuffix(v, "%"):
+			pct, err := strconv.Atoi(strings.TrimSuffix(v, "%"))
+			if err != nil || pct < 0 || pct > 100 {
+				return nil, fmt.Errorf("flags: %s: invalid percentage %q", k, v)
+			}
+			f.Enabled, f.Percentage = pct > 0, pct
+		default:
+			return nil, fmt.Errorf("flags: %s: value %q is not on, off or a percentage", k, v)
+		}
+		out[name] = f
+	}
+	return out, nil
+}
+
+func (e Env) String() string { return "env:" + e.Prefix + "*" }
+
+// Remote 는 URL 에 GET 요청을 보내 File 과 같은 형식의 JSON 을 읽는다.
+type Remote struct {
+	URL string
+	// Client 가 nil 이면 http.DefaultClient 를 쓴다.
+	Client *http.Client
+}
+
+func (rm Remote) Load(ctx context.Context) (map[string]Flag, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rm.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	c := rm.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("flags: %s returned %s", rm.URL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	return decode(data)
+}
+
+func (rm Remote) String() string { return "remote:" + rm.URL }
+
+// decode 는 플래그 JSON 을 읽고 비율을 검사한다.
+func decode(data []byte) (map[string]Flag, error) {
+	var m map[string]Flag
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("flags: %w", err)
+	}
+	for name, f := range m {
+		if f.Percentage < 0 || f.Percentage > 100 {
+			return nil, fmt.Errorf("flags: %s: percentage must be between 0 and 100", name)
+		}
+	}
+	if m == nil {
+		m = map[string]Flag{}
+	}
+	return m, nil
+}
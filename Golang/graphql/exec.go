@@ -0,0 +1,787 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hgsong234/_stack/Golang/logging"
+)
+
+// DefaultMaxDepth 는 Schema.MaxDepth 가 0 일 때 허용하는 선택 집합의 중첩 깊이다.
+const DefaultMaxDepth = 12
+
+// Request 는 GraphQL over HTTP 요청 본문이다.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response 는 실행 결과다. 문법이나 검사 에러로 실행하지 못했으면 Data 가 없다.
+type Response struct {
+	Data   any      `json:"data"`
+	Errors []*Error `json:"errors,omitempty"`
+
+	executed bool
+}
+
+// MarshalJSON 은 실행하지 못한 응답에서 data 를 뺀다.
+func (r *Response) MarshalJSON() ([]byte, error) {
+	type plain Response
+	if r.executed {
+		return json.Marshal((*plain)(r))
+	}
+	return json.Marshal(struct {
+		Errors []*Error `json:"errors"`
+	}{r.Errors})
+}
+
+// Error 는 응답의 에러 하나다. 리졸버가 *Error 를 돌려주면 Message 와 Extensions 를 그대로 쓴다.
+// 다른 에러는 Error() 문자열이 메시지가 된다.
+type Error struct {
+	Message    string         `json:"message"`
+	Locations  []Location     `json:"locations,omitempty"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (s *Schema) maxDepth() int {
+	if s.MaxDepth > 0 {
+		return s.MaxDepth
+	}
+	return DefaultMaxDepth
+}
+
+// Execute 는 req 를 실행한다.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	return s.execute(ctx, req, false)
+}
+
+// execute 는 req 를 실행한다. queryOnly 이면 뮤테이션을 거절한다. (GET 요청)
+func (s *Schema) execute(ctx context.Context, req Request, queryOnly bool) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		var se *syntaxError
+		if errors.As(err, &se) {
+			return &Response{Errors: []*Error{{Message: "Syntax Error: " + se.msg, Locations: []Location{se.loc}}}}
+		}
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	op, gerr := pickOperation(doc, req.OperationName)
+	if gerr != nil {
+		return &Response{Errors: []*Error{gerr}}
+	}
+	var root *Object
+	switch op.kind {
+	case "query":
+		root = s.Query
+	case "mutation":
+		if queryOnly {
+			return &Response{Errors: []*Error{{Message: "mutations must be sent with POST", Locations: []Location{op.loc}}}}
+		}
+		root = s.Mutation
+	}
+	if root == nil {
+		return &Response{Errors: []*Error{{Message: fmt.Sprintf("schema does not support %s operations", op.kind), Locations: []Location{op.loc}}}}
+	}
+	e := &execution{schema: s, doc: doc}
+	if errs := e.coerceVariables(op, normalize(req.Variables)); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+	if errs := e.validate(root, op); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+	ctx = context.WithValue(ctx, execKey{}, e)
+	data, _ := e.selectionSet(ctx, root, nil, op.sel, nil, op.kind == "mutation")
+	resp := &Response{executed: true, Errors: e.errs}
+	if data != nil {
+		resp.Data = data
+	}
+	return resp
+}
+
+func pickOperation(doc *document, name string) (*operation, *Error) {
+	if name == "" {
+		if len(doc.ops) > 1 {
+			return nil, &Error{Message: "operationName is required when the document has several operations"}
+		}
+		return doc.ops[0], nil
+	}
+	for _, op := range doc.ops {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, &Error{Message: fmt.Sprintf("unknown operation %q", name)}
+}
+
+// normalize 는 JSON 변수의 json.Number 를 int64 나 float64 로 바꾼다.
+func normalize(v map[string]any) map[string]any {
+	out := make(map[string]any, len(v))
+	for k, x := range v {
+		out[k] = normalizeValue(x)
+	}
+	return out
+}
+
+func normalizeValue(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		return normalize(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, x := range v {
+			out[i] = normalizeValue(x)
+		}
+		return out
+	}
+	return v
+}
+
+type execKey struct{}
+
+type execution struct {
+	schema *Schema
+	doc    *document
+	vars   map[string]any
+
+	mu      sync.Mutex
+	errs    []*Error
+	loaders map[string]any
+}
+
+func (e *execution) fail(err *Error) {
+	e.mu.Lock()
+	e.errs = append(e.errs, err)
+	e.mu.Unlock()
+}
+
+// fieldError 는 리졸버 에러를 path 의 Error 로 기록한다.
+func (e *execution) fieldError(err error, f *selField, path []any) {
+	ge := &Error{Message: err.Error()}
+	var custom *Error
+	if errors.As(err, &custom) {
+		ge.Message, ge.Extensions = custom.Message, custom.Extensions
+	}
+	ge.Locations = []Location{f.loc}
+	ge.Path = slices.Clone(path)
+	e.fail(ge)
+}
+
+// 변수
+
+func (e *execution) inputType(t *typeRef) Type {
+	var out Type
+	if t.list != nil {
+		of := e.inputType(t.list)
+		if of == nil {
+			return nil
+		}
+		out = NewList(of)
+	} else {
+		out = e.schema.types[t.name]
+		if out == nil || !isInput(out) {
+			return nil
+		}
+	}
+	if t.nonNull {
+		return NonNull(out)
+	}
+	return out
+}
+
+func (e *execution) coerceVariables(op *operation, given map[string]any) []*Error {
+	e.vars = map[string]any{}
+	var errs []*Error
+	for _, v := range op.vars {
+		t := e.inputType(v.typ)
+		if t == nil {
+			errs = append(errs, &Error{Message: fmt.Sprintf("variable $%s has unknown or non-input type %s", v.name, v.typ), Locations: []Location{v.loc}})
+			continue
+		}
+		val, ok := given[v.name]
+		switch {
+		case ok:
+			c, err := coerceInput(val, t)
+			if err != nil {
+				errs = append(errs, &Error{Message: fmt.Sprintf("variable $%s: %v", v.name, err), Locations: []Location{v.loc}})
+				continue
+			}
+			e.vars[v.name] = c
+		case v.def != nil:
+			c, _, err := e.coerceLiteral(v.def, t)
+			if err != nil {
+				errs = append(errs, &Error{Message: fmt.Sprintf("variable $%s default: %v", v.name, err), Locations: []Location{v.loc}})
+				continue
+			}
+			e.vars[v.name] = c
+		default:
+			if _, nn := t.(*NonNullType); nn {
+				errs = append(errs, &Error{Message: fmt.Sprintf("variable $%s of required type %s was not provided", v.name, t), Locations: []Location{v.loc}})
+			}
+		}
+	}
+	return errs
+}
+
+// coerceInput 은 변수(JSON) 값을 t 로 바꾼다.
+func coerceInput(v any, t Type) (any, error) {
+	if nn, ok := t.(*NonNullType); ok {
+		if v == nil {
+			return nil, fmt.Errorf("expected non-null %s", t)
+		}
+		return coerceInput(v, nn.Of)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	switch t := t.(type) {
+	case *List:
+		items, ok := v.([]any)
+		if !ok {
+			// 리스트 자리에 값 하나를 주면 한 항목짜리 리스트로 본다.
+			items = []any{v}
+		}
+		out := make([]any, len(items))
+		for i, item := range items {
+			c, err := coerceInput(item, t.Of)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			out[i] = c
+		}
+		return out, nil
+	case *Scalar:
+		return t.Parse(v)
+	case *Enum:
+		s, ok := v.(string)
+		if !ok || !slices.Contains(t.Values, s) {
+			return nil, fmt.Errorf("%v is not a value of enum %s", v, t.Name)
+		}
+		return s, nil
+	case *InputObject:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected object of type %s", t.Name)
+		}
+		for k := range m {
+			if !slices.ContainsFunc(t.Fields, func(a *Arg) bool { return a.Name == k }) {
+				return nil, fmt.Errorf("field %q is not defined by type %s", k, t.Name)
+			}
+		}
+		out := map[string]any{}
+		for _, f := range t.Fields {
+			x, ok := m[f.Name]
+			switch {
+			case ok:
+				c, err := coerceInput(x, f.Type)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", t.Name, f.Name, err)
+				}
+				out[f.Name] = c
+			case f.Default != nil:
+				c, err := coerceInput(normalizeValue(f.Default), f.Type)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s default: %w", t.Name, f.Name, err)
+				}
+				out[f.Name] = c
+			default:
+				if _, nn := f.Type.(*NonNullType); nn {
+					return nil, fmt.Errorf("field %s.%s of required type %s was not provided", t.Name, f.Name, f.Type)
+				}
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("%s is not an input type", t)
+}
+
+// coerceLiteral 은 쿼리에 적은 값을 t 로 바꾼다. 정의되지 않았거나 주지 않은 변수이면 present 가 false 다.
+func (e *execution) coerceLiteral(v *value, t Type) (out any, present bool, err error) {
+	if v.kind == valVariable {
+		x, ok := e.vars[v.raw]
+		if !ok {
+			if !slices.ContainsFunc(e.declared(), func(name string) bool { return name == v.raw }) {
+				return nil, false, fmt.Errorf("variable $%s is not defined", v.raw)
+			}
+			return nil, false, nil
+		}
+		if _, nn := t.(*NonNullType); nn && x == nil {
+			return nil, true, fmt.Errorf("variable $%s must not be null", v.raw)
+		}
+		return x, true, nil
+	}
+	if nn, ok := t.(*NonNullType); ok {
+		if v.kind == valNull {
+			return nil, true, fmt.Errorf("expected non-null %s", t)
+		}
+		return e.coerceLiteral(v, nn.Of)
+	}
+	if v.kind == valNull {
+		return nil, true, nil
+	}
+	switch t := t.(type) {
+	case *List:
+		if v.kind != valList {
+			c, _, err := e.coerceLiteral(v, t.Of)
+			return []any{c}, true, err
+		}
+		items := make([]any, len(v.list))
+		for i, item := range v.list {
+			c, _, err := e.coerceLiteral(item, t.Of)
+			if err != nil {
+				return nil, true, fmt.Errorf("[%d]: %w", i, err)
+			}
+			items[i] = c
+		}
+		return items, true, nil
+	case *Scalar:
+		var x any
+		switch v.kind {
+		case valInt:
+			n, err := strconv.ParseInt(v.raw, 10, 64)
+			if err != nil {
+				return nil, true, fmt.Errorf("%s cannot represent %s", t.Name, v.raw)
+			}
+			x = n
+		case valFloat:
+			f, _ := strconv.ParseFloat(v.raw, 64)
+			x = f
+		case valString:
+			x = v.raw
+		case valBool:
+			x = v.raw == "true"
+		default:
+			return nil, true, fmt.Errorf("%s cannot represent a non-scalar value", t.Name)
+		}
+		c, err := t.Parse(x)
+		return c, true, err
+	case *Enum:
+		if v.kind != valEnum || !slices.Contains(t.Values, v.raw) {
+			return nil, true, fmt.Errorf("%s is not a value of enum %s", v.raw, t.Name)
+		}
+		return v.raw, true, nil
+	case *InputObject:
+		if v.kind != valObject {
+			return nil, true, fmt.Errorf("expected object of type %s", t.Name)
+		}
+		out := map[string]any{}
+		args, err := e.coerceArgs(t.Fields, v.fields)
+		for k, x := range args {
+			out[k] = x
+		}
+		return out, true, err
+	}
+	return nil, true, fmt.Errorf("%s is not an input type", t)
+}
+
+func (e *execution) declared() []string {
+	var names []string
+	for _, op := range e.doc.ops {
+		for _, v := range op.vars {
+			names = append(names, v.name)
+		}
+	}
+	return names
+}
+
+// coerceArgs 는 인자 값에 기본값을 채우고 타입에 맞게 바꾼다.
+func (e *execution) coerceArgs(defs []*Arg, given []*argument) (map[string]any, error) {
+	out := map[string]any{}
+	for _, a := range given {
+		if !slices.ContainsFunc(defs, func(d *Arg) bool { return d.Name == a.name }) {
+			return nil, fmt.Errorf("unknown argument %q", a.name)
+		}
+	}
+	for _, d := range defs {
+		i := slices.IndexFunc(given, func(a *argument) bool { return a.name == d.Name })
+		if i >= 0 {
+			v, present, err := e.coerceLiteral(given[i].val, d.Type)
+			if err != nil {
+				return nil, fmt.Errorf("argument %q: %w", d.Name, err)
+			}
+			if present {
+				out[d.Name] = v
+				continue
+			}
+		}
+		switch {
+		case d.Default != nil:
+			v, err := coerceInput(normalizeValue(d.Default), d.Type)
+			if err != nil {
+				return nil, fmt.Errorf("argument %q default: %w", d.Name, err)
+			}
+			out[d.Name] = v
+		default:
+			if _, nn := d.Type.(*NonNullType); nn {
+				return nil, fmt.Errorf("argument %q of required type %s was not provided", d.Name, d.Type)
+			}
+		}
+	}
+	return out, nil
+}
+
+// 검사
+
+func (e *execution) validate(root *Object, op *operation) []*Error {
+	v := &validator{e: e, max: e.schema.maxDepth()}
+	v.selections(root, op.sel, 1, map[string]bool{})
+	return v.errs
+}
+
+type validator struct {
+	e   *execution
+	max int
+	// introspection 은 __schema, __type 아래를 검사하는 중인지다. 표준 인트로스펙션 쿼리는
+	// ofType 을 깊게 중첩하므로 깊이 제한을 두지 않는다.
+	introspection bool
+	errs          []*Error
+}
+
+func (v *validator) errorf(loc Location, format string, args ...any) {
+	v.errs = append(v.errs, &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}})
+}
+
+func (v *validator) selections(t *Object, sels []selection, depth int, spreading map[string]bool) {
+	if depth > v.max && !v.introspection {
+		loc := Location{}
+		if f, ok := sels[0].(*selField); ok {
+			loc = f.loc
+		}
+		v.errorf(loc, "query is nested deeper than %d levels", v.max)
+		return
+	}
+	for _, s := range sels {
+		switch s := s.(type) {
+		case *selField:
+			v.field(t, s, depth, spreading)
+		case *inlineFragment:
+			if s.on != "" && s.on != t.Name {
+				v.errorf(s.loc, "fragment on %s cannot be spread on type %s", s.on, t.Name)
+				continue
+			}
+			v.directives(s.dirs)
+			v.selections(t, s.sel, depth, spreading)
+		case *fragmentSpread:
+			f := v.e.doc.frags[s.name]
+			switch {
+			case f == nil:
+				v.errorf(s.loc, "unknown fragment %q", s.name)
+			case spreading[s.name]:
+				v.errorf(s.loc, "fragment %q spreads itself", s.name)
+			case f.on != t.Name:
+				v.errorf(s.loc, "fragment %q on %s cannot be spread on type %s", s.name, f.on, t.Name)
+			default:
+				v.directives(s.dirs)
+				spreading[s.name] = true
+				v.selections(t, f.sel, depth, spreading)
+				delete(spreading, s.name)
+			}
+		}
+	}
+}
+
+func (v *validator) field(t *Object, f *selField, depth int, spreading map[string]bool) {
+	v.directives(f.dirs)
+	def := v.e.fieldDef(t, f.name)
+	if def == nil {
+		v.errorf(f.loc, "cannot query field %q on type %q", f.name, t.Name)
+		return
+	}
+	if _, err := v.e.coerceArgs(def.Args, f.args); err != nil {
+		v.errorf(f.loc, "field %q: %v", f.name, err)
+	}
+	switch nt := named(def.Type).(type) {
+	case *Object:
+		if len(f.sel) == 0 {
+			v.errorf(f.loc, "field %q of type %q must have a selection of subfields", f.name, def.Type)
+			return
+		}
+		if def == schemaField || def == typeField {
+			v.introspection = true
+			defer func() { v.introspection = false }()
+		}
+		v.selections(nt, f.sel, depth+1, spreading)
+	default:
+		if len(f.sel) > 0 {
+			v.errorf(f.loc, "field %q must not have a selection since type %q has no subfields", f.name, def.Type)
+		}
+	}
+}
+
+func (v *validator) directives(dirs []*directive) {
+	for _, d := range dirs {
+		if d.name != "skip" && d.name != "include" {
+			v.errorf(d.loc, "unknown directive @%s", d.name)
+			continue
+		}
+		if _, err := v.e.coerceArgs(ifArg, d.args); err != nil {
+			v.errorf(d.loc, "@%s: %v", d.name, err)
+		}
+	}
+}
+
+var ifArg = []*Arg{{Name: "if", Type: NonNull(Boolean)}}
+
+// fieldDef 는 t 의 name 필드다. __typename 과 루트의 __schema, __type 도 찾는다.
+func (e *execution) fieldDef(t *Object, name string) *Field {
+	switch {
+	case name == "__typename":
+		return typenameField
+	case t == e.schema.Query && name == "__schema":
+		return schemaField
+	case t == e.schema.Query && name == "__type":
+		return typeField
+	}
+	return t.Field(name)
+}
+
+// 실행
+
+// object 는 순서를 지키는 결과 객체다.
+type object []fieldValue
+
+type fieldValue struct {
+	key string
+	val any
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(f.key)
+		b.Write(k)
+		b.WriteByte(':')
+		v, err := json.Marshal(f.val)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// collect 는 선택 집합을 응답 키별로 모은다. 같은 키의 필드는 하위 선택을 합친다.
+func (e *execution) collect(t *Object, sels []selection, keys *[]string, groups map[string][]*selField) {
+	for _, s := range sels {
+		switch s := s.(type) {
+		case *selField:
+			if !e.included(s.dirs) {
+				continue
+			}
+			k := s.key()
+			if _, ok := groups[k]; !ok {
+				*keys = append(*keys, k)
+			}
+			groups[k] = append(groups[k], s)
+		case *inlineFragment:
+			if e.included(s.dirs) && (s.on == "" || s.on == t.Name) {
+				e.collect(t, s.sel, keys, groups)
+			}
+		case *fragmentSpread:
+			if f := e.doc.frags[s.name]; f != nil && e.included(s.dirs) && f.on == t.Name {
+				e.collect(t, f.sel, keys, groups)
+			}
+		}
+	}
+}
+
+func (e *execution) included(dirs []*directive) bool {
+	for _, d := range dirs {
+		args, _ := e.coerceArgs(ifArg, d.args)
+		on, _ := args["if"].(bool)
+		if d.name == "skip" && on || d.name == "include" && !on {
+			return false
+		}
+	}
+	return true
+}
+
+// selectionSet 은 src 의 필드를 실행한다. serial 이면(뮤테이션 루트) 필드를 순서대로 하나씩 실행하고,
+// 아니면 동시에 실행한다. non-null 필드가 null 이 되면 객체 전체가 null 이다.
+func (e *execution) selectionSet(ctx context.Context, t *Object, src any, sels []selection, path []any, serial bool) (object, bool) {
+	var keys []string
+	groups := map[string][]*selField{}
+	e.collect(t, sels, &keys, groups)
+	out := make(object, len(keys))
+	failed := make([]bool, len(keys))
+	run := func(i int) {
+		fields := groups[keys[i]]
+		def := e.fieldDef(t, fields[0].name)
+		v, _ := e.resolve(ctx, t, def, src, fields, append(slices.Clip(path), keys[i]))
+		out[i] = fieldValue{keys[i], v}
+		_, nn := def.Type.(*NonNullType)
+		failed[i] = v == nil && nn
+	}
+	if serial || len(keys) == 1 {
+		for i := range keys {
+			run(i)
+		}
+	} else {
+		var wg sync.WaitGroup
+		for i := range keys {
+			wg.Go(func() { run(i) })
+		}
+		wg.Wait()
+	}
+	if slices.Contains(failed, true) {
+		return nil, true
+	}
+	return out, false
+}
+
+// resolve 는 필드 하나의 값을 만들고 타입에 맞게 완성한다. 두 번째 값은 에러를 이미 기록한 null 인지다.
+func (e *execution) resolve(ctx context.Context, t *Object, def *Field, src any, fields []*selField, path []any) (v any, errored bool) {
+	if def == typenameField {
+		return t.Name, false
+	}
+	f := fields[0]
+	args, err := e.coerceArgs(def.Args, f.args)
+	if err != nil {
+		e.fieldError(err, f, path)
+		return nil, true
+	}
+	res, err := e.call(ctx, def, Params{Context: ctx, Source: src, Args: args})
+	if err != nil {
+		e.fieldError(err, f, path)
+		return nil, true
+	}
+	return e.complete(ctx, def.Type, fields, res, path)
+}
+
+// call 은 리졸버를 부른다. 패닉은 기록하고 에러로 바꾼다.
+func (e *execution) call(ctx context.Context, def *Field, p Params) (v any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.From(ctx).Error("graphql: resolver panic", "field", def.Name, "panic", fmt.Sprint(r))
+			v, err = nil, errors.New("internal server error")
+		}
+	}()
+	if def.Resolve != nil {
+		return def.Resolve(p)
+	}
+	return defaultResolve(p.Source, def.Name), nil
+}
+
+// defaultResolve 는 src 에서 name 값을 읽는다. 구조체는 json 태그, 없으면 필드 이름(대소문자 무시)으로 찾는다.
+func defaultResolve(src any, name string) any {
+	if m, ok := src.(map[string]any); ok {
+		return m[name]
+	}
+	rv := reflect.ValueOf(src)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+		tag, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if sf.IsExported() && (tag == name || tag == "" && strings.EqualFold(sf.Name, name)) {
+			return rv.Field(i).Interface()
+		}
+	}
+	return nil
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func (e *execution) complete(ctx context.Context, t Type, fields []*selField, v any, path []any) (any, bool) {
+	if nn, ok := t.(*NonNullType); ok {
+		out, errored := e.complete(ctx, nn.Of, fields, v, path)
+		if out == nil && !errored {
+			e.fieldError(fmt.Errorf("cannot return null for non-nullable field %s", fields[0].name), fields[0], path)
+		}
+		return out, out == nil
+	}
+	if isNil(v) {
+		return nil, false
+	}
+	switch t := t.(type) {
+	case *List:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.fieldError(fmt.Errorf("expected a list for field %s", fields[0].name), fields[0], path)
+			return nil, true
+		}
+		n := rv.Len()
+		items := make([]any, n)
+		failed := make([]bool, n)
+		_, nnItem := t.Of.(*NonNullType)
+		var wg sync.WaitGroup
+		for i := range n {
+			wg.Go(func() {
+				c, _ := e.complete(ctx, t.Of, fields, rv.Index(i).Interface(), append(slices.Clip(path), i))
+				items[i] = c
+				failed[i] = c == nil && nnItem
+			})
+		}
+		wg.Wait()
+		if slices.Contains(failed, true) {
+			return nil, true
+		}
+		return items, false
+	case *Scalar:
+		out, err := t.Serialize(v)
+		if err != nil {
+			e.fieldError(err, fields[0], path)
+			return nil, true
+		}
+		return out, false
+	case *Enum:
+		s := fmt.Sprint(v)
+		if !slices.Contains(t.Values, s) {
+			e.fieldError(fmt.Errorf("%q is not a value of enum %s", s, t.Name), fields[0], path)
+			return nil, true
+		}
+		return s, false
+	case *Object:
+		var sels []selection
+		for _, f := range fields {
+			sels = append(sels, f.sel...)
+		}
+		out, errored := e.selectionSet(ctx, t, v, sels, path, false)
+		if out == nil {
+			return nil, errored
+		}
+		return out, false
+	}
+	e.fieldError(fmt.Errorf("field %s has an unsupported type %s", fields[0].name, t), fields[0], path)
+	return nil, true
+}
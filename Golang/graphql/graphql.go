@@ -0,0 +1,454 @@
+// Package graphql 은 코드로 정의한 스키마에 GraphQL 쿼리와 뮤테이션을 실행한다.
+//
+//	user := &graphql.Object{Name: "User", Fields: []*graphql.Field{
+//		{Name: "id", Type: graphql.NonNull(graphql.ID)},
+//		{Name: "name", Type: graphql.NonNull(graphql.String)},
+//	}}
+//	query := &graphql.Object{Name: "Query", Fields: []*graphql.Field{{
+//		Name: "user", Type: user,
+//		Args: []*graphql.Arg{{Name: "id", Type: graphql.NonNull(graphql.ID)}},
+//		Resolve: func(p graphql.Params) (any, error) { return users.Get(p.Context, p.Args["id"]...) },
+//	}}}
+//	schema, err := graphql.NewSchema(query, nil)
+//
+// 지원하는 것은 객체, 스칼라, 열거형, 입력 객체, 리스트, non-null 타입과 변수, 별칭, 프래그먼트,
+// @skip/@include, 인트로스펙션이다. 인터페이스, 유니온, 구독은 지원하지 않는다.
+// 같은 단계의 필드와 리스트 항목은 동시에 해석하므로 Loader 로 여러 조회를 한 번에 묶을 수 있다.
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type 은 GraphQL 타입이다. *Scalar, *Enum, *Object, *InputObject, *List, *NonNullType 중 하나다.
+type Type interface {
+	String() string
+}
+
+// Scalar 는 값 하나다. Serialize 는 결과 값을, Parse 는 변수(JSON)나 리터럴에서 온 입력 값을 바꾼다.
+type Scalar struct {
+	Name        string
+	Description string
+	Serialize   func(any) (any, error)
+	Parse       func(any) (any, error)
+}
+
+func (t *Scalar) String() string { return t.Name }
+
+// Enum 은 정해진 이름 중 하나다. 입력과 결과 모두 문자열이다.
+type Enum struct {
+	Name        string
+	Description string
+	Values      []string
+}
+
+func (t *Enum) String() string { return t.Name }
+
+// Object 는 필드의 모음이다.
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*Field
+
+	index map[string]*Field
+}
+
+func (t *Object) String() string { return t.Name }
+
+// Field 는 name 필드다. 없으면 nil 이다.
+func (t *Object) Field(name string) *Field {
+	if t.index == nil {
+		for _, f := range t.Fields {
+			if f.Name == name {
+				return f
+			}
+		}
+		return nil
+	}
+	return t.index[name]
+}
+
+// InputObject 는 인자로 받는 객체다. 해석한 값은 map[string]any 다.
+type InputObject struct {
+	Name        string
+	Description string
+	Fields      []*Arg
+}
+
+func (t *InputObject) String() string { return t.Name }
+
+// List 는 Of 타입 값의 목록이다.
+type List struct{ Of Type }
+
+func (t *List) String() string { return "[" + t.Of.String() + "]" }
+
+// NonNullType 은 null 일 수 없는 Of 타입이다.
+type NonNullType struct{ Of Type }
+
+func (t *NonNullType) String() string { return t.Of.String() + "!" }
+
+// NewList 는 [of] 타입이다.
+func NewList(of Type) *List { return &List{Of: of} }
+
+// NonNull 은 of! 타입이다.
+func NonNull(of Type) *NonNullType { return &NonNullType{Of: of} }
+
+// named 는 List 와 NonNull 을 벗긴 타입이다.
+func named(t Type) Type {
+	for {
+		switch u := t.(type) {
+		case *List:
+			t = u.Of
+		case *NonNullType:
+			t = u.Of
+		default:
+			return t
+		}
+	}
+}
+
+// ResolveFunc 는 필드의 값을 만든다. 객체 타입 필드는 구조체, 구조체 포인터, map[string]any 를 돌려준다.
+type ResolveFunc func(p Params) (any, error)
+
+// Params 는 ResolveFunc 에 넘기는 값이다.
+type Params struct {
+	Context context.Context
+	// Source 는 부모 필드가 돌려준 값이다. 루트 필드는 nil 이다.
+	Source any
+	// Args 는 기본값과 변수를 적용하고 타입에 맞게 바꾼 인자다.
+	Args map[string]any
+}
+
+// Field 는 객체의 필드다. Resolve 가 nil 이면 Source 에서 같은 이름(구조체는 json 태그 또는 필드 이름)의 값을 읽는다.
+type Field struct {
+	Name        string
+	Description string
+	Type        Type
+	Args        []*Arg
+	Resolve     ResolveFunc
+	// Deprecated 가 있으면 인트로스펙션에 폐기 이유로 나온다.
+	Deprecated string
+}
+
+// Arg 는 필드 인자 또는 입력 객체의 필드다.
+type Arg struct {
+	Name        string
+	Description string
+	Type        Type
+	// Default 는 인자가 없을 때의 값이다. (Go 값: int, string, bool, ...)
+	Default any
+}
+
+// 기본 스칼라
+var (
+	Int = &Scalar{
+		Name: "Int", Description: "A signed 32-bit integer.",
+		Serialize: func(v any) (any, error) {
+			n, err := toInt(v)
+			if err != nil || n > math.MaxInt32 || n < math.MinInt32 {
+				return nil, fmt.Errorf("Int cannot represent %v", v)
+			}
+			return n, nil
+		},
+		Parse: func(v any) (any, error) {
+			n, err := toInt(v)
+			if err != nil || n > math.MaxInt32 || n < math.MinInt32 {
+				return nil, fmt.Errorf("Int cannot represent %v", v)
+			}
+			return int(n), nil
+		},
+	}
+	Float = &Scalar{
+		Name: "Float", Description: "A double-precision floating point number.",
+		Serialize: toFloat, Parse: toFloat,
+	}
+	String = &Scalar{
+		Name: "String", Description: "A UTF-8 character sequence.",
+		Serialize: func(v any) (any, error) {
+			switch v := v.(type) {
+			case string:
+				return v, nil
+			case fmt.Stringer:
+				return v.String(), nil
+			}
+			return fmt.Sprint(v), nil
+		},
+		Parse: func(v any) (any, error) {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("String cannot represent %v", v)
+			}
+			return s, nil
+		},
+	}
+	Boolean = &Scalar{
+		Name: "Boolean", Description: "true or false.",
+		Serialize: func(v any) (any, error) {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("Boolean cannot represent %v", v)
+			}
+			return b, nil
+		},
+	}
+	// ID 는 결과에서 문자열이고, 입력으로는 문자열이나 정수를 받아 문자열로 바꾼다.
+	ID = &Scalar{
+		Name: "ID", Description: "A unique identifier, serialized as a string.",
+		Serialize: func(v any) (any, error) {
+			if n, err := toInt(v); err == nil {
+				return strconv.FormatInt(n, 10), nil
+			}
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("ID cannot represent %v", v)
+		},
+		Parse: func(v any) (any, error) {
+			switch v := v.(type) {
+			case string:
+				return v, nil
+			case int64:
+				return strconv.FormatInt(v, 10), nil
+			}
+			return nil, fmt.Errorf("ID cannot represent %v", v)
+		},
+	}
+	// DateTime 은 RFC 3339 시각이다. time.Time 값을 쓴다.
+	DateTime = &Scalar{
+		Name: "DateTime", Description: "An RFC 3339 timestamp.",
+		Serialize: func(v any) (any, error) {
+			switch t := v.(type) {
+			case time.Time:
+				return t.UTC().Format(time.RFC3339Nano), nil
+			case *time.Time:
+				return t.UTC().Format(time.RFC3339Nano), nil
+			}
+			return nil, fmt.Errorf("DateTime cannot represent %v", v)
+		},
+		Parse: func(v any) (any, error) {
+			s, _ := v.(string)
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return nil, fmt.Errorf("DateTime cannot represent %v", v)
+			}
+			return t, nil
+		},
+	}
+)
+
+func init() {
+	Boolean.Parse = Boolean.Serialize
+}
+
+func toInt(v any) (int64, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() <= math.MaxInt64 {
+			return int64(rv.Uint()), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == math.Trunc(f) && f >= math.MinInt64 && f <= math.MaxInt64 {
+			return int64(f), nil
+		}
+	}
+	return 0, fmt.Errorf("not an integer: %v", v)
+}
+
+func toFloat(v any) (any, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	}
+	return nil, fmt.Errorf("Float cannot represent %v", v)
+}
+
+// Schema 는 검사한 타입 모음이다.
+type Schema struct {
+	Query    *Object
+	Mutation *Object
+	// MaxDepth 는 쿼리 선택 집합의 최대 중첩 깊이다. 0 이면 DefaultMaxDepth 다.
+	MaxDepth int
+
+	types map[string]Type
+}
+
+// NewSchema 는 query(필수)와 mutation 에서 닿는 타입을 모으고 이름이 겹치지 않는지 확인한다.
+func NewSchema(query, mutation *Object) (*Schema, error) {
+	if query == nil {
+		return nil, errors.New("graphql: query type is required")
+	}
+	s := &Schema{Query: query, Mutation: mutation, types: map[string]Type{}}
+	for _, t := range []Type{Int, Float, String, Boolean, ID} {
+		s.types[t.String()] = t
+	}
+	roots := []Type{query}
+	if mutation != nil {
+		roots = append(roots, mutation)
+	}
+	roots = append(roots, introspectionTypes()...)
+	for _, t := range roots {
+		if err := s.add(t); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Schema) add(t Type) error {
+	t = named(t)
+	name := t.String()
+	if prev, ok := s.types[name]; ok {
+		if prev != t {
+			return fmt.Errorf("graphql: two types named %s", name)
+		}
+		return nil
+	}
+	s.types[name] = t
+	switch t := t.(type) {
+	case *Object:
+		t.index = map[string]*Field{}
+		for _, f := range t.Fields {
+			if t.index[f.Name] != nil {
+				return fmt.Errorf("graphql: duplicate field %s.%s", t.Name, f.Name)
+			}
+			t.index[f.Name] = f
+			if err := s.add(f.Type); err != nil {
+				return err
+			}
+			for _, a := range f.Args {
+				if !isInput(a.Type) {
+					return fmt.Errorf("graphql: argument %s.%s(%s) must be an input type", t.Name, f.Name, a.Name)
+				}
+				if err := s.add(a.Type); err != nil {
+					return err
+				}
+			}
+		}
+	case *InputObject:
+		for _, f := range t.Fields {
+			if !isInput(f.Type) {
+				return fmt.Errorf("graphql: input field %s.%s must be an input type", t.Name, f.Name)
+			}
+			if err := s.add(f.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isInput(t Type) bool {
+	switch named(t).(type) {
+	case *Scalar, *Enum, *InputObject:
+		return true
+	}
+	return false
+}
+
+// Type 은 이름으로 타입을 찾는다.
+func (s *Schema) Type(name string) Type { return s.types[name] }
+
+// SDL 은 스키마를 GraphQL 스키마 정의 언어로 적는다. 기본 스칼라와 인트로스펙션 타입은 빼고 이름 순서로 적는다.
+func (s *Schema) SDL() string {
+	var b strings.Builder
+	if s.Mutation != nil && (s.Query.Name != "Query" || s.Mutation.Name != "Mutation") {
+		fmt.Fprintf(&b, "schema {\n  query: %s\n  mutation: %s\n}\n\n", s.Query.Name, s.Mutation.Name)
+	}
+	names := make([]string, 0, len(s.types))
+	for name := range s.types {
+		if !strings.HasPrefix(name, "__") && !slices.Contains([]string{"Int", "Float", "String", "Boolean", "ID"}, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		switch t := s.types[name].(type) {
+		case *Scalar:
+			writeDescription(&b, "", t.Description)
+			fmt.Fprintf(&b, "scalar %s\n\n", t.Name)
+		case *Enum:
+			writeDescription(&b, "", t.Description)
+			fmt.Fprintf(&b, "enum %s {\n", t.Name)
+			for _, v := range t.Values {
+				fmt.Fprintf(&b, "  %s\n", v)
+			}
+			b.WriteString("}\n\n")
+		case *InputObject:
+			writeDescription(&b, "", t.Description)
+			fmt.Fprintf(&b, "input %s {\n", t.Name)
+			for _, f := range t.Fields {
+				writeDescription(&b, "  ", f.Description)
+				fmt.Fprintf(&b, "  %s\n", inputValueSDL(f))
+			}
+			b.WriteString("}\n\n")
+		case *Object:
+			writeDescription(&b, "", t.Description)
+			fmt.Fprintf(&b, "type %s {\n", t.Name)
+			for _, f := range t.Fields {
+				writeDescription(&b, "  ", f.Description)
+				b.WriteString("  " + f.Name)
+				if len(f.Args) > 0 {
+					args := make([]string, len(f.Args))
+					for i, a := range f.Args {
+						args[i] = inputValueSDL(a)
+					}
+					b.WriteString("(" + strings.Join(args, ", ") + ")")
+				}
+				b.WriteString(": " + f.Type.String())
+				if f.Deprecated != "" {
+					fmt.Fprintf(&b, " @deprecated(reason: %s)", strconv.Quote(f.Deprecated))
+				}
+				b.WriteString("\n")
+			}
+			b.WriteString("}\n\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func inputValueSDL(a *Arg) string {
+	s := a.Name + ": " + a.Type.String()
+	if a.Default != nil {
+		s += " = " + literal(a.Default)
+	}
+	return s
+}
+
+// literal 은 Go 값을 GraphQL 리터럴로 적는다.
+func literal(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []any:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = literal(e)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprint(v)
+}
+
+func writeDescription(b *strings.Builder, indent, desc string) {
+	if desc != "" {
+		fmt.Fprintf(b, "%s%s\n", indent, strconv.Quote(desc))
+	}
+}
@@ -0,0 +1,142 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBytes 는 Options.MaxBytes 가 0 일 때 받는 요청 본문의 최대 크기다.
+const DefaultMaxBytes = 1 << 20
+
+// Options 는 Handler 설정이다.
+type Options struct {
+	// Playground 이면 브라우저(Accept: text/html)의 GET 요청에 GraphiQL 페이지를 보여준다.
+	Playground bool
+	// MaxBytes 는 POST 본문의 최대 크기다. 0 이면 DefaultMaxBytes 다.
+	MaxBytes int64
+}
+
+// Handler 는 GraphQL over HTTP 핸들러다.
+//
+//	POST /graphql  {"query": "...", "operationName": "...", "variables": {...}}
+//	GET  /graphql?query=...&variables=...   (쿼리만, 뮤테이션은 POST)
+//
+// 실행한 요청은 필드 에러가 있어도 200 이고, 문법이나 검사 에러로 실행하지 못하면 400 이다.
+func Handler(s *Schema, opts Options) http.HandlerFunc {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		switch r.Method {
+		case http.MethodGet:
+			q := r.URL.Query()
+			if opts.Playground && !q.Has("query") && strings.Contains(r.Header.Get("Accept"), "text/html") {
+				servePlayground(w)
+				return
+			}
+			req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+			if v := q.Get("variables"); v != "" {
+				if err := decodeJSON(strings.NewReader(v), &req.Variables); err != nil {
+					writeErrors(w, http.StatusBadRequest, "variables must be a JSON object")
+					return
+				}
+			}
+		case http.MethodPost:
+			mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mt != "application/json" && mt != "application/graphql-response+json" {
+				writeErrors(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+				return
+			}
+			if err := decodeJSON(http.MaxBytesReader(w, r.Body, opts.MaxBytes), &req); err != nil {
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					writeErrors(w, http.StatusRequestEntityTooLarge, "request body is too large")
+					return
+				}
+				writeErrors(w, http.StatusBadRequest, "request body must be a JSON object with a query")
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeErrors(w, http.StatusMethodNotAllowed, "use GET or POST")
+			return
+		}
+		if strings.TrimSpace(req.Query) == "" {
+			writeErrors(w, http.StatusBadRequest, "query is required")
+			return
+		}
+		resp := s.execute(r.Context(), req, r.Method == http.MethodGet)
+		status := http.StatusOK
+		if !resp.executed {
+			status = http.StatusBadRequest
+		}
+		writeResponse(w, status, resp)
+	}
+}
+
+// SDLHandler 는 스키마를 text/plain SDL 로 보여준다.
+func SDLHandler(s *Schema) http.HandlerFunc {
+	sdl := s.SDL() + "\n"
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, sdl)
+	}
+}
+
+func decodeJSON(r io.Reader, dst any) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return dec.Decode(dst)
+}
+
+func writeErrors(w http.ResponseWriter, status int, msg string) {
+	writeResponse(w, status, &Response{Errors: []*Error{{Message: msg}}})
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp *Response) {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		status, b = http.StatusInternalServerError, []byte(`{"errors":[{"message":"internal server error"}]}`)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(append(b, '\n'))
+}
+
+// GraphiQL 버전 (unpkg CDN 에서 불러온다)
+const graphiQLVersion = "3.7.1"
+
+var playgroundPage = template.Must(template.New("graphiql").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>GraphiQL</title>
+  <style>body { margin: 0; height: 100vh; } #graphiql { height: 100vh; }</style>
+  <link rel="stylesheet" href="https://unpkg.com/graphiql@{{.}}/graphiql.min.css">
+</head>
+<body>
+<div id="graphiql">Loading…</div>
+<script crossorigin src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+<script crossorigin src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+<script crossorigin src="https://unpkg.com/graphiql@{{.}}/graphiql.min.js"></script>
+<script>
+const fetcher = GraphiQL.createFetcher({url: window.location.pathname});
+ReactDOM.createRoot(document.getElementById("graphiql")).render(React.createElement(GraphiQL, {fetcher}));
+</script>
+</body>
+</html>
+`))
+
+// servePlayground 는 GraphiQL 페이지를 쓴다. 기본 CSP 가 외부 스크립트를 막으므로 이 페이지에서만 CDN 을 허용한다.
+func servePlayground(w http.ResponseWriter) {
+	w.Header().Set("Content-Security-Policy",
+		"default-src 'self'; script-src 'self' 'unsafe-inline' https://unpkg.com; style-src 'self' 'unsafe-inline' https://unpkg.com; img-src 'self' data: https://unpkg.com; font-src https://unpkg.com; connect-src 'self'")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	playgroundPage.Execute(w, graphiQLVersion)
+}
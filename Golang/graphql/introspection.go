@@ -0,0 +1,236 @@
+package graphql
+
+import (
+	"cmp"
+	"context"
+	"slices"
+)
+
+// 인트로스펙션 타입. GraphiQL 같은 도구가 쓰는 표준 쿼리(getIntrospectionQuery)에 답한다.
+
+// directiveDef 는 __schema.directives 에 나오는 지시자다.
+type directiveDef struct {
+	Name        string
+	Description string
+	Locations   []string
+	Args        []*Arg
+}
+
+var directives = []*directiveDef{
+	{Name: "include", Description: "Directs the executor to include this field or fragment only when the `if` argument is true.",
+		Locations: []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		Args:      []*Arg{{Name: "if", Description: "Included when true.", Type: NonNull(Boolean)}}},
+	{Name: "skip", Description: "Directs the executor to skip this field or fragment when the `if` argument is true.",
+		Locations: []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		Args:      []*Arg{{Name: "if", Description: "Skipped when true.", Type: NonNull(Boolean)}}},
+	{Name: "deprecated", Description: "Marks an element of a GraphQL schema as no longer supported.",
+		Locations: []string{"FIELD_DEFINITION", "ENUM_VALUE"},
+		Args:      []*Arg{{Name: "reason", Type: String, Default: "No longer supported"}}},
+}
+
+var (
+	typeKind = &Enum{Name: "__TypeKind", Values: []string{
+		"SCALAR", "OBJECT", "INTERFACE", "UNION", "ENUM", "INPUT_OBJECT", "LIST", "NON_NULL"}}
+	directiveLocation = &Enum{Name: "__DirectiveLocation", Values: []string{
+		"QUERY", "MUTATION", "SUBSCRIPTION", "FIELD", "FRAGMENT_DEFINITION", "FRAGMENT_SPREAD", "INLINE_FRAGMENT",
+		"VARIABLE_DEFINITION", "SCHEMA", "SCALAR", "OBJECT", "FIELD_DEFINITION", "ARGUMENT_DEFINITION", "INTERFACE",
+		"UNION", "ENUM", "ENUM_VALUE", "INPUT_OBJECT", "INPUT_FIELD_DEFINITION"}}
+
+	schemaType     = &Object{Name: "__Schema"}
+	typeType       = &Object{Name: "__Type"}
+	fieldType      = &Object{Name: "__Field"}
+	inputValueType = &Object{Name: "__InputValue"}
+	enumValueType  = &Object{Name: "__EnumValue"}
+	directiveType  = &Object{Name: "__Directive"}
+)
+
+var (
+	typenameField = &Field{Name: "__typename", Type: NonNull(String)}
+	schemaField   = &Field{Name: "__schema", Type: NonNull(schemaType),
+		Resolve: func(p Params) (any, error) { return schemaFrom(p.Context), nil }}
+	typeField = &Field{Name: "__type", Type: typeType,
+		Args: []*Arg{{Name: "name", Type: NonNull(String)}},
+		Resolve: func(p Params) (any, error) {
+			if t := schemaFrom(p.Context).types[p.Args["name"].(string)]; t != nil {
+				return t, nil
+			}
+			return nil, nil
+		}}
+)
+
+func schemaFrom(ctx context.Context) *Schema {
+	return ctx.Value(execKey{}).(*execution).schema
+}
+
+var includeDeprecated = []*Arg{{Name: "includeDeprecated", Type: Boolean, Default: false}}
+
+func introspectionTypes() []Type {
+	return []Type{schemaType, typeType, fieldType, inputValueType, enumValueType, directiveType, typeKind, directiveLocation}
+}
+
+func init() {
+	str := func(f func(any) string) ResolveFunc {
+		return func(p Params) (any, error) {
+			if s := f(p.Source); s != "" {
+				return s, nil
+			}
+			return nil, nil
+		}
+	}
+	constant := func(v any) ResolveFunc { return func(Params) (any, error) { return v, nil } }
+
+	schemaType.Fields = []*Field{
+		{Name: "description", Type: String, Resolve: constant(nil)},
+		{Name: "types", Type: NonNull(NewList(NonNull(typeType))), Resolve: func(p Params) (any, error) {
+			s := p.Source.(*Schema)
+			types := make([]Type, 0, len(s.types))
+			for _, t := range s.types {
+				types = append(types, t)
+			}
+			slices.SortFunc(types, func(a, b Type) int { return cmp.Compare(a.String(), b.String()) })
+			return types, nil
+		}},
+		{Name: "queryType", Type: NonNull(typeType), Resolve: func(p Params) (any, error) { return p.Source.(*Schema).Query, nil }},
+		{Name: "mutationType", Type: typeType, Resolve: func(p Params) (any, error) {
+			if m := p.Source.(*Schema).Mutation; m != nil {
+				return m, nil
+			}
+			return nil, nil
+		}},
+		{Name: "subscriptionType", Type: typeType, Resolve: constant(nil)},
+		{Name: "directives", Type: NonNull(NewList(NonNull(directiveType))), Resolve: constant(directives)},
+	}
+
+	typeType.Fields = []*Field{
+		{Name: "kind", Type: NonNull(typeKind), Resolve: func(p Params) (any, error) {
+			switch p.Source.(type) {
+			case *Scalar:
+				return "SCALAR", nil
+			case *Object:
+				return "OBJECT", nil
+			case *Enum:
+				return "ENUM", nil
+			case *InputObject:
+				return "INPUT_OBJECT", nil
+			case *List:
+				return "LIST", nil
+			}
+			return "NON_NULL", nil
+		}},
+		{Name: "name", Type: String, Resolve: str(func(t any) string {
+			switch t.(type) {
+			case *List, *NonNullType:
+				return ""
+			}
+			return t.(Type).String()
+		})},
+		{Name: "description", Type: String, Resolve: str(func(t any) string {
+			switch t := t.(type) {
+			case *Scalar:
+				return t.Description
+			case *Object:
+				return t.Description
+			case *Enum:
+				return t.Description
+			case *InputObject:
+				return t.Description
+			}
+			return ""
+		})},
+		{Name: "specifiedByURL", Type: String, Resolve: constant(nil)},
+		{Name: "fields", Type: NewList(NonNull(fieldType)), Args: includeDeprecated, Resolve: func(p Params) (any, error) {
+			t, ok := p.Source.(*Object)
+			if !ok {
+				return nil, nil
+			}
+			all := p.Args["includeDeprecated"] == true
+			fields := []*Field{}
+			for _, f := range t.Fields {
+				if all || f.Deprecated == "" {
+					fields = append(fields, f)
+				}
+			}
+			return fields, nil
+		}},
+		{Name: "interfaces", Type: NewList(NonNull(typeType)), Resolve: func(p Params) (any, error) {
+			if _, ok := p.Source.(*Object); ok {
+				return []Type{}, nil
+			}
+			return nil, nil
+		}},
+		{Name: "possibleTypes", Type: NewList(NonNull(typeType)), Resolve: constant(nil)},
+		{Name: "enumValues", Type: NewList(NonNull(enumValueType)), Args: includeDeprecated, Resolve: func(p Params) (any, error) {
+			if t, ok := p.Source.(*Enum); ok {
+				return t.Values, nil
+			}
+			return nil, nil
+		}},
+		{Name: "inputFields", Type: NewList(NonNull(inputValueType)), Args: includeDeprecated, Resolve: func(p Params) (any, error) {
+			if t, ok := p.Source.(*InputObject); ok {
+				return t.Fields, nil
+			}
+			return nil, nil
+		}},
+		{Name: "ofType", Type: typeType, Resolve: func(p Params) (any, error) {
+			switch t := p.Source.(type) {
+			case *List:
+				return t.Of, nil
+			case *NonNullType:
+				return t.Of, nil
+			}
+			return nil, nil
+		}},
+		{Name: "isOneOf", Type: Boolean, Resolve: constant(false)},
+	}
+
+	fieldType.Fields = []*Field{
+		{Name: "name", Type: NonNull(String), Resolve: func(p Params) (any, error) { return p.Source.(*Field).Name, nil }},
+		{Name: "description", Type: String, Resolve: str(func(f any) string { return f.(*Field).Description })},
+		{Name: "args", Type: NonNull(NewList(NonNull(inputValueType))), Args: includeDeprecated, Resolve: func(p Params) (any, error) {
+			if args := p.Source.(*Field).Args; args != nil {
+				return args, nil
+			}
+			return []*Arg{}, nil
+		}},
+		{Name: "type", Type: NonNull(typeType), Resolve: func(p Params) (any, error) { return p.Source.(*Field).Type, nil }},
+		{Name: "isDeprecated", Type: NonNull(Boolean), Resolve: func(p Params) (any, error) { return p.Source.(*Field).Deprecated != "", nil }},
+		{Name: "deprecationReason", Type: String, Resolve: str(func(f any) string { return f.(*Field).Deprecated })},
+	}
+
+	inputValueType.Fields = []*Field{
+		{Name: "name", Type: NonNull(String), Resolve: func(p Params) (any, error) { return p.Source.(*Arg).Name, nil }},
+		{Name: "description", Type: String, Resolve: str(func(a any) string { return a.(*Arg).Description })},
+		{Name: "type", Type: NonNull(typeType), Resolve: func(p Params) (any, error) { return p.Source.(*Arg).Type, nil }},
+		{Name: "defaultValue", Type: String, Resolve: func(p Params) (any, error) {
+			a := p.Source.(*Arg)
+			if a.Default == nil {
+				return nil, nil
+			}
+			if s, ok := a.Default.(string); ok {
+				if e, ok := named(a.Type).(*Enum); ok && slices.Contains(e.Values, s) {
+					return s, nil
+				}
+			}
+			return literal(a.Default), nil
+		}},
+		{Name: "isDeprecated", Type: NonNull(Boolean), Resolve: constant(false)},
+		{Name: "deprecationReason", Type: String, Resolve: constant(nil)},
+	}
+
+	enumValueType.Fields = []*Field{
+		{Name: "name", Type: NonNull(String), Resolve: func(p Params) (any, error) { return p.Source, nil }},
+		{Name: "description", Type: String, Resolve: constant(nil)},
+		{Name: "isDeprecated", Type: NonNull(Boolean), Resolve: constant(false)},
+		{Name: "deprecationReason", Type: String, Resolve: constant(nil)},
+	}
+
+	directiveType.Fields = []*Field{
+		{Name: "name", Type: NonNull(String)},
+		{Name: "description", Type: String, Resolve: str(func(d any) string { return d.(*directiveDef).Description })},
+		{Name: "locations", Type: NonNull(NewList(NonNull(directiveLocation)))},
+		{Name: "args", Type: NonNull(NewList(NonNull(inputValueType))), Args: includeDeprecated, Resolve: func(p Params) (any, error) {
+			return p.Source.(*directiveDef).Args, nil
+		}},
+		{Name: "isRepeatable", Type: NonNull(Boolean), Resolve: constant(false)},
+	}
+}
@@ -0,0 +1,146 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound 는 BatchFunc 가 결과에 넣지 않은 키를 Load 할 때의 에러다.
+var ErrNotFound = errors.New("not found")
+
+// BatchFunc 는 키 여러 개를 한 번에 조회한다. 없는 키는 결과에서 빼면 된다.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Loader 는 짧은 시간 안에 들어온 Load 를 모아 BatchFunc 한 번으로 조회하고 결과를 기억한다.
+// (dataloader) 같은 단계의 필드는 동시에 해석하므로 user(id:) 를 여러 번 쓴 쿼리나 리스트의 각 항목이
+// 같은 객체를 찾는 쿼리도 조회 한 번으로 끝난다. 결과를 요청 사이에 나누지 않도록 LoaderFor 로 실행마다 만든다.
+type Loader[K comparable, V any] struct {
+	fetch    BatchFunc[K, V]
+	wait     time.Duration
+	maxBatch int
+
+	mu    sync.Mutex
+	cache map[K]*loadResult[V]
+	batch *loadBatch[K, V]
+}
+
+type loadResult[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type loadBatch[K comparable, V any] struct {
+	ctx     context.Context
+	keys    []K
+	results []*loadResult[V]
+	timer   *time.Timer
+}
+
+// 기본 대기 시간과 묶음 크기
+const (
+	defaultLoaderWait  = time.Millisecond
+	defaultLoaderBatch = 100
+)
+
+// NewLoader 는 fetch 로 조회하는 Loader 를 만든다. 첫 Load 뒤 1ms 를 기다리거나 키가 100개 모이면 조회한다.
+func NewLoader[K comparable, V any](fetch BatchFunc[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{fetch: fetch, wait: defaultLoaderWait, maxBatch: defaultLoaderBatch, cache: map[K]*loadResult[V]{}}
+}
+
+// Load 는 key 의 값이다. BatchFunc 결과에 없으면 ErrNotFound 다.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	r, ok := l.cache[key]
+	if !ok {
+		r = &loadResult[V]{done: make(chan struct{})}
+		l.cache[key] = r
+		if l.batch == nil {
+			b := &loadBatch[K, V]{ctx: ctx}
+			b.timer = time.AfterFunc(l.wait, func() { l.dispatch(b) })
+			l.batch = b
+		}
+		b := l.batch
+		b.keys = append(b.keys, key)
+		b.results = append(b.results, r)
+		if len(b.keys) >= l.maxBatch {
+			b.timer.Stop()
+			l.batch = nil
+			l.mu.Unlock()
+			go l.run(b)
+			return l.await(ctx, r)
+		}
+	}
+	l.mu.Unlock()
+	return l.await(ctx, r)
+}
+
+func (l *Loader[K, V]) await(ctx context.Context, r *loadResult[V]) (V, error) {
+	select {
+	case <-r.done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// dispatch 는 대기 시간이 지난 묶음을 조회한다. 그 사이 maxBatch 로 이미 보냈으면 아무것도 하지 않는다.
+func (l *Loader[K, V]) dispatch(b *loadBatch[K, V]) {
+	l.mu.Lock()
+	if l.batch != b {
+		l.mu.Unlock()
+		return
+	}
+	l.batch = nil
+	l.mu.Unlock()
+	l.run(b)
+}
+
+func (l *Loader[K, V]) run(b *loadBatch[K, V]) {
+	values, err := l.fetch(b.ctx, b.keys)
+	for i, key := range b.keys {
+		r := b.results[i]
+		switch v, ok := values[key]; {
+		case err != nil:
+			r.err = err
+		case !ok:
+			r.err = ErrNotFound
+		default:
+			r.value = v
+		}
+		close(r.done)
+	}
+	if err != nil {
+		// 실패한 키는 잊어서 다음 Load 가 다시 조회하게 한다.
+		l.mu.Lock()
+		for i, key := range b.keys {
+			if l.cache[key] == b.results[i] {
+				delete(l.cache, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// LoaderFor 는 현재 실행에서 name 으로 쓰는 Loader 다. 처음 부르면 fetch 로 만들고, 같은 실행의 다른
+// 리졸버는 같은 Loader 를 받는다. 실행 밖에서 부르면 매번 새 Loader 다.
+func LoaderFor[K comparable, V any](ctx context.Context, name string, fetch BatchFunc[K, V]) *Loader[K, V] {
+	e, ok := ctx.Value(execKey{}).(*execution)
+	if !ok {
+		return NewLoader(fetch)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if l, ok := e.loaders[name].(*Loader[K, V]); ok {
+		return l
+	}
+	if e.loaders == nil {
+		e.loaders = map[string]any{}
+	}
+	l := NewLoader(fetch)
+	e.loaders[name] = l
+	return l
+}
@@ -0,0 +1,765 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 쿼리 문서의 구문 트리
+
+type document struct {
+	ops   []*operation
+	frags map[string]*fragment
+}
+
+type operation struct {
+	kind string // query, mutation, subscription
+	name string
+	vars []*varDef
+	dirs []*directive
+	sel  []selection
+	loc  Location
+}
+
+type varDef struct {
+	name string
+	typ  *typeRef
+	def  *value
+	loc  Location
+}
+
+// typeRef 는 쿼리에 적은 타입이다. ("[Int!]!")
+type typeRef struct {
+	name    string
+	list    *typeRef
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.list != nil {
+		s = "[" + t.list.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+type fragment struct {
+	name string
+	on   string
+	dirs []*directive
+	sel  []selection
+	loc  Location
+}
+
+// selection 은 *selField, *fragmentSpread, *inlineFragment 중 하나다.
+type selection any
+
+type selField struct {
+	alias, name string
+	args        []*argument
+	dirs        []*directive
+	sel         []selection
+	loc         Location
+}
+
+func (f *selField) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name string
+	dirs []*directive
+	loc  Location
+}
+
+type inlineFragment struct {
+	on   string
+	dirs []*directive
+	sel  []selection
+	loc  Location
+}
+
+type argument struct {
+	name string
+	val  *value
+	loc  Location
+}
+
+type directive struct {
+	name string
+	args []*argument
+	loc  Location
+}
+
+type valueKind int
+
+const (
+	valVariable valueKind = iota
+	valInt
+	valFloat
+	valString
+	valBool
+	valNull
+	valEnum
+	valList
+	valObject
+)
+
+type value struct {
+	kind   valueKind
+	raw    string // 변수 이름, 숫자, 문자열, 열거형 이름
+	list   []*value
+	fields []*argument
+	loc    Location
+}
+
+// Location 은 쿼리 문서의 위치다. (1부터 센다)
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// 토큰
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind tokKind
+	val  string
+	loc  Location
+}
+
+type lexer struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+// syntaxError 는 쿼리 문서의 문법 에러다.
+type syntaxError struct {
+	msg string
+	loc Location
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("Syntax Error: %s (line %d, column %d)", e.msg, e.loc.Line, e.loc.Column)
+}
+
+func (l *lexer) advance(n int) {
+	for range n {
+		if l.src[l.pos] == '\n' {
+			l.line++
+			l.col = 1
+		} else {
+			l.col++
+		}
+		l.pos++
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	// 공백, 쉼표, 주석은 무시한다.
+skip:
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.advance(1)
+			continue
+		case strings.HasPrefix(l.src[l.pos:], "\uFEFF"):
+			l.pos += 3
+			continue
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.advance(1)
+			}
+			continue
+		}
+		break skip
+	}
+	loc := Location{Line: l.line, Column: l.col}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, loc: loc}, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.advance(3)
+		return token{kind: tokPunct, val: "...", loc: loc}, nil
+	case strings.IndexByte("!$&()/:=@[]{|}", c) >= 0:
+		l.advance(1)
+		return token{kind: tokPunct, val: string(c), loc: loc}, nil
+	case c == '_' || isLetter(c):
+		start := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.advance(1)
+		}
+		return token{kind: tokName, val: l.src[start:l.pos], loc: loc}, nil
+	case c == '-' || isDigit(c):
+		return l.number(loc)
+	case c == '"':
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			return l.blockString(loc)
+		}
+		return l.str(loc)
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, &syntaxError{fmt.Sprintf("unexpected character %q", r), loc}
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+func (l *lexer) number(loc Location) (token, error) {
+	start := l.pos
+	float := false
+	if l.src[l.pos] == '-' {
+		l.advance(1)
+	}
+	digits := func() int {
+		n := 0
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.advance(1)
+			n++
+		}
+		return n
+	}
+	if digits() == 0 {
+		return token{}, &syntaxError{"invalid number", loc}
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		float = true
+		l.advance(1)
+		if digits() == 0 {
+			return token{}, &syntaxError{"invalid number", loc}
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		float = true
+		l.advance(1)
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.advance(1)
+		}
+		if digits() == 0 {
+			return token{}, &syntaxError{"invalid number", loc}
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == '_' || l.src[l.pos] == '.' || isLetter(l.src[l.pos])) {
+		return token{}, &syntaxError{"invalid number", loc}
+	}
+	kind := tokInt
+	if float {
+		kind = tokFloat
+	}
+	return token{kind: kind, val: l.src[start:l.pos], loc: loc}, nil
+}
+
+func (l *lexer) str(loc Location) (token, error) {
+	l.advance(1)
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.advance(1)
+			return token{kind: tokString, val: b.String(), loc: loc}, nil
+		case c == '\n' || c == '\r':
+			return token{}, &syntaxError{"unterminated string", loc}
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, &syntaxError{"unterminated string", loc}
+			}
+			esc := l.src[l.pos+1]
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+6 > len(l.src) {
+					return token{}, &syntaxError{"invalid unicode escape", loc}
+				}
+				n, err := strconv.ParseUint(l.src[l.pos+2:l.pos+6], 16, 32)
+				if err != nil {
+					return token{}, &syntaxError{"invalid unicode escape", loc}
+				}
+				b.WriteRune(rune(n))
+				l.advance(4)
+			default:
+				return token{}, &syntaxError{fmt.Sprintf("invalid escape \\%c", esc), loc}
+			}
+			l.advance(2)
+		default:
+			b.WriteByte(c)
+			l.advance(1)
+		}
+	}
+	return token{}, &syntaxError{"unterminated string", loc}
+}
+
+// blockString 은 """...""" 문자열이다. 공통 들여쓰기와 앞뒤 빈 줄을 뗀다.
+func (l *lexer) blockString(loc Location) (token, error) {
+	l.advance(3)
+	start := l.pos
+	for l.pos < len(l.src) {
+		if strings.HasPrefix(l.src[l.pos:], `\"""`) {
+			l.advance(4)
+			continue
+		}
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			raw := strings.ReplaceAll(l.src[start:l.pos], `\"""`, `"""`)
+			l.advance(3)
+			return token{kind: tokString, val: blockValue(raw), loc: loc}, nil
+		}
+		l.advance(1)
+	}
+	return token{}, &syntaxError{"unterminated block string", loc}
+}
+
+func blockValue(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if n < len(line) && (indent < 0 || n < indent) {
+			indent = n
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 파서
+
+type parser struct {
+	lex lexer
+	tok token
+	// depth 는 선택 집합과 값의 중첩 깊이다. 지나치게 깊은 문서로 스택을 다 쓰지 않게 막는다.
+	depth int
+}
+
+const maxParseDepth = 64
+
+func parse(src string) (doc *document, err error) {
+	p := &parser{lex: lexer{src: src, line: 1, col: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc = &document{frags: map[string]*fragment{}}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.peek("{"):
+			op := &operation{kind: "query", loc: p.tok.loc}
+			if op.sel, err = p.selectionSet(); err != nil {
+				return nil, err
+			}
+			doc.ops = append(doc.ops, op)
+		case p.tok.kind == tokName && (p.tok.val == "query" || p.tok.val == "mutation" || p.tok.val == "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.ops = append(doc.ops, op)
+		case p.tok.kind == tokName && p.tok.val == "fragment":
+			f, err := p.fragmentDef()
+			if err != nil {
+				return nil, err
+			}
+			if doc.frags[f.name] != nil {
+				return nil, &syntaxError{fmt.Sprintf("there can be only one fragment named %q", f.name), f.loc}
+			}
+			doc.frags[f.name] = f
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.ops) == 0 {
+		return nil, &syntaxError{"document has no operation", p.tok.loc}
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	t, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = t
+	return nil
+}
+
+func (p *parser) peek(punct string) bool { return p.tok.kind == tokPunct && p.tok.val == punct }
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokEOF {
+		return &syntaxError{"unexpected end of document", p.tok.loc}
+	}
+	return &syntaxError{fmt.Sprintf("unexpected %q", p.tok.val), p.tok.loc}
+}
+
+// found 는 에러 메시지에 쓰는 현재 토큰이다.
+func (p *parser) found() string {
+	if p.tok.kind == tokEOF {
+		return "<EOF>"
+	}
+	return strconv.Quote(p.tok.val)
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.peek(punct) {
+		return &syntaxError{fmt.Sprintf("expected %q, found %s", punct, p.found()), p.tok.loc}
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", &syntaxError{"expected name, found " + p.found(), p.tok.loc}
+	}
+	n := p.tok.val
+	return n, p.advance()
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: p.tok.val, loc: p.tok.loc}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if p.tok.kind == tokName {
+		if op.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for !p.peek(")") {
+			v := &varDef{loc: p.tok.loc}
+			if err := p.expect("$"); err != nil {
+				return nil, err
+			}
+			if v.name, err = p.name(); err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if v.typ, err = p.typeRef(); err != nil {
+				return nil, err
+			}
+			if p.peek("=") {
+				if err := p.advance(); err != nil {
+					return nil, err
+				}
+				if v.def, err = p.value(true); err != nil {
+					return nil, err
+				}
+			}
+			if _, err := p.directives(); err != nil {
+				return nil, err
+			}
+			op.vars = append(op.vars, v)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if op.dirs, err = p.directives(); err != nil {
+		return nil, err
+	}
+	op.sel, err = p.selectionSet()
+	return op, err
+}
+
+func (p *parser) fragmentDef() (*fragment, error) {
+	f := &fragment{loc: p.tok.loc}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if f.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if f.name == "on" {
+		return nil, &syntaxError{`fragment cannot be named "on"`, f.loc}
+	}
+	if p.tok.kind != tokName || p.tok.val != "on" {
+		return nil, &syntaxError{`expected "on"`, p.tok.loc}
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if f.on, err = p.name(); err != nil {
+		return nil, err
+	}
+	if f.dirs, err = p.directives(); err != nil {
+		return nil, err
+	}
+	f.sel, err = p.selectionSet()
+	return f, err
+}
+
+func (p *parser) typeRef() (*typeRef, error) {
+	t := &typeRef{}
+	if p.peek("[") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		of, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		t.list = of
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if t.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("!") {
+		t.nonNull = true
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if p.depth++; p.depth > maxParseDepth {
+		return nil, &syntaxError{"document is nested too deeply", p.tok.loc}
+	}
+	defer func() { p.depth-- }()
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []selection
+	for !p.peek("}") {
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, s)
+	}
+	if len(sels) == 0 {
+		return nil, &syntaxError{"selection set cannot be empty", p.tok.loc}
+	}
+	return sels, p.advance()
+}
+
+func (p *parser) selection() (selection, error) {
+	loc := p.tok.loc
+	if p.peek("...") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokName && p.tok.val != "on" {
+			fs := &fragmentSpread{loc: loc}
+			var err error
+			if fs.name, err = p.name(); err != nil {
+				return nil, err
+			}
+			fs.dirs, err = p.directives()
+			return fs, err
+		}
+		inl := &inlineFragment{loc: loc}
+		var err error
+		if p.tok.kind == tokName && p.tok.val == "on" {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if inl.on, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if inl.dirs, err = p.directives(); err != nil {
+			return nil, err
+		}
+		inl.sel, err = p.selectionSet()
+		return inl, err
+	}
+	f := &selField{loc: loc}
+	var err error
+	if f.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.peek(":") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		f.alias = f.name
+		if f.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if f.args, err = p.arguments(false); err != nil {
+		return nil, err
+	}
+	if f.dirs, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		f.sel, err = p.selectionSet()
+	}
+	return f, err
+}
+
+func (p *parser) arguments(constant bool) ([]*argument, error) {
+	if !p.peek("(") {
+		return nil, nil
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var args []*argument
+	for !p.peek(")") {
+		a := &argument{loc: p.tok.loc}
+		var err error
+		if a.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if a.val, err = p.value(constant); err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+	if len(args) == 0 {
+		return nil, &syntaxError{"argument list cannot be empty", p.tok.loc}
+	}
+	return args, p.advance()
+}
+
+func (p *parser) directives() ([]*directive, error) {
+	var dirs []*directive
+	for p.peek("@") {
+		d := &directive{loc: p.tok.loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		if d.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if d.args, err = p.arguments(false); err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs, nil
+}
+
+// value 는 값 리터럴이다. constant 이면 변수를 쓸 수 없다. (변수 기본값)
+func (p *parser) value(constant bool) (*value, error) {
+	if p.depth++; p.depth > maxParseDepth {
+		return nil, &syntaxError{"value is nested too deeply", p.tok.loc}
+	}
+	defer func() { p.depth-- }()
+	v := &value{loc: p.tok.loc}
+	switch p.tok.kind {
+	case tokInt, tokFloat, tokString:
+		v.kind, v.raw = map[tokKind]valueKind{tokInt: valInt, tokFloat: valFloat, tokString: valString}[p.tok.kind], p.tok.val
+		return v, p.advance()
+	case tokName:
+		switch p.tok.val {
+		case "true", "false":
+			v.kind = valBool
+		case "null":
+			v.kind = valNull
+		default:
+			v.kind = valEnum
+		}
+		v.raw = p.tok.val
+		return v, p.advance()
+	}
+	switch {
+	case p.peek("$") && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v.kind = valVariable
+		var err error
+		v.raw, err = p.name()
+		return v, err
+	case p.peek("["):
+		v.kind = valList
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for !p.peek("]") {
+			e, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			v.list = append(v.list, e)
+		}
+		return v, p.advance()
+	case p.peek("{"):
+		v.kind = valObject
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for !p.peek("}") {
+			f := &argument{loc: p.tok.loc}
+			var err error
+			if f.name, err = p.name(); err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if f.val, err = p.value(constant); err != nil {
+				return nil, err
+			}
+			v.fields = append(v.fields, f)
+		}
+		return v, p.advance()
+	}
+	return nil, p.unexpected()
+}
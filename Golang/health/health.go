@@ -0,0 +1,156 @@
+// Package health 는 /healthz, /readyz, /livez 엔드포인트와 의존성 검사 레지스트리를 제공한다.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Check 는 의존성 하나의 상태를 검사한다. nil 이 아니면 실패다.
+type Check func(ctx context.Context) error
+
+// DefaultTimeout 은 검사 하나에 허용되는 기본 시간이다.
+const DefaultTimeout = 2 * time.Second
+
+// Registry 는 준비(readiness) 검사와 생존(liveness) 검사를 보관한다.
+type Registry struct {
+	// Timeout 은 검사 하나에 허용되는 시간이다.
+	Timeout time.Duration
+
+	mu    sync.RWMutex
+	ready map[string]Check
+	live  map[string]Check
+}
+
+// New 는 비어 있는 Registry 를 만든다.
+func New() *Registry {
+	return &Registry{Timeout: DefaultTimeout, ready: map[string]Check{}, live: map[string]Check{}}
+}
+
+// Default 는 패키지 수준 함수가 사용하는 기본 Registry 다.
+var Default = New()
+
+// Register 는 Default 에 준비 검사를 등록한다. (예: health.Register("db", db.PingContext))
+func Register(name string, c Check) { Default.Register(name, c) }
+
+// RegisterLiveness 는 Default 에 생존 검사를 등록한다.
+func RegisterLiveness(name string, c Check) { Default.RegisterLiveness(name, c) }
+
+// Register 는 준비 검사를 등록한다. 실패하면 /readyz 와 /healthz 가 503 을 돌려준다.
+func (r *Registry) Register(name string, c Check) {
+	r.mu.Lock()
+	r.ready[name] = c
+	r.mu.Unlock()
+}
+
+// RegisterLiveness 는 생존 검사를 등록한다. 실패하면 /livez 와 /healthz 가 503 을 돌려준다.
+// 생존 검사는 재시작으로만 복구되는 상태(교착 등)에만 사용한다.
+func (r *Registry) RegisterLiveness(name string, c Check) {
+	r.mu.Lock()
+	r.live[name] = c
+	r.mu.Unlock()
+}
+
+// Unregister 는 이름에 해당하는 검사를 제거한다.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.ready, name)
+	delete(r.live, name)
+	r.mu.Unlock()
+}
+
+// Result 는 검사 결과 응답 본문이다.
+type Result struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult 는 개별 검사의 결과다.
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// run 은 검사들을 동시에 실행하고 결과를 모은다.
+func (r *Registry) run(ctx context.Context, sets ...map[string]Check) Result {
+	r.mu.RLock()
+	checks := map[string]Check{}
+	for _, set := range sets {
+		for n, c := range set {
+			checks[n] = c
+		}
+	}
+	timeout := r.Timeout
+	r.mu.RUnlock()
+
+	res := Result{Status: "ok", Checks: make(map[string]CheckResult, len(checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	names := make([]string, 0, len(checks))
+	for n := range checks {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		wg.Add(1)
+		go func(name string, c Check) {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			cr := CheckResult{Status: "ok"}
+			if err := c(cctx); err != nil {
+				cr = CheckResult{Status: "fail", Error: err.Error()}
+			}
+			mu.Lock()
+			res.Checks[name] = cr
+			if cr.Status != "ok" {
+				res.Status = "fail"
+			}
+			mu.Unlock()
+		}(n, checks[n])
+	}
+	wg.Wait()
+	return res
+}
+
+func (r *Registry) handler(sets func() []map[string]Check) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		res := r.run(req.Context(), sets()...)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if res.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(res)
+	}
+}
+
+// Healthz 는 모든 검사를 실행하는 핸들러다.
+func (r *Registry) Healthz() http.HandlerFunc {
+	return r.handler(func() []map[string]Check { return []map[string]Check{r.live, r.ready} })
+}
+
+// Readyz 는 준비 검사만 실행하는 핸들러다. 로드 밸런서의 트래픽 투입 여부 판단에 사용한다.
+func (r *Registry) Readyz() http.HandlerFunc {
+	return r.handler(func() []map[string]Check { return []map[string]Check{r.ready} })
+}
+
+// Livez 는 생존 검사만 실행하는 핸들러다. 실패하면 오케스트레이터가 프로세스를 재시작한다.
+func (r *Registry) Livez() http.HandlerFunc {
+	return r.handler(func() []map[string]Check { return []map[string]Check{r.live} })
+}
+
+// Mount 는 세 엔드포인트를 라우터에 등록한다.
+func (r *Registry) Mount(rt *router.Router) {
+	rt.GET("/healthz", r.Healthz())
+	rt.GET("/readyz", r.Readyz())
+	rt.GET("/livez", r.Livez())
+}
@@ -0,0 +1,154 @@
+package httpclient
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/metrics"
+)
+
+// ErrCircuitOpen 은 차단기가 열려 있어 요청을 보내지 않았을 때 돌려주는 에러다.
+var ErrCircuitOpen = errors.New("httpclient: circuit breaker is open")
+
+// State 는 차단기 상태다.
+type State int
+
+const (
+	Closed   State = iota // 정상. 모든 요청을 보낸다.
+	HalfOpen              // 시험 중. 제한된 수의 요청만 보낸다.
+	Open                  // 차단. 요청을 보내지 않고 ErrCircuitOpen 을 돌려준다.
+)
+
+func (s State) String() string {
+	switch s {
+	case HalfOpen:
+		return "half_open"
+	case Open:
+		return "open"
+	}
+	return "closed"
+}
+
+var (
+	breakerState = metrics.NewGaugeVec("http_client_breaker_state",
+		"Circuit breaker state by host (0 closed, 1 half-open, 2 open).", "host")
+	breakerTransitions = metrics.NewCounterVec("http_client_breaker_transitions_total",
+		"Circuit breaker state changes by host and new state.", "host", "state")
+)
+
+// BreakerConfig 는 호스트별 차단기 설정이다. 0 인 값은 기본값을 쓴다.
+type BreakerConfig struct {
+	// Failures 번 연속 실패하면 차단기를 연다. 기본값 5. 음수이면 차단기를 쓰지 않는다.
+	Failures int
+	// OpenTimeout 이 지나면 열린 차단기를 반쯤 열어 시험 요청을 보낸다. 기본값 30초.
+	OpenTimeout time.Duration
+	// HalfOpenRequests 는 반쯤 열린 상태에서 동시에 보낼 수 있는 시험 요청 수다. 기본값 1.
+	// 시험 요청이 모두 성공하면 닫히고, 하나라도 실패하면 다시 열린다.
+	HalfOpenRequests int
+}
+
+func (c BreakerConfig) withDefaults() BreakerConfig {
+	if c.Failures == 0 {
+		c.Failures = 5
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = 30 * time.Second
+	}
+	if c.HalfOpenRequests <= 0 {
+		c.HalfOpenRequests = 1
+	}
+	return c
+}
+
+// breaker 는 호스트 하나의 차단기다.
+type breaker struct {
+	host string
+	cfg  BreakerConfig
+
+	mu       sync.Mutex
+	state    State
+	failures int       // 닫힌 상태의 연속 실패 수
+	openedAt time.Time // 열린 시각
+	trials   int       // 반쯤 열린 상태에서 진행 중인 시험 요청 수
+	passed   int       // 반쯤 열린 상태에서 성공한 시험 요청 수
+}
+
+func newBreaker(host string, cfg BreakerConfig) *breaker {
+	breakerState.Set(float64(Closed), host)
+	return &breaker{host: host, cfg: cfg}
+}
+
+// allow 는 요청을 보내도 되는지 확인한다. true 이면 결과를 반드시 done 이나 release 로 알려야 한다.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case Open:
+		if time.Since(b.openedAt) < b.cfg.OpenTimeout {
+			return false
+		}
+		b.setState(HalfOpen)
+		fallthrough
+	case HalfOpen:
+		if b.trials >= b.cfg.HalfOpenRequests {
+			return false
+		}
+		b.trials++
+	}
+	return true
+}
+
+// done 은 allow 로 허용된 요청의 결과를 기록한다.
+func (b *breaker) done(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case Closed:
+		if ok {
+			b.failures = 0
+			return
+		}
+		if b.failures++; b.failures >= b.cfg.Failures {
+			b.open()
+		}
+	case HalfOpen:
+		b.trials = max(b.trials-1, 0)
+		if !ok {
+			b.open()
+			return
+		}
+		if b.passed++; b.passed >= b.cfg.HalfOpenRequests {
+			b.setState(Closed)
+		}
+	}
+}
+
+// release 는 결과를 알 수 없이 끝난 요청의 시험 자리를 돌려준다.
+func (b *breaker) release() {
+	b.mu.Lock()
+	if b.state == HalfOpen {
+		b.trials = max(b.trials-1, 0)
+	}
+	b.mu.Unlock()
+}
+
+func (b *breaker) open() {
+	b.openedAt = time.Now()
+	b.setState(Open)
+}
+
+// setState 는 상태를 바꾸고 카운터를 초기화한다. b.mu 를 잡은 채로 호출한다.
+func (b *breaker) setState(s State) {
+	b.state = s
+	b.failures, b.trials, b.passed = 0, 0, 0
+	breakerState.Set(float64(s), b.host)
+	breakerTransitions.Inc(b.host, s.String())
+}
+
+// State 는 현재 상태다.
+func (b *breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
@@ -0,0 +1,202 @@
+// Package httpclient 는 재시도와 호스트별 차단기(circuit breaker)를 갖춘 외부 호출용 HTTP 클라이언트다.
+//
+// 재시도는 멱등 메서드(GET, HEAD, OPTIONS, TRACE, PUT, DELETE)에만 적용하며,
+// 시도 사이에는 지터가 섞인 지수 백오프로 기다린다.
+package httpclient
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/metrics"
+)
+
+var clientRetries = metrics.NewCounterVec("http_client_retries_total",
+	"Outbound requests retried by host.", "host")
+
+// RetryPolicy 는 재시도 설정이다. 0 인 값은 기본값을 쓴다.
+type RetryPolicy struct {
+	// Max 는 첫 시도 이후의 최대 재시도 횟수다. 기본값 2. 음수이면 재시도하지 않는다.
+	Max int
+	// BaseDelay 는 첫 재시도 전 대기 시간이다. 이후 두 배씩 늘어난다. 기본값 100ms.
+	BaseDelay time.Duration
+	// MaxDelay 는 대기 시간의 상한이다. 기본값 2초.
+	MaxDelay time.Duration
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.Max == 0 {
+		p.Max = 2
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = 100 * time.Millisecond
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = 2 * time.Second
+	}
+	return p
+}
+
+// backoff 는 attempt 번째(1부터) 재시도 전 대기 시간이다. [d/2, d) 범위의 지터를 쓴다.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// Transport 는 재시도와 차단기를 적용하는 http.RoundTripper 다.
+type Transport struct {
+	// Base 는 실제 요청을 보낼 RoundTripper 다. nil 이면 http.DefaultTransport.
+	Base    http.RoundTripper
+	Retry   RetryPolicy
+	Breaker BreakerConfig
+
+	once     sync.Once
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// New 는 t 를 Transport 로 쓰는 http.Client 를 만든다. timeout 은 재시도를 포함한 전체 시간 제한이다.
+func New(t *Transport, timeout time.Duration) *http.Client {
+	return &http.Client{Transport: t, Timeout: timeout}
+}
+
+func (t *Transport) init() {
+	t.Retry = t.Retry.withDefaults()
+	t.Breaker = t.Breaker.withDefaults()
+	t.breakers = map[string]*breaker{}
+}
+
+// breaker 는 host 의 차단기를 찾거나 만든다. 차단기를 쓰지 않으면 nil 이다.
+func (t *Transport) breaker(host string) *breaker {
+	if t.Breaker.Failures < 0 {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, ok := t.breakers[host]
+	if !ok {
+		b = newBreaker(host, t.Breaker)
+		t.breakers[host] = b
+	}
+	return b
+}
+
+// State 는 host 의 차단기 상태다. 요청한 적이 없는 호스트는 Closed 다.
+func (t *Transport) State(host string) State {
+	t.once.Do(t.init)
+	t.mu.Lock()
+	b := t.breakers[host]
+	t.mu.Unlock()
+	if b == nil {
+		return Closed
+	}
+	return b.State()
+}
+
+// RoundTrip 은 요청을 보내고, 재시도할 수 있는 실패이면 백오프 후 다시 보낸다.
+// 차단기가 열려 있으면 보내지 않고 ErrCircuitOpen 을 돌려준다.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.once.Do(t.init)
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	host := req.URL.Host
+	br := t.breaker(host)
+	retries := t.Retry.Max
+	if !replayable(req) {
+		retries = 0
+	}
+	var wait time.Duration
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if err := sleep(req.Context(), wait); err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+			clientRetries.Inc(host)
+		}
+		if br != nil && !br.allow() {
+			return nil, ErrCircuitOpen
+		}
+		resp, err := base.RoundTrip(req)
+		if br != nil {
+			if req.Context().Err() != nil {
+				// 요청자가 취소한 경우는 상대 호스트의 문제가 아니므로 결과로 치지 않는다.
+				br.release()
+			} else {
+				br.done(err == nil && resp.StatusCode < 500)
+			}
+		}
+		if attempt >= retries || req.Context().Err() != nil || (err == nil && !retryableStatus(resp.StatusCode)) {
+			return resp, err
+		}
+		wait = t.Retry.backoff(attempt + 1)
+		if resp != nil {
+			if d := retryAfter(resp); d > 0 {
+				if d > t.Retry.MaxDelay {
+					// 기다릴 수 있는 시간보다 길면 응답을 그대로 돌려준다.
+					return resp, nil
+				}
+				wait = d
+			}
+			// 연결을 재사용할 수 있도록 본문을 조금 읽고 닫는다.
+			io.CopyN(io.Discard, resp.Body, 4<<10)
+			resp.Body.Close()
+		}
+	}
+}
+
+// replayable 은 요청을 다시 보내도 안전한지 확인한다. 멱등 메서드이고 본문을 다시 만들 수 있어야 한다.
+func replayable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// retryableStatus 는 다시 시도할 만한 응답 상태인지 확인한다.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter 는 Retry-After 헤더의 초 값이다. 없거나 날짜 형식이면 0 이다.
+func retryAfter(resp *http.Response) time.Duration {
+	s, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || s < 0 {
+		return 0
+	}
+	return time.Duration(s) * time.Second
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
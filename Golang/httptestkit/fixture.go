@@ -0,0 +1,36 @@
+package httptestkit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// LoadJSON 은 testdata/name 의 JSON 을 v 에 디코드한다. 읽지 못하면 테스트를 멈춘다.
+func (k *Kit) LoadJSON(name string, v any) {
+	k.T.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		k.T.Fatalf("httptestkit: %v", err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		k.T.Fatalf("httptestkit: testdata/%s: %v", name, err)
+	}
+}
+
+// ExecSQL 은 testdata/name 의 SQL 문을 DB 에 실행한다. 테스트에 필요한 행을 미리 넣을 때 쓴다.
+// WithDB 없이 부르거나 실패하면 테스트를 멈춘다.
+func (k *Kit) ExecSQL(name string) {
+	k.T.Helper()
+	if k.DB == nil {
+		k.T.Fatalf("httptestkit: ExecSQL needs WithDB")
+	}
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		k.T.Fatalf("httptestkit: %v", err)
+	}
+	if _, err := k.DB.ExecContext(context.Background(), string(b)); err != nil {
+		k.T.Fatalf("httptestkit: testdata/%s: %v", name, err)
+	}
+}
@@ -0,0 +1,161 @@
+// Package httptestkit 은 핸들러 통합 테스트 도우미다. 서버와 같은 순서의 기본 미들웨어(요청 ID, 패닉 복구,
+// 보안 헤더, 언어 선택, 세션, 토큰 인증)를 건 라우터를 프로세스 안의 테스트 서버로 띄우고, 인증된 요청,
+// JSON 검사, 픽스처 읽기를 제공한다.
+//
+//	kit := httptestkit.New(t, httptestkit.WithDB())
+//	kit.Router.GET("/api/me", meHandler, kit.Keys.Require())
+//	kit.GET("/api/me").As("alice", "admin").Do().ExpectStatus(200).ExpectJSONField("sub", "alice")
+//
+// 서버는 첫 요청 때 시작하고 테스트가 끝나면 닫는다. 요청은 쿠키를 유지하는 같은 클라이언트로 보낸다.
+package httptestkit
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/auth"
+	"github.com/hgsong234/_stack/Golang/config"
+	"github.com/hgsong234/_stack/Golang/i18n"
+	"github.com/hgsong234/_stack/Golang/middleware"
+	"github.com/hgsong234/_stack/Golang/migrate"
+	"github.com/hgsong234/_stack/Golang/rbac"
+	"github.com/hgsong234/_stack/Golang/router"
+	"github.com/hgsong234/_stack/Golang/session"
+	"github.com/hgsong234/_stack/Golang/store"
+)
+
+// Kit 은 테스트 하나의 서버와 도우미다. 라우트는 첫 요청 전에 Router 에 등록한다.
+type Kit struct {
+	T      testing.TB
+	Router *router.Router
+	// Keys 는 테스트용 HMAC 키로 토큰을 서명하고 검증한다.
+	Keys *auth.KeySet
+	// Policy 는 config 기본 역할(admin, editor, viewer)의 정책이다. WithRoles 로 바꾼다.
+	Policy   *rbac.Policy
+	Sessions *session.Manager
+	// DB 는 WithDB 를 쓰면 마이그레이션을 마친 임시 SQLite 데이터베이스다.
+	DB     store.DB
+	Client *http.Client
+
+	mws     []router.Middleware
+	handler http.Handler
+	server  *httptest.Server
+}
+
+// Option 은 New 의 선택 사항이다.
+type Option func(*Kit) error
+
+// WithDB 는 임시 디렉터리의 SQLite 데이터베이스를 열고 마이그레이션을 적용한다.
+func WithDB() Option {
+	return func(k *Kit) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		db, err := store.Open(ctx, store.Config{Driver: "sqlite", DSN: filepath.Join(k.T.TempDir(), "test.db")})
+		if err != nil {
+			return err
+		}
+		k.T.Cleanup(func() { db.Close() })
+		m, err := migrate.New(db)
+		if err != nil {
+			return err
+		}
+		if _, err := m.Up(ctx); err != nil {
+			return err
+		}
+		k.DB = db
+		return nil
+	}
+}
+
+// WithRoles 는 rbac.Parse 형식("admin=*", "viewer=users:read")으로 역할을 정한다.
+func WithRoles(defs ...string) Option {
+	return func(k *Kit) error {
+		p, err := rbac.Parse(defs)
+		if err != nil {
+			return err
+		}
+		k.Policy = p
+		return nil
+	}
+}
+
+// WithMiddleware 는 기본 미들웨어 안쪽에 전역 미들웨어를 더한다.
+func WithMiddleware(mws ...router.Middleware) Option {
+	return func(k *Kit) error {
+		k.mws = append(k.mws, mws...)
+		return nil
+	}
+}
+
+// New 는 Kit 을 만든다. 준비에 실패하면 테스트를 멈춘다.
+func New(t testing.TB, opts ...Option) *Kit {
+	t.Helper()
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	k := &Kit{T: t, Router: router.New(), Keys: auth.NewKeySet(auth.HMACKey("test", secret))}
+	sessions, err := session.NewManager(session.NewMemoryStore(0), session.Options{Secret: secret})
+	if err != nil {
+		t.Fatalf("httptestkit: %v", err)
+	}
+	k.Sessions = sessions
+	if k.Policy, err = rbac.Parse(config.Default().RBAC.Roles); err != nil {
+		t.Fatalf("httptestkit: %v", err)
+	}
+	for _, o := range opts {
+		if err := o(k); err != nil {
+			t.Fatalf("httptestkit: %v", err)
+		}
+	}
+	jar, _ := cookiejar.New(nil)
+	k.Client = &http.Client{
+		Jar:     jar,
+		Timeout: 10 * time.Second,
+		// 리다이렉트는 따라가지 않고 응답으로 검사한다.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	return k
+}
+
+// Handler 는 기본 미들웨어를 건 라우터다. 서버 없이 httptest.NewRecorder 로 부를 때 쓴다.
+func (k *Kit) Handler() http.Handler {
+	if k.handler != nil {
+		return k.handler
+	}
+	k.Router.Use(
+		middleware.RequestID(),
+		middleware.Recover(middleware.RecoverConfig{}),
+		middleware.SecureHeaders(middleware.SecureConfig(config.Default().Security)),
+		i18n.Default.Middleware(),
+		k.Sessions.Middleware(),
+		k.Keys.Authenticate(),
+	)
+	k.Router.Use(k.mws...)
+	k.handler = k.Router
+	return k.handler
+}
+
+// URL 은 테스트 서버의 path 주소다. 서버가 없으면 시작한다.
+func (k *Kit) URL(path string) string {
+	if k.server == nil {
+		k.server = httptest.NewServer(k.Handler())
+		k.T.Cleanup(k.server.Close)
+	}
+	return k.server.URL + path
+}
+
+// Token 은 subject 와 roles 로 서명한 한 시간짜리 토큰이다.
+func (k *Kit) Token(subject string, roles ...string) string {
+	k.T.Helper()
+	now := time.Now()
+	tok, err := k.Keys.Sign(auth.Claims{Subject: subject, Roles: roles, IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()})
+	if err != nil {
+		k.T.Fatalf("httptestkit: sign token: %v", err)
+	}
+	return tok
+}
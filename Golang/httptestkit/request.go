@@ -0,0 +1,100 @@
+package httptestkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Request 는 보낼 요청이다. 메서드는 체이닝하고 Do 로 보낸다.
+type Request struct {
+	kit    *Kit
+	method string
+	path   string
+	header http.Header
+	body   io.Reader
+}
+
+// NewRequest 는 method 와 path 로 요청을 만든다.
+func (k *Kit) NewRequest(method, path string) *Request {
+	return &Request{kit: k, method: method, path: path, header: http.Header{}}
+}
+
+func (k *Kit) GET(path string) *Request    { return k.NewRequest(http.MethodGet, path) }
+func (k *Kit) DELETE(path string) *Request { return k.NewRequest(http.MethodDelete, path) }
+
+// POST, PUT, PATCH 는 body 를 JSON 으로 보낸다. body 가 nil 이면 본문이 없다.
+func (k *Kit) POST(path string, body any) *Request {
+	return k.NewRequest(http.MethodPost, path).JSON(body)
+}
+
+func (k *Kit) PUT(path string, body any) *Request {
+	return k.NewRequest(http.MethodPut, path).JSON(body)
+}
+
+func (k *Kit) PATCH(path string, body any) *Request {
+	return k.NewRequest(http.MethodPatch, path).JSON(body)
+}
+
+// As 는 subject 와 roles 의 Bearer 토큰을 붙인다.
+func (r *Request) As(subject string, roles ...string) *Request {
+	return r.Header("Authorization", "Bearer "+r.kit.Token(subject, roles...))
+}
+
+// APIKey 는 X-API-Key 헤더를 붙인다.
+func (r *Request) APIKey(key string) *Request { return r.Header("X-API-Key", key) }
+
+func (r *Request) Header(name, value string) *Request {
+	r.header.Set(name, value)
+	return r
+}
+
+// JSON 은 v 를 JSON 본문으로 보낸다. v 가 문자열이나 []byte 면 그대로 보낸다.
+func (r *Request) JSON(v any) *Request {
+	var b []byte
+	switch v := v.(type) {
+	case nil:
+		return r
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			r.kit.T.Fatalf("httptestkit: encode body: %v", err)
+		}
+	}
+	r.body = bytes.NewReader(b)
+	return r.Header("Content-Type", "application/json")
+}
+
+// Form 은 v 를 폼 본문으로 보낸다.
+func (r *Request) Form(v url.Values) *Request {
+	r.body = strings.NewReader(v.Encode())
+	return r.Header("Content-Type", "application/x-www-form-urlencoded")
+}
+
+// Do 는 요청을 보내고 본문까지 읽은 응답을 돌려준다. 보내지 못하면 테스트를 멈춘다.
+func (r *Request) Do() *Response {
+	t := r.kit.T
+	t.Helper()
+	req, err := http.NewRequest(r.method, r.kit.URL(r.path), r.body)
+	if err != nil {
+		t.Fatalf("httptestkit: %s %s: %v", r.method, r.path, err)
+	}
+	req.Header = r.header
+	resp, err := r.kit.Client.Do(req)
+	if err != nil {
+		t.Fatalf("httptestkit: %s %s: %v", r.method, r.path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("httptestkit: %s %s: read body: %v", r.method, r.path, err)
+	}
+	return &Response{Response: resp, Body: body, t: t, name: r.method + " " + r.path}
+}
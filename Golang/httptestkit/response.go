@@ -0,0 +1,109 @@
+package httptestkit
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hgsong234/_stack/Golang/apperror"
+)
+
+// Response 는 본문까지 읽은 응답이다. Expect 메서드는 실패하면 테스트를 실패로 표시하고 계속한다.
+type Response struct {
+	*http.Response
+	Body []byte
+
+	t    testing.TB
+	name string
+}
+
+// ExpectStatus 는 상태 코드를 검사한다.
+func (r *Response) ExpectStatus(code int) *Response {
+	r.t.Helper()
+	if r.StatusCode != code {
+		r.t.Errorf("%s: status = %d, want %d; body: %s", r.name, r.StatusCode, code, r.Body)
+	}
+	return r
+}
+
+// ExpectHeader 는 헤더 값을 검사한다.
+func (r *Response) ExpectHeader(name, value string) *Response {
+	r.t.Helper()
+	if got := r.Header.Get(name); got != value {
+		r.t.Errorf("%s: header %s = %q, want %q", r.name, name, got, value)
+	}
+	return r
+}
+
+// Decode 는 JSON 본문을 v 에 디코드한다. 디코드하지 못하면 테스트를 멈춘다.
+func (r *Response) Decode(v any) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.t.Fatalf("%s: decode body: %v; body: %s", r.name, err, r.Body)
+	}
+	return r
+}
+
+// ExpectJSON 은 본문이 JSON 문서 want 와 값으로 같은지 검사한다. 키 순서와 공백은 무시한다.
+func (r *Response) ExpectJSON(want string) *Response {
+	r.t.Helper()
+	var w, got any
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		r.t.Fatalf("%s: invalid expected JSON: %v", r.name, err)
+	}
+	r.Decode(&got)
+	if !reflect.DeepEqual(w, got) {
+		r.t.Errorf("%s: body = %s, want %s", r.name, r.Body, want)
+	}
+	return r
+}
+
+// ExpectJSONField 는 점으로 구분한 경로(예: "error.code", "items.0.id")의 값을 검사한다.
+// want 는 JSON 으로 바꿔서 비교하므로 숫자는 int 로 넘겨도 된다.
+func (r *Response) ExpectJSONField(path string, want any) *Response {
+	r.t.Helper()
+	var doc any
+	r.Decode(&doc)
+	got, ok := lookup(doc, path)
+	if !ok {
+		r.t.Errorf("%s: field %q not found; body: %s", r.name, path, r.Body)
+		return r
+	}
+	var w any
+	b, _ := json.Marshal(want)
+	json.Unmarshal(b, &w)
+	if !reflect.DeepEqual(w, got) {
+		r.t.Errorf("%s: field %q = %v, want %v", r.name, path, got, want)
+	}
+	return r
+}
+
+// ExpectError 는 apperror 형식의 에러 응답(application/problem+json 의 code)인지 검사한다.
+func (r *Response) ExpectError(status int, code string) *Response {
+	r.t.Helper()
+	return r.ExpectStatus(status).ExpectHeader("Content-Type", apperror.ContentType).ExpectJSONField("code", code)
+}
+
+func lookup(v any, path string) (any, bool) {
+	for _, p := range strings.Split(path, ".") {
+		switch x := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = x[p]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil, false
+			}
+			v = x[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
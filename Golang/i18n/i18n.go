@@ -0,0 +1,186 @@
+// Package i18n 은 메시지 카탈로그와 요청별 언어 선택을 제공한다.
+//
+// 언어는 ?lang= 쿼리, lang 쿠키, Accept-Language 헤더 순서로 정한다.
+// 쿼리로 고른 언어는 쿠키에 저장되어 다음 요청에도 유지된다.
+// 카탈로그는 locales/<언어>.json 이며 값은 fmt 형식 문자열이다. (예: "Hello, %s!")
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+//go:embed locales/*.json
+var embedded embed.FS
+
+// 언어를 지정하는 쿼리 파라미터와 쿠키 이름
+const (
+	QueryParam = "lang"
+	CookieName = "lang"
+)
+
+// Bundle 은 언어별 메시지 카탈로그다.
+type Bundle struct {
+	fallback string
+	catalogs map[string]map[string]string
+}
+
+// Load 는 fsys 의 *.json 카탈로그를 읽는다. fallback 은 메시지가 없을 때 쓰는 언어다.
+func Load(fsys fs.FS, fallback string) (*Bundle, error) {
+	names, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{fallback: fallback, catalogs: map[string]map[string]string{}}
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		msgs := map[string]string{}
+		if err := json.Unmarshal(data, &msgs); err != nil {
+			return nil, fmt.Errorf("i18n: %s: %w", name, err)
+		}
+		b.catalogs[strings.TrimSuffix(path.Base(name), ".json")] = msgs
+	}
+	if _, ok := b.catalogs[fallback]; !ok {
+		return nil, fmt.Errorf("i18n: no catalog for fallback language %q", fallback)
+	}
+	return b, nil
+}
+
+// Default 는 내장 카탈로그(영어, 한국어)로 만든 Bundle 이다. 기본 언어는 영어다.
+var Default = func() *Bundle {
+	sub, _ := fs.Sub(embedded, "locales")
+	b, err := Load(sub, "en")
+	if err != nil {
+		panic(err)
+	}
+	return b
+}()
+
+// Languages 는 카탈로그가 있는 언어를 정렬해 돌려준다.
+func (b *Bundle) Languages() []string {
+	out := make([]string, 0, len(b.catalogs))
+	for lang := range b.catalogs {
+		out = append(out, lang)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Translate 는 lang 의 key 메시지에 args 를 넣어 돌려준다.
+// lang 에 없으면 기본 언어를, 그래도 없으면 key 를 그대로 쓴다.
+func (b *Bundle) Translate(lang, key string, args ...any) string {
+	msg, ok := b.catalogs[lang][key]
+	if !ok {
+		if msg, ok = b.catalogs[b.fallback][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// FuncMap 은 템플릿 함수다. {{t .Lang "greeting" .Name}}
+func (b *Bundle) FuncMap() template.FuncMap {
+	return template.FuncMap{"t": b.Translate}
+}
+
+// 컨텍스트 키
+type ctxKey struct{}
+
+type locale struct {
+	b    *Bundle
+	lang string
+}
+
+// Middleware 는 요청 언어를 정해 컨텍스트에 저장하고 Content-Language 를 설정하는 미들웨어를 만든다.
+func (b *Bundle) Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lang := ""
+			if q := r.URL.Query().Get(QueryParam); q != "" {
+				if lang = b.match(q); lang != "" {
+					http.SetCookie(w, &http.Cookie{
+						Name: CookieName, Value: lang, Path: "/", MaxAge: 365 * 24 * 3600,
+						SameSite: http.SameSiteLaxMode,
+					})
+				}
+			}
+			if c, err := r.Cookie(CookieName); lang == "" && err == nil {
+				lang = b.match(c.Value)
+			}
+			if lang == "" {
+				lang = b.fromHeader(r.Header.Get("Accept-Language"))
+			}
+			h := w.Header()
+			h.Add("Vary", "Accept-Language")
+			h.Add("Vary", "Cookie")
+			h.Set("Content-Language", lang)
+			ctx := context.WithValue(r.Context(), ctxKey{}, locale{b, lang})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// match 는 "ko-KR" 같은 언어 태그를 카탈로그 언어로 바꾼다. 없으면 "" 다.
+func (b *Bundle) match(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if _, ok := b.catalogs[tag]; ok {
+		return tag
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	if _, ok := b.catalogs[base]; ok {
+		return base
+	}
+	return ""
+}
+
+// fromHeader 는 Accept-Language 에서 q 값이 가장 높은 지원 언어를 고른다.
+func (b *Bundle) fromHeader(header string) string {
+	best, bestQ := b.fallback, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if p := strings.TrimSpace(params); strings.HasPrefix(p, "q=") {
+			// "q=NaN", "q=9" 같은 값은 무시한다.
+			if f, err := strconv.ParseFloat(p[2:], 64); err == nil && f >= 0 && f <= 1 {
+				q = f
+			}
+		}
+		if lang := b.match(tag); lang != "" && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// Lang 은 요청 언어를 돌려준다. 미들웨어가 없으면 Default 의 기본 언어다.
+func Lang(ctx context.Context) string {
+	if l, ok := ctx.Value(ctxKey{}).(locale); ok {
+		return l.lang
+	}
+	return Default.fallback
+}
+
+// T 는 요청 언어로 key 메시지를 번역한다. (예: i18n.T(r.Context(), "greeting", name))
+func T(ctx context.Context, key string, args ...any) string {
+	if l, ok := ctx.Value(ctxKey{}).(locale); ok {
+		return l.b.Translate(l.lang, key, args...)
+	}
+	return Default.Translate(Default.fallback, key, args...)
+}
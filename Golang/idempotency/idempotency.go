@@ -0,0 +1,191 @@
+// Package idempotency 는 Idempotency-Key 헤더로 POST/PATCH 요청을 한 번만 처리하게 하는 미들웨어다.
+//
+// 키가 붙은 첫 요청의 응답을 저장해 두고, TTL 안에 같은 키로 다시 온 요청에는 핸들러를 실행하지 않고
+// 저장한 응답을 돌려준다(Idempotent-Replayed: true). 같은 키에 다른 본문을 보내거나 첫 요청이 아직 처리 중이면
+// 409 로 거절한다. 5xx 응답은 저장하지 않으므로 클라이언트는 같은 키로 다시 시도할 수 있다.
+//
+// 키는 요청한 주체(Scope)마다 따로 관리하므로 다른 사용자의 응답을 받지 않는다.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/metrics"
+	"github.com/hgsong234/_stack/Golang/middleware"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// 요청과 응답 헤더
+const (
+	Header         = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+// MaxKeyLength 는 받는 키의 최대 길이다.
+const MaxKeyLength = 255
+
+var idempotentRequests = metrics.NewCounterVec("idempotency_requests_total",
+	"Requests carrying an Idempotency-Key by result (new, replayed, conflict, in_progress).", "result")
+
+// Config 는 Middleware 설정이다.
+type Config struct {
+	Store Store
+	// TTL 은 응답을 보관하는 시간이다. 0 이면 24시간.
+	TTL time.Duration
+	// LockTimeout 은 처리 중 표시를 남겨 두는 최대 시간이다. 처리 도중 서버가 죽어도 이 시간이 지나면
+	// 같은 키로 다시 시도할 수 있다. 0 이면 1분.
+	LockTimeout time.Duration
+	// MaxBody 는 저장할 응답 본문의 최대 크기다. 넘으면 저장하지 않는다. 0 이면 1MB.
+	MaxBody int
+	// Scope 는 요청한 주체를 돌려준다. nil 이면 Authorization 과 X-API-Key 헤더 값으로 구분한다.
+	Scope func(*http.Request) string
+}
+
+// record 는 저장된 기록이다. Status 가 0 이면 첫 요청이 아직 처리 중이다.
+type record struct {
+	Fingerprint string      `json:"fingerprint"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// Middleware 는 Idempotency-Key 가 붙은 POST/PATCH 요청을 한 번만 처리한다. 키가 없거나 다른 메서드면 그대로 통과한다.
+// 인증 미들웨어 안쪽, 요청을 바꾸는 핸들러 바로 바깥에 건다.
+func Middleware(cfg Config) router.Middleware {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.LockTimeout <= 0 {
+		cfg.LockTimeout = time.Minute
+	}
+	if cfg.MaxBody <= 0 {
+		cfg.MaxBody = 1 << 20
+	}
+	if cfg.Scope == nil {
+		cfg.Scope = credentials
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > MaxKeyLength {
+				api.WriteError(w, api.NewError(http.StatusBadRequest, "invalid_idempotency_key",
+					"Idempotency-Key must be at most "+strconv.Itoa(MaxKeyLength)+" characters"))
+				return
+			}
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				api.WriteError(w, api.NewError(http.StatusRequestEntityTooLarge, "body_too_large", "request body is too large"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fp := fingerprint(r, body)
+
+			ctx := r.Context()
+			storeKey := hash(cfg.Scope(r)) + ":" + key
+			pending, _ := json.Marshal(record{Fingerprint: fp})
+			data, ok, err := cfg.Store.Reserve(ctx, storeKey, pending, cfg.LockTimeout)
+			if err != nil {
+				logging.From(ctx).Error("idempotency: reserve", "err", err)
+				api.WriteError(w, api.Internal())
+				return
+			}
+			if !ok {
+				replay(w, r, data, fp)
+				return
+			}
+			idempotentRequests.Inc("new")
+
+			// 핸들러가 패닉하거나 저장할 수 없는 응답을 돌려주면 처리 중 표시를 지워 다시 시도할 수 있게 한다.
+			saved := false
+			defer func() {
+				if !saved {
+					if err := cfg.Store.Delete(context.WithoutCancel(ctx), storeKey); err != nil {
+						logging.From(ctx).Warn("idempotency: release key", "err", err)
+					}
+				}
+			}()
+			pre := w.Header().Clone()
+			rec := middleware.NewBodyRecorder(w, cfg.MaxBody)
+			next.ServeHTTP(rec, r)
+			resBody, complete := rec.Body()
+			switch {
+			case rec.Status() >= 500:
+				return
+			case !complete:
+				logging.From(ctx).Warn("idempotency: response too large to store; retries will run again", "limit", cfg.MaxBody)
+				return
+			}
+			// 쿠키는 처음 요청한 클라이언트의 것이므로 다시 보내지 않는다.
+			done, _ := json.Marshal(record{Fingerprint: fp, Status: rec.Status(), Header: rec.Changed(pre, "Set-Cookie"), Body: resBody})
+			if err := cfg.Store.Save(context.WithoutCancel(ctx), storeKey, done, cfg.TTL); err != nil {
+				logging.From(ctx).Error("idempotency: save", "err", err)
+				return
+			}
+			saved = true
+		})
+	}
+}
+
+// replay 는 같은 키로 온 요청에 저장된 응답이나 409 를 쓴다.
+func replay(w http.ResponseWriter, r *http.Request, data []byte, fp string) {
+	var rec record
+	if data != nil {
+		if err := json.Unmarshal(data, &rec); err != nil {
+			logging.From(r.Context()).Error("idempotency: decode record", "err", err)
+			api.WriteError(w, api.Internal())
+			return
+		}
+	}
+	switch {
+	case data != nil && rec.Fingerprint != fp:
+		idempotentRequests.Inc("conflict")
+		api.WriteError(w, api.NewError(http.StatusConflict, "idempotency_key_reused",
+			"Idempotency-Key was already used with a different request"))
+	case rec.Status == 0:
+		idempotentRequests.Inc("in_progress")
+		w.Header().Set("Retry-After", "1")
+		api.WriteError(w, api.NewError(http.StatusConflict, "idempotency_key_in_use",
+			"a request with this Idempotency-Key is still being processed"))
+	default:
+		idempotentRequests.Inc("replayed")
+		h := w.Header()
+		for k, v := range rec.Header {
+			h[k] = v
+		}
+		h.Set(ReplayedHeader, "true")
+		h.Set("Content-Length", strconv.Itoa(len(rec.Body)))
+		w.WriteHeader(rec.Status)
+		w.Write(rec.Body)
+	}
+}
+
+// fingerprint 는 메서드, 경로, 쿼리와 본문의 해시다. 같은 키에 다른 요청을 보냈는지 가린다.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func credentials(r *http.Request) string {
+	return r.Header.Get("Authorization") + "\n" + r.Header.Get("X-API-Key")
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:12])
+}
@@ -0,0 +1,119 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store 는 직렬화된 기록을 보관한다. 구현은 동시에 호출해도 안전해야 한다.
+type Store interface {
+	// Reserve 는 key 가 없으면 data 를 ttl 동안 저장하고 ok 를 true 로 돌려준다.
+	// 이미 있으면 저장하지 않고 저장된 값을 돌려준다. 두 요청이 동시에 불러도 한쪽만 ok 다.
+	Reserve(ctx context.Context, key string, data []byte, ttl time.Duration) (existing []byte, ok bool, err error)
+	// Save 는 key 의 값을 data 로 바꾼다.
+	Save(ctx context.Context, key string, data []byte, ttl time.Duration) error
+	// Delete 는 key 를 지운다.
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryStore 는 프로세스 내 Store 다. 인스턴스가 여럿이면 RedisStore 를 쓴다.
+type MemoryStore struct {
+	mu    sync.Mutex
+	items map[string]memItem
+}
+
+type memItem struct {
+	data   []byte
+	expiry time.Time
+}
+
+// NewMemoryStore 는 MemoryStore 를 만든다. 만료된 기록은 interval 마다 정리된다.
+func NewMemoryStore(interval time.Duration) *MemoryStore {
+	s := &MemoryStore{items: map[string]memItem{}}
+	if interval > 0 {
+		go func() {
+			for range time.Tick(interval) {
+				s.Cleanup()
+			}
+		}()
+	}
+	return s
+}
+
+// Reserve 는 Store 구현이다.
+func (s *MemoryStore) Reserve(_ context.Context, key string, data []byte, ttl time.Duration) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if it, ok := s.items[key]; ok && time.Now().Before(it.expiry) {
+		return it.data, false, nil
+	}
+	s.items[key] = memItem{data: data, expiry: time.Now().Add(ttl)}
+	return nil, true, nil
+}
+
+// Save 는 Store 구현이다.
+func (s *MemoryStore) Save(_ context.Context, key string, data []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	s.items[key] = memItem{data: data, expiry: time.Now().Add(ttl)}
+	s.mu.Unlock()
+	return nil
+}
+
+// Delete 는 Store 구현이다.
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.items, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// Cleanup 은 만료된 기록을 지운다.
+func (s *MemoryStore) Cleanup() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, it := range s.items {
+		if now.After(it.expiry) {
+			delete(s.items, k)
+		}
+	}
+}
+
+// RedisStore 는 Redis 에 기록을 보관해 여러 인스턴스가 같은 키를 알아보게 한다.
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore 는 client 로 RedisStore 를 만든다. 키는 "idempotency:" 접두사를 갖는다.
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client, prefix: "idempotency:"}
+}
+
+// Reserve 는 Store 구현이다. SET NX 로 자리를 잡고, 이미 있으면 GET 으로 읽는다.
+func (s *RedisStore) Reserve(ctx context.Context, key string, data []byte, ttl time.Duration) ([]byte, bool, error) {
+	ok, err := s.client.SetNX(ctx, s.prefix+key, data, ttl).Result()
+	if err != nil || ok {
+		return nil, ok, err
+	}
+	b, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if err == redis.Nil {
+		// 그 사이에 만료되었다. 한 번 더 자리를 잡아 본다.
+		ok, err = s.client.SetNX(ctx, s.prefix+key, data, ttl).Result()
+		return nil, ok, err
+	}
+	return b, false, err
+}
+
+// Save 는 Store 구현이다.
+func (s *RedisStore) Save(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+key, data, ttl).Err()
+}
+
+// Delete 는 Store 구현이다.
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Mount 는 r 의 prefix 아래에 큐 관리 라우트를 등록한다. mws 로 관리자 인증을 건다.
+//
+//	GET    prefix                  대기 상태와 실패 목록
+//	POST   prefix/dead/{id}/retry  실패한 작업을 다시 넣음
+//	DELETE prefix/dead/{id}        실패한 작업을 지움
+func (q *Queue) Mount(r router.Routes, prefix string, mws ...router.Middleware) {
+	g := r.Group(prefix, mws...)
+	g.GET("", q.status)
+	g.POST("/dead/{id}/retry", q.retry)
+	g.DELETE("/dead/{id}", q.discard)
+}
+
+func (q *Queue) status(w http.ResponseWriter, r *http.Request) {
+	api.WriteJSON(w, http.StatusOK, map[string]any{"stats": q.Stats(), "dead": q.DeadLetters()})
+}
+
+func (q *Queue) retry(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+	ok, err := q.Retry(id)
+	switch {
+	case errors.Is(err, ErrClosed), errors.Is(err, ErrFull):
+		api.WriteError(w, api.NewError(http.StatusServiceUnavailable, "queue_unavailable", err.Error()))
+		return
+	case !ok:
+		api.WriteError(w, api.NewError(http.StatusNotFound, "not_found", "no dead job with this id"))
+		return
+	}
+	logging.From(r.Context()).Info("dead job requeued", "job_id", id)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (q *Queue) discard(w http.ResponseWriter, r *http.Request) {
+	if !q.Discard(router.Param(r, "id")) {
+		api.WriteError(w, api.NewError(http.StatusNotFound, "not_found", "no dead job with this id"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
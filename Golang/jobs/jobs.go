@@ -0,0 +1,466 @@
+// Package jobs 는 핸들러에서 넣은 작업을 백그라운드 워커가 처리하는 작업 큐다.
+//
+// 실패한 작업은 지수 백오프로 다시 시도하고, MaxAttempts 를 넘기면 실패 목록(dead letter)에 남긴다.
+// Shutdown 은 새 작업을 막고 이미 받은 작업을 끝까지 처리한다. 기한 안에 끝내지 못한 작업은
+// SpoolFile 에 저장했다가 다음 Start 에서 다시 넣는다.
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/lifecycle"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/metrics"
+)
+
+var (
+	// ErrClosed 는 Shutdown 이 시작된 뒤 Enqueue 하면 돌려주는 에러다.
+	ErrClosed = errors.New("jobs: queue is shutting down")
+	// ErrFull 은 대기 중인 작업이 QueueSize 만큼 차 있을 때 돌려주는 에러다.
+	ErrFull = errors.New("jobs: queue is full")
+)
+
+var (
+	jobsEnqueued = metrics.NewCounterVec("jobs_enqueued_total", "Jobs accepted by kind.", "kind")
+	jobsDone     = metrics.NewCounterVec("jobs_processed_total",
+		"Job attempts by kind and result (ok, retry, dead).", "kind", "result")
+	jobsDuration = metrics.NewHistogramVec("jobs_duration_seconds", "Job attempt duration by kind.", nil, "kind")
+	jobsWaiting  = metrics.NewGaugeVec("jobs_queue_depth", "Jobs waiting to run, including scheduled retries.")
+)
+
+// Handler 는 작업 하나를 처리한다. 에러를 돌려주면 다시 시도하고, Permanent 로 감싼 에러이면 바로 실패 목록에 넣는다.
+// ctx 는 Shutdown 기한이 지나면 취소된다.
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+// permanentError 는 다시 시도해도 소용없는 실패다.
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent 는 err 를 재시도하지 않을 실패로 표시한다. (예: 잘못된 payload)
+func Permanent(err error) error { return permanentError{err} }
+
+// IsPermanent 는 err 가 Permanent 로 표시된 실패인지 확인한다.
+func IsPermanent(err error) bool { return errors.As(err, new(permanentError)) }
+
+// Job 은 큐에 들어간 작업이다.
+type Job struct {
+	ID        string          `json:"id"`
+	Kind      string          `json:"kind"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error,omitempty"`
+	Enqueued  time.Time       `json:"enqueued"`
+	// RunAt 은 다시 시도할 시각이다. 처음 넣은 작업은 영(zero) 값이다.
+	RunAt time.Time `json:"run_at,omitzero"`
+}
+
+// Options 는 큐 설정이다. 0 인 값은 기본값을 쓴다.
+type Options struct {
+	// Concurrency 는 워커 수다. 기본값 4.
+	Concurrency int
+	// QueueSize 는 대기할 수 있는 작업 수다. 기본값 1000.
+	QueueSize int
+	// MaxAttempts 는 실패 목록으로 보내기 전 최대 시도 횟수다. 기본값 5.
+	MaxAttempts int
+	// BaseDelay 는 첫 재시도 전 대기 시간이다. 이후 두 배씩 늘어난다. 기본값 1초.
+	BaseDelay time.Duration
+	// MaxDelay 는 대기 시간의 상한이다. 기본값 5분.
+	MaxDelay time.Duration
+	// DeadLetters 는 보관할 실패 작업 수다. 넘으면 오래된 것부터 버린다. 기본값 100.
+	DeadLetters int
+	// SpoolFile 이 있으면 종료 때 끝내지 못한 작업을 저장하고 시작할 때 다시 읽는다.
+	SpoolFile string
+}
+
+func (o Options) withDefaults() Options {
+	if o.Concurrency <= 0 {
+		o.Concurrency = 4
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = 1000
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 5
+	}
+	if o.BaseDelay <= 0 {
+		o.BaseDelay = time.Second
+	}
+	if o.MaxDelay <= 0 {
+		o.MaxDelay = 5 * time.Minute
+	}
+	if o.DeadLetters <= 0 {
+		o.DeadLetters = 100
+	}
+	return o
+}
+
+// Queue 는 메모리 작업 큐와 워커 풀이다.
+type Queue struct {
+	opts     Options
+	handlers map[string]Handler
+
+	ready chan *Job
+	wg    sync.WaitGroup // 워커
+
+	// runCtx 는 워커가 핸들러에 넘기는 컨텍스트다. Shutdown 기한이 지나면 취소한다.
+	runCtx    context.Context
+	cancelRun context.CancelFunc
+
+	mu       sync.Mutex
+	closed   bool
+	delayed  map[string]*delayedJob // 재시도를 기다리는 작업
+	dead     []Job
+	leftover []*Job // 종료 중에 실패해 SpoolFile 로 보낼 작업
+}
+
+type delayedJob struct {
+	job   *Job
+	timer *time.Timer
+}
+
+// New 는 작업 큐를 만든다. Register 로 처리기를 등록한 뒤 Start 한다.
+func New(opts Options) *Queue {
+	opts = opts.withDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		opts:      opts,
+		handlers:  map[string]Handler{},
+		ready:     make(chan *Job, opts.QueueSize),
+		runCtx:    ctx,
+		cancelRun: cancel,
+		delayed:   map[string]*delayedJob{},
+	}
+}
+
+// Register 는 kind 작업의 처리기를 등록한다. Start 전에 호출한다.
+func (q *Queue) Register(kind string, h Handler) {
+	q.handlers[kind] = h
+}
+
+// Start 는 SpoolFile 에 남은 작업을 다시 넣고 워커를 시작한다.
+func (q *Queue) Start() error {
+	if err := q.loadSpool(); err != nil {
+		return err
+	}
+	for range q.opts.Concurrency {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return nil
+}
+
+// RegisterLifecycle 은 lc 의 시작 훅으로 Start 를, 종료 훅으로 Shutdown 을 등록한다.
+func (q *Queue) RegisterLifecycle(lc *lifecycle.Manager) {
+	lc.OnStart("jobs", 0, func(context.Context) error { return q.Start() })
+	lc.OnShutdown("jobs", 0, q.Shutdown)
+}
+
+// Enqueue 는 payload 를 JSON 으로 바꿔 kind 작업을 넣고 작업 ID 를 돌려준다.
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any) (string, error) {
+	if _, ok := q.handlers[kind]; !ok {
+		return "", fmt.Errorf("jobs: no handler for kind %q", kind)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	j := &Job{ID: newID(), Kind: kind, Payload: data, Enqueued: time.Now()}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return "", ErrClosed
+	}
+	select {
+	case q.ready <- j:
+	default:
+		return "", ErrFull
+	}
+	jobsEnqueued.Inc(kind)
+	jobsWaiting.Add(1)
+	logging.From(ctx).Debug("job enqueued", "job_id", j.ID, "kind", kind)
+	return j.ID, nil
+}
+
+// work 는 워커 하나다. ready 가 닫히면 남은 작업을 모두 처리하고 끝난다.
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for j := range q.ready {
+		jobsWaiting.Add(-1)
+		q.run(j)
+	}
+}
+
+// run 은 작업을 한 번 시도하고 결과에 따라 재시도를 예약하거나 실패 목록에 넣는다.
+func (q *Queue) run(j *Job) {
+	l := logging.Default()
+	if q.runCtx.Err() != nil {
+		// 기한이 지나 처리할 수 없으므로 다음 실행을 위해 남긴다.
+		q.keep(j)
+		return
+	}
+	j.Attempts++
+	start := time.Now()
+	err := q.call(j)
+	jobsDuration.Observe(time.Since(start).Seconds(), j.Kind)
+	if err == nil {
+		jobsDone.Inc(j.Kind, "ok")
+		l.Debug("job done", "job_id", j.ID, "kind", j.Kind, "attempts", j.Attempts)
+		return
+	}
+	j.LastError = err.Error()
+	if j.Attempts >= q.opts.MaxAttempts || IsPermanent(err) {
+		jobsDone.Inc(j.Kind, "dead")
+		l.Error("job failed permanently", "job_id", j.ID, "kind", j.Kind, "attempts", j.Attempts, "err", err)
+		q.bury(j)
+		return
+	}
+	jobsDone.Inc(j.Kind, "retry")
+	delay := q.backoff(j.Attempts)
+	l.Warn("job failed, retrying", "job_id", j.ID, "kind", j.Kind, "attempts", j.Attempts, "in", delay.String(), "err", err)
+	q.schedule(j, delay)
+}
+
+// call 은 처리기를 실행한다. 패닉은 에러로 바꾼다.
+func (q *Queue) call(j *Job) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic: %v", v)
+		}
+	}()
+	return q.handlers[j.Kind](q.runCtx, j.Payload)
+}
+
+// backoff 는 attempts 번 실패한 뒤의 대기 시간이다. [d/2, d) 범위의 지터를 쓴다.
+func (q *Queue) backoff(attempts int) time.Duration {
+	d := q.opts.BaseDelay << (attempts - 1)
+	if d <= 0 || d > q.opts.MaxDelay {
+		d = q.opts.MaxDelay
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// schedule 은 delay 뒤에 작업을 다시 넣는다. 종료 중이면 바로 SpoolFile 로 보낸다.
+func (q *Queue) schedule(j *Job, delay time.Duration) {
+	j.RunAt = time.Now().Add(delay)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		q.leftover = append(q.leftover, j)
+		return
+	}
+	jobsWaiting.Add(1)
+	q.delayed[j.ID] = &delayedJob{job: j, timer: time.AfterFunc(delay, func() { q.wake(j.ID) })}
+}
+
+// wake 는 재시도 시각이 된 작업을 ready 로 옮긴다. 큐가 차 있으면 잠시 뒤에 다시 시도한다.
+func (q *Queue) wake(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	d, ok := q.delayed[id]
+	if !ok || q.closed {
+		return
+	}
+	select {
+	case q.ready <- d.job:
+		delete(q.delayed, id)
+	default:
+		d.timer.Reset(time.Second)
+	}
+}
+
+// keep 은 종료 기한이 지나 처리하지 못한 작업을 SpoolFile 로 보낼 목록에 넣는다.
+func (q *Queue) keep(j *Job) {
+	q.mu.Lock()
+	q.leftover = append(q.leftover, j)
+	q.mu.Unlock()
+}
+
+// bury 는 작업을 실패 목록에 넣는다.
+func (q *Queue) bury(j *Job) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dead = append(q.dead, *j)
+	if n := len(q.dead) - q.opts.DeadLetters; n > 0 {
+		q.dead = append(q.dead[:0:0], q.dead[n:]...)
+	}
+}
+
+// DeadLetters 는 실패 목록이다. 오래된 것이 앞에 있다.
+func (q *Queue) DeadLetters() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Job{}, q.dead...)
+}
+
+// Retry 는 실패 목록의 작업을 시도 횟수를 초기화해 다시 넣는다. 없으면 false 다.
+func (q *Queue) Retry(id string) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false, ErrClosed
+	}
+	for i, j := range q.dead {
+		if j.ID != id {
+			continue
+		}
+		j.Attempts, j.RunAt = 0, time.Time{}
+		select {
+		case q.ready <- &j:
+		default:
+			return false, ErrFull
+		}
+		q.dead = append(q.dead[:i], q.dead[i+1:]...)
+		jobsWaiting.Add(1)
+		return true, nil
+	}
+	return false, nil
+}
+
+// Discard 는 실패 목록에서 작업을 지운다. 없으면 false 다.
+func (q *Queue) Discard(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, j := range q.dead {
+		if j.ID == id {
+			q.dead = append(q.dead[:i], q.dead[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Stats 는 큐 상태 요약이다.
+type Stats struct {
+	Ready   int `json:"ready"`
+	Delayed int `json:"delayed"`
+	Dead    int `json:"dead"`
+	Workers int `json:"workers"`
+}
+
+// Stats 는 현재 대기, 재시도 대기, 실패 작업 수다.
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return Stats{Ready: len(q.ready), Delayed: len(q.delayed), Dead: len(q.dead), Workers: q.opts.Concurrency}
+}
+
+// Shutdown 은 새 작업을 막고 대기 중인 작업을 모두 처리할 때까지 기다린다. server.Hook 으로 쓸 수 있다.
+// 재시도를 기다리던 작업과 ctx 기한 안에 끝내지 못한 작업은 SpoolFile 에 저장한다.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	// 타이머가 이미 울렸더라도 wake 는 closed 를 보고 그냥 돌아가므로 남은 항목은 모두 저장한다.
+	for id, d := range q.delayed {
+		d.timer.Stop()
+		q.leftover = append(q.leftover, d.job)
+		jobsWaiting.Add(-1)
+		delete(q.delayed, id)
+	}
+	close(q.ready)
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		// 진행 중인 처리기를 취소하고, 남은 작업은 워커가 leftover 로 옮긴다.
+		q.cancelRun()
+		<-done
+		err = fmt.Errorf("jobs: drain: %w", ctx.Err())
+	}
+	q.cancelRun()
+	return errors.Join(err, q.saveSpool())
+}
+
+// saveSpool 은 남은 작업을 SpoolFile 에 JSON 줄로 쓴다.
+func (q *Queue) saveSpool() error {
+	q.mu.Lock()
+	left := q.leftover
+	q.leftover = nil
+	q.mu.Unlock()
+	if len(left) == 0 {
+		return nil
+	}
+	if q.opts.SpoolFile == "" {
+		logging.Default().Error("jobs: unfinished jobs dropped (no spool file)", "count", len(left))
+		return nil
+	}
+	f, err := os.OpenFile(q.opts.SpoolFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("jobs: spool: %w", err)
+	}
+	enc := json.NewEncoder(f)
+	for _, j := range left {
+		if err := enc.Encode(j); err != nil {
+			f.Close()
+			return fmt.Errorf("jobs: spool: %w", err)
+		}
+	}
+	logging.Default().Warn("jobs: unfinished jobs saved for the next start", "count", len(left), "file", q.opts.SpoolFile)
+	return f.Close()
+}
+
+// loadSpool 은 SpoolFile 의 작업을 다시 넣고 파일을 지운다.
+func (q *Queue) loadSpool() error {
+	if q.opts.SpoolFile == "" {
+		return nil
+	}
+	f, err := os.Open(q.opts.SpoolFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("jobs: spool: %w", err)
+	}
+	defer f.Close()
+	n := 0
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 16<<20)
+	for sc.Scan() {
+		var j Job
+		if err := json.Unmarshal(sc.Bytes(), &j); err != nil {
+			return fmt.Errorf("jobs: spool: %w", err)
+		}
+		if _, ok := q.handlers[j.Kind]; !ok {
+			logging.Default().Warn("jobs: spooled job has no handler, dropping", "job_id", j.ID, "kind", j.Kind)
+			continue
+		}
+		select {
+		case q.ready <- &j:
+			jobsWaiting.Add(1)
+			n++
+		default:
+			return fmt.Errorf("jobs: spool: %w", ErrFull)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("jobs: spool: %w", err)
+	}
+	if n > 0 {
+		logging.Default().Info("jobs: restored spooled jobs", "count", n)
+	}
+	return os.Remove(q.opts.SpoolFile)
+}
+
+func newID() string {
+	return fmt.Sprintf("%016x", rand.Uint64())
+}
@@ -0,0 +1,241 @@
+// Package lifecycle 은 서브시스템(DB, 작업 큐, WebSocket 허브 등)이 스스로 등록한 시작·준비·종료 훅을
+// 순서대로 실행한다.
+//
+// 시작 훅은 등록 순서로, 요청을 받기 전에 실행된다. 하나라도 실패하면 그때까지 시작한 서브시스템의
+// 종료 훅을 실행하고 멈춘다. 준비 훅은 리스너가 요청을 받기 시작한 뒤에 실행된다. 드레인 훅은 요청
+// 드레인을 시작할 때 함께 동시에 실행되어 오래 열려 있는 연결(WebSocket, SSE)에 종료를 알린다.
+// 종료 훅은 요청 드레인이 끝난 뒤 등록의 역순으로 실행되므로 먼저 등록한 서브시스템(DB 등)이 가장 늦게 닫힌다.
+// 훅은 각자의 타임아웃 안에서 실행된다.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/logging"
+)
+
+// DefaultTimeout 은 훅 하나의 기본 실행 시간 제한이다.
+const DefaultTimeout = 10 * time.Second
+
+// Func 는 훅 함수다. ctx 는 훅의 타임아웃이 지나면 취소된다.
+type Func func(ctx context.Context) error
+
+// Phase 는 Manager 의 현재 단계다.
+type Phase int
+
+const (
+	Idle Phase = iota
+	Starting
+	Started
+	Ready
+	Draining
+	Stopping
+	Stopped
+)
+
+var phaseNames = [...]string{"idle", "starting", "started", "ready", "draining", "stopping", "stopped"}
+
+func (p Phase) String() string { return phaseNames[p] }
+
+// kind 는 훅의 종류다.
+type kind int
+
+const (
+	onStart kind = iota
+	onReady
+	onDrain
+	onShutdown
+)
+
+var kindNames = [...]string{"start", "ready", "drain", "shutdown"}
+
+type hook struct {
+	kind    kind
+	name    string
+	timeout time.Duration
+	fn      Func
+}
+
+// Manager 는 등록된 훅을 실행한다. 훅 등록은 Start 전에 끝내야 한다.
+type Manager struct {
+	// Timeout 은 타임아웃을 정하지 않은 훅의 실행 시간 제한이다. 0 이면 DefaultTimeout.
+	Timeout time.Duration
+	// Logger 가 nil 이면 logging.Default 다.
+	Logger logging.Logger
+
+	mu    sync.Mutex
+	hooks []hook
+	phase Phase
+}
+
+// New 는 빈 Manager 를 만든다.
+func New() *Manager { return &Manager{} }
+
+// OnStart 는 요청을 받기 전에 실행할 훅을 등록한다. timeout 이 0 이면 Manager.Timeout 이다.
+func (m *Manager) OnStart(name string, timeout time.Duration, fn Func) {
+	m.add(hook{onStart, name, timeout, fn})
+}
+
+// OnReady 는 리스너가 요청을 받기 시작한 뒤 실행할 훅을 등록한다. 실패해도 서버는 멈추지 않는다.
+func (m *Manager) OnReady(name string, timeout time.Duration, fn Func) {
+	m.add(hook{onReady, name, timeout, fn})
+}
+
+// OnDrain 은 요청 드레인을 시작할 때 실행할 훅을 등록한다. 새 연결을 거부하고 열린 연결을 닫은 뒤
+// 연결이 다 끊길 때까지 기다리는 용도다. 드레인 훅끼리는 동시에 실행된다.
+func (m *Manager) OnDrain(name string, timeout time.Duration, fn Func) {
+	m.add(hook{onDrain, name, timeout, fn})
+}
+
+// OnShutdown 은 요청 드레인이 끝난 뒤 실행할 훅을 등록한다. 종료 훅은 등록의 역순으로 실행된다.
+func (m *Manager) OnShutdown(name string, timeout time.Duration, fn Func) {
+	m.add(hook{onShutdown, name, timeout, fn})
+}
+
+func (m *Manager) add(h hook) {
+	m.mu.Lock()
+	m.hooks = append(m.hooks, h)
+	m.mu.Unlock()
+}
+
+// Phase 는 현재 단계다.
+func (m *Manager) Phase() Phase {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.phase
+}
+
+// Start 는 시작 훅을 등록 순서로 실행한다. 훅이 실패하면 앞서 등록된 종료 훅을 역순으로 실행하고
+// 그 에러를 돌려준다.
+func (m *Manager) Start(ctx context.Context) error {
+	hooks := m.enter(Starting)
+	for i, h := range hooks {
+		if h.kind != onStart {
+			continue
+		}
+		if err := m.run(ctx, h); err != nil {
+			// 실패한 훅 뒤에 등록된 서브시스템은 시작하지 않았으므로 닫지 않는다.
+			if serr := m.shutdown(context.WithoutCancel(ctx), hooks[:i]); serr != nil {
+				err = errors.Join(err, serr)
+			}
+			return err
+		}
+	}
+	m.enter(Started)
+	return nil
+}
+
+// Ready 는 준비 훅을 등록 순서로 실행한다. 실패한 훅은 기록하고 나머지를 계속 실행한다.
+func (m *Manager) Ready(ctx context.Context) error {
+	var errs []error
+	for _, h := range m.enter(Ready) {
+		if h.kind != onReady {
+			continue
+		}
+		if err := m.run(ctx, h); err != nil {
+			m.logger().Error("lifecycle: ready hook failed", "hook", h.name, "err", err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Drain 은 드레인 훅을 한 번만, 모두 동시에 실행하고 끝날 때까지 기다린다. 실패한 훅의 에러를 모아 돌려준다.
+// ctx 가 끝나면 훅은 남은 연결을 강제로 닫아야 한다.
+func (m *Manager) Drain(ctx context.Context) error {
+	m.mu.Lock()
+	if m.phase >= Draining {
+		m.mu.Unlock()
+		return nil
+	}
+	m.phase = Draining
+	hooks := append([]hook(nil), m.hooks...)
+	m.mu.Unlock()
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, h := range hooks {
+		if h.kind != onDrain {
+			continue
+		}
+		wg.Go(func() {
+			if err := m.run(ctx, h); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Shutdown 은 종료 훅을 등록의 역순으로 한 번만 실행한다. 실패해도 나머지 훅을 계속 실행한다.
+// ctx 가 끝나면 아직 실행하지 않은 훅도 취소된 ctx 로 불린다. (자원을 그냥 닫는 훅을 위해)
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	hooks := append([]hook(nil), m.hooks...)
+	m.mu.Unlock()
+	return m.shutdown(ctx, hooks)
+}
+
+func (m *Manager) shutdown(ctx context.Context, hooks []hook) error {
+	m.mu.Lock()
+	if m.phase >= Stopping {
+		m.mu.Unlock()
+		return nil
+	}
+	m.phase = Stopping
+	m.mu.Unlock()
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if h := hooks[i]; h.kind == onShutdown {
+			if err := m.run(ctx, h); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	m.enter(Stopped)
+	return errors.Join(errs...)
+}
+
+// enter 는 단계를 바꾸고 그 시점의 훅 목록을 돌려준다.
+func (m *Manager) enter(p Phase) []hook {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.phase = p
+	return append([]hook(nil), m.hooks...)
+}
+
+// run 은 훅 하나를 타임아웃 안에서 실행한다. 이름이 있는 훅의 에러는 이름을 붙여 돌려준다.
+func (m *Manager) run(ctx context.Context, h hook) error {
+	timeout := h.timeout
+	if timeout <= 0 {
+		timeout = m.Timeout
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	err := h.fn(ctx)
+	m.logger().Debug("lifecycle: hook done", "phase", kindNames[h.kind], "hook", h.name, "took", time.Since(start).Round(time.Microsecond).String(), "err", err)
+	if err != nil && h.name != "" {
+		err = fmt.Errorf("%s %s: %w", h.name, kindNames[h.kind], err)
+	}
+	return err
+}
+
+func (m *Manager) logger() logging.Logger {
+	if m.Logger != nil {
+		return m.Logger
+	}
+	return logging.Default()
+}
@@ -0,0 +1,47 @@
+package listquery
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TotalHeader 는 필터에 맞는 전체 항목 수를 알려 주는 헤더다.
+const TotalHeader = "X-Total-Count"
+
+// SetHeaders 는 Link(first, prev, next)와 X-Total-Count 헤더를 붙인다.
+// next 는 다음 커서(커서 페이지일 때), hasMore 는 다음 페이지가 있는지, total 이 음수면 개수를 모른다.
+func (q *Query) SetHeaders(w http.ResponseWriter, r *http.Request, hasMore bool, next string, total int) {
+	h := w.Header()
+	if total >= 0 {
+		h.Set(TotalHeader, strconv.Itoa(total))
+	}
+	var links []string
+	link := func(rel string, set map[string]string) {
+		v := r.URL.Query()
+		for k, val := range set {
+			if val == "" {
+				v.Del(k)
+			} else {
+				v.Set(k, val)
+			}
+		}
+		links = append(links, "<"+r.URL.Path+"?"+v.Encode()+`>; rel="`+rel+`"`)
+	}
+	limit := strconv.Itoa(q.Limit)
+	link("first", map[string]string{"limit": limit, "offset": "", "cursor": ""})
+	switch {
+	case q.After != nil:
+		if hasMore && next != "" {
+			link("next", map[string]string{"limit": limit, "cursor": next})
+		}
+	default:
+		if q.Offset > 0 {
+			link("prev", map[string]string{"limit": limit, "offset": strconv.Itoa(max(q.Offset-q.Limit, 0))})
+		}
+		if hasMore {
+			link("next", map[string]string{"limit": limit, "offset": strconv.Itoa(q.Offset + q.Limit)})
+		}
+	}
+	h.Set("Link", strings.Join(links, ", "))
+}
@@ -0,0 +1,333 @@
+// Package listquery 는 목록 API 의 쿼리 문자열 규칙(페이지, 필터, 정렬)을 구현한다.
+//
+//	?limit=20&offset=40             오프셋 페이지
+//	?limit=20&cursor=eyJ2Ijpb...    커서 페이지 (앞 응답의 next_cursor)
+//	?name=alice                     같음 필터
+//	?created_at[gte]=2024-01-01     연산자 필터 (eq, ne, lt, lte, gt, gte, like, in)
+//	?sort=-created_at,name          정렬 (- 는 내림차순)
+//
+// 필터와 정렬은 Spec 에 선언한 필드만 받는다. 필드 이름은 응답 JSON 의 이름을 쓰고, SQL 의 열 이름은
+// Spec 에서만 오므로 쿼리 문자열이 SQL 에 그대로 들어가지 않는다.
+package listquery
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/openapi"
+)
+
+// Type 은 필드 값의 종류다. 쿼리 문자열의 값을 이 종류로 바꿔 SQL 인자로 넘긴다.
+type Type int
+
+const (
+	String Type = iota
+	Int
+	Time // RFC 3339 또는 2006-01-02
+	Bool
+)
+
+// Field 는 목록에서 쓸 수 있는 필드 하나다.
+type Field struct {
+	// Column 은 SQL 열 이름이다. 비어 있으면 필드 이름과 같다.
+	Column string
+	Type   Type
+	// Filter 는 거를 수 있는 연산자다. 비어 있으면 거를 수 없다. (예: []string{"eq", "like"})
+	Filter []string
+	// Sort 이면 정렬할 수 있다.
+	Sort bool
+}
+
+// Spec 은 목록 엔드포인트 하나가 받는 필드와 기본값이다.
+type Spec struct {
+	Fields map[string]Field
+	// Key 는 Fields 중 값이 겹치지 않는 필드(보통 "id")다. 정렬이 같은 행의 순서를 정하고 커서에도 들어간다.
+	Key string
+	// Sort 는 sort 가 없을 때의 정렬이다. 비어 있으면 Key 오름차순.
+	Sort string
+	// DefaultLimit 과 MaxLimit 이 0 이면 20, 100.
+	DefaultLimit, MaxLimit int
+}
+
+// reserved 는 필터가 아닌 쿼리 이름이다.
+var reserved = []string{"limit", "offset", "cursor", "sort"}
+
+// Filter 는 조건 하나다. Values 는 in 이면 여러 개, 그 밖에는 하나다.
+type Filter struct {
+	Field  string
+	Op     string
+	Values []any
+}
+
+// Order 는 정렬 기준 하나다.
+type Order struct {
+	Field string
+	Desc  bool
+}
+
+// Query 는 읽어 들인 목록 요청이다.
+type Query struct {
+	Spec    *Spec
+	Limit   int
+	Offset  int
+	Filters []Filter
+	// Sort 의 마지막은 언제나 Key 다.
+	Sort []Order
+	// After 는 커서가 가리키는 마지막 행의 정렬 값이다. 커서가 없으면 nil 이다.
+	After []any
+}
+
+// Parse 는 r 의 쿼리 문자열을 읽는다. 잘못된 값은 400 api.Error 다.
+func (s *Spec) Parse(r *http.Request) (*Query, error) {
+	q := r.URL.Query()
+	def, max := s.limits()
+	lq := &Query{Spec: s, Limit: def}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > max {
+			return nil, api.BadRequest("limit must be between 1 and " + strconv.Itoa(max))
+		}
+		lq.Limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, api.BadRequest("offset must be a non-negative integer")
+		}
+		lq.Offset = n
+	}
+	sort := s.Sort
+	if v := q.Get("sort"); v != "" {
+		sort = v
+	}
+	if err := lq.parseSort(sort); err != nil {
+		return nil, err
+	}
+	if v := q.Get("cursor"); v != "" {
+		if q.Has("offset") {
+			return nil, api.BadRequest("cursor and offset cannot be used together")
+		}
+		if err := lq.decodeCursor(v); err != nil {
+			return nil, err
+		}
+	}
+	names := make([]string, 0, len(q))
+	for name := range q {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if slices.Contains(reserved, name) {
+			continue
+		}
+		f, err := s.parseFilter(name, q[name])
+		if err != nil {
+			return nil, err
+		}
+		lq.Filters = append(lq.Filters, f...)
+	}
+	return lq, nil
+}
+
+func (s *Spec) limits() (def, max int) {
+	def, max = s.DefaultLimit, s.MaxLimit
+	if max <= 0 {
+		max = 100
+	}
+	if def <= 0 {
+		def = min(20, max)
+	}
+	return def, max
+}
+
+// parseSort 는 "-created_at,name" 을 읽고 끝에 Key 를 붙인다.
+func (q *Query) parseSort(sort string) error {
+	s := q.Spec
+	for _, part := range strings.Split(sort, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		o := Order{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if f, ok := s.Fields[o.Field]; !ok || (!f.Sort && o.Field != s.Key) {
+			return api.BadRequest(fmt.Sprintf("cannot sort by %q; sortable fields: %s", o.Field, strings.Join(s.sortable(), ", ")))
+		}
+		if slices.ContainsFunc(q.Sort, func(x Order) bool { return x.Field == o.Field }) {
+			return api.BadRequest(fmt.Sprintf("sort field %q is repeated", o.Field))
+		}
+		q.Sort = append(q.Sort, o)
+	}
+	if !slices.ContainsFunc(q.Sort, func(x Order) bool { return x.Field == s.Key }) {
+		q.Sort = append(q.Sort, Order{Field: s.Key})
+	}
+	return nil
+}
+
+func (s *Spec) sortable() []string {
+	var names []string
+	for name, f := range s.Fields {
+		if f.Sort || name == s.Key {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// parseFilter 는 "name" 또는 "name[op]" 쿼리를 읽는다. 같은 이름이 여러 번 오면 모두 조건이 된다.
+func (s *Spec) parseFilter(name string, values []string) ([]Filter, error) {
+	field, op := name, "eq"
+	if i := strings.IndexByte(name, '['); i > 0 && strings.HasSuffix(name, "]") {
+		field, op = name[:i], name[i+1:len(name)-1]
+	}
+	f, ok := s.Fields[field]
+	if !ok || len(f.Filter) == 0 {
+		return nil, api.BadRequest(fmt.Sprintf("unknown query parameter %q", name))
+	}
+	if !slices.Contains(f.Filter, op) {
+		return nil, api.BadRequest(fmt.Sprintf("%s cannot be filtered with %q; allowed: %s", field, op, strings.Join(f.Filter, ", ")))
+	}
+	var filters []Filter
+	for _, raw := range values {
+		parts := []string{raw}
+		if op == "in" {
+			parts = strings.Split(raw, ",")
+		}
+		fl := Filter{Field: field, Op: op}
+		for _, p := range parts {
+			v, err := f.Type.parse(p)
+			if op == "like" {
+				v, err = p, nil
+			}
+			if err != nil {
+				return nil, api.BadRequest(fmt.Sprintf("%s: %v", name, err))
+			}
+			fl.Values = append(fl.Values, v)
+		}
+		filters = append(filters, fl)
+	}
+	return filters, nil
+}
+
+func (t Type) parse(s string) (any, error) {
+	switch t {
+	case Int:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", s)
+		}
+		return n, nil
+	case Time:
+		if d, err := time.Parse(time.DateOnly, s); err == nil {
+			return d, nil
+		}
+		ts, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a date (2006-01-02) or RFC 3339 time", s)
+		}
+		return ts.UTC(), nil
+	case Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", s)
+		}
+		return b, nil
+	}
+	return s, nil
+}
+
+// cursor 는 커서의 내용이다. 정렬이 바뀐 커서는 쓸 수 없다.
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+func (q *Query) sortString() string {
+	parts := make([]string, len(q.Sort))
+	for i, o := range q.Sort {
+		parts[i] = o.Field
+		if o.Desc {
+			parts[i] = "-" + o.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (q *Query) decodeCursor(s string) error {
+	bad := api.BadRequest("cursor is invalid")
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return bad
+	}
+	var c cursor
+	if json.Unmarshal(b, &c) != nil {
+		return bad
+	}
+	if c.Sort != q.sortString() {
+		return api.BadRequest("cursor was issued for a different sort order")
+	}
+	if len(c.Values) != len(q.Sort) {
+		return bad
+	}
+	for i, o := range q.Sort {
+		v, err := q.Spec.Fields[o.Field].Type.parse(c.Values[i])
+		if err != nil {
+			return bad
+		}
+		q.After = append(q.After, v)
+	}
+	return nil
+}
+
+// Cursor 는 item 다음부터 읽는 커서다. item 은 목록의 마지막 항목이고, 정렬 필드는 item 의 JSON 이름으로 찾는다.
+func (q *Query) Cursor(item any) string {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return ""
+	}
+	var fields map[string]any
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	dec.UseNumber()
+	if dec.Decode(&fields) != nil {
+		return ""
+	}
+	c := cursor{Sort: q.sortString()}
+	for _, o := range q.Sort {
+		v, ok := fields[o.Field]
+		if !ok {
+			return ""
+		}
+		c.Values = append(c.Values, fmt.Sprint(v))
+	}
+	b, _ = json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Params 는 OpenAPI 문서에 넣을 cursor, sort 와 필터 쿼리 매개변수다.
+func (s *Spec) Params() []openapi.Param {
+	params := []openapi.Param{
+		{Name: "cursor", Description: "next_cursor of the previous page; cannot be combined with offset"},
+		{Name: "sort", Description: "comma-separated fields, - for descending: " + strings.Join(s.sortable(), ", ")},
+	}
+	names := make([]string, 0, len(s.Fields))
+	for name := range s.Fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		f := s.Fields[name]
+		if len(f.Filter) == 0 {
+			continue
+		}
+		typ := map[Type]string{Int: "integer", Bool: "boolean"}[f.Type]
+		params = append(params, openapi.Param{Name: name, Type: typ, Description: "filter; also " + name + "[op] with op in " + strings.Join(f.Filter, ", ")})
+	}
+	return params
+}
@@ -0,0 +1,85 @@
+package listquery
+
+import (
+	"strings"
+)
+
+// Where 는 필터와 커서 조건의 WHERE 절(" WHERE ..." 또는 빈 문자열)과 인자다. 자리 표시자는 "?" 이므로
+// store.DB.Rebind 로 바꿔서 쓴다.
+func (q *Query) Where() (string, []any) {
+	return q.where(true)
+}
+
+// CountWhere 는 커서 조건을 뺀 Where 다. 전체 개수를 셀 때 쓴다.
+func (q *Query) CountWhere() (string, []any) {
+	return q.where(false)
+}
+
+// OrderBy 는 " ORDER BY ..." 절이다.
+func (q *Query) OrderBy() string {
+	parts := make([]string, len(q.Sort))
+	for i, o := range q.Sort {
+		parts[i] = q.column(o.Field)
+		if o.Desc {
+			parts[i] += " DESC"
+		}
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+func (q *Query) where(withCursor bool) (string, []any) {
+	var conds []string
+	var args []any
+	for _, f := range q.Filters {
+		col := q.column(f.Field)
+		switch f.Op {
+		case "in":
+			conds = append(conds, col+" IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(f.Values)), ", ")+")")
+			args = append(args, f.Values...)
+		case "like":
+			conds = append(conds, col+` LIKE ? ESCAPE '\'`)
+			args = append(args, "%"+likeEscape(f.Values[0].(string))+"%")
+		default:
+			conds = append(conds, col+" "+sqlOps[f.Op]+" ?")
+			args = append(args, f.Values[0])
+		}
+	}
+	if withCursor && q.After != nil {
+		// (a, b, id) 순서의 다음 행: a > ? OR (a = ? AND b > ?) OR (a = ? AND b = ? AND id > ?)
+		// 내림차순 필드는 < 로 비교한다.
+		var ors []string
+		for i, o := range q.Sort {
+			var ands []string
+			for j := range i {
+				ands = append(ands, q.column(q.Sort[j].Field)+" = ?")
+				args = append(args, q.After[j])
+			}
+			op := " > ?"
+			if o.Desc {
+				op = " < ?"
+			}
+			ands = append(ands, q.column(o.Field)+op)
+			args = append(args, q.After[i])
+			ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+		}
+		conds = append(conds, "("+strings.Join(ors, " OR ")+")")
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+var sqlOps = map[string]string{"eq": "=", "ne": "<>", "lt": "<", "lte": "<=", "gt": ">", "gte": ">="}
+
+func (q *Query) column(field string) string {
+	if c := q.Spec.Fields[field].Column; c != "" {
+		return c
+	}
+	return field
+}
+
+// likeEscape 는 LIKE 패턴의 특수 문자를 이스케이프한다.
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,442 @@
+// Package localauth 는 이메일과 비밀번호로 가입하고 로그인하는 HTML 폼 흐름이다.
+//
+//	GET/POST /auth/signup       가입. 확인 메일을 보낸다.
+//	GET      /auth/verify       메일의 링크로 이메일 확인
+//	GET/POST /auth/signin       비밀번호 로그인. 연속으로 틀리면 계정을 잠시 잠근다.
+//	GET/POST /auth/forgot       비밀번호 재설정 메일 요청
+//	GET/POST /auth/reset        메일의 링크로 새 비밀번호 설정
+//	GET/POST /auth/2fa          2단계 인증을 켠 계정의 로그인 두 번째 단계 (TOTP 또는 백업 코드)
+//	GET/POST /auth/2fa/setup    로그인한 사용자의 2단계 인증 등록
+//	POST     /auth/2fa/codes    백업 코드 다시 만들기
+//	POST     /auth/2fa/disable  2단계 인증 끄기
+//
+// 로그인에 성공하면 oauth.SignIn 으로 세션에 사용자를 저장하므로 oauth.User 와 /auth/logout 을 그대로 쓴다.
+// 확인과 재설정 토큰은 메일로만 보내고 DB 에는 SHA-256 해시만 저장하며, 한 번 쓰면 지운다.
+package localauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hgsong234/_stack/Golang/audit"
+	"github.com/hgsong234/_stack/Golang/csrf"
+	"github.com/hgsong234/_stack/Golang/i18n"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/mailer"
+	"github.com/hgsong234/_stack/Golang/metrics"
+	"github.com/hgsong234/_stack/Golang/oauth"
+	"github.com/hgsong234/_stack/Golang/render"
+	"github.com/hgsong234/_stack/Golang/router"
+	"github.com/hgsong234/_stack/Golang/session"
+	"github.com/hgsong234/_stack/Golang/store"
+	"github.com/hgsong234/_stack/Golang/timing"
+)
+
+var logins = metrics.NewCounterVec("localauth_logins_total", "Password sign-in attempts by result (ok, invalid, locked, unverified, invalid_code).", "result")
+
+// maxPassword 는 비밀번호 최대 길이(바이트)다. bcrypt 는 72 바이트 뒤를 무시하므로 bcrypt 에서는 72 다.
+const maxPassword = 256
+
+// Handler 는 가입과 로그인 핸들러 묶음이다. 세션 미들웨어가 필요하다.
+type Handler struct {
+	Accounts *store.Accounts
+	Mail     *mailer.Mailer
+	// Hash 는 새 비밀번호에 쓰는 해시 방식이다. "argon2id"(기본) 또는 "bcrypt".
+	// 다른 방식이나 예전 인자로 저장된 해시는 다음 로그인 때 바꿔 저장한다.
+	Hash        string
+	MinPassword int
+	// RequireVerified 이면 이메일을 확인해야 로그인할 수 있다.
+	RequireVerified bool
+	// LockAfter 번 연속으로 틀리면 LockFor 동안 로그인을 막는다. LockAfter 가 0 이면 잠그지 않는다.
+	LockAfter int
+	LockFor   time.Duration
+	VerifyTTL time.Duration
+	ResetTTL  time.Duration
+	// BaseURL 은 메일 링크의 앞부분이다. (예: https://example.com) 요청의 Host 는 클라이언트가 정하므로
+	// 링크를 만들 때 쓰지 않는다. (재설정 링크가 공격자의 호스트를 가리키게 된다)
+	BaseURL string
+	// Issuer 는 인증 앱에 표시되는 서비스 이름이다.
+	Issuer string
+}
+
+// Mount 는 가입, 확인, 로그인, 비밀번호 재설정 경로를 등록한다.
+func (h *Handler) Mount(r *router.Router) {
+	r.GET("/auth/signup", h.Signup)
+	r.POST("/auth/signup", h.Signup)
+	r.GET("/auth/verify", h.Verify)
+	r.GET("/auth/signin", h.Signin)
+	r.POST("/auth/signin", h.Signin)
+	r.GET("/auth/forgot", h.Forgot)
+	r.POST("/auth/forgot", h.Forgot)
+	r.GET("/auth/reset", h.Reset)
+	r.POST("/auth/reset", h.Reset)
+	r.GET("/auth/2fa", h.SecondFactor)
+	r.POST("/auth/2fa", h.SecondFactor)
+	r.GET("/auth/2fa/setup", h.Setup)
+	r.POST("/auth/2fa/setup", h.Setup)
+	r.POST("/auth/2fa/codes", h.RegenerateCodes)
+	r.POST("/auth/2fa/disable", h.Disable)
+}
+
+// view 는 auth_*.html 템플릿 데이터다.
+type view struct {
+	Lang   string
+	CSRF   string
+	Error  string
+	Notice string
+	Name   string
+	Email  string
+	Token  string
+	Return string
+	// 2단계 인증 등록 화면
+	TwoFactor bool
+	Secret    string
+	URI       string
+	Codes     []string
+	CodesLeft int
+}
+
+func newView(r *http.Request) view {
+	return view{Lang: i18n.Lang(r.Context()), CSRF: csrf.Token(r), Return: oauth.SafeReturn(r.FormValue("return"))}
+}
+
+func (h *Handler) render(w http.ResponseWriter, r *http.Request, status int, page string, v view) {
+	defer timing.Start(r.Context(), "render")()
+	if err := render.Default().RenderStatus(w, status, page, v); err != nil {
+		logging.From(r.Context()).Error("localauth: render", "page", page, "err", err)
+	}
+}
+
+// notice 는 안내 문구만 있는 페이지를 보여준다.
+func (h *Handler) notice(w http.ResponseWriter, r *http.Request, status int, key string) {
+	v := newView(r)
+	v.Notice = i18n.T(r.Context(), key)
+	h.render(w, r, status, "auth_notice.html", v)
+}
+
+// Signup 은 가입 폼을 보여주고, 제출되면 계정을 만들고 확인 메일을 보낸다.
+func (h *Handler) Signup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	v := newView(r)
+	if r.Method != http.MethodPost {
+		h.render(w, r, http.StatusOK, "auth_signup.html", v)
+		return
+	}
+	v.Name = strings.TrimSpace(r.PostFormValue("name"))
+	v.Email = strings.TrimSpace(r.PostFormValue("email"))
+	password := r.PostFormValue("password")
+	if key := h.checkSignup(v.Name, v.Email, password); key != "" {
+		v.Error = h.errorText(r, key)
+		h.render(w, r, http.StatusUnprocessableEntity, "auth_signup.html", v)
+		return
+	}
+	hash, err := HashPassword(password, h.Hash)
+	if err != nil {
+		logging.From(ctx).Error("localauth: hash password", "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	acc := &store.Account{User: store.User{Name: v.Name, Email: v.Email}, PasswordHash: hash}
+	switch err := h.Accounts.Create(ctx, acc); {
+	case errors.Is(err, store.ErrConflict):
+		// 가입된 이메일인지 알려주지 않도록 새 가입과 같은 안내를 보여준다.
+		logging.From(ctx).Info("localauth: signup with existing email")
+		h.record(r, "auth.signup", audit.Failure, 0, v.Email, map[string]any{"reason": "existing_email"})
+		h.notice(w, r, http.StatusOK, "auth.signup.sent")
+		return
+	case err != nil:
+		logging.From(ctx).Error("localauth: create account", "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	h.record(r, "auth.signup", audit.Success, acc.ID, acc.Email, nil)
+	h.sendVerify(r, acc)
+	if !h.RequireVerified {
+		h.signIn(w, r, acc, v.Return, "password")
+		return
+	}
+	h.notice(w, r, http.StatusOK, "auth.signup.sent")
+}
+
+// checkSignup 은 가입 입력값을 확인하고 잘못되었으면 오류 문구 키를 돌려준다.
+func (h *Handler) checkSignup(name, email, password string) string {
+	if name == "" || utf8.RuneCountInString(name) > 64 {
+		return "auth.error.name"
+	}
+	if a, err := mail.ParseAddress(email); err != nil || a.Address != email {
+		return "auth.error.email"
+	}
+	return h.checkPassword(password)
+}
+
+// errorText 는 checkSignup, checkPassword 의 오류 문구 키를 번역한다.
+func (h *Handler) errorText(r *http.Request, key string) string {
+	if key == "auth.error.password" {
+		return i18n.T(r.Context(), key, h.MinPassword)
+	}
+	return i18n.T(r.Context(), key)
+}
+
+func (h *Handler) checkPassword(password string) string {
+	max := maxPassword
+	if h.Hash == "bcrypt" {
+		max = 72
+	}
+	if utf8.RuneCountInString(password) < h.MinPassword || len(password) > max {
+		return "auth.error.password"
+	}
+	return ""
+}
+
+// sendVerify 는 이메일 확인 링크를 보낸다. 실패해도 가입은 유지하며, 로그인할 때 다시 보낸다.
+func (h *Handler) sendVerify(r *http.Request, acc *store.Account) {
+	h.sendToken(r, acc, store.TokenVerify, h.VerifyTTL, "/auth/verify", "email_verify.html")
+}
+
+// sendToken 은 purpose 토큰을 만들어 path?token=... 링크를 page 메일로 보낸다.
+func (h *Handler) sendToken(r *http.Request, acc *store.Account, purpose string, ttl time.Duration, path, page string) {
+	ctx := r.Context()
+	token, hash := newToken()
+	if err := h.Accounts.CreateToken(ctx, hash, acc.ID, purpose, time.Now().Add(ttl)); err != nil {
+		logging.From(ctx).Error("localauth: create token", "purpose", purpose, "user_id", acc.ID, "err", err)
+		return
+	}
+	data := tokenMail{Name: acc.Name, Lang: i18n.Lang(ctx), Link: strings.TrimSuffix(h.BaseURL, "/") + path + "?token=" + token, Hours: int(ttl.Hours())}
+	if err := h.Mail.SendTemplate(ctx, page, data, acc.Email); err != nil {
+		logging.From(ctx).Error("localauth: mail not queued", "purpose", purpose, "user_id", acc.ID, "err", err)
+	}
+}
+
+// tokenMail 은 email_verify.html, email_reset.html 템플릿 데이터다.
+type tokenMail struct {
+	Name  string
+	Lang  string
+	Link  string
+	Hours int
+}
+
+// Verify 는 확인 링크의 토큰으로 이메일 확인을 마치고 로그인 페이지로 보낸다.
+func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := h.Accounts.ConsumeToken(ctx, hashToken(r.URL.Query().Get("token")), store.TokenVerify)
+	if errors.Is(err, store.ErrNotFound) {
+		h.notice(w, r, http.StatusBadRequest, "auth.error.token")
+		return
+	}
+	if err == nil {
+		err = h.Accounts.MarkVerified(ctx, id)
+	}
+	if err != nil {
+		logging.From(ctx).Error("localauth: verify", "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logging.From(ctx).Info("localauth: email verified", "user_id", id)
+	h.record(r, "auth.email_verified", audit.Success, id, "", nil)
+	http.Redirect(w, r, "/auth/signin?verified=1", http.StatusSeeOther)
+}
+
+// Signin 은 로그인 폼을 보여주고, 제출되면 비밀번호를 확인해 세션에 로그인시킨다.
+func (h *Handler) Signin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	v := newView(r)
+	if r.Method != http.MethodPost {
+		if r.URL.Query().Get("verified") != "" {
+			v.Notice = i18n.T(ctx, "auth.verified")
+		}
+		h.render(w, r, http.StatusOK, "auth_signin.html", v)
+		return
+	}
+	v.Email = strings.TrimSpace(r.PostFormValue("email"))
+	password := r.PostFormValue("password")
+	// acc 는 없는 계정이면 nil 이다.
+	fail := func(status int, result, key string, acc *store.Account) {
+		logins.Inc(result)
+		outcome, id := audit.Denied, int64(0)
+		if status == http.StatusUnauthorized {
+			outcome = audit.Failure
+		}
+		if acc != nil {
+			id = acc.ID
+		}
+		h.record(r, "auth.login", outcome, id, v.Email, map[string]any{"reason": result})
+		v.Error = i18n.T(ctx, key)
+		h.render(w, r, status, "auth_signin.html", v)
+	}
+	acc, err := h.Accounts.ByEmail(ctx, v.Email)
+	if errors.Is(err, store.ErrNotFound) {
+		// 없는 계정도 같은 시간이 걸리도록 해시를 비교한다.
+		CheckPassword(dummyHash, password, h.Hash)
+		fail(http.StatusUnauthorized, "invalid", "auth.error.credentials", nil)
+		return
+	}
+	if err != nil {
+		logging.From(ctx).Error("localauth: find account", "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	if acc.Locked(now) {
+		fail(http.StatusTooManyRequests, "locked", "auth.error.locked", acc)
+		return
+	}
+	ok, rehash := CheckPassword(acc.PasswordHash, password, h.Hash)
+	if !ok {
+		if h.failed(r, acc, now) {
+			fail(http.StatusTooManyRequests, "locked", "auth.error.locked", acc)
+			return
+		}
+		fail(http.StatusUnauthorized, "invalid", "auth.error.credentials", acc)
+		return
+	}
+	if h.RequireVerified && !acc.Verified() {
+		// 비밀번호가 맞을 때만 확인 메일을 다시 보낸다.
+		h.sendVerify(r, acc)
+		fail(http.StatusForbidden, "unverified", "auth.error.unverified", acc)
+		return
+	}
+	if rehash {
+		if hash, err := HashPassword(password, h.Hash); err == nil {
+			if err := h.Accounts.SetPassword(ctx, acc.ID, hash); err != nil {
+				logging.From(ctx).Warn("localauth: rehash password", "user_id", acc.ID, "err", err)
+			}
+		}
+	}
+	if acc.TwoFactor() {
+		// 실패 횟수는 두 번째 단계까지 통과해야 지운다.
+		h.challenge(w, r, acc, v.Return)
+		return
+	}
+	h.signIn(w, r, acc, v.Return, "password")
+}
+
+// failed 는 로그인 실패를 기록하고, 이번 실패로 계정을 잠갔으면 true 를 돌려준다.
+func (h *Handler) failed(r *http.Request, acc *store.Account, now time.Time) bool {
+	ctx := r.Context()
+	locked, err := h.Accounts.LoginFailed(ctx, acc.ID, h.LockAfter, now.Add(h.LockFor))
+	if err != nil {
+		logging.From(ctx).Error("localauth: record failure", "user_id", acc.ID, "err", err)
+	}
+	if locked {
+		logging.From(ctx).Warn("localauth: account locked", "user_id", acc.ID, "until", now.Add(h.LockFor))
+		h.record(r, "auth.lockout", audit.Success, acc.ID, acc.Email, map[string]any{"until": now.Add(h.LockFor).UTC()})
+	}
+	return locked
+}
+
+// signIn 은 실패 횟수를 지우고 acc 를 세션에 로그인시킨 뒤 ret(없으면 "/")로 보낸다.
+// method 는 감사 로그에 남기는 인증 방식이다. (예: "password", "password+totp")
+func (h *Handler) signIn(w http.ResponseWriter, r *http.Request, acc *store.Account, ret, method string) {
+	if err := h.Accounts.LoginSucceeded(r.Context(), acc.ID); err != nil {
+		logging.From(r.Context()).Error("localauth: reset failures", "user_id", acc.ID, "err", err)
+	}
+	logins.Inc("ok")
+	oauth.SignIn(r, oauth.Identity{Provider: "local", ID: strconv.FormatInt(acc.ID, 10), Name: acc.Name, Email: acc.Email})
+	logging.From(r.Context()).Info("localauth: signed in", "user_id", acc.ID)
+	h.record(r, "auth.login", audit.Success, acc.ID, acc.Email, map[string]any{"method": method})
+	if ret == "" {
+		ret = "/"
+	}
+	http.Redirect(w, r, ret, http.StatusSeeOther)
+}
+
+// Forgot 은 재설정 요청 폼을 보여주고, 제출되면 가입된 이메일일 때만 재설정 메일을 보낸다.
+// 가입 여부를 알려주지 않도록 응답은 항상 같다.
+func (h *Handler) Forgot(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodPost {
+		h.render(w, r, http.StatusOK, "auth_forgot.html", newView(r))
+		return
+	}
+	acc, err := h.Accounts.ByEmail(ctx, strings.TrimSpace(r.PostFormValue("email")))
+	switch {
+	case err == nil:
+		h.record(r, "auth.password_reset", audit.Success, acc.ID, acc.Email, map[string]any{"step": "requested"})
+		h.sendToken(r, acc, store.TokenReset, h.ResetTTL, "/auth/reset", "email_reset.html")
+	case !errors.Is(err, store.ErrNotFound):
+		logging.From(ctx).Error("localauth: find account", "err", err)
+	}
+	h.notice(w, r, http.StatusOK, "auth.forgot.sent")
+}
+
+// Reset 은 새 비밀번호 폼을 보여주고, 제출되면 토큰을 확인해 비밀번호를 바꾼다.
+// 메일을 받았다는 것은 이메일을 가진 것이므로 확인도 함께 마친다.
+func (h *Handler) Reset(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	v := newView(r)
+	v.Token = r.FormValue("token")
+	if r.Method != http.MethodPost {
+		h.render(w, r, http.StatusOK, "auth_reset.html", v)
+		return
+	}
+	password := r.PostFormValue("password")
+	if key := h.checkPassword(password); key != "" {
+		v.Error = h.errorText(r, key)
+		h.render(w, r, http.StatusUnprocessableEntity, "auth_reset.html", v)
+		return
+	}
+	id, err := h.Accounts.ConsumeToken(ctx, hashToken(v.Token), store.TokenReset)
+	if errors.Is(err, store.ErrNotFound) {
+		h.notice(w, r, http.StatusBadRequest, "auth.error.token")
+		return
+	}
+	var hash string
+	if err == nil {
+		hash, err = HashPassword(password, h.Hash)
+	}
+	if err == nil {
+		err = h.Accounts.SetPassword(ctx, id, hash)
+	}
+	if err == nil {
+		err = h.Accounts.MarkVerified(ctx, id)
+	}
+	if err != nil {
+		logging.From(ctx).Error("localauth: reset password", "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logging.From(ctx).Info("localauth: password reset", "user_id", id)
+	h.record(r, "auth.password_reset", audit.Success, id, "", map[string]any{"step": "completed"})
+	h.notice(w, r, http.StatusOK, "auth.reset.done")
+}
+
+// Logout 은 세션을 삭제하고 홈으로 리다이렉트한다. OAuth 로그인을 쓰지 않을 때 /auth/logout 에 등록한다.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	if oauth.User(r) != nil {
+		audit.RecordRequest(r, audit.Event{Type: "auth.logout"})
+	}
+	session.Destroy(r)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// record 는 계정 이벤트를 감사 로그에 남긴다. id 가 0 이면(없는 계정) 행위자는 anonymous 이고,
+// 로그인 세션과 같은 "local:<id>" 형식이다.
+func (h *Handler) record(r *http.Request, typ, outcome string, id int64, email string, fields map[string]any) {
+	actor := "anonymous"
+	if id != 0 {
+		actor = "local:" + strconv.FormatInt(id, 10)
+	}
+	audit.RecordRequest(r, audit.Event{Type: typ, Outcome: outcome, Actor: actor, Target: email, Fields: fields})
+}
+
+// newToken 은 메일로 보낼 토큰과 저장할 해시를 만든다.
+func newToken() (token, hash string) {
+	b := make([]byte, 32)
+	rand.Read(b)
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
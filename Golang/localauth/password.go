@@ -0,0 +1,87 @@
+package localauth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// argon2id 인자 (OWASP 권장 최소값: 19MiB, 2회, 병렬 1)
+const (
+	argonMemory  = 19 * 1024
+	argonTime    = 2
+	argonThreads = 1
+	argonKeyLen  = 32
+	bcryptCost   = 12
+)
+
+// HashPassword 는 algo("argon2id" 또는 "bcrypt")로 비밀번호 해시를 만든다.
+// argon2id 는 "$argon2id$v=19$m=...,t=...,p=...$salt$hash" (PHC 형식) 문자열이다.
+func HashPassword(password, algo string) (string, error) {
+	switch algo {
+	case "bcrypt":
+		h, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+		return string(h), err
+	case "argon2id", "":
+		salt := make([]byte, 16)
+		rand.Read(salt)
+		key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argonMemory, argonTime, argonThreads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+	return "", fmt.Errorf("localauth: unknown password hash %q", algo)
+}
+
+// CheckPassword 는 password 가 hash 와 맞는지 확인한다. 맞지만 hash 가 algo 의 현재 인자로 만든 것이 아니면
+// rehash 가 true 이므로 새 해시로 바꿔 저장한다.
+func CheckPassword(hash, password, algo string) (ok, rehash bool) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		m, t, p, salt, key, err := parseArgon2(hash)
+		if err != nil {
+			return false, false
+		}
+		got := argon2.IDKey([]byte(password), salt, t, m, p, uint32(len(key)))
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return false, false
+		}
+		return true, algo == "bcrypt" || m != argonMemory || t != argonTime || p != argonThreads
+	case strings.HasPrefix(hash, "$2"):
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+			return false, false
+		}
+		cost, _ := bcrypt.Cost([]byte(hash))
+		return true, algo != "bcrypt" || cost != bcryptCost
+	}
+	return false, false
+}
+
+func parseArgon2(hash string) (m, t uint32, p uint8, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return 0, 0, 0, nil, nil, errors.New("localauth: malformed argon2id hash")
+	}
+	var v int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &v); err != nil || v != argon2.Version {
+		return 0, 0, 0, nil, nil, errors.New("localauth: unsupported argon2 version")
+	}
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &m, &t, &p); err != nil {
+		return 0, 0, 0, nil, nil, err
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return 0, 0, 0, nil, nil, err
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return 0, 0, 0, nil, nil, err
+	}
+	return m, t, p, salt, key, nil
+}
+
+// dummyHash 는 없는 계정으로 로그인할 때도 같은 시간이 걸리도록 비교에 쓰는 해시다.
+var dummyHash, _ = HashPassword("not a real password", "argon2id")
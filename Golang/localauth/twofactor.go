@@ -0,0 +1,292 @@
+package localauth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/audit"
+	"github.com/hgsong234/_stack/Golang/i18n"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/oauth"
+	"github.com/hgsong234/_stack/Golang/session"
+	"github.com/hgsong234/_stack/Golang/store"
+	"github.com/hgsong234/_stack/Golang/totp"
+)
+
+// 세션 키. 비밀번호를 확인한 뒤 두 번째 단계를 기다리는 동안의 상태와 등록 중인 비밀 키다.
+const (
+	keyPending       = "localauth_pending"
+	keyPendingUntil  = "localauth_pending_until"
+	keyPendingReturn = "localauth_pending_return"
+	keySetupSecret   = "localauth_totp_setup"
+)
+
+// pendingTTL 은 비밀번호를 확인한 뒤 코드를 입력할 수 있는 시간이다.
+const pendingTTL = 5 * time.Minute
+
+// backupCodes 는 한 번에 만드는 백업 코드 수다.
+const backupCodes = 10
+
+// challenge 는 비밀번호를 확인한 acc 를 두 번째 단계 페이지로 보낸다.
+func (h *Handler) challenge(w http.ResponseWriter, r *http.Request, acc *store.Account, ret string) {
+	session.Set(r, keyPending, strconv.FormatInt(acc.ID, 10))
+	session.Set(r, keyPendingUntil, strconv.FormatInt(time.Now().Add(pendingTTL).Unix(), 10))
+	session.Set(r, keyPendingReturn, ret)
+	http.Redirect(w, r, "/auth/2fa", http.StatusSeeOther)
+}
+
+// pending 은 두 번째 단계를 기다리는 사용자 ID 다. 없거나 시간이 지났으면 0 이다.
+func pending(r *http.Request) int64 {
+	id, _ := strconv.ParseInt(session.GetString(r, keyPending), 10, 64)
+	until, _ := strconv.ParseInt(session.GetString(r, keyPendingUntil), 10, 64)
+	if id == 0 || time.Now().Unix() > until {
+		return 0
+	}
+	return id
+}
+
+func clearPending(r *http.Request) {
+	for _, k := range []string{keyPending, keyPendingUntil, keyPendingReturn} {
+		session.Delete(r, k)
+	}
+}
+
+// SecondFactor 는 인증 앱의 코드나 백업 코드를 받아 로그인을 마친다. 틀린 코드도 비밀번호처럼 잠금 횟수에 들어간다.
+func (h *Handler) SecondFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := pending(r)
+	if id == 0 {
+		clearPending(r)
+		http.Redirect(w, r, "/auth/signin", http.StatusSeeOther)
+		return
+	}
+	v := newView(r)
+	if r.Method != http.MethodPost {
+		h.render(w, r, http.StatusOK, "auth_2fa.html", v)
+		return
+	}
+	acc, err := h.Accounts.Get(ctx, id)
+	if err != nil {
+		logging.From(ctx).Error("localauth: find account", "user_id", id, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	if acc.Locked(now) {
+		clearPending(r)
+		logins.Inc("locked")
+		h.record(r, "auth.login", audit.Denied, acc.ID, acc.Email, map[string]any{"reason": "locked"})
+		v.Error = i18n.T(ctx, "auth.error.locked")
+		h.render(w, r, http.StatusTooManyRequests, "auth_signin.html", v)
+		return
+	}
+	method, err := h.checkCode(r, acc, r.PostFormValue("code"))
+	if err != nil {
+		logging.From(ctx).Error("localauth: check code", "user_id", id, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if method == "" {
+		h.record(r, "auth.login", audit.Failure, acc.ID, acc.Email, map[string]any{"reason": "invalid_code"})
+		if h.failed(r, acc, now) {
+			clearPending(r)
+			logins.Inc("locked")
+			v.Error = i18n.T(ctx, "auth.error.locked")
+			h.render(w, r, http.StatusTooManyRequests, "auth_signin.html", v)
+			return
+		}
+		logins.Inc("invalid_code")
+		v.Error = i18n.T(ctx, "auth.2fa.error.code")
+		h.render(w, r, http.StatusUnauthorized, "auth_2fa.html", v)
+		return
+	}
+	ret := oauth.SafeReturn(session.GetString(r, keyPendingReturn))
+	clearPending(r)
+	h.signIn(w, r, acc, ret, "password+"+method)
+}
+
+// checkCode 는 code 가 acc 의 현재 TOTP 코드이거나 남은 백업 코드인지 확인하고, 맞으면 "totp" 또는
+// "backup_code" 를, 아니면 "" 를 돌려준다. 같은 TOTP 코드와 이미 쓴 백업 코드는 다시 받지 않는다.
+func (h *Handler) checkCode(r *http.Request, acc *store.Account, code string) (string, error) {
+	ctx := r.Context()
+	if step, ok := totp.Validate(acc.TOTPSecret, code, time.Now(), 1); ok {
+		if ok, err := h.Accounts.UseTOTPStep(ctx, acc.ID, step); !ok || err != nil {
+			return "", err
+		}
+		return "totp", nil
+	}
+	code = normalizeBackupCode(code)
+	if code == "" {
+		return "", nil
+	}
+	if ok, err := h.Accounts.UseBackupCode(ctx, acc.ID, hashToken(code)); !ok || err != nil {
+		return "", err
+	}
+	logging.From(ctx).Info("localauth: backup code used", "user_id", acc.ID)
+	return "backup_code", nil
+}
+
+// account 는 로그인한 비밀번호 계정이다. 로그인하지 않았으면 로그인 페이지로 보내고 nil 을 돌려준다.
+func (h *Handler) account(w http.ResponseWriter, r *http.Request) *store.Account {
+	if u := oauth.User(r); u != nil && u.Provider == "local" {
+		id, _ := strconv.ParseInt(u.ID, 10, 64)
+		acc, err := h.Accounts.Get(r.Context(), id)
+		if err == nil {
+			return acc
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			logging.From(r.Context()).Error("localauth: find account", "user_id", id, "err", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return nil
+		}
+	}
+	http.Redirect(w, r, "/auth/signin?return="+url.QueryEscape("/auth/2fa/setup"), http.StatusSeeOther)
+	return nil
+}
+
+// Setup 은 2단계 인증 상태를 보여준다. 꺼져 있으면 새 비밀 키의 등록 주소를 보여주고,
+// 제출된 코드가 그 키와 맞으면 켜고 백업 코드를 한 번만 보여준다.
+func (h *Handler) Setup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	acc := h.account(w, r)
+	if acc == nil {
+		return
+	}
+	v := newView(r)
+	if acc.TwoFactor() {
+		if r.Method == http.MethodPost {
+			http.Redirect(w, r, "/auth/2fa/setup", http.StatusSeeOther)
+			return
+		}
+		h.renderEnabled(w, r, acc, v, http.StatusOK)
+		return
+	}
+	secret := session.GetString(r, keySetupSecret)
+	if secret == "" {
+		secret = totp.NewSecret()
+		session.Set(r, keySetupSecret, secret)
+	}
+	v.Secret = secret
+	v.URI = totp.URI(h.Issuer, acc.Email, secret)
+	if r.Method != http.MethodPost {
+		h.render(w, r, http.StatusOK, "auth_2fa_setup.html", v)
+		return
+	}
+	step, ok := totp.Validate(secret, r.PostFormValue("code"), time.Now(), 1)
+	if !ok {
+		v.Error = i18n.T(ctx, "auth.2fa.error.code")
+		h.render(w, r, http.StatusUnprocessableEntity, "auth_2fa_setup.html", v)
+		return
+	}
+	codes, hashes := newBackupCodes()
+	if err := h.Accounts.EnableTOTP(ctx, acc.ID, secret, step, hashes); err != nil {
+		logging.From(ctx).Error("localauth: enable totp", "user_id", acc.ID, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	session.Delete(r, keySetupSecret)
+	logging.From(ctx).Info("localauth: two-factor enabled", "user_id", acc.ID)
+	h.record(r, "auth.2fa", audit.Success, acc.ID, acc.Email, map[string]any{"action": "enabled"})
+	v = newView(r)
+	v.Notice = i18n.T(ctx, "auth.2fa.enabled")
+	v.Codes = codes
+	acc.TOTPSecret = secret
+	h.renderEnabled(w, r, acc, v, http.StatusOK)
+}
+
+func (h *Handler) renderEnabled(w http.ResponseWriter, r *http.Request, acc *store.Account, v view, status int) {
+	left, err := h.Accounts.BackupCodesLeft(r.Context(), acc.ID)
+	if err != nil {
+		logging.From(r.Context()).Error("localauth: count backup codes", "user_id", acc.ID, "err", err)
+	}
+	v.TwoFactor = true
+	v.CodesLeft = left
+	h.render(w, r, status, "auth_2fa_setup.html", v)
+}
+
+// RegenerateCodes 는 현재 코드를 확인하고 백업 코드를 새로 만든다. 예전 백업 코드는 더 쓸 수 없다.
+func (h *Handler) RegenerateCodes(w http.ResponseWriter, r *http.Request) {
+	h.withCode(w, r, func(acc *store.Account, v view) {
+		ctx := r.Context()
+		codes, hashes := newBackupCodes()
+		if err := h.Accounts.ReplaceBackupCodes(ctx, acc.ID, hashes); err != nil {
+			logging.From(ctx).Error("localauth: replace backup codes", "user_id", acc.ID, "err", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		logging.From(ctx).Info("localauth: backup codes regenerated", "user_id", acc.ID)
+		h.record(r, "auth.2fa", audit.Success, acc.ID, acc.Email, map[string]any{"action": "backup_codes_regenerated"})
+		v.Codes = codes
+		h.renderEnabled(w, r, acc, v, http.StatusOK)
+	})
+}
+
+// Disable 은 현재 코드를 확인하고 2단계 인증을 끈다.
+func (h *Handler) Disable(w http.ResponseWriter, r *http.Request) {
+	h.withCode(w, r, func(acc *store.Account, v view) {
+		ctx := r.Context()
+		if err := h.Accounts.DisableTOTP(ctx, acc.ID); err != nil {
+			logging.From(ctx).Error("localauth: disable totp", "user_id", acc.ID, "err", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		logging.From(ctx).Info("localauth: two-factor disabled", "user_id", acc.ID)
+		h.record(r, "auth.2fa", audit.Success, acc.ID, acc.Email, map[string]any{"action": "disabled"})
+		h.notice(w, r, http.StatusOK, "auth.2fa.disabled")
+	})
+}
+
+// withCode 는 2단계 인증을 켠 로그인 사용자가 제출한 코드를 확인한 뒤 fn 을 실행한다.
+func (h *Handler) withCode(w http.ResponseWriter, r *http.Request, fn func(*store.Account, view)) {
+	ctx := r.Context()
+	acc := h.account(w, r)
+	if acc == nil {
+		return
+	}
+	if !acc.TwoFactor() {
+		http.Redirect(w, r, "/auth/2fa/setup", http.StatusSeeOther)
+		return
+	}
+	v := newView(r)
+	method, err := h.checkCode(r, acc, r.PostFormValue("code"))
+	if err != nil {
+		logging.From(ctx).Error("localauth: check code", "user_id", acc.ID, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if method == "" {
+		v.Error = i18n.T(ctx, "auth.2fa.error.code")
+		h.renderEnabled(w, r, acc, v, http.StatusUnprocessableEntity)
+		return
+	}
+	fn(acc, v)
+}
+
+var backupEncoding = base32.NewEncoding("abcdefghijkmnpqrstuvwxyz23456789").WithPadding(base32.NoPadding)
+
+// newBackupCodes 는 "xxxx-xxxx" 형식의 백업 코드와 저장할 해시를 만든다.
+func newBackupCodes() (codes, hashes []string) {
+	for range backupCodes {
+		b := make([]byte, 5)
+		rand.Read(b)
+		c := backupEncoding.EncodeToString(b)
+		codes = append(codes, c[:4]+"-"+c[4:])
+		hashes = append(hashes, hashToken(c))
+	}
+	return codes, hashes
+}
+
+// normalizeBackupCode 는 입력한 백업 코드에서 하이픈과 공백을 빼고 소문자로 바꾼다. 형식이 다르면 "" 이다.
+func normalizeBackupCode(s string) string {
+	s = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(s))
+	if len(s) != 8 {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"net/http"
+
+	"github.com/hgsong234/_stack/Golang/api"
+)
+
+// levelBody 는 LevelHandler 의 요청·응답 본문이다.
+type levelBody struct {
+	Level string `json:"level" validate:"required"`
+}
+
+// LevelHandler 는 로그 레벨을 조회(GET)하고 바꾸는(PUT {"level": "debug"}) 핸들러다.
+// 관리자 인가 미들웨어 뒤에 등록한다.
+func LevelHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			api.WriteJSON(w, http.StatusOK, levelBody{Level: Level()})
+			return
+		}
+		var body levelBody
+		if err := api.ReadJSON(r, &body); err != nil {
+			api.WriteError(w, err)
+			return
+		}
+		prev := Level()
+		if err := SetLevel(body.Level); err != nil {
+			api.WriteError(w, api.BadRequest(err.Error()))
+			return
+		}
+		From(r.Context()).Warn("log level changed", "from", prev, "to", Level())
+		api.WriteJSON(w, http.StatusOK, levelBody{Level: Level()})
+	}
+}
@@ -0,0 +1,139 @@
+// Package logging 은 log/slog 기반의 구조화 로거를 제공한다.
+//
+// 로그는 레벨(debug, info, warn, error)을 가지며 JSON 또는 사람이 읽기 쉬운 console 형식으로 쓴다.
+// 레벨은 프로세스 전체가 공유하므로 SetLevel 로 실행 중에 바꿀 수 있다.
+//
+// 요청 처리 중에는 From(ctx) 로 요청 ID 가 붙은 자식 로거를 꺼내 쓴다.
+//
+//	logging.From(r.Context()).Error("hello: create user", "err", err)
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Logger 는 레벨별로 메시지와 키-값 속성을 기록한다.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+	// With 는 args 속성이 항상 붙는 자식 로거를 만든다.
+	With(args ...any) Logger
+}
+
+// Options 는 로거 설정이다.
+type Options struct {
+	// Format 은 "json", "console", "pretty" 중 하나다. 기본값은 "json".
+	// pretty 는 개발용 한 줄 형식이고, 터미널에 쓸 때는 색을 칠한다. (NO_COLOR 환경 변수가 있으면 칠하지 않는다)
+	Format string
+	// Output 은 로그를 쓸 곳이다. nil 이면 os.Stderr.
+	Output io.Writer
+}
+
+// level 은 이 패키지로 만든 모든 로거가 공유하는 최소 레벨이다.
+var level = new(slog.LevelVar)
+
+// New 는 opts 에 맞는 Logger 를 만든다.
+func New(opts Options) (Logger, error) {
+	out := opts.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	hopts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch opts.Format {
+	case "", "json":
+		h = slog.NewJSONHandler(out, hopts)
+	case "console":
+		h = slog.NewTextHandler(out, hopts)
+	case "pretty":
+		_, noColor := os.LookupEnv("NO_COLOR")
+		h = newPrettyHandler(out, hopts, !noColor && terminal(out))
+	default:
+		return nil, fmt.Errorf("logging: unknown format %q", opts.Format)
+	}
+	return FromSlog(slog.New(h)), nil
+}
+
+// FromSlog 는 *slog.Logger 를 Logger 로 감싼다.
+func FromSlog(l *slog.Logger) Logger { return slogLogger{l} }
+
+// slogLogger 는 *slog.Logger 로 구현한 Logger 다.
+type slogLogger struct{ *slog.Logger }
+
+func (l slogLogger) With(args ...any) Logger { return slogLogger{l.Logger.With(args...)} }
+
+// ParseLevel 은 "debug", "info", "warn", "error" 를 slog.Level 로 바꾼다.
+func ParseLevel(s string) (slog.Level, error) {
+	var lv slog.Level
+	if err := lv.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("logging: unknown level %q", s)
+	}
+	return lv, nil
+}
+
+// SetLevel 은 모든 로거의 최소 레벨을 바꾼다.
+func SetLevel(s string) error {
+	lv, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+	level.Set(lv)
+	return nil
+}
+
+// Level 은 현재 최소 레벨을 소문자로 돌려준다.
+func Level() string { return strings.ToLower(level.Level().String()) }
+
+var defaultLogger atomic.Value // Logger
+
+func init() {
+	defaultLogger.Store(FromSlog(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))))
+}
+
+// Default 는 기본 로거다.
+func Default() Logger { return defaultLogger.Load().(Logger) }
+
+// SetDefault 는 기본 로거를 바꾼다. slog 로 만든 로거라면 log 패키지와 slog 의 기본 출력도 이 로거로 보낸다.
+func SetDefault(l Logger) {
+	defaultLogger.Store(l)
+	if sl, ok := l.(slogLogger); ok {
+		slog.SetDefault(sl.Logger)
+	}
+}
+
+// 로거 컨텍스트 키
+type ctxKey struct{}
+
+// WithContext 는 l 을 담은 컨텍스트를 돌려준다.
+func WithContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// From 은 컨텍스트의 요청 로거를 돌려준다. 없으면 Default 다.
+func From(ctx context.Context) Logger {
+	if l, ok := ctx.Value(ctxKey{}).(Logger); ok {
+		return l
+	}
+	return Default()
+}
+
+// terminal 은 w 가 터미널인지 확인한다. 출력이 하나뿐인 Sink 는 그 출력을 본다.
+func terminal(w io.Writer) bool {
+	if s, ok := w.(*Sink); ok {
+		w = s.Writer
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,160 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// prettyHandler 는 사람이 읽기 좋은 한 줄 형식의 slog.Handler 다. (개발 모드)
+//
+//	15:04:05.000 INFO  server listening addr=:8080
+//
+// color 이면 레벨을 ANSI 색으로 칠하고, 여러 줄 값(스택 트레이스 등)은 들여 써서 다음 줄부터 쓴다.
+type prettyHandler struct {
+	out   io.Writer
+	mu    *sync.Mutex
+	opts  slog.HandlerOptions
+	color bool
+	// attrs 는 With 로 붙인 속성을 미리 그려 둔 것이고, group 은 WithGroup 접두사다.
+	attrs string
+	group string
+}
+
+func newPrettyHandler(out io.Writer, opts *slog.HandlerOptions, color bool) *prettyHandler {
+	return &prettyHandler{out: out, mu: new(sync.Mutex), opts: *opts, color: color}
+}
+
+func (h *prettyHandler) Enabled(_ context.Context, lv slog.Level) bool {
+	min := slog.LevelInfo
+	if h.opts.Level != nil {
+		min = h.opts.Level.Level()
+	}
+	return lv >= min
+}
+
+func (h *prettyHandler) Handle(_ context.Context, r slog.Record) error {
+	var b, multi bytes.Buffer
+	if !r.Time.IsZero() {
+		b.WriteString(r.Time.Format("15:04:05.000 "))
+	}
+	b.WriteString(h.level(r.Level))
+	b.WriteByte(' ')
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		h.appendAttr(&b, &multi, h.group, a)
+		return true
+	})
+	b.WriteByte('\n')
+	b.Write(multi.Bytes())
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.out.Write(b.Bytes())
+	return err
+}
+
+func (h *prettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b, multi bytes.Buffer
+	for _, a := range attrs {
+		h.appendAttr(&b, &multi, h.group, a)
+	}
+	h2 := *h
+	// With 로 붙인 여러 줄 값은 드물므로 한 줄에 따옴표로 쓴다.
+	h2.attrs = h.attrs + b.String() + strings.ReplaceAll(multi.String(), "\n", " ")
+	return &h2
+}
+
+func (h *prettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.group = h.group + name + "."
+	return &h2
+}
+
+var levelColors = map[slog.Level]string{
+	slog.LevelDebug: "\x1b[90m", // 회색
+	slog.LevelInfo:  "\x1b[36m", // 청록
+	slog.LevelWarn:  "\x1b[33m", // 노랑
+	slog.LevelError: "\x1b[31m", // 빨강
+}
+
+func (h *prettyHandler) level(lv slog.Level) string {
+	s := fmt.Sprintf("%-5s", lv.String())
+	if !h.color {
+		return s
+	}
+	c, ok := levelColors[lv]
+	if !ok {
+		c = levelColors[slog.LevelError]
+	}
+	return c + s + "\x1b[0m"
+}
+
+func (h *prettyHandler) appendAttr(b, multi *bytes.Buffer, group string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if h.opts.ReplaceAttr != nil && a.Value.Kind() != slog.KindGroup {
+		a = h.opts.ReplaceAttr(strings.Split(strings.TrimSuffix(group, "."), "."), a)
+	}
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if a.Key != "" {
+			group += a.Key + "."
+		}
+		for _, ga := range attrs {
+			h.appendAttr(b, multi, group, ga)
+		}
+		return
+	}
+	key := group + a.Key
+	val := prettyValue(a.Value)
+	if strings.Contains(val, "\n") {
+		fmt.Fprintf(multi, "    %s:\n", key)
+		for line := range strings.Lines(strings.TrimRight(val, "\n")) {
+			multi.WriteString("      " + line)
+			if !strings.HasSuffix(line, "\n") {
+				multi.WriteByte('\n')
+			}
+		}
+		return
+	}
+	if h.color {
+		key = "\x1b[2m" + key + "=\x1b[0m"
+	} else {
+		key += "="
+	}
+	b.WriteByte(' ')
+	b.WriteString(key)
+	b.WriteString(val)
+}
+
+func prettyValue(v slog.Value) string {
+	var s string
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			s = err.Error()
+		} else {
+			s = fmt.Sprint(v.Any())
+		}
+	default:
+		s = v.String()
+	}
+	if s == "" || (!strings.Contains(s, "\n") && strings.ContainsAny(s, " \t\"=")) {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat 은 회전된 파일 이름에 붙는 시각 형식이다. (app.log.20260102T150405.000)
+const backupTimeFormat = "20060102T150405.000"
+
+// RotatingFile 은 크기가 MaxSize 를 넘으면 새 파일로 바꾸는 로그 파일이다.
+// 회전된 파일은 "경로.시각" 이름으로 남고, MaxBackups 와 MaxAge 를 넘는 것은 지운다.
+type RotatingFile struct {
+	// Path 는 현재 로그 파일 경로다.
+	Path string
+	// MaxSize 는 파일 하나의 최대 바이트 수다. 0 이면 회전하지 않는다.
+	MaxSize int64
+	// MaxAge 는 회전된 파일을 보관하는 기간이다. 0 이면 기간으로 지우지 않는다.
+	MaxAge time.Duration
+	// MaxBackups 는 보관할 회전된 파일의 최대 개수다. 0 이면 개수로 지우지 않는다.
+	MaxBackups int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// OpenRotatingFile 은 path 를 추가 모드로 연다. 디렉터리가 없으면 만든다.
+func OpenRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*RotatingFile, error) {
+	rf := &RotatingFile{Path: path, MaxSize: maxSize, MaxAge: maxAge, MaxBackups: maxBackups}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(rf.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(rf.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.f, rf.size = f, st.Size()
+	return nil
+}
+
+// Write 는 p 를 쓴다. p 를 쓰면 MaxSize 를 넘는 경우 먼저 회전한다.
+// 레코드 하나가 두 파일로 나뉘지 않도록 Write 단위로 회전한다.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return 0, os.ErrClosed
+	}
+	if rf.MaxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.MaxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.f.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Rotate 는 크기와 상관없이 지금 회전한다.
+func (rf *RotatingFile) Rotate() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.rotate()
+}
+
+func (rf *RotatingFile) rotate() error {
+	if err := rf.f.Close(); err != nil {
+		return err
+	}
+	rf.f = nil
+	backup := rf.Path + "." + time.Now().Format(backupTimeFormat)
+	if err := os.Rename(rf.Path, backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("logging: rotate: %w", err)
+	}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	rf.prune()
+	return nil
+}
+
+// prune 은 오래되었거나 개수를 넘는 회전된 파일을 지운다.
+func (rf *RotatingFile) prune() {
+	matches, err := filepath.Glob(rf.Path + ".*")
+	if err != nil {
+		return
+	}
+	type backup struct {
+		path string
+		t    time.Time
+	}
+	var backups []backup
+	for _, m := range matches {
+		t, err := time.Parse(backupTimeFormat, strings.TrimPrefix(m, rf.Path+"."))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{m, t})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].t.After(backups[j].t) })
+	cutoff := time.Now().Add(-rf.MaxAge)
+	for i, b := range backups {
+		if (rf.MaxBackups > 0 && i >= rf.MaxBackups) || (rf.MaxAge > 0 && b.t.Before(cutoff)) {
+			os.Remove(b.path)
+		}
+	}
+}
+
+// Close 는 파일을 닫는다.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return nil
+	}
+	err := rf.f.Close()
+	rf.f = nil
+	return err
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// SinkConfig 는 로그를 보낼 곳들의 설정이다.
+type SinkConfig struct {
+	// Outputs 는 "stdout", "stderr", "file", "syslog" 중 하나 이상이다. 비어 있으면 stdout.
+	Outputs []string
+
+	// File 은 "file" 출력의 경로다.
+	File string
+	// FileMaxSize 는 회전 전 파일 하나의 최대 바이트 수다.
+	FileMaxSize int64
+	// FileMaxAge 는 회전된 파일의 보관 기간이다.
+	FileMaxAge time.Duration
+	// FileMaxBackups 는 보관할 회전된 파일 수다.
+	FileMaxBackups int
+
+	// SyslogNetwork, SyslogAddr 는 원격 syslog 주소다. 비어 있으면 로컬 syslog 데몬에 보낸다.
+	SyslogNetwork string
+	SyslogAddr    string
+	// SyslogTag 는 syslog 메시지의 태그(프로그램 이름)다.
+	SyslogTag string
+}
+
+// Sink 는 설정된 출력들에 같은 내용을 쓰는 Writer 다.
+type Sink struct {
+	io.Writer
+	closers []io.Closer
+}
+
+// OpenSink 는 cfg 의 출력들을 연다. 하나라도 실패하면 이미 연 출력을 닫고 에러를 돌려준다.
+func OpenSink(cfg SinkConfig) (*Sink, error) {
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []string{"stdout"}
+	}
+	s := &Sink{}
+	var writers []io.Writer
+	for _, o := range outputs {
+		var w io.Writer
+		switch o {
+		case "stdout":
+			w = os.Stdout
+		case "stderr":
+			w = os.Stderr
+		case "file":
+			if cfg.File == "" {
+				s.Close()
+				return nil, errors.New("logging: file output requires a path")
+			}
+			rf, err := OpenRotatingFile(cfg.File, cfg.FileMaxSize, cfg.FileMaxAge, cfg.FileMaxBackups)
+			if err != nil {
+				s.Close()
+				return nil, fmt.Errorf("logging: open %s: %w", cfg.File, err)
+			}
+			w = rf
+			s.closers = append(s.closers, rf)
+		case "syslog":
+			sw, err := dialSyslog(cfg.SyslogNetwork, cfg.SyslogAddr, cfg.SyslogTag)
+			if err != nil {
+				s.Close()
+				return nil, fmt.Errorf("logging: syslog: %w", err)
+			}
+			w = sw
+			s.closers = append(s.closers, sw)
+		default:
+			s.Close()
+			return nil, fmt.Errorf("logging: unknown output %q", o)
+		}
+		writers = append(writers, w)
+	}
+	if len(writers) == 1 {
+		s.Writer = writers[0]
+	} else {
+		s.Writer = io.MultiWriter(writers...)
+	}
+	return s, nil
+}
+
+// Close 는 파일과 syslog 연결을 닫는다.
+func (s *Sink) Close() error {
+	var errs []error
+	for _, c := range s.closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
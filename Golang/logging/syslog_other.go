@@ -0,0 +1,13 @@
+//go:build !unix
+
+package logging
+
+import (
+	"errors"
+	"io"
+)
+
+// dialSyslog 는 syslog 가 없는 플랫폼에서는 항상 실패한다.
+func dialSyslog(network, addr, tag string) (io.WriteCloser, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
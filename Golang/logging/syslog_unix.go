@@ -0,0 +1,13 @@
+//go:build unix
+
+package logging
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// dialSyslog 는 syslog 에 연결한다. 레코드 하나가 메시지 하나가 되며 우선순위는 daemon.info 다.
+func dialSyslog(network, addr, tag string) (io.WriteCloser, error) {
+	return syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+}
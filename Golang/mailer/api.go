@@ -0,0 +1,206 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/jobs"
+)
+
+// SendGrid 는 SendGrid v3 Mail Send API 로 보내는 백엔드다.
+type SendGrid struct {
+	APIKey string
+	// Endpoint 가 비어 있으면 https://api.sendgrid.com/v3/mail/send 다.
+	Endpoint string
+	// Client 가 nil 이면 http.DefaultClient 를 쓴다.
+	Client *http.Client
+}
+
+func (s SendGrid) Name() string { return "sendgrid" }
+
+func (s SendGrid) Send(ctx context.Context, m Message) error {
+	type addr struct {
+		Email string `json:"email"`
+		Name  string `json:"name,omitempty"`
+	}
+	type content struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	parse := func(a string) (addr, error) {
+		p, err := mail.ParseAddress(a)
+		if err != nil {
+			return addr{}, jobs.Permanent(err)
+		}
+		return addr{Email: p.Address, Name: p.Name}, nil
+	}
+	var body struct {
+		Personalizations []struct {
+			To []addr `json:"to"`
+		} `json:"personalizations"`
+		From    addr      `json:"from"`
+		ReplyTo *addr     `json:"reply_to,omitempty"`
+		Subject string    `json:"subject"`
+		Content []content `json:"content"`
+	}
+	body.Personalizations = make([]struct {
+		To []addr `json:"to"`
+	}, 1)
+	for _, a := range m.To {
+		to, err := parse(a)
+		if err != nil {
+			return err
+		}
+		body.Personalizations[0].To = append(body.Personalizations[0].To, to)
+	}
+	var err error
+	if body.From, err = parse(m.From); err != nil {
+		return err
+	}
+	if m.ReplyTo != "" {
+		rt, err := parse(m.ReplyTo)
+		if err != nil {
+			return err
+		}
+		body.ReplyTo = &rt
+	}
+	body.Subject = m.Subject
+	// SendGrid 는 text/plain 이 text/html 보다 앞에 있어야 한다.
+	if m.Text != "" {
+		body.Content = append(body.Content, content{"text/plain", m.Text})
+	}
+	if m.HTML != "" {
+		body.Content = append(body.Content, content{"text/html", m.HTML})
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://api.sendgrid.com/v3/mail/send"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	return doAPI(s.Client, req, "sendgrid")
+}
+
+// SES 는 Amazon SES v2 SendEmail API 로 보내는 백엔드다. 요청은 Signature Version 4 로 서명한다.
+type SES struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken 은 임시 자격 증명을 쓸 때만 넣는다.
+	SessionToken string
+	// Endpoint 가 비어 있으면 https://email.<Region>.amazonaws.com 이다.
+	Endpoint string
+	Client   *http.Client
+}
+
+func (s SES) Name() string { return "ses" }
+
+func (s SES) Send(ctx context.Context, m Message) error {
+	type text struct {
+		Data    string `json:"Data"`
+		Charset string `json:"Charset"`
+	}
+	body := map[string]any{
+		"FromEmailAddress": m.From,
+		"Destination":      map[string][]string{"ToAddresses": m.To},
+	}
+	if m.ReplyTo != "" {
+		body["ReplyToAddresses"] = []string{m.ReplyTo}
+	}
+	parts := map[string]text{}
+	if m.Text != "" {
+		parts["Text"] = text{m.Text, "UTF-8"}
+	}
+	if m.HTML != "" {
+		parts["Html"] = text{m.HTML, "UTF-8"}
+	}
+	body["Content"] = map[string]any{"Simple": map[string]any{"Subject": text{m.Subject, "UTF-8"}, "Body": parts}}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://email." + s.Region + ".amazonaws.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/v2/email/outbound-emails", bytes.NewReader(data))
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	s.sign(req, data, time.Now())
+	return doAPI(s.Client, req, "ses")
+}
+
+// sign 은 AWS Signature Version 4 로 req 에 Authorization 헤더를 붙인다.
+func (s SES) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	signed := "content-type;host;x-amz-date"
+	headers := "content-type:" + req.Header.Get("Content-Type") + "\nhost:" + req.URL.Host + "\nx-amz-date:" + amzDate + "\n"
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+		signed += ";x-amz-security-token"
+		headers += "x-amz-security-token:" + s.SessionToken + "\n"
+	}
+	canonical := req.Method + "\n" + req.URL.EscapedPath() + "\n" + req.URL.RawQuery + "\n" + headers + "\n" + signed + "\n" + sha256Hex(body)
+	scope := date + "/" + s.Region + "/ses/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+	key := []byte("AWS4" + s.SecretAccessKey)
+	for _, part := range []string{date, s.Region, "ses", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signed, hex.EncodeToString(hmacSHA256(key, toSign))))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// doAPI 는 메일 API 요청을 보낸다. 429 와 5xx 는 다시 시도하고, 다른 4xx 는 다시 시도하지 않는다.
+func doAPI(c *http.Client, req *http.Request, name string) error {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("mailer: %s returned %s: %s", name, resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return jobs.Permanent(err)
+	}
+	return err
+}
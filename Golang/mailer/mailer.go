@@ -0,0 +1,162 @@
+// Package mailer 는 메일을 만들어 SMTP 나 메일 API(SES, SendGrid)로 보낸다.
+//
+// 본문은 render 템플릿으로 만든다. 메일 템플릿은 일반 페이지처럼 templates/ 에 두되 사이트 레이아웃을 쓰지 않고
+// {{define "subject"}}, {{define "html"}}, {{define "text"}} 로 제목, HTML 본문, 텍스트 본문을 정의한다.
+// 제목은 반드시 있어야 하고 본문은 둘 중 하나 이상 있으면 된다.
+//
+// Enqueue 와 SendTemplate 은 jobs 큐로 비동기 발송하므로 요청 처리가 SMTP 서버를 기다리지 않으며,
+// 실패하면 큐의 백오프로 다시 보낸다.
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/mail"
+	"strings"
+
+	"github.com/hgsong234/_stack/Golang/jobs"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/metrics"
+	"github.com/hgsong234/_stack/Golang/render"
+)
+
+// JobKind 는 발송 작업의 jobs 종류다.
+const JobKind = "mail.send"
+
+var mailSent = metrics.NewCounterVec("mail_sent_total", "Mail send attempts by backend and result (ok, error).", "backend", "result")
+
+// Message 는 보낼 메일 한 통이다. Text 와 HTML 중 하나 이상이 있어야 한다.
+type Message struct {
+	From    string   `json:"from,omitempty"`
+	To      []string `json:"to"`
+	ReplyTo string   `json:"reply_to,omitempty"`
+	Subject string   `json:"subject"`
+	Text    string   `json:"text,omitempty"`
+	HTML    string   `json:"html,omitempty"`
+}
+
+// validate 는 주소 형식과 본문을 확인한다.
+func (m Message) validate() error {
+	if len(m.To) == 0 {
+		return errors.New("mailer: message has no recipients")
+	}
+	for _, a := range append([]string{m.From}, m.To...) {
+		if _, err := mail.ParseAddress(a); err != nil {
+			return fmt.Errorf("mailer: invalid address %q: %w", a, err)
+		}
+	}
+	if m.Text == "" && m.HTML == "" {
+		return errors.New("mailer: message has no body")
+	}
+	return nil
+}
+
+// Sender 는 메일을 실제로 보내는 백엔드다.
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+	// Name 은 메트릭과 로그에 쓰는 백엔드 이름이다.
+	Name() string
+}
+
+// Mailer 는 기본 발신자, 템플릿, 발송 큐를 묶은 것이다.
+type Mailer struct {
+	Sender Sender
+	// From 은 Message.From 이 비어 있을 때 쓰는 발신자다.
+	From  string
+	Views *render.Engine
+
+	queue *jobs.Queue
+}
+
+// New 는 Mailer 를 만들고 q 에 발송 처리기를 등록한다. q.Start 전에 호출한다.
+func New(sender Sender, from string, views *render.Engine, q *jobs.Queue) *Mailer {
+	m := &Mailer{Sender: sender, From: from, Views: views, queue: q}
+	q.Register(JobKind, m.deliver)
+	return m
+}
+
+// Send 는 메일을 바로 보낸다.
+func (ml *Mailer) Send(ctx context.Context, m Message) error {
+	if m.From == "" {
+		m.From = ml.From
+	}
+	if err := m.validate(); err != nil {
+		return jobs.Permanent(err)
+	}
+	err := ml.Sender.Send(ctx, m)
+	backend := ml.Sender.Name()
+	if err != nil {
+		mailSent.Inc(backend, "error")
+		return err
+	}
+	mailSent.Inc(backend, "ok")
+	logging.From(ctx).Info("mail sent", "backend", backend, "to", strings.Join(m.To, ","), "subject", m.Subject)
+	return nil
+}
+
+// Enqueue 는 메일을 큐에 넣는다. 주소나 본문이 잘못되었으면 넣지 않고 에러를 돌려준다.
+func (ml *Mailer) Enqueue(ctx context.Context, m Message) error {
+	if m.From == "" {
+		m.From = ml.From
+	}
+	if err := m.validate(); err != nil {
+		return err
+	}
+	_, err := ml.queue.Enqueue(ctx, JobKind, m)
+	return err
+}
+
+// SendTemplate 은 page 템플릿을 data 로 렌더링해 to 에게 보내도록 큐에 넣는다.
+func (ml *Mailer) SendTemplate(ctx context.Context, page string, data any, to ...string) error {
+	m, err := ml.Render(page, data)
+	if err != nil {
+		return err
+	}
+	m.To = to
+	return ml.Enqueue(ctx, m)
+}
+
+// Render 는 메일 템플릿에서 제목, 텍스트 본문, HTML 본문을 만든다.
+func (ml *Mailer) Render(page string, data any) (Message, error) {
+	var m Message
+	blocks := map[string]string{}
+	for _, name := range []string{"subject", "text", "html"} {
+		out, ok, err := ml.Views.ExecuteTemplate(page, name, data)
+		if err != nil {
+			return m, err
+		}
+		if ok {
+			blocks[name] = strings.TrimSpace(string(out))
+		}
+	}
+	if _, ok := blocks["subject"]; !ok {
+		return m, fmt.Errorf("mailer: %s has no subject template", page)
+	}
+	// 제목과 텍스트 본문은 HTML 이 아니므로 html/template 의 이스케이프를 되돌린다.
+	m.Subject = strings.Join(strings.Fields(html.UnescapeString(blocks["subject"])), " ")
+	m.Text = html.UnescapeString(blocks["text"])
+	m.HTML = blocks["html"]
+	return m, nil
+}
+
+// deliver 는 큐에서 꺼낸 메일을 보낸다.
+func (ml *Mailer) deliver(ctx context.Context, payload json.RawMessage) error {
+	var m Message
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return jobs.Permanent(err)
+	}
+	return ml.Send(ctx, m)
+}
+
+// Log 는 메일을 보내지 않고 로그로만 남기는 개발용 백엔드다.
+type Log struct{}
+
+func (Log) Send(ctx context.Context, m Message) error {
+	logging.From(ctx).Info("mail (not sent, log backend)", "from", m.From, "to", strings.Join(m.To, ","), "subject", m.Subject, "text", m.Text)
+	return nil
+}
+
+func (Log) Name() string { return "log" }
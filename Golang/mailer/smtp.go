@@ -0,0 +1,197 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/jobs"
+)
+
+// SMTP 는 SMTP 서버로 보내는 백엔드다. 포트가 465 이면 처음부터 TLS 로 연결하고,
+// 다른 포트에서는 서버가 지원하면 STARTTLS 로 전환한다. 인증은 Username 이 있을 때만 한다.
+type SMTP struct {
+	Addr     string
+	Username string
+	Password string
+	// Timeout 은 연결부터 전송 완료까지의 제한 시간이다. 0 이면 30초.
+	Timeout time.Duration
+}
+
+func (s SMTP) Name() string { return "smtp" }
+
+func (s SMTP) Send(ctx context.Context, m Message) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	rcpts := make([]string, 0, len(m.To))
+	for _, a := range m.To {
+		addr, err := mail.ParseAddress(a)
+		if err != nil {
+			return jobs.Permanent(err)
+		}
+		rcpts = append(rcpts, addr.Address)
+	}
+	data, err := buildMIME(m, time.Now())
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	host, port, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	conn, err := s.dial(ctx, host, port)
+	if err != nil {
+		return err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(dl)
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if _, isTLS := conn.(*tls.Conn); !isTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+				return err
+			}
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return smtpError(err)
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return smtpError(err)
+	}
+	for _, r := range rcpts {
+		if err := c.Rcpt(r); err != nil {
+			return smtpError(err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return smtpError(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return smtpError(err)
+	}
+	return c.Quit()
+}
+
+func (s SMTP) dial(ctx context.Context, host, port string) (net.Conn, error) {
+	if port == "465" {
+		d := &tls.Dialer{Config: &tls.Config{ServerName: host}}
+		return d.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+}
+
+// smtpError 는 5xx 응답(주소 거부, 인증 실패 등)을 다시 시도하지 않을 실패로 표시한다.
+func smtpError(err error) error {
+	var te *textproto.Error
+	if errors.As(err, &te) && te.Code >= 500 {
+		return jobs.Permanent(err)
+	}
+	return err
+}
+
+// buildMIME 은 메일 헤더와 본문을 만든다. 텍스트와 HTML 이 모두 있으면 multipart/alternative 다.
+func buildMIME(m Message, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	h := func(k, v string) { fmt.Fprintf(&buf, "%s: %s\r\n", k, v) }
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return nil, err
+	}
+	h("From", from.String())
+	to := make([]string, 0, len(m.To))
+	for _, a := range m.To {
+		addr, err := mail.ParseAddress(a)
+		if err != nil {
+			return nil, err
+		}
+		to = append(to, addr.String())
+	}
+	h("To", strings.Join(to, ", "))
+	if m.ReplyTo != "" {
+		h("Reply-To", m.ReplyTo)
+	}
+	h("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	h("Date", now.Format(time.RFC1123Z))
+	id := make([]byte, 12)
+	rand.Read(id)
+	_, domain, _ := strings.Cut(from.Address, "@")
+	h("Message-ID", "<"+hex.EncodeToString(id)+"@"+domain+">")
+	h("MIME-Version", "1.0")
+
+	if m.Text == "" || m.HTML == "" {
+		ct, body := "text/plain", m.Text
+		if m.HTML != "" {
+			ct, body = "text/html", m.HTML
+		}
+		h("Content-Type", ct+"; charset=utf-8")
+		h("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQP(&buf, body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	mw := multipart.NewWriter(&buf)
+	h("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ ct, body string }{{"text/plain", m.Text}, {"text/html", m.HTML}} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.ct + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQP(pw, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQP(w io.Writer, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(s)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
@@ -0,0 +1,122 @@
+// Package maintenance 는 실행 중에 켜고 끌 수 있는 점검 모드다.
+//
+// 점검 중에는 예외 경로(상태 검사, 관리 화면 등)를 뺀 모든 요청에 503 과 Retry-After 를 돌려준다.
+// 브라우저에는 maintenance.html 템플릿을, API 클라이언트에는 JSON 에러를 보낸다.
+package maintenance
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/i18n"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/render"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// State 는 점검 모드의 한 시점 상태다.
+type State struct {
+	Enabled bool `json:"enabled"`
+	// Message 는 점검 페이지와 에러 응답에 보여 줄 안내문이다. 비어 있으면 기본 문구를 쓴다.
+	Message string `json:"message,omitempty"`
+	// RetryAfter 는 Retry-After 헤더로 보낼 초 단위 예상 시간이다.
+	RetryAfter int       `json:"retry_after"`
+	Since      time.Time `json:"since,omitzero"`
+}
+
+// Mode 는 점검 모드 스위치다. 여러 요청에서 동시에 읽어도 안전하다.
+type Mode struct {
+	exempt []string
+	state  atomic.Pointer[State]
+}
+
+// New 는 exempt 경로를 점검에서 빼는 Mode 를 만든다. 경로는 그 자체와 하위 경로 전체와 일치한다. ("/admin" 은 "/admin/x" 도 포함)
+func New(initial State, exempt ...string) *Mode {
+	m := &Mode{exempt: exempt}
+	m.Set(initial)
+	return m
+}
+
+// State 는 현재 상태다.
+func (m *Mode) State() State { return *m.state.Load() }
+
+// Set 은 상태를 바꾼다. 점검을 새로 켜면 Since 를 지금으로 정한다.
+func (m *Mode) Set(s State) {
+	prev := m.state.Load()
+	switch {
+	case !s.Enabled:
+		s.Since = time.Time{}
+	case prev != nil && prev.Enabled:
+		s.Since = prev.Since
+	default:
+		s.Since = time.Now()
+	}
+	if s.RetryAfter < 0 {
+		s.RetryAfter = 0
+	}
+	m.state.Store(&s)
+}
+
+// exempted 는 path 가 점검에서 빠지는 경로인지 확인한다.
+func (m *Mode) exempted(path string) bool {
+	for _, p := range m.exempt {
+		p = strings.TrimSuffix(p, "/")
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware 는 점검 중이면 예외가 아닌 요청에 503 을 돌려준다. i18n 미들웨어 안쪽에 등록한다.
+func (m *Mode) Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s := m.state.Load()
+			if !s.Enabled || m.exempted(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx := r.Context()
+			msg := s.Message
+			if msg == "" {
+				msg = i18n.T(ctx, "maintenance.message")
+			}
+			if s.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(s.RetryAfter))
+			}
+			w.Header().Set("Cache-Control", "no-store")
+			if strings.HasPrefix(r.URL.Path, "/api/") ||
+				render.PreferredType(r.Header.Get("Accept"), "text/html", "application/json") == "application/json" {
+				api.WriteError(w, api.NewError(http.StatusServiceUnavailable, "maintenance", msg))
+				return
+			}
+			render.Default().RenderStatus(w, http.StatusServiceUnavailable, "maintenance.html", map[string]any{
+				"Message": msg, "Minutes": s.RetryAfter / 60, "Lang": i18n.Lang(ctx),
+			})
+		})
+	}
+}
+
+// Handler 는 GET 으로 상태를 돌려주고 PUT {"enabled": true, "message": "...", "retry_after": 600} 으로 바꾸는
+// 관리 API 핸들러다. 관리자 인가 미들웨어 뒤에 등록한다.
+func (m *Mode) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			api.WriteJSON(w, http.StatusOK, m.State())
+			return
+		}
+		body := m.State()
+		if err := api.ReadJSON(r, &body); err != nil {
+			api.WriteError(w, err)
+			return
+		}
+		m.Set(body)
+		logging.From(r.Context()).Warn("maintenance mode changed", "enabled", body.Enabled, "message", body.Message)
+		api.WriteJSON(w, http.StatusOK, m.State())
+	}
+}
@@ -0,0 +1,144 @@
+package markdown
+
+import (
+	"html"
+	"strings"
+)
+
+// lang 은 강조할 수 있는 언어의 어휘다.
+type lang struct {
+	keywords map[string]bool
+	// 대소문자를 가리지 않는 키워드 (SQL)
+	fold         bool
+	lineComments []string
+	block        [2]string
+	quotes       string
+}
+
+func words(s string) map[string]bool {
+	m := map[string]bool{}
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+var (
+	cLike = lang{lineComments: []string{"//"}, block: [2]string{"/*", "*/"}, quotes: `"'`}
+	langs = map[string]lang{}
+)
+
+func init() {
+	def := func(l lang, kw string, names ...string) {
+		l.keywords = words(kw)
+		for _, n := range names {
+			langs[n] = l
+		}
+	}
+	goLang := cLike
+	goLang.quotes = "\"'`"
+	def(goLang, `break case chan const continue default defer else fallthrough for func go goto if import interface
+		map package range return select struct switch type var nil true false iota`, "go", "golang")
+	js := cLike
+	js.quotes = "\"'`"
+	def(js, `async await break case catch class const continue debugger default delete do else export extends
+		finally for function if import in instanceof let new of return super switch this throw try typeof var void
+		while yield null undefined true false interface type enum implements readonly`, "js", "javascript", "ts", "typescript", "jsx", "tsx")
+	def(cLike, `abstract break case catch char class const continue default do double else enum extends final
+		finally float for if implements import int interface long new package private protected public return short
+		static struct super switch this throw throws try void volatile while null true false`, "java", "c", "cpp", "c++", "cs", "csharp", "kotlin")
+	def(cLike, `as async await break const continue crate else enum extern false fn for if impl in let loop match
+		mod move mut pub ref return self Self static struct super trait true type unsafe use where while`, "rust", "rs")
+	def(lang{lineComments: []string{"#"}, quotes: `"'`}, `and as assert async await break class continue def del
+		elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while
+		with yield None True False`, "python", "py")
+	def(lang{lineComments: []string{"#"}, quotes: `"'`}, `if then else elif fi for in do done while until case esac
+		function return local export readonly exit`, "sh", "bash", "shell", "zsh")
+	def(lang{lineComments: []string{"#"}, quotes: `"'`}, `true false null yes no`, "yaml", "yml", "toml")
+	def(lang{quotes: `"`}, `true false null`, "json")
+	def(lang{fold: true, lineComments: []string{"--"}, block: [2]string{"/*", "*/"}, quotes: `'"`}, `select from where
+		and or not insert into values update set delete create table index drop alter add primary key foreign
+		references join left right inner outer on group by order having limit offset as distinct null is in like
+		between case when then else end union all exists default unique returning begin commit rollback`, "sql")
+}
+
+// highlight 는 code 를 언어 name 의 규칙으로 강조한다. 모르는 언어는 이스케이프만 한다.
+// 클래스는 hl-keyword, hl-string, hl-comment, hl-number 다.
+func highlight(code, name string) string {
+	l, ok := langs[strings.ToLower(name)]
+	if !ok {
+		return html.EscapeString(code)
+	}
+	var b strings.Builder
+	span := func(class, s string) {
+		b.WriteString(`<span class="hl-` + class + `">` + html.EscapeString(s) + "</span>")
+	}
+	for i := 0; i < len(code); {
+		rest := code[i:]
+		if l.block[0] != "" && strings.HasPrefix(rest, l.block[0]) {
+			end := strings.Index(rest[len(l.block[0]):], l.block[1])
+			n := len(rest)
+			if end >= 0 {
+				n = len(l.block[0]) + end + len(l.block[1])
+			}
+			span("comment", rest[:n])
+			i += n
+			continue
+		}
+		if lineComment(rest, l.lineComments) && (i == 0 || !isWord(code[i-1])) {
+			n := strings.IndexByte(rest, '\n')
+			if n < 0 {
+				n = len(rest)
+			}
+			span("comment", rest[:n])
+			i += n
+			continue
+		}
+		c := code[i]
+		switch {
+		case strings.IndexByte(l.quotes, c) >= 0:
+			n := 1
+			for n < len(rest) && rest[n] != c && (c == '`' || rest[n] != '\n') {
+				if rest[n] == '\\' && c != '`' {
+					n++
+				}
+				n++
+			}
+			n = min(n+1, len(rest))
+			span("string", rest[:n])
+			i += n
+		case c >= '0' && c <= '9' && (i == 0 || !isWord(code[i-1])):
+			n := 1
+			for n < len(rest) && (isWord(rest[n]) || rest[n] == '.') {
+				n++
+			}
+			span("number", rest[:n])
+			i += n
+		case isWord(c):
+			n := 1
+			for n < len(rest) && isWord(rest[n]) {
+				n++
+			}
+			w := rest[:n]
+			if l.keywords[w] || (l.fold && l.keywords[strings.ToLower(w)]) {
+				span("keyword", w)
+			} else {
+				b.WriteString(html.EscapeString(w))
+			}
+			i += n
+		default:
+			b.WriteString(html.EscapeString(rest[:1]))
+			i++
+		}
+	}
+	return b.String()
+}
+
+func lineComment(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
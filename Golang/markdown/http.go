@@ -0,0 +1,33 @@
+package markdown
+
+import (
+	"net/http"
+
+	"github.com/hgsong234/_stack/Golang/api"
+)
+
+// Request 는 렌더링 API 의 요청 본문이다.
+type Request struct {
+	Markdown string `json:"markdown"`
+}
+
+// Response 는 렌더링 API 의 응답이다. HTML 은 Sanitize 를 거친 값이다.
+type Response struct {
+	HTML string `json:"html"`
+}
+
+// Handler 는 {"markdown": "..."} 를 받아 걸러진 HTML 을 돌려주는 API 핸들러다. maxBytes 는 요청 본문의
+// 최대 크기이고 0 이면 api.MaxBodyBytes 다.
+func Handler(opts Options, maxBytes int64) http.HandlerFunc {
+	if maxBytes <= 0 {
+		maxBytes = api.MaxBodyBytes
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := api.ReadJSONLimit(r, &req, maxBytes); err != nil {
+			api.WriteError(w, err)
+			return
+		}
+		api.WriteJSON(w, http.StatusOK, Response{HTML: string(Safe(req.Markdown, opts))})
+	}
+}
@@ -0,0 +1,571 @@
+// Package markdown 은 Markdown 을 HTML 로 바꾸고, 믿을 수 없는 입력으로 만든 HTML 을 허용 목록으로 거른다.
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Options 는 켜 둘 확장 문법이다.
+type Options struct {
+	// Tables 는 GitHub 형식의 표, Strikethrough 는 ~~취소선~~ 이다.
+	Tables        bool
+	Strikethrough bool
+	// Highlight 는 언어를 적은 코드 블록의 키워드, 문자열, 주석, 숫자를 hl-* 클래스의 <span> 으로 감싼다.
+	Highlight bool
+	// HTML 이 거짓이면 본문 속 HTML 도 글자로 이스케이프한다.
+	HTML bool
+}
+
+// Default 는 모든 확장을 켠 Options 다.
+var Default = Options{Tables: true, Strikethrough: true, Highlight: true, HTML: true}
+
+// Render 는 src 를 HTML 로 바꾼다. CommonMark 의 자주 쓰는 부분과 opts 에서 켠 확장을 지원한다.
+//
+//	# 제목            ATX 제목 (id 가 붙는다), 제목 아래 === / --- 도 제목이다
+//	*기울임* **굵게** ~~취소~~ `코드`
+//	[링크](url "제목") ![그림](url) <https://자동.링크>
+//	- 목록, 1. 번호 목록 (들여 써서 중첩)
+//	> 인용
+//	```lang 코드 블록 ``` 또는 네 칸 들여쓰기
+//	| 표 | 머리 |  /  |---|:---:|
+//	---               가로줄
+//
+// opts.HTML 이면 줄 첫머리가 태그로 시작하는 HTML 블록과 본문 속 태그를 그대로 내보낸다. 결과는 거르지
+// 않으므로 사용자가 쓴 글은 Sanitize 를 거치거나 Safe 를 쓴다.
+func Render(src string, opts Options) string {
+	lines := strings.Split(strings.ReplaceAll(strings.ReplaceAll(src, "\r\n", "\n"), "\t", "    "), "\n")
+	var b strings.Builder
+	r := &renderer{opts: opts, ids: map[string]int{}}
+	r.blocks(&b, lines)
+	return b.String()
+}
+
+// renderer 는 문서 하나를 렌더링하는 동안의 상태다. ids 는 제목 id 가 겹치지 않게 센다.
+type renderer struct {
+	opts Options
+	ids  map[string]int
+}
+
+var (
+	reHeading  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ ]+(.*?))?(?:[ ]+#+)?[ ]*$`)
+	reRule     = regexp.MustCompile(`^ {0,3}((?:\*[ ]*){3,}|(?:-[ ]*){3,}|(?:_[ ]*){3,})$`)
+	reFence    = regexp.MustCompile("^ {0,3}(```+|~~~+)[ ]*([^` ]*)")
+	reBullet   = regexp.MustCompile(`^( {0,3})([-*+])( +|$)`)
+	reOrdered  = regexp.MustCompile(`^( {0,3})(\d{1,9})([.)])( +|$)`)
+	reHTMLOpen = regexp.MustCompile(`^ {0,3}</?[A-Za-z][A-Za-z0-9-]*(\s|/?>|$)|^ {0,3}<!--`)
+	reTableSep = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+func (r *renderer) blocks(b *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+		case reFence.MatchString(line):
+			m := reFence.FindStringSubmatch(line)
+			fence := m[1]
+			var code []string
+			i++
+			for i < len(lines) && !strings.HasPrefix(strings.TrimLeft(lines[i], " "), fence) {
+				code = append(code, lines[i])
+				i++
+			}
+			i++ // 닫는 펜스
+			b.WriteString("<pre><code")
+			if m[2] != "" {
+				b.WriteString(` class="language-` + html.EscapeString(m[2]) + `"`)
+			}
+			b.WriteString(">")
+			if r.opts.Highlight && m[2] != "" {
+				b.WriteString(highlight(strings.Join(code, "\n")+"\n", m[2]))
+			} else {
+				for _, c := range code {
+					b.WriteString(html.EscapeString(c) + "\n")
+				}
+			}
+			b.WriteString("</code></pre>\n")
+		case strings.HasPrefix(line, "    "):
+			var code []string
+			for i < len(lines) && (strings.HasPrefix(lines[i], "    ") || strings.TrimSpace(lines[i]) == "") {
+				code = append(code, strings.TrimPrefix(lines[i], "    "))
+				i++
+			}
+			for len(code) > 0 && strings.TrimSpace(code[len(code)-1]) == "" {
+				code = code[:len(code)-1]
+			}
+			b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "\n</code></pre>\n")
+		case reHeading.MatchString(line):
+			m := reHeading.FindStringSubmatch(line)
+			r.heading(b, len(m[1]), m[2])
+			i++
+		case reRule.MatchString(line):
+			b.WriteString("<hr>\n")
+			i++
+		case strings.HasPrefix(strings.TrimLeft(line, " "), ">"):
+			var quote []string
+			for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+				l := strings.TrimLeft(lines[i], " ")
+				l = strings.TrimPrefix(l, ">")
+				quote = append(quote, strings.TrimPrefix(l, " "))
+				i++
+			}
+			b.WriteString("<blockquote>\n")
+			r.blocks(b, quote)
+			b.WriteString("</blockquote>\n")
+		case reBullet.MatchString(line) || reOrdered.MatchString(line):
+			i = r.list(b, lines, i)
+		case r.opts.HTML && reHTMLOpen.MatchString(line):
+			for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+				b.WriteString(lines[i] + "\n")
+				i++
+			}
+		case r.opts.Tables && strings.Contains(line, "|") && i+1 < len(lines) && reTableSep.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-"):
+			i = r.table(b, lines, i)
+		default:
+			var para []string
+			for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+				l := lines[i]
+				if len(para) > 0 {
+					// 문단 바로 아래의 === 와 --- 는 제목이다.
+					if t := strings.TrimSpace(l); strings.Trim(t, "=") == "" || strings.Trim(t, "-") == "" {
+						level := 1
+						if t[0] == '-' {
+							level = 2
+						}
+						r.heading(b, level, strings.Join(para, " "))
+						para = nil
+						i++
+						break
+					}
+					if reHeading.MatchString(l) || reFence.MatchString(l) || reRule.MatchString(l) ||
+						reBullet.MatchString(l) || strings.HasPrefix(strings.TrimLeft(l, " "), ">") {
+						break
+					}
+				}
+				para = append(para, strings.TrimLeft(l, " "))
+				i++
+			}
+			if len(para) > 0 {
+				b.WriteString("<p>" + r.inlineLines(para) + "</p>\n")
+			}
+		}
+	}
+}
+
+func (r *renderer) heading(b *strings.Builder, level int, text string) {
+	id := slug(text)
+	if n := r.ids[id]; n > 0 {
+		r.ids[id] = n + 1
+		id += "-" + strconv.Itoa(n)
+	} else {
+		r.ids[id] = 1
+	}
+	tag := "h" + strconv.Itoa(level)
+	b.WriteString("<" + tag + ` id="` + id + `">` + r.inline(strings.TrimSpace(text)) + "</" + tag + ">\n")
+}
+
+// slug 는 제목으로 id 를 만든다. 글자와 숫자만 남기고 공백은 - 로 바꾼다.
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(stripTags(s)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			dash = false
+		case (r == ' ' || r == '-' || r == '_') && !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+var reTag = regexp.MustCompile(`<[^>]*>|[*_~` + "`" + `]`)
+
+func stripTags(s string) string { return reTag.ReplaceAllString(s, "") }
+
+// Title 은 src 의 첫 # 제목을 태그와 강조 기호 없이 돌려준다. 없으면 빈 문자열이다.
+func Title(src string) string {
+	for _, line := range strings.Split(src, "\n") {
+		if m := reHeading.FindStringSubmatch(line); m != nil && len(m[1]) == 1 {
+			return stripTags(strings.TrimSpace(m[2]))
+		}
+	}
+	return ""
+}
+
+// list 는 lines[i] 부터 같은 종류의 목록을 쓰고 다음 줄 번호를 돌려준다.
+func (r *renderer) list(b *strings.Builder, lines []string, i int) int {
+	ordered := !reBullet.MatchString(lines[i])
+	marker := func(l string) (indent int, ok bool) {
+		if ordered {
+			if m := reOrdered.FindStringSubmatch(l); m != nil {
+				return len(m[0]), true
+			}
+		} else if m := reBullet.FindStringSubmatch(l); m != nil {
+			return len(m[0]), true
+		}
+		return 0, false
+	}
+	if ordered {
+		start := reOrdered.FindStringSubmatch(lines[i])[2]
+		if n, _ := strconv.Atoi(start); n != 1 {
+			b.WriteString(`<ol start="` + strconv.Itoa(n) + `">` + "\n")
+		} else {
+			b.WriteString("<ol>\n")
+		}
+	} else {
+		b.WriteString("<ul>\n")
+	}
+	var items [][]string
+	loose := false
+	for i < len(lines) {
+		indent, ok := marker(lines[i])
+		if !ok {
+			break
+		}
+		item := []string{lines[i][indent:]}
+		i++
+		for i < len(lines) {
+			l := lines[i]
+			if strings.TrimSpace(l) == "" {
+				// 빈 줄 뒤에 들여 쓴 줄이 오면 같은 항목이고, 목록은 느슨해진다.
+				if i+1 < len(lines) && leading(lines[i+1]) >= 2 {
+					item = append(item, "")
+					loose = true
+					i++
+					continue
+				}
+				if i+1 < len(lines) {
+					if _, next := marker(lines[i+1]); next {
+						loose = true
+						i++
+					}
+				}
+				break
+			}
+			if _, next := marker(l); next && leading(l) < 2 {
+				break
+			}
+			if leading(l) == 0 && (reHeading.MatchString(l) || reRule.MatchString(l) || reBullet.MatchString(l) || reOrdered.MatchString(l)) {
+				break
+			}
+			item = append(item, dedent(l, indent))
+			i++
+		}
+		items = append(items, item)
+	}
+	for _, item := range items {
+		b.WriteString("<li>")
+		var inner strings.Builder
+		r.blocks(&inner, item)
+		out := inner.String()
+		if !loose {
+			// 촘촘한 목록은 항목의 첫 문단을 <p> 로 감싸지 않는다.
+			if strings.HasPrefix(out, "<p>") {
+				if end := strings.Index(out, "</p>\n"); end >= 0 {
+					out = out[3:end] + out[end+5:]
+				}
+			}
+		}
+		b.WriteString(strings.TrimSuffix(out, "\n"))
+		b.WriteString("</li>\n")
+	}
+	if ordered {
+		b.WriteString("</ol>\n")
+	} else {
+		b.WriteString("</ul>\n")
+	}
+	return i
+}
+
+func leading(s string) int { return len(s) - len(strings.TrimLeft(s, " ")) }
+
+// dedent 는 목록 항목 안의 줄에서 n 칸까지 들여쓰기를 없앤다.
+func dedent(s string, n int) string {
+	return s[min(leading(s), n):]
+}
+
+// table 은 lines[i] 에서 시작하는 GitHub 형식의 표를 쓰고 다음 줄 번호를 돌려준다.
+func (r *renderer) table(b *strings.Builder, lines []string, i int) int {
+	head := cells(lines[i])
+	var align []string
+	for _, c := range cells(lines[i+1]) {
+		switch {
+		case strings.HasPrefix(c, ":") && strings.HasSuffix(c, ":"):
+			align = append(align, "center")
+		case strings.HasSuffix(c, ":"):
+			align = append(align, "right")
+		case strings.HasPrefix(c, ":"):
+			align = append(align, "left")
+		default:
+			align = append(align, "")
+		}
+	}
+	row := func(tag string, cs []string) {
+		b.WriteString("<tr>")
+		for j := range head {
+			c := ""
+			if j < len(cs) {
+				c = cs[j]
+			}
+			b.WriteString("<" + tag)
+			if j < len(align) && align[j] != "" {
+				b.WriteString(` style="text-align: ` + align[j] + `"`)
+			}
+			b.WriteString(">" + r.inline(c) + "</" + tag + ">")
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("<table>\n<thead>\n")
+	row("th", head)
+	b.WriteString("</thead>\n<tbody>\n")
+	i += 2
+	for i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != "" {
+		row("td", cells(lines[i]))
+		i++
+	}
+	b.WriteString("</tbody>\n</table>\n")
+	return i
+}
+
+func cells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(strings.TrimSuffix(line, "|"), "|")
+	var out []string
+	for _, c := range strings.Split(strings.ReplaceAll(line, `\|`, "\x00"), "|") {
+		out = append(out, strings.ReplaceAll(strings.TrimSpace(c), "\x00", "|"))
+	}
+	return out
+}
+
+// inlineLines 는 문단의 줄들을 잇는다. 두 칸 이상의 공백이나 \ 로 끝난 줄은 <br> 로 끊는다.
+func (r *renderer) inlineLines(lines []string) string {
+	var parts []string
+	for i, l := range lines {
+		last := i == len(lines)-1
+		switch {
+		case !last && strings.HasSuffix(l, "  "):
+			parts = append(parts, r.inline(strings.TrimRight(l, " "))+"<br>")
+		case !last && strings.HasSuffix(l, `\`):
+			parts = append(parts, r.inline(strings.TrimSuffix(l, `\`))+"<br>")
+		default:
+			parts = append(parts, r.inline(strings.TrimRight(l, " ")))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+var (
+	reAutolink   = regexp.MustCompile(`^<((?:https?|mailto):[^\s<>]+)>`)
+	reInlineHTML = regexp.MustCompile(`^(?:<[A-Za-z][A-Za-z0-9-]*(?:\s+[A-Za-z_:][A-Za-z0-9_.:-]*(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?)*\s*/?>|</[A-Za-z][A-Za-z0-9-]*\s*>|<!--.*?-->)`)
+	reLinkDest   = regexp.MustCompile(`^\(\s*<?([^\s()<>]*(?:\([^\s()]*\)[^\s()<>]*)*)>?(?:\s+"([^"]*)")?\s*\)`)
+)
+
+// inline 은 한 덩어리의 본문을 HTML 로 바꾼다.
+func (r *renderer) inline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_{}[]()#+-.!|~<>\"'", s[i+1]) >= 0:
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+		case c == '`':
+			n := run(s[i:], '`')
+			if end := strings.Index(s[i+n:], s[i:i+n]); end >= 0 {
+				code := s[i+n : i+n+end]
+				if t := strings.TrimSpace(code); t != "" && len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' {
+					code = code[1 : len(code)-1]
+				}
+				b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i += n + end + n
+			} else {
+				b.WriteString(s[i : i+n])
+				i += n
+			}
+		case c == '!' && strings.HasPrefix(s[i+1:], "["):
+			if alt, dest, title, n, ok := link(s[i+1:]); ok {
+				b.WriteString(`<img src="` + html.EscapeString(dest) + `" alt="` + html.EscapeString(stripTags(alt)) + `"`)
+				if title != "" {
+					b.WriteString(` title="` + html.EscapeString(title) + `"`)
+				}
+				b.WriteString(">")
+				i += 1 + n
+			} else {
+				b.WriteString("!")
+				i++
+			}
+		case c == '[':
+			if text, dest, title, n, ok := link(s[i:]); ok {
+				b.WriteString(`<a href="` + html.EscapeString(dest) + `"`)
+				if title != "" {
+					b.WriteString(` title="` + html.EscapeString(title) + `"`)
+				}
+				b.WriteString(">" + r.inline(text) + "</a>")
+				i += n
+			} else {
+				b.WriteString("[")
+				i++
+			}
+		case c == '<':
+			if m := reAutolink.FindStringSubmatch(s[i:]); m != nil {
+				u := html.EscapeString(m[1])
+				b.WriteString(`<a href="` + u + `">` + strings.TrimPrefix(u, "mailto:") + "</a>")
+				i += len(m[0])
+			} else if m := reInlineHTML.FindString(s[i:]); r.opts.HTML && m != "" {
+				b.WriteString(m)
+				i += len(m)
+			} else {
+				b.WriteString("&lt;")
+				i++
+			}
+		case c == '*' || c == '_' || c == '~':
+			n := run(s[i:], c)
+			if c == '~' && (n != 2 || !r.opts.Strikethrough) {
+				b.WriteString(s[i : i+n])
+				i += n
+				break
+			}
+			if inner, width, ok := r.emphasis(s, i, c, min(n, 3)); ok {
+				b.WriteString(inner)
+				i += width
+			} else {
+				b.WriteString(s[i : i+n])
+				i += n
+			}
+		case c == '&':
+			// 이미 쓴 엔티티(&amp; &#39;)는 두고 나머지 & 는 이스케이프한다.
+			if end := strings.IndexByte(s[i:], ';'); end > 1 && end < 10 && isEntity(s[i+1:i+end]) {
+				b.WriteString(s[i : i+end+1])
+				i += end + 1
+			} else {
+				b.WriteString("&amp;")
+				i++
+			}
+		case c == '>':
+			b.WriteString("&gt;")
+			i++
+		case c == '"':
+			b.WriteString("&#34;")
+			i++
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func run(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+func isEntity(s string) bool {
+	if strings.HasPrefix(s, "#") {
+		_, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimPrefix(s[1:], "x"), "X"), 16, 32)
+		return err == nil
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// emphasis 는 s[i:] 의 n 개짜리 구분자와 짝이 되는 닫는 구분자를 찾아 <em>, <strong>, <del> 로 감싼다.
+// 단어 안의 _ 는 강조가 아니다.
+func (r *renderer) emphasis(s string, i int, c byte, n int) (string, int, bool) {
+	open := strings.Repeat(string(c), n)
+	body := s[i+n:]
+	if body == "" || body[0] == ' ' || (c == '_' && i > 0 && isWord(s[i-1])) {
+		return "", 0, false
+	}
+	for j := 0; j < len(body); j++ {
+		switch body[j] {
+		case '`':
+			// 코드 안의 구분자는 건너뛴다.
+			if end := strings.IndexByte(body[j+1:], '`'); end >= 0 {
+				j += end + 1
+			}
+			continue
+		case '\\':
+			j++
+			continue
+		}
+		if j == 0 || !strings.HasPrefix(body[j:], open) || body[j-1] == ' ' {
+			continue
+		}
+		after := i + n + j + n
+		if c == '_' && after < len(s) && isWord(s[after]) {
+			continue
+		}
+		if after < len(s) && s[after] == c && n < 3 {
+			// 더 긴 구분자의 일부다. (예: **굵게 *기울임***)
+			if k := run(s[i+n+j:], c); k > n && k != n*2 {
+				continue
+			}
+		}
+		inner := r.inline(body[:j])
+		switch {
+		case c == '~':
+			inner = "<del>" + inner + "</del>"
+		case n == 1:
+			inner = "<em>" + inner + "</em>"
+		case n == 2:
+			inner = "<strong>" + inner + "</strong>"
+		default:
+			inner = "<em><strong>" + inner + "</strong></em>"
+		}
+		return inner, n + j + n, true
+	}
+	if n > 1 {
+		// 짝이 맞는 짧은 구분자가 있는지 다시 본다. (예: **a* → *<em>a</em>)
+		if inner, width, ok := r.emphasis(s, i+1, c, n-1); ok {
+			return string(c) + inner, width + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+func isWord(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// link 는 s 가 [텍스트](주소 "제목") 로 시작하면 그 부분을 읽는다. n 은 읽은 길이다.
+func link(s string) (text, dest, title string, n int, ok bool) {
+	depth := 0
+	for j := 0; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			if end := strings.IndexByte(s[j+1:], '`'); end >= 0 {
+				j += end + 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				m := reLinkDest.FindStringSubmatch(s[j+1:])
+				if m == nil {
+					return "", "", "", 0, false
+				}
+				return s[1:j], m[1], m[2], j + 1 + len(m[0]), true
+			}
+		}
+	}
+	return "", "", "", 0, false
+}
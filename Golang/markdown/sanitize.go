@@ -0,0 +1,171 @@
+package markdown
+
+import (
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// allowed 는 남길 태그와 그 태그에서 남길 속성이다. globalAttrs 는 모든 태그에서 남긴다.
+var allowed = map[string][]string{
+	"a": {"href"}, "img": {"src", "alt", "width", "height"},
+	"p": nil, "br": nil, "hr": nil, "blockquote": nil, "pre": nil, "code": {"class"}, "span": {"class"},
+	"h1": {"id"}, "h2": {"id"}, "h3": {"id"}, "h4": {"id"}, "h5": {"id"}, "h6": {"id"},
+	"ul": nil, "ol": {"start"}, "li": nil, "dl": nil, "dt": nil, "dd": nil,
+	"em": nil, "strong": nil, "b": nil, "i": nil, "u": nil, "s": nil, "del": nil, "ins": nil, "mark": nil,
+	"sub": nil, "sup": nil, "small": nil, "kbd": nil, "abbr": nil, "q": nil, "cite": nil,
+	"details": nil, "summary": nil, "figure": nil, "figcaption": nil, "div": nil,
+	"table": nil, "thead": nil, "tbody": nil, "tr": nil, "th": {"style", "colspan", "rowspan"}, "td": {"style", "colspan", "rowspan"},
+}
+
+var globalAttrs = []string{"title", "lang", "dir"}
+
+// dropped 는 내용까지 통째로 버리는 태그다. 나머지 허용하지 않은 태그는 태그만 버리고 글자는 남긴다.
+var dropped = map[string]bool{
+	"script": true, "style": true, "iframe": true, "frame": true, "frameset": true, "object": true, "embed": true,
+	"noscript": true, "template": true, "textarea": true, "select": true, "svg": true, "math": true,
+	"title": true, "head": true, "xmp": true, "noembed": true, "noframes": true, "plaintext": true,
+}
+
+var voids = map[string]bool{"br": true, "hr": true, "img": true}
+
+var (
+	reClass = regexp.MustCompile(`^(?:language-[\w+#.-]+|hl-(?:keyword|string|comment|number))$`)
+	reAlign = regexp.MustCompile(`^text-align:\s*(?:left|right|center);?$`)
+	reDigit = regexp.MustCompile(`^\d{1,4}$`)
+)
+
+// Sanitize 는 s 에서 허용 목록에 없는 태그와 속성을 없앤다. 스크립트, 이벤트 속성(on*), javascript: 같은
+// 주소와 주석은 남지 않고, 닫히지 않은 태그는 끝에서 닫는다. 링크에는 rel="nofollow noopener" 가 붙는다.
+func Sanitize(s string) string {
+	var b strings.Builder
+	z := xhtml.NewTokenizer(strings.NewReader(s))
+	var open []string
+	skip, depth := "", 0
+	for {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			break
+		}
+		t := z.Token()
+		if skip != "" {
+			// 버리는 태그 안에서는 같은 이름의 태그 깊이만 센다.
+			switch {
+			case tt == xhtml.StartTagToken && t.Data == skip:
+				depth++
+			case tt == xhtml.EndTagToken && t.Data == skip:
+				if depth--; depth == 0 {
+					skip = ""
+				}
+			}
+			continue
+		}
+		switch tt {
+		case xhtml.TextToken:
+			b.WriteString(html.EscapeString(t.Data))
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			if dropped[t.Data] {
+				if tt == xhtml.StartTagToken && !voids[t.Data] {
+					skip, depth = t.Data, 1
+				}
+				continue
+			}
+			attrs, ok := allowed[t.Data]
+			if !ok {
+				continue
+			}
+			b.WriteString("<" + t.Data)
+			for _, a := range t.Attr {
+				if a.Namespace != "" || !(slices.Contains(attrs, a.Key) || slices.Contains(globalAttrs, a.Key)) {
+					continue
+				}
+				v, ok := attrValue(t.Data, a.Key, a.Val)
+				if !ok {
+					continue
+				}
+				b.WriteString(" " + a.Key + `="` + html.EscapeString(v) + `"`)
+			}
+			if t.Data == "a" {
+				b.WriteString(` rel="nofollow noopener"`)
+			}
+			b.WriteString(">")
+			if !voids[t.Data] && tt == xhtml.StartTagToken {
+				open = append(open, t.Data)
+			}
+		case xhtml.EndTagToken:
+			// 열린 적 없는 닫는 태그는 버리고, 사이에 닫히지 않은 태그는 함께 닫는다.
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != t.Data {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return b.String()
+}
+
+// attrValue 는 속성 값을 검사한다. 거짓이면 속성을 버린다.
+func attrValue(tag, key, val string) (string, bool) {
+	switch key {
+	case "href", "src":
+		return val, safeURL(val, tag == "img")
+	case "class":
+		var keep []string
+		for _, c := range strings.Fields(val) {
+			if reClass.MatchString(c) {
+				keep = append(keep, c)
+			}
+		}
+		return strings.Join(keep, " "), len(keep) > 0
+	case "style":
+		v := strings.TrimSpace(val)
+		return v, reAlign.MatchString(v)
+	case "start", "width", "height", "colspan", "rowspan":
+		return val, reDigit.MatchString(val)
+	}
+	return val, true
+}
+
+// safeURL 은 http, https, mailto 와 상대 주소만 받는다. 그림은 mailto 를 받지 않는다.
+func safeURL(s string, image bool) bool {
+	s = strings.TrimSpace(s)
+	if strings.ContainsFunc(s, func(r rune) bool { return r < 0x20 || r == 0x7f }) {
+		return false
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https":
+		return true
+	case "mailto":
+		return !image
+	}
+	return false
+}
+
+// Safe 는 믿을 수 없는 src 를 opts 로 렌더링하고 걸러서 템플릿에 바로 넣을 수 있는 HTML 로 돌려준다.
+func Safe(src string, opts Options) template.HTML {
+	return template.HTML(Sanitize(Render(src, opts)))
+}
+
+// FuncMap 은 저장된 Markdown 을 템플릿에서 렌더링하는 "markdown" 함수다. 결과는 Safe 와 같이 걸러진다.
+//
+//	{{markdown .Post.Body}}
+func FuncMap(opts Options) template.FuncMap {
+	return template.FuncMap{"markdown": func(src string) template.HTML { return Safe(src, opts) }}
+}
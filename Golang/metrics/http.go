@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/middleware"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+var (
+	httpRequests = NewCounterVec("http_requests_total",
+		"Total number of HTTP requests by route pattern.", "method", "route", "status")
+	httpDuration = NewHistogramVec("http_request_duration_seconds",
+		"HTTP request latency by route pattern.", nil, "method", "route")
+	httpInFlight = NewGaugeVec("http_requests_in_flight",
+		"Number of HTTP requests currently being served.")
+)
+
+// UnmatchedRoute 는 어떤 라우트와도 일치하지 않은 요청의 route 레이블 값이다.
+const UnmatchedRoute = "unmatched"
+
+// Middleware 는 요청 수, 지연 시간, 진행 중인 요청 수를 기록한다.
+// route 레이블은 실제 URL 이 아니라 라우트 패턴을 사용해 카디널리티 폭발을 막는다.
+func Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			httpInFlight.Add(1)
+			defer httpInFlight.Add(-1)
+
+			sw := middleware.NewStatusWriter(w)
+			next.ServeHTTP(sw, r)
+
+			route := router.Pattern(r)
+			if route == "" {
+				route = UnmatchedRoute
+			}
+			httpRequests.Inc(r.Method, route, strconv.Itoa(sw.Code()))
+			httpDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+		})
+	}
+}
+
+// Handler 는 Default Registry 를 노출하는 /metrics 핸들러다.
+func Handler() http.Handler { return Default.Handler() }
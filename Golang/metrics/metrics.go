@@ -0,0 +1,301 @@
+// Package metrics 는 Prometheus 텍스트 형식으로 노출되는 카운터, 게이지, 히스토그램을 제공한다.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Collector 는 자신의 메트릭을 Prometheus 텍스트 형식으로 쓴다.
+type Collector interface {
+	Collect(w io.Writer)
+}
+
+// Registry 는 등록된 Collector 들을 모아 노출한다.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+// NewRegistry 는 비어 있는 Registry 를 만든다.
+func NewRegistry() *Registry { return &Registry{} }
+
+// Default 는 패키지 수준 헬퍼가 사용하는 기본 Registry 다.
+var Default = NewRegistry()
+
+// Register 는 Collector 를 등록한다.
+func (r *Registry) Register(c Collector) {
+	r.mu.Lock()
+	r.collectors = append(r.collectors, c)
+	r.mu.Unlock()
+}
+
+// Write 는 모든 메트릭을 w 에 쓴다.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	cs := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+	for _, c := range cs {
+		c.Collect(w)
+	}
+}
+
+// Handler 는 /metrics 엔드포인트 핸들러다.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// vec 은 레이블 값 조합별 시계열을 보관하는 공통 구조다.
+type vec[T any] struct {
+	name, help, typ string
+	labels          []string
+	mu              sync.Mutex
+	series          map[string]*T
+	values          map[string][]string
+	newT            func() *T
+}
+
+func newVec[T any](name, help, typ string, labels []string, newT func() *T) *vec[T] {
+	return &vec[T]{
+		name: name, help: help, typ: typ, labels: labels,
+		series: map[string]*T{}, values: map[string][]string{}, newT: newT,
+	}
+}
+
+// get 은 레이블 값에 해당하는 시계열을 찾거나 새로 만든다.
+func (v *vec[T]) get(lvs []string) *T {
+	if len(lvs) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(lvs)))
+	}
+	key := strings.Join(lvs, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = v.newT()
+		v.series[key] = s
+		v.values[key] = append([]string(nil), lvs...)
+	}
+	return s
+}
+
+// each 는 레이블 문자열 순서대로 시계열을 방문한다.
+func (v *vec[T]) each(fn func(labels string, s *T)) {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	type item struct {
+		labels string
+		s      *T
+	}
+	items := make([]item, len(keys))
+	for i, k := range keys {
+		items[i] = item{formatLabels(v.labels, v.values[k]), v.series[k]}
+	}
+	v.mu.Unlock()
+	for _, it := range items {
+		fn(it.labels, it.s)
+	}
+}
+
+func (v *vec[T]) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.typ)
+}
+
+// Forget 는 더 이상 필요 없는 레이블 조합을 제거한다.
+func (v *vec[T]) Forget(lvs ...string) {
+	key := strings.Join(lvs, "\xff")
+	v.mu.Lock()
+	delete(v.series, key)
+	delete(v.values, key)
+	v.mu.Unlock()
+}
+
+// float 은 원자적으로 갱신되는 float64 값이다.
+type float struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (f *float) add(d float64) {
+	f.mu.Lock()
+	f.v += d
+	f.mu.Unlock()
+}
+
+func (f *float) set(v float64) {
+	f.mu.Lock()
+	f.v = v
+	f.mu.Unlock()
+}
+
+func (f *float) load() float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.v
+}
+
+// CounterVec 은 증가만 하는 카운터다.
+type CounterVec struct{ *vec[float] }
+
+// NewCounterVec 은 카운터를 만들어 Default 에 등록한다.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec(name, help, "counter", labels, func() *float { return &float{} })}
+	Default.Register(c)
+	return c
+}
+
+// Inc 는 카운터를 1 증가시킨다.
+func (c *CounterVec) Inc(lvs ...string) { c.get(lvs).add(1) }
+
+// Add 는 카운터를 d 만큼 증가시킨다. d 는 0 이상이어야 한다.
+func (c *CounterVec) Add(d float64, lvs ...string) {
+	if d < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.get(lvs).add(d)
+}
+
+// Collect 는 Collector 구현이다.
+func (c *CounterVec) Collect(w io.Writer) {
+	c.header(w)
+	c.each(func(l string, s *float) { fmt.Fprintf(w, "%s%s %s\n", c.name, l, formatFloat(s.load())) })
+}
+
+// GaugeVec 은 증가와 감소가 모두 가능한 값이다.
+type GaugeVec struct{ *vec[float] }
+
+// NewGaugeVec 은 게이지를 만들어 Default 에 등록한다.
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{newVec(name, help, "gauge", labels, func() *float { return &float{} })}
+	Default.Register(g)
+	return g
+}
+
+// Set 은 게이지 값을 설정한다.
+func (g *GaugeVec) Set(v float64, lvs ...string) { g.get(lvs).set(v) }
+
+// Add 는 게이지에 d 를 더한다. (음수 가능)
+func (g *GaugeVec) Add(d float64, lvs ...string) { g.get(lvs).add(d) }
+
+// Collect 는 Collector 구현이다.
+func (g *GaugeVec) Collect(w io.Writer) {
+	g.header(w)
+	g.each(func(l string, s *float) { fmt.Fprintf(w, "%s%s %s\n", g.name, l, formatFloat(s.load())) })
+}
+
+// DefBuckets 는 HTTP 지연 시간(초)에 알맞은 기본 히스토그램 버킷이다.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// histogram 은 하나의 레이블 조합에 대한 버킷 카운트다.
+type histogram struct {
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// HistogramVec 은 값의 분포를 버킷별로 센다.
+type HistogramVec struct {
+	*vec[histogram]
+	buckets []float64
+}
+
+// NewHistogramVec 은 히스토그램을 만들어 Default 에 등록한다. buckets 가 nil 이면 DefBuckets 를 사용한다.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	h := &HistogramVec{
+		vec:     newVec(name, help, "histogram", labels, func() *histogram { return &histogram{counts: make([]uint64, len(b))} }),
+		buckets: b,
+	}
+	Default.Register(h)
+	return h
+}
+
+// Observe 는 값 v 를 기록한다.
+func (h *HistogramVec) Observe(v float64, lvs ...string) {
+	s := h.get(lvs)
+	s.mu.Lock()
+	for i, ub := range h.buckets {
+		if v <= ub {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+	s.mu.Unlock()
+}
+
+// Collect 는 Collector 구현이다.
+func (h *HistogramVec) Collect(w io.Writer) {
+	h.header(w)
+	h.each(func(l string, s *histogram) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, ub := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, withLabel(l, "le", formatFloat(ub)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, withLabel(l, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, l, formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, l, s.count)
+	})
+}
+
+// formatLabels 는 {a="x",b="y"} 형태의 레이블 문자열을 만든다.
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(n)
+		b.WriteString(`="`)
+		b.WriteString(escape(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// withLabel 은 기존 레이블 문자열에 레이블 하나를 덧붙인다.
+func withLabel(labels, name, value string) string {
+	kv := name + `="` + value + `"`
+	if labels == "" {
+		return "{" + kv + "}"
+	}
+	return labels[:len(labels)-1] + "," + kv + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string { return labelEscaper.Replace(s) }
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/realip"
+	"github.com/hgsong234/_stack/Golang/router"
+	"github.com/hgsong234/_stack/Golang/timing"
+)
+
+// AccessEntry 는 요청 하나에 대한 접근 로그 항목이다.
+type AccessEntry struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	LatencyMS float64   `json:"latency_ms"`
+	Bytes     int64     `json:"bytes"`
+	RemoteIP  string    `json:"remote_ip"`
+	UserAgent string    `json:"user_agent"`
+	RequestID string    `json:"request_id,omitempty"`
+	// Timing 은 단계별 처리 시간(밀리초)이다. timing.Middleware 안쪽에서만 채워진다.
+	Timing map[string]float64 `json:"timing,omitempty"`
+}
+
+// AccessLogConfig 는 접근 로그 설정이다.
+type AccessLogConfig struct {
+	// Out 이 있으면 항목을 JSON 한 줄로 Out 에 쓰고, 없으면 Logger 에 info 레벨("request")로 쓴다.
+	// 개발 모드처럼 로그를 사람이 읽는 형식으로 볼 때 Logger 를 쓴다.
+	Out    io.Writer
+	Logger logging.Logger
+	// SampleRate 는 기록할 성공 응답(상태 400 미만)의 비율(0~1)이다. 에러 응답과 느린 요청은 항상 기록한다.
+	SampleRate float64
+	// SlowThreshold 보다 오래 걸린 요청은 요청 헤더와 시간 분석을 담아 요청 로거(logging.From)에
+	// warn 으로 한 번 더 기록한다. 0 이면 끈다.
+	SlowThreshold time.Duration
+}
+
+// slowRedacted 는 느린 요청 로그에서 값을 가리는 헤더다.
+var slowRedacted = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key", "X-Csrf-Token"}
+
+// AccessLog 는 요청마다 접근 로그를 기록하는 미들웨어를 만든다.
+// 요청 ID 를 기록하려면 RequestID 미들웨어 뒤에 등록한다.
+func AccessLog(cfg AccessLogConfig) router.Middleware {
+	var mu sync.Mutex
+	var enc *json.Encoder
+	if cfg.Out != nil {
+		enc = json.NewEncoder(cfg.Out)
+	}
+	write := func(e AccessEntry, phases []timing.Metric) {
+		if enc == nil {
+			args := []any{"method", e.Method, "path", e.Path, "status", e.Status, "latency_ms", e.LatencyMS, "bytes", e.Bytes, "remote_ip", e.RemoteIP}
+			if e.RequestID != "" {
+				args = append(args, "request_id", e.RequestID)
+			}
+			for _, m := range phases {
+				args = append(args, "timing."+m.Name, ms(m.Dur))
+			}
+			cfg.Logger.Info("request", args...)
+			return
+		}
+		mu.Lock()
+		enc.Encode(e)
+		mu.Unlock()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t := &stamps{start: time.Now()}
+			sw := NewStatusWriter(w)
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = &timedBody{ReadCloser: r.Body, t: t}
+			}
+			next.ServeHTTP(sw, r)
+			t.end = time.Now()
+
+			total := t.end.Sub(t.start)
+			slow := cfg.SlowThreshold > 0 && total >= cfg.SlowThreshold
+			if sw.Code() < 400 && !slow && cfg.SampleRate < 1 && rand.Float64() >= cfg.SampleRate {
+				return
+			}
+			write(AccessEntry{
+				Time:      t.start.UTC(),
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Status:    sw.Code(),
+				LatencyMS: ms(total),
+				Bytes:     sw.Bytes,
+				RemoteIP:  realip.Host(r),
+				UserAgent: r.UserAgent(),
+				RequestID: RequestIDFrom(r.Context()),
+				Timing:    timing.From(r.Context()).Millis(),
+			}, timing.From(r.Context()).Metrics())
+			if slow {
+				logSlow(r, sw, t)
+			}
+		})
+	}
+}
+
+// logSlow 는 느린 요청의 상세(요청 헤더, 본문 읽기·첫 바이트·응답 쓰기 시간)를 기록한다.
+func logSlow(r *http.Request, sw *StatusWriter, t *stamps) {
+	header := r.Header.Clone()
+	for _, h := range slowRedacted {
+		if _, ok := header[h]; ok {
+			header[h] = []string{"[REDACTED]"}
+		}
+	}
+	phases := []any{"total_ms", ms(t.end.Sub(t.start))}
+	if !t.bodyDone.IsZero() {
+		phases = append(phases, "read_body_ms", ms(t.bodyDone.Sub(t.start)))
+	}
+	if !sw.WroteAt.IsZero() {
+		phases = append(phases,
+			"first_byte_ms", ms(sw.WroteAt.Sub(t.start)),
+			"write_ms", ms(t.end.Sub(sw.WroteAt)))
+	}
+	for _, m := range timing.From(r.Context()).Metrics() {
+		phases = append(phases, m.Name+"_ms", ms(m.Dur))
+	}
+	logging.From(r.Context()).Warn("slow request",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"query", r.URL.RawQuery,
+		"proto", r.Proto,
+		"host", r.Host,
+		"status", sw.Code(),
+		"bytes_in", t.bodyBytes,
+		"bytes_out", sw.Bytes,
+		"header", header,
+		slog.Group("timing", phases...),
+	)
+}
+
+// stamps 는 요청 하나의 단계별 시각이다. 요청 고루틴에서만 쓴다.
+type stamps struct {
+	start     time.Time
+	bodyDone  time.Time // 마지막으로 본문을 읽은 시각
+	bodyBytes int64
+	end       time.Time
+}
+
+// timedBody 는 요청 본문을 읽은 양과 마지막으로 읽은 시각을 기록한다.
+type timedBody struct {
+	io.ReadCloser
+	t *stamps
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.t.bodyBytes += int64(n)
+	b.t.bodyDone = time.Now()
+	return n, err
+}
+
+func ms(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// 요청 본문 제한 컨텍스트 키
+type bodyLimitKey struct{}
+
+// MaxBodyBytes 는 모든 요청 본문을 n 바이트로 제한하는 전역 미들웨어를 만든다.
+// 제한을 넘으면 본문을 읽을 때 *http.MaxBytesError 가 반환된다. (api.ReadJSON 은 413 으로 응답한다)
+// 업로드처럼 더 큰 본문이 필요한 라우트는 BodyLimit 로 제한을 바꿀 수 있다.
+func MaxBodyBytes(n int64) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+			lb := &limitedBody{rc: r.Body, w: w, contentLength: r.ContentLength}
+			lb.limit.Store(n)
+			r.Body = lb
+			r = r.WithContext(context.WithValue(r.Context(), bodyLimitKey{}, lb))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BodyLimit 는 이 라우트의 본문 제한을 n 바이트로 바꾸는 라우트 미들웨어를 만든다.
+// MaxBodyBytes 가 등록되지 않았으면 새로 제한을 건다.
+func BodyLimit(n int64) router.Middleware {
+	global := MaxBodyBytes(n)
+	return func(next http.Handler) http.Handler {
+		limited := global(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if lb, ok := r.Context().Value(bodyLimitKey{}).(*limitedBody); ok {
+				lb.limit.Store(n)
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitedBody 는 읽은 바이트 수가 limit 을 넘으면 에러를 돌려주는 본문 래퍼다.
+// 라우트 미들웨어가 읽기 전에 제한을 바꿀 수 있도록 limit 을 나중에 읽는다.
+type limitedBody struct {
+	rc            io.ReadCloser
+	w             http.ResponseWriter
+	contentLength int64
+	limit         atomic.Int64
+	n             int64
+	exceeded      bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	limit := b.limit.Load()
+	if b.exceeded || (b.n == 0 && b.contentLength > limit) {
+		return 0, b.tooLarge(limit)
+	}
+	if int64(len(p)) > limit-b.n+1 {
+		p = p[:limit-b.n+1]
+	}
+	n, err := b.rc.Read(p)
+	b.n += int64(n)
+	if b.n > limit {
+		return n - int(b.n-limit), b.tooLarge(limit)
+	}
+	return n, err
+}
+
+// tooLarge 는 제한 초과를 기록하고, 남은 본문을 읽지 않도록 연결을 닫게 한다.
+func (b *limitedBody) tooLarge(limit int64) error {
+	if !b.exceeded {
+		b.exceeded = true
+		b.w.Header().Set("Connection", "close")
+	}
+	return &http.MaxBytesError{Limit: limit}
+}
+
+func (b *limitedBody) Close() error { return b.rc.Close() }
@@ -0,0 +1,268 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// CompressConfig 는 응답 압축 설정이다.
+type CompressConfig struct {
+	// MinSize 보다 작은 응답은 압축하지 않는다. 기본값 1024 바이트.
+	MinSize int
+	// ContentTypes 는 압축할 MIME 타입 목록이다. "text/*" 처럼 접두사 와일드카드를 쓸 수 있다.
+	// 비어 있으면 DefaultCompressTypes 를 사용한다.
+	ContentTypes []string
+	// Level 은 gzip 압축 수준이다. 0 이면 gzip.DefaultCompression.
+	Level int
+}
+
+// DefaultCompressTypes 는 압축 효과가 있는 기본 MIME 타입이다.
+// 이미지·동영상·압축 파일처럼 이미 압축된 형식은 포함하지 않는다.
+var DefaultCompressTypes = []string{
+	"text/*", "application/json", "application/javascript", "application/xml",
+	"application/problem+json", "image/svg+xml", "application/wasm",
+}
+
+var (
+	gzipPool   sync.Pool
+	brotliPool sync.Pool
+)
+
+// Compress 는 Accept-Encoding 에 따라 br 또는 gzip 으로 응답을 압축하는 미들웨어를 만든다.
+func Compress(cfg CompressConfig) router.Middleware {
+	if cfg.MinSize <= 0 {
+		cfg.MinSize = 1024
+	}
+	if len(cfg.ContentTypes) == 0 {
+		cfg.ContentTypes = DefaultCompressTypes
+	}
+	if cfg.Level == 0 {
+		cfg.Level = gzip.DefaultCompression
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			enc := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if enc == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, cfg: &cfg, enc: enc}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding 은 br, gzip 중 클라이언트가 받아들이는 것을 고른다. (br 우선)
+func negotiateEncoding(header string) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		v := 1.0
+		if p := strings.TrimSpace(params); strings.HasPrefix(p, "q=") {
+			// 0~1 밖의 q 값(NaN, Inf 포함)은 잘못된 것이므로 무시한다.
+			if f, err := strconv.ParseFloat(p[2:], 64); err == nil && f >= 0 && f <= 1 {
+				v = f
+			}
+		}
+		q[name] = v
+	}
+	pick := func(enc string) float64 {
+		if v, ok := q[enc]; ok {
+			return v
+		}
+		if v, ok := q["*"]; ok {
+			return v
+		}
+		return 0
+	}
+	br, gz := pick("br"), pick("gzip")
+	switch {
+	case br > 0 && br >= gz:
+		return "br"
+	case gz > 0:
+		return "gzip"
+	}
+	return ""
+}
+
+// compressWriter 는 MinSize 만큼 모일 때까지 버퍼링한 뒤 압축 여부를 결정한다.
+type compressWriter struct {
+	http.ResponseWriter
+	cfg *CompressConfig
+	enc string
+
+	status  int
+	buf     []byte
+	decided bool
+	zw      io.WriteCloser // nil 이면 압축하지 않고 그대로 쓴다.
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	// 1xx 정보 응답(103 Early Hints 등)은 바로 전달하고, 상태와 압축 여부는 뒤따르는 최종 응답으로 정한다.
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+	// 101 은 최종 응답이고 그 뒤의 바이트는 다른 프로토콜이므로 압축하지 않는다.
+	if code == http.StatusSwitchingProtocols && !w.decided {
+		w.decided = true
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.cfg.MinSize {
+			return len(b), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.zw != nil {
+		return w.zw.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// decide 는 버퍼에 모인 내용과 헤더로 압축 여부를 정하고 헤더와 버퍼를 내보낸다.
+func (w *compressWriter) decide() error {
+	w.decided = true
+	h := w.Header()
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if w.shouldCompress() {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.enc)
+		w.zw = w.newEncoder()
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.zw != nil {
+		_, err = w.zw.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	h := w.Header()
+	if len(w.buf) < w.cfg.MinSize {
+		return false
+	}
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	switch w.status {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return false
+	}
+	if strings.Contains(h.Get("Cache-Control"), "no-transform") {
+		return false
+	}
+	mt, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	for _, t := range w.cfg.ContentTypes {
+		if t == mt || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mt, t[:len(t)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *compressWriter) newEncoder() io.WriteCloser {
+	if w.enc == "br" {
+		if bw, ok := brotliPool.Get().(*brotli.Writer); ok {
+			bw.Reset(w.ResponseWriter)
+			return bw
+		}
+		return brotli.NewWriterLevel(w.ResponseWriter, brotli.DefaultCompression)
+	}
+	if gw, ok := gzipPool.Get().(*gzip.Writer); ok {
+		gw.Reset(w.ResponseWriter)
+		return gw
+	}
+	gw, _ := gzip.NewWriterLevel(w.ResponseWriter, w.cfg.Level)
+	return gw
+}
+
+// Flush 는 지금까지의 내용으로 압축 여부를 확정하고 클라이언트로 내보낸다. (스트리밍 응답)
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+	type flusher interface{ Flush() error }
+	if f, ok := w.zw.(flusher); ok {
+		f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close 는 남은 버퍼를 내보내고 압축기를 닫아 풀에 돌려준다.
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if w.status == 0 && len(w.buf) == 0 {
+			// 핸들러가 아무것도 쓰지 않았다.
+			return nil
+		}
+		if err := w.decide(); err != nil {
+			return err
+		}
+	}
+	if w.zw == nil {
+		return nil
+	}
+	err := w.zw.Close()
+	switch zw := w.zw.(type) {
+	case *gzip.Writer:
+		gzipPool.Put(zw)
+	case *brotli.Writer:
+		brotliPool.Put(zw)
+	}
+	w.zw = nil
+	return err
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Hijack 은 압축 없이 내부 연결을 넘긴다.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// DeprecationConfig 는 지원 종료 예정인 API 의 안내 정보다.
+type DeprecationConfig struct {
+	// Since 는 폐기가 결정된 시각이다. (Deprecation 헤더, RFC 9745)
+	Since time.Time
+	// Sunset 은 API 가 제거될 시각이다. 0 이면 보내지 않는다. (Sunset 헤더, RFC 8594)
+	Sunset time.Time
+	// Successor 는 대체 API 의 URL 이다. (Link rel="successor-version")
+	Successor string
+}
+
+// Deprecation 은 폐기 예정 API 응답에 Deprecation, Sunset, Link 헤더를 붙이는 미들웨어를 만든다.
+// 보통 버전 그룹에 건다. (예: v1 := r.Group("/api/v1", middleware.Deprecation(...)))
+func Deprecation(cfg DeprecationConfig) router.Middleware {
+	deprecation := "true"
+	if !cfg.Since.IsZero() {
+		deprecation = "@" + strconv.FormatInt(cfg.Since.Unix(), 10)
+	}
+	var sunset string
+	if !cfg.Sunset.IsZero() {
+		sunset = cfg.Sunset.UTC().Format(http.TimeFormat)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Deprecation", deprecation)
+			if sunset != "" {
+				h.Set("Sunset", sunset)
+			}
+			if cfg.Successor != "" {
+				h.Add("Link", "<"+cfg.Successor+`>; rel="successor-version"`)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
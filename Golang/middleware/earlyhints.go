@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// EarlyHints 는 HTML 페이지 요청에 103 Early Hints 로 Link 헤더를 먼저 보내는 미들웨어다.
+// 브라우저는 핸들러가 본문을 만드는 동안 CSS, 스크립트 같은 정적 자산을 미리 받는다.
+// links 의 각 항목은 Link 헤더 값이다. (예: "</static/app.css>; rel=preload; as=style")
+// HTTP/2 서버 푸시는 주요 브라우저가 지원을 중단했으므로 쓰지 않는다.
+// 다른 미들웨어의 ResponseWriter 래퍼가 103 을 최종 상태로 오인하지 않도록 가장 바깥에 등록한다.
+func EarlyHints(links ...string) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		if len(links) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// 1xx 응답은 HTTP/1.1 이상에서만 보낼 수 있다.
+			if r.Method == http.MethodGet && r.ProtoAtLeast(1, 1) &&
+				strings.Contains(r.Header.Get("Accept"), "text/html") {
+				h := w.Header()
+				for _, l := range links {
+					h.Add("Link", l)
+				}
+				w.WriteHeader(http.StatusEarlyHints)
+				// Link 헤더는 최종 응답에도 남겨 103 을 무시하는 클라이언트도 활용할 수 있게 한다.
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
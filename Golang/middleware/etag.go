@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// maxETagBody 보다 큰 응답은 ETag 없이 그대로 내보낸다.
+const maxETagBody = 4 << 20
+
+// ETag 는 GET/HEAD 의 200 응답 본문으로 강한 ETag 를 만들고, 요청의 If-None-Match 나
+// If-Modified-Since 가 일치하면 본문 없이 304 로 응답하는 미들웨어다.
+// 핸들러가 ETag 를 직접 설정했으면 그 값을 사용한다. 본문을 버퍼링하므로 스트리밍 응답에는 쓰지 않는다.
+func ETag() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ew := &etagWriter{ResponseWriter: w}
+			next.ServeHTTP(ew, r)
+			ew.finish(r)
+		})
+	}
+}
+
+// CheckModified 는 etag 와 modTime 으로 응답 헤더를 설정하고, 요청의 조건과 일치하면 304 를 쓴다.
+// true 를 돌려주면 핸들러는 본문을 쓰지 않고 끝내야 한다. 버전이나 수정 시각을 싸게 알 수 있는
+// 핸들러가 본문을 만들기 전에 호출한다. 빈 etag 와 영(zero) modTime 은 무시한다.
+//
+//	if middleware.CheckModified(w, r, strconv.Quote(version), updatedAt) {
+//		return
+//	}
+func CheckModified(w http.ResponseWriter, r *http.Request, etag string, modTime time.Time) bool {
+	h := w.Header()
+	if etag != "" {
+		h.Set("ETag", etag)
+	}
+	if !modTime.IsZero() {
+		h.Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+	if (r.Method != http.MethodGet && r.Method != http.MethodHead) || !notModified(r, etag, modTime) {
+		return false
+	}
+	writeNotModified(w)
+	return true
+}
+
+// notModified 는 조건부 요청이 현재 표현과 일치하는지 확인한다.
+// If-None-Match 가 있으면 If-Modified-Since 는 보지 않는다. (RFC 9110 13.2.2)
+func notModified(r *http.Request, etag string, modTime time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etag != "" && etagMatch(inm, etag)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modTime.IsZero() {
+		t, err := http.ParseTime(ims)
+		// HTTP 날짜는 초 단위이므로 modTime 도 초 단위로 비교한다.
+		return err == nil && !modTime.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// etagMatch 는 If-None-Match 목록에 etag 가 있는지 약한 비교로 확인한다.
+func etagMatch(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range strings.Split(list, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeNotModified 는 304 를 쓴다. 본문을 설명하는 헤더는 지운다.
+func writeNotModified(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	h.Del("Content-Encoding")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// etagWriter 는 200 응답을 버퍼링했다가 finish 에서 ETag 와 함께 내보낸다.
+// 200 이 아니거나 maxETagBody 를 넘으면 그때부터 그대로 흘려보낸다.
+type etagWriter struct {
+	http.ResponseWriter
+	status      int
+	buf         bytes.Buffer
+	passthrough bool
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = code
+	if code != http.StatusOK {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.buf.Len()+len(b) > maxETagBody {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(w.status)
+		if _, err := w.ResponseWriter.Write(w.buf.Bytes()); err != nil {
+			return 0, err
+		}
+		w.buf.Reset()
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+// finish 는 버퍼링한 응답에 ETag 를 붙여 쓰거나, 조건이 일치하면 304 를 쓴다.
+func (w *etagWriter) finish(r *http.Request) {
+	if w.passthrough {
+		return
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	h := w.Header()
+	etag := h.Get("ETag")
+	if etag == "" {
+		sum := sha256.Sum256(w.buf.Bytes())
+		etag = `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+		h.Set("ETag", etag)
+	}
+	modTime, _ := http.ParseTime(h.Get("Last-Modified"))
+	if notModified(r, etag, modTime) {
+		writeNotModified(w.ResponseWriter)
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.buf.Bytes())
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+)
+
+// BodyRecorder 는 응답을 클라이언트에 쓰면서 본문을 limit 바이트까지 복사해 두는 ResponseWriter 래퍼다.
+// 응답 캐시, 요청 병합, 멱등성 키처럼 같은 응답을 나중에 다시 보내는 미들웨어가 쓴다.
+type BodyRecorder struct {
+	http.ResponseWriter
+	limit    int
+	status   int
+	header   http.Header // 핸들러가 응답을 쓰기 시작한 순간의 헤더
+	body     bytes.Buffer
+	overflow bool
+}
+
+// NewBodyRecorder 는 w 를 감싸 본문을 limit 바이트까지 복사하는 BodyRecorder 를 만든다.
+func NewBodyRecorder(w http.ResponseWriter, limit int) *BodyRecorder {
+	return &BodyRecorder{ResponseWriter: w, limit: limit}
+}
+
+// start 는 상태 코드와 헤더를 기록한다. 압축처럼 바깥 래퍼가 쓰는 중에 붙이는 헤더는 포함하지 않는다.
+func (w *BodyRecorder) start(code int) {
+	if w.status == 0 {
+		w.status = code
+		w.header = w.Header().Clone()
+	}
+}
+
+// WriteHeader 는 처음 쓴 최종 상태 코드와 그때의 헤더를 기록한다. 1xx 정보 응답은 그대로 전달만 한다.
+func (w *BodyRecorder) WriteHeader(code int) {
+	if code < 100 || code >= 200 || code == http.StatusSwitchingProtocols {
+		w.start(code)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write 는 본문을 쓰면서 복사한다. limit 를 넘으면 복사를 멈추고 버린다.
+func (w *BodyRecorder) Write(b []byte) (int, error) {
+	w.start(http.StatusOK)
+	if !w.overflow {
+		if w.body.Len()+len(b) > w.limit {
+			w.overflow = true
+			w.body.Reset()
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap 은 http.ResponseController 가 내부 ResponseWriter 에 접근하도록 한다.
+func (w *BodyRecorder) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Status 는 응답 상태 코드다. 아무것도 쓰지 않았다면 200 으로 확정한다.
+func (w *BodyRecorder) Status() int {
+	w.start(http.StatusOK)
+	return w.status
+}
+
+// Sent 는 핸들러가 응답을 쓰기 시작한 순간의 헤더다. 아무것도 쓰지 않았다면 지금의 헤더다.
+func (w *BodyRecorder) Sent() http.Header {
+	w.start(http.StatusOK)
+	return w.header
+}
+
+// Body 는 복사한 본문이다. 본문이 limit 를 넘었으면 ok 는 false 다.
+func (w *BodyRecorder) Body() (b []byte, ok bool) {
+	if w.overflow {
+		return nil, false
+	}
+	return w.body.Bytes(), true
+}
+
+// Changed 는 핸들러가 더하거나 바꾼 헤더다. pre 는 핸들러 실행 전의 헤더이고, 요청 ID 처럼 바깥
+// 미들웨어가 붙인 헤더는 다시 보낼 때 새로 붙으므로 뺀다. Content-Length 와 skip 의 헤더도 뺀다.
+func (w *BodyRecorder) Changed(pre http.Header, skip ...string) http.Header {
+	h := http.Header{}
+	for k, v := range w.Sent() {
+		if k == "Content-Length" || slices.Contains(skip, k) || slices.Equal(pre[k], v) {
+			continue
+		}
+		h[k] = slices.Clone(v)
+	}
+	return h
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/hgsong234/_stack/Golang/apperror"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// RecoverConfig 는 패닉 복구 시의 500 응답을 정한다.
+type RecoverConfig struct {
+	// Message 는 클라이언트에게 보여 줄 메시지다. 비어 있으면 "internal server error".
+	Message string
+	// HTML 은 브라우저 요청에 사용할 에러 페이지 템플릿이다.
+	// .Message, .RequestID, .Stack 을 사용할 수 있다. nil 이면 기본 페이지를 사용한다.
+	HTML *template.Template
+	// ShowStack 이 true 이면 응답에 패닉 값과 스택 트레이스를 담는다. 개발 모드에서만 켠다.
+	ShowStack bool
+	// Logger 는 스택 트레이스를 기록할 로거다. nil 이면 요청 로거(logging.From)를 사용한다.
+	Logger logging.Logger
+}
+
+var defaultErrorPage = template.Must(template.New("500").Parse(`<!DOCTYPE html>
+<html><head><title>500 Internal Server Error</title></head>
+<body><h1>500 Internal Server Error</h1><p>{{.Message}}</p>
+{{if .RequestID}}<p><small>Request ID: {{.RequestID}}</small></p>{{end}}
+{{if .Stack}}<pre>{{.Stack}}</pre>{{end}}</body></html>
+`))
+
+// Recover 는 핸들러의 패닉을 잡아 스택 트레이스를 남기고 500 응답을 보내는 미들웨어를 만든다.
+// 요청 ID 를 기록하려면 RequestID 미들웨어 뒤에 등록한다.
+func Recover(cfg RecoverConfig) router.Middleware {
+	if cfg.Message == "" {
+		cfg.Message = "internal server error"
+	}
+	if cfg.HTML == nil {
+		cfg.HTML = defaultErrorPage
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sw := NewStatusWriter(w)
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					// 연결을 끊으려는 의도적인 패닉은 net/http 에 맡긴다.
+					panic(v)
+				}
+				id := RequestIDFrom(r.Context())
+				logger := cfg.Logger
+				if logger == nil {
+					logger = logging.From(r.Context())
+				}
+				stack := string(debug.Stack())
+				logger.Error("panic", "value", fmt.Sprint(v), "method", r.Method, "path", r.URL.Path, "stack", stack)
+				if sw.Status != 0 {
+					// 이미 응답을 쓰기 시작했으면 헤더를 바꿀 수 없다.
+					return
+				}
+				var shown string
+				if cfg.ShowStack {
+					shown = fmt.Sprintf("panic: %v\n\n%s", v, stack)
+				}
+				writeServerError(sw, r, cfg, id, shown)
+			}()
+			next.ServeHTTP(sw, r)
+		})
+	}
+}
+
+// writeServerError 는 Accept 헤더에 따라 HTML 또는 problem+json 으로 500 응답을 쓴다.
+func writeServerError(w http.ResponseWriter, r *http.Request, cfg RecoverConfig, id, stack string) {
+	if wantsHTML(r) {
+		data := struct{ Message, RequestID, Stack string }{cfg.Message, id, stack}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		cfg.HTML.Execute(w, data)
+		return
+	}
+	p := apperror.Internal(nil).Problem()
+	p.Detail, p.Instance, p.RequestID = cfg.Message, r.URL.Path, id
+	if stack != "" {
+		p.Extra = map[string]any{"stack": stack}
+	}
+	apperror.WriteProblem(w, p)
+}
+
+// wantsHTML 은 클라이언트가 HTML 을 원하는지 판단한다. (브라우저의 Accept 헤더)
+func wantsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
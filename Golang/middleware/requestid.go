@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// RequestIDHeader 는 요청 ID 를 주고받는 헤더 이름이다.
+const RequestIDHeader = "X-Request-ID"
+
+// 요청 ID 컨텍스트 키
+type requestIDKey struct{}
+
+// RequestID 는 요청마다 고유 ID 를 부여하는 미들웨어를 만든다.
+// 클라이언트가 올바른 X-Request-ID 를 보내면 그 값을 그대로 사용하고,
+// ID 는 컨텍스트에 저장한 뒤 응답 헤더로 돌려준다.
+// 컨텍스트에는 request_id 속성이 붙은 요청 로거(logging.From)도 함께 넣는다.
+func RequestID() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			ctx = logging.WithContext(ctx, logging.From(ctx).With("request_id", id))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestIDFrom 은 컨텍스트에 저장된 요청 ID 를 꺼낸다.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newRequestID 는 128비트 난수를 16진수 문자열로 만든다.
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID 는 외부에서 받은 ID 가 로그에 그대로 써도 안전한지 검사한다.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
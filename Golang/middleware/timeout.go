@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// WithTimeout 은 핸들러 실행 시간을 d 로 제한하는 라우트 미들웨어를 만든다.
+// 시간이 지나면 핸들러의 컨텍스트를 취소하고 503 을 돌려준다.
+// 응답은 끝날 때까지 버퍼에 모아 두므로 스트리밍 라우트에는 사용하지 않는다.
+// d 가 0 이하이면 제한하지 않는다.
+func WithTimeout(d time.Duration) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			// 바깥 미들웨어가 넣은 헤더(X-Request-ID 등)를 핸들러도 볼 수 있도록 복사해 시작한다.
+			tw := &timeoutWriter{h: w.Header().Clone()}
+			done := make(chan struct{})
+			panicc := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicc <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+			select {
+			case p := <-panicc:
+				// 바깥의 Recover 미들웨어가 처리하도록 다시 패닉을 일으킨다.
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				dst := w.Header()
+				for k, v := range tw.h {
+					dst[k] = v
+				}
+				if tw.code == 0 {
+					tw.code = http.StatusOK
+				}
+				w.WriteHeader(tw.code)
+				w.Write(tw.buf.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if r.Context().Err() != nil {
+					// 클라이언트가 먼저 연결을 끊었다.
+					return
+				}
+				api.WriteError(w, api.NewError(http.StatusServiceUnavailable, "timeout", "request timed out"))
+			}
+		})
+	}
+}
+
+// timeoutWriter 는 핸들러 응답을 버퍼에 모으고, 시간 초과 이후의 쓰기는 버린다.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	h        http.Header
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+}
+
+func (w *timeoutWriter) Header() http.Header { return w.h }
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.code != 0 {
+		return
+	}
+	w.code = code
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.buf.Write(b)
+}
@@ -0,0 +1,79 @@
+// Package middleware 는 라우터에 공통으로 적용하는 HTTP 미들웨어 모음이다.
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"time"
+)
+
+// StatusWriter 는 응답 상태 코드와 바이트 수를 기록하는 ResponseWriter 래퍼다.
+type StatusWriter struct {
+	http.ResponseWriter
+	Status int
+	Bytes  int64
+	// WroteAt 은 응답 헤더나 본문을 처음 쓴 시각이다. 1xx 정보 응답은 치지 않는다.
+	WroteAt time.Time
+}
+
+// NewStatusWriter 는 w 를 감싼 StatusWriter 를 만든다.
+// 이미 StatusWriter 라면 그대로 돌려준다.
+func NewStatusWriter(w http.ResponseWriter) *StatusWriter {
+	if sw, ok := w.(*StatusWriter); ok {
+		return sw
+	}
+	return &StatusWriter{ResponseWriter: w}
+}
+
+// WriteHeader 는 처음 기록된 상태 코드를 저장한다.
+func (w *StatusWriter) WriteHeader(code int) {
+	if code >= 200 && w.WroteAt.IsZero() {
+		w.WroteAt = time.Now()
+	}
+	if w.Status == 0 {
+		w.Status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write 는 기록한 바이트 수를 누적한다.
+func (w *StatusWriter) Write(b []byte) (int, error) {
+	if w.Status == 0 {
+		w.Status = http.StatusOK
+	}
+	if w.WroteAt.IsZero() {
+		w.WroteAt = time.Now()
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.Bytes += int64(n)
+	return n, err
+}
+
+// Code 는 응답 상태 코드를 돌려준다. 아무것도 쓰지 않았다면 200 이다.
+func (w *StatusWriter) Code() int {
+	if w.Status == 0 {
+		return http.StatusOK
+	}
+	return w.Status
+}
+
+// Flush 는 내부 ResponseWriter 가 지원하면 버퍼를 비운다.
+func (w *StatusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap 은 http.ResponseController 가 내부 ResponseWriter 에 접근하도록 한다.
+func (w *StatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack 은 WebSocket 업그레이드처럼 연결을 직접 다루려는 핸들러를 위해 내부 연결을 넘긴다.
+func (w *StatusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.Status == 0 {
+		w.Status = http.StatusSwitchingProtocols
+	}
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
@@ -0,0 +1,221 @@
+// Package migrate 는 내장된 SQL 마이그레이션(migrations/*.sql)을 적용하고 되돌린다.
+//
+// 파일 이름은 "<버전>_<이름>.<up|down>.sql" 이다. 드라이버마다 SQL 이 달라야 하면
+// "<버전>_<이름>.up.sqlite.sql" 처럼 드라이버 이름을 붙인 파일을 두면 그 드라이버에서 우선 사용한다.
+// 적용 기록은 schema_migrations 테이블에 남고, 마이그레이션 하나는 트랜잭션 하나로 실행된다.
+//
+// 여러 인스턴스가 동시에 시작하는 배포에서는 자동 마이그레이션 대신 "migrate up" 을 배포 단계로 실행한다.
+package migrate
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/store"
+)
+
+//go:embed migrations/*.sql
+var embedded embed.FS
+
+// Migration 은 버전 하나의 up/down SQL 이다.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// String 은 "0001_create_users" 형식의 이름을 돌려준다.
+func (m Migration) String() string { return fmt.Sprintf("%04d_%s", m.Version, m.Name) }
+
+// Status 는 마이그레이션 하나의 적용 상태다. AppliedAt 이 nil 이면 아직 적용되지 않았다.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator 는 db 에 마이그레이션을 적용한다.
+type Migrator struct {
+	db         store.DB
+	migrations []Migration
+}
+
+// New 는 내장 마이그레이션을 사용하는 Migrator 를 만든다.
+func New(db store.DB) (*Migrator, error) {
+	sub, _ := fs.Sub(embedded, "migrations")
+	return NewFS(db, sub)
+}
+
+// NewFS 는 fsys 최상위의 *.sql 파일을 사용하는 Migrator 를 만든다.
+func NewFS(db store.DB, fsys fs.FS) (*Migrator, error) {
+	ms, err := load(fsys, db.Driver())
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: ms}, nil
+}
+
+// load 는 파일들을 버전 순서로 읽는다.
+func load(fsys fs.FS, driver string) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	// 드라이버 전용 파일이 일반 파일을 덮어쓰도록 일반 파일을 먼저 처리한다.
+	sort.Slice(names, func(i, j int) bool {
+		return strings.Count(names[i], ".") < strings.Count(names[j], ".")
+	})
+	for _, name := range names {
+		parts := strings.Split(strings.TrimSuffix(name, ".sql"), ".")
+		if len(parts) < 2 || len(parts) > 3 || (parts[1] != "up" && parts[1] != "down") {
+			return nil, fmt.Errorf("migrate: bad file name %q", name)
+		}
+		if len(parts) == 3 && parts[2] != driver {
+			continue
+		}
+		ver, title, ok := strings.Cut(parts[0], "_")
+		v, err := strconv.ParseInt(ver, 10, 64)
+		if !ok || err != nil || v <= 0 {
+			return nil, fmt.Errorf("migrate: bad version in %q", name)
+		}
+		b, err := fs.ReadFile(fsys, path.Clean(name))
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[v]
+		if m == nil {
+			m = &Migration{Version: v, Name: title}
+			byVersion[v] = m
+		} else if m.Name != title {
+			return nil, fmt.Errorf("migrate: version %d has two names: %s, %s", v, m.Name, title)
+		}
+		if parts[1] == "up" {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migrate: %s has no up migration", m)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+const versionsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    BIGINT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+)`
+
+// applied 는 적용된 버전과 적용 시각을 읽는다.
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	if _, err := m.db.ExecContext(ctx, versionsTable); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	defer rows.Close()
+	out := map[int64]time.Time{}
+	for rows.Next() {
+		var v int64
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, fmt.Errorf("migrate: %w", err)
+		}
+		out[v] = at
+	}
+	return out, rows.Err()
+}
+
+// Status 는 모든 마이그레이션의 적용 상태를 버전 순서로 돌려준다.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, len(m.migrations))
+	for i, mg := range m.migrations {
+		out[i].Migration = mg
+		if at, ok := done[mg.Version]; ok {
+			out[i].AppliedAt = &at
+		}
+	}
+	return out, nil
+}
+
+// Up 은 적용되지 않은 마이그레이션을 모두 적용하고, 적용한 목록을 돌려준다.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []Migration
+	for _, mg := range m.migrations {
+		if _, ok := done[mg.Version]; ok {
+			continue
+		}
+		err := m.run(ctx, mg.Up,
+			`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			mg.Version, mg.Name, time.Now().UTC())
+		if err != nil {
+			return out, fmt.Errorf("migrate: up %s: %w", mg, err)
+		}
+		out = append(out, mg)
+	}
+	return out, nil
+}
+
+// Down 은 최근에 적용된 마이그레이션을 steps 개 되돌리고, 되돌린 목록을 돌려준다.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(out) < steps; i-- {
+		mg := m.migrations[i]
+		if _, ok := done[mg.Version]; !ok {
+			continue
+		}
+		if mg.Down == "" {
+			return out, fmt.Errorf("migrate: %s has no down migration", mg)
+		}
+		err := m.run(ctx, mg.Down, `DELETE FROM schema_migrations WHERE version = ?`, mg.Version)
+		if err != nil {
+			return out, fmt.Errorf("migrate: down %s: %w", mg, err)
+		}
+		out = append(out, mg)
+	}
+	return out, nil
+}
+
+// run 은 마이그레이션 SQL 과 기록 갱신을 한 트랜잭션으로 실행한다.
+func (m *Migrator) run(ctx context.Context, script, record string, args ...any) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, m.db.Rebind(record), args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,6 @@
+CREATE TABLE users (
+	id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	name       TEXT NOT NULL,
+	email      TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL
+);
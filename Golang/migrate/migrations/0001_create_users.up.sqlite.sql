@@ -0,0 +1,7 @@
+-- SQLite 에는 IDENTITY 가 없으므로 INTEGER PRIMARY KEY(rowid) 를 쓴다.
+CREATE TABLE users (
+	id         INTEGER PRIMARY KEY,
+	name       TEXT NOT NULL,
+	email      TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,11 @@
+CREATE TABLE api_keys (
+	id           BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	name         TEXT NOT NULL,
+	prefix       TEXT NOT NULL UNIQUE,
+	hash         TEXT NOT NULL,
+	scopes       TEXT NOT NULL DEFAULT '',
+	rate_limit   DOUBLE PRECISION NOT NULL DEFAULT 0,
+	burst        INTEGER NOT NULL DEFAULT 0,
+	created_at   TIMESTAMP NOT NULL,
+	revoked_at   TIMESTAMP
+);
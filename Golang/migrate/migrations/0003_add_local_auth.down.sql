@@ -0,0 +1,6 @@
+DROP TABLE auth_tokens;
+DROP INDEX users_login_email;
+ALTER TABLE users DROP COLUMN locked_until;
+ALTER TABLE users DROP COLUMN failed_logins;
+ALTER TABLE users DROP COLUMN email_verified_at;
+ALTER TABLE users DROP COLUMN password_hash;
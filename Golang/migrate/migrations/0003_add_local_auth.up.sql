@@ -0,0 +1,16 @@
+-- 로그인 정보가 있는 사용자(password_hash 가 비어 있지 않음)끼리만 이메일이 겹치지 않아야 한다.
+ALTER TABLE users ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN failed_logins INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP;
+CREATE UNIQUE INDEX users_login_email ON users (lower(email)) WHERE password_hash <> '';
+
+-- 이메일 확인과 비밀번호 재설정 토큰. 원문은 메일로만 보내고 해시만 저장한다.
+CREATE TABLE auth_tokens (
+	hash       TEXT PRIMARY KEY,
+	user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	purpose    TEXT NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX auth_tokens_user ON auth_tokens (user_id, purpose);
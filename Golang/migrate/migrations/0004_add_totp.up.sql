@@ -0,0 +1,11 @@
+-- TOTP 2단계 인증. totp_secret 이 비어 있지 않으면 켜진 것이고, totp_last_step 은 마지막으로 받은 코드의
+-- 시간 단계로 같은 코드를 다시 쓰지 못하게 한다.
+ALTER TABLE users ADD COLUMN totp_secret TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+-- 인증 앱을 잃어버렸을 때 쓰는 일회용 백업 코드. 해시만 저장한다.
+CREATE TABLE totp_backup_codes (
+	user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	hash    TEXT NOT NULL,
+	PRIMARY KEY (user_id, hash)
+);
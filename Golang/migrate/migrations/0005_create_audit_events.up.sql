@@ -0,0 +1,22 @@
+-- 보안 감사 로그. 추가만 할 수 있도록 수정과 삭제는 트리거로 막는다.
+CREATE TABLE audit_events (
+	id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	time       TIMESTAMP NOT NULL,
+	type       TEXT NOT NULL,
+	outcome    TEXT NOT NULL,
+	actor      TEXT NOT NULL DEFAULT '',
+	target     TEXT NOT NULL DEFAULT '',
+	remote_ip  TEXT NOT NULL DEFAULT '',
+	request_id TEXT NOT NULL DEFAULT '',
+	fields     TEXT NOT NULL DEFAULT '{}'
+);
+CREATE INDEX audit_events_time ON audit_events (time);
+CREATE INDEX audit_events_type ON audit_events (type, time);
+
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events
+	FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
@@ -0,0 +1,24 @@
+-- SQLite 에는 IDENTITY 가 없으므로 INTEGER PRIMARY KEY(rowid) 를 쓴다.
+CREATE TABLE audit_events (
+	id         INTEGER PRIMARY KEY,
+	time       TIMESTAMP NOT NULL,
+	type       TEXT NOT NULL,
+	outcome    TEXT NOT NULL,
+	actor      TEXT NOT NULL DEFAULT '',
+	target     TEXT NOT NULL DEFAULT '',
+	remote_ip  TEXT NOT NULL DEFAULT '',
+	request_id TEXT NOT NULL DEFAULT '',
+	fields     TEXT NOT NULL DEFAULT '{}'
+);
+CREATE INDEX audit_events_time ON audit_events (time);
+CREATE INDEX audit_events_type ON audit_events (type, time);
+
+-- 추가만 할 수 있도록 수정과 삭제를 막는다.
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+BEGIN
+	SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON audit_events
+BEGIN
+	SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
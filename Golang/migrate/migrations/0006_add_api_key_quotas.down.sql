@@ -0,0 +1,3 @@
+DROP TABLE quota_usage;
+ALTER TABLE api_keys DROP COLUMN monthly_quota;
+ALTER TABLE api_keys DROP COLUMN daily_quota;
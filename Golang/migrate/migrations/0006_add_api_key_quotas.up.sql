@@ -0,0 +1,13 @@
+-- 키별 할당량 재정의. NULL 이면 quota 설정의 기본값을 쓰고, 0 이면 제한하지 않는다.
+ALTER TABLE api_keys ADD COLUMN daily_quota BIGINT;
+ALTER TABLE api_keys ADD COLUMN monthly_quota BIGINT;
+
+-- quota.backend 가 db 일 때의 사용량. window_start 는 기간(하루, 한 달)이 시작하는 UTC 시각이다.
+CREATE TABLE quota_usage (
+	subject      TEXT NOT NULL,
+	period       TEXT NOT NULL,
+	window_start TIMESTAMP NOT NULL,
+	used         BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (subject, period, window_start)
+);
+CREATE INDEX quota_usage_window ON quota_usage (window_start);
@@ -0,0 +1,324 @@
+// Package mock 은 개발용 가짜 응답이다. 픽스처 파일에 선언한 라우트는 실제 핸들러, 프록시, 데이터베이스 대신
+// 미리 정한 응답을 돌려주므로 프런트엔드 개발자가 백엔드 없이 서버를 띄울 수 있다.
+//
+// 픽스처는 JSON 이다.
+//
+//	{"routes": [{
+//	  "method": "GET", "path": "/api/users/{id}", "status": 200,
+//	  "headers": {"X-Total-Count": "1"},
+//	  "body": {"id": "{{.Params.id}}", "name": "User {{.Params.id}}", "at": "{{now}}"},
+//	  "latency": "150ms", "jitter": "100ms"
+//	}]}
+//
+// body 는 JSON 값이고 그 안의 문자열은 text/template 으로 실행한다. JSON 이 아닌 본문은 text 에 쓰고
+// Content-Type 은 headers 로 정한다. 템플릿에서는 다음 값과 함수를 쓸 수 있다.
+//
+//	.Params  경로 파라미터          .Query   쿼리 (url.Values, {{.Query.Get "q"}})
+//	.Header  요청 헤더              .Body    JSON 요청 본문을 디코드한 값
+//	.Method, .Path
+//	now      현재 시각 (RFC 3339)   uuid     임의 UUID   randInt a b   a 이상 b 미만 정수
+package mock
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	mrand "math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/apperror"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Route 는 픽스처의 라우트 하나다.
+type Route struct {
+	// Method 가 비어 있으면 GET 이다.
+	Method string `json:"method"`
+	// Path 는 router 패턴이다. (예: /api/users/{id})
+	Path string `json:"path"`
+	// Status 가 0 이면 200 이다.
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+	Text    string            `json:"text"`
+	// Latency 만큼 기다린 뒤 응답하고, Jitter 가 있으면 0~Jitter 를 더 기다린다. (예: "200ms")
+	Latency string `json:"latency"`
+	Jitter  string `json:"jitter"`
+}
+
+// Fixture 는 픽스처 파일의 내용이다.
+type Fixture struct {
+	Routes []Route `json:"routes"`
+}
+
+// Server 는 픽스처 라우트를 서비스한다. Prepare 로 실행 중에 픽스처를 바꿀 수 있다.
+type Server struct {
+	current atomic.Pointer[router.Router]
+}
+
+// nextKey 는 픽스처에 없는 요청을 넘길 다음 핸들러의 컨텍스트 키다.
+type nextKey struct{}
+
+// Open 은 path 의 픽스처를 읽어 Server 를 만든다. latency 는 latency 를 정하지 않은 라우트가 기다리는 시간이다.
+func Open(path string, latency time.Duration) (*Server, error) {
+	s := &Server{}
+	apply, err := s.Prepare(path, latency)
+	if err != nil {
+		return nil, err
+	}
+	apply()
+	return s, nil
+}
+
+// Prepare 는 path 의 픽스처를 읽고 확인한다. 돌려준 함수를 부르면 지금 라우트를 바꾼다.
+func (s *Server) Prepare(path string, latency time.Duration) (func(), error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("mock: %w", err)
+	}
+	var fx Fixture
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fx); err != nil {
+		return nil, fmt.Errorf("mock: %s: %w", path, err)
+	}
+	rt := router.New()
+	rt.NotFound = http.HandlerFunc(passThrough)
+	rt.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 같은 경로의 다른 메서드는 실제 라우트가 처리할 수 있다.
+		w.Header().Del("Allow")
+		passThrough(w, r)
+	})
+	for i, rc := range fx.Routes {
+		method := strings.ToUpper(rc.Method)
+		if method == "" {
+			method = http.MethodGet
+		}
+		h, err := compile(rc, latency)
+		if err != nil {
+			return nil, fmt.Errorf("mock: %s: routes[%d] %s %s: %w", path, i, method, rc.Path, err)
+		}
+		rt.Handle(method, rc.Path, h)
+	}
+	return func() {
+		s.current.Store(rt)
+		logging.Default().Info("mock: fixture loaded", "file", path, "routes", len(fx.Routes))
+	}, nil
+}
+
+// Middleware 는 픽스처에 있는 요청에 가짜 응답을 쓰고, 없는 요청은 next 로 넘긴다.
+func (s *Server) Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), nextKey{}, next)
+			s.current.Load().ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func passThrough(w http.ResponseWriter, r *http.Request) {
+	r.Context().Value(nextKey{}).(http.Handler).ServeHTTP(w, r)
+}
+
+// data 는 템플릿에 넘기는 요청 정보다.
+type data struct {
+	Params router.Params
+	Query  url.Values
+	Header http.Header
+	Body   any
+	Method string
+	Path   string
+}
+
+var funcs = template.FuncMap{
+	"now": func() string { return time.Now().UTC().Format(time.RFC3339) },
+	"uuid": func() string {
+		b := make([]byte, 16)
+		rand.Read(b)
+		b[6], b[8] = b[6]&0x0f|0x40, b[8]&0x3f|0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[:4], b[4:6], b[6:8], b[8:10], b[10:])
+	},
+	"randInt": func(lo, hi int) (int, error) {
+		if hi <= lo {
+			return 0, fmt.Errorf("randInt: %d must be less than %d", lo, hi)
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(hi-lo)))
+		if err != nil {
+			return 0, err
+		}
+		return lo + int(n.Int64()), nil
+	},
+}
+
+// compile 은 라우트의 템플릿과 지연 시간을 미리 확인하고 핸들러를 만든다.
+func compile(rc Route, latency time.Duration) (http.Handler, error) {
+	if rc.Path == "" || rc.Path[0] != '/' {
+		return nil, fmt.Errorf("path must begin with /")
+	}
+	if len(rc.Body) > 0 && rc.Text != "" {
+		return nil, fmt.Errorf("body and text cannot both be set")
+	}
+	jitter := time.Duration(0)
+	for _, d := range []struct {
+		v   string
+		dst *time.Duration
+	}{{rc.Latency, &latency}, {rc.Jitter, &jitter}} {
+		if d.v == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.v)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid duration %q", d.v)
+		}
+		*d.dst = v
+	}
+	status := rc.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	var (
+		body   any
+		text   *template.Template
+		tmpls  = map[string]*template.Template{}
+		isJSON = len(rc.Body) > 0
+	)
+	if isJSON {
+		if err := json.Unmarshal(rc.Body, &body); err != nil {
+			return nil, err
+		}
+		if err := walkStrings(body, func(s string) error {
+			t, err := parse(s)
+			tmpls[s] = t
+			return err
+		}); err != nil {
+			return nil, err
+		}
+	} else if rc.Text != "" {
+		var err error
+		if text, err = parse(rc.Text); err != nil {
+			return nil, err
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d := latency + randDuration(jitter); d > 0 {
+			select {
+			case <-time.After(d):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		in := data{
+			Params: router.ParamsFrom(r.Context()),
+			Query:  r.URL.Query(),
+			Header: r.Header,
+			Method: r.Method,
+			Path:   r.URL.Path,
+		}
+		if r.Body != nil && strings.Contains(r.Header.Get("Content-Type"), "json") {
+			json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&in.Body)
+		}
+		var out []byte
+		var err error
+		switch {
+		case isJSON:
+			var v any
+			if v, err = render(body, tmpls, in); err == nil {
+				out, err = json.Marshal(v)
+			}
+		case text != nil:
+			var buf bytes.Buffer
+			err = text.Execute(&buf, in)
+			out = buf.Bytes()
+		}
+		if err != nil {
+			logging.From(r.Context()).Error("mock: render", "path", rc.Path, "err", err)
+			apperror.Write(w, r, apperror.Wrap(err, http.StatusInternalServerError, "mock_render_failed", "mock: "+err.Error()))
+			return
+		}
+		if isJSON {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		}
+		for k, v := range rc.Headers {
+			w.Header().Set(k, v)
+		}
+		w.Header().Set("X-Mock", "true")
+		w.WriteHeader(status)
+		w.Write(out)
+	}), nil
+}
+
+func parse(s string) (*template.Template, error) {
+	return template.New("").Funcs(funcs).Option("missingkey=zero").Parse(s)
+}
+
+func randDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return mrand.N(max)
+}
+
+// walkStrings 는 JSON 값의 모든 문자열에 fn 을 부른다.
+func walkStrings(v any, fn func(string) error) error {
+	switch v := v.(type) {
+	case string:
+		return fn(v)
+	case map[string]any:
+		for _, x := range v {
+			if err := walkStrings(x, fn); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, x := range v {
+			if err := walkStrings(x, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// render 는 v 의 문자열을 템플릿으로 실행한 새 값을 만든다. v 자체는 바꾸지 않는다.
+func render(v any, tmpls map[string]*template.Template, in data) (any, error) {
+	switch v := v.(type) {
+	case string:
+		var buf strings.Builder
+		if err := tmpls[v].Execute(&buf, in); err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, x := range v {
+			y, err := render(x, tmpls, in)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = y
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, x := range v {
+			y, err := render(x, tmpls, in)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = y
+		}
+		return out, nil
+	}
+	return v, nil
+}
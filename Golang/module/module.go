@@ -0,0 +1,151 @@
+// Package module 은 기능(업로드 등)을 라우트, 시작, 종료를 가진 모듈로 나누어 main 이 조립하게 한다.
+//
+// 모듈 패키지는 init 에서 Register 로 자신을 만드는 함수를 등록하고, main 은 그 패키지를 import 한 뒤
+// Load 와 Mount 로 켜진 모듈을 라우터와 수명 주기에 붙인다. 따라서 다른 저장소의 모듈도 핵심 파일을
+// 고치지 않고 import 한 줄로 더할 수 있다.
+//
+//	func init() {
+//		module.Register("hello", func(env *module.Env) (module.Module, error) {
+//			return &hello{}, nil
+//		})
+//	}
+package module
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/hgsong234/_stack/Golang/canary"
+	"github.com/hgsong234/_stack/Golang/config"
+	"github.com/hgsong234/_stack/Golang/jobs"
+	"github.com/hgsong234/_stack/Golang/lifecycle"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+	"github.com/hgsong234/_stack/Golang/store"
+)
+
+// Module 은 라우트와 시작·종료를 함께 가진 기능 단위다.
+type Module interface {
+	// Name 은 설정(modules.disabled, modules.settings)과 로그에서 쓰는 이름이다.
+	Name() string
+	// Routes 는 주 라우터에 모듈의 라우트를 등록한다. 전역 미들웨어는 이미 걸려 있다.
+	Routes(r *router.Router)
+	// Start 는 서버가 요청을 받기 전에, Stop 은 요청 드레인이 끝난 뒤에 불린다.
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// Base 는 시작과 종료에 할 일이 없는 모듈이 임베드하는 빈 구현이다.
+type Base struct{}
+
+func (Base) Start(context.Context) error { return nil }
+func (Base) Stop(context.Context) error  { return nil }
+
+// Env 는 모듈을 만들 때 넘기는 핵심 서비스다.
+type Env struct {
+	Config *config.Config
+	// DB 는 database.driver 가 비어 있으면 nil 이다.
+	DB   store.DB
+	Jobs *jobs.Queue
+	// Publish 는 웹훅 이벤트를 보낸다. 실패는 Publish 가 기록한다.
+	Publish func(ctx context.Context, event string, data any)
+	Logger  logging.Logger
+	// Canaries 는 canary.splits 로 선언한 트래픽 분할이다. Get(name).Handler(stable, canary) 로 두 구현을 나눈다.
+	Canaries *canary.Set
+	// Settings 는 설정 파일의 modules.settings.<이름> 절이다. 없으면 비어 있다.
+	Settings json.RawMessage
+}
+
+// Decode 는 Settings 를 v 로 읽는다. 모르는 필드는 에러다.
+func (e *Env) Decode(v any) error {
+	if len(e.Settings) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(e.Settings))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// Factory 는 모듈을 만든다. 설정으로 꺼져 있으면 nil Module 을 돌려준다.
+type Factory func(env *Env) (Module, error)
+
+var (
+	mu        sync.Mutex
+	factories = map[string]Factory{}
+)
+
+// Register 는 name 모듈의 Factory 를 등록한다. 보통 모듈 패키지의 init 에서 부른다.
+// 같은 이름을 두 번 등록하면 패닉이다.
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := factories[name]; dup {
+		panic("module: Register called twice for " + name)
+	}
+	factories[name] = f
+}
+
+// Names 는 등록된 모듈 이름을 정렬해 돌려준다.
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Load 는 modules.disabled 에 없는 모듈을 이름 순서로 만든다. env.Settings 는 모듈마다 채운다.
+// 설정에 등록되지 않은 모듈 이름이 있으면 에러다.
+func Load(env Env) ([]Module, error) {
+	cfg := env.Config.Modules
+	names := Names()
+	for _, name := range cfg.Disabled {
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("module: modules.disabled: unknown module %q", name)
+		}
+	}
+	for name := range cfg.Settings {
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("module: modules.settings: unknown module %q", name)
+		}
+	}
+	var mods []Module
+	for _, name := range names {
+		if slices.Contains(cfg.Disabled, name) {
+			continue
+		}
+		mu.Lock()
+		f := factories[name]
+		mu.Unlock()
+		e := env
+		e.Settings = cfg.Settings[name]
+		if e.Logger == nil {
+			e.Logger = logging.Default()
+		}
+		e.Logger = e.Logger.With("module", name)
+		m, err := f(&e)
+		if err != nil {
+			return nil, fmt.Errorf("module: %s: %w", name, err)
+		}
+		if m != nil {
+			mods = append(mods, m)
+		}
+	}
+	return mods, nil
+}
+
+// Mount 는 모듈의 라우트를 r 에 등록하고 Start 와 Stop 을 lc 의 시작·종료 훅으로 등록한다.
+func Mount(r *router.Router, lc *lifecycle.Manager, mods []Module) {
+	for _, m := range mods {
+		m.Routes(r)
+		lc.OnStart(m.Name(), 0, m.Start)
+		lc.OnShutdown(m.Name(), 0, m.Stop)
+	}
+}
@@ -0,0 +1,282 @@
+// Package oauth 는 Google/GitHub OAuth2 로그인(PKCE)을 처리하고 로그인한 사용자를 세션에 저장한다.
+//
+//	GET  /auth/login?provider=github&return=/hello  공급자 로그인 페이지로 이동
+//	GET  /auth/callback                           인가 코드를 토큰으로 교환하고 세션에 사용자 저장
+//	POST /auth/logout                             세션 삭제
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+
+	"github.com/hgsong234/_stack/Golang/audit"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+	"github.com/hgsong234/_stack/Golang/session"
+)
+
+// Identity 는 공급자에게서 받은 로그인 사용자 정보다.
+type Identity struct {
+	Provider string `json:"provider"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Email    string `json:"email,omitempty"`
+}
+
+// Provider 는 OAuth2 공급자 하나의 설정과 사용자 정보 조회 방법이다.
+type Provider struct {
+	Name   string
+	Config *oauth2.Config
+	// UserInfo 는 액세스 토큰으로 사용자 정보를 조회한다.
+	UserInfo func(ctx context.Context, client *http.Client) (*Identity, error)
+}
+
+// Google 은 OpenID Connect userinfo 엔드포인트를 사용하는 Google 공급자다.
+func Google(clientID, secret, redirectURL string) *Provider {
+	return &Provider{
+		Name: "google",
+		Config: &oauth2.Config{
+			ClientID: clientID, ClientSecret: secret, RedirectURL: redirectURL,
+			Endpoint: endpoints.Google,
+			Scopes:   []string{"openid", "email", "profile"},
+		},
+		UserInfo: func(ctx context.Context, c *http.Client) (*Identity, error) {
+			var u struct {
+				Sub   string `json:"sub"`
+				Name  string `json:"name"`
+				Email string `json:"email"`
+			}
+			if err := getJSON(ctx, c, "https://openidconnect.googleapis.com/v1/userinfo", &u); err != nil {
+				return nil, err
+			}
+			return &Identity{Provider: "google", ID: u.Sub, Name: u.Name, Email: u.Email}, nil
+		},
+	}
+}
+
+// GitHub 는 GitHub 공급자다.
+func GitHub(clientID, secret, redirectURL string) *Provider {
+	return &Provider{
+		Name: "github",
+		Config: &oauth2.Config{
+			ClientID: clientID, ClientSecret: secret, RedirectURL: redirectURL,
+			Endpoint: endpoints.GitHub,
+			Scopes:   []string{"read:user", "user:email"},
+		},
+		UserInfo: func(ctx context.Context, c *http.Client) (*Identity, error) {
+			var u struct {
+				ID    int64  `json:"id"`
+				Login string `json:"login"`
+				Name  string `json:"name"`
+				Email string `json:"email"`
+			}
+			if err := getJSON(ctx, c, "https://api.github.com/user", &u); err != nil {
+				return nil, err
+			}
+			name := u.Name
+			if name == "" {
+				name = u.Login
+			}
+			return &Identity{Provider: "github", ID: fmt.Sprint(u.ID), Name: name, Email: u.Email}, nil
+		},
+	}
+}
+
+func getJSON(ctx context.Context, c *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oauth: userinfo: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// 세션 키
+const (
+	keyState    = "oauth_state"
+	keyVerifier = "oauth_verifier"
+	keyProvider = "oauth_provider"
+	keyReturn   = "oauth_return"
+	keyUser     = "user"
+)
+
+// Handler 는 로그인 흐름 핸들러 묶음이다. 세션 미들웨어가 필요하다.
+type Handler struct {
+	// Client 가 있으면 토큰 교환과 사용자 정보 조회에 사용한다. nil 이면 http.DefaultClient.
+	Client *http.Client
+
+	providers map[string]*Provider
+}
+
+// New 는 공급자들로 Handler 를 만든다.
+func New(providers ...*Provider) *Handler {
+	h := &Handler{providers: map[string]*Provider{}}
+	for _, p := range providers {
+		h.providers[p.Name] = p
+	}
+	return h
+}
+
+// Mount 는 /auth/login, /auth/callback, /auth/logout 을 등록한다.
+func (h *Handler) Mount(r *router.Router) {
+	r.GET("/auth/login", h.Login)
+	r.GET("/auth/callback", h.Callback)
+	r.POST("/auth/logout", h.Logout)
+}
+
+// Login 은 state 와 PKCE verifier 를 세션에 저장하고 공급자로 리다이렉트한다.
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	p, err := h.provider(r.URL.Query().Get("provider"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	state := randomString()
+	verifier := oauth2.GenerateVerifier()
+	session.Set(r, keyState, state)
+	session.Set(r, keyVerifier, verifier)
+	session.Set(r, keyProvider, p.Name)
+	session.Set(r, keyReturn, SafeReturn(r.URL.Query().Get("return")))
+	url := p.Config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// Callback 은 인가 코드를 교환하고 사용자 정보를 세션에 저장한다.
+func (h *Handler) Callback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	state := session.GetString(r, keyState)
+	verifier := session.GetString(r, keyVerifier)
+	p := h.providers[session.GetString(r, keyProvider)]
+	ret := session.GetString(r, keyReturn)
+	for _, k := range []string{keyState, keyVerifier, keyProvider, keyReturn} {
+		session.Delete(r, k)
+	}
+	if errMsg := q.Get("error"); errMsg != "" {
+		loginFailed(r, p, errMsg)
+		http.Error(w, "login failed: "+errMsg, http.StatusUnauthorized)
+		return
+	}
+	if p == nil || state == "" || q.Get("state") != state {
+		loginFailed(r, p, "invalid_state")
+		http.Error(w, "invalid login state", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	if h.Client != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, h.Client)
+	}
+	tok, err := p.Config.Exchange(ctx, q.Get("code"), oauth2.VerifierOption(verifier))
+	if err != nil {
+		logging.From(ctx).Error("oauth: exchange", "provider", p.Name, "err", err)
+		loginFailed(r, p, "exchange_failed")
+		http.Error(w, "login failed", http.StatusUnauthorized)
+		return
+	}
+	id, err := p.UserInfo(ctx, p.Config.Client(ctx, tok))
+	if err != nil {
+		logging.From(ctx).Error("oauth: userinfo", "provider", p.Name, "err", err)
+		loginFailed(r, p, "userinfo_failed")
+		http.Error(w, "login failed", http.StatusBadGateway)
+		return
+	}
+	SignIn(r, *id)
+	audit.RecordRequest(r, audit.Event{
+		Type: "auth.login", Actor: id.Provider + ":" + id.ID, Target: id.Email, Fields: map[string]any{"method": "oauth:" + p.Name},
+	})
+	if ret == "" {
+		ret = "/"
+	}
+	http.Redirect(w, r, ret, http.StatusFound)
+}
+
+// Logout 은 세션을 삭제하고 홈으로 리다이렉트한다.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	if User(r) != nil {
+		audit.RecordRequest(r, audit.Event{Type: "auth.logout"})
+	}
+	session.Destroy(r)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// loginFailed 는 실패한 OAuth 로그인을 감사 로그에 남긴다. p 는 state 가 없으면 nil 이다.
+func loginFailed(r *http.Request, p *Provider, reason string) {
+	fields := map[string]any{"reason": reason}
+	if p != nil {
+		fields["method"] = "oauth:" + p.Name
+	}
+	audit.RecordRequest(r, audit.Event{Type: "auth.login", Outcome: audit.Failure, Actor: "anonymous", Fields: fields})
+}
+
+// provider 는 이름으로 공급자를 찾는다. 공급자가 하나뿐이면 이름을 생략할 수 있다.
+func (h *Handler) provider(name string) (*Provider, error) {
+	if name == "" && len(h.providers) == 1 {
+		for _, p := range h.providers {
+			return p, nil
+		}
+	}
+	if p, ok := h.providers[name]; ok {
+		return p, nil
+	}
+	names := make([]string, 0, len(h.providers))
+	for n := range h.providers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown provider %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// SignIn 은 id 를 로그인 사용자로 세션에 저장한다. 로그인으로 권한이 바뀌므로 세션 ID 를 새로 발급한다.
+// 다른 로그인 방식(비밀번호 등)도 이 함수로 로그인시키면 User 로 같은 형태의 사용자를 읽을 수 있다.
+func SignIn(r *http.Request, id Identity) {
+	session.Renew(r)
+	session.Set(r, keyUser, id)
+}
+
+// User 는 세션에 저장된 로그인 사용자를 돌려준다. 로그인하지 않았으면 nil 이다.
+func User(r *http.Request) *Identity {
+	// 세션 값은 JSON 으로 저장되므로 다시 구조체로 변환한다.
+	v := session.Get(r, keyUser)
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var id Identity
+	if json.Unmarshal(b, &id) != nil || id.ID == "" {
+		return nil
+	}
+	return &id
+}
+
+// SafeReturn 은 오픈 리다이렉트를 막기 위해 같은 사이트의 경로만 허용한다. 아니면 "" 이다.
+func SafeReturn(p string) string {
+	if strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "//") && !strings.HasPrefix(p, "/\\") {
+		return p
+	}
+	return ""
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
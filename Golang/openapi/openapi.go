@@ -0,0 +1,201 @@
+// Package openapi 는 라우터에 등록된 라우트로 OpenAPI 3 문서를 만든다.
+//
+// 경로와 경로 파라미터는 라우터에서 가져오고, 요약·요청/응답 스키마는 Describe 로 선언한다.
+// 스키마는 Go 타입의 json 태그에서 리플렉션으로 만든다.
+package openapi
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/apperror"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Param 은 쿼리 파라미터 하나의 설명이다.
+type Param struct {
+	Name        string
+	Description string
+	// Type 은 JSON 스키마 타입이다. 기본값 "string".
+	Type     string
+	Required bool
+}
+
+// Operation 은 라우트 하나의 문서 정보다.
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Query       []Param
+	// Request 와 Response 는 본문 타입의 값이다. (예: store.User{}) 타입만 사용한다.
+	Request  any
+	Response any
+	// Status 는 성공 응답 코드다. 기본값 200.
+	Status int
+	// Auth 가 true 이면 Bearer 토큰이 필요하다.
+	Auth bool
+	// Errors 는 문서화할 에러 응답 코드다.
+	Errors []int
+}
+
+// Document 는 OpenAPI 문서의 메타데이터와 선언된 Operation 들이다.
+type Document struct {
+	Title       string
+	Version     string
+	Description string
+	// Include 는 문서에 포함할 경로 접두사다. 비어 있으면 모든 라우트를 포함한다.
+	Include []string
+
+	mu  sync.RWMutex
+	ops map[string]Operation
+}
+
+// New 는 빈 문서를 만든다.
+func New(title, version string) *Document {
+	return &Document{Title: title, Version: version, ops: map[string]Operation{}}
+}
+
+// Describe 는 method 와 pattern 라우트의 문서 정보를 선언한다.
+func (d *Document) Describe(method, pattern string, op Operation) {
+	d.mu.Lock()
+	d.ops[method+" "+pattern] = op
+	d.mu.Unlock()
+}
+
+// Build 는 routes 로 OpenAPI 문서(JSON 객체)를 만든다.
+func (d *Document) Build(routes []router.RouteInfo) map[string]any {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	sc := newSchemas()
+	errRef := sc.of(apperror.Problem{})
+	paths := map[string]map[string]any{}
+	for _, rt := range routes {
+		if !d.included(rt.Pattern) || strings.HasSuffix(rt.Pattern, "/") && rt.Pattern != "/" {
+			continue
+		}
+		op := d.ops[rt.Method+" "+rt.Pattern]
+		item := paths[rt.Pattern]
+		if item == nil {
+			item = map[string]any{}
+			paths[rt.Pattern] = item
+		}
+		item[strings.ToLower(rt.Method)] = d.operation(rt, op, sc, errRef)
+	}
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]any{"title": d.Title, "version": d.Version, "description": d.Description},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": sc.defs,
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+	return doc
+}
+
+// operation 은 라우트 하나의 Operation 객체를 만든다.
+func (d *Document) operation(rt router.RouteInfo, op Operation, sc *schemas, errRef map[string]any) map[string]any {
+	out := map[string]any{"operationId": operationID(rt)}
+	if op.Summary != "" {
+		out["summary"] = op.Summary
+	}
+	if op.Description != "" {
+		out["description"] = op.Description
+	}
+	if len(op.Tags) > 0 {
+		out["tags"] = op.Tags
+	}
+	var params []map[string]any
+	for _, seg := range strings.Split(rt.Pattern, "/") {
+		if len(seg) > 2 && seg[0] == '{' && seg[len(seg)-1] == '}' {
+			params = append(params, map[string]any{
+				"name": seg[1 : len(seg)-1], "in": "path", "required": true,
+				"schema": map[string]any{"type": "string"},
+			})
+		}
+	}
+	for _, q := range op.Query {
+		typ := q.Type
+		if typ == "" {
+			typ = "string"
+		}
+		p := map[string]any{"name": q.Name, "in": "query", "required": q.Required, "schema": map[string]any{"type": typ}}
+		if q.Description != "" {
+			p["description"] = q.Description
+		}
+		params = append(params, p)
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+	if op.Request != nil {
+		out["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": sc.of(op.Request)}},
+		}
+	}
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	ok := map[string]any{"description": http.StatusText(status)}
+	if op.Response != nil {
+		ok["content"] = map[string]any{"application/json": map[string]any{"schema": sc.of(op.Response)}}
+	}
+	responses := map[string]any{itoa(status): ok}
+	errs := op.Errors
+	if op.Auth {
+		out["security"] = []map[string][]string{{"bearer": {}}}
+		errs = append(errs, http.StatusUnauthorized)
+	}
+	for _, code := range errs {
+		responses[itoa(code)] = map[string]any{
+			"description": http.StatusText(code),
+			"content":     map[string]any{apperror.ContentType: map[string]any{"schema": errRef}},
+		}
+	}
+	out["responses"] = responses
+	return out
+}
+
+func (d *Document) included(pattern string) bool {
+	if len(d.Include) == 0 {
+		return true
+	}
+	for _, p := range d.Include {
+		if strings.HasPrefix(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// operationID 는 "GET /api/v1/users/{id}" 를 "getApiV1UsersById" 로 바꾼다.
+func operationID(rt router.RouteInfo) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(rt.Method))
+	for _, seg := range strings.Split(rt.Pattern, "/") {
+		if seg == "" {
+			continue
+		}
+		if seg[0] == '{' {
+			b.WriteString("By")
+			seg = strings.Trim(seg, "{}")
+		}
+		for _, part := range strings.FieldsFunc(seg, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// Handler 는 rt 의 라우트로 만든 문서를 JSON 으로 응답하는 핸들러다. (/openapi.json)
+func (d *Document) Handler(rt *router.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		api.WriteJSON(w, http.StatusOK, d.Build(rt.Routes()))
+	}
+}
@@ -0,0 +1,182 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textType      = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	// 제네릭 타입 이름의 패키지 경로 ("Page[github.com/x/store.User]" → "Page[User]")
+	pkgPath = regexp.MustCompile(`[\w./-]*\.`)
+)
+
+// schemas 는 이름 있는 구조체 타입의 스키마를 components/schemas 에 모은다.
+type schemas struct {
+	defs map[string]any
+	seen map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{defs: map[string]any{}, seen: map[reflect.Type]string{}}
+}
+
+// of 는 v 의 타입에 대한 스키마(또는 $ref)를 돌려준다.
+func (s *schemas) of(v any) map[string]any {
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s *schemas) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType):
+		// 직접 JSON 을 만드는 타입은 모양을 알 수 없다.
+		return map[string]any{}
+	case t.Implements(textType) || reflect.PointerTo(t).Implements(textType):
+		return map[string]any{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name := schemaName(t)
+		if _, ok := s.seen[t]; !ok {
+			s.seen[t] = name
+			// 재귀 타입을 위해 먼저 등록한 뒤 채운다.
+			s.defs[name] = map[string]any{}
+			s.defs[name] = s.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]any{}
+}
+
+// object 는 구조체의 json 필드로 object 스키마를 만든다.
+func (s *schemas) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			// 임베드된 구조체의 필드는 바깥 구조체에 포함된다.
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				inner := s.object(ft)
+				for k, v := range inner["properties"].(map[string]any) {
+					props[k] = v
+				}
+				if req, ok := inner["required"].([]string); ok {
+					required = append(required, req...)
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = constrain(s.schema(f.Type), f.Tag.Get("validate"))
+		if strings.Contains(f.Tag.Get("validate"), "required") ||
+			!strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+	}
+	out := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		out["required"] = required
+	}
+	return out
+}
+
+// constrain 은 validate 태그의 규칙을 스키마 제약으로 옮긴다. ($ref 스키마는 그대로 둔다)
+func constrain(sc map[string]any, tag string) map[string]any {
+	if tag == "" || sc["$ref"] != nil {
+		return sc
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		n, _ := strconv.Atoi(arg)
+		switch sc["type"] {
+		case "string":
+			switch name {
+			case "min":
+				sc["minLength"] = n
+			case "max":
+				sc["maxLength"] = n
+			case "len":
+				sc["minLength"], sc["maxLength"] = n, n
+			case "email":
+				sc["format"] = "email"
+			case "url":
+				sc["format"] = "uri"
+			case "oneof":
+				sc["enum"] = strings.Fields(arg)
+			case "required":
+				sc["minLength"] = 1
+			}
+		case "array":
+			switch name {
+			case "min":
+				sc["minItems"] = n
+			case "max":
+				sc["maxItems"] = n
+			}
+		case "integer", "number":
+			switch name {
+			case "min":
+				sc["minimum"] = n
+			case "max":
+				sc["maximum"] = n
+			}
+		}
+	}
+	return sc
+}
+
+// schemaName 은 타입 이름을 스키마 이름으로 바꾼다. ("Page[User]" → "Page_User")
+func schemaName(t reflect.Type) string {
+	n := pkgPath.ReplaceAllString(t.Name(), "")
+	n = strings.NewReplacer("[", "_", "]", "", ",", "_", "*", "").Replace(n)
+	return n
+}
+
+func itoa(n int) string { return strconv.Itoa(n) }
@@ -0,0 +1,39 @@
+package openapi
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// Swagger UI 버전 (unpkg CDN 에서 정적 파일을 불러온다)
+const swaggerUIVersion = "5.17.14"
+
+var uiPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@{{.Version}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@{{.Version}}/swagger-ui-bundle.js"></script>
+<script>
+window.onload = function () {
+  SwaggerUIBundle({url: {{.SpecURL}}, dom_id: "#swagger-ui"});
+};
+</script>
+</body>
+</html>
+`))
+
+// UIHandler 는 specURL 의 문서를 보여주는 Swagger UI 페이지 핸들러다. (/docs)
+// 보안 헤더 미들웨어의 기본 CSP 는 외부 스크립트를 막으므로 이 페이지에서만 CDN 을 허용한다.
+func UIHandler(title, specURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy",
+			"default-src 'self'; script-src 'self' 'unsafe-inline' https://unpkg.com; style-src 'self' https://unpkg.com; img-src 'self' data: https://unpkg.com")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		uiPage.Execute(w, map[string]string{"Title": title, "SpecURL": specURL, "Version": swaggerUIVersion})
+	}
+}
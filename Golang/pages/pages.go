@@ -0,0 +1,432 @@
+// Package pages 는 디렉터리의 Markdown(.md)과 HTML 템플릿(.html) 파일을 경로에 맞춰 페이지로 내보낸다.
+//
+//	index.md          → /
+//	about.md          → /about
+//	docs/index.html   → /docs
+//	docs/setup.md     → /docs/setup
+//
+// 파일 첫머리의 "---" 줄 사이에 "키: 값" 형식의 머리말(front matter)을 쓸 수 있다. title, description,
+// layout, date, updated(2006-01-02), draft 는 정해진 뜻이 있고 나머지는 Meta 로 템플릿에 넘어간다.
+// 본문은 레이아웃 페이지(기본 "page.html")의 .Content 로 렌더링된다. 이름이 _ 나 . 로 시작하는 파일과
+// 디렉터리는 건너뛴다.
+package pages
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/feed"
+	"github.com/hgsong234/_stack/Golang/i18n"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/markdown"
+	"github.com/hgsong234/_stack/Golang/render"
+	"github.com/hgsong234/_stack/Golang/router"
+	"github.com/hgsong234/_stack/Golang/sse"
+	"github.com/hgsong234/_stack/Golang/timing"
+)
+
+// Page 는 파일 하나에서 읽은 페이지다.
+type Page struct {
+	// Path 는 URL 경로, File 은 디렉터리 안의 파일 경로다.
+	Path string
+	File string
+
+	Title       string
+	Description string
+	// Layout 은 본문을 감쌀 템플릿 페이지다. 비어 있으면 Options.Layout.
+	Layout string
+	Date   time.Time
+	// Updated 는 머리말의 updated, 없으면 파일의 수정 시각이다.
+	Updated time.Time
+	Draft   bool
+	// Meta 는 나머지 머리말이다.
+	Meta map[string]string
+
+	body     string
+	markdown bool
+	tmpl     *template.Template
+}
+
+// View 는 레이아웃 템플릿이 받는 값이다.
+type View struct {
+	*Page
+	Lang    string
+	Content template.HTML
+	// Live 이면 레이아웃이 실시간 새로고침 스크립트(LiveScript)를 넣는다.
+	Live bool
+	// Data 는 Options.Data 가 돌려준 요청별 값이다.
+	Data map[string]any
+}
+
+// LiveScript 는 실시간 새로고침 스크립트의 경로다. CSP 가 인라인 스크립트를 막으므로 파일로 내보낸다.
+const LiveScript = "/_pages/live.js"
+
+// Options 는 Site 설정이다.
+type Options struct {
+	// Views 는 레이아웃을 렌더링할 템플릿 엔진이다.
+	Views *render.Engine
+	// Layout 은 기본 레이아웃 페이지다. 기본값 "page.html".
+	Layout string
+	// Funcs 는 .html 페이지에서 쓸 템플릿 함수다.
+	Funcs template.FuncMap
+	// Markdown 은 .md 페이지의 확장 문법이다. nil 이면 markdown.Default. 파일은 믿을 수 있는 내용으로 보고 거르지 않는다.
+	Markdown *markdown.Options
+	// Data 가 있으면 요청마다 View.Data 를 채운다.
+	Data func(r *http.Request) map[string]any
+	// Live 이면 요청마다 파일을 다시 읽고, 파일이 바뀌면 열려 있는 페이지를 새로고침한다. draft 도 보인다. (개발 모드)
+	Live bool
+}
+
+// Site 는 디렉터리 하나의 페이지 묶음이다.
+type Site struct {
+	fsys fs.FS
+	opts Options
+	live *sse.Broker
+
+	mu    sync.RWMutex
+	pages map[string]*Page
+	stamp string
+}
+
+// New 는 fsys 의 페이지를 모두 읽은 Site 를 만든다.
+func New(fsys fs.FS, opts Options) (*Site, error) {
+	if opts.Layout == "" {
+		opts.Layout = "page.html"
+	}
+	if opts.Markdown == nil {
+		opts.Markdown = &markdown.Default
+	}
+	s := &Site{fsys: fsys, opts: opts}
+	if opts.Live {
+		s.live = sse.NewBroker(0)
+	}
+	if err := s.Load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Load 는 페이지를 다시 읽는다. 실패하면 기존 페이지를 유지한다.
+func (s *Site) Load() error {
+	pages := map[string]*Page{}
+	err := fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		base := d.Name()
+		if name != "." && (strings.HasPrefix(base, "_") || strings.HasPrefix(base, ".")) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		ext := path.Ext(name)
+		if d.IsDir() || (ext != ".md" && ext != ".html") {
+			return nil
+		}
+		p, err := s.read(name)
+		if err != nil {
+			return fmt.Errorf("pages: %s: %w", name, err)
+		}
+		if p.Draft && !s.opts.Live {
+			return nil
+		}
+		if other, ok := pages[p.Path]; ok {
+			return fmt.Errorf("pages: %s and %s both map to %s", other.File, name, p.Path)
+		}
+		pages[p.Path] = p
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.pages = pages
+	s.mu.Unlock()
+	return nil
+}
+
+// read 는 파일 하나를 Page 로 읽는다.
+func (s *Site) read(name string) (*Page, error) {
+	src, err := fs.ReadFile(s.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	meta, body, err := frontMatter(string(src))
+	if err != nil {
+		return nil, err
+	}
+	p := &Page{Path: urlPath(name), File: name, Meta: map[string]string{}, body: body, markdown: path.Ext(name) == ".md"}
+	if strings.ContainsAny(p.Path, "{}") {
+		return nil, errors.New("file names must not contain { or }")
+	}
+	for k, v := range meta {
+		switch k {
+		case "title":
+			p.Title = v
+		case "description":
+			p.Description = v
+		case "layout":
+			p.Layout = v
+		case "date", "updated":
+			t, err := time.Parse(time.DateOnly, v)
+			if err != nil {
+				return nil, fmt.Errorf("%s %q is not 2006-01-02", k, v)
+			}
+			if k == "date" {
+				p.Date = t
+			} else {
+				p.Updated = t
+			}
+		case "draft":
+			if p.Draft, err = strconv.ParseBool(v); err != nil {
+				return nil, fmt.Errorf("draft %q is not a boolean", v)
+			}
+		default:
+			p.Meta[k] = v
+		}
+	}
+	if info, err := fs.Stat(s.fsys, name); err == nil && p.Updated.IsZero() {
+		p.Updated = info.ModTime()
+	}
+	if p.Title == "" && p.markdown {
+		p.Title = markdown.Title(body)
+	}
+	if !p.markdown {
+		if p.tmpl, err = template.New(name).Funcs(s.opts.Funcs).Parse(body); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Text 는 검색 색인에 넣을 본문이다. 템플릿 액션과 HTML 태그는 빼고, Markdown 문법 기호는 그대로 둔다.
+func (p *Page) Text() string {
+	var b strings.Builder
+	s := p.body
+	for s != "" {
+		i := strings.IndexAny(s, "<{")
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:i])
+		end := ">"
+		if s[i] == '{' {
+			if !strings.HasPrefix(s[i:], "{{") {
+				b.WriteByte('{')
+				s = s[i+1:]
+				continue
+			}
+			end = "}}"
+		}
+		j := strings.Index(s[i:], end)
+		if j < 0 {
+			b.WriteString(s[i:])
+			break
+		}
+		b.WriteByte(' ')
+		s = s[i+j+len(end):]
+	}
+	return b.String()
+}
+
+// urlPath 는 파일 경로를 URL 경로로 바꾼다. index 는 디렉터리 경로가 된다.
+func urlPath(name string) string {
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if name == "index" {
+		return "/"
+	}
+	return "/" + strings.TrimSuffix(name, "/index")
+}
+
+// frontMatter 는 첫 줄이 "---" 이면 다음 "---" 줄까지를 "키: 값" 으로 읽고 나머지 본문을 돌려준다.
+func frontMatter(src string) (map[string]string, string, error) {
+	src = strings.TrimPrefix(src, "\ufeff")
+	if !strings.HasPrefix(src, "---\n") && !strings.HasPrefix(src, "---\r\n") {
+		return nil, src, nil
+	}
+	lines := strings.SplitAfter(src, "\n")
+	meta := map[string]string{}
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "---" {
+			return meta, strings.Join(lines[i+1:], ""), nil
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, "", fmt.Errorf("front matter line %d: %q is not \"key: value\"", i+1, line)
+		}
+		v = strings.TrimSpace(v)
+		if len(v) >= 2 && (v[0] == '"' && v[len(v)-1] == '"' || v[0] == '\'' && v[len(v)-1] == '\'') {
+			v = v[1 : len(v)-1]
+		}
+		meta[strings.ToLower(strings.TrimSpace(k))] = v
+	}
+	return nil, "", errors.New("front matter is not closed with ---")
+}
+
+// Pages 는 경로 순서의 페이지 목록이다.
+func (s *Site) Pages() []*Page {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*Page, 0, len(s.pages))
+	for _, p := range s.pages {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// Entries 는 피드와 사이트맵에 넣을 페이지다. date 가 있는 페이지가 피드의 글이 되고, draft 는 빠진다.
+func (s *Site) Entries(context.Context) ([]feed.Entry, error) {
+	var out []feed.Entry
+	for _, p := range s.Pages() {
+		if p.Draft {
+			continue
+		}
+		out = append(out, feed.Entry{Path: p.Path, Title: p.Title, Summary: p.Description, Published: p.Date, Updated: p.Updated})
+	}
+	return out, nil
+}
+
+// Lookup 은 URL 경로의 페이지다. 끝의 / 는 무시한다.
+func (s *Site) Lookup(urlPath string) *Page {
+	if urlPath != "/" {
+		urlPath = strings.TrimSuffix(urlPath, "/")
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pages[urlPath]
+}
+
+// Mount 는 페이지마다 GET 라우트를 등록한다. 먼저 등록한 같은 경로의 라우트가 이긴다.
+// Live 이면 새로고침 이벤트와 스크립트 경로도 등록한다. 실행 중에 새로 생긴 파일은 Fallback 으로 내보낸다.
+func (s *Site) Mount(r router.Routes, mws ...router.Middleware) {
+	for _, p := range s.Pages() {
+		r.Handle(http.MethodGet, p.Path, s.Handler(), mws...)
+	}
+	if s.live != nil {
+		r.GET("/_pages/live", s.live.Handler())
+		r.GET(LiveScript, liveScript)
+	}
+}
+
+// Handler 는 요청 경로의 페이지를 렌더링한다. 페이지가 없으면 404 다.
+func (s *Site) Handler() http.Handler {
+	return s.Fallback(http.HandlerFunc(http.NotFound))
+}
+
+// Fallback 은 요청 경로에 페이지가 있으면 렌더링하고 없으면 next 로 넘긴다. 라우터의 NotFound 에 건다.
+func (s *Site) Fallback(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if s.opts.Live {
+			if err := s.Load(); err != nil {
+				logging.From(r.Context()).Error("pages: reload", "err", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		p := s.Lookup(r.URL.Path)
+		if p == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := s.render(w, r, p); err != nil {
+			logging.From(r.Context()).Error("pages: render", "file", p.File, "err", err)
+		}
+	})
+}
+
+func (s *Site) render(w http.ResponseWriter, r *http.Request, p *Page) error {
+	defer timing.Start(r.Context(), "render")()
+	view := &View{Page: p, Lang: i18n.Lang(r.Context()), Live: s.opts.Live}
+	if s.opts.Data != nil {
+		view.Data = s.opts.Data(r)
+	}
+	if p.markdown {
+		view.Content = template.HTML(markdown.Render(p.body, *s.opts.Markdown))
+	} else {
+		var buf bytes.Buffer
+		if err := p.tmpl.Execute(&buf, view); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return err
+		}
+		view.Content = template.HTML(buf.String())
+	}
+	layout := p.Layout
+	if layout == "" {
+		layout = s.opts.Layout
+	}
+	return s.opts.Views.Render(w, layout, view)
+}
+
+// Watch 는 Live 일 때 interval 마다 파일의 크기와 수정 시각을 보고, 바뀌었으면 열려 있는 페이지에 새로고침 이벤트를 보낸다.
+// ctx 가 끝날 때까지 돌아간다.
+func (s *Site) Watch(ctx context.Context, interval time.Duration, also ...fs.FS) {
+	if s.live == nil {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		stamp := fingerprint(append([]fs.FS{s.fsys}, also...))
+		s.mu.Lock()
+		changed := s.stamp != "" && stamp != s.stamp
+		s.stamp = stamp
+		s.mu.Unlock()
+		if changed {
+			s.live.Publish(sse.Event{Event: "reload", Data: "changed"})
+		}
+	}
+}
+
+// fingerprint 는 파일 이름, 크기, 수정 시각을 이어 붙인 값이다.
+func fingerprint(fss []fs.FS) string {
+	var b strings.Builder
+	for _, fsys := range fss {
+		var names []string
+		fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				names = append(names, name+" "+strconv.FormatInt(info.Size(), 10)+" "+info.ModTime().String())
+			}
+			return nil
+		})
+		slices.Sort(names)
+		b.WriteString(strings.Join(names, "\n") + "\n\n")
+	}
+	return b.String()
+}
+
+func liveScript(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(`new EventSource("/_pages/live").addEventListener("reload", () => location.reload());` + "\n"))
+}
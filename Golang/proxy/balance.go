@@ -0,0 +1,200 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/metrics"
+	"github.com/hgsong234/_stack/Golang/middleware"
+)
+
+// 부하 분산 방식
+const (
+	RoundRobin = "round_robin"
+	LeastConn  = "least_conn"
+)
+
+var (
+	upstreamRequests = metrics.NewCounterVec("proxy_upstream_requests_total",
+		"Total number of proxied requests by upstream and status.", "upstream", "status")
+	upstreamErrors = metrics.NewCounterVec("proxy_upstream_errors_total",
+		"Proxied requests that failed with a transport error or a 5xx response.", "upstream")
+	upstreamDuration = metrics.NewHistogramVec("proxy_upstream_duration_seconds",
+		"Proxied request latency by upstream.", nil, "upstream")
+	upstreamActive = metrics.NewGaugeVec("proxy_upstream_active_requests",
+		"Number of requests currently being proxied to the upstream.", "upstream")
+	upstreamHealthy = metrics.NewGaugeVec("proxy_upstream_healthy",
+		"Whether the upstream passes its health check (1) or is ejected (0).", "upstream")
+)
+
+// HealthCheck 는 백엔드 상태 검사 설정이다. 0 인 값은 기본값을 쓴다.
+type HealthCheck struct {
+	// Path 는 백엔드 호스트에 GET 으로 요청할 경로다. Upstream 의 경로와는 무관하다.
+	// 2xx, 3xx 응답이면 정상이다. 기본값 "/healthz".
+	Path string
+	// Interval 은 검사 주기다. 기본값 10초.
+	Interval time.Duration
+	// Timeout 은 검사 하나의 시간 제한이다. 기본값 2초.
+	Timeout time.Duration
+	// UnhealthyThreshold 번 연속 실패하면 백엔드를 제외한다. 기본값 3.
+	UnhealthyThreshold int
+	// HealthyThreshold 번 연속 성공하면 제외된 백엔드를 되돌린다. 기본값 2.
+	HealthyThreshold int
+}
+
+func (hc HealthCheck) withDefaults() HealthCheck {
+	if hc.Path == "" {
+		hc.Path = "/healthz"
+	}
+	if hc.Interval <= 0 {
+		hc.Interval = 10 * time.Second
+	}
+	if hc.Timeout <= 0 {
+		hc.Timeout = 2 * time.Second
+	}
+	if hc.UnhealthyThreshold <= 0 {
+		hc.UnhealthyThreshold = 3
+	}
+	if hc.HealthyThreshold <= 0 {
+		hc.HealthyThreshold = 2
+	}
+	return hc
+}
+
+// backend 는 규칙에 속한 백엔드 하나다.
+type backend struct {
+	target  *url.URL
+	name    string // 메트릭 레이블 (scheme://host)
+	handler http.Handler
+	healthy atomic.Bool
+	active  atomic.Int64
+	// 연속 성공·실패 횟수. 상태 검사 고루틴만 사용한다.
+	oks, fails int
+}
+
+func newBackend(target *url.URL) *backend {
+	b := &backend{target: target, name: target.Scheme + "://" + target.Host}
+	b.healthy.Store(true)
+	upstreamHealthy.Set(1, b.name)
+	return b
+}
+
+// pick 은 정상인 백엔드 중 하나를 고른다. 모두 제외되었으면 nil 이다.
+// 최소 연결 방식에서도 시작 위치를 돌려 가며 골라, 진행 중인 요청 수가 같으면 고르게 나눈다.
+func (rt *route) pick() *backend {
+	n := len(rt.backends)
+	start := int(rt.next.Add(1) % uint64(n))
+	var best *backend
+	for i := 0; i < n; i++ {
+		b := rt.backends[(start+i)%n]
+		if !b.healthy.Load() {
+			continue
+		}
+		if rt.Balance != LeastConn {
+			return b
+		}
+		if best == nil || b.active.Load() < best.active.Load() {
+			best = b
+		}
+	}
+	return best
+}
+
+// serve 는 백엔드를 골라 요청을 보내고 백엔드별 지연 시간과 에러를 기록한다.
+func (rt *route) serve(w http.ResponseWriter, r *http.Request) {
+	b := rt.pick()
+	if b == nil {
+		api.WriteError(w, api.NewError(http.StatusServiceUnavailable, "no_upstream", "no healthy upstream is available"))
+		return
+	}
+	b.active.Add(1)
+	upstreamActive.Add(1, b.name)
+	start := time.Now()
+	sw := middleware.NewStatusWriter(w)
+	defer func() {
+		b.active.Add(-1)
+		upstreamActive.Add(-1, b.name)
+		upstreamDuration.Observe(time.Since(start).Seconds(), b.name)
+		upstreamRequests.Inc(b.name, strconv.Itoa(sw.Code()))
+		if sw.Code() >= 500 {
+			upstreamErrors.Inc(b.name)
+		}
+	}()
+	b.handler.ServeHTTP(sw, r)
+}
+
+// CheckHealth 는 ctx 가 끝날 때까지 HealthCheck 가 있는 규칙의 백엔드를 주기적으로 검사한다.
+func (p *Proxy) CheckHealth(ctx context.Context) {
+	for _, rt := range p.routes {
+		if rt.HealthCheck == nil {
+			continue
+		}
+		hc := rt.HealthCheck.withDefaults()
+		backends := rt.backends
+		if rt.canary != nil {
+			backends = append(slices.Clip(backends), rt.canary.backends...)
+		}
+		for _, b := range backends {
+			go b.watch(ctx, p.checkClient, hc)
+		}
+	}
+	<-ctx.Done()
+}
+
+// watch 는 백엔드 하나를 hc.Interval 마다 검사하고 연속 결과에 따라 제외하거나 되돌린다.
+func (b *backend) watch(ctx context.Context, client *http.Client, hc HealthCheck) {
+	t := time.NewTicker(hc.Interval)
+	defer t.Stop()
+	for {
+		err := b.probe(ctx, client, hc)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			b.oks, b.fails = 0, b.fails+1
+			if b.fails == hc.UnhealthyThreshold && b.healthy.Swap(false) {
+				upstreamHealthy.Set(0, b.name)
+				logging.Default().Warn("proxy: upstream ejected", "upstream", b.name, "err", err)
+			}
+		} else {
+			b.oks, b.fails = b.oks+1, 0
+			if b.oks == hc.HealthyThreshold && !b.healthy.Swap(true) {
+				upstreamHealthy.Set(1, b.name)
+				logging.Default().Info("proxy: upstream restored", "upstream", b.name)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// probe 는 상태 검사 요청 하나를 보낸다.
+func (b *backend) probe(ctx context.Context, client *http.Client, hc HealthCheck) error {
+	ctx, cancel := context.WithTimeout(ctx, hc.Timeout)
+	defer cancel()
+	u := url.URL{Scheme: b.target.Scheme, Host: b.target.Host, Path: hc.Path}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("health check returned %d", resp.StatusCode)
+	}
+	return nil
+}
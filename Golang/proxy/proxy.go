@@ -0,0 +1,298 @@
+// Package proxy 는 경로 접두사나 호스트별로 요청을 내부 백엔드에 전달하는 리버스 프록시다.
+//
+// 요청·응답 본문은 버퍼링하지 않고 흘려보내며, 백엔드 연결은 Transport 가 재사용한다.
+// 백엔드에는 X-Forwarded-For/-Host/-Proto 와 X-Request-ID 를 붙여 보낸다.
+// 규칙 하나에 백엔드가 여럿이면 라운드 로빈 또는 최소 연결 방식으로 나누고,
+// 상태 검사에 실패한 백엔드는 회복될 때까지 제외한다. Canary 를 지정하면 요청 일부를 카나리 백엔드로 보낸다.
+// 응답 헤더와 본문(HTML 링크, JSON 필드)은 Transform 으로 스트리밍하며 바꿀 수 있다.
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/canary"
+	"github.com/hgsong234/_stack/Golang/httpclient"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/middleware"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Route 는 요청을 백엔드 하나에 보내는 규칙이다. Host 와 Prefix 중 하나 이상이 있어야 한다.
+type Route struct {
+	// Host 는 요청 Host 헤더(포트 제외)와 비교한다. 비어 있으면 모든 호스트다.
+	Host string
+	// Prefix 는 경로 접두사다. (예: "/billing/") 비어 있으면 "/" 다.
+	Prefix string
+	// Upstream 은 백엔드 주소다. (예: "http://10.0.0.5:9000/api")
+	Upstream string
+	// Upstreams 는 부하를 나눌 백엔드 주소 목록이다. Upstream 과 함께 쓰면 둘 다 포함된다.
+	Upstreams []string
+	// Balance 는 "round_robin"(기본값) 또는 "least_conn" 이다.
+	Balance string
+	// HealthCheck 가 있으면 백엔드를 주기적으로 검사한다.
+	HealthCheck *HealthCheck
+	// StripPrefix 가 true 이면 백엔드에 보낼 때 경로에서 Prefix 를 뗀다.
+	StripPrefix bool
+	// SetHeaders, RemoveHeaders 는 백엔드로 보내는 요청 헤더를 바꾼다.
+	SetHeaders    map[string]string
+	RemoveHeaders []string
+	// ResponseHeaders 는 클라이언트에게 돌려줄 응답 헤더에 덮어쓴다. Transform 의 헤더 치환 뒤에 적용한다.
+	ResponseHeaders map[string]string
+	// Transform 이 있으면 응답 헤더와 본문을 바꾼다.
+	Transform *Transform
+	// MaxBodyBytes 가 0 보다 크면 이 경로의 요청 본문 제한을 바꾼다. 0 이면 전역 제한을 따른다.
+	MaxBodyBytes int64
+	// Canary 가 있으면 요청 일부를 카나리 백엔드로 보낸다.
+	Canary *Canary
+}
+
+// Canary 는 규칙 하나의 카나리 백엔드다. Split 이 canary 로 고른 요청은 Upstreams 로 가고,
+// 나머지는 규칙의 Upstream(s) 로 간다. 부하 분산과 상태 검사는 규칙의 설정을 따른다.
+type Canary struct {
+	Upstreams []string
+	Split     *canary.Split
+}
+
+// Options 는 백엔드 연결 풀 설정이다. 0 인 값은 기본값을 쓴다.
+type Options struct {
+	// MaxIdleConnsPerHost 는 백엔드마다 유지할 유휴 연결 수다. 기본값 32.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout 은 유휴 연결을 닫기까지의 시간이다. 기본값 90초.
+	IdleConnTimeout time.Duration
+	// DialTimeout 은 백엔드에 연결하는 시간 제한이다. 기본값 5초.
+	DialTimeout time.Duration
+	// ResponseHeaderTimeout 은 백엔드가 응답 헤더를 보내기까지의 시간 제한이다. 기본값 30초.
+	ResponseHeaderTimeout time.Duration
+	// Transport 가 있으면 위 설정 대신 사용한다. (추적용 tracing.Transport 등)
+	Transport http.RoundTripper
+}
+
+// Proxy 는 규칙들과 공유 연결 풀이다.
+type Proxy struct {
+	routes      []*route
+	checkClient *http.Client
+}
+
+type route struct {
+	Route
+	backends []*backend
+	next     atomic.Uint64
+	handler  http.Handler
+	// canary 는 카나리 백엔드로 보내는 규칙이다. 없으면 nil 이다.
+	canary *route
+}
+
+// New 는 routes 를 검사하고 Proxy 를 만든다. 더 구체적인 규칙(호스트 지정, 긴 접두사)이 먼저 일치한다.
+func New(routes []Route, opts Options) (*Proxy, error) {
+	transport := opts.Transport
+	if transport == nil {
+		transport = NewTransport(opts)
+	}
+	// 상태 검사는 추적하지 않도록 Options.Transport 와 별도의 연결 풀을 쓴다.
+	p := &Proxy{checkClient: &http.Client{Transport: NewTransport(opts)}}
+	for _, rt := range routes {
+		if rt.Prefix == "" {
+			rt.Prefix = "/"
+		}
+		if !strings.HasPrefix(rt.Prefix, "/") {
+			return nil, fmt.Errorf("proxy: prefix %q must start with /", rt.Prefix)
+		}
+		switch rt.Balance {
+		case "", RoundRobin, LeastConn:
+		default:
+			return nil, fmt.Errorf("proxy: unknown balance %q", rt.Balance)
+		}
+		rt.Host = strings.ToLower(rt.Host)
+		r := &route{Route: rt}
+		upstreams := rt.Upstreams
+		if rt.Upstream != "" {
+			upstreams = append([]string{rt.Upstream}, upstreams...)
+		}
+		if len(upstreams) == 0 {
+			return nil, fmt.Errorf("proxy: route %q has no upstream", rt.Host+rt.Prefix)
+		}
+		if err := r.addBackends(upstreams, transport); err != nil {
+			return nil, err
+		}
+		r.handler = http.HandlerFunc(r.serve)
+		if c := rt.Canary; c != nil {
+			if c.Split == nil || len(c.Upstreams) == 0 {
+				return nil, fmt.Errorf("proxy: route %q canary needs a split and upstreams", rt.Host+rt.Prefix)
+			}
+			r.canary = &route{Route: rt}
+			if err := r.canary.addBackends(c.Upstreams, transport); err != nil {
+				return nil, err
+			}
+			r.handler = c.Split.Handler(r.handler, http.HandlerFunc(r.canary.serve))
+		}
+		if rt.MaxBodyBytes > 0 {
+			r.handler = middleware.BodyLimit(rt.MaxBodyBytes)(r.handler)
+		}
+		p.routes = append(p.routes, r)
+	}
+	sort.SliceStable(p.routes, func(i, j int) bool {
+		a, b := p.routes[i], p.routes[j]
+		if (a.Host != "") != (b.Host != "") {
+			return a.Host != ""
+		}
+		return len(a.Prefix) > len(b.Prefix)
+	})
+	return p, nil
+}
+
+// addBackends 는 upstreams 를 검사해 규칙의 백엔드로 추가한다.
+func (rt *route) addBackends(upstreams []string, transport http.RoundTripper) error {
+	for _, u := range upstreams {
+		target, err := url.Parse(u)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return fmt.Errorf("proxy: invalid upstream %q", u)
+		}
+		b := newBackend(target)
+		b.handler = rt.reverseProxy(b, transport)
+		rt.backends = append(rt.backends, b)
+	}
+	return nil
+}
+
+// NewTransport 는 opts 의 연결 풀 설정으로 *http.Transport 를 만든다.
+// 추적 등으로 감싼 Transport 를 Options.Transport 에 넘길 때 사용한다.
+func NewTransport(opts Options) *http.Transport {
+	or := func(d, def time.Duration) time.Duration {
+		if d > 0 {
+			return d
+		}
+		return def
+	}
+	perHost := opts.MaxIdleConnsPerHost
+	if perHost <= 0 {
+		perHost = 32
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = (&net.Dialer{Timeout: or(opts.DialTimeout, 5*time.Second), KeepAlive: 30 * time.Second}).DialContext
+	t.MaxIdleConns = 0
+	t.MaxIdleConnsPerHost = perHost
+	t.IdleConnTimeout = or(opts.IdleConnTimeout, 90*time.Second)
+	t.ResponseHeaderTimeout = or(opts.ResponseHeaderTimeout, 30*time.Second)
+	return t
+}
+
+// reverseProxy 는 규칙 하나의 백엔드 b 로 보내는 httputil.ReverseProxy 를 만든다.
+func (rt *route) reverseProxy(b *backend, transport http.RoundTripper) http.Handler {
+	return &httputil.ReverseProxy{
+		Transport: transport,
+		// 스트리밍 응답(SSE, 긴 다운로드)이 지연되지 않도록 쓸 때마다 내보낸다.
+		FlushInterval: -1,
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			if rt.StripPrefix {
+				pr.Out.URL.Path = stripPrefix(pr.In.URL.Path, rt.Prefix)
+				pr.Out.URL.RawPath = ""
+			}
+			pr.SetURL(b.target)
+			pr.SetXForwarded()
+			// 대부분의 내부 서비스는 원래 Host 로 가상 호스트를 고르므로 그대로 전달한다.
+			pr.Out.Host = pr.In.Host
+			if id := middleware.RequestIDFrom(pr.In.Context()); id != "" {
+				pr.Out.Header.Set(middleware.RequestIDHeader, id)
+			}
+			for _, h := range rt.RemoveHeaders {
+				pr.Out.Header.Del(h)
+			}
+			for k, v := range rt.SetHeaders {
+				pr.Out.Header.Set(k, v)
+			}
+			if rt.Transform != nil && len(rt.Transform.Body) > 0 {
+				pr.Out.Header.Del("Accept-Encoding")
+			}
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			if rt.Transform != nil {
+				rt.Transform.apply(resp)
+			}
+			for k, v := range rt.ResponseHeaders {
+				resp.Header.Set(k, v)
+			}
+			return nil
+		},
+		ErrorHandler: b.fail,
+	}
+}
+
+// fail 은 백엔드 에러를 응답으로 바꾼다. 본문 제한 초과는 413, 차단기가 열려 있으면 503,
+// 시간 초과는 504, 그 외는 502 다.
+func (b *backend) fail(w http.ResponseWriter, r *http.Request, err error) {
+	var maxErr *http.MaxBytesError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &maxErr):
+		api.WriteError(w, api.NewError(http.StatusRequestEntityTooLarge, "body_too_large",
+			fmt.Sprintf("request body must not exceed %d bytes", maxErr.Limit)))
+		return
+	case errors.Is(err, httpclient.ErrCircuitOpen):
+		w.Header().Set("Retry-After", "5")
+		api.WriteError(w, api.NewError(http.StatusServiceUnavailable, "upstream_unavailable", "upstream is temporarily unavailable"))
+		return
+	case errors.Is(err, r.Context().Err()) && r.Context().Err() != nil:
+		// 클라이언트가 먼저 끊었다.
+		return
+	case errors.As(err, &netErr) && netErr.Timeout():
+		logging.From(r.Context()).Warn("proxy: upstream timeout", "upstream", b.name, "err", err)
+		api.WriteError(w, api.NewError(http.StatusGatewayTimeout, "upstream_timeout", "upstream did not respond in time"))
+		return
+	}
+	logging.From(r.Context()).Error("proxy: upstream", "upstream", b.name, "err", err)
+	api.WriteError(w, api.NewError(http.StatusBadGateway, "bad_gateway", "upstream is unavailable"))
+}
+
+// match 는 요청과 일치하는 첫 규칙이다.
+func (p *Proxy) match(r *http.Request) *route {
+	host := strings.ToLower(r.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, rt := range p.routes {
+		if rt.Host != "" && rt.Host != host {
+			continue
+		}
+		if hasPrefix(r.URL.Path, rt.Prefix) {
+			return rt
+		}
+	}
+	return nil
+}
+
+// hasPrefix 는 p 가 prefix 경로 아래인지 경로 조각 단위로 확인한다. "/billing" 과 "/billing/" 은 둘 다
+// /billing, /billing/x 와 일치하고 /billingfoo 와는 일치하지 않는다. prefix 가 비어 있으면 모든 경로다.
+func hasPrefix(p, prefix string) bool {
+	base := strings.TrimSuffix(prefix, "/")
+	return base == "" || p == base || strings.HasPrefix(p, base+"/")
+}
+
+// stripPrefix 는 p 에서 prefix 를 떼고 "/" 하나로 시작하는 나머지 경로를 돌려준다. (예: /billing/x → /x)
+func stripPrefix(p, prefix string) string {
+	rest := strings.TrimPrefix(p, strings.TrimSuffix(prefix, "/"))
+	return "/" + strings.TrimLeft(rest, "/")
+}
+
+// Middleware 는 규칙과 일치하는 요청을 백엔드로 보내고, 나머지는 다음 핸들러로 넘긴다.
+// 일치한 요청에는 이후의 미들웨어(세션, CSRF 등)와 라우트가 적용되지 않는다.
+func (p *Proxy) Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rt := p.match(r); rt != nil {
+				rt.handler.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
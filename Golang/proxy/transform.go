@@ -0,0 +1,267 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// Transform 은 백엔드 응답을 클라이언트에게 보내기 전에 바꾸는 규칙이다.
+//
+// 본문 변환은 스트리밍으로 동작하므로 큰 응답도 메모리에 모으지 않는다. 압축된 응답은 변환할 수 없으므로
+// Body 가 있는 규칙은 백엔드에 Accept-Encoding 을 보내지 않는다. (클라이언트 압축은 서버의 압축 미들웨어가 맡는다)
+// 본문을 바꾼 응답은 Content-Length 를 지우고 ETag 를 약한 ETag 로 바꾼다.
+type Transform struct {
+	// Headers 는 응답 헤더 값의 문자열 치환이다. (Location 의 내부 주소를 공개 주소로 바꾸는 등)
+	Headers []HeaderRewrite
+	// Body 는 Content-Type 이 일치하는 응답 본문에 차례로 적용한다.
+	Body []BodyHook
+}
+
+// HeaderRewrite 는 응답 헤더 Header 의 값에서 From 을 To 로 바꾼다.
+type HeaderRewrite struct {
+	Header, From, To string
+}
+
+// BodyFunc 는 src 를 읽어 바꾼 본문을 dst 에 쓴다. src 를 끝까지 읽지 않아도 된다.
+type BodyFunc func(dst io.Writer, src io.Reader) error
+
+// BodyHook 은 미디어 타입이 Types 중 하나인 응답에 적용할 본문 변환이다.
+// "+json" 처럼 "+" 로 시작하는 값은 구조화 접미사와 일치한다.
+type BodyHook struct {
+	Types []string
+	Func  BodyFunc
+}
+
+func (h BodyHook) matches(mediaType string) bool {
+	for _, t := range h.Types {
+		if t == mediaType || (strings.HasPrefix(t, "+") && strings.HasSuffix(mediaType, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply 는 resp 의 헤더를 바꾸고, 일치하는 본문 변환을 파이프로 이어 붙인다.
+func (t *Transform) apply(resp *http.Response) {
+	for _, h := range t.Headers {
+		vs := resp.Header.Values(h.Header)
+		for i, v := range vs {
+			vs[i] = strings.ReplaceAll(v, h.From, h.To)
+		}
+	}
+	if enc := resp.Header.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		return
+	}
+	mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	changed := false
+	for _, h := range t.Body {
+		if h.matches(mt) {
+			resp.Body = pipeBody(resp.Body, h.Func)
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		resp.Header.Set("ETag", "W/"+etag)
+	}
+}
+
+// pipeBody 는 src 를 f 로 바꾸며 읽는 본문이다. f 는 별도 고루틴에서 돌고, 읽는 쪽이 닫으면 src 도 닫는다.
+func pipeBody(src io.ReadCloser, f BodyFunc) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		err := f(pw, src)
+		src.Close()
+		pw.CloseWithError(err)
+	}()
+	return &piped{PipeReader: pr, src: src}
+}
+
+type piped struct {
+	*io.PipeReader
+	src io.Closer
+}
+
+func (p *piped) Close() error {
+	p.PipeReader.Close()
+	return p.src.Close()
+}
+
+// Rewrite 는 From 으로 시작하는 값을 To 로 시작하도록 바꾸는 치환이다.
+type Rewrite struct {
+	From, To string
+}
+
+// linkAttrs 는 RewriteLinks 가 바꾸는 URL 속성이다.
+var linkAttrs = []string{"href", "src", "action", "formaction", "poster"}
+
+// RewriteLinks 는 HTML 의 링크 속성(href, src, action 등)이 From 으로 시작하면 To 로 바꾸는 본문 변환이다.
+// 백엔드가 내부 주소로 만든 절대 링크를 공개 주소로 고칠 때 쓴다. 바꾸지 않은 태그와 텍스트는 그대로 쓴다.
+func RewriteLinks(rules []Rewrite) BodyHook {
+	return BodyHook{Types: []string{"text/html"}, Func: func(dst io.Writer, src io.Reader) error {
+		w := bufio.NewWriter(dst)
+		z := xhtml.NewTokenizer(src)
+		for {
+			tt := z.Next()
+			if tt == xhtml.ErrorToken {
+				if err := z.Err(); !errors.Is(err, io.EOF) {
+					return err
+				}
+				return w.Flush()
+			}
+			// Token 을 부르면 Raw 의 내용이 바뀔 수 있으므로 먼저 복사한다.
+			raw := slices.Clone(z.Raw())
+			if tt == xhtml.StartTagToken || tt == xhtml.SelfClosingTagToken {
+				tok := z.Token()
+				if rewriteAttrs(tok.Attr, rules) {
+					w.WriteString(tok.String())
+					continue
+				}
+			}
+			w.Write(raw)
+		}
+	}}
+}
+
+func rewriteAttrs(attrs []xhtml.Attribute, rules []Rewrite) bool {
+	changed := false
+	for i, a := range attrs {
+		if a.Namespace != "" || !slices.Contains(linkAttrs, a.Key) {
+			continue
+		}
+		for _, r := range rules {
+			if strings.HasPrefix(a.Val, r.From) {
+				attrs[i].Val = r.To + strings.TrimPrefix(a.Val, r.From)
+				changed = true
+				break
+			}
+		}
+	}
+	return changed
+}
+
+// Redacted 는 RedactJSON 이 가린 값 대신 쓰는 문자열이다.
+const Redacted = "[REDACTED]"
+
+// RedactJSON 은 JSON 본문에서 이름이 fields 중 하나인 필드의 값을 깊이와 관계없이 Redacted 로 바꾸는
+// 본문 변환이다. 값이 객체나 배열이면 통째로 가린다. 줄마다 값이 하나인 NDJSON 도 처리한다.
+func RedactJSON(fields []string) BodyHook {
+	return BodyHook{Types: []string{"application/json", "application/x-ndjson", "+json"}, Func: func(dst io.Writer, src io.Reader) error {
+		w := bufio.NewWriter(dst)
+		if err := redact(w, src, fields); err != nil {
+			return err
+		}
+		return w.Flush()
+	}}
+}
+
+func redact(w *bufio.Writer, src io.Reader, fields []string) error {
+	dec := json.NewDecoder(src)
+	dec.UseNumber()
+	// n 은 객체에서는 지금까지 쓴 키와 값의 수, 배열에서는 값의 수다.
+	type frame struct {
+		obj bool
+		n   int
+	}
+	var stack []frame
+	// sep 은 다음 키나 값 앞에 쉼표, 콜론, 또는 최상위 값 사이의 줄바꿈을 쓴다.
+	top := 0
+	sep := func() {
+		if len(stack) == 0 {
+			if top > 0 {
+				w.WriteByte('\n')
+			}
+			top++
+			return
+		}
+		f := &stack[len(stack)-1]
+		switch {
+		case f.obj && f.n%2 == 1:
+			w.WriteByte(':')
+		case f.n > 0:
+			w.WriteByte(',')
+		}
+		f.n++
+	}
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			if top > 0 {
+				w.WriteByte('\n')
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch v := tok.(type) {
+		case json.Delim:
+			if v == '}' || v == ']' {
+				stack = stack[:len(stack)-1]
+				w.WriteByte(byte(v))
+				continue
+			}
+			sep()
+			w.WriteByte(byte(v))
+			stack = append(stack, frame{obj: v == '{'})
+			continue
+		case string:
+			isKey := len(stack) > 0 && stack[len(stack)-1].obj && stack[len(stack)-1].n%2 == 0
+			sep()
+			writeJSON(w, v)
+			if isKey && slices.Contains(fields, v) {
+				if err := skipValue(dec); err != nil {
+					return err
+				}
+				sep()
+				writeJSON(w, Redacted)
+			}
+			continue
+		}
+		sep()
+		writeJSON(w, tok)
+	}
+}
+
+// skipValue 는 다음 값 하나(객체나 배열이면 끝까지)를 읽어 버린다.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if d, ok := tok.(json.Delim); ok {
+			if d == '{' || d == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// writeJSON 은 토큰 값 하나를 쓴다. 백엔드가 보낸 <, >, & 는 그대로 둔다.
+func writeJSON(w *bufio.Writer, v any) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+	w.Write(bytes.TrimSuffix(b.Bytes(), []byte("\n")))
+}
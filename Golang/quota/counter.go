@@ -0,0 +1,117 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/hgsong234/_stack/Golang/store"
+)
+
+// MemoryCounter 는 프로세스 메모리에 사용량을 보관한다. 단일 노드용이며 재시작하면 사라진다.
+type MemoryCounter struct {
+	mu     sync.Mutex
+	counts map[memoryKey]*memoryCount
+	now    func() time.Time
+}
+
+type memoryKey struct {
+	subject string
+	period  Period
+	start   time.Time
+}
+
+type memoryCount struct {
+	used int64
+	end  time.Time
+}
+
+// NewMemoryCounter 는 MemoryCounter 를 만든다. 끝난 기간의 사용량은 주기적으로 정리된다.
+func NewMemoryCounter() *MemoryCounter {
+	c := &MemoryCounter{counts: map[memoryKey]*memoryCount{}, now: time.Now}
+	go func() {
+		for range time.Tick(10 * time.Minute) {
+			c.cleanup()
+		}
+	}()
+	return c
+}
+
+// Add 는 Counter 구현이다.
+func (c *MemoryCounter) Add(_ context.Context, subject string, p Period, start, end time.Time, n int64) (int64, error) {
+	k := memoryKey{subject, p, start}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	mc, ok := c.counts[k]
+	if !ok {
+		if n == 0 {
+			return 0, nil
+		}
+		mc = &memoryCount{end: end}
+		c.counts[k] = mc
+	}
+	mc.used += n
+	return mc.used, nil
+}
+
+func (c *MemoryCounter) cleanup() {
+	now := c.now()
+	c.mu.Lock()
+	for k, mc := range c.counts {
+		if !now.Before(mc.end) {
+			delete(c.counts, k)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// RedisCounter 는 Redis 에 사용량을 보관해 여러 인스턴스가 할당량을 공유하게 한다.
+// 키는 기간이 끝나고 하루 뒤에 만료된다.
+type RedisCounter struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisCounter 는 client 로 RedisCounter 를 만든다. 키는 "quota:" 접두사를 갖는다.
+func NewRedisCounter(client redis.UniversalClient) *RedisCounter {
+	return &RedisCounter{client: client, prefix: "quota:"}
+}
+
+// Add 는 Counter 구현이다.
+func (c *RedisCounter) Add(ctx context.Context, subject string, p Period, start, end time.Time, n int64) (int64, error) {
+	key := c.prefix + subject + ":" + string(p) + ":" + start.Format("20060102")
+	if n == 0 {
+		used, err := c.client.Get(ctx, key).Int64()
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return used, err
+	}
+	var incr *redis.IntCmd
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.IncrBy(ctx, key, n)
+		pipe.ExpireAt(ctx, key, end.Add(24*time.Hour))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// DBCounter 는 데이터베이스(quota_usage 테이블)에 사용량을 보관한다. 끝난 기간의 행은
+// store.QuotaUsage.DeleteBefore 로 지운다.
+type DBCounter struct {
+	Usage *store.QuotaUsage
+}
+
+// Add 는 Counter 구현이다.
+func (c DBCounter) Add(ctx context.Context, subject string, p Period, start, _ time.Time, n int64) (int64, error) {
+	if n == 0 {
+		return c.Usage.Used(ctx, subject, string(p), start)
+	}
+	return c.Usage.Add(ctx, subject, string(p), start, n)
+}
@@ -0,0 +1,156 @@
+// Package quota 는 API 키별 일간·월간 요청 할당량을 센다. 기간은 UTC 기준으로 자정과 매달 1일에 바뀐다.
+//
+// 사용량은 Counter 에 보관하므로 단일 노드(메모리), 여러 인스턴스(Redis), 데이터베이스 중에서 고를 수 있다.
+// 할당량이 없는(0) 기간도 사용량은 센다.
+package quota
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/store"
+)
+
+// Period 는 할당량 기간이다.
+type Period string
+
+const (
+	Day   Period = "day"
+	Month Period = "month"
+)
+
+// Periods 는 Tracker 가 세는 기간 목록이다.
+var Periods = []Period{Day, Month}
+
+// Window 는 t 가 속한 기간의 시작과 끝(다음 기간의 시작)이다.
+func (p Period) Window(t time.Time) (start, end time.Time) {
+	t = t.UTC()
+	if p == Month {
+		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+	start = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// Counter 는 기간별 사용량을 보관한다.
+type Counter interface {
+	// Add 는 subject 의 기간(start 에서 end 까지) 사용량에 n 을 더하고 더한 뒤의 값을 돌려준다.
+	// n 이 0 이면 읽기만 한다. end 가 지나면 사용량을 지워도 된다.
+	Add(ctx context.Context, subject string, p Period, start, end time.Time, n int64) (int64, error)
+}
+
+// Limits 는 기간별 할당량이다. 0 이면 제한하지 않는다.
+type Limits struct {
+	Daily   int64
+	Monthly int64
+}
+
+func (l Limits) of(p Period) int64 {
+	if p == Month {
+		return l.Monthly
+	}
+	return l.Daily
+}
+
+// Usage 는 한 기간의 사용량이다.
+type Usage struct {
+	Period Period `json:"period"`
+	// Limit 이 0 이면 제한이 없고 Remaining 은 비어 있다.
+	Limit     int64     `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining *int64    `json:"remaining,omitempty"`
+	Reset     time.Time `json:"reset"`
+}
+
+// Exceeded 는 할당량을 넘었는지 확인한다.
+func (u Usage) Exceeded() bool { return u.Limit > 0 && u.Used > u.Limit }
+
+func usage(p Period, limit, used int64, reset time.Time) Usage {
+	u := Usage{Period: p, Limit: limit, Used: used, Reset: reset}
+	if limit > 0 {
+		rem := max(0, limit-used)
+		u.Remaining = &rem
+	}
+	return u
+}
+
+// Tracker 는 API 키의 요청을 할당량에 맞추어 센다.
+type Tracker struct {
+	Counter Counter
+	// Default 는 키에 재정의가 없을 때의 할당량이다.
+	Default Limits
+
+	now func() time.Time
+}
+
+// Limits 는 k 의 할당량이다. 키의 재정의가 기본값보다 우선한다.
+func (t *Tracker) Limits(k *store.APIKey) Limits {
+	l := t.Default
+	if k.DailyQuota != nil {
+		l.Daily = *k.DailyQuota
+	}
+	if k.MonthlyQuota != nil {
+		l.Monthly = *k.MonthlyQuota
+	}
+	return l
+}
+
+// Consume 은 k 의 요청 하나를 센다. 어느 기간이든 할당량을 넘으면 ok 가 false 이고, 거부한 요청은
+// 사용량에서 다시 뺀다. 돌려주는 사용량은 응답 헤더에 쓴다.
+func (t *Tracker) Consume(ctx context.Context, k *store.APIKey) (usages []Usage, ok bool, err error) {
+	l, now := t.Limits(k), t.clock()
+	subject := Subject(k)
+	ok = true
+	for _, p := range Periods {
+		start, end := p.Window(now)
+		used, err := t.Counter.Add(ctx, subject, p, start, end, 1)
+		if err != nil {
+			t.undo(ctx, subject, usages, now)
+			return nil, true, err
+		}
+		u := usage(p, l.of(p), used, end)
+		usages = append(usages, u)
+		if u.Exceeded() {
+			ok = false
+		}
+	}
+	if !ok {
+		t.undo(ctx, subject, usages, now)
+	}
+	return usages, ok, nil
+}
+
+// undo 는 Consume 이 더한 사용량을 되돌린다. 실패해도 다음 기간에는 지워지므로 무시한다.
+func (t *Tracker) undo(ctx context.Context, subject string, usages []Usage, now time.Time) {
+	for _, u := range usages {
+		start, end := u.Period.Window(now)
+		t.Counter.Add(ctx, subject, u.Period, start, end, -1)
+	}
+}
+
+// Usage 는 k 의 현재 기간 사용량이다. 요청으로 세지 않는다.
+func (t *Tracker) Usage(ctx context.Context, k *store.APIKey) ([]Usage, error) {
+	l, now := t.Limits(k), t.clock()
+	usages := make([]Usage, 0, len(Periods))
+	for _, p := range Periods {
+		start, end := p.Window(now)
+		used, err := t.Counter.Add(ctx, Subject(k), p, start, end, 0)
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, usage(p, l.of(p), used, end))
+	}
+	return usages, nil
+}
+
+func (t *Tracker) clock() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// Subject 는 k 의 사용량을 구분하는 이름이다.
+func Subject(k *store.APIKey) string { return "apikey:" + strconv.FormatInt(k.ID, 10) }
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryBackend 는 프로세스 메모리에 버킷을 보관한다. 단일 노드용이다.
+type MemoryBackend struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewMemoryBackend 는 MemoryBackend 를 만든다. 오래 쓰이지 않은 버킷은 주기적으로 정리된다.
+func NewMemoryBackend() *MemoryBackend {
+	b := &MemoryBackend{buckets: map[string]*bucket{}, now: time.Now}
+	go func() {
+		for range time.Tick(time.Minute) {
+			b.cleanup(10 * time.Minute)
+		}
+	}()
+	return b
+}
+
+// Take 는 Backend 구현이다.
+func (b *MemoryBackend) Take(_ context.Context, key string, rate float64, burst int) (Result, error) {
+	now := b.now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bk, ok := b.buckets[key]
+	if !ok {
+		bk = &bucket{tokens: float64(burst), last: now}
+		b.buckets[key] = bk
+	}
+	return refill(bk, now, rate, burst), nil
+}
+
+// refill 은 경과 시간만큼 토큰을 충전한 뒤 하나를 꺼낸다.
+func refill(bk *bucket, now time.Time, rate float64, burst int) Result {
+	bk.tokens += now.Sub(bk.last).Seconds() * rate
+	if bk.tokens > float64(burst) {
+		bk.tokens = float64(burst)
+	}
+	bk.last = now
+	if bk.tokens >= 1 {
+		bk.tokens--
+		return Result{Allowed: true, Remaining: int(bk.tokens)}
+	}
+	wait := time.Duration((1 - bk.tokens) / rate * float64(time.Second))
+	return Result{Allowed: false, RetryAfter: wait}
+}
+
+// cleanup 은 idle 동안 사용되지 않은 버킷을 제거한다.
+func (b *MemoryBackend) cleanup(idle time.Duration) {
+	cut := b.now().Add(-idle)
+	b.mu.Lock()
+	for k, bk := range b.buckets {
+		if bk.last.Before(cut) {
+			delete(b.buckets, k)
+		}
+	}
+	b.mu.Unlock()
+}
@@ -0,0 +1,120 @@
+// Package ratelimit 는 클라이언트별 토큰 버킷 요청 제한 미들웨어를 제공한다.
+// 버킷 상태는 Backend 에 보관되므로 단일 노드(메모리)와 여러 인스턴스(Redis) 모두에서 사용할 수 있다.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/realip"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Result 는 토큰을 꺼낸 결과다.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter 는 거부되었을 때 다음 토큰이 생길 때까지의 시간이다.
+	RetryAfter time.Duration
+}
+
+// Backend 는 토큰 버킷 상태를 보관한다.
+type Backend interface {
+	// Take 는 key 의 버킷(초당 rate 개 충전, 최대 burst 개)에서 토큰 하나를 꺼낸다.
+	Take(ctx context.Context, key string, rate float64, burst int) (Result, error)
+}
+
+// KeyFunc 는 요청을 제한 단위(클라이언트)로 구분하는 키를 만든다.
+type KeyFunc func(r *http.Request) string
+
+// ByIP 는 클라이언트 IP(realip.ClientIP)로 구분한다.
+func ByIP(r *http.Request) string { return "ip:" + realip.Host(r) }
+
+// ByHeader 는 헤더 값이 verify 를 통과하면 verify 가 돌려준 ID(예: API 키 ID)로, 아니면 IP 로 구분한다.
+// 확인하지 않은 값으로 구분하면 클라이언트가 값을 바꿔 가며 제한을 피하거나 남의 키 값으로 그 버킷을
+// 써 버릴 수 있다. verify 가 nil 이면 언제나 IP 로 구분한다.
+func ByHeader(name string, verify func(r *http.Request, value string) (id string, ok bool)) KeyFunc {
+	return func(r *http.Request) string {
+		if v := r.Header.Get(name); v != "" && verify != nil {
+			if id, ok := verify(r, v); ok {
+				return "key:" + id
+			}
+		}
+		return ByIP(r)
+	}
+}
+
+// Limiter 는 요청 제한 설정이다.
+type Limiter struct {
+	Backend Backend
+	// Rate 는 초당 충전되는 토큰 수, Burst 는 버킷 크기다. Rate 가 0 이하이면 제한하지 않는다.
+	// 실행 중에 바꾸려면 SetLimits 를 사용한다.
+	Rate  float64
+	Burst int
+	// Key 가 nil 이면 ByIP 를 사용한다.
+	Key KeyFunc
+
+	limits atomic.Pointer[limits]
+}
+
+type limits struct {
+	rate  float64
+	burst int
+}
+
+// SetLimits 는 Rate 와 Burst 를 실행 중에 바꾼다. 다음 요청부터 적용된다.
+func (l *Limiter) SetLimits(rate float64, burst int) {
+	l.limits.Store(&limits{rate: rate, burst: burst})
+}
+
+// current 는 SetLimits 로 바꾼 값이 있으면 그것을, 없으면 Rate, Burst 를 돌려준다.
+func (l *Limiter) current() (float64, int) {
+	if c := l.limits.Load(); c != nil {
+		return c.rate, c.burst
+	}
+	return l.Rate, l.Burst
+}
+
+// Middleware 는 한도를 넘은 요청에 429 와 Retry-After 를 돌려준다.
+// 백엔드에 장애가 있으면 요청을 막지 않고 통과시킨다. (fail open)
+func (l *Limiter) Middleware() router.Middleware {
+	key := l.Key
+	if key == nil {
+		key = ByIP
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rate, burst := l.current()
+			if rate <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			res, err := l.Backend.Take(r.Context(), key(r), rate, burst)
+			if err != nil {
+				logging.From(r.Context()).Error("ratelimit: backend", "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(burst))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			// X-RateLimit-Reset 은 버킷이 다시 가득 찰 때까지의 초다.
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(float64(burst-res.Remaining)/rate))))
+			if !res.Allowed {
+				secs := int(math.Ceil(res.RetryAfter.Seconds()))
+				if secs < 1 {
+					secs = 1
+				}
+				w.Header().Set("Retry-After", strconv.Itoa(secs))
+				api.WriteError(w, api.NewError(http.StatusTooManyRequests, "rate_limited", "too many requests"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
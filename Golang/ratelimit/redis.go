@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucket 은 Redis 안에서 버킷을 원자적으로 갱신하는 Lua 스크립트다.
+// KEYS[1]=버킷 키, ARGV = rate, burst, 현재 시각(ms)
+// 반환: {허용 여부(1/0), 남은 토큰, 재시도까지 ms}
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local b = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(b[1]) or burst
+local last = tonumber(b[2]) or now
+tokens = math.min(burst, tokens + (now - last) / 1000 * rate)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate * 1000)
+end
+redis.call("HSET", KEYS[1], "tokens", tokens, "last", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, math.floor(tokens), wait}
+`)
+
+// RedisBackend 는 Redis 에 버킷을 보관해 여러 인스턴스가 한도를 공유하게 한다.
+type RedisBackend struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisBackend 는 client 로 RedisBackend 를 만든다. 키는 "ratelimit:" 접두사를 갖는다.
+func NewRedisBackend(client redis.UniversalClient) *RedisBackend {
+	return &RedisBackend{client: client, prefix: "ratelimit:"}
+}
+
+// Take 는 Backend 구현이다.
+func (b *RedisBackend) Take(ctx context.Context, key string, rate float64, burst int) (Result, error) {
+	now := time.Now().UnixMilli()
+	v, err := tokenBucket.Run(ctx, b.client, []string{b.prefix + key}, rate, burst, now).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Allowed:    v[0] == 1,
+		Remaining:  int(v[1]),
+		RetryAfter: time.Duration(v[2]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,222 @@
+// Package rbac 는 역할(role)과 권한(permission)으로 요청을 인가한다.
+//
+// 역할 정의는 "역할=권한 권한 ..." 형식이다. (예: "editor=users:read users:write")
+// 권한 "*" 는 모든 권한, "users:*" 는 "users:" 로 시작하는 모든 권한이다.
+//
+// 주체(Principal)는 인증 미들웨어가 남긴 정보에서 만든다.
+// JWT 는 roles 클레임의 역할을, API 키는 scope 를 권한으로 사용한다.
+// 따라서 이 패키지의 미들웨어는 인증 미들웨어(auth.Authenticate, apikey) 안쪽에 건다.
+//
+// RequireMFA 로 지정한 역할(보통 admin 같은 권한이 큰 역할)을 가진 주체는 2단계 인증으로 로그인했어야 한다.
+// 그렇지 않은 주체의 요청은 RequireRole, RequirePermission 에서 403 mfa_required 로 거부된다.
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/apikey"
+	"github.com/hgsong234/_stack/Golang/audit"
+	"github.com/hgsong234/_stack/Golang/auth"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Policy 는 역할별 권한 표다.
+type Policy struct {
+	roles    map[string][]string
+	mfaRoles []string
+}
+
+// Parse 는 "역할=권한 ..." 정의 목록으로 Policy 를 만든다.
+func Parse(defs []string) (*Policy, error) {
+	p := &Policy{roles: map[string][]string{}}
+	for _, d := range defs {
+		role, perms, ok := strings.Cut(d, "=")
+		role = strings.TrimSpace(role)
+		if !ok || role == "" {
+			return nil, fmt.Errorf("rbac: role definition %q must have the form role=perm ...", d)
+		}
+		p.roles[role] = append(p.roles[role], strings.Fields(perms)...)
+	}
+	return p, nil
+}
+
+// RequireMFA 는 roles 역할을 가진 주체에게 2단계 인증을 요구한다. 정의되지 않은 역할이면 에러다.
+func (p *Policy) RequireMFA(roles ...string) error {
+	for _, r := range roles {
+		if _, ok := p.roles[r]; !ok {
+			return fmt.Errorf("rbac: require_mfa role %q is not defined", r)
+		}
+	}
+	p.mfaRoles = roles
+	return nil
+}
+
+// Roles 는 정의된 역할 이름을 정렬해 돌려준다.
+func (p *Policy) Roles() []string {
+	out := make([]string, 0, len(p.roles))
+	for r := range p.roles {
+		out = append(out, r)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Permissions 는 역할들이 가진 권한을 모두 돌려준다.
+func (p *Policy) Permissions(roles []string) []string {
+	var out []string
+	for _, r := range roles {
+		for _, perm := range p.roles[r] {
+			if !slices.Contains(out, perm) {
+				out = append(out, perm)
+			}
+		}
+	}
+	return out
+}
+
+// Principal 은 요청을 보낸 주체와 그 권한이다.
+type Principal struct {
+	Subject     string   `json:"subject"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	// Via 는 인증 방식이다. "jwt" 또는 "api_key".
+	Via string `json:"via"`
+	// MFA 는 2단계 인증으로 로그인했는지다. JWT 의 amr 클레임에 "mfa" 또는 "otp" 가 있으면 true 다.
+	MFA bool `json:"mfa"`
+}
+
+// HasRole 은 주체에 role 이 있는지 확인한다.
+func (pr *Principal) HasRole(role string) bool {
+	return pr != nil && slices.Contains(pr.Roles, role)
+}
+
+// Can 은 주체에 perm 권한이 있는지 확인한다.
+func (pr *Principal) Can(perm string) bool {
+	if pr == nil {
+		return false
+	}
+	for _, g := range pr.Permissions {
+		if grants(g, perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// grants 는 부여된 권한 g 가 perm 을 포함하는지 확인한다.
+func grants(g, perm string) bool {
+	if g == "*" || g == perm {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(g, "*"); ok {
+		return strings.HasPrefix(perm, prefix)
+	}
+	return false
+}
+
+// Resolve 는 요청 컨텍스트의 인증 정보로 주체를 만든다. 인증되지 않았으면 nil 이다.
+func (p *Policy) Resolve(ctx context.Context) *Principal {
+	if c := auth.ClaimsFrom(ctx); c != nil {
+		mfa := slices.Contains(c.AMR, "mfa") || slices.Contains(c.AMR, "otp")
+		return &Principal{Subject: c.Subject, Roles: c.Roles, Permissions: p.Permissions(c.Roles), Via: "jwt", MFA: mfa}
+	}
+	if k := apikey.From(ctx); k != nil {
+		return &Principal{Subject: "apikey:" + strconv.FormatInt(k.ID, 10), Roles: []string{}, Permissions: k.Scopes, Via: "api_key"}
+	}
+	return nil
+}
+
+// 컨텍스트 키
+type ctxKey struct{}
+
+// From 은 RequireRole/RequirePermission 이 컨텍스트에 저장한 주체를 돌려준다.
+func From(ctx context.Context) *Principal {
+	pr, _ := ctx.Value(ctxKey{}).(*Principal)
+	return pr
+}
+
+// HasRole 은 요청 주체에 role 이 있는지 확인한다.
+func (p *Policy) HasRole(ctx context.Context, role string) bool {
+	return p.principal(ctx).HasRole(role)
+}
+
+// Can 은 요청 주체에 perm 권한이 있는지 확인한다.
+func (p *Policy) Can(ctx context.Context, perm string) bool {
+	return p.principal(ctx).Can(perm)
+}
+
+func (p *Policy) principal(ctx context.Context) *Principal {
+	if pr := From(ctx); pr != nil {
+		return pr
+	}
+	return p.Resolve(ctx)
+}
+
+// RequireRole 은 주체에 roles 중 하나가 없으면 거부하는 미들웨어를 만든다.
+// 인증되지 않은 요청은 401, 역할이 없으면 403 이다.
+func (p *Policy) RequireRole(roles ...string) router.Middleware {
+	return p.require(func(pr *Principal) bool {
+		return slices.ContainsFunc(roles, pr.HasRole)
+	}, "role "+strings.Join(roles, " or ")+" required")
+}
+
+// RequirePermission 은 주체에 perm 권한이 없으면 거부하는 미들웨어를 만든다.
+func (p *Policy) RequirePermission(perm string) router.Middleware {
+	return p.require(func(pr *Principal) bool { return pr.Can(perm) }, "permission "+strconv.Quote(perm)+" required")
+}
+
+func (p *Policy) require(ok func(*Principal) bool, msg string) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pr := p.principal(r.Context())
+			if pr == nil {
+				denied(r, "", "unauthenticated", msg)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				api.WriteError(w, api.NewError(http.StatusUnauthorized, "unauthorized", "authentication required"))
+				return
+			}
+			if !ok(pr) {
+				denied(r, pr.Subject, "forbidden", msg)
+				api.WriteError(w, api.NewError(http.StatusForbidden, "forbidden", msg))
+				return
+			}
+			if role := p.needsMFA(pr); role != "" {
+				denied(r, pr.Subject, "mfa_required", msg)
+				api.WriteError(w, api.NewError(http.StatusForbidden, "mfa_required", "role "+role+" requires two-factor authentication"))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, pr)))
+		})
+	}
+}
+
+// denied 는 거부를 감사 로그에 남긴다. actor 가 비어 있으면 익명 요청이다.
+func denied(r *http.Request, actor, reason, required string) {
+	if actor == "" {
+		actor = "anonymous"
+	}
+	audit.RecordRequest(r, audit.Event{
+		Type: "authz.denied", Outcome: audit.Denied, Actor: actor, Target: r.Method + " " + r.URL.Path,
+		Fields: map[string]any{"reason": reason, "required": required},
+	})
+}
+
+// needsMFA 는 pr 이 2단계 인증 없이 가진 RequireMFA 역할을 돌려준다. 없으면 "" 이다.
+func (p *Policy) needsMFA(pr *Principal) string {
+	if pr.MFA {
+		return ""
+	}
+	for _, r := range p.mfaRoles {
+		if pr.HasRole(r) {
+			return r
+		}
+	}
+	return ""
+}
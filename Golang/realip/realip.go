@@ -0,0 +1,218 @@
+// Package realip 은 신뢰하는 프록시 뒤에서 실제 클라이언트 IP 를 찾아 요청 컨텍스트에 넣는다.
+//
+// 직접 연결한 주소가 TrustedProxies 에 있을 때만 Forwarded(RFC 7239), X-Forwarded-For, X-Real-IP 를
+// 읽는다. 신뢰하지 않는 주소에서 온 헤더는 위조될 수 있으므로 무시한다. 접근 로그, 요청 로거의 client_ip,
+// 요청 제한, ACL, 감사 로그는 모두 ClientIP 로 같은 주소를 쓴다.
+package realip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Config 는 프록시 설정이다. 주소는 CIDR("10.0.0.0/8") 또는 단일 IP 다.
+type Config struct {
+	TrustedProxies []string
+	// Depth 는 클라이언트 앞에 있는 신뢰 프록시의 최대 개수다. 기본값 1.
+	Depth int
+	// Headers 는 읽을 헤더와 그 순서다. 처음으로 있는 헤더 하나만 쓴다.
+	// 기본값은 Forwarded, X-Forwarded-For, X-Real-IP.
+	Headers []string
+}
+
+// DefaultHeaders 는 Config.Headers 의 기본값이다.
+var DefaultHeaders = []string{"Forwarded", "X-Forwarded-For", "X-Real-IP"}
+
+// Resolver 는 파싱된 Config 다. Update 로 실행 중에 바꿀 수 있다.
+type Resolver struct {
+	rules atomic.Pointer[rules]
+}
+
+type rules struct {
+	trusted []netip.Prefix
+	depth   int
+	headers []string
+}
+
+// Default 는 서버 전체에서 쓰는 Resolver 다. 설정하기 전에는 어떤 프록시도 믿지 않는다.
+var Default = &Resolver{}
+
+func init() { Default.rules.Store(&rules{depth: 1, headers: DefaultHeaders}) }
+
+// New 는 cfg 로 Resolver 를 만든다.
+func New(cfg Config) (*Resolver, error) {
+	r := &Resolver{}
+	if err := r.Update(cfg); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Update 는 cfg 를 파싱해 한 번에 바꾼다. 파싱에 실패하면 기존 설정을 유지한다.
+func (res *Resolver) Update(cfg Config) error {
+	rs := &rules{depth: cfg.Depth, headers: cfg.Headers}
+	if rs.depth <= 0 {
+		rs.depth = 1
+	}
+	if len(rs.headers) == 0 {
+		rs.headers = DefaultHeaders
+	}
+	for _, h := range rs.headers {
+		switch http.CanonicalHeaderKey(h) {
+		case "Forwarded", "X-Forwarded-For", "X-Real-Ip":
+		default:
+			return fmt.Errorf("realip: unsupported header %q", h)
+		}
+	}
+	var err error
+	if rs.trusted, err = ParsePrefixes(cfg.TrustedProxies); err != nil {
+		return err
+	}
+	res.rules.Store(rs)
+	return nil
+}
+
+// ParsePrefixes 는 CIDR 또는 단일 IP 목록을 파싱한다.
+func ParsePrefixes(list []string) ([]netip.Prefix, error) {
+	out := make([]netip.Prefix, 0, len(list))
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q", s)
+			}
+			out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", s)
+		}
+		out = append(out, p.Masked())
+	}
+	return out, nil
+}
+
+func contains(list []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range list {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve 는 r 의 실제 클라이언트 IP 를 찾는다. 헤더의 주소 목록을 오른쪽(가까운 프록시)부터 최대 Depth 개
+// 따라가며, 신뢰하지 않는 주소를 만나면 멈춘다.
+func (res *Resolver) Resolve(r *http.Request) netip.Addr {
+	rs := res.rules.Load()
+	addr := ParseAddr(r.RemoteAddr)
+	if !addr.IsValid() || !contains(rs.trusted, addr) {
+		return addr
+	}
+	var chain []string
+	for _, h := range rs.headers {
+		if chain = forwardedFor(r.Header, h); len(chain) > 0 {
+			break
+		}
+	}
+	for i, hops := len(chain)-1, 0; i >= 0; i-- {
+		ip := ParseAddr(chain[i])
+		if !ip.IsValid() {
+			// 잘못된 항목 너머는 믿을 수 없으므로 마지막으로 확인한 주소를 쓴다.
+			break
+		}
+		addr = ip
+		hops++
+		if hops >= rs.depth || !contains(rs.trusted, ip) {
+			break
+		}
+	}
+	return addr
+}
+
+// forwardedFor 는 헤더 name 의 클라이언트 쪽부터의 주소 목록이다.
+func forwardedFor(h http.Header, name string) []string {
+	var chain []string
+	switch http.CanonicalHeaderKey(name) {
+	case "Forwarded":
+		// Forwarded: for=192.0.2.60;proto=http, for="[2001:db8::1]:4711"
+		for _, v := range h.Values("Forwarded") {
+			for _, elem := range strings.Split(v, ",") {
+				for _, pair := range strings.Split(elem, ";") {
+					k, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+					if ok && strings.EqualFold(k, "for") {
+						chain = append(chain, strings.Trim(val, `"`))
+					}
+				}
+			}
+		}
+	case "X-Forwarded-For":
+		for _, v := range h.Values("X-Forwarded-For") {
+			chain = append(chain, strings.Split(v, ",")...)
+		}
+	default:
+		if v := h.Get(name); v != "" {
+			chain = []string{v}
+		}
+	}
+	return chain
+}
+
+// ParseAddr 는 "ip", "ip:port", "[ipv6]:port" 를 파싱한다. 실패하면 유효하지 않은 Addr 이다.
+func ParseAddr(s string) netip.Addr {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+type ctxKey struct{}
+
+// Middleware 는 찾은 주소를 요청 컨텍스트에 넣고 요청 로거에 client_ip 로 붙인다.
+// 주소를 쓰는 다른 미들웨어(접근 로그, 요청 제한, ACL)보다 바깥에 둔다.
+func (res *Resolver) Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addr := res.Resolve(r)
+			ctx := context.WithValue(r.Context(), ctxKey{}, addr)
+			if addr.IsValid() {
+				ctx = logging.WithContext(ctx, logging.From(ctx).With("client_ip", addr.String()))
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ClientIP 는 Middleware 가 찾은 주소다. Middleware 를 거치지 않은 요청은 RemoteAddr 를 쓴다.
+func ClientIP(r *http.Request) netip.Addr {
+	if addr, ok := r.Context().Value(ctxKey{}).(netip.Addr); ok {
+		return addr
+	}
+	return ParseAddr(r.RemoteAddr)
+}
+
+// Host 는 ClientIP 의 문자열이다. 주소를 알 수 없으면 RemoteAddr 를 그대로 돌려준다.
+func Host(r *http.Request) string {
+	if addr := ClientIP(r); addr.IsValid() {
+		return addr.String()
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,163 @@
+// Package reload 는 설정의 일부를 재시작 없이 다시 적용한다.
+//
+// 다시 읽은 설정은 검사를 통과해야 하고, 등록된 모든 부분이 준비(Prepare)에 성공했을 때만
+// 한꺼번에 적용된다. 하나라도 실패하면 아무것도 바뀌지 않는다.
+package reload
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/audit"
+	"github.com/hgsong234/_stack/Golang/config"
+	"github.com/hgsong234/_stack/Golang/logging"
+)
+
+// Prepare 는 새 설정으로 바꿀 준비를 한다. 적용은 돌려준 apply 가 하며, apply 는 실패하지 않아야 한다.
+// 파싱이나 파일 읽기처럼 실패할 수 있는 일은 모두 Prepare 에서 끝낸다.
+type Prepare func(cfg *config.Config) (apply func(), err error)
+
+// Reloader 는 설정을 다시 읽어 등록된 부분들에 적용한다.
+type Reloader struct {
+	load    func() (*config.Config, error)
+	mu      sync.Mutex // Reload 를 한 번에 하나만 실행한다.
+	current atomic.Pointer[config.Config]
+	parts   []part
+}
+
+type part struct {
+	name    string
+	prepare Prepare
+}
+
+// New 는 현재 설정 cfg 와 설정을 다시 읽는 load 로 Reloader 를 만든다.
+func New(cfg *config.Config, load func() (*config.Config, error)) *Reloader {
+	r := &Reloader{load: load}
+	r.current.Store(cfg)
+	return r
+}
+
+// Register 는 다시 적용할 부분을 등록한다. 등록 순서대로 준비하고 적용한다.
+func (r *Reloader) Register(name string, p Prepare) {
+	r.mu.Lock()
+	r.parts = append(r.parts, part{name, p})
+	r.mu.Unlock()
+}
+
+// Current 는 마지막으로 적용된 설정이다.
+func (r *Reloader) Current() *config.Config { return r.current.Load() }
+
+// Result 는 Reload 결과다.
+type Result struct {
+	Applied []string `json:"applied"`
+}
+
+// Reload 는 설정을 다시 읽어 모든 부분을 준비한 뒤 한꺼번에 적용한다.
+func (r *Reloader) Reload() (Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cfg, err := r.load()
+	if err != nil {
+		return Result{}, err
+	}
+	applies := make([]func(), len(r.parts))
+	for i, p := range r.parts {
+		if applies[i], err = p.prepare(cfg); err != nil {
+			return Result{}, fmt.Errorf("reload %s: %w", p.name, err)
+		}
+	}
+	res := Result{Applied: make([]string, 0, len(r.parts))}
+	for i, apply := range applies {
+		if apply != nil {
+			apply()
+		}
+		res.Applied = append(res.Applied, r.parts[i].name)
+	}
+	r.current.Store(cfg)
+	return res, nil
+}
+
+// WatchSignal 은 ctx 가 끝날 때까지 SIGHUP 을 받을 때마다 Reload 한다.
+func (r *Reloader) WatchSignal(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+		res, err := r.reloadAndLog(logging.Default(), "SIGHUP")
+		audit.Record(ctx, auditEvent("SIGHUP", res, err))
+	}
+}
+
+// WatchFile 은 ctx 가 끝날 때까지 interval 마다 path 의 크기와 수정 시각을 확인해 바뀌면 Reload 한다. (개발 모드)
+// 편집기가 파일을 지웠다 다시 쓰는 동안 잠시 없어지는 것은 무시한다.
+func (r *Reloader) WatchFile(ctx context.Context, path string, interval time.Duration) {
+	stamp := func() string {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprint(fi.Size(), fi.ModTime().UnixNano())
+	}
+	last := stamp()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		cur := stamp()
+		if cur == "" || cur == last {
+			continue
+		}
+		last = cur
+		res, err := r.reloadAndLog(logging.Default(), "file")
+		audit.Record(ctx, auditEvent("file", res, err))
+	}
+}
+
+func (r *Reloader) reloadAndLog(l logging.Logger, trigger string) (Result, error) {
+	res, err := r.Reload()
+	if err != nil {
+		l.Error("reload: rejected, keeping current configuration", "trigger", trigger, "err", err)
+		return res, err
+	}
+	l.Info("reload: applied", "trigger", trigger, "parts", res.Applied)
+	return res, nil
+}
+
+// auditEvent 는 Reload 결과를 감사 이벤트로 만든다.
+func auditEvent(trigger string, res Result, err error) audit.Event {
+	if err != nil {
+		return audit.Event{Type: "config.reload", Outcome: audit.Failure, Fields: map[string]any{"trigger": trigger, "error": err.Error()}}
+	}
+	return audit.Event{Type: "config.reload", Fields: map[string]any{"trigger": trigger, "parts": res.Applied}}
+}
+
+// Handler 는 POST 로 Reload 하는 관리 API 핸들러다. 관리자 인가 미들웨어 뒤에 등록한다.
+// 새 설정이 잘못되었으면 422 와 함께 이유를 돌려주고 현재 설정을 유지한다.
+func (r *Reloader) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		res, err := r.reloadAndLog(logging.From(req.Context()), "api")
+		audit.RecordRequest(req, auditEvent("api", res, err))
+		if err != nil {
+			api.WriteError(w, api.NewError(http.StatusUnprocessableEntity, "invalid_config", err.Error()))
+			return
+		}
+		api.WriteJSON(w, http.StatusOK, res)
+	}
+}
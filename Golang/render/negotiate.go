@@ -0,0 +1,126 @@
+package render
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Templater 는 HTML 로 렌더링할 수 있는 값이다. Template 은 페이지 템플릿 이름이다.
+type Templater interface {
+	Template() string
+}
+
+// Negotiate 는 Accept 헤더에 따라 data 를 JSON, XML, 텍스트 또는 HTML 로 200 응답한다.
+//
+// HTML 은 data 가 Templater 일 때만 제공하며, 이때는 HTML 이 기본값이다. (Accept 가 없거나 "*/*")
+// 텍스트는 data 가 fmt.Stringer 이면 String(), 아니면 fmt.Sprint 결과다.
+// 제공할 수 있는 형식이 없으면 406 으로 응답한다.
+func Negotiate(w http.ResponseWriter, r *http.Request, data any) error {
+	return NegotiateStatus(w, r, http.StatusOK, data)
+}
+
+// NegotiateStatus 는 상태 코드를 지정하는 Negotiate 다.
+func NegotiateStatus(w http.ResponseWriter, r *http.Request, status int, data any) error {
+	offers := []string{"application/json", "application/xml", "text/plain"}
+	page, isPage := data.(Templater)
+	if isPage {
+		offers = append([]string{"text/html"}, offers...)
+	}
+	w.Header().Add("Vary", "Accept")
+	var (
+		body []byte
+		err  error
+	)
+	ct := PreferredType(r.Header.Get("Accept"), offers...)
+	switch ct {
+	case "text/html":
+		e := Default()
+		if e == nil {
+			err = fmt.Errorf("render: default engine is not set")
+			break
+		}
+		return e.RenderStatus(w, status, page.Template(), data)
+	case "application/json":
+		body, err = json.Marshal(data)
+	case "application/xml":
+		if body, err = xml.Marshal(data); err == nil {
+			body = append([]byte(xml.Header), body...)
+		}
+	case "text/plain":
+		if s, ok := data.(fmt.Stringer); ok {
+			body = []byte(s.String())
+		} else {
+			body = []byte(fmt.Sprint(data))
+		}
+	default:
+		http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	w.Header().Set("Content-Type", ct+"; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = w.Write(append(body, '\n'))
+	return err
+}
+
+// PreferredType 은 Accept 헤더에서 가장 선호하는 offer 를 고른다.
+// q 값이 같으면 offers 의 앞쪽이 우선이다. Accept 가 비어 있으면 첫 번째 offer 다.
+// 받아들일 수 있는 것이 없으면 "" 다.
+func PreferredType(accept string, offers ...string) string {
+	if strings.TrimSpace(accept) == "" {
+		if len(offers) > 0 {
+			return offers[0]
+		}
+		return ""
+	}
+	type mediaRange struct {
+		typ, sub string
+		q        float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		typ, sub, _ := strings.Cut(strings.ToLower(strings.TrimSpace(mt)), "/")
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(p), "="); ok && k == "q" {
+				// q 는 0~1 이다. 범위를 벗어난 값은 q 가 없는 것으로 본다.
+				if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+					q = f
+				}
+			}
+		}
+		ranges = append(ranges, mediaRange{typ, sub, q})
+	}
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		typ, sub, _ := strings.Cut(offer, "/")
+		// 가장 구체적으로 일치하는 범위의 q 를 쓴다.
+		q, spec := 0.0, -1
+		for _, mr := range ranges {
+			s := -1
+			switch {
+			case mr.typ == typ && mr.sub == sub:
+				s = 2
+			case mr.typ == typ && mr.sub == "*":
+				s = 1
+			case mr.typ == "*" && mr.sub == "*":
+				s = 0
+			}
+			if s > spec {
+				q, spec = mr.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,193 @@
+// Package render 는 html/template 을 감싼 템플릿 엔진이다.
+//
+// 템플릿 디렉터리 구조:
+//
+//	layouts/*.html   레이아웃 ({{define "base"}} ... {{block "content" .}}{{end}} ...)
+//	partials/*.html  모든 페이지에서 쓸 수 있는 부분 템플릿
+//	*.html           페이지. {{define "content"}} 를 정의하면 레이아웃 안에 렌더링된다.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"sync"
+)
+
+// Options 는 템플릿 엔진 설정이다.
+type Options struct {
+	// Layout 은 페이지를 감쌀 레이아웃 템플릿 이름이다. 기본값은 "base".
+	Layout string
+	// Funcs 는 템플릿에서 사용할 추가 함수다.
+	Funcs template.FuncMap
+	// Reload 가 true 이면 렌더링할 때마다 템플릿을 다시 읽는다. (개발 모드)
+	Reload bool
+}
+
+// Engine 은 시작할 때 파싱한 페이지 템플릿들을 보관한다.
+type Engine struct {
+	fsys fs.FS
+	opts Options
+
+	mu    sync.RWMutex
+	pages map[string]*template.Template
+}
+
+// New 는 fsys 의 템플릿을 모두 파싱한 Engine 을 만든다.
+func New(fsys fs.FS, opts Options) (*Engine, error) {
+	if opts.Layout == "" {
+		opts.Layout = "base"
+	}
+	e := &Engine{fsys: fsys, opts: opts}
+	if err := e.Load(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Load 는 템플릿을 다시 파싱한다. 실패하면 기존 템플릿을 유지한다.
+func (e *Engine) Load() error {
+	shared, err := e.globs("layouts/*.html", "partials/*.html")
+	if err != nil {
+		return err
+	}
+	names, err := fs.Glob(e.fsys, "*.html")
+	if err != nil {
+		return err
+	}
+	pages := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		t := template.New(name).Funcs(e.funcs())
+		files := append(append([]string(nil), shared...), name)
+		if t, err = t.ParseFS(e.fsys, files...); err != nil {
+			return fmt.Errorf("render: %s: %w", name, err)
+		}
+		pages[name] = t
+	}
+	e.mu.Lock()
+	e.pages = pages
+	e.mu.Unlock()
+	return nil
+}
+
+// globs 는 패턴과 일치하는 파일을 모은다.
+func (e *Engine) globs(patterns ...string) ([]string, error) {
+	var out []string
+	for _, p := range patterns {
+		m, err := fs.Glob(e.fsys, p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m...)
+	}
+	return out, nil
+}
+
+// funcs 는 템플릿 함수 맵을 돌려준다.
+func (e *Engine) funcs() template.FuncMap {
+	fm := template.FuncMap{}
+	for k, v := range e.opts.Funcs {
+		fm[k] = v
+	}
+	return fm
+}
+
+// Execute 는 페이지를 렌더링한 결과를 돌려준다.
+func (e *Engine) Execute(name string, data any) ([]byte, error) {
+	t, err := e.page(name)
+	if err != nil {
+		return nil, err
+	}
+	entry := name
+	if t.Lookup("content") != nil && t.Lookup(e.opts.Layout) != nil {
+		entry = e.opts.Layout
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, entry, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ExecuteTemplate 은 페이지 안의 이름 있는 템플릿 하나만 레이아웃 없이 렌더링한다.
+// 페이지에 그 템플릿이 없으면 found 가 false 다. (예: 메일 템플릿의 {{define "subject"}})
+func (e *Engine) ExecuteTemplate(page, name string, data any) (out []byte, found bool, err error) {
+	t, err := e.page(page)
+	if err != nil {
+		return nil, false, err
+	}
+	if t.Lookup(name) == nil {
+		return nil, false, nil
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+		return nil, true, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+// page 는 이름으로 페이지 템플릿을 찾는다. Reload 이면 먼저 다시 읽는다.
+func (e *Engine) page(name string) (*template.Template, error) {
+	if e.opts.Reload {
+		if err := e.Load(); err != nil {
+			return nil, err
+		}
+	}
+	e.mu.RLock()
+	t, ok := e.pages[name]
+	e.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("render: template %q not found", name)
+	}
+	return t, nil
+}
+
+// Render 는 페이지를 200 으로 렌더링한다.
+// 템플릿 오류가 나면 응답 일부만 전송되지 않도록 버퍼에 먼저 렌더링한 뒤 쓴다.
+func (e *Engine) Render(w http.ResponseWriter, name string, data any) error {
+	return e.RenderStatus(w, http.StatusOK, name, data)
+}
+
+// RenderStatus 는 상태 코드를 지정해 페이지를 렌더링한다.
+func (e *Engine) RenderStatus(w http.ResponseWriter, status int, name string, data any) error {
+	b, err := e.Execute(name, data)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = w.Write(b)
+	return err
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultEngine *Engine
+)
+
+// SetDefault 는 패키지 수준 Render 가 사용할 Engine 을 지정한다.
+func SetDefault(e *Engine) {
+	defaultMu.Lock()
+	defaultEngine = e
+	defaultMu.Unlock()
+}
+
+// Default 는 SetDefault 로 지정한 Engine 을 돌려준다.
+func Default() *Engine {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultEngine
+}
+
+// Render 는 기본 Engine 으로 페이지를 렌더링한다. (예: render.Render(w, "hello.html", data))
+func Render(w http.ResponseWriter, name string, data any) error {
+	e := Default()
+	if e == nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return fmt.Errorf("render: default engine is not set")
+	}
+	return e.Render(w, name, data)
+}
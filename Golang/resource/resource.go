@@ -0,0 +1,294 @@
+// Package resource 는 저장소 하나로 REST CRUD 엔드포인트를 만드는 범용 계층이다.
+//
+//	GET    /prefix          목록 (?limit=&offset=, Query 가 있으면 listquery 의 커서·필터·정렬)
+//	POST   /prefix          생성 (201, Location 헤더)
+//	GET    /prefix/{id}     조회
+//	PUT    /prefix/{id}     전체 수정
+//	DELETE /prefix/{id}     삭제 (204)
+//
+// 모든 에러는 api.WriteError 형식으로 응답한다.
+package resource
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/listquery"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/openapi"
+	"github.com/hgsong234/_stack/Golang/router"
+	"github.com/hgsong234/_stack/Golang/store"
+)
+
+// Repository 는 리소스가 사용하는 저장소다. 없는 id 에는 store.ErrNotFound 를 돌려준다.
+type Repository[T any] interface {
+	List(ctx context.Context, limit, offset int) ([]T, error)
+	Get(ctx context.Context, id int64) (*T, error)
+	Create(ctx context.Context, v *T) error
+	Update(ctx context.Context, id int64, v *T) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// Finder 는 listquery 로 거르고 정렬하는 저장소다. Resource.Query 가 있으면 List 대신 쓴다.
+// Count 는 커서를 뺀 같은 조건의 전체 개수다.
+type Finder[T any] interface {
+	Find(ctx context.Context, q *listquery.Query) ([]T, error)
+	Count(ctx context.Context, q *listquery.Query) (int, error)
+}
+
+// 페이지 크기 기본값
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Resource 는 T 타입 리소스의 CRUD 핸들러다.
+type Resource[T any] struct {
+	// Name 은 에러 메시지에 쓰는 단수형 이름이다. (예: "user")
+	Name string
+	Repo Repository[T]
+	// ID 는 생성된 값의 id 를 돌려준다. Location 헤더에 사용한다.
+	ID func(*T) int64
+	// Validate 는 생성·수정 본문을 검사한다. nil 이면 검사하지 않는다.
+	Validate func(*T) error
+	// Query 는 목록이 받는 필터와 정렬 필드다. Repo 가 Finder 여야 한다.
+	Query *listquery.Spec
+	// Read 와 Write 는 조회(GET), 변경(POST, PUT, DELETE) 라우트에만 거는 미들웨어다. (예: 권한 검사)
+	Read, Write []router.Middleware
+	// OnChange 가 있으면 변경에 성공한 뒤 "created", "updated", "deleted" 와 함께 호출한다. 삭제에서 v 는 nil 이다.
+	OnChange func(ctx context.Context, action string, id int64, v *T)
+}
+
+// Page 는 목록 응답이다. HasMore 가 true 이면 offset+limit 부터 더 있다.
+// NextCursor 는 Query 가 있을 때 다음 페이지를 읽는 ?cursor= 값이다.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Mount 는 prefix 와 prefix/{id} 에 CRUD 라우트를 등록한다. mws 는 모든 라우트에 적용된다.
+func (res *Resource[T]) Mount(r router.Routes, prefix string, mws ...router.Middleware) {
+	item := prefix + "/{id}"
+	read := append(append([]router.Middleware(nil), mws...), res.Read...)
+	write := append(append([]router.Middleware(nil), mws...), res.Write...)
+	r.GET(prefix, res.list, read...)
+	r.POST(prefix, res.create, write...)
+	r.GET(item, res.get, read...)
+	r.PUT(item, res.update, write...)
+	r.DELETE(item, res.delete, write...)
+}
+
+// Describe 는 Mount 로 등록한 라우트의 문서 정보를 doc 에 선언한다. prefix 는 전체 경로다.
+func (res *Resource[T]) Describe(doc *openapi.Document, prefix string) {
+	var zero T
+	item := prefix + "/{id}"
+	tags := []string{res.Name + "s"}
+	bad := []int{http.StatusBadRequest, http.StatusUnprocessableEntity}
+	missing := []int{http.StatusBadRequest, http.StatusNotFound}
+	query := []openapi.Param{
+		{Name: "limit", Type: "integer", Description: "page size (1-" + strconv.Itoa(MaxLimit) + ")"},
+		{Name: "offset", Type: "integer"},
+	}
+	if res.Query != nil {
+		query = append(query, res.Query.Params()...)
+	}
+	doc.Describe(http.MethodGet, prefix, openapi.Operation{
+		Summary: "List " + res.Name + "s", Tags: tags, Response: Page[T]{}, Errors: bad, Query: query,
+	})
+	doc.Describe(http.MethodPost, prefix, openapi.Operation{
+		Summary: "Create a " + res.Name, Tags: tags, Request: zero, Response: zero,
+		Status: http.StatusCreated, Errors: bad,
+	})
+	doc.Describe(http.MethodGet, item, openapi.Operation{
+		Summary: "Get a " + res.Name, Tags: tags, Response: zero, Errors: missing,
+	})
+	doc.Describe(http.MethodPut, item, openapi.Operation{
+		Summary: "Replace a " + res.Name, Tags: tags, Request: zero, Response: zero,
+		Errors: append(missing, http.StatusUnprocessableEntity),
+	})
+	doc.Describe(http.MethodDelete, item, openapi.Operation{
+		Summary: "Delete a " + res.Name, Tags: tags, Status: http.StatusNoContent, Errors: missing,
+	})
+}
+
+func (res *Resource[T]) list(w http.ResponseWriter, r *http.Request) {
+	if f, ok := res.Repo.(Finder[T]); ok && res.Query != nil {
+		res.find(w, r, f)
+		return
+	}
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+	// 한 개를 더 읽어서 다음 페이지가 있는지 판단한다.
+	items, err := res.Repo.List(r.Context(), limit+1, offset)
+	if err != nil {
+		res.fail(w, r, err)
+		return
+	}
+	page := Page[T]{Items: items, Limit: limit, Offset: offset}
+	if len(items) > limit {
+		page.Items, page.HasMore = items[:limit], true
+	}
+	(&listquery.Query{Limit: limit, Offset: offset}).SetHeaders(w, r, page.HasMore, "", -1)
+	api.WriteJSON(w, http.StatusOK, page)
+}
+
+// find 는 Query 로 거르고 정렬한 목록이다. 다음 페이지는 오프셋과 커서 양쪽으로 읽을 수 있다.
+func (res *Resource[T]) find(w http.ResponseWriter, r *http.Request, f Finder[T]) {
+	q, err := res.Query.Parse(r)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+	total, err := f.Count(r.Context(), q)
+	if err != nil {
+		res.fail(w, r, err)
+		return
+	}
+	more := *q
+	more.Limit++
+	items, err := f.Find(r.Context(), &more)
+	if err != nil {
+		res.fail(w, r, err)
+		return
+	}
+	page := Page[T]{Items: items, Limit: q.Limit, Offset: q.Offset}
+	if len(items) > q.Limit {
+		page.Items, page.HasMore = items[:q.Limit], true
+		page.NextCursor = q.Cursor(page.Items[q.Limit-1])
+	}
+	q.SetHeaders(w, r, page.HasMore, page.NextCursor, total)
+	api.WriteJSON(w, http.StatusOK, page)
+}
+
+func (res *Resource[T]) create(w http.ResponseWriter, r *http.Request) {
+	v, ok := res.decode(w, r)
+	if !ok {
+		return
+	}
+	if err := res.Repo.Create(r.Context(), v); err != nil {
+		res.fail(w, r, err)
+		return
+	}
+	var id int64
+	if res.ID != nil {
+		id = res.ID(v)
+		w.Header().Set("Location", r.URL.Path+"/"+strconv.FormatInt(id, 10))
+	}
+	res.changed(r, "created", id, v)
+	api.WriteJSON(w, http.StatusCreated, v)
+}
+
+func (res *Resource[T]) get(w http.ResponseWriter, r *http.Request) {
+	id, ok := res.id(w, r)
+	if !ok {
+		return
+	}
+	v, err := res.Repo.Get(r.Context(), id)
+	if err != nil {
+		res.fail(w, r, err)
+		return
+	}
+	api.WriteJSON(w, http.StatusOK, v)
+}
+
+func (res *Resource[T]) update(w http.ResponseWriter, r *http.Request) {
+	id, ok := res.id(w, r)
+	if !ok {
+		return
+	}
+	v, ok := res.decode(w, r)
+	if !ok {
+		return
+	}
+	if err := res.Repo.Update(r.Context(), id, v); err != nil {
+		res.fail(w, r, err)
+		return
+	}
+	res.changed(r, "updated", id, v)
+	api.WriteJSON(w, http.StatusOK, v)
+}
+
+func (res *Resource[T]) delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := res.id(w, r)
+	if !ok {
+		return
+	}
+	if err := res.Repo.Delete(r.Context(), id); err != nil {
+		res.fail(w, r, err)
+		return
+	}
+	res.changed(r, "deleted", id, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (res *Resource[T]) changed(r *http.Request, action string, id int64, v *T) {
+	if res.OnChange != nil {
+		res.OnChange(r.Context(), action, id, v)
+	}
+}
+
+// decode 는 본문을 읽고 validate 태그와 Validate 로 검사한다. 실패하면 에러 응답을 쓰고 false 를 돌려준다.
+func (res *Resource[T]) decode(w http.ResponseWriter, r *http.Request) (*T, bool) {
+	v := new(T)
+	if err := api.ReadJSON(r, v); err != nil {
+		api.WriteError(w, err)
+		return nil, false
+	}
+	if res.Validate != nil {
+		if err := res.Validate(v); err != nil {
+			var apiErr *api.Error
+			if !errors.As(err, &apiErr) {
+				err = api.BadRequest(err.Error())
+			}
+			api.WriteError(w, err)
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// id 는 경로의 {id} 를 읽는다.
+func (res *Resource[T]) id(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(router.Param(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		api.WriteError(w, api.BadRequest("id must be a positive integer"))
+		return 0, false
+	}
+	return id, true
+}
+
+// fail 은 저장소 에러를 응답으로 바꾼다. 예상하지 못한 에러는 기록하고 500 으로 응답한다.
+func (res *Resource[T]) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		api.WriteError(w, api.NotFound(res.Name+" not found"))
+		return
+	}
+	logging.From(r.Context()).Error("resource: "+res.Name, "method", r.Method, "path", r.URL.Path, "err", err)
+	api.WriteError(w, err)
+}
+
+// pageParams 는 ?limit=&offset= 을 읽는다.
+func pageParams(r *http.Request) (limit, offset int, err error) {
+	q := r.URL.Query()
+	limit, offset = DefaultLimit, 0
+	if s := q.Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 1 || limit > MaxLimit {
+			return 0, 0, api.BadRequest("limit must be between 1 and " + strconv.Itoa(MaxLimit))
+		}
+	}
+	if s := q.Get("offset"); s != "" {
+		if offset, err = strconv.Atoi(s); err != nil || offset < 0 {
+			return 0, 0, api.BadRequest("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
@@ -0,0 +1,236 @@
+// Package respcache 는 GET/HEAD 응답을 저장해 두었다가 같은 요청에 그대로 돌려주는 응답 캐시 미들웨어다.
+//
+// 키는 메서드, 경로, 쿼리와 응답의 Vary 헤더가 가리키는 요청 헤더 값으로 만든다.
+// Authorization 헤더가 있거나 Cache-Control: no-store 인 요청, Set-Cookie 나
+// Cache-Control: private/no-store/no-cache 가 붙은 응답은 캐시하지 않는다.
+package respcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/metrics"
+	"github.com/hgsong234/_stack/Golang/middleware"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Header 는 응답이 캐시에서 왔는지 알려 주는 헤더다. 값은 HIT, MISS, BYPASS 중 하나다.
+const Header = "X-Cache"
+
+var cacheRequests = metrics.NewCounterVec("respcache_requests_total",
+	"Response cache lookups by result (hit, miss, bypass).", "result")
+
+// Cache 는 Store 위의 응답 캐시다.
+type Cache struct {
+	Store Store
+	// MaxEntryBytes 는 저장할 응답 본문의 최대 크기다. 넘으면 캐시하지 않는다. 0 이면 1MB.
+	MaxEntryBytes int
+}
+
+// entry 는 저장된 응답이다. Vary 만 있고 Status 가 0 이면 변형 키를 가리키는 안내 항목이다.
+type entry struct {
+	Vary   []string    `json:"vary,omitempty"`
+	Status int         `json:"status,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+	Stored time.Time   `json:"stored"`
+}
+
+// For 는 응답을 ttl 동안 캐시하는 라우트 미들웨어를 돌려준다. c 가 nil 이면 아무것도 하지 않는다.
+func (c *Cache) For(ttl time.Duration) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		if c == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			reqCC := r.Header.Get("Cache-Control")
+			if r.Header.Get("Authorization") != "" || hasDirective(reqCC, "no-store") {
+				cacheRequests.Inc("bypass")
+				w.Header().Set(Header, "BYPASS")
+				next.ServeHTTP(w, r)
+				return
+			}
+			base := baseKey(r)
+			// no-cache 요청은 캐시를 읽지 않지만 새 응답은 저장한다.
+			if !hasDirective(reqCC, "no-cache") && !strings.Contains(reqCC, "max-age=0") {
+				if e := c.lookup(r, base); e != nil {
+					cacheRequests.Inc("hit")
+					c.replay(w, r, e)
+					return
+				}
+			}
+			cacheRequests.Inc("miss")
+			w.Header().Set(Header, "MISS")
+			pre := w.Header().Clone()
+			rec := middleware.NewBodyRecorder(w, c.maxEntryBytes())
+			next.ServeHTTP(rec, r)
+			if e := newEntry(rec, pre); e != nil {
+				c.save(r, base, e, ttl)
+			}
+		})
+	}
+}
+
+func (c *Cache) maxEntryBytes() int {
+	if c.MaxEntryBytes > 0 {
+		return c.MaxEntryBytes
+	}
+	return 1 << 20
+}
+
+// baseKey 는 "경로?쿼리 메서드" 형식이다. 경로가 앞에 있어 Purge 의 접두사로 지울 수 있다.
+func baseKey(r *http.Request) string {
+	return r.URL.Path + "?" + r.URL.RawQuery + " " + r.Method
+}
+
+// variantKey 는 Vary 헤더가 가리키는 요청 헤더 값까지 포함한 키다.
+func variantKey(base string, vary []string, r *http.Request) string {
+	h := sha256.New()
+	for _, name := range vary {
+		h.Write([]byte(name + ":" + strings.Join(r.Header.Values(name), ",") + "\n"))
+	}
+	return base + " " + hex.EncodeToString(h.Sum(nil)[:12])
+}
+
+// lookup 은 요청에 맞는 저장된 응답을 찾는다. 없으면 nil 이다.
+func (c *Cache) lookup(r *http.Request, base string) *entry {
+	e := c.get(r, base)
+	if e != nil && e.Status == 0 {
+		e = c.get(r, variantKey(base, e.Vary, r))
+	}
+	if e == nil || e.Status == 0 {
+		return nil
+	}
+	return e
+}
+
+func (c *Cache) get(r *http.Request, key string) *entry {
+	data, ok, err := c.Store.Get(r.Context(), key)
+	if err != nil {
+		logging.From(r.Context()).Warn("respcache: get", "err", err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil
+	}
+	return &e
+}
+
+func (c *Cache) save(r *http.Request, base string, e *entry, ttl time.Duration) {
+	set := func(key string, e *entry) {
+		data, _ := json.Marshal(e)
+		if err := c.Store.Set(r.Context(), key, data, ttl); err != nil {
+			logging.From(r.Context()).Warn("respcache: set", "err", err)
+		}
+	}
+	if len(e.Vary) == 0 {
+		set(base, e)
+		return
+	}
+	set(base, &entry{Vary: e.Vary, Stored: e.Stored})
+	set(variantKey(base, e.Vary, r), e)
+}
+
+// replay 는 저장된 응답을 쓴다. 바깥 미들웨어가 먼저 설정한 헤더 중 같은 이름은 저장된 값으로 덮어쓴다.
+func (c *Cache) replay(w http.ResponseWriter, r *http.Request, e *entry) {
+	h := w.Header()
+	for k, v := range e.Header {
+		h[k] = v
+	}
+	h.Set(Header, "HIT")
+	h.Set("Age", strconv.Itoa(int(time.Since(e.Stored).Seconds())))
+	h.Set("Content-Length", strconv.Itoa(len(e.Body)))
+	w.WriteHeader(e.Status)
+	if r.Method != http.MethodHead {
+		w.Write(e.Body)
+	}
+}
+
+// Purge 는 경로가 prefix 로 시작하는 항목을 모두 지운다.
+func (c *Cache) Purge(r *http.Request, prefix string) (int, error) {
+	return c.Store.Purge(r.Context(), prefix)
+}
+
+// PurgeHandler 는 DELETE ?prefix=/경로 요청으로 캐시를 지우는 관리용 핸들러다.
+// prefix 가 없으면 전부 지운다. 응답은 {"purged": 지운 개수} 다.
+func (c *Cache) PurgeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prefix := r.URL.Query().Get("prefix")
+		if prefix != "" && !strings.HasPrefix(prefix, "/") {
+			api.WriteError(w, api.BadRequest("prefix must start with /"))
+			return
+		}
+		n, err := c.Purge(r, prefix)
+		if err != nil {
+			logging.From(r.Context()).Error("respcache: purge", "err", err)
+			api.WriteError(w, api.Internal())
+			return
+		}
+		logging.From(r.Context()).Info("respcache: purged", "prefix", prefix, "entries", n)
+		api.WriteJSON(w, http.StatusOK, map[string]int{"purged": n})
+	}
+}
+
+// newEntry 는 기록한 응답이 캐시할 수 있으면 저장할 항목을 만든다. pre 는 핸들러 실행 전의 헤더다.
+func newEntry(rec *middleware.BodyRecorder, pre http.Header) *entry {
+	status := rec.Status()
+	switch status {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusMovedPermanently, http.StatusNotFound, http.StatusGone:
+	default:
+		return nil
+	}
+	h := rec.Sent()
+	cc := h.Get("Cache-Control")
+	body, ok := rec.Body()
+	if !ok || h.Get("Set-Cookie") != "" || hasDirective(cc, "private") ||
+		hasDirective(cc, "no-store") || hasDirective(cc, "no-cache") {
+		return nil
+	}
+	vary := varyNames(h)
+	if slices.Contains(vary, "*") {
+		return nil
+	}
+	// 핸들러가 추가하거나 바꾼 헤더만 저장한다. 요청 ID 같은 바깥 미들웨어의 헤더는 요청마다 새로 붙는다.
+	return &entry{Vary: vary, Status: status, Header: rec.Changed(pre, Header), Body: body, Stored: time.Now()}
+}
+
+// varyNames 는 Vary 헤더의 이름들을 정규화해 정렬한다.
+func varyNames(h http.Header) []string {
+	var names []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// hasDirective 는 Cache-Control 값에 지시어가 있는지 확인한다.
+func hasDirective(cc, directive string) bool {
+	for _, d := range strings.Split(cc, ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(d), "=")
+		if strings.EqualFold(name, directive) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package respcache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore 는 Redis 에 캐시를 보관한다. 여러 인스턴스가 캐시를 공유할 때 사용한다.
+// 크기 제한과 축출은 Redis 의 maxmemory 정책을 따른다.
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore 는 client 를 사용하는 RedisStore 를 만든다. 키는 "respcache:" 접두사를 갖는다.
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client, prefix: "respcache:"}
+}
+
+// Get 은 Store 구현이다.
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// Set 은 Store 구현이다.
+func (s *RedisStore) Set(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+key, data, ttl).Err()
+}
+
+// Purge 는 Store 구현이다. SCAN 으로 키를 찾으므로 키가 많으면 오래 걸릴 수 있다.
+func (s *RedisStore) Purge(ctx context.Context, prefix string) (int, error) {
+	n := 0
+	iter := s.client.Scan(ctx, 0, globEscape(s.prefix+prefix)+"*", 500).Iterator()
+	var batch []string
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		deleted, err := s.client.Del(ctx, batch...).Result()
+		n += int(deleted)
+		batch = batch[:0]
+		return err
+	}
+	for iter.Next(ctx) {
+		if batch = append(batch, iter.Val()); len(batch) == 500 {
+			if err := flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return n, err
+	}
+	return n, flush()
+}
+
+// globEscape 는 SCAN MATCH 패턴의 특수 문자를 이스케이프한다.
+func globEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
@@ -0,0 +1,104 @@
+package respcache
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store 는 직렬화된 캐시 항목을 보관한다. 구현은 동시에 호출해도 안전해야 한다.
+type Store interface {
+	// Get 은 key 의 값을 돌려준다. 없거나 만료되었으면 ok 가 false 다.
+	Get(ctx context.Context, key string) (data []byte, ok bool, err error)
+	// Set 은 key 에 data 를 ttl 동안 저장한다.
+	Set(ctx context.Context, key string, data []byte, ttl time.Duration) error
+	// Purge 는 prefix 로 시작하는 키를 모두 지우고 지운 개수를 돌려준다. prefix 가 비어 있으면 전부 지운다.
+	Purge(ctx context.Context, prefix string) (int, error)
+}
+
+// MemoryStore 는 전체 크기 제한이 있는 프로세스 내 LRU Store 다.
+type MemoryStore struct {
+	maxBytes int64
+
+	mu    sync.Mutex
+	size  int64
+	order *list.List // 앞쪽이 최근에 사용한 항목
+	items map[string]*list.Element
+}
+
+type memItem struct {
+	key     string
+	data    []byte
+	expires time.Time
+}
+
+// NewMemoryStore 는 키와 값 크기의 합이 maxBytes 를 넘지 않도록 오래 쓰지 않은 항목부터 버리는 MemoryStore 를 만든다.
+func NewMemoryStore(maxBytes int64) *MemoryStore {
+	return &MemoryStore{maxBytes: maxBytes, order: list.New(), items: map[string]*list.Element{}}
+}
+
+// Get 은 Store 구현이다.
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	it := el.Value.(*memItem)
+	if time.Now().After(it.expires) {
+		s.remove(el)
+		return nil, false, nil
+	}
+	s.order.MoveToFront(el)
+	return it.data, true, nil
+}
+
+// Set 은 Store 구현이다. 항목 하나가 maxBytes 보다 크면 저장하지 않는다.
+func (s *MemoryStore) Set(_ context.Context, key string, data []byte, ttl time.Duration) error {
+	n := int64(len(key) + len(data))
+	if n > s.maxBytes {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		s.remove(el)
+	}
+	s.items[key] = s.order.PushFront(&memItem{key: key, data: data, expires: time.Now().Add(ttl)})
+	s.size += n
+	for s.size > s.maxBytes {
+		s.remove(s.order.Back())
+	}
+	return nil
+}
+
+// Purge 는 Store 구현이다.
+func (s *MemoryStore) Purge(_ context.Context, prefix string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for key, el := range s.items {
+		if strings.HasPrefix(key, prefix) {
+			s.remove(el)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Len 은 보관 중인 항목 수다.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+func (s *MemoryStore) remove(el *list.Element) {
+	it := el.Value.(*memItem)
+	s.order.Remove(el)
+	delete(s.items, it.key)
+	s.size -= int64(len(it.key) + len(it.data))
+}
@@ -0,0 +1,81 @@
+package router
+
+import "net/http"
+
+// Routes 는 라우트를 등록할 수 있는 대상이다. *Router 와 *Group 이 구현한다.
+type Routes interface {
+	Handle(method, pattern string, h http.Handler, mws ...Middleware)
+	HandleFunc(method, pattern string, h http.HandlerFunc, mws ...Middleware)
+	GET(pattern string, h http.HandlerFunc, mws ...Middleware)
+	POST(pattern string, h http.HandlerFunc, mws ...Middleware)
+	PUT(pattern string, h http.HandlerFunc, mws ...Middleware)
+	DELETE(pattern string, h http.HandlerFunc, mws ...Middleware)
+	Group(prefix string, mws ...Middleware) *Group
+}
+
+// Group 은 공통 경로 접두사와 미들웨어를 가진 라우트 묶음이다. (예: api := r.Group("/api/v1"))
+// 그룹 미들웨어는 전역 미들웨어 안쪽, 라우트 미들웨어 바깥에서 실행된다.
+type Group struct {
+	rt          *Router
+	prefix      string
+	middlewares []Middleware
+}
+
+// Group 은 prefix 아래에 라우트를 등록하는 그룹을 만든다.
+func (rt *Router) Group(prefix string, mws ...Middleware) *Group {
+	return &Group{rt: rt, prefix: trimSlash(prefix), middlewares: mws}
+}
+
+// Group 은 이 그룹 아래에 중첩 그룹을 만든다. 바깥 그룹의 미들웨어가 먼저 실행된다.
+func (g *Group) Group(prefix string, mws ...Middleware) *Group {
+	all := append(append([]Middleware(nil), g.middlewares...), mws...)
+	return &Group{rt: g.rt, prefix: g.prefix + trimSlash(prefix), middlewares: all}
+}
+
+// Use 는 그룹 미들웨어를 추가한다. 이후에 등록하는 라우트에만 적용된다.
+func (g *Group) Use(mws ...Middleware) {
+	g.middlewares = append(g.middlewares, mws...)
+}
+
+// Handle 은 그룹 접두사를 붙여 핸들러를 등록한다. pattern 이 "" 이면 접두사 자체와 일치한다.
+func (g *Group) Handle(method, pattern string, h http.Handler, mws ...Middleware) {
+	all := append(append([]Middleware(nil), g.middlewares...), mws...)
+	p := g.prefix + pattern
+	if p == "" {
+		p = "/"
+	}
+	g.rt.Handle(method, p, h, all...)
+}
+
+// HandleFunc 는 일반 함수를 핸들러로 등록한다.
+func (g *Group) HandleFunc(method, pattern string, h http.HandlerFunc, mws ...Middleware) {
+	g.Handle(method, pattern, h, mws...)
+}
+
+// GET 요청 핸들러 등록
+func (g *Group) GET(pattern string, h http.HandlerFunc, mws ...Middleware) {
+	g.HandleFunc(http.MethodGet, pattern, h, mws...)
+}
+
+// POST 요청 핸들러 등록
+func (g *Group) POST(pattern string, h http.HandlerFunc, mws ...Middleware) {
+	g.HandleFunc(http.MethodPost, pattern, h, mws...)
+}
+
+// PUT 요청 핸들러 등록
+func (g *Group) PUT(pattern string, h http.HandlerFunc, mws ...Middleware) {
+	g.HandleFunc(http.MethodPut, pattern, h, mws...)
+}
+
+// DELETE 요청 핸들러 등록
+func (g *Group) DELETE(pattern string, h http.HandlerFunc, mws ...Middleware) {
+	g.HandleFunc(http.MethodDelete, pattern, h, mws...)
+}
+
+// trimSlash 는 접두사 끝의 "/" 를 없앤다. ("/api/v1/" → "/api/v1")
+func trimSlash(prefix string) string {
+	for len(prefix) > 0 && prefix[len(prefix)-1] == '/' {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix
+}
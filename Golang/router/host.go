@@ -0,0 +1,89 @@
+package router
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Hosts 는 Host 헤더로 요청을 라우트 트리 하나에 나눠 보내는 핸들러다.
+// 정확한 호스트("api.example.com")가 와일드카드("*.example.com")보다 우선하고,
+// 와일드카드끼리는 더 긴 접미사가 우선한다. 와일드카드는 하위 도메인에만 일치하고 "example.com" 자체와는 일치하지 않는다.
+type Hosts struct {
+	// Default 는 일치하는 호스트가 없을 때 실행된다. nil 이면 http.NotFound 다.
+	Default http.Handler
+
+	exact    map[string]http.Handler
+	wildcard []wildcardHost // 접미사가 긴 순서
+}
+
+type wildcardHost struct {
+	suffix  string // ".example.com"
+	handler http.Handler
+}
+
+// NewHosts 는 비어 있는 Hosts 를 만든다.
+func NewHosts() *Hosts {
+	return &Hosts{exact: map[string]http.Handler{}}
+}
+
+// Handle 은 pattern 의 요청을 h 로 보낸다. pattern 은 포트 없는 호스트 이름이나 "*.도메인" 이다.
+func (hs *Hosts) Handle(pattern string, h http.Handler) error {
+	p := normalizeHost(pattern)
+	if suffix, ok := strings.CutPrefix(p, "*"); ok {
+		if !strings.HasPrefix(suffix, ".") || len(suffix) < 2 || strings.Contains(suffix, "*") {
+			return fmt.Errorf("router: invalid wildcard host %q", pattern)
+		}
+		for _, w := range hs.wildcard {
+			if w.suffix == suffix {
+				return fmt.Errorf("router: host %q registered twice", pattern)
+			}
+		}
+		hs.wildcard = append(hs.wildcard, wildcardHost{suffix: suffix, handler: h})
+		sort.SliceStable(hs.wildcard, func(i, j int) bool {
+			return len(hs.wildcard[i].suffix) > len(hs.wildcard[j].suffix)
+		})
+		return nil
+	}
+	if p == "" || strings.ContainsAny(p, "*:/") {
+		return fmt.Errorf("router: invalid host %q", pattern)
+	}
+	if _, dup := hs.exact[p]; dup {
+		return fmt.Errorf("router: host %q registered twice", pattern)
+	}
+	hs.exact[p] = h
+	return nil
+}
+
+// Match 는 host (포트가 붙어 있어도 된다) 를 처리할 핸들러다. 없으면 Default 다.
+func (hs *Hosts) Match(host string) http.Handler {
+	host = normalizeHost(host)
+	if h, ok := hs.exact[host]; ok {
+		return h
+	}
+	for _, w := range hs.wildcard {
+		if strings.HasSuffix(host, w.suffix) && len(host) > len(w.suffix) {
+			return w.handler
+		}
+	}
+	return hs.Default
+}
+
+func (hs *Hosts) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h := hs.Match(r.Host)
+	if h == nil {
+		h = http.HandlerFunc(http.NotFound)
+	}
+	h.ServeHTTP(w, r)
+}
+
+// normalizeHost 는 포트와 끝의 점을 떼고 소문자로 바꾼다.
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
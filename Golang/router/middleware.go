@@ -0,0 +1,21 @@
+package router
+
+import "net/http"
+
+// Middleware 는 핸들러를 감싸 공통 처리(로깅, 인증, 복구 등)를 덧붙인다.
+type Middleware func(http.Handler) http.Handler
+
+// Chain 은 미들웨어를 순서대로 감싼다. 첫 번째 미들웨어가 가장 바깥에서 실행된다.
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// Use 는 모든 라우트에 적용될 전역 미들웨어를 추가한다.
+// 전역 미들웨어는 라우트 매칭 이후에 실행되므로 경로 파라미터와 패턴을 읽을 수 있고,
+// 404/405 응답에도 적용된다. 라우트 미들웨어보다 항상 바깥에서 실행된다.
+func (rt *Router) Use(mws ...Middleware) {
+	rt.middlewares = append(rt.middlewares, mws...)
+}
@@ -0,0 +1,258 @@
+// Package router 는 경로 파라미터("/hello/{name}")를 지원하는 간단한 HTTP 라우터다.
+package router
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// 컨텍스트 키 타입 (다른 패키지의 키와 충돌하지 않도록 비공개 타입을 사용한다)
+type ctxKey int
+
+const (
+	paramsKey ctxKey = iota
+	patternKey
+)
+
+// Params 는 경로 파라미터 이름과 값의 맵이다.
+type Params map[string]string
+
+// 등록된 하나의 라우트
+type route struct {
+	method   string
+	pattern  string
+	segments []string
+	subtree  bool         // "/" 로 끝나는 패턴("/static/")은 하위 경로 전체와 일치한다. 루트 "/" 는 제외.
+	handler  http.Handler // 라우트 미들웨어가 적용된 핸들러
+}
+
+// Router 는 메서드와 경로 패턴으로 핸들러를 찾아 실행한다.
+type Router struct {
+	// NotFound 는 일치하는 경로가 없을 때 실행된다. nil 이면 http.NotFound 다.
+	NotFound http.Handler
+	// MethodNotAllowed 는 경로는 있지만 메서드가 맞지 않을 때 실행된다.
+	// 실행 전에 Allow 헤더가 설정된다. nil 이면 텍스트 405 응답이다.
+	MethodNotAllowed http.Handler
+
+	routes      []*route
+	middlewares []Middleware
+}
+
+// New 는 비어 있는 Router 를 만든다.
+func New() *Router {
+	return &Router{}
+}
+
+// Handle 은 method 와 pattern 에 핸들러를 등록한다.
+// 패턴의 "{name}" 세그먼트는 경로 파라미터로 추출된다.
+// "/" 는 루트 경로에만 일치하고, "/static/" 처럼 "/" 로 끝나는 다른 패턴은 하위 경로 전체와 일치한다.
+// 하위 경로 없이 정확히 일치시키려면 Go 1.22 ServeMux 처럼 "/static/{$}" 로 쓴다.
+// mws 는 이 라우트에만 적용되는 미들웨어로, 전역 미들웨어 안쪽에서 순서대로 실행된다.
+func (rt *Router) Handle(method, pattern string, h http.Handler, mws ...Middleware) {
+	if pattern == "" || pattern[0] != '/' {
+		panic("router: pattern must begin with '/': " + pattern)
+	}
+	segs := split(pattern)
+	seen := map[string]bool{}
+	for _, seg := range segs {
+		if name, ok := paramName(seg); ok {
+			if seen[name] {
+				panic("router: duplicate parameter {" + name + "} in pattern " + pattern)
+			}
+			seen[name] = true
+		}
+	}
+	subtree := strings.HasSuffix(pattern, "/") && pattern != "/"
+	if n := len(segs); n > 0 && segs[n-1] == "{$}" {
+		segs, subtree = segs[:n-1], false
+	}
+	rt.routes = append(rt.routes, &route{
+		method:   method,
+		pattern:  pattern,
+		segments: segs,
+		subtree:  subtree,
+		handler:  Chain(h, mws...),
+	})
+}
+
+// HandleFunc 는 일반 함수를 핸들러로 등록한다.
+func (rt *Router) HandleFunc(method, pattern string, h http.HandlerFunc, mws ...Middleware) {
+	rt.Handle(method, pattern, h, mws...)
+}
+
+// GET 요청 핸들러 등록
+func (rt *Router) GET(pattern string, h http.HandlerFunc, mws ...Middleware) {
+	rt.HandleFunc(http.MethodGet, pattern, h, mws...)
+}
+
+// POST 요청 핸들러 등록
+func (rt *Router) POST(pattern string, h http.HandlerFunc, mws ...Middleware) {
+	rt.HandleFunc(http.MethodPost, pattern, h, mws...)
+}
+
+// PUT 요청 핸들러 등록
+func (rt *Router) PUT(pattern string, h http.HandlerFunc, mws ...Middleware) {
+	rt.HandleFunc(http.MethodPut, pattern, h, mws...)
+}
+
+// DELETE 요청 핸들러 등록
+func (rt *Router) DELETE(pattern string, h http.HandlerFunc, mws ...Middleware) {
+	rt.HandleFunc(http.MethodDelete, pattern, h, mws...)
+}
+
+// ServeHTTP 는 요청 경로에 맞는 라우트를 찾아 실행한다.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segs := split(r.URL.Path)
+	var (
+		best      *route
+		bestScore = -1
+		bestArgs  Params
+		allowed   []string
+	)
+	for _, rte := range rt.routes {
+		params, score, ok := rte.match(segs)
+		if !ok {
+			continue
+		}
+		allowed = append(allowed, rte.method)
+		if rte.method != r.Method && !(rte.method == http.MethodGet && r.Method == http.MethodHead) {
+			continue
+		}
+		if score > bestScore {
+			best, bestScore, bestArgs = rte, score, params
+		}
+	}
+	var h http.Handler
+	switch {
+	case best != nil:
+		ctx := context.WithValue(r.Context(), paramsKey, bestArgs)
+		ctx = context.WithValue(ctx, patternKey, best.pattern)
+		r = r.WithContext(ctx)
+		h = best.handler
+	case len(allowed) > 0:
+		w.Header().Set("Allow", allowHeader(allowed))
+		h = rt.MethodNotAllowed
+		if h == nil {
+			h = http.HandlerFunc(methodNotAllowed)
+		}
+	default:
+		h = rt.NotFound
+		if h == nil {
+			h = http.HandlerFunc(http.NotFound)
+		}
+	}
+	Chain(h, rt.middlewares...).ServeHTTP(w, r)
+}
+
+// 경로는 있지만 메서드가 맞지 않을 때의 기본 응답
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// allowHeader 는 경로에 등록된 메서드로 Allow 헤더 값을 만든다. GET 이 있으면 HEAD 도 허용한다.
+func allowHeader(methods []string) string {
+	seen := map[string]bool{}
+	var out []string
+	add := func(m string) {
+		if !seen[m] {
+			seen[m] = true
+			out = append(out, m)
+		}
+	}
+	for _, m := range methods {
+		add(m)
+		if m == http.MethodGet {
+			add(http.MethodHead)
+		}
+	}
+	sort.Strings(out)
+	return strings.Join(out, ", ")
+}
+
+// match 는 경로 세그먼트가 라우트와 일치하는지 검사한다.
+// score 는 고정 세그먼트가 많을수록 높아서 더 구체적인 라우트가 우선한다.
+func (rte *route) match(segs []string) (Params, int, bool) {
+	pat := rte.segments
+	if rte.subtree {
+		if len(segs) < len(pat) {
+			return nil, 0, false
+		}
+	} else if len(segs) != len(pat) {
+		return nil, 0, false
+	}
+	var params Params
+	score := 0
+	for i, p := range pat {
+		if name, ok := paramName(p); ok {
+			if segs[i] == "" {
+				return nil, 0, false
+			}
+			if params == nil {
+				params = Params{}
+			}
+			params[name] = segs[i]
+			score++
+			continue
+		}
+		if p != segs[i] {
+			return nil, 0, false
+		}
+		score += 2
+	}
+	if !rte.subtree {
+		// 정확히 일치하는 라우트는 하위 트리 라우트보다 우선한다.
+		score += 2
+	}
+	return params, score, true
+}
+
+// paramName 은 "{name}" 세그먼트에서 이름을 꺼낸다.
+func paramName(seg string) (string, bool) {
+	if len(seg) > 2 && seg != "{$}" && seg[0] == '{' && seg[len(seg)-1] == '}' {
+		return seg[1 : len(seg)-1], true
+	}
+	return "", false
+}
+
+// split 은 경로를 세그먼트로 나눈다. 끝의 "/" 는 무시한다.
+func split(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// Param 은 요청 컨텍스트에서 경로 파라미터 값을 꺼낸다.
+func Param(r *http.Request, name string) string {
+	return ParamsFrom(r.Context())[name]
+}
+
+// ParamsFrom 은 컨텍스트에 저장된 모든 경로 파라미터를 반환한다.
+func ParamsFrom(ctx context.Context) Params {
+	p, _ := ctx.Value(paramsKey).(Params)
+	return p
+}
+
+// Pattern 은 요청과 일치한 라우트 패턴을 반환한다. (예: "/hello/{name}")
+func Pattern(r *http.Request) string {
+	p, _ := r.Context().Value(patternKey).(string)
+	return p
+}
+
+// RouteInfo 는 등록된 라우트의 메서드와 패턴이다.
+type RouteInfo struct {
+	Method  string `json:"method" xml:"method,attr"`
+	Pattern string `json:"pattern" xml:"pattern,attr"`
+}
+
+// Routes 는 등록된 라우트를 등록 순서대로 돌려준다. (문서 생성 등에 사용한다)
+func (rt *Router) Routes() []RouteInfo {
+	out := make([]RouteInfo, len(rt.routes))
+	for i, rte := range rt.routes {
+		out[i] = RouteInfo{Method: rte.method, Pattern: rte.pattern}
+	}
+	return out
+}
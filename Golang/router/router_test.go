@@ -0,0 +1,279 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// patternHandler 는 일치한 패턴과 파라미터를 응답 헤더로 돌려준다.
+func patternHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Pattern", Pattern(r))
+	for k, v := range ParamsFrom(r.Context()) {
+		w.Header().Set("X-Param-"+k, v)
+	}
+}
+
+func testRouter() *Router {
+	rt := New()
+	for _, p := range []string{
+		"/",
+		"/users",
+		"/users/me",
+		"/users/{id}",
+		"/users/{id}/posts/{post}",
+		"/static/",
+		"/files/",
+		"/files/{$}",
+		"/{tenant}/settings",
+	} {
+		rt.GET(p, patternHandler)
+	}
+	rt.POST("/users", patternHandler)
+	return rt
+}
+
+func serve(rt *Router, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestMatchSpecificity(t *testing.T) {
+	rt := testRouter()
+	tests := []struct {
+		path    string
+		pattern string
+		params  map[string]string
+	}{
+		{"/", "/", nil},
+		{"/users", "/users", nil},
+		{"/users/", "/users", nil},
+		// 고정 세그먼트가 파라미터보다 우선한다.
+		{"/users/me", "/users/me", nil},
+		{"/users/42", "/users/{id}", map[string]string{"id": "42"}},
+		{"/users/42/posts/7", "/users/{id}/posts/{post}", map[string]string{"id": "42", "post": "7"}},
+		{"/static/css/site.css", "/static/", nil},
+		{"/static", "/static/", nil},
+		// {$} 는 정확히 일치하고 하위 트리 라우트보다 우선한다.
+		{"/files/", "/files/{$}", nil},
+		{"/files/a/b", "/files/", nil},
+		{"/acme/settings", "/{tenant}/settings", map[string]string{"tenant": "acme"}},
+	}
+	for _, tt := range tests {
+		w := serve(rt, http.MethodGet, tt.path)
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: status %d, want 200", tt.path, w.Code)
+			continue
+		}
+		if got := w.Header().Get("X-Pattern"); got != tt.pattern {
+			t.Errorf("GET %s: pattern %q, want %q", tt.path, got, tt.pattern)
+		}
+		for k, v := range tt.params {
+			if got := w.Header().Get("X-Param-" + k); got != v {
+				t.Errorf("GET %s: param %s = %q, want %q", tt.path, k, got, v)
+			}
+		}
+	}
+}
+
+func TestMatchTieKeepsFirstRoute(t *testing.T) {
+	rt := New()
+	rt.GET("/{a}/x", patternHandler)
+	rt.GET("/x/{b}", patternHandler)
+	if got := serve(rt, http.MethodGet, "/x/x").Header().Get("X-Pattern"); got != "/{a}/x" {
+		t.Fatalf("pattern %q, want the first registered /{a}/x", got)
+	}
+}
+
+func TestNotFoundAndMethodNotAllowed(t *testing.T) {
+	rt := testRouter()
+	tests := []struct {
+		method, path string
+		status       int
+		allow        string
+	}{
+		{http.MethodGet, "/nope/deeper/still", http.StatusNotFound, ""},
+		// 빈 세그먼트는 파라미터와 일치하지 않는다.
+		{http.MethodGet, "/users//posts/7", http.StatusNotFound, ""},
+		{http.MethodHead, "/users/42", http.StatusOK, ""},
+		{http.MethodDelete, "/users", http.StatusMethodNotAllowed, "GET, HEAD, POST"},
+		{http.MethodPost, "/users/42", http.StatusMethodNotAllowed, "GET, HEAD"},
+	}
+	for _, tt := range tests {
+		w := serve(rt, tt.method, tt.path)
+		if w.Code != tt.status {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, w.Code, tt.status)
+		}
+		if got := w.Header().Get("Allow"); got != tt.allow {
+			t.Errorf("%s %s: Allow %q, want %q", tt.method, tt.path, got, tt.allow)
+		}
+	}
+}
+
+func TestHandlePanics(t *testing.T) {
+	for _, p := range []string{"", "users", "/a/{id}/b/{id}"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Handle(%q) did not panic", p)
+				}
+			}()
+			New().GET(p, patternHandler)
+		}()
+	}
+}
+
+// 비어 있지 않고 "/" 가 없는 세그먼트라면 어떤 값이든 그대로 파라미터로 꺼내진다.
+func TestParamExtraction(t *testing.T) {
+	rt := New()
+	rt.GET("/a/{x}/b/{y}", patternHandler)
+	prop := func(x, y string) bool {
+		if !segment(x) || !segment(y) {
+			return true
+		}
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.URL = &url.URL{Path: "/a/" + x + "/b/" + y}
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, r)
+		return w.Code == http.StatusOK && w.Header().Get("X-Param-X") == x && w.Header().Get("X-Param-Y") == y
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func segment(s string) bool {
+	return s != "" && !strings.ContainsAny(s, "/\r\n") && strings.TrimSpace(s) == s
+}
+
+// FuzzRouter 는 임의의 메서드와 요청 URI 를 고정된 라우트 표에 보낸다.
+func FuzzRouter(f *testing.F) {
+	for _, s := range [][2]string{
+		{"GET", "/"},
+		{"GET", "/users/42"},
+		{"HEAD", "/users/me/"},
+		{"DELETE", "/users"},
+		{"GET", "/static/../../etc/passwd"},
+		{"GET", "/users/%2F/posts/1"},
+		{"GET", "//users///42"},
+		{"GET", "/files/?q=1#frag"},
+		{"PATCH", "/acme/settings"},
+	} {
+		f.Add(s[0], s[1])
+	}
+	// 전역 미들웨어는 라우트가 정해진 뒤에 실행되므로 여기서 파라미터를 볼 수 있다.
+	var params Params
+	rt := testRouter()
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			params = ParamsFrom(r.Context())
+			next.ServeHTTP(w, r)
+		})
+	})
+	patterns := map[string]bool{}
+	for _, ri := range rt.Routes() {
+		patterns[ri.Pattern] = true
+	}
+	f.Fuzz(func(t *testing.T, method, target string) {
+		u, err := url.ParseRequestURI(target)
+		if err != nil || method == "" || strings.ContainsAny(method, " \r\n") {
+			t.Skip()
+		}
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Method, r.URL = method, u
+		params = nil
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, r)
+		switch w.Code {
+		case http.StatusOK:
+			if p := w.Header().Get("X-Pattern"); !patterns[p] {
+				t.Fatalf("%s %s: matched unknown pattern %q", method, target, p)
+			}
+			segs := split(u.Path)
+			for name, v := range params {
+				if v == "" || strings.Contains(v, "/") || !slices.Contains(segs, v) {
+					t.Fatalf("%s %s: param %s = %q is not a path segment of %q", method, target, name, v, segs)
+				}
+			}
+		case http.StatusNotFound:
+		case http.StatusMethodNotAllowed:
+			if w.Header().Get("Allow") == "" {
+				t.Fatalf("%s %s: 405 without Allow", method, target)
+			}
+		default:
+			t.Fatalf("%s %s: unexpected status %d", method, target, w.Code)
+		}
+	})
+}
+
+// FuzzMatch 는 임의의 패턴과 경로로 match 의 불변식을 확인한다.
+// 일치하면 고정 세그먼트는 같고, 파라미터마다 그 자리의 세그먼트가 들어 있으며,
+// 파라미터를 패턴에 다시 넣으면 같은 경로 세그먼트가 나온다.
+func FuzzMatch(f *testing.F) {
+	for _, s := range [][2]string{
+		{"/", "/"},
+		{"/users/{id}", "/users/42"},
+		{"/users/{id}", "/users/"},
+		{"/static/", "/static/a/b"},
+		{"/files/{$}", "/files/"},
+		{"/{a}/{b}/", "/x/y/z"},
+		{"/a/{id}/b/{id}", "/a/1/b/2"},
+		{"/{}/{x", "/{}/{x"},
+	} {
+		f.Add(s[0], s[1])
+	}
+	f.Fuzz(func(t *testing.T, pattern, path string) {
+		rt := New()
+		if !register(rt, pattern) {
+			t.Skip()
+		}
+		rte := rt.routes[0]
+		segs := split(path)
+		params, score, ok := rte.match(segs)
+		if !ok {
+			return
+		}
+		if rte.subtree && len(segs) < len(rte.segments) || !rte.subtree && len(segs) != len(rte.segments) {
+			t.Fatalf("%q matched %q with %d segments", pattern, path, len(segs))
+		}
+		want := 0
+		rebuilt := make([]string, len(rte.segments))
+		for i, p := range rte.segments {
+			if name, isParam := paramName(p); isParam {
+				if params[name] != segs[i] || params[name] == "" {
+					t.Fatalf("%q on %q: param %s = %q, want %q", pattern, path, name, params[name], segs[i])
+				}
+				rebuilt[i] = params[name]
+				want++
+				continue
+			}
+			rebuilt[i] = p
+			want += 2
+		}
+		if !rte.subtree {
+			want += 2
+		}
+		if score != want {
+			t.Fatalf("%q on %q: score %d, want %d", pattern, path, score, want)
+		}
+		if strings.Join(rebuilt, "/") != strings.Join(segs[:len(rebuilt)], "/") {
+			t.Fatalf("%q on %q: rebuilt %q", pattern, path, rebuilt)
+		}
+	})
+}
+
+// register 는 pattern 을 등록한다. Handle 이 거부하면(panic) false 다.
+func register(rt *Router, pattern string) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	rt.GET(pattern, patternHandler)
+	return true
+}
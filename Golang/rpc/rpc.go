@@ -0,0 +1,169 @@
+// Package rpc 는 gRPC 서비스를 HTTP 서버와 같은 리스너에서 제공하고, 서비스에 선언한 HTTP 규칙
+// (google.api.http)으로 만든 JSON 게이트웨이를 라우터에 붙인다.
+//
+// gRPC 는 HTTP/2 가 필요하므로 TLS 를 쓰거나, 평문이면 server.h2c 를 켠다. 같은 포트의 요청 중
+// HTTP/2 이고 Content-Type 이 application/grpc 인 것만 gRPC 서버로 간다. (Split)
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/protoadapt"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/validate"
+)
+
+// NewServer 는 패닉 복구와 실패 로깅 인터셉터를 건 gRPC 서버를 만든다.
+func NewServer(opts ...grpc.ServerOption) *grpc.Server {
+	return grpc.NewServer(append([]grpc.ServerOption{grpc.ChainUnaryInterceptor(recoverUnary, logUnary)}, opts...)...)
+}
+
+func recoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.From(ctx).Error("grpc: panic", "method", info.FullMethod, "panic", fmt.Sprint(r))
+			resp, err = nil, status.Error(codes.Internal, "internal server error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func logUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		logging.From(ctx).Warn("grpc call failed", "method", info.FullMethod, "code", status.Code(err).String(), "err", err)
+	}
+	return resp, err
+}
+
+// Split 은 HTTP/2 의 gRPC 요청을 grpcHandler 로, 나머지를 next 로 보낸다.
+func Split(grpcHandler, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcHandler.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Gateway 는 JSON 게이트웨이의 ServeMux 를 만든다. REST API 와 맞추어 JSON 필드 이름은 proto 이름
+// (created_at)이고 기본값 필드도 쓰며, 에러는 api 패키지 형식(application/problem+json)이다.
+func Gateway() *runtime.ServeMux {
+	return runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+		}),
+		runtime.WithErrorHandler(writeError),
+	)
+}
+
+// writeError 는 gRPC 상태를 api 에러로 쓴다. Error 가 붙인 ErrorInfo 와 BadRequest 가 있으면 REST API 와
+// 같은 상태, 코드, 필드 에러를 되살린다.
+func writeError(ctx context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	st := status.Convert(err)
+	e := api.NewError(runtime.HTTPStatusFromCode(st.Code()), codeName(st.Code()), st.Message())
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			e.Code = d.Reason
+			if n, err := strconv.Atoi(d.Metadata["http_status"]); err == nil {
+				e.Status = n
+			}
+		case *errdetails.BadRequest:
+			fields := make(validate.Errors, len(d.FieldViolations))
+			for i, v := range d.FieldViolations {
+				fields[i] = validate.FieldError{Field: v.Field, Rule: v.Reason, Message: v.Description}
+			}
+			e.Details = fields
+		}
+	}
+	if st.Code() == codes.Unauthenticated {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	}
+	api.WriteError(w, e)
+}
+
+// Error 는 서비스 에러를 gRPC 상태로 바꾼다. *api.Error 는 HTTP 상태에 맞는 코드에 api 코드를 ErrorInfo 로,
+// 필드 에러를 BadRequest 로 붙인다. 그 밖의 에러는 기록하고 Internal 로 감춘다.
+func Error(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var e *api.Error
+	if !errors.As(err, &e) {
+		logging.From(ctx).Error("grpc: handler failed", "err", err)
+		return status.Error(codes.Internal, "internal server error")
+	}
+	st := status.New(codeFor(e.Status), e.Message)
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{
+		Reason: e.Code, Domain: "api", Metadata: map[string]string{"http_status": strconv.Itoa(e.Status)},
+	}}
+	if fields, ok := e.Details.(validate.Errors); ok {
+		br := &errdetails.BadRequest{}
+		for _, f := range fields {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Reason: f.Rule, Description: f.Message})
+		}
+		details = append(details, br)
+	}
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// codeName 은 gRPC 코드 이름을 api 코드처럼 snake_case 로 바꾼다. (InvalidArgument → invalid_argument)
+func codeName(c codes.Code) string {
+	var b strings.Builder
+	for i, r := range c.String() {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func codeFor(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	return codes.Internal
+}
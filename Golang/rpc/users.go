@@ -0,0 +1,139 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/listquery"
+	"github.com/hgsong234/_stack/Golang/rbac"
+	"github.com/hgsong234/_stack/Golang/resource"
+	"github.com/hgsong234/_stack/Golang/store"
+	"github.com/hgsong234/_stack/Golang/usersv1"
+)
+
+// Users 는 store.Users 로 usersv1.UserService 를 구현한다. 쓰기 메서드는 REST 리소스와 같이
+// users:write 권한이 필요하다. 게이트웨이는 인터셉터를 거치지 않고 메서드를 바로 부르므로 권한은 여기서 확인한다.
+type Users struct {
+	usersv1.UnimplementedUserServiceServer
+
+	Store  *store.Users
+	Policy *rbac.Policy
+	// OnChange 가 있으면 사용자가 바뀐 뒤 부른다. (action: created, updated, deleted)
+	OnChange func(ctx context.Context, action string, id int64, u *store.User)
+}
+
+func (s *Users) GetUser(ctx context.Context, req *usersv1.GetUserRequest) (*usersv1.User, error) {
+	u, err := s.Store.Get(ctx, req.Id)
+	if err != nil {
+		return nil, Error(ctx, notFound(err))
+	}
+	return toProto(u), nil
+}
+
+func (s *Users) ListUsers(ctx context.Context, req *usersv1.ListUsersRequest) (*usersv1.ListUsersResponse, error) {
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = resource.DefaultLimit
+	}
+	if limit < 0 || limit > resource.MaxLimit {
+		return nil, Error(ctx, api.BadRequest("limit must be between 1 and "+strconv.Itoa(resource.MaxLimit)))
+	}
+	if req.Offset < 0 {
+		return nil, Error(ctx, api.BadRequest("offset must not be negative"))
+	}
+	list, err := s.Store.List(ctx, limit, int(req.Offset))
+	if err != nil {
+		return nil, Error(ctx, err)
+	}
+	total, err := s.Store.Count(ctx, &listquery.Query{})
+	if err != nil {
+		return nil, Error(ctx, err)
+	}
+	resp := &usersv1.ListUsersResponse{Users: make([]*usersv1.User, len(list)), Total: int32(total)}
+	for i := range list {
+		resp.Users[i] = toProto(&list[i])
+	}
+	return resp, nil
+}
+
+func (s *Users) CreateUser(ctx context.Context, req *usersv1.CreateUserRequest) (*usersv1.User, error) {
+	u, err := s.input(ctx, req.User)
+	if err != nil {
+		return nil, Error(ctx, err)
+	}
+	if err := s.Store.Create(ctx, u); err != nil {
+		return nil, Error(ctx, err)
+	}
+	s.changed(ctx, "created", u.ID, u)
+	return toProto(u), nil
+}
+
+func (s *Users) UpdateUser(ctx context.Context, req *usersv1.UpdateUserRequest) (*usersv1.User, error) {
+	u, err := s.input(ctx, req.User)
+	if err != nil {
+		return nil, Error(ctx, err)
+	}
+	if err := s.Store.Update(ctx, req.Id, u); err != nil {
+		return nil, Error(ctx, notFound(err))
+	}
+	s.changed(ctx, "updated", u.ID, u)
+	return toProto(u), nil
+}
+
+func (s *Users) DeleteUser(ctx context.Context, req *usersv1.DeleteUserRequest) (*emptypb.Empty, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, Error(ctx, err)
+	}
+	if err := s.Store.Delete(ctx, req.Id); err != nil {
+		return nil, Error(ctx, notFound(err))
+	}
+	s.changed(ctx, "deleted", req.Id, nil)
+	return &emptypb.Empty{}, nil
+}
+
+// authorize 는 요청 주체에 users:write 권한이 있는지 확인한다.
+func (s *Users) authorize(ctx context.Context) error {
+	pr := s.Policy.Resolve(ctx)
+	switch {
+	case pr == nil:
+		return api.NewError(http.StatusUnauthorized, "unauthorized", "authentication required")
+	case !pr.Can("users:write"):
+		return api.NewError(http.StatusForbidden, "forbidden", `permission "users:write" required`)
+	}
+	return nil
+}
+
+// input 은 권한을 확인하고 요청의 사용자를 검사한다.
+func (s *Users) input(ctx context.Context, in *usersv1.User) (*store.User, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if in == nil {
+		return nil, api.BadRequest("user is required")
+	}
+	u := &store.User{Name: in.Name, Email: in.Email}
+	return u, api.Validate(u)
+}
+
+func (s *Users) changed(ctx context.Context, action string, id int64, u *store.User) {
+	if s.OnChange != nil {
+		s.OnChange(ctx, action, id, u)
+	}
+}
+
+func notFound(err error) error {
+	if errors.Is(err, store.ErrNotFound) {
+		return api.NotFound("user not found")
+	}
+	return err
+}
+
+func toProto(u *store.User) *usersv1.User {
+	return &usersv1.User{Id: u.ID, Name: u.Name, Email: u.Email, CreatedAt: timestamppb.New(u.CreatedAt)}
+}
@@ -0,0 +1,245 @@
+package search
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// BM25 매개변수
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// titleWeight 는 제목에서 찾은 낱말의 가중치다. 본문은 1 이다.
+	titleWeight = 2
+	// snippetWords 는 발췌문의 낱말 수다.
+	snippetWords = 12
+)
+
+// Memory 는 프로세스 메모리의 역색인이다. 재시작하면 비므로 Source 로 다시 채운다. 단일 노드용이다.
+type Memory struct {
+	mu       sync.RWMutex
+	docs     map[docKey]*memDoc
+	postings map[string]map[docKey]struct{}
+}
+
+type docKey struct{ kind, id string }
+
+// memDoc 은 색인한 문서다. words 는 본문의 원래 낱말이고 body 는 그 소문자다.
+type memDoc struct {
+	Document
+	title []string
+	body  []string
+	words []string
+}
+
+// NewMemory 는 빈 Memory 색인을 만든다.
+func NewMemory() *Memory {
+	return &Memory{docs: map[docKey]*memDoc{}, postings: map[string]map[docKey]struct{}{}}
+}
+
+// Backend 는 Index 구현이다.
+func (m *Memory) Backend() string { return "memory" }
+
+// Put 은 Index 구현이다.
+func (m *Memory) Put(_ context.Context, docs ...Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range docs {
+		k := docKey{d.Kind, d.ID}
+		m.remove(k)
+		words := strings.FieldsFunc(d.Body, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) })
+		md := &memDoc{Document: d, title: Tokenize(d.Title), words: words, body: make([]string, len(words))}
+		for i, w := range words {
+			md.body[i] = strings.ToLower(w)
+		}
+		m.docs[k] = md
+		for _, w := range slices.Concat(md.title, md.body) {
+			set := m.postings[w]
+			if set == nil {
+				set = map[docKey]struct{}{}
+				m.postings[w] = set
+			}
+			set[k] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// Delete 는 Index 구현이다.
+func (m *Memory) Delete(_ context.Context, kind, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(docKey{kind, id})
+	return nil
+}
+
+// Clear 는 Index 구현이다.
+func (m *Memory) Clear(_ context.Context, kind string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k := range m.docs {
+		if k.kind == kind {
+			m.remove(k)
+		}
+	}
+	return nil
+}
+
+// remove 는 k 문서와 그 낱말의 색인을 뺀다. mu 를 잡고 부른다.
+func (m *Memory) remove(k docKey) {
+	d, ok := m.docs[k]
+	if !ok {
+		return
+	}
+	delete(m.docs, k)
+	for _, w := range slices.Concat(d.title, d.body) {
+		if set := m.postings[w]; set != nil {
+			delete(set, k)
+			if len(set) == 0 {
+				delete(m.postings, w)
+			}
+		}
+	}
+}
+
+// Search 는 Index 구현이다. 모든 조각이 나오는 문서를 BM25 점수 순서로 돌려준다.
+func (m *Memory) Search(_ context.Context, q Query) (*Result, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.docs) == 0 || len(q.Terms) == 0 {
+		return &Result{Hits: []Hit{}}, nil
+	}
+	var avgTitle, avgBody float64
+	for _, d := range m.docs {
+		avgTitle += float64(len(d.title))
+		avgBody += float64(len(d.body))
+	}
+	n := float64(len(m.docs))
+	avgTitle, avgBody = max(avgTitle/n, 1), max(avgBody/n, 1)
+
+	scores := map[docKey]float64{}
+	first := map[docKey]int{}
+	for i, t := range q.Terms {
+		matched := map[docKey][2]int{}
+		for k := range m.candidates(t) {
+			if i > 0 {
+				if _, ok := scores[k]; !ok {
+					continue
+				}
+			}
+			d := m.docs[k]
+			if len(q.Kinds) > 0 && !slices.Contains(q.Kinds, d.Kind) {
+				continue
+			}
+			tt, _ := occurrences(d.title, t)
+			tb, pos := occurrences(d.body, t)
+			if tt+tb == 0 {
+				continue
+			}
+			matched[k] = [2]int{tt, tb}
+			if _, ok := first[k]; !ok && pos >= 0 {
+				first[k] = pos
+			}
+		}
+		idf := math.Log(1 + (n-float64(len(matched))+0.5)/(float64(len(matched))+0.5))
+		next := make(map[docKey]float64, len(matched))
+		for k, tf := range matched {
+			d := m.docs[k]
+			next[k] = scores[k] + idf*(titleWeight*bm25(tf[0], len(d.title), avgTitle)+bm25(tf[1], len(d.body), avgBody))
+		}
+		scores = next
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for k, s := range scores {
+		d := m.docs[k]
+		pos, ok := first[k]
+		if !ok {
+			pos = 0
+		}
+		hits = append(hits, Hit{Document: d.Document, Score: s, Snippet: snippet(d.words, pos)})
+	}
+	slices.SortFunc(hits, func(a, b Hit) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		if c := strings.Compare(a.Kind, b.Kind); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	total := len(hits)
+	start := min(q.Offset, total)
+	end := min(start+q.Limit, total)
+	return &Result{Hits: hits[start:end], Total: total}, nil
+}
+
+// candidates 는 t 의 첫 낱말이 나오는 문서다. 한 낱말 접두사 조각이면 그 접두사로 시작하는 모든 낱말의 문서다.
+func (m *Memory) candidates(t Term) map[docKey]struct{} {
+	if !(t.Prefix && len(t.Words) == 1) {
+		return m.postings[t.Words[0]]
+	}
+	out := map[docKey]struct{}{}
+	for w, set := range m.postings {
+		if strings.HasPrefix(w, t.Words[0]) {
+			for k := range set {
+				out[k] = struct{}{}
+			}
+		}
+	}
+	return out
+}
+
+// occurrences 는 words 에서 t 가 나오는 횟수와 처음 나온 위치(없으면 -1)다.
+func occurrences(words []string, t Term) (int, int) {
+	count, first := 0, -1
+	last := len(t.Words) - 1
+	for i := 0; i+last < len(words); i++ {
+		ok := true
+		for j, w := range t.Words {
+			if got := words[i+j]; got != w && !(j == last && t.Prefix && strings.HasPrefix(got, w)) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			count++
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	return count, first
+}
+
+func bm25(tf, length int, avg float64) float64 {
+	if tf == 0 {
+		return 0
+	}
+	f := float64(tf)
+	return f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*float64(length)/avg))
+}
+
+// snippet 은 pos 번째 낱말 근처의 본문이다. 잘라낸 쪽에는 … 을 붙인다.
+func snippet(words []string, pos int) string {
+	if len(words) == 0 {
+		return ""
+	}
+	start := max(0, min(pos-snippetWords/3, len(words)-snippetWords))
+	end := min(len(words), start+snippetWords)
+	s := strings.Join(words[start:end], " ")
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(words) {
+		s += "…"
+	}
+	return s
+}
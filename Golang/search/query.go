@@ -0,0 +1,55 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Term 은 질의의 한 조각이다. 낱말이 둘 이상이면 이어서 나와야 하는 구절이고,
+// Prefix 이면 마지막 낱말을 접두사로 찾는다.
+type Term struct {
+	Words  []string
+	Prefix bool
+}
+
+// Parse 는 질의 문자열을 조각으로 나눈다. 큰따옴표로 묶은 부분은 구절이고, 끝이 * 인 낱말은 접두사다.
+// 낱말은 색인과 같은 규칙(Tokenize)으로 나누므로 문장 부호는 무시된다.
+func Parse(s string) ([]Term, error) {
+	var terms []Term
+	for s != "" {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			break
+		}
+		var chunk string
+		if s[0] == '"' {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				return nil, errors.New("unterminated phrase")
+			}
+			chunk, s = s[1:end+1], s[end+2:]
+		} else if end := strings.IndexFunc(s, unicode.IsSpace); end >= 0 {
+			chunk, s = s[:end], s[end:]
+		} else {
+			chunk, s = s, ""
+		}
+		prefix := strings.HasSuffix(chunk, "*")
+		if s != "" && s[0] == '*' {
+			// "구절"* 처럼 따옴표 뒤에 붙은 * 도 접두사다.
+			prefix, s = true, s[1:]
+		}
+		words := Tokenize(chunk)
+		if len(words) > 0 {
+			terms = append(terms, Term{Words: words, Prefix: prefix})
+		}
+	}
+	return terms, nil
+}
+
+// Tokenize 는 s 를 소문자 낱말로 나눈다. 글자와 숫자가 아닌 문자는 모두 구분자다.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
@@ -0,0 +1,180 @@
+// Package search 는 여러 저장소의 내용을 하나의 전문 검색 색인으로 찾는다.
+//
+// Index 구현은 프로세스 메모리(Memory)와 SQLite FTS5(SQLite) 두 가지다. 저장소는 종류(Kind)마다
+// Source 로 전체 문서를 내보내 처음 색인을 채우고, 쓰기가 성공할 때마다 Put·Delete 로 색인을 고친다.
+//
+// 질의는 공백으로 나눈 낱말을 모두 포함하는 문서를 찾는다. "두 낱말" 은 구절, hel* 은 접두사다.
+package search
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/metrics"
+)
+
+// 페이지 크기 기본값
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var queryDuration = metrics.NewHistogramVec("search_query_duration_seconds", "Search query duration by backend.", nil, "backend")
+
+// Document 는 색인에 넣는 문서다. Kind 와 ID 가 함께 문서를 가리킨다.
+type Document struct {
+	Kind  string `json:"kind"`
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Body  string `json:"-"`
+	// URL 은 결과에서 원래 자원을 가리키는 경로다.
+	URL string `json:"url,omitempty"`
+}
+
+// Hit 은 검색 결과 하나다. Score 가 클수록 질의와 가깝다. (제목 일치는 본문보다 두 배로 친다)
+type Hit struct {
+	Document
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet,omitempty"`
+}
+
+// Query 는 검색 요청이다. Kinds 가 비어 있으면 모든 종류를 찾는다.
+type Query struct {
+	Terms  []Term
+	Kinds  []string
+	Limit  int
+	Offset int
+}
+
+// Result 는 점수 순서의 한 페이지와 조건에 맞는 전체 개수다.
+type Result struct {
+	Hits  []Hit
+	Total int
+}
+
+// Index 는 문서 색인이다. 여러 요청에서 동시에 써도 안전해야 한다.
+type Index interface {
+	// Put 은 같은 Kind, ID 의 문서를 바꾸거나 새로 넣는다.
+	Put(ctx context.Context, docs ...Document) error
+	// Delete 는 문서를 뺀다. 없으면 아무것도 하지 않는다.
+	Delete(ctx context.Context, kind, id string) error
+	// Clear 는 kind 의 문서를 모두 뺀다.
+	Clear(ctx context.Context, kind string) error
+	Search(ctx context.Context, q Query) (*Result, error)
+	// Backend 는 메트릭과 로그에 쓰는 구현 이름이다. ("memory", "sqlite")
+	Backend() string
+}
+
+// Source 는 한 종류의 문서를 모두 put 으로 내보낸다. put 이 에러를 돌려주면 멈추고 그 에러를 돌려준다.
+type Source func(ctx context.Context, put func(Document) error) error
+
+// Reindex 는 kind 의 문서를 모두 지우고 src 로 다시 채운다. 넣은 문서 수를 돌려준다.
+func Reindex(ctx context.Context, idx Index, kind string, src Source) (int, error) {
+	if err := idx.Clear(ctx, kind); err != nil {
+		return 0, err
+	}
+	n := 0
+	batch := make([]Document, 0, 100)
+	err := src(ctx, func(d Document) error {
+		d.Kind = kind
+		batch = append(batch, d)
+		n++
+		if len(batch) < cap(batch) {
+			return nil
+		}
+		err := idx.Put(ctx, batch...)
+		batch = batch[:0]
+		return err
+	})
+	if err == nil && len(batch) > 0 {
+		err = idx.Put(ctx, batch...)
+	}
+	return n, err
+}
+
+// Sources 는 종류별 Source 다. 전체 재색인(ReindexAll)과 관리 API 에 쓴다.
+type Sources map[string]Source
+
+// ReindexAll 은 모든 종류를 다시 채운다. 한 종류가 실패해도 나머지는 계속한다.
+func (s Sources) ReindexAll(ctx context.Context, idx Index) map[string]int {
+	counts := map[string]int{}
+	for kind, src := range s {
+		start := time.Now()
+		n, err := Reindex(ctx, idx, kind, src)
+		if err != nil {
+			logging.From(ctx).Error("search: reindex", "kind", kind, "err", err)
+			continue
+		}
+		counts[kind] = n
+		logging.From(ctx).Info("search: reindexed", "kind", kind, "documents", n, "took", time.Since(start).Round(time.Millisecond).String())
+	}
+	return counts
+}
+
+// Page 는 검색 응답이다. HasMore 가 true 이면 offset+limit 부터 더 있다.
+type Page struct {
+	Query   string `json:"query"`
+	Items   []Hit  `json:"items"`
+	Total   int    `json:"total"`
+	Limit   int    `json:"limit"`
+	Offset  int    `json:"offset"`
+	HasMore bool   `json:"has_more"`
+}
+
+// Handler 는 GET ?q=...&kind=user,page&limit=&offset= 으로 찾는 핸들러다.
+func Handler(idx Index) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		text := strings.TrimSpace(params.Get("q"))
+		terms, err := Parse(text)
+		if err != nil {
+			api.WriteError(w, api.NewError(http.StatusBadRequest, "invalid_query", err.Error()))
+			return
+		}
+		if len(terms) == 0 {
+			api.WriteError(w, api.NewError(http.StatusBadRequest, "invalid_query", "q is required"))
+			return
+		}
+		q := Query{Terms: terms, Limit: DefaultLimit}
+		if s := params.Get("kind"); s != "" {
+			q.Kinds = strings.Split(s, ",")
+		}
+		if s := params.Get("limit"); s != "" {
+			if q.Limit, err = strconv.Atoi(s); err != nil || q.Limit < 1 || q.Limit > MaxLimit {
+				api.WriteError(w, api.BadRequest("limit must be between 1 and "+strconv.Itoa(MaxLimit)))
+				return
+			}
+		}
+		if s := params.Get("offset"); s != "" {
+			if q.Offset, err = strconv.Atoi(s); err != nil || q.Offset < 0 {
+				api.WriteError(w, api.BadRequest("offset must be a non-negative integer"))
+				return
+			}
+		}
+		start := time.Now()
+		res, err := idx.Search(r.Context(), q)
+		queryDuration.Observe(time.Since(start).Seconds(), idx.Backend())
+		if err != nil {
+			logging.From(r.Context()).Error("search", "query", text, "err", err)
+			api.WriteError(w, err)
+			return
+		}
+		api.WriteJSON(w, http.StatusOK, Page{
+			Query: text, Items: res.Hits, Total: res.Total, Limit: q.Limit, Offset: q.Offset,
+			HasMore: q.Offset+len(res.Hits) < res.Total,
+		})
+	}
+}
+
+// ReindexHandler 는 POST 로 모든 종류를 다시 채우고 종류별 문서 수를 돌려주는 관리 API 핸들러다.
+func ReindexHandler(idx Index, sources Sources) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		counts := sources.ReindexAll(r.Context(), idx)
+		api.WriteJSON(w, http.StatusOK, map[string]any{"backend": idx.Backend(), "documents": counts})
+	}
+}
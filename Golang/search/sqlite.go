@@ -0,0 +1,144 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hgsong234/_stack/Golang/store"
+)
+
+// sqliteSchema 는 문서 테이블과 그 내용을 가리키는 FTS5 색인이다. 트리거가 둘을 맞춰 둔다.
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS search_documents (
+		rowid INTEGER PRIMARY KEY,
+		kind  TEXT NOT NULL,
+		id    TEXT NOT NULL,
+		title TEXT NOT NULL,
+		body  TEXT NOT NULL,
+		url   TEXT NOT NULL,
+		UNIQUE (kind, id)
+	)`,
+	`CREATE VIRTUAL TABLE IF NOT EXISTS search_fts USING fts5(title, body, content='search_documents', content_rowid='rowid')`,
+	`CREATE TRIGGER IF NOT EXISTS search_documents_ai AFTER INSERT ON search_documents BEGIN
+		INSERT INTO search_fts (rowid, title, body) VALUES (new.rowid, new.title, new.body);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS search_documents_ad AFTER DELETE ON search_documents BEGIN
+		INSERT INTO search_fts (search_fts, rowid, title, body) VALUES ('delete', old.rowid, old.title, old.body);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS search_documents_au AFTER UPDATE ON search_documents BEGIN
+		INSERT INTO search_fts (search_fts, rowid, title, body) VALUES ('delete', old.rowid, old.title, old.body);
+		INSERT INTO search_fts (rowid, title, body) VALUES (new.rowid, new.title, new.body);
+	END`,
+}
+
+// SQLite 는 SQLite FTS5 색인이다. 앱 DB 를 같이 써도 되고 따로 둔 파일을 써도 된다.
+type SQLite struct {
+	db store.DB
+}
+
+// NewSQLite 는 db 에 색인 테이블이 없으면 만들고 SQLite 색인을 돌려준다.
+func NewSQLite(ctx context.Context, db store.DB) (*SQLite, error) {
+	if db.Driver() != "sqlite" {
+		return nil, fmt.Errorf("search: the sqlite index needs a sqlite database, not %s", db.Driver())
+	}
+	for _, stmt := range sqliteSchema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("search: create index: %w", err)
+		}
+	}
+	return &SQLite{db: db}, nil
+}
+
+// Backend 는 Index 구현이다.
+func (s *SQLite) Backend() string { return "sqlite" }
+
+// Put 은 Index 구현이다. 문서를 한 트랜잭션으로 넣는다.
+func (s *SQLite) Put(ctx context.Context, docs ...Document) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("search: put: %w", err)
+	}
+	defer tx.Rollback()
+	for _, d := range docs {
+		_, err := tx.ExecContext(ctx, `INSERT INTO search_documents (kind, id, title, body, url) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (kind, id) DO UPDATE SET title = excluded.title, body = excluded.body, url = excluded.url`,
+			d.Kind, d.ID, d.Title, d.Body, d.URL)
+		if err != nil {
+			return fmt.Errorf("search: put %s %s: %w", d.Kind, d.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("search: put: %w", err)
+	}
+	return nil
+}
+
+// Delete 는 Index 구현이다.
+func (s *SQLite) Delete(ctx context.Context, kind, id string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM search_documents WHERE kind = ? AND id = ?`, kind, id); err != nil {
+		return fmt.Errorf("search: delete %s %s: %w", kind, id, err)
+	}
+	return nil
+}
+
+// Clear 는 Index 구현이다.
+func (s *SQLite) Clear(ctx context.Context, kind string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM search_documents WHERE kind = ?`, kind); err != nil {
+		return fmt.Errorf("search: clear %s: %w", kind, err)
+	}
+	return nil
+}
+
+// Search 는 Index 구현이다. 점수는 FTS5 의 bm25 에 제목 가중치를 준 값이다.
+func (s *SQLite) Search(ctx context.Context, q Query) (*Result, error) {
+	where := `search_fts MATCH ?`
+	args := []any{matchExpr(q.Terms)}
+	if len(q.Kinds) > 0 {
+		where += ` AND d.kind IN (` + strings.TrimSuffix(strings.Repeat("?, ", len(q.Kinds)), ", ") + `)`
+		for _, k := range q.Kinds {
+			args = append(args, k)
+		}
+	}
+	from := ` FROM search_fts JOIN search_documents d ON d.rowid = search_fts.rowid WHERE ` + where
+	res := &Result{Hits: []Hit{}}
+	if err := s.db.QueryRowContext(ctx, `SELECT count(*)`+from, args...).Scan(&res.Total); err != nil {
+		return nil, fmt.Errorf("search: query: %w", err)
+	}
+	if res.Total == 0 {
+		return res, nil
+	}
+	rank := fmt.Sprintf("bm25(search_fts, %d, 1)", titleWeight)
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT d.kind, d.id, d.title, d.url, -`+rank+`, snippet(search_fts, 1, '', '', '…', `+fmt.Sprint(snippetWords)+`)`+
+			from+` ORDER BY `+rank+`, d.kind, d.id LIMIT ? OFFSET ?`,
+		append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("search: query: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var h Hit
+		if err := rows.Scan(&h.Kind, &h.ID, &h.Title, &h.URL, &h.Score, &h.Snippet); err != nil {
+			return nil, fmt.Errorf("search: query: %w", err)
+		}
+		h.Snippet = strings.Join(strings.Fields(h.Snippet), " ")
+		res.Hits = append(res.Hits, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search: query: %w", err)
+	}
+	return res, nil
+}
+
+// matchExpr 는 조각을 FTS5 질의로 바꾼다. 낱말에는 따옴표가 없으므로(Tokenize) 그대로 감싼다.
+func matchExpr(terms []Term) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = `"` + strings.Join(t.Words, " ") + `"`
+		if t.Prefix {
+			parts[i] += "*"
+		}
+	}
+	return strings.Join(parts, " AND ")
+}
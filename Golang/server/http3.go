@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+
+	"github.com/hgsong234/_stack/Golang/logging"
+)
+
+// UseHTTP3 은 addr(UDP, 보통 TCP 와 같은 ":443")에서 HTTP/3 도 서비스하도록 설정한다. (실험적)
+// 주 서버와 같은 핸들러와 TLS 설정을 쓰며, TCP 응답에 Alt-Svc 헤더를 붙여 클라이언트에게 알린다.
+// UseTLS 나 UseAutocert 로 TLS 를 설정한 뒤에 호출해야 한다.
+func (s *Server) UseHTTP3(addr string) error {
+	if s.srv.TLSConfig == nil {
+		return errors.New("server: HTTP/3 requires TLS")
+	}
+	s.h3 = &http3.Server{
+		Addr:      addr,
+		Handler:   s.srv.Handler,
+		TLSConfig: http3.ConfigureTLSConfig(s.srv.TLSConfig),
+	}
+	// 응답을 쓰기 전에 Alt-Svc 를 붙인다. TCP 로 받은 요청에만 필요하다.
+	next := s.srv.Handler
+	s.srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 3 {
+			s.h3.SetQUICHeaders(w.Header())
+		}
+		next.ServeHTTP(w, r)
+	})
+	return nil
+}
+
+// udpPrefix 는 재시작 시 자식에게 넘기는 UDP 소켓의 주소 앞에 붙는다. TCP 리스너와 같은 주소(":443")를 쓰기 때문이다.
+const udpPrefix = "udp:"
+
+// serveHTTP3 은 UDP 리스너를 열고 HTTP/3 서비스를 시작한다. 에러는 errc 로 보낸다.
+// 재시작으로 실행된 자식은 부모의 소켓을 물려받아 쓴다. 부모가 듣고 있는 UDP 포트는 새로 열 수 없다.
+func (s *Server) serveHTTP3(errc chan<- error) error {
+	pc := inheritedPacketConn(udpPrefix + s.h3.Addr)
+	if pc == nil {
+		var err error
+		if pc, err = net.ListenPacket("udp", s.h3.Addr); err != nil {
+			return err
+		}
+	}
+	s.listeners = append(s.listeners, namedListener{addr: udpPrefix + s.h3.Addr, ln: pc})
+	go func() {
+		errc <- s.h3.Serve(pc)
+	}()
+	return nil
+}
+
+// shutdownHTTP3 은 새 QUIC 연결을 막고 진행 중인 요청을 기다린다.
+// GOAWAY 에 응답하지 않는 클라이언트(끊긴 모바일 연결 등)가 남아 시간이 다 되면 강제로 닫는다.
+func (s *Server) shutdownHTTP3(ctx context.Context) error {
+	if s.h3 == nil {
+		return nil
+	}
+	err := s.h3.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		logging.Default().Warn("http3: connections did not close in time, closing them")
+		return s.h3.Close()
+	}
+	return err
+}
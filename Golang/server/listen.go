@@ -0,0 +1,50 @@
+package server
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// 주소 접두사. 그 외의 주소는 TCP "host:port" 다.
+const (
+	// UnixPrefix 로 시작하는 주소는 유닉스 도메인 소켓 경로다. (예: "unix:/run/hello/http.sock")
+	UnixPrefix = "unix:"
+	// SystemdPrefix 로 시작하는 주소는 systemd 가 넘겨준 소켓이다. "systemd" 는 첫 번째 소켓,
+	// "systemd:이름" 은 .socket 유닛의 FileDescriptorName 이 일치하는 소켓이다.
+	SystemdPrefix = "systemd"
+)
+
+// newListener 는 addr 형식에 맞는 리스너를 연다.
+func newListener(addr string) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(addr, UnixPrefix):
+		return listenUnix(strings.TrimPrefix(addr, UnixPrefix))
+	case addr == SystemdPrefix || strings.HasPrefix(addr, SystemdPrefix+":"):
+		return systemdListener(strings.TrimPrefix(strings.TrimPrefix(addr, SystemdPrefix), ":"))
+	}
+	return net.Listen("tcp", addr)
+}
+
+// listenUnix 는 path 에 유닉스 소켓을 연다. 이전 프로세스가 남긴 소켓 파일은 아무도 듣고 있지
+// 않을 때만 지운다. 재시작 시 자식이 같은 소켓을 이어 쓰므로 닫을 때 파일을 지우지 않는다.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Stat(path); err == nil && fi.Mode().Type() == fs.ModeSocket {
+		if c, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			c.Close()
+			return nil, errors.New("server: another process is listening on " + path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	return ln, nil
+}
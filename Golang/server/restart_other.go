@@ -0,0 +1,22 @@
+//go:build !unix
+
+package server
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// 유닉스가 아닌 환경에서는 소켓 상속 재시작을 지원하지 않는다.
+func restartSignal() <-chan os.Signal { return nil }
+
+func (s *Server) restart() error {
+	return errors.New("graceful restart is not supported on this platform")
+}
+
+func inheritedListener(string) net.Listener { return nil }
+
+func inheritedPacketConn(string) net.PacketConn { return nil }
+
+func notifyReady() {}
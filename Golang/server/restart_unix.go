@@ -0,0 +1,175 @@
+//go:build unix
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 자식 프로세스에 리스너를 넘길 때 쓰는 환경 변수
+const (
+	envListenAddrs = "SERVER_LISTEN_ADDRS" // 쉼표로 구분된 주소. fd 3 부터 순서대로 대응한다. (UDP 는 udpPrefix)
+	envReadyFD     = "SERVER_READY_FD"     // 준비되면 1바이트를 쓸 파이프 fd
+)
+
+// ReadyTimeout 은 재시작 시 새 프로세스가 준비될 때까지 기다리는 최대 시간이다.
+var ReadyTimeout = 30 * time.Second
+
+// restartSignal 은 무중단 재시작을 요청하는 SIGUSR2 채널이다.
+func restartSignal() <-chan os.Signal {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR2)
+	return ch
+}
+
+// restart 는 현재 리스너의 fd 를 넘겨 같은 바이너리를 다시 실행하고,
+// 자식이 요청을 받을 준비가 될 때까지 기다린다.
+func (s *Server) restart() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	var (
+		files []*os.File
+		addrs []string
+	)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for _, nl := range s.listeners {
+		fl, ok := nl.ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			return fmt.Errorf("listener %s cannot be inherited", nl.addr)
+		}
+		f, err := fl.File()
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		addrs = append(addrs, nl.addr)
+	}
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer pr.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(append([]*os.File(nil), files...), pw)
+	cmd.Env = append(childEnv(),
+		envListenAddrs+"="+strings.Join(addrs, ","),
+		envReadyFD+"="+strconv.Itoa(3+len(files)),
+	)
+	if err := cmd.Start(); err != nil {
+		pw.Close()
+		return err
+	}
+	pw.Close()
+
+	ready := make(chan error, 1)
+	go func() {
+		b := make([]byte, 1)
+		_, err := pr.Read(b)
+		ready <- err
+	}()
+	select {
+	case err := <-ready:
+		if err != nil {
+			// 준비 신호 전에 파이프가 닫혔다면 자식이 시작에 실패한 것이다.
+			cmd.Wait()
+			return fmt.Errorf("new process exited before becoming ready: %w", err)
+		}
+	case <-time.After(ReadyTimeout):
+		cmd.Process.Kill()
+		cmd.Wait()
+		return errors.New("new process did not become ready in time")
+	}
+	return cmd.Process.Release()
+}
+
+// childEnv 는 이전 재시작에서 받은 변수를 뺀 환경 변수 목록이다.
+func childEnv() []string {
+	var out []string
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, envListenAddrs+"=") || strings.HasPrefix(kv, envReadyFD+"=") {
+			continue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+var (
+	inheritOnce sync.Once
+	inherited   map[string]*os.File
+)
+
+// inheritedFile 은 부모에게 물려받은 addr 소켓의 파일을 돌려준다. 없으면 nil 이다.
+func inheritedFile(addr string) *os.File {
+	inheritOnce.Do(func() {
+		inherited = map[string]*os.File{}
+		v := os.Getenv(envListenAddrs)
+		if v == "" {
+			return
+		}
+		for i, a := range strings.Split(v, ",") {
+			inherited[a] = os.NewFile(uintptr(3+i), a)
+		}
+	})
+	f := inherited[addr]
+	delete(inherited, addr)
+	return f
+}
+
+// inheritedListener 는 부모에게 물려받은 addr 리스너를 돌려준다. 없으면 nil 이다.
+func inheritedListener(addr string) net.Listener {
+	f := inheritedFile(addr)
+	if f == nil {
+		return nil
+	}
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil
+	}
+	return ln
+}
+
+// inheritedPacketConn 은 부모에게 물려받은 addr UDP 소켓을 돌려준다. 없으면 nil 이다.
+func inheritedPacketConn(addr string) net.PacketConn {
+	f := inheritedFile(addr)
+	if f == nil {
+		return nil
+	}
+	defer f.Close()
+	pc, err := net.FilePacketConn(f)
+	if err != nil {
+		return nil
+	}
+	return pc
+}
+
+// notifyReady 는 부모 프로세스에게 요청을 받을 준비가 되었음을 알린다.
+func notifyReady() {
+	fd, err := strconv.Atoi(os.Getenv(envReadyFD))
+	if err != nil {
+		return
+	}
+	f := os.NewFile(uintptr(fd), "ready")
+	f.Write([]byte{1})
+	f.Close()
+	os.Unsetenv(envReadyFD)
+}
@@ -0,0 +1,257 @@
+// Package server 는 http.Server 를 감싸 시그널 기반의 안전한 종료(graceful shutdown)를 제공한다.
+// 서브시스템의 시작·준비·종료 훅은 Lifecycle 이 서버의 단계에 맞춰 실행한다.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/hgsong234/_stack/Golang/lifecycle"
+	"github.com/hgsong234/_stack/Golang/logging"
+)
+
+// DefaultDrainTimeout 은 종료 시 진행 중인 요청을 기다리는 기본 시간이다.
+const DefaultDrainTimeout = 15 * time.Second
+
+// Hook 은 종료 시 실행되는 정리 함수다.
+type Hook = lifecycle.Func
+
+// Server 는 시그널을 받으면 진행 중인 요청을 마무리한 뒤 종료하는 HTTP 서버다.
+type Server struct {
+	// DrainTimeout 은 Shutdown 이 진행 중인 요청을 기다리는 최대 시간이다.
+	DrainTimeout time.Duration
+	// RealtimeDrainTimeout 은 드레인 훅(WebSocket, SSE 연결 닫기)에 주는 시간이다. 0 이거나 DrainTimeout 보다
+	// 길면 DrainTimeout 이다.
+	RealtimeDrainTimeout time.Duration
+	// Lifecycle 의 시작 훅은 리스너를 열기 전에, 준비 훅은 요청을 받기 시작한 뒤에, 드레인 훅은 요청
+	// 드레인과 함께, 종료 훅은 요청 드레인이 끝난 뒤에 실행된다.
+	Lifecycle *lifecycle.Manager
+
+	srv   *http.Server
+	cert  atomic.Pointer[tls.Certificate] // UseTLS 로 읽은 인증서
+	extra []*http.Server                  // 리다이렉트, 디버그, AddListener 로 등록한 추가 리스너
+	acme  *autocert.Manager
+	h3    *http3.Server // UseHTTP3 로 켠 QUIC 리스너
+
+	listeners []namedListener // 재시작 시 자식에게 넘길 리스너와 UDP 소켓
+	socket    SocketOptions
+}
+
+// namedListener 는 설정 주소와 실제 소켓의 쌍이다. ln 은 net.Listener 이거나 HTTP/3 의 UDP 소켓이다.
+type namedListener struct {
+	addr string
+	ln   any
+}
+
+// New 는 addr 에서 h 를 서비스하는 Server 를 만든다.
+func New(addr string, h http.Handler) *Server {
+	return &Server{
+		DrainTimeout: DefaultDrainTimeout,
+		Lifecycle:    lifecycle.New(),
+		srv:          &http.Server{Addr: addr, Handler: h},
+	}
+}
+
+// Listen 은 addr 에서 h 를 서비스하는 추가 평문 HTTP 리스너를 등록한다.
+// 추가 리스너는 주 서버와 함께 시작하고 함께 종료된다.
+func (s *Server) Listen(addr string, h http.Handler) {
+	s.extra = append(s.extra, &http.Server{Addr: addr, Handler: h, ReadHeaderTimeout: 5 * time.Second})
+}
+
+// AddListener 는 addr 에서 h 를 서비스하는 추가 리스너를 등록한다. 주 서버의 타임아웃, 프로토콜, 연결 설정을 복사하고,
+// useTLS 이면 주 서버의 TLS 설정(인증서 파일 또는 autocert)을 함께 쓴다.
+// 따라서 SetTimeouts, SetProtocols, SetSocketOptions, UseTLS/UseAutocert 뒤에 호출해야 한다.
+func (s *Server) AddListener(addr string, h http.Handler, useTLS bool) error {
+	es := &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: s.srv.ReadHeaderTimeout,
+		ReadTimeout:       s.srv.ReadTimeout,
+		WriteTimeout:      s.srv.WriteTimeout,
+		IdleTimeout:       s.srv.IdleTimeout,
+		Protocols:         s.srv.Protocols,
+		MaxHeaderBytes:    s.srv.MaxHeaderBytes,
+	}
+	es.SetKeepAlivesEnabled(!s.socket.DisableKeepAlives)
+	if useTLS {
+		if s.srv.TLSConfig == nil {
+			return errors.New("server: TLS listener " + addr + " requires UseTLS or UseAutocert first")
+		}
+		es.TLSConfig = s.srv.TLSConfig
+	}
+	s.extra = append(s.extra, es)
+	return nil
+}
+
+// Timeouts 는 http.Server 의 연결 단위 타임아웃이다. 0 인 값은 제한하지 않는다.
+type Timeouts struct {
+	// ReadHeader 는 요청 헤더를 읽는 시간 제한이다. 느린 헤더 전송(slowloris)을 막는다.
+	ReadHeader time.Duration
+	// Read 는 본문을 포함한 요청 전체를 읽는 시간 제한이다.
+	Read time.Duration
+	// Write 는 응답을 쓰는 시간 제한이다. SSE 처럼 오래 걸리는 응답은 핸들러에서 해제해야 한다.
+	Write time.Duration
+	// Idle 은 keep-alive 연결이 다음 요청을 기다리는 시간이다.
+	Idle time.Duration
+}
+
+// SetTimeouts 는 서버 타임아웃을 설정한다.
+func (s *Server) SetTimeouts(t Timeouts) {
+	s.srv.ReadHeaderTimeout = t.ReadHeader
+	s.srv.ReadTimeout = t.Read
+	s.srv.WriteTimeout = t.Write
+	s.srv.IdleTimeout = t.Idle
+}
+
+// OnShutdown 은 요청 드레인이 끝난 뒤 실행할 정리 함수를 Lifecycle 에 등록한다.
+// 훅은 등록의 역순으로 실행된다.
+func (s *Server) OnShutdown(h Hook) {
+	s.Lifecycle.OnShutdown("", 0, h)
+}
+
+// Run 은 서버를 시작하고 SIGINT/SIGTERM 또는 ctx 취소를 받을 때까지 블록한다.
+// 종료 신호를 받으면 DrainTimeout 동안 진행 중인 요청을 기다리고 정리 훅을 실행한다.
+func (s *Server) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := s.Lifecycle.Start(ctx); err != nil {
+		return err
+	}
+	ln, err := s.listen(s.srv.Addr)
+	if err != nil {
+		return errors.Join(err, s.Lifecycle.Shutdown(context.Background()))
+	}
+	extra := make([]net.Listener, len(s.extra))
+	for i, es := range s.extra {
+		if extra[i], err = s.listen(es.Addr); err != nil {
+			for _, l := range append(extra[:i], ln) {
+				l.Close()
+			}
+			return errors.Join(err, s.Lifecycle.Shutdown(context.Background()))
+		}
+	}
+
+	errc := make(chan error, 2+len(s.extra))
+	if s.h3 != nil {
+		if err := s.serveHTTP3(errc); err != nil {
+			for _, l := range append(extra, ln) {
+				l.Close()
+			}
+			return errors.Join(err, s.Lifecycle.Shutdown(context.Background()))
+		}
+	}
+	// 추가 TLS 리스너도 같은 설정을 쓰므로 서비스를 시작하기 전에 한 번만 고친다.
+	s.nextProtos()
+	go func() {
+		if s.srv.TLSConfig != nil {
+			// 인증서는 TLSConfig.GetCertificate (파일 또는 autocert)가 고른다.
+			errc <- s.srv.ServeTLS(ln, "", "")
+			return
+		}
+		errc <- s.srv.Serve(ln)
+	}()
+	for i, es := range s.extra {
+		go func() {
+			if es.TLSConfig != nil {
+				errc <- es.ServeTLS(extra[i], "", "")
+				return
+			}
+			errc <- es.Serve(extra[i])
+		}()
+	}
+	// 재시작으로 실행된 자식 프로세스라면 부모에게 준비되었음을 알린다.
+	notifyReady()
+	// 준비 훅의 실패는 Ready 가 기록하고, 서버는 계속 요청을 받는다.
+	s.Lifecycle.Ready(ctx)
+
+	restart := restartSignal()
+	for waiting := true; waiting; {
+		select {
+		case err := <-errc:
+			// 서비스 자체가 실패한 경우
+			return errors.Join(err, s.Lifecycle.Shutdown(context.Background()))
+		case <-ctx.Done():
+			waiting = false
+		case <-restart:
+			if err := s.restart(); err != nil {
+				logging.Default().Error("restart failed, keeping current process", "err", err)
+				continue
+			}
+			logging.Default().Info("new process is serving, draining this one")
+			waiting = false
+		}
+	}
+	stop()
+	logging.Default().Info("shutting down, draining requests", "timeout", s.DrainTimeout.String())
+	return s.Shutdown()
+}
+
+// listen 은 부모 프로세스에게 물려받은 리스너가 있으면 그것을, 없으면 addr 형식에 맞는 새 리스너를 돌려준다.
+// addr 은 TCP "host:port", UnixPrefix 로 시작하는 소켓 경로, SystemdPrefix 중 하나다.
+func (s *Server) listen(addr string) (net.Listener, error) {
+	ln := inheritedListener(addr)
+	if ln == nil {
+		var err error
+		if ln, err = newListener(addr); err != nil {
+			return nil, err
+		}
+	}
+	// 자식 프로세스에는 감싸지 않은 리스너의 파일을 넘긴다.
+	s.listeners = append(s.listeners, namedListener{addr: addr, ln: ln})
+	return s.tune(addr, ln), nil
+}
+
+// Shutdown 은 모든 리스너에서 새 연결을 막고 진행 중인 요청을 기다린 뒤 종료 훅을 실행한다.
+// 종료 훅은 드레인과 별도로 각자의 타임아웃을 갖는다.
+func (s *Server) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.DrainTimeout)
+	defer cancel()
+
+	// SSE 처럼 스스로 끝나지 않는 요청은 드레인 훅이 닫아 주어야 아래의 srv.Shutdown 이 기다림을 마친다.
+	drained := make(chan error, 1)
+	go func() {
+		dctx := ctx
+		if s.RealtimeDrainTimeout > 0 {
+			var dcancel context.CancelFunc
+			dctx, dcancel = context.WithTimeout(ctx, s.RealtimeDrainTimeout)
+			defer dcancel()
+		}
+		drained <- s.Lifecycle.Drain(dctx)
+	}()
+
+	// 모든 리스너를 동시에 닫는다. 차례로 닫으면 앞의 서버가 드레인하는 동안 뒤의 서버는 새 연결을 계속 받고,
+	// 나중에 닫히는 서버일수록 DrainTimeout 을 덜 받는다.
+	servers := append([]*http.Server{s.srv}, s.extra...)
+	errs := make([]error, len(servers)+1)
+	var wg sync.WaitGroup
+	for i, srv := range servers {
+		wg.Go(func() { errs[i] = srv.Shutdown(ctx) })
+	}
+	wg.Go(func() { errs[len(servers)] = s.shutdownHTTP3(ctx) })
+	wg.Wait()
+	if err := <-drained; err != nil {
+		errs = append(errs, err)
+	}
+	if err := s.Lifecycle.Shutdown(context.Background()); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// IsClosed 는 err 가 정상 종료에 의한 것인지 확인한다.
+func IsClosed(err error) bool {
+	return err == nil || errors.Is(err, http.ErrServerClosed)
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// protoHandler 는 서버가 받은 요청의 HTTP 주 버전을 본문으로 돌려준다.
+func protoHandler(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, strconv.Itoa(r.ProtoMajor))
+}
+
+// start 는 s 의 http.Server 를 그대로 httptest 서버로 띄운다.
+// httptest 는 ServeTLS 대신 Serve 를 쓰므로 비어 있는 ALPN 목록은 ServeTLS 처럼 프로토콜 설정에 맞춰 채운다.
+func start(t *testing.T, s *Server, useTLS bool) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewUnstartedServer(nil)
+	ts.Config = s.srv
+	if useTLS {
+		s.nextProtos()
+		cfg := s.srv.TLSConfig
+		if cfg.NextProtos == nil {
+			cfg.NextProtos = []string{"http/1.1"}
+			if s.HTTP2() {
+				cfg.NextProtos = []string{"h2", "http/1.1"}
+			}
+		}
+		ts.TLS = cfg
+		ts.StartTLS()
+	} else {
+		ts.Start()
+	}
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// get 은 요청을 보내고 서버가 본 주 버전과 클라이언트가 받은 응답의 주 버전을 돌려준다.
+func get(t *testing.T, c *http.Client, url string) (server, client int) {
+	t.Helper()
+	resp, err := c.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err = strconv.Atoi(string(b))
+	if err != nil {
+		t.Fatalf("body %q: %v", b, err)
+	}
+	return server, resp.ProtoMajor
+}
+
+// tlsClient 는 ts 의 인증서를 믿고 ALPN 으로 h2 를 먼저 제안하는 클라이언트다.
+func tlsClient(ts *httptest.Server) *http.Client {
+	cfg := ts.Client().Transport.(*http.Transport).TLSClientConfig
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, ForceAttemptHTTP2: true}}
+}
+
+// h2cClient 는 업그레이드 없이 평문 HTTP/2 만 쓰는(prior knowledge) 클라이언트다.
+func h2cClient() *http.Client {
+	p := new(http.Protocols)
+	p.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: &http.Transport{Protocols: p}}
+}
+
+func TestHTTP2OverTLS(t *testing.T) {
+	s := New("", http.HandlerFunc(protoHandler))
+	s.SetProtocols(true, false)
+	s.srv.TLSConfig = tlsConfig()
+	ts := start(t, s, true)
+	if server, client := get(t, tlsClient(ts), ts.URL); server != 2 || client != 2 {
+		t.Fatalf("ProtoMajor server=%d client=%d, want 2 (ALPN h2)", server, client)
+	}
+}
+
+func TestHTTP2Disabled(t *testing.T) {
+	tests := []struct {
+		name       string
+		nextProtos []string
+	}{
+		{"tls file", nil},
+		// autocert 의 TLSConfig 처럼 미리 h2 를 넣어 둔 설정에서도 h2 를 빼야 한다.
+		{"autocert", []string{"h2", "http/1.1", "acme-tls/1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New("", http.HandlerFunc(protoHandler))
+			s.SetProtocols(false, false)
+			s.srv.TLSConfig = tlsConfig()
+			s.srv.TLSConfig.NextProtos = tt.nextProtos
+			ts := start(t, s, true)
+			if server, client := get(t, tlsClient(ts), ts.URL); server != 1 || client != 1 {
+				t.Fatalf("ProtoMajor server=%d client=%d, want HTTP/1.1 with server.http2 off", server, client)
+			}
+		})
+	}
+}
+
+func TestH2C(t *testing.T) {
+	s := New("", http.HandlerFunc(protoHandler))
+	s.SetProtocols(true, true)
+	ts := start(t, s, false)
+	if server, client := get(t, h2cClient(), ts.URL); server != 2 || client != 2 {
+		t.Fatalf("ProtoMajor server=%d client=%d, want 2 (h2c prior knowledge)", server, client)
+	}
+	// 평문 HTTP/1.1 도 계속 받는다.
+	if server, _ := get(t, http.DefaultClient, ts.URL); server != 1 {
+		t.Fatalf("ProtoMajor server=%d, want 1 for a plain HTTP/1.1 client", server)
+	}
+}
+
+func TestH2CDisabled(t *testing.T) {
+	s := New("", http.HandlerFunc(protoHandler))
+	s.SetProtocols(true, false)
+	ts := start(t, s, false)
+	resp, err := h2cClient().Get(ts.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("h2c request succeeded with %s, want failure when server.h2c is off", resp.Proto)
+	}
+}
+
+// 추가 리스너에 끝나지 않은 요청이 있어도 모든 리스너가 곧바로 새 연결을 막아야 한다.
+func TestShutdownClosesListenersTogether(t *testing.T) {
+	release := make(chan struct{})
+	inflight := make(chan struct{})
+	s := New("", http.HandlerFunc(protoHandler))
+	s.Listen("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(inflight)
+		<-release
+	}))
+	primary := start(t, s, false)
+	extra := httptest.NewUnstartedServer(nil)
+	extra.Config = s.extra[0]
+	extra.Start()
+	t.Cleanup(extra.Close)
+
+	go http.Get(extra.URL)
+	<-inflight
+	done := make(chan error, 1)
+	go func() { done <- s.Shutdown() }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		c, err := net.Dial("tcp", primary.Listener.Addr().String())
+		if err != nil {
+			break
+		}
+		c.Close()
+		if time.Now().After(deadline) {
+			close(release)
+			t.Fatal("main listener still accepts connections while an extra listener drains")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
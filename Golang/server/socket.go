@@ -0,0 +1,136 @@
+package server
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/metrics"
+)
+
+var (
+	openConns = metrics.NewGaugeVec("http_open_connections",
+		"Number of accepted connections that are still open, by listener address.", "listener")
+	connLimitWaits = metrics.NewCounterVec("http_connection_limit_waits_total",
+		"Times the accept loop paused because the listener reached max_connections.", "listener")
+)
+
+// SocketOptions 는 연결과 소켓 단위 설정이다. 0 인 값은 Go 의 기본값을 쓴다.
+type SocketOptions struct {
+	// MaxHeaderBytes 는 요청 줄과 헤더의 최대 크기다. 0 이면 1MB. (http.DefaultMaxHeaderBytes)
+	MaxHeaderBytes int
+	// DisableKeepAlives 이면 응답마다 HTTP 연결을 닫는다.
+	DisableKeepAlives bool
+	// KeepAlivePeriod 는 TCP keep-alive 탐침 주기다. 0 이면 15초, 음수이면 TCP keep-alive 를 끈다.
+	KeepAlivePeriod time.Duration
+	// DisableNoDelay 이면 Nagle 알고리즘을 켠다. 기본은 TCP_NODELAY 로 작은 응답을 바로 보낸다.
+	DisableNoDelay bool
+	// ReadBuffer, WriteBuffer 는 연결의 커널 수신·송신 버퍼 크기(SO_RCVBUF, SO_SNDBUF)다.
+	ReadBuffer, WriteBuffer int
+	// Backlog 는 accept 를 기다리는 연결 큐의 길이다. (listen(2) 의 backlog) 0 이면 커널 기본값
+	// (somaxconn) 이다. 유닉스에서만 적용한다.
+	Backlog int
+	// MaxConns 는 리스너 하나가 동시에 열어 둘 연결 수다. 가득 차면 연결이 닫힐 때까지 accept 를
+	// 멈추므로 새 연결은 커널 큐에서 기다린다. 0 이면 제한하지 않는다.
+	MaxConns int
+}
+
+// SetSocketOptions 는 연결과 소켓 설정을 바꾼다. AddListener 로 등록하는 리스너도 같은 설정을 쓰므로
+// AddListener 보다 먼저 호출한다. 소켓 설정은 Run 이 여는 모든 리스너에 적용된다.
+func (s *Server) SetSocketOptions(o SocketOptions) {
+	s.socket = o
+	s.srv.MaxHeaderBytes = o.MaxHeaderBytes
+	s.srv.SetKeepAlivesEnabled(!o.DisableKeepAlives)
+}
+
+// tune 은 ln 에 backlog 를 적용하고, 연결 설정과 동시 연결 제한을 거는 리스너로 감싼다.
+func (s *Server) tune(addr string, ln net.Listener) net.Listener {
+	o := s.socket
+	if o.Backlog > 0 {
+		if err := setBacklog(ln, o.Backlog); err != nil {
+			logging.Default().Warn("server: cannot set listen backlog", "addr", addr, "err", err)
+		}
+	}
+	tl := &tunedListener{Listener: ln, opts: o, addr: addr}
+	if o.MaxConns > 0 {
+		tl.sem = make(chan struct{}, o.MaxConns)
+	}
+	return tl
+}
+
+// tunedListener 는 받은 TCP 연결에 소켓 설정을 적용하고, 동시 연결 수를 MaxConns 로 제한한다.
+type tunedListener struct {
+	net.Listener
+	opts SocketOptions
+	addr string
+	sem  chan struct{}
+	full bool // 마지막으로 기다렸는지. 경고를 한 번만 남기기 위해 Accept 고루틴만 쓴다.
+}
+
+func (l *tunedListener) Accept() (net.Conn, error) {
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+			l.full = false
+		default:
+			// 연결이 닫힐 때까지 accept 를 멈춘다. 그동안 새 연결은 커널의 backlog 에 쌓인다.
+			connLimitWaits.Inc(l.addr)
+			if !l.full {
+				logging.Default().Warn("server: max connections reached, pausing accept", "addr", l.addr, "max", l.opts.MaxConns)
+				l.full = true
+			}
+			l.sem <- struct{}{}
+		}
+	}
+	c, err := l.Listener.Accept()
+	if err != nil {
+		if l.sem != nil {
+			<-l.sem
+		}
+		return nil, err
+	}
+	if tc, ok := c.(*net.TCPConn); ok {
+		l.configure(tc)
+	}
+	openConns.Add(1, l.addr)
+	return &trackedConn{Conn: c, l: l}, nil
+}
+
+// configure 는 TCP 연결에 소켓 설정을 적용한다. 실패해도 연결은 그대로 쓴다.
+func (l *tunedListener) configure(c *net.TCPConn) {
+	o := l.opts
+	if o.DisableNoDelay {
+		c.SetNoDelay(false)
+	}
+	switch {
+	case o.KeepAlivePeriod < 0:
+		c.SetKeepAlive(false)
+	case o.KeepAlivePeriod > 0:
+		c.SetKeepAliveConfig(net.KeepAliveConfig{Enable: true, Idle: o.KeepAlivePeriod, Interval: o.KeepAlivePeriod})
+	}
+	if o.ReadBuffer > 0 {
+		c.SetReadBuffer(o.ReadBuffer)
+	}
+	if o.WriteBuffer > 0 {
+		c.SetWriteBuffer(o.WriteBuffer)
+	}
+}
+
+// trackedConn 은 닫힐 때 동시 연결 자리를 돌려준다. WebSocket 처럼 가로챈 연결도 닫을 때 돌려준다.
+type trackedConn struct {
+	net.Conn
+	l    *tunedListener
+	once sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		openConns.Add(-1, c.l.addr)
+		if c.l.sem != nil {
+			<-c.l.sem
+		}
+	})
+	return err
+}
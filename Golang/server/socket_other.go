@@ -0,0 +1,12 @@
+//go:build !unix
+
+package server
+
+import (
+	"errors"
+	"net"
+)
+
+func setBacklog(net.Listener, int) error {
+	return errors.New("listen backlog is only configurable on unix")
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package server
+
+import (
+	"errors"
+	"net"
+)
+
+// 유닉스가 아닌 환경에서는 systemd 소켓 활성화를 지원하지 않는다.
+func systemdListener(string) (net.Listener, error) {
+	return nil, errors.New("server: systemd socket activation is not supported on this platform")
+}
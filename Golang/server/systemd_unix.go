@@ -0,0 +1,81 @@
+//go:build unix
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// systemd 소켓 활성화의 첫 fd (SD_LISTEN_FDS_START)
+const listenFDsStart = 3
+
+var (
+	systemdOnce sync.Once
+	systemdFDs  []systemdFD
+	systemdErr  error
+)
+
+type systemdFD struct {
+	name string
+	ln   net.Listener
+}
+
+// systemdListener 는 LISTEN_FDS 로 넘겨받은 소켓 중 name 이 일치하는 것을 돌려준다.
+// name 이 비어 있으면 아직 쓰지 않은 첫 번째 소켓이다.
+func systemdListener(name string) (net.Listener, error) {
+	systemdOnce.Do(loadSystemdFDs)
+	if systemdErr != nil {
+		return nil, systemdErr
+	}
+	for i, fd := range systemdFDs {
+		if fd.ln != nil && (name == "" || fd.name == name) {
+			systemdFDs[i].ln = nil
+			return fd.ln, nil
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("server: no systemd socket left (LISTEN_FDS=%d)", len(systemdFDs))
+	}
+	return nil, fmt.Errorf("server: no systemd socket named %q", name)
+}
+
+// loadSystemdFDs 는 sd_listen_fds(3) 규약에 따라 환경 변수를 읽고 지운다.
+// 자식 프로세스가 같은 소켓을 다시 해석하지 않도록 변수를 지우는 것도 규약의 일부다.
+func loadSystemdFDs() {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		systemdErr = fmt.Errorf("server: not started by systemd socket activation")
+		return
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		systemdErr = fmt.Errorf("server: LISTEN_FDS is not set")
+		return
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	for i := 0; i < n; i++ {
+		fd := listenFDsStart + i
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(fd), name)
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			systemdErr = fmt.Errorf("server: systemd fd %d: %w", fd, err)
+			return
+		}
+		systemdFDs = append(systemdFDs, systemdFD{name: name, ln: ln})
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// tlsConfig 는 서버 공통 TLS 기본값이다.
+func tlsConfig() *tls.Config {
+	return &tls.Config{MinVersion: tls.VersionTLS12}
+}
+
+// SetProtocols 는 서버가 받아들일 프로토콜을 정한다. HTTP/1.1 은 항상 켜져 있다.
+// http2 는 TLS 위의 HTTP/2(ALPN "h2"), h2c 는 평문 HTTP/2 (prior knowledge) 다.
+// h2c 는 TLS 를 종료하는 프록시 뒤에서 내부 구간에 HTTP/2 를 쓸 때 사용한다.
+func (s *Server) SetProtocols(http2, h2c bool) {
+	p := new(http.Protocols)
+	p.SetHTTP1(true)
+	p.SetHTTP2(http2)
+	p.SetUnencryptedHTTP2(h2c)
+	s.srv.Protocols = p
+}
+
+// HTTP2 는 TLS 에서 HTTP/2 를 협상하는지 확인한다.
+func (s *Server) HTTP2() bool {
+	return s.srv.Protocols == nil || s.srv.Protocols.HTTP2()
+}
+
+// nextProtos 는 HTTP/2 가 꺼져 있으면 ALPN 목록에서 "h2" 를 뺀다.
+// autocert 의 TLSConfig 처럼 미리 "h2" 를 넣어 둔 설정을 위한 것이다.
+func (s *Server) nextProtos() {
+	cfg := s.srv.TLSConfig
+	if cfg == nil || s.HTTP2() {
+		return
+	}
+	protos := cfg.NextProtos[:0:0]
+	for _, p := range cfg.NextProtos {
+		if p != "h2" {
+			protos = append(protos, p)
+		}
+	}
+	cfg.NextProtos = protos
+}
+
+// UseTLS 는 인증서 파일로 HTTPS 를 서비스하도록 설정한다.
+// 인증서는 ReloadCert 로 재시작 없이 바꿀 수 있다.
+func (s *Server) UseTLS(certFile, keyFile string) error {
+	if err := s.ReloadCert(certFile, keyFile); err != nil {
+		return err
+	}
+	cfg := tlsConfig()
+	cfg.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return s.cert.Load(), nil
+	}
+	s.srv.TLSConfig = cfg
+	return nil
+}
+
+// ReloadCert 는 인증서 파일을 다시 읽어 새 연결부터 사용한다. 읽기에 실패하면 기존 인증서를 유지한다.
+func (s *Server) ReloadCert(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	s.SetCert(&cert)
+	return nil
+}
+
+// SetCert 는 새 연결에 사용할 인증서를 바꾼다. UseTLS 로 설정한 서버에서만 의미가 있다.
+func (s *Server) SetCert(cert *tls.Certificate) {
+	s.cert.Store(cert)
+}
+
+// UseAutocert 는 ACME(Let's Encrypt)로 hosts 의 인증서를 자동으로 발급·갱신하도록 설정한다.
+// 발급된 인증서는 cacheDir 에 저장되어 재시작 후에도 재사용된다.
+func (s *Server) UseAutocert(hosts []string, cacheDir, email string) *autocert.Manager {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Cache:      autocert.DirCache(cacheDir),
+		Email:      email,
+	}
+	cfg := m.TLSConfig()
+	cfg.MinVersion = tls.VersionTLS12
+	s.srv.TLSConfig = cfg
+	s.acme = m
+	return m
+}
+
+// RedirectHTTP 는 addr(보통 ":80")에 모든 요청을 HTTPS 로 보내는 리스너를 추가한다.
+// autocert 를 사용 중이면 이 리스너가 ACME http-01 챌린지도 처리한다.
+func (s *Server) RedirectHTTP(addr string) {
+	_, httpsPort, _ := net.SplitHostPort(s.srv.Addr)
+	var h http.Handler = redirectHandler(httpsPort)
+	if s.acme != nil {
+		h = s.acme.HTTPHandler(h)
+	}
+	s.Listen(addr, h)
+}
+
+// redirectHandler 는 같은 호스트의 HTTPS 주소로 영구 리다이렉트한다.
+func redirectHandler(httpsPort string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	}
+}
@@ -0,0 +1,132 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// Info 는 활성 세션 하나의 요약이다.
+type Info struct {
+	// ID 는 세션 ID 의 앞부분이다. 전체 ID 는 쿠키를 위조하는 데 쓰일 수 있으므로 노출하지 않는다.
+	ID     string    `json:"id" xml:"id,attr"`
+	Expiry time.Time `json:"expiry" xml:"expiry,attr"`
+	// Size 는 저장된 데이터의 바이트 수다.
+	Size int `json:"size" xml:"size,attr"`
+}
+
+// Lister 는 활성 세션을 나열할 수 있는 Store 다.
+type Lister interface {
+	List(ctx context.Context) ([]Info, error)
+}
+
+// ErrNotListable 은 Store 가 Lister 를 구현하지 않을 때의 에러다.
+var ErrNotListable = errors.New("session: store cannot list sessions")
+
+// Active 는 저장소의 활성 세션을 만료 시각이 늦은 순서로 돌려준다.
+func (m *Manager) Active(ctx context.Context) ([]Info, error) {
+	l, ok := m.store.(Lister)
+	if !ok {
+		return nil, ErrNotListable
+	}
+	out, err := l.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].ID = shortID(out[i].ID)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Expiry.After(out[j].Expiry) })
+	return out, nil
+}
+
+// shortID 는 세션 ID 를 구분할 수 있을 만큼만 남긴다.
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8] + "…"
+	}
+	return id
+}
+
+// List 는 Lister 구현이다.
+func (s *MemoryStore) List(_ context.Context) ([]Info, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Info, 0, len(s.items))
+	for id, it := range s.items {
+		if now.After(it.expiry) {
+			continue
+		}
+		out = append(out, Info{ID: id, Expiry: it.expiry, Size: len(it.data)})
+	}
+	return out, nil
+}
+
+// List 는 Lister 구현이다. SCAN 으로 키를 훑으므로 세션이 많으면 느릴 수 있다.
+func (s *RedisStore) List(ctx context.Context) ([]Info, error) {
+	var out []Info
+	now := time.Now()
+	iter := s.client.Scan(ctx, 0, s.prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		ttl, err := s.client.PTTL(ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ttl <= 0 {
+			continue
+		}
+		size, err := s.client.StrLen(ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Info{ID: key[len(s.prefix):], Expiry: now.Add(ttl), Size: int(size)})
+	}
+	return out, iter.Err()
+}
+
+// Record 는 백업으로 내보낸 세션 하나다. ID 는 잘리지 않은 전체 값이므로 백업 파일은 비밀로 다룬다.
+type Record struct {
+	ID     string    `json:"id"`
+	Expiry time.Time `json:"expiry"`
+	Data   []byte    `json:"data"`
+}
+
+// Export 는 만료되지 않은 세션을 데이터와 함께 fn 에 넘긴다. 저장소가 Lister 가 아니면 ErrNotListable 이다.
+func (m *Manager) Export(ctx context.Context, fn func(*Record) error) error {
+	l, ok := m.store.(Lister)
+	if !ok {
+		return ErrNotListable
+	}
+	list, err := l.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, info := range list {
+		data, found, err := m.store.Load(ctx, info.ID)
+		if err != nil {
+			return err
+		}
+		if !found {
+			// 나열한 뒤에 만료되었거나 지워졌다.
+			continue
+		}
+		if err := fn(&Record{ID: info.ID, Expiry: info.Expiry, Data: data}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Exists 는 id 세션이 저장소에 있는지 확인한다.
+func (m *Manager) Exists(ctx context.Context, id string) (bool, error) {
+	_, found, err := m.store.Load(ctx, id)
+	return found, err
+}
+
+// Restore 는 rec 를 그 만료 시각까지 저장한다. 같은 ID 가 있으면 덮어쓴다.
+func (m *Manager) Restore(ctx context.Context, rec *Record) error {
+	return m.store.Save(ctx, rec.ID, rec.Data, rec.Expiry)
+}
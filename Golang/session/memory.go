@@ -0,0 +1,73 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore 는 프로세스 메모리에 세션을 보관한다. 단일 인스턴스 배포용이다.
+type MemoryStore struct {
+	mu    sync.Mutex
+	items map[string]memItem
+}
+
+type memItem struct {
+	data   []byte
+	expiry time.Time
+}
+
+// NewMemoryStore 는 MemoryStore 를 만든다. 만료된 세션은 interval 마다 정리된다.
+func NewMemoryStore(interval time.Duration) *MemoryStore {
+	s := &MemoryStore{items: map[string]memItem{}}
+	if interval > 0 {
+		go func() {
+			for range time.Tick(interval) {
+				s.Cleanup()
+			}
+		}()
+	}
+	return s
+}
+
+// Load 는 Store 구현이다.
+func (s *MemoryStore) Load(_ context.Context, id string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.items[id]
+	if !ok || time.Now().After(it.expiry) {
+		return nil, false, nil
+	}
+	return it.data, true, nil
+}
+
+// Save 는 Store 구현이다.
+func (s *MemoryStore) Save(_ context.Context, id string, data []byte, expiry time.Time) error {
+	s.mu.Lock()
+	s.items[id] = memItem{data: data, expiry: expiry}
+	s.mu.Unlock()
+	return nil
+}
+
+// Delete 는 Store 구현이다.
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.items, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// Cleanup 은 만료된 세션을 제거하고 제거한 수를 돌려준다.
+func (s *MemoryStore) Cleanup() int {
+	now := time.Now()
+	n := 0
+	s.mu.Lock()
+	for id, it := range s.items {
+		if now.After(it.expiry) {
+			delete(s.items, id)
+			n++
+		}
+	}
+	s.mu.Unlock()
+	return n
+}
@@ -0,0 +1,47 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore 는 Redis 에 세션을 보관한다. 여러 인스턴스가 세션을 공유할 때 사용한다.
+// 만료는 Redis 의 키 TTL 로 처리된다.
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore 는 client 를 사용하는 RedisStore 를 만든다. 키는 "session:" 접두사를 갖는다.
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client, prefix: "session:"}
+}
+
+// Load 는 Store 구현이다.
+func (s *RedisStore) Load(ctx context.Context, id string) ([]byte, bool, error) {
+	b, err := s.client.Get(ctx, s.prefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// Save 는 Store 구현이다.
+func (s *RedisStore) Save(ctx context.Context, id string, data []byte, expiry time.Time) error {
+	ttl := time.Until(expiry)
+	if ttl <= 0 {
+		return s.Delete(ctx, id)
+	}
+	return s.client.Set(ctx, s.prefix+id, data, ttl).Err()
+}
+
+// Delete 는 Store 구현이다.
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	return s.client.Del(ctx, s.prefix+id).Err()
+}
@@ -0,0 +1,251 @@
+// Package session 은 서명·암호화된 쿠키로 식별되는 서버 측 세션을 제공한다.
+//
+// Manager.Middleware 를 등록한 뒤 핸들러에서 session.Get(r, "user") / session.Set(r, "user", id) 처럼 사용한다.
+// 값은 JSON 으로 저장되므로 숫자는 다시 읽을 때 float64 가 된다.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/cookies"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+	"github.com/hgsong234/_stack/Golang/timing"
+)
+
+// Store 는 세션 데이터를 보관하는 저장소다.
+type Store interface {
+	// Load 는 세션 데이터를 읽는다. 없거나 만료되었으면 found 가 false 다.
+	Load(ctx context.Context, id string) (data []byte, found bool, err error)
+	// Save 는 세션 데이터를 expiry 까지 보관한다.
+	Save(ctx context.Context, id string, data []byte, expiry time.Time) error
+	// Delete 는 세션을 제거한다.
+	Delete(ctx context.Context, id string) error
+}
+
+// Options 는 세션 쿠키 설정이다.
+type Options struct {
+	CookieName string
+	// TTL 은 마지막 변경 이후 세션이 유지되는 시간이다.
+	TTL time.Duration
+	// Secure 가 true 이면 HTTPS 로만 쿠키를 전송한다.
+	Secure bool
+	// Secret 은 쿠키 서명·암호화 키를 만드는 비밀 값이다. (32바이트 이상 권장)
+	Secret []byte
+	// OldSecrets 는 바꾸기 전의 Secret 들이다. 이 값으로 만든 쿠키도 읽는다.
+	OldSecrets [][]byte
+}
+
+// Manager 는 요청마다 세션을 읽고, 변경되면 저장하고 쿠키를 보낸다.
+type Manager struct {
+	store Store
+	opts  Options
+	jar   *cookies.Jar
+}
+
+// NewManager 는 Store 와 설정으로 Manager 를 만든다.
+func NewManager(store Store, opts Options) (*Manager, error) {
+	if opts.CookieName == "" {
+		opts.CookieName = "session"
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 24 * time.Hour
+	}
+	// 세션 ID 는 AES-GCM 으로 암호화되어 쿠키에 들어가므로 변조된 쿠키는 거부된다.
+	jar, err := cookies.New(cookies.Options{
+		Secrets: append([][]byte{opts.Secret}, opts.OldSecrets...),
+		Encrypt: true,
+		MaxAge:  opts.TTL,
+		// 세션 쿠키가 필요한 로컬 개발 환경을 위해 Secure 는 설정을 따른다.
+		Insecure: !opts.Secure,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("session: %w", err)
+	}
+	return &Manager{store: store, opts: opts, jar: jar}, nil
+}
+
+// Cleanup 은 저장소가 스스로 만료하지 않는 경우 만료된 세션을 지우고 지운 수를 돌려준다.
+// Redis 처럼 TTL 로 만료되는 저장소에서는 아무것도 하지 않는다.
+func (m *Manager) Cleanup() int {
+	if c, ok := m.store.(interface{ Cleanup() int }); ok {
+		return c.Cleanup()
+	}
+	return 0
+}
+
+// Session 은 요청 하나에서 사용하는 세션 상태다.
+type Session struct {
+	mu        sync.Mutex
+	id        string
+	oldID     string // Renew 로 교체되기 전 ID
+	values    map[string]any
+	dirty     bool
+	destroyed bool
+}
+
+type ctxKey struct{}
+
+// Middleware 는 세션을 불러와 요청 컨텍스트에 넣는다.
+// 세션이 변경되었으면 응답 헤더를 쓰기 직전에 저장하고 쿠키를 보낸다.
+func (m *Manager) Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			done := timing.Start(r.Context(), "session")
+			s := m.load(r)
+			done()
+			r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, s))
+			sw := &writer{ResponseWriter: w, commit: func() { m.commit(w, r, s) }}
+			next.ServeHTTP(sw, r)
+			sw.flushSession()
+		})
+	}
+}
+
+// load 는 쿠키에서 세션 ID 를 꺼내 저장소에서 데이터를 읽는다.
+func (m *Manager) load(r *http.Request) *Session {
+	s := &Session{values: map[string]any{}}
+	id, err := m.jar.Value(r, m.opts.CookieName)
+	if err != nil {
+		return s
+	}
+	data, found, err := m.store.Load(r.Context(), id)
+	if err != nil {
+		logging.From(r.Context()).Error("session: load", "err", err)
+		return s
+	}
+	if !found {
+		return s
+	}
+	if err := json.Unmarshal(data, &s.values); err != nil {
+		logging.From(r.Context()).Error("session: decode", "err", err)
+		return &Session{values: map[string]any{}}
+	}
+	s.id = id
+	return s
+}
+
+// commit 은 변경된 세션을 저장하고 쿠키를 설정한다. 헤더를 쓰기 전에 한 번만 호출된다.
+func (m *Manager) commit(w http.ResponseWriter, r *http.Request, s *Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ctx := r.Context()
+	if s.oldID != "" {
+		if err := m.store.Delete(ctx, s.oldID); err != nil {
+			logging.From(ctx).Error("session: delete", "err", err)
+		}
+	}
+	if s.destroyed {
+		if s.id != "" {
+			if err := m.store.Delete(ctx, s.id); err != nil {
+				logging.From(ctx).Error("session: delete", "err", err)
+			}
+		}
+		m.jar.Delete(w, m.opts.CookieName)
+		return
+	}
+	if !s.dirty {
+		return
+	}
+	if s.id == "" {
+		s.id = newID()
+	}
+	data, err := json.Marshal(s.values)
+	if err != nil {
+		logging.From(ctx).Error("session: encode", "err", err)
+		return
+	}
+	expiry := time.Now().Add(m.opts.TTL)
+	if err := m.store.Save(ctx, s.id, data, expiry); err != nil {
+		logging.From(ctx).Error("session: save", "err", err)
+		return
+	}
+	http.SetCookie(w, m.jar.Cookie(m.opts.CookieName, s.id))
+}
+
+// newID 는 256비트 난수 세션 ID 를 만든다.
+func newID() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ErrNoSession 은 세션 미들웨어 없이 세션을 사용하려 할 때의 에러다.
+var ErrNoSession = errors.New("session: middleware not installed")
+
+// From 은 요청의 세션을 돌려준다. 미들웨어가 없으면 nil 이다.
+func From(r *http.Request) *Session {
+	s, _ := r.Context().Value(ctxKey{}).(*Session)
+	return s
+}
+
+// Get 은 세션 값을 읽는다.
+func Get(r *http.Request, key string) any {
+	s := From(r)
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// GetString 은 문자열 세션 값을 읽는다.
+func GetString(r *http.Request, key string) string {
+	v, _ := Get(r, key).(string)
+	return v
+}
+
+// Set 은 세션 값을 저장한다. 응답을 쓰기 전에 호출해야 쿠키가 전송된다.
+func Set(r *http.Request, key string, v any) error {
+	s := From(r)
+	if s == nil {
+		return ErrNoSession
+	}
+	s.mu.Lock()
+	s.values[key] = v
+	s.dirty = true
+	s.mu.Unlock()
+	return nil
+}
+
+// Delete 는 세션 값 하나를 제거한다.
+func Delete(r *http.Request, key string) {
+	if s := From(r); s != nil {
+		s.mu.Lock()
+		delete(s.values, key)
+		s.dirty = true
+		s.mu.Unlock()
+	}
+}
+
+// Renew 는 세션 ID 를 새로 발급한다. 로그인처럼 권한이 바뀔 때 세션 고정 공격을 막기 위해 호출한다.
+func Renew(r *http.Request) {
+	if s := From(r); s != nil {
+		s.mu.Lock()
+		if s.id != "" && s.oldID == "" {
+			s.oldID = s.id
+		}
+		s.id = ""
+		s.dirty = true
+		s.mu.Unlock()
+	}
+}
+
+// Destroy 는 세션을 제거하고 쿠키를 만료시킨다.
+func Destroy(r *http.Request) {
+	if s := From(r); s != nil {
+		s.mu.Lock()
+		s.values = map[string]any{}
+		s.destroyed = true
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,40 @@
+package session
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// writer 는 첫 WriteHeader/Write 직전에 세션을 저장하는 ResponseWriter 래퍼다.
+type writer struct {
+	http.ResponseWriter
+	commit func()
+	once   sync.Once
+}
+
+func (w *writer) flushSession() { w.once.Do(w.commit) }
+
+func (w *writer) WriteHeader(code int) {
+	w.flushSession()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	w.flushSession()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *writer) Flush() {
+	w.flushSession()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *writer) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
@@ -0,0 +1,245 @@
+// Package shed 는 동시에 처리하는 요청 수를 전역과 라우트별로 제한한다. 한도가 차면 요청은 잠깐 줄을 서고,
+// 줄이 가득 찼거나 기다리는 시간이 지나면 503 과 Retry-After 로 거절(load shedding)한다.
+//
+// Target 을 정하면 한도는 응답 시간에 맞춰 움직인다(AIMD). 응답이 Target 보다 빠르면 한도를 조금씩 올리고,
+// 느리거나 줄에서 거절이 생기면 줄인다. 한도는 언제나 Min 과 Max 사이다.
+package shed
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/metrics"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+var (
+	shedTotal = metrics.NewCounterVec("shed_rejected_total", "Requests rejected by the concurrency limiter.", "limiter", "reason")
+	inFlight  = metrics.NewGaugeVec("shed_in_flight", "Requests being served under the concurrency limiter.", "limiter")
+	queued    = metrics.NewGaugeVec("shed_queued", "Requests waiting for a concurrency slot.", "limiter")
+	limits    = metrics.NewGaugeVec("shed_limit", "Current concurrency limit.", "limiter")
+)
+
+// 거절 이유
+var (
+	ErrQueueFull = errors.New("shed: queue is full")
+	ErrTimeout   = errors.New("shed: timed out waiting for a slot")
+)
+
+// Config 는 Limiter 설정이다.
+type Config struct {
+	// Max 는 동시에 처리할 최대 요청 수다. Target 이 없으면 한도는 Max 로 고정이다.
+	Max int
+	// Min 은 한도를 줄일 때의 하한이다. 0 이면 1.
+	Min int
+	// Queue 는 한도가 찼을 때 기다릴 수 있는 요청 수, QueueTimeout 은 기다리는 최대 시간이다.
+	Queue        int
+	QueueTimeout time.Duration
+	// Target 은 한도를 조절하는 기준 응답 시간이다. 0 이면 조절하지 않는다.
+	Target time.Duration
+}
+
+// Limiter 는 세마포어와 대기열이다.
+type Limiter struct {
+	name string
+	cfg  Config
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	waiters  []chan struct{} // 먼저 온 순서
+}
+
+// New 는 name(메트릭 레이블) 의 Limiter 를 만든다. 한도는 Max 에서 시작한다.
+func New(name string, cfg Config) *Limiter {
+	if cfg.Min <= 0 {
+		cfg.Min = 1
+	}
+	if cfg.Max < cfg.Min {
+		cfg.Max = cfg.Min
+	}
+	l := &Limiter{name: name, cfg: cfg, limit: float64(cfg.Max)}
+	limits.Set(l.limit, name)
+	return l
+}
+
+// Limit 은 지금 한도다.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// Acquire 는 자리를 얻을 때까지 기다린다. 얻으면 처리가 끝났을 때 부를 함수를 돌려준다.
+// 대기열이 가득 찼으면 ErrQueueFull, 기다리는 시간이 지났으면 ErrTimeout, ctx 가 끝났으면 ctx.Err() 다.
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	l.mu.Lock()
+	if l.inFlight < int(l.limit) && len(l.waiters) == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return l.releaser(), nil
+	}
+	if len(l.waiters) >= l.cfg.Queue {
+		l.decrease()
+		l.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	queued.Set(float64(len(l.waiters)), l.name)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.cfg.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case <-ready:
+		return l.releaser(), nil
+	case <-timer.C:
+		err = ErrTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.remove(ready) {
+		// 시간이 지나는 사이에 자리를 넘겨받았다. 쓰지 않고 돌려준다.
+		l.inFlight--
+		l.handOff()
+	}
+	if err == ErrTimeout {
+		l.decrease()
+	}
+	return nil, err
+}
+
+// releaser 는 자리를 돌려주고 처리 시간으로 한도를 조절하는 함수를 만든다.
+func (l *Limiter) releaser() func() {
+	inFlight.Add(1, l.name)
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d := time.Since(start)
+			inFlight.Add(-1, l.name)
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.inFlight--
+			if t := l.cfg.Target; t > 0 {
+				if d > t {
+					l.decrease()
+				} else if l.limit < float64(l.cfg.Max) {
+					// 한도 하나를 채울 만큼 빠른 응답이 오면 1 씩 오른다.
+					l.limit = math.Min(l.limit+1/l.limit, float64(l.cfg.Max))
+					limits.Set(l.limit, l.name)
+				}
+			}
+			l.handOff()
+		})
+	}
+}
+
+// handOff 는 빈자리를 대기열의 맨 앞 요청에게 넘긴다. l.mu 를 잡고 부른다.
+func (l *Limiter) handOff() {
+	for len(l.waiters) > 0 && l.inFlight < int(l.limit) {
+		ready := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		l.inFlight++
+		close(ready)
+	}
+	queued.Set(float64(len(l.waiters)), l.name)
+}
+
+func (l *Limiter) remove(ready chan struct{}) bool {
+	for i, c := range l.waiters {
+		if c == ready {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			queued.Set(float64(len(l.waiters)), l.name)
+			return true
+		}
+	}
+	return false
+}
+
+// decrease 는 조절 중이면 한도를 10% 줄인다. l.mu 를 잡고 부른다.
+func (l *Limiter) decrease() {
+	if l.cfg.Target <= 0 {
+		return
+	}
+	l.limit = math.Max(l.limit*0.9, float64(l.cfg.Min))
+	limits.Set(l.limit, l.name)
+}
+
+// Shedder 는 전역 Limiter 와 라우트 패턴별 Limiter 를 거는 미들웨어다. 전역 미들웨어로 등록하면
+// 라우트 매칭 뒤에 실행되므로 router.Pattern 으로 라우트를 고른다.
+type Shedder struct {
+	// Global 이 nil 이면 전역 제한이 없다.
+	Global *Limiter
+	// Routes 는 "METHOD 패턴" 또는 "패턴" 별 Limiter 다. (예: "POST /api/export", "/api/reports/{id}")
+	Routes map[string]*Limiter
+	// Exempt 는 제한하지 않는 경로 접두사다. (예: /healthz, /metrics)
+	Exempt []string
+}
+
+// Middleware 는 자리를 얻은 요청만 처리하고 나머지는 503 과 Retry-After 로 거절한다.
+// 라우트 제한을 먼저 얻고 전역 제한을 얻으므로, 느린 라우트 하나가 전역 자리를 모두 잡고 기다리지 않는다.
+func (s *Shedder) Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, p := range s.Exempt {
+				if strings.HasPrefix(r.URL.Path, p) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			pattern := router.Pattern(r)
+			for _, l := range []*Limiter{s.route(r.Method, pattern), s.Global} {
+				if l == nil {
+					continue
+				}
+				release, err := l.Acquire(r.Context())
+				if err != nil {
+					reject(w, r, l, err)
+					return
+				}
+				defer release()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (s *Shedder) route(method, pattern string) *Limiter {
+	if pattern == "" {
+		return nil
+	}
+	if l, ok := s.Routes[method+" "+pattern]; ok {
+		return l
+	}
+	return s.Routes[pattern]
+}
+
+func reject(w http.ResponseWriter, r *http.Request, l *Limiter, err error) {
+	reason := "timeout"
+	switch {
+	case errors.Is(err, ErrQueueFull):
+		reason = "queue_full"
+	case r.Context().Err() != nil:
+		// 클라이언트가 먼저 끊었다. 응답은 읽히지 않는다.
+		shedTotal.Inc(l.name, "canceled")
+		return
+	}
+	shedTotal.Inc(l.name, reason)
+	secs := int(math.Ceil(l.cfg.QueueTimeout.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	api.WriteError(w, api.NewError(http.StatusServiceUnavailable, "overloaded", "server is busy, try again later"))
+}
@@ -0,0 +1,161 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/lifecycle"
+	"github.com/hgsong234/_stack/Golang/logging"
+)
+
+// ShutdownRetry 는 종료할 때 보내는 마지막 이벤트의 retry 값이다. 클라이언트는 그만큼 기다렸다가
+// 다른 인스턴스(또는 재시작한 서버)로 다시 연결한다.
+var ShutdownRetry = 3 * time.Second
+
+// Broker 는 이벤트를 구독자들에게 전달하고 최근 이벤트를 보관해
+// 재연결한 클라이언트가 놓친 이벤트를 Last-Event-ID 이후부터 다시 받을 수 있게 한다.
+type Broker struct {
+	mu      sync.Mutex
+	history []Event
+	size    int
+	nextID  uint64
+	subs    map[chan Event]struct{}
+	streams int  // Handler 로 열려 있는 스트림 수
+	closing bool // Shutdown 을 시작했다
+}
+
+// NewBroker 는 최근 history 개의 이벤트를 보관하는 Broker 를 만든다.
+func NewBroker(history int) *Broker {
+	return &Broker{size: history, subs: map[chan Event]struct{}{}}
+}
+
+// Publish 는 이벤트에 순번 ID 를 붙여 모든 구독자에게 보낸다.
+// 느린 구독자의 버퍼가 가득 차면 그 구독자에게는 이벤트를 건너뛴다. (재연결 시 다시 받을 수 있다)
+func (b *Broker) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	e.ID = strconv.FormatUint(b.nextID, 10)
+	if b.size > 0 {
+		b.history = append(b.history, e)
+		if len(b.history) > b.size {
+			b.history = b.history[len(b.history)-b.size:]
+		}
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe 는 새 구독을 만든다. lastID 가 있으면 그 이후의 보관된 이벤트를 먼저 보낸다.
+// Shutdown 을 시작한 뒤에는 닫힌 채널을 돌려준다.
+func (b *Broker) Subscribe(lastID string) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closing {
+		ch := make(chan Event)
+		close(ch)
+		return ch, func() {}
+	}
+	var missed []Event
+	if n, err := strconv.ParseUint(lastID, 10, 64); err == nil {
+		for _, e := range b.history {
+			if id, _ := strconv.ParseUint(e.ID, 10, 64); id > n {
+				missed = append(missed, e)
+			}
+		}
+	}
+	ch := make(chan Event, len(missed)+16)
+	for _, e := range missed {
+		ch <- e
+	}
+	b.subs[ch] = struct{}{}
+	cancel := func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+	return ch, cancel
+}
+
+// Handler 는 브로커를 구독하는 SSE 엔드포인트다. Shutdown 을 시작한 뒤의 새 연결에는 503 을 돌려준다.
+func (b *Broker) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b.mu.Lock()
+		closing := b.closing
+		if !closing {
+			b.streams++
+		}
+		b.mu.Unlock()
+		if closing {
+			api.WriteError(w, api.NewError(http.StatusServiceUnavailable, "shutting_down", "server is shutting down"))
+			return
+		}
+		defer func() {
+			b.mu.Lock()
+			b.streams--
+			b.mu.Unlock()
+		}()
+		ch, cancel := b.Subscribe(LastEventID(r))
+		defer cancel()
+		Stream(w, r, ch)
+	}
+}
+
+// Len 은 열려 있는 구독 수다.
+func (b *Broker) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// RegisterLifecycle 은 lc 의 드레인 훅으로 Shutdown 을 등록한다.
+func (b *Broker) RegisterLifecycle(lc *lifecycle.Manager) {
+	lc.OnDrain("sse", 0, b.Shutdown)
+}
+
+// Shutdown 은 새 구독을 거부하고, 구독자마다 shutdown 이벤트(retry: ShutdownRetry)를 마지막으로 보낸 뒤
+// 채널을 닫는다. Handler 로 연 스트림이 모두 끝날 때까지(또는 ctx 가 끝날 때까지) 기다린다.
+// 마지막 이벤트에는 ID 가 없으므로 다시 연결한 클라이언트는 놓친 이벤트를 그대로 받는다.
+func (b *Broker) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	b.closing = true
+	if n := len(b.subs); n > 0 {
+		logging.Default().Info("sse: closing streams", "subscribers", n)
+	}
+	final := Event{Event: "shutdown", Data: "server shutting down", Retry: ShutdownRetry}
+	for ch := range b.subs {
+		// 버퍼가 가득 찬 느린 구독자는 마지막 이벤트 없이 닫는다.
+		select {
+		case ch <- final:
+		default:
+		}
+		close(ch)
+		delete(b.subs, ch)
+	}
+	b.mu.Unlock()
+
+	t := time.NewTicker(50 * time.Millisecond)
+	defer t.Stop()
+	for {
+		b.mu.Lock()
+		n := b.streams
+		b.mu.Unlock()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			logging.Default().Warn("sse: streams did not finish in time", "streams", n)
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
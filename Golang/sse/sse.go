@@ -0,0 +1,99 @@
+// Package sse 는 Server-Sent Events 스트리밍 헬퍼와 재연결(Last-Event-ID)을 지원하는 브로커를 제공한다.
+package sse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Heartbeat 는 연결이 프록시에서 끊기지 않도록 주석 줄을 보내는 간격이다.
+var Heartbeat = 15 * time.Second
+
+// Event 는 스트림으로 보내는 이벤트 하나다.
+type Event struct {
+	ID    string
+	Event string // 비어 있으면 클라이언트에서 "message" 이벤트가 된다.
+	Data  string
+	// Retry 는 클라이언트의 재연결 대기 시간이다. 0 이면 보내지 않는다.
+	Retry time.Duration
+}
+
+// WriteTo 는 이벤트를 text/event-stream 형식으로 쓴다.
+func (e Event) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", oneLine(e.ID))
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", oneLine(e.Event))
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", e.Retry.Milliseconds())
+	}
+	// 여러 줄 데이터는 줄마다 data: 필드로 나눈다.
+	for _, line := range strings.Split(e.Data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", strings.TrimSuffix(line, "\r"))
+	}
+	b.WriteByte('\n')
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func oneLine(s string) string {
+	return strings.NewReplacer("\n", "", "\r", "").Replace(s)
+}
+
+// LastEventID 는 재연결한 클라이언트가 마지막으로 받은 이벤트 ID 다.
+// 헤더가 없으면 lastEventId 쿼리 파라미터를 사용한다. (EventSource 폴리필 호환)
+func LastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("lastEventId")
+}
+
+// ErrNoFlush 는 응답을 중간에 내보낼 수 없는 ResponseWriter 일 때의 에러다.
+var ErrNoFlush = errors.New("sse: response writer does not support flushing")
+
+// Stream 은 events 채널의 이벤트를 클라이언트가 연결을 끊거나 채널이 닫힐 때까지 보낸다.
+func Stream(w http.ResponseWriter, r *http.Request, events <-chan Event) error {
+	rc := http.NewResponseController(w)
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no") // nginx 버퍼링 끄기
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return ErrNoFlush
+	}
+	// 장시간 연결이므로 서버 WriteTimeout 을 적용하지 않는다.
+	rc.SetWriteDeadline(time.Time{})
+
+	hb := time.NewTicker(Heartbeat)
+	defer hb.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if _, err := e.WriteTo(w); err != nil {
+				return err
+			}
+		case <-hb.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return err
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package static
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// ImmutableMaxAge 는 지문이 붙은 파일의 Cache-Control max-age(1년)다. 내용이 바뀌면 이름도 바뀌므로
+// 브라우저가 재검증하지 않게 immutable 을 붙인다.
+const ImmutableMaxAge = 365 * 24 * 60 * 60
+
+// AssetOptions 는 Assets 설정이다.
+type AssetOptions struct {
+	// Prefix 는 자산 URL 의 경로 접두사다. (예: "/static/")
+	Prefix string
+	// Options 는 지문 없는 이름으로 요청한 파일에 쓰는 캐시 설정이다.
+	Options Options
+	// Reload 가 true 이면 Path 를 부를 때마다 파일을 다시 해시한다. (개발 모드)
+	Reload bool
+}
+
+// Assets 는 fsys 의 파일 이름에 내용 해시(지문)를 붙여 서비스한다. "css/app.css" 는
+// "css/app.3a7bd3e2360a.css" 가 되고, 지문이 붙은 이름은 오래 캐시된다. 템플릿에서는
+// asset 함수({{asset "css/app.css"}})로 현재 이름을 얻는다.
+//
+// CSS 안의 url() 같은 자산끼리의 참조는 바꾸지 않는다. 그런 참조는 지문 없는 이름으로 요청되어
+// Options 의 캐시 설정을 따른다.
+type Assets struct {
+	fsys      fs.FS
+	opts      AssetOptions
+	plain     http.Handler
+	immutable http.Handler
+
+	mu     sync.RWMutex
+	hashed map[string]string // 원래 이름 → 지문 이름
+	files  map[string]string // 지문 이름 → 원래 이름
+}
+
+// NewAssets 는 fsys 의 모든 파일을 해시한 Assets 를 만든다.
+func NewAssets(fsys fs.FS, opts AssetOptions) (*Assets, error) {
+	if opts.Prefix == "" {
+		opts.Prefix = "/"
+	}
+	a := &Assets{
+		fsys:      fsys,
+		opts:      opts,
+		plain:     Handler(fsys, opts.Options),
+		immutable: newHandler(fsys, Options{}, fmt.Sprintf("public, max-age=%d, immutable", ImmutableMaxAge)),
+		hashed:    map[string]string{},
+		files:     map[string]string{},
+	}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		_, err = a.fingerprint(name)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("static: %w", err)
+	}
+	return a, nil
+}
+
+// fingerprint 는 name 을 해시해 지문 이름을 기록하고 돌려준다.
+func (a *Assets) fingerprint(name string) (string, error) {
+	b, err := fs.ReadFile(a.fsys, name)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	ext := path.Ext(name)
+	hashed := strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:6]) + ext
+	a.mu.Lock()
+	if old, ok := a.hashed[name]; ok && old != hashed {
+		delete(a.files, old)
+	}
+	a.hashed[name], a.files[hashed] = hashed, name
+	a.mu.Unlock()
+	return hashed, nil
+}
+
+// Path 는 name 의 지문이 붙은 URL 경로다. 모르는 파일이면 지문 없이 돌려준다.
+func (a *Assets) Path(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if a.opts.Reload {
+		if hashed, err := a.fingerprint(name); err == nil {
+			return a.opts.Prefix + hashed
+		}
+	}
+	a.mu.RLock()
+	hashed, ok := a.hashed[name]
+	a.mu.RUnlock()
+	if !ok {
+		return a.opts.Prefix + name
+	}
+	return a.opts.Prefix + hashed
+}
+
+// FuncMap 은 템플릿 함수 asset 이다.
+func (a *Assets) FuncMap() template.FuncMap {
+	return template.FuncMap{"asset": a.Path}
+}
+
+// ServeHTTP 는 지문이 붙은 이름을 원래 파일로 바꾸어 immutable 캐시 헤더와 함께 서비스한다.
+// 지문 없는 이름은 Options 의 캐시 설정으로 서비스한다. 요청 경로는 Prefix 가 제거되어 있어야 한다.
+func (a *Assets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	a.mu.RLock()
+	orig, ok := a.files[name]
+	a.mu.RUnlock()
+	if !ok {
+		a.plain.ServeHTTP(w, r)
+		return
+	}
+	r2 := new(http.Request)
+	*r2 = *r
+	u := *r.URL
+	u.Path = "/" + orig
+	r2.URL = &u
+	a.immutable.ServeHTTP(w, r2)
+}
@@ -0,0 +1,120 @@
+// Package static 은 디렉터리나 embed.FS 의 정적 파일을 캐시 헤더와 함께 서비스하고, 파일 이름에
+// 내용 해시를 붙여 배포마다 캐시를 무효화한다. (Assets)
+package static
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options 는 정적 파일 핸들러 설정이다.
+type Options struct {
+	// MaxAge 는 Cache-Control max-age 값이다. 0 이면 "no-cache" 로 매번 재검증한다.
+	MaxAge time.Duration
+	// Index 가 있으면 디렉터리 경로("/", "/docs/")에 이 파일(예: "index.html")을 서비스한다.
+	Index string
+}
+
+// Handler 는 fsys 의 파일을 서비스한다. 요청 경로는 접두사가 이미 제거되어 있어야 한다.
+// (예: http.StripPrefix("/static/", static.Handler(...)))
+// 디렉터리 목록은 노출하지 않는다.
+func Handler(fsys fs.FS, opts Options) http.Handler {
+	cc := "no-cache"
+	if opts.MaxAge > 0 {
+		cc = fmt.Sprintf("public, max-age=%d", int(opts.MaxAge.Seconds()))
+	}
+	return newHandler(fsys, opts, cc)
+}
+
+func newHandler(fsys fs.FS, opts Options, cacheControl string) *handler {
+	return &handler{fsys: fsys, opts: opts, cacheControl: cacheControl, etags: map[string]etagEntry{}}
+}
+
+type handler struct {
+	fsys         fs.FS
+	opts         Options
+	cacheControl string
+
+	mu    sync.Mutex
+	etags map[string]etagEntry
+}
+
+// etagEntry 는 내용 해시로 만든 ETag 캐시 항목이다.
+type etagEntry struct {
+	size    int64
+	modTime time.Time
+	etag    string
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if h.opts.Index != "" && (name == "" || strings.HasSuffix(r.URL.Path, "/")) {
+		name = path.Join(name, h.opts.Index)
+	}
+	if name == "" {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		http.Error(w, "file is not seekable", http.StatusInternalServerError)
+		return
+	}
+
+	etag, err := h.etag(name, fi, rs)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", h.cacheControl)
+	// ServeContent 가 확장자로 Content-Type 을 정하고 If-None-Match / If-Modified-Since / Range 를 처리한다.
+	// embed.FS 처럼 수정 시각이 없으면 Last-Modified 는 생략된다.
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), rs)
+}
+
+// etag 는 파일 내용의 SHA-256 으로 강한 ETag 를 만든다. 크기와 수정 시각이 같으면 캐시를 재사용한다.
+func (h *handler) etag(name string, fi fs.FileInfo, rs io.ReadSeeker) (string, error) {
+	h.mu.Lock()
+	e, ok := h.etags[name]
+	h.mu.Unlock()
+	if ok && e.size == fi.Size() && e.modTime.Equal(fi.ModTime()) {
+		return e.etag, nil
+	}
+	sum := sha256.New()
+	if _, err := io.Copy(sum, rs); err != nil {
+		return "", err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum.Sum(nil)[:16]) + `"`
+	h.mu.Lock()
+	h.etags[name] = etagEntry{size: fi.Size(), modTime: fi.ModTime(), etag: etag}
+	h.mu.Unlock()
+	return etag, nil
+}
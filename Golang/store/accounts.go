@@ -0,0 +1,296 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrConflict 는 같은 이메일로 로그인 정보가 있는 계정이 이미 있다는 뜻이다.
+var ErrConflict = errors.New("store: already exists")
+
+// Account 는 비밀번호로 로그인하는 사용자다. users 테이블에서 password_hash 가 있는 행이다.
+type Account struct {
+	User
+	PasswordHash string
+	VerifiedAt   *time.Time
+	FailedLogins int
+	LockedUntil  *time.Time
+	// TOTPSecret 이 있으면 2단계 인증을 켠 것이다. TOTPLastStep 은 마지막으로 받은 코드의 시간 단계다.
+	TOTPSecret   string
+	TOTPLastStep int64
+}
+
+// Verified 는 이메일을 확인했는지 돌려준다.
+func (a *Account) Verified() bool { return a.VerifiedAt != nil }
+
+// TwoFactor 는 2단계 인증을 켰는지 돌려준다.
+func (a *Account) TwoFactor() bool { return a.TOTPSecret != "" }
+
+// Locked 는 now 에 계정이 잠겨 있는지 돌려준다.
+func (a *Account) Locked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
+
+// 토큰 용도
+const (
+	TokenVerify = "verify"
+	TokenReset  = "reset"
+)
+
+// Accounts 는 로그인 정보와 인증 토큰(auth_tokens) 저장소다.
+type Accounts struct {
+	db DB
+}
+
+// NewAccounts 는 db 를 사용하는 Accounts 저장소를 만든다.
+func NewAccounts(db DB) *Accounts {
+	return &Accounts{db: db}
+}
+
+const accountColumns = `id, name, email, created_at, password_hash, email_verified_at, failed_logins, locked_until, totp_secret, totp_last_step`
+
+func scanAccount(row interface{ Scan(...any) error }) (*Account, error) {
+	var (
+		a                Account
+		verified, locked sql.NullTime
+	)
+	if err := row.Scan(&a.ID, &a.Name, &a.Email, &a.CreatedAt, &a.PasswordHash, &verified, &a.FailedLogins, &locked, &a.TOTPSecret, &a.TOTPLastStep); err != nil {
+		return nil, err
+	}
+	if verified.Valid {
+		a.VerifiedAt = &verified.Time
+	}
+	if locked.Valid {
+		a.LockedUntil = &locked.Time
+	}
+	return &a, nil
+}
+
+// Create 는 계정을 추가하고 a.ID 와 a.CreatedAt 을 채운다. 같은 이메일의 계정이 있으면 ErrConflict 다.
+func (s *Accounts) Create(ctx context.Context, a *Account) error {
+	if _, err := s.ByEmail(ctx, a.Email); err == nil {
+		return ErrConflict
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	}
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(
+		`INSERT INTO users (name, email, created_at, password_hash) VALUES (?, ?, ?, ?) RETURNING id`),
+		a.Name, a.Email, a.CreatedAt, a.PasswordHash,
+	).Scan(&a.ID)
+	if err != nil {
+		// 동시에 가입한 경우 유니크 인덱스가 막는다.
+		if _, lookupErr := s.ByEmail(ctx, a.Email); lookupErr == nil {
+			return ErrConflict
+		}
+		return fmt.Errorf("store: create account: %w", err)
+	}
+	return nil
+}
+
+// ByEmail 은 이메일(대소문자 무시)로 계정을 찾는다. 없으면 ErrNotFound 다.
+func (s *Accounts) ByEmail(ctx context.Context, email string) (*Account, error) {
+	a, err := scanAccount(s.db.QueryRowContext(ctx, s.db.Rebind(
+		`SELECT `+accountColumns+` FROM users WHERE lower(email) = ? AND password_hash <> ''`), strings.ToLower(email)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get account: %w", err)
+	}
+	return a, nil
+}
+
+// Get 은 id 로 계정을 찾는다. 없으면 ErrNotFound 다.
+func (s *Accounts) Get(ctx context.Context, id int64) (*Account, error) {
+	a, err := scanAccount(s.db.QueryRowContext(ctx, s.db.Rebind(
+		`SELECT `+accountColumns+` FROM users WHERE id = ? AND password_hash <> ''`), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get account %d: %w", id, err)
+	}
+	return a, nil
+}
+
+// LoginFailed 는 실패 횟수를 늘리고, lockAfter 번째 실패이면 until 까지 잠그고 횟수를 0 으로 되돌린다.
+// 잠갔으면 true 다.
+func (s *Accounts) LoginFailed(ctx context.Context, id int64, lockAfter int, until time.Time) (bool, error) {
+	var failed int
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(
+		`UPDATE users SET failed_logins = failed_logins + 1 WHERE id = ? RETURNING failed_logins`), id).Scan(&failed)
+	if err != nil {
+		return false, fmt.Errorf("store: record login failure %d: %w", id, err)
+	}
+	if lockAfter <= 0 || failed < lockAfter {
+		return false, nil
+	}
+	_, err = s.db.ExecContext(ctx, s.db.Rebind(
+		`UPDATE users SET failed_logins = 0, locked_until = ? WHERE id = ?`), until.UTC(), id)
+	if err != nil {
+		return false, fmt.Errorf("store: lock account %d: %w", id, err)
+	}
+	return true, nil
+}
+
+// LoginSucceeded 는 실패 횟수와 잠금을 지운다.
+func (s *Accounts) LoginSucceeded(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(
+		`UPDATE users SET failed_logins = 0, locked_until = NULL WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("store: reset login failures %d: %w", id, err)
+	}
+	return nil
+}
+
+// SetPassword 는 비밀번호 해시를 바꾸고 잠금을 푼다.
+func (s *Accounts) SetPassword(ctx context.Context, id int64, hash string) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(
+		`UPDATE users SET password_hash = ?, failed_logins = 0, locked_until = NULL WHERE id = ?`), hash, id)
+	if err != nil {
+		return fmt.Errorf("store: set password %d: %w", id, err)
+	}
+	return nil
+}
+
+// MarkVerified 는 이메일 확인 시각을 기록한다. 이미 확인했으면 그대로 둔다.
+func (s *Accounts) MarkVerified(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(
+		`UPDATE users SET email_verified_at = ? WHERE id = ? AND email_verified_at IS NULL`), time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("store: verify account %d: %w", id, err)
+	}
+	return nil
+}
+
+// CreateToken 은 user 의 purpose 용도 토큰 해시를 expires 까지 저장한다.
+func (s *Accounts) CreateToken(ctx context.Context, hash string, user int64, purpose string, expires time.Time) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(
+		`INSERT INTO auth_tokens (hash, user_id, purpose, expires_at, created_at) VALUES (?, ?, ?, ?, ?)`),
+		hash, user, purpose, expires.UTC(), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("store: create %s token: %w", purpose, err)
+	}
+	return nil
+}
+
+// ConsumeToken 은 만료되지 않은 토큰을 지우고 사용자 ID 를 돌려준다. 한 번만 쓸 수 있으며 없으면 ErrNotFound 다.
+// 성공하면 그 사용자의 같은 용도 토큰도 모두 지운다.
+func (s *Accounts) ConsumeToken(ctx context.Context, hash, purpose string) (int64, error) {
+	var user int64
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(
+		`DELETE FROM auth_tokens WHERE hash = ? AND purpose = ? AND expires_at > ? RETURNING user_id`),
+		hash, purpose, time.Now().UTC()).Scan(&user)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("store: consume %s token: %w", purpose, err)
+	}
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind(
+		`DELETE FROM auth_tokens WHERE user_id = ? AND purpose = ?`), user, purpose); err != nil {
+		return 0, fmt.Errorf("store: consume %s token: %w", purpose, err)
+	}
+	return user, nil
+}
+
+// DeleteExpiredTokens 는 만료된 토큰을 지우고 지운 수를 돌려준다.
+func (s *Accounts) DeleteExpiredTokens(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM auth_tokens WHERE expires_at <= ?`), time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("store: delete expired tokens: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// EnableTOTP 는 2단계 인증을 켜고 백업 코드 해시를 codes 로 바꾼다. step 은 등록할 때 확인한 코드의 시간 단계다.
+func (s *Accounts) EnableTOTP(ctx context.Context, id int64, secret string, step int64, codes []string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, s.db.Rebind(
+			`UPDATE users SET totp_secret = ?, totp_last_step = ? WHERE id = ?`), secret, step, id); err != nil {
+			return err
+		}
+		return s.replaceCodes(ctx, tx, id, codes)
+	})
+}
+
+// DisableTOTP 는 2단계 인증을 끄고 백업 코드를 지운다.
+func (s *Accounts) DisableTOTP(ctx context.Context, id int64) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, s.db.Rebind(
+			`UPDATE users SET totp_secret = '', totp_last_step = 0 WHERE id = ?`), id); err != nil {
+			return err
+		}
+		return s.replaceCodes(ctx, tx, id, nil)
+	})
+}
+
+// ReplaceBackupCodes 는 백업 코드 해시를 codes 로 바꾼다. 예전 코드는 더 쓸 수 없다.
+func (s *Accounts) ReplaceBackupCodes(ctx context.Context, id int64, codes []string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error { return s.replaceCodes(ctx, tx, id, codes) })
+}
+
+func (s *Accounts) replaceCodes(ctx context.Context, tx *sql.Tx, id int64, codes []string) error {
+	if _, err := tx.ExecContext(ctx, s.db.Rebind(`DELETE FROM totp_backup_codes WHERE user_id = ?`), id); err != nil {
+		return err
+	}
+	for _, h := range codes {
+		if _, err := tx.ExecContext(ctx, s.db.Rebind(
+			`INSERT INTO totp_backup_codes (user_id, hash) VALUES (?, ?)`), id, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Accounts) inTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: begin: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("store: update two-factor settings: %w", err)
+	}
+	return tx.Commit()
+}
+
+// UseTOTPStep 은 step 단계의 코드를 받았다고 기록한다. 이미 그 단계 이후의 코드를 받았으면
+// 같은 코드를 다시 쓰는 것이므로 false 다.
+func (s *Accounts) UseTOTPStep(ctx context.Context, id int64, step int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, s.db.Rebind(
+		`UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?`), step, id, step)
+	if err != nil {
+		return false, fmt.Errorf("store: record totp step %d: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// UseBackupCode 는 백업 코드를 지운다. 없는(이미 쓴) 코드이면 false 다.
+func (s *Accounts) UseBackupCode(ctx context.Context, id int64, hash string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, s.db.Rebind(
+		`DELETE FROM totp_backup_codes WHERE user_id = ? AND hash = ?`), id, hash)
+	if err != nil {
+		return false, fmt.Errorf("store: use backup code %d: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// BackupCodesLeft 는 남은 백업 코드 수다.
+func (s *Accounts) BackupCodesLeft(ctx context.Context, id int64) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(`SELECT count(*) FROM totp_backup_codes WHERE user_id = ?`), id).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("store: count backup codes %d: %w", id, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// APIKey 는 api_keys 테이블의 행이다. 키 원문은 저장하지 않고 해시만 보관한다.
+type APIKey struct {
+	ID     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Prefix string   `json:"prefix"`
+	Hash   string   `json:"-"`
+	Scopes []string `json:"scopes"`
+	// RateLimit 은 초당 허용 요청 수다. 0 이면 키별 제한이 없다.
+	RateLimit float64 `json:"rate_limit"`
+	Burst     int     `json:"burst"`
+	// DailyQuota 와 MonthlyQuota 는 설정의 기본 할당량을 재정의한다. nil 이면 기본값, 0 이면 제한이 없다.
+	DailyQuota   *int64     `json:"daily_quota"`
+	MonthlyQuota *int64     `json:"monthly_quota"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Revoked 는 키가 폐기되었는지 확인한다.
+func (k *APIKey) Revoked() bool { return k.RevokedAt != nil }
+
+// APIKeys 는 api_keys 테이블 저장소다.
+type APIKeys struct {
+	db DB
+}
+
+// NewAPIKeys 는 db 를 사용하는 APIKeys 저장소를 만든다.
+func NewAPIKeys(db DB) *APIKeys {
+	return &APIKeys{db: db}
+}
+
+const apiKeyColumns = `id, name, prefix, hash, scopes, rate_limit, burst, daily_quota, monthly_quota, created_at, revoked_at`
+
+func scanAPIKey(row interface{ Scan(...any) error }) (*APIKey, error) {
+	var (
+		k              APIKey
+		scopes         string
+		daily, monthly sql.NullInt64
+		revoked        sql.NullTime
+	)
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Hash, &scopes, &k.RateLimit, &k.Burst, &daily, &monthly, &k.CreatedAt, &revoked); err != nil {
+		return nil, err
+	}
+	k.Scopes = strings.Fields(scopes)
+	if daily.Valid {
+		k.DailyQuota = &daily.Int64
+	}
+	if monthly.Valid {
+		k.MonthlyQuota = &monthly.Int64
+	}
+	if revoked.Valid {
+		k.RevokedAt = &revoked.Time
+	}
+	return &k, nil
+}
+
+// Create 는 키를 추가하고 k.ID 와 k.CreatedAt 을 채운다.
+func (s *APIKeys) Create(ctx context.Context, k *APIKey) error {
+	if k.CreatedAt.IsZero() {
+		k.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	}
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(
+		`INSERT INTO api_keys (name, prefix, hash, scopes, rate_limit, burst, daily_quota, monthly_quota, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		k.Name, k.Prefix, k.Hash, strings.Join(k.Scopes, " "), k.RateLimit, k.Burst, k.DailyQuota, k.MonthlyQuota, k.CreatedAt,
+	).Scan(&k.ID)
+	if err != nil {
+		return fmt.Errorf("store: create api key: %w", err)
+	}
+	return nil
+}
+
+// Get 은 id 로 키를 찾는다. 없으면 ErrNotFound 다.
+func (s *APIKeys) Get(ctx context.Context, id int64) (*APIKey, error) {
+	k, err := scanAPIKey(s.db.QueryRowContext(ctx,
+		s.db.Rebind(`SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get api key %d: %w", id, err)
+	}
+	return k, nil
+}
+
+// ByPrefix 는 키 접두사로 키를 찾는다. 없으면 ErrNotFound 다.
+func (s *APIKeys) ByPrefix(ctx context.Context, prefix string) (*APIKey, error) {
+	k, err := scanAPIKey(s.db.QueryRowContext(ctx,
+		s.db.Rebind(`SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix = ?`), prefix))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get api key: %w", err)
+	}
+	return k, nil
+}
+
+// List 는 모든 키를 id 순서로 돌려준다.
+func (s *APIKeys) List(ctx context.Context) ([]APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("store: list api keys: %w", err)
+	}
+	defer rows.Close()
+	keys := []APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: list api keys: %w", err)
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke 는 키를 폐기한다. 없거나 이미 폐기된 키이면 ErrNotFound 다.
+func (s *APIKeys) Revoke(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx,
+		s.db.Rebind(`UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`),
+		time.Now().UTC().Truncate(time.Microsecond), id)
+	if err != nil {
+		return fmt.Errorf("store: revoke api key %d: %w", id, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetQuotas 는 키의 할당량 재정의를 바꾼다. nil 은 기본값으로 되돌린다. 없으면 ErrNotFound 다.
+func (s *APIKeys) SetQuotas(ctx context.Context, id int64, daily, monthly *int64) error {
+	res, err := s.db.ExecContext(ctx,
+		s.db.Rebind(`UPDATE api_keys SET daily_quota = ?, monthly_quota = ? WHERE id = ?`), daily, monthly, id)
+	if err != nil {
+		return fmt.Errorf("store: set api key %d quotas: %w", id, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package store
+
+import (
+	_ "github.com/jackc/pgx/v5/stdlib" // "pgx" 드라이버
+)
+
+func init() {
+	dialects["postgres"] = dialect{
+		sqlName:  "pgx",
+		dsn:      func(dsn string) string { return dsn },
+		numbered: true,
+	}
+}
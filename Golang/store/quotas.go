@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// QuotaUsage 는 quota_usage 테이블 저장소다. 주체(subject)의 기간별 사용량을 센다.
+type QuotaUsage struct {
+	db DB
+}
+
+// NewQuotaUsage 는 db 를 사용하는 QuotaUsage 저장소를 만든다.
+func NewQuotaUsage(db DB) *QuotaUsage {
+	return &QuotaUsage{db: db}
+}
+
+// Add 는 subject 의 (period, start) 사용량에 n 을 더하고 더한 뒤의 값을 돌려준다. 행이 없으면 만든다.
+func (s *QuotaUsage) Add(ctx context.Context, subject, period string, start time.Time, n int64) (int64, error) {
+	var used int64
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(
+		`INSERT INTO quota_usage (subject, period, window_start, used) VALUES (?, ?, ?, ?)
+		 ON CONFLICT (subject, period, window_start) DO UPDATE SET used = quota_usage.used + excluded.used
+		 RETURNING used`),
+		subject, period, start.UTC(), n,
+	).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("store: add quota usage: %w", err)
+	}
+	return used, nil
+}
+
+// Used 는 subject 의 (period, start) 사용량이다. 행이 없으면 0 이다.
+func (s *QuotaUsage) Used(ctx context.Context, subject, period string, start time.Time) (int64, error) {
+	var used int64
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(
+		`SELECT COALESCE(SUM(used), 0) FROM quota_usage WHERE subject = ? AND period = ? AND window_start = ?`),
+		subject, period, start.UTC(),
+	).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("store: get quota usage: %w", err)
+	}
+	return used, nil
+}
+
+// DeleteBefore 는 before 보다 먼저 시작한 기간의 사용량을 지우고 지운 행 수를 돌려준다.
+func (s *QuotaUsage) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM quota_usage WHERE window_start < ?`), before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("store: delete quota usage: %w", err)
+	}
+	return res.RowsAffected()
+}
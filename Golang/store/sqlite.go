@@ -0,0 +1,28 @@
+package store
+
+import (
+	"strings"
+
+	_ "modernc.org/sqlite" // "sqlite" 드라이버 (cgo 불필요)
+)
+
+func init() {
+	dialects["sqlite"] = dialect{
+		sqlName: "sqlite",
+		dsn:     sqliteDSN,
+		// SQLite 는 쓰기가 파일 단위로 잠기므로 연결 하나로 직렬화하는 편이 busy 에러를 줄인다.
+		maxOpen: 1,
+	}
+}
+
+// sqliteDSN 은 외래 키, WAL 저널, busy timeout 을 기본으로 켠다.
+func sqliteDSN(dsn string) string {
+	if strings.Contains(dsn, "_pragma=") {
+		return dsn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+}
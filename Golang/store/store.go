@@ -0,0 +1,130 @@
+// Package store 는 database/sql 위의 영속 계층이다.
+//
+// 드라이버(SQLite, Postgres)마다 다른 점(자리 표시자, 연결 옵션)은 DB 가 감추고,
+// 저장소(Users 등)는 DB 인터페이스만 사용한다.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/timing"
+)
+
+// ErrNotFound 는 찾는 행이 없을 때의 에러다.
+var ErrNotFound = errors.New("store: not found")
+
+// DB 는 저장소가 사용하는 데이터베이스 연결이다. *sql.DB 와 *sql.Tx 의 공통 부분에 드라이버 정보를 더했다.
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	PingContext(ctx context.Context) error
+	Close() error
+
+	// Driver 는 "sqlite" 또는 "postgres" 다.
+	Driver() string
+	// Rebind 는 "?" 자리 표시자를 드라이버 형식으로 바꾼다. (Postgres 는 "$1", "$2", ...)
+	Rebind(query string) string
+}
+
+// Config 는 연결과 커넥션 풀 설정이다.
+type Config struct {
+	// Driver 는 "sqlite" 또는 "postgres" 다.
+	Driver string
+	// DSN 은 SQLite 파일 경로 또는 Postgres 연결 문자열이다.
+	DSN string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// 드라이버별 구현 (sqlite.go, postgres.go 에서 등록한다)
+type dialect struct {
+	// sqlName 은 database/sql 에 등록된 드라이버 이름이다.
+	sqlName string
+	// dsn 은 설정의 DSN 에 드라이버별 기본 옵션을 더한다.
+	dsn func(string) string
+	// numbered 가 true 이면 "$n" 자리 표시자를 쓴다.
+	numbered bool
+	// maxOpen 은 MaxOpenConns 가 0 일 때의 기본값이다.
+	maxOpen int
+}
+
+var dialects = map[string]dialect{}
+
+// Open 은 cfg 로 데이터베이스에 연결하고 Ping 으로 확인한다.
+func Open(ctx context.Context, cfg Config) (DB, error) {
+	d, ok := dialects[cfg.Driver]
+	if !ok {
+		return nil, fmt.Errorf("store: unknown driver %q", cfg.Driver)
+	}
+	db, err := sql.Open(d.sqlName, d.dsn(cfg.DSN))
+	if err != nil {
+		return nil, fmt.Errorf("store: %w", err)
+	}
+	if cfg.MaxOpenConns == 0 {
+		cfg.MaxOpenConns = d.maxOpen
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("store: connect %s: %w", cfg.Driver, err)
+	}
+	return &sqlDB{DB: db, driver: cfg.Driver, d: d}, nil
+}
+
+// sqlDB 는 *sql.DB 에 드라이버 정보를 붙인 DB 구현이다.
+type sqlDB struct {
+	*sql.DB
+	driver string
+	d      dialect
+}
+
+func (db *sqlDB) Driver() string { return db.driver }
+
+// ExecContext, QueryContext, QueryRowContext 는 요청의 "db" 단계 시간을 잰다. 결과 행을 읽는 시간은 들어가지 않는다.
+
+func (db *sqlDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	defer timing.Start(ctx, "db")()
+	return db.DB.ExecContext(ctx, query, args...)
+}
+
+func (db *sqlDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	defer timing.Start(ctx, "db")()
+	return db.DB.QueryContext(ctx, query, args...)
+}
+
+func (db *sqlDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	defer timing.Start(ctx, "db")()
+	return db.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (db *sqlDB) Rebind(query string) string {
+	if !db.d.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(query); i++ {
+		if query[i] == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(query[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,195 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/listquery"
+)
+
+// User 는 users 테이블의 행이다.
+type User struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name" validate:"required,max=100"`
+	Email     string    `json:"email,omitempty" validate:"omitempty,email,max=254"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Users 는 users 테이블 저장소다.
+type Users struct {
+	db DB
+}
+
+// NewUsers 는 db 를 사용하는 Users 저장소를 만든다.
+func NewUsers(db DB) *Users {
+	return &Users{db: db}
+}
+
+// Create 는 사용자를 추가하고 u.ID 와 u.CreatedAt 을 채운다.
+func (s *Users) Create(ctx context.Context, u *User) error {
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	}
+	err := s.db.QueryRowContext(ctx,
+		s.db.Rebind(`INSERT INTO users (name, email, created_at) VALUES (?, ?, ?) RETURNING id`),
+		u.Name, u.Email, u.CreatedAt,
+	).Scan(&u.ID)
+	if err != nil {
+		return fmt.Errorf("store: create user: %w", err)
+	}
+	return nil
+}
+
+// Get 은 id 로 사용자를 찾는다. 없으면 ErrNotFound 다.
+func (s *Users) Get(ctx context.Context, id int64) (*User, error) {
+	var u User
+	err := s.db.QueryRowContext(ctx,
+		s.db.Rebind(`SELECT id, name, email, created_at FROM users WHERE id = ?`), id,
+	).Scan(&u.ID, &u.Name, &u.Email, &u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get user %d: %w", id, err)
+	}
+	return &u, nil
+}
+
+// GetMany 는 ids 중 있는 사용자를 id 순서로 돌려준다. 한 번의 쿼리로 찾으므로 여러 사용자를 묶어 조회할 때 쓴다.
+func (s *Users) GetMany(ctx context.Context, ids []int64) ([]User, error) {
+	users := []User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	marks := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	rows, err := s.db.QueryContext(ctx,
+		s.db.Rebind(`SELECT id, name, email, created_at FROM users WHERE id IN (`+marks+`) ORDER BY id`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("store: get users: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.CreatedAt); err != nil {
+			return nil, fmt.Errorf("store: get users: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// List 는 id 순서로 사용자를 최대 limit 명 돌려준다.
+func (s *Users) List(ctx context.Context, limit, offset int) ([]User, error) {
+	rows, err := s.db.QueryContext(ctx,
+		s.db.Rebind(`SELECT id, name, email, created_at FROM users ORDER BY id LIMIT ? OFFSET ?`), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("store: list users: %w", err)
+	}
+	defer rows.Close()
+	users := []User{}
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.CreatedAt); err != nil {
+			return nil, fmt.Errorf("store: list users: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// Find 는 q 의 필터, 정렬, 페이지대로 사용자를 돌려준다.
+func (s *Users) Find(ctx context.Context, q *listquery.Query) ([]User, error) {
+	where, args := q.Where()
+	query := `SELECT id, name, email, created_at FROM users` + where + q.OrderBy() + ` LIMIT ?`
+	args = append(args, q.Limit)
+	if q.After == nil {
+		query += ` OFFSET ?`
+		args = append(args, q.Offset)
+	}
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("store: find users: %w", err)
+	}
+	defer rows.Close()
+	users := []User{}
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.CreatedAt); err != nil {
+			return nil, fmt.Errorf("store: find users: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// Count 는 q 의 필터에 맞는 사용자 수다.
+func (s *Users) Count(ctx context.Context, q *listquery.Query) (int, error) {
+	where, args := q.CountWhere()
+	var n int
+	if err := s.db.QueryRowContext(ctx, s.db.Rebind(`SELECT COUNT(*) FROM users`+where), args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("store: count users: %w", err)
+	}
+	return n, nil
+}
+
+// Update 는 id 사용자의 이름과 이메일을 바꾸고 u 를 저장된 값으로 채운다.
+func (s *Users) Update(ctx context.Context, id int64, u *User) error {
+	err := s.db.QueryRowContext(ctx,
+		s.db.Rebind(`UPDATE users SET name = ?, email = ? WHERE id = ? RETURNING created_at`),
+		u.Name, u.Email, id,
+	).Scan(&u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("store: update user %d: %w", id, err)
+	}
+	u.ID = id
+	return nil
+}
+
+// Restore 는 백업의 사용자를 id 와 생성 시각 그대로 넣는다. 같은 id 가 있으면 덮어쓴다.
+func (s *Users) Restore(ctx context.Context, u *User) error {
+	_, err := s.db.ExecContext(ctx,
+		s.db.Rebind(`INSERT INTO users (id, name, email, created_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET name = excluded.name, email = excluded.email, created_at = excluded.created_at`),
+		u.ID, u.Name, u.Email, u.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("store: restore user %d: %w", u.ID, err)
+	}
+	return nil
+}
+
+// SyncIDs 는 Restore 로 id 를 직접 넣은 뒤 다음 Create 가 같은 id 를 받지 않도록 Postgres 의 IDENTITY 를
+// 가장 큰 id 뒤로 옮긴다. SQLite 는 rowid 가 알아서 따라오므로 할 일이 없다.
+func (s *Users) SyncIDs(ctx context.Context) error {
+	if s.db.Driver() != "postgres" {
+		return nil
+	}
+	_, err := s.db.ExecContext(ctx, `SELECT setval(pg_get_serial_sequence('users', 'id'), GREATEST((SELECT MAX(id) FROM users), 1))`)
+	if err != nil {
+		return fmt.Errorf("store: sync user ids: %w", err)
+	}
+	return nil
+}
+
+// Delete 는 id 사용자를 삭제한다. 없으면 ErrNotFound 다.
+func (s *Users) Delete(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM users WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("store: delete user %d: %w", id, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
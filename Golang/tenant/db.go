@@ -0,0 +1,64 @@
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/hgsong234/_stack/Golang/store"
+)
+
+// DB 는 쿼리를 컨텍스트의 테넌트 데이터베이스로 보내는 store.DB 다. store.Users 같은 저장소를 DB 로
+// 만들면 핸들러를 고치지 않고 테넌트별 데이터에 접근한다. 따로 데이터베이스가 없는 테넌트와 테넌트가
+// 없는 요청은 기본 데이터베이스를 쓴다. 모든 데이터베이스는 같은 드라이버여야 한다.
+type DB struct {
+	store.DB
+	tenants map[string]store.DB
+}
+
+// NewDB 는 기본 데이터베이스 def 와 테넌트 ID 별 데이터베이스로 DB 를 만든다.
+func NewDB(def store.DB, tenants map[string]store.DB) *DB {
+	return &DB{DB: def, tenants: tenants}
+}
+
+// For 는 ctx 의 테넌트가 쓰는 데이터베이스다.
+func (d *DB) For(ctx context.Context) store.DB {
+	if db, ok := d.tenants[ID(ctx)]; ok {
+		return db
+	}
+	return d.DB
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return d.For(ctx).ExecContext(ctx, query, args...)
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return d.For(ctx).QueryContext(ctx, query, args...)
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return d.For(ctx).QueryRowContext(ctx, query, args...)
+}
+
+func (d *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return d.For(ctx).BeginTx(ctx, opts)
+}
+
+// PingContext 는 기본 데이터베이스와 모든 테넌트 데이터베이스를 확인한다.
+func (d *DB) PingContext(ctx context.Context) error {
+	err := d.DB.PingContext(ctx)
+	for _, db := range d.tenants {
+		err = errors.Join(err, db.PingContext(ctx))
+	}
+	return err
+}
+
+// Close 는 모든 데이터베이스를 닫는다.
+func (d *DB) Close() error {
+	err := d.DB.Close()
+	for _, db := range d.tenants {
+		err = errors.Join(err, db.Close())
+	}
+	return err
+}
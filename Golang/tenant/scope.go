@@ -0,0 +1,104 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/ratelimit"
+	"github.com/hgsong234/_stack/Golang/respcache"
+	"github.com/hgsong234/_stack/Golang/session"
+)
+
+// Key 는 key 앞에 컨텍스트의 테넌트 ID 를 붙인다. ("acme:" + key) 테넌트가 없으면 key 그대로다.
+func Key(ctx context.Context, key string) string {
+	if id := ID(ctx); id != "" {
+		return id + ":" + key
+	}
+	return key
+}
+
+// Sessions 는 세션 ID 를 테넌트별로 나누는 session.Store 다. 다른 테넌트에서 만든 세션 쿠키는
+// 같은 ID 라도 찾지 못하므로 로그인이 테넌트를 넘지 않는다.
+func Sessions(s session.Store) session.Store { return sessionStore{s} }
+
+type sessionStore struct{ session.Store }
+
+func (s sessionStore) Load(ctx context.Context, id string) ([]byte, bool, error) {
+	return s.Store.Load(ctx, Key(ctx, id))
+}
+
+func (s sessionStore) Save(ctx context.Context, id string, data []byte, expiry time.Time) error {
+	return s.Store.Save(ctx, Key(ctx, id), data, expiry)
+}
+
+func (s sessionStore) Delete(ctx context.Context, id string) error {
+	return s.Store.Delete(ctx, Key(ctx, id))
+}
+
+// Cleanup 은 감싼 저장소가 Cleanup 을 지원하면 그대로 부른다.
+func (s sessionStore) Cleanup() int {
+	if c, ok := s.Store.(interface{ Cleanup() int }); ok {
+		return c.Cleanup()
+	}
+	return 0
+}
+
+// List 는 컨텍스트에 테넌트가 있으면 그 테넌트의 세션만, 없으면(관리 리스너) 모든 세션을 돌려준다.
+func (s sessionStore) List(ctx context.Context) ([]session.Info, error) {
+	l, ok := s.Store.(session.Lister)
+	if !ok {
+		return nil, session.ErrNotListable
+	}
+	all, err := l.List(ctx)
+	if err != nil || ID(ctx) == "" {
+		return all, err
+	}
+	prefix := Key(ctx, "")
+	out := all[:0]
+	for _, info := range all {
+		if id, ok := strings.CutPrefix(info.ID, prefix); ok {
+			info.ID = id
+			out = append(out, info)
+		}
+	}
+	return out, nil
+}
+
+// RateLimits 는 요청 제한 버킷을 테넌트별로 나누는 ratelimit.Backend 다. 한 테넌트의 트래픽이 같은
+// IP 나 API 키를 쓰는 다른 테넌트의 한도를 쓰지 않는다.
+func RateLimits(b ratelimit.Backend) ratelimit.Backend { return rateBackend{b} }
+
+type rateBackend struct{ ratelimit.Backend }
+
+func (b rateBackend) Take(ctx context.Context, key string, rate float64, burst int) (ratelimit.Result, error) {
+	return b.Backend.Take(ctx, Key(ctx, key), rate, burst)
+}
+
+// Cache 는 응답 캐시 항목을 테넌트별로 나누는 respcache.Store 다. 캐시 키가 경로로 시작해야 Purge 가
+// 경로 접두사로 지울 수 있으므로 테넌트 ID 는 키 뒤에 붙이고, Purge 는 모든 테넌트의 항목을 지운다.
+func Cache(s respcache.Store) respcache.Store { return cacheStore{s} }
+
+type cacheStore struct{ respcache.Store }
+
+func cacheKey(ctx context.Context, key string) string {
+	if id := ID(ctx); id != "" {
+		return key + " @" + id
+	}
+	return key
+}
+
+// CoalesceKey 는 요청 합치기(coalesce) 키 뒤에 테넌트 ID 를 붙인다. 같은 URL 이라도 테넌트마다 DB 가 다르므로
+// 다른 테넌트의 동시 요청이 한 응답을 나눠 받으면 안 된다.
+func CoalesceKey(key func(*http.Request) string) func(*http.Request) string {
+	return func(r *http.Request) string { return cacheKey(r.Context(), key(r)) }
+}
+
+func (s cacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return s.Store.Get(ctx, cacheKey(ctx, key))
+}
+
+func (s cacheStore) Set(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	return s.Store.Set(ctx, cacheKey(ctx, key), data, ttl)
+}
@@ -0,0 +1,243 @@
+// Package tenant 는 요청마다 테넌트를 찾아 컨텍스트에 넣는다.
+//
+// 테넌트는 하위 도메인(acme.example.com) 또는 테넌트에 등록한 호스트, 헤더(X-Tenant-ID), 경로 접두사
+// (/t/acme/...)로 찾는다. 경로로 찾은 경우 접두사를 떼고 라우터로 넘기므로 Middleware 는 라우터 바깥에 건다.
+//
+// 세션, 요청 제한, 응답 캐시는 scope.go 의 래퍼로 테넌트별로 나누고, 데이터는 DB 로 테넌트의 스키마나
+// 데이터베이스에 보낸다.
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Tenant 는 테넌트 하나의 설정이다.
+type Tenant struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Hosts 는 하위 도메인 외에 이 테넌트로 볼 호스트 이름이다. (예: 사용자 도메인 "shop.acme.com")
+	Hosts []string `json:"hosts,omitempty"`
+	// Schema 와 DSN 은 테넌트의 데이터가 있는 곳이다. 둘 다 비어 있으면 기본 데이터베이스를 같이 쓴다.
+	Schema string `json:"-"`
+	DSN    string `json:"-"`
+	// Settings 는 핸들러와 템플릿이 읽는 테넌트별 값이다.
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+// Setting 은 Settings[key] 다. t 가 nil 이면 빈 문자열이다.
+func (t *Tenant) Setting(key string) string {
+	if t == nil {
+		return ""
+	}
+	return t.Settings[key]
+}
+
+// 테넌트를 찾는 방법
+const (
+	SourceSubdomain = "subdomain"
+	SourceHeader    = "header"
+	SourcePath      = "path"
+)
+
+// Config 는 테넌트 목록과 찾는 방법이다.
+type Config struct {
+	// Sources 는 찾는 방법과 순서다. 기본값은 subdomain 하나다.
+	// header 는 클라이언트가 마음대로 보낼 수 있으므로 헤더를 덮어쓰는 게이트웨이 뒤에서만 쓴다.
+	Sources []string
+	// Domain 은 subdomain 의 기준 도메인이다. 비어 있으면 Tenant.Hosts 만 본다.
+	Domain string
+	// Header 의 기본값은 X-Tenant-ID, PathPrefix 의 기본값은 /t/ 다.
+	Header     string
+	PathPrefix string
+	// Default 는 어느 방법으로도 찾지 못했을 때의 테넌트다. 비어 있으면 테넌트 없이 처리한다.
+	Default string
+	// Required 이면 테넌트가 없는 요청을 404 로 거절한다.
+	Required bool
+	Tenants  []Tenant
+}
+
+// Registry 는 파싱된 Config 다.
+type Registry struct {
+	cfg    Config
+	byID   map[string]*Tenant
+	byHost map[string]*Tenant
+}
+
+// New 는 cfg 를 검사해 Registry 를 만든다.
+func New(cfg Config) (*Registry, error) {
+	if len(cfg.Sources) == 0 {
+		cfg.Sources = []string{SourceSubdomain}
+	}
+	if cfg.Header == "" {
+		cfg.Header = "X-Tenant-ID"
+	}
+	if cfg.PathPrefix == "" {
+		cfg.PathPrefix = "/t/"
+	}
+	cfg.PathPrefix = "/" + strings.Trim(cfg.PathPrefix, "/") + "/"
+	cfg.Domain = strings.ToLower(strings.Trim(cfg.Domain, "."))
+	for _, s := range cfg.Sources {
+		if s != SourceSubdomain && s != SourceHeader && s != SourcePath {
+			return nil, fmt.Errorf("tenant: unknown source %q", s)
+		}
+	}
+	g := &Registry{cfg: cfg, byID: map[string]*Tenant{}, byHost: map[string]*Tenant{}}
+	for i := range cfg.Tenants {
+		t := &cfg.Tenants[i]
+		if !ValidID(t.ID) {
+			return nil, fmt.Errorf("tenant: invalid id %q", t.ID)
+		}
+		if g.byID[t.ID] != nil {
+			return nil, fmt.Errorf("tenant: duplicate id %q", t.ID)
+		}
+		g.byID[t.ID] = t
+		for _, h := range t.Hosts {
+			h = strings.ToLower(h)
+			if other := g.byHost[h]; other != nil {
+				return nil, fmt.Errorf("tenant: host %q belongs to %q and %q", h, other.ID, t.ID)
+			}
+			g.byHost[h] = t
+		}
+	}
+	if cfg.Default != "" && g.byID[cfg.Default] == nil {
+		return nil, fmt.Errorf("tenant: default %q is not a tenant", cfg.Default)
+	}
+	return g, nil
+}
+
+// ValidID 는 id 가 하위 도메인과 키 접두사로 쓸 수 있는지(소문자, 숫자, '-', 63자 이하) 확인한다.
+func ValidID(id string) bool {
+	if id == "" || len(id) > 63 || id[0] == '-' || id[len(id)-1] == '-' {
+		return false
+	}
+	return strings.Trim(id, "abcdefghijklmnopqrstuvwxyz0123456789-") == ""
+}
+
+// Get 은 id 의 테넌트다. 없으면 nil 이다.
+func (g *Registry) Get(id string) *Tenant { return g.byID[id] }
+
+// List 는 모든 테넌트를 ID 순서로 돌려준다.
+func (g *Registry) List() []*Tenant {
+	out := make([]*Tenant, 0, len(g.byID))
+	for _, t := range g.byID {
+		out = append(out, t)
+	}
+	slices.SortFunc(out, func(a, b *Tenant) int { return strings.Compare(a.ID, b.ID) })
+	return out
+}
+
+// Resolve 는 r 의 테넌트 ID 와, 경로 접두사로 찾았으면 접두사를 뗀 경로를 돌려준다.
+// 찾지 못하면 id 가 비어 있다. 찾은 ID 가 등록된 테넌트인지는 확인하지 않는다.
+func (g *Registry) Resolve(r *http.Request) (id, path string) {
+	for _, s := range g.cfg.Sources {
+		switch s {
+		case SourceSubdomain:
+			host := strings.ToLower(r.Host)
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if t := g.byHost[host]; t != nil {
+				return t.ID, ""
+			}
+			if g.cfg.Domain != "" {
+				if sub, ok := strings.CutSuffix(host, "."+g.cfg.Domain); ok && !strings.Contains(sub, ".") {
+					return sub, ""
+				}
+			}
+		case SourceHeader:
+			if v := strings.TrimSpace(r.Header.Get(g.cfg.Header)); v != "" {
+				return strings.ToLower(v), ""
+			}
+		case SourcePath:
+			if rest, ok := strings.CutPrefix(r.URL.Path, g.cfg.PathPrefix); ok {
+				id, tail, _ := strings.Cut(rest, "/")
+				return id, "/" + tail
+			}
+		}
+	}
+	return "", ""
+}
+
+type ctxKey struct{}
+
+// WithTenant 는 t 를 담은 컨텍스트다. 백그라운드 작업이 테넌트의 데이터에 접근할 때 쓴다.
+func WithTenant(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, ctxKey{}, t)
+}
+
+// From 은 컨텍스트의 테넌트다. 없으면 nil 이다.
+func From(ctx context.Context) *Tenant {
+	t, _ := ctx.Value(ctxKey{}).(*Tenant)
+	return t
+}
+
+// ID 는 컨텍스트의 테넌트 ID 다. 없으면 빈 문자열이다.
+func ID(ctx context.Context) string {
+	if t := From(ctx); t != nil {
+		return t.ID
+	}
+	return ""
+}
+
+// Middleware 는 테넌트를 찾아 컨텍스트와 요청 로거(tenant)에 넣는다. 등록되지 않은 테넌트와, Required 일 때
+// 테넌트가 없는 요청은 404 다. 경로로 찾았으면 접두사를 뗀 경로로 다음 핸들러를 부른다.
+func (g *Registry) Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, path := g.Resolve(r)
+			if id == "" {
+				id = g.cfg.Default
+			}
+			t := g.byID[id]
+			switch {
+			case t == nil && id != "":
+				api.WriteError(w, api.NewError(http.StatusNotFound, "unknown_tenant", "unknown tenant"))
+				return
+			case t == nil && g.cfg.Required:
+				api.WriteError(w, api.NewError(http.StatusNotFound, "tenant_required", "tenant is required"))
+				return
+			case t == nil:
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx := WithTenant(r.Context(), t)
+			ctx = logging.WithContext(ctx, logging.From(ctx).With("tenant", t.ID))
+			r = r.WithContext(ctx)
+			if path != "" {
+				r = stripPath(r, path)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// stripPath 는 경로를 path 로 바꾼 요청의 사본이다.
+func stripPath(r *http.Request, path string) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	u := *r.URL
+	u.Path, u.RawPath = path, ""
+	r2.URL = &u
+	return r2
+}
+
+// Handler 는 현재 테넌트의 ID, 이름, 설정을 JSON 으로 보여 준다. 테넌트가 없으면 404 다.
+func Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t := From(r.Context())
+		if t == nil {
+			api.WriteError(w, api.NotFound("no tenant for this request"))
+			return
+		}
+		api.WriteJSON(w, http.StatusOK, t)
+	}
+}
@@ -0,0 +1,219 @@
+// Package timing 은 요청 하나의 단계별(인증, DB, 렌더링 등) 처리 시간을 모은다.
+//
+// Middleware 가 요청마다 Timings 를 컨텍스트에 넣고, 각 단계는 Start 로 시간을 잰다. 모은 시간은
+// Server-Timing 응답 헤더(브라우저 개발자 도구의 Timing 탭)와 접근 로그에 실린다.
+// 같은 이름의 단계는 합치고 횟수를 센다. 단계는 겹칠 수 있다. (인증 중의 DB 조회 등)
+// 헤더를 쓰는 시점에 아직 끝나지 않은 단계(응답을 쓰는 렌더링 등)는 그때까지의 시간으로 싣는다.
+package timing
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Header 는 응답 헤더 이름이다.
+const Header = "Server-Timing"
+
+// Metric 은 한 단계의 누적 시간과 횟수다.
+type Metric struct {
+	Name  string
+	Dur   time.Duration
+	Count int
+}
+
+// Timings 는 요청 하나의 단계별 시간이다. 여러 고루틴에서 써도 된다.
+type Timings struct {
+	start   time.Time
+	mu      sync.Mutex
+	metrics []Metric
+	open    map[int]phase
+	nextID  int
+}
+
+// phase 는 Start 로 시작해 아직 끝나지 않은 단계다.
+type phase struct {
+	name  string
+	begin time.Time
+}
+
+// New 는 지금 시작한 요청의 Timings 를 만든다.
+func New() *Timings { return &Timings{start: time.Now()} }
+
+type ctxKey struct{}
+
+// WithContext 는 t 를 담은 컨텍스트를 돌려준다.
+func WithContext(ctx context.Context, t *Timings) context.Context {
+	return context.WithValue(ctx, ctxKey{}, t)
+}
+
+// From 은 컨텍스트의 Timings 다. 없으면 nil 이고, nil 에 기록하면 무시된다.
+func From(ctx context.Context) *Timings {
+	t, _ := ctx.Value(ctxKey{}).(*Timings)
+	return t
+}
+
+// Start 는 name 단계의 시간을 재기 시작하고, 끝낼 때 부를 함수를 돌려준다.
+//
+//	defer timing.Start(r.Context(), "render")()
+func Start(ctx context.Context, name string) func() {
+	t := From(ctx)
+	if t == nil {
+		return func() {}
+	}
+	begin := time.Now()
+	t.mu.Lock()
+	id := t.nextID
+	t.nextID++
+	if t.open == nil {
+		t.open = map[int]phase{}
+	}
+	t.open[id] = phase{name, begin}
+	t.mu.Unlock()
+	return func() {
+		t.mu.Lock()
+		_, ok := t.open[id]
+		delete(t.open, id)
+		t.mu.Unlock()
+		if ok {
+			t.Add(name, time.Since(begin))
+		}
+	}
+}
+
+// Add 는 name 단계에 d 를 더한다.
+func (t *Timings) Add(name string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.metrics = add(t.metrics, name, d)
+	t.mu.Unlock()
+}
+
+func add(ms []Metric, name string, d time.Duration) []Metric {
+	for i := range ms {
+		if ms[i].Name == name {
+			ms[i].Dur += d
+			ms[i].Count++
+			return ms
+		}
+	}
+	return append(ms, Metric{Name: name, Dur: d, Count: 1})
+}
+
+// Metrics 는 처음 기록된 순서대로의 단계 목록이다. 끝나지 않은 단계는 지금까지의 시간으로 들어간다.
+func (t *Timings) Metrics() []Metric {
+	if t == nil {
+		return nil
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ms := append([]Metric(nil), t.metrics...)
+	// 같은 이름의 열린 단계가 여럿이어도 순서가 일정하도록 시작한 순서로 더한다.
+	ids := make([]int, 0, len(t.open))
+	for id := range t.open {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		p := t.open[id]
+		ms = add(ms, p.name, now.Sub(p.begin))
+	}
+	return ms
+}
+
+// Elapsed 는 요청이 시작된 뒤 지난 시간이다.
+func (t *Timings) Elapsed() time.Duration { return time.Since(t.start) }
+
+// Millis 는 단계별 시간(밀리초)이다. 로그 필드로 쓴다.
+func (t *Timings) Millis() map[string]float64 {
+	ms := t.Metrics()
+	if len(ms) == 0 {
+		return nil
+	}
+	m := make(map[string]float64, len(ms))
+	for _, mt := range ms {
+		m[mt.Name] = millis(mt.Dur)
+	}
+	return m
+}
+
+// String 은 Server-Timing 헤더 값이다. 마지막의 app 은 지금까지 걸린 전체 시간이다.
+//
+//	auth;dur=0.41, db;dur=3.2;desc="4 calls", app;dur=5.1
+func (t *Timings) String() string {
+	var b strings.Builder
+	for _, m := range t.Metrics() {
+		b.WriteString(m.Name)
+		b.WriteString(";dur=")
+		b.WriteString(strconv.FormatFloat(millis(m.Dur), 'f', -1, 64))
+		if m.Count > 1 {
+			b.WriteString(`;desc="` + strconv.Itoa(m.Count) + ` calls"`)
+		}
+		b.WriteString(", ")
+	}
+	b.WriteString("app;dur=")
+	b.WriteString(strconv.FormatFloat(millis(t.Elapsed()), 'f', -1, 64))
+	return b.String()
+}
+
+func millis(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+
+// Middleware 는 요청마다 Timings 를 컨텍스트에 넣는다. header 가 true 이면 응답 헤더를 쓰기 직전까지
+// 모은 시간을 Server-Timing 헤더로 보낸다. 접근 로그가 시간을 싣도록 AccessLog 바깥에 등록한다.
+func Middleware(header bool) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t := New()
+			r = r.WithContext(WithContext(r.Context(), t))
+			if header {
+				w = &writer{ResponseWriter: w, t: t}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writer 는 처음 헤더를 쓸 때 Server-Timing 헤더를 붙인다.
+type writer struct {
+	http.ResponseWriter
+	t     *Timings
+	wrote bool
+}
+
+func (w *writer) setHeader() {
+	if !w.wrote {
+		w.wrote = true
+		w.Header().Set(Header, w.t.String())
+	}
+}
+
+func (w *writer) WriteHeader(code int) {
+	// 1xx 정보 응답 뒤에 오는 최종 응답에 붙인다.
+	if code >= 200 {
+		w.setHeader()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush 는 헤더를 붙인 뒤 내부 ResponseWriter 를 비운다.
+func (w *writer) Flush() {
+	w.setHeader()
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap 은 http.ResponseController 가 내부 ResponseWriter 에 접근하도록 한다.
+func (w *writer) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/hgsong234/_stack/Golang/middleware"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Middleware 는 요청마다 서버 span 을 만든다.
+// 요청 헤더의 trace context 를 부모로 이어받고, span 이름은 "메서드 라우트패턴" 이다.
+// 5xx 응답은 span 상태를 Error 로 표시한다.
+func Middleware() router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			route := router.Pattern(r)
+			name := r.Method
+			if route != "" {
+				name += " " + route
+			}
+			ctx, span := otel.Tracer(instrumentation).Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+					semconv.HTTPRoute(route),
+					semconv.ServerAddress(r.Host),
+					semconv.UserAgentOriginal(r.UserAgent()),
+				),
+			)
+			defer span.End()
+
+			sw := middleware.NewStatusWriter(w)
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			status := sw.Code()
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if id := middleware.RequestIDFrom(r.Context()); id != "" {
+				span.SetAttributes(attribute.String("http.request_id", id))
+			}
+			if status >= 500 {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
+
+// Transport 는 나가는 요청마다 클라이언트 span 을 만들고 trace context 헤더를 붙이는 RoundTripper 다.
+// 요청 컨텍스트에 서버 span 이 있으면 그 자식이 된다.
+type Transport struct {
+	// Base 는 실제 요청을 보낼 RoundTripper 다. nil 이면 http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+// RoundTrip 은 http.RoundTripper 구현이다.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ctx, span := otel.Tracer(instrumentation).Start(req.Context(), req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.ServerAddress(req.URL.Hostname()),
+			attribute.String("url.full", req.URL.Redacted()),
+		),
+	)
+	defer span.End()
+
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, strconv.Itoa(resp.StatusCode))
+	}
+	return resp, nil
+}
@@ -0,0 +1,90 @@
+// Package tracing 은 OpenTelemetry 분산 추적을 설정한다.
+//
+// Setup 으로 TracerProvider 와 W3C trace context·baggage 전파기를 전역으로 등록한 뒤,
+// 서버에는 Middleware, 하위 서비스 호출에는 Transport 를 사용한다.
+// 들어온 traceparent 헤더의 추적은 요청 span 의 부모가 되고, Transport 로 나가는 요청에 이어진다.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// instrumentation 은 이 패키지가 만드는 Tracer 의 이름이다.
+const instrumentation = "github.com/hgsong234/_stack/Golang/tracing"
+
+// DefaultOTLPEndpoint 는 OTLP/HTTP 수집기의 기본 주소다. Jaeger 도 이 포트로 OTLP 를 받는다.
+const DefaultOTLPEndpoint = "http://localhost:4318"
+
+// Config 는 추적 내보내기 설정이다.
+type Config struct {
+	// Exporter 는 "otlp", "jaeger", "stdout" 중 하나다. 비어 있으면 span 을 내보내지 않고 전파만 한다.
+	// "jaeger" 는 Jaeger 수집기의 OTLP 수신기로 보내는 "otlp" 와 같다.
+	Exporter string
+	// Endpoint 는 OTLP/HTTP 수집기 주소다. (예: "http://otel-collector:4318")
+	Endpoint string
+	// Headers 는 수집기에 보낼 추가 헤더다. (인증 토큰 등)
+	Headers map[string]string
+	// ServiceName 은 resource 의 service.name 이다.
+	ServiceName string
+	// ServiceVersion 은 resource 의 service.version 이다.
+	ServiceVersion string
+	// SampleRatio 는 새로 시작하는 추적 중 기록할 비율(0~1)이다. 부모가 있으면 부모의 결정을 따른다.
+	SampleRatio float64
+}
+
+// Setup 은 설정에 맞는 TracerProvider 와 전파기를 전역으로 등록한다.
+// 돌려준 shutdown 은 남은 span 을 내보내고 exporter 를 닫는다. 종료 훅에 등록한다.
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+	if cfg.Exporter == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var exp sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case "otlp", "jaeger":
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = DefaultOTLPEndpoint
+		}
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(endpoint)}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		exp, err = otlptracehttp.New(ctx, opts...)
+	case "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %s exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(cfg.ServiceVersion),
+	))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
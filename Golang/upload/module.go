@@ -0,0 +1,78 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"path/filepath"
+	"strings"
+
+	"github.com/hgsong234/_stack/Golang/jobs"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/middleware"
+	"github.com/hgsong234/_stack/Golang/module"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// 업로드 모듈은 upload.dir 이 있으면 POST /upload 를 열고, 저장한 파일마다 upload.saved 웹훅 이벤트를 보낸다.
+// upload.thumbnail_size 가 있으면 이미지의 썸네일을 작업 큐로 만든다.
+func init() {
+	module.Register("upload", func(env *module.Env) (module.Module, error) {
+		cfg := env.Config.Upload
+		if cfg.Dir == "" {
+			return nil, nil
+		}
+		m := &Module{dir: cfg.Dir, maxBytes: cfg.MaxBytes, thumbSize: cfg.ThumbnailSize, jobs: env.Jobs, publish: env.Publish}
+		if m.thumbSize > 0 && m.jobs != nil {
+			m.jobs.Register(thumbnailJob, m.thumbnail)
+		}
+		return m, nil
+	})
+}
+
+// thumbnailJob 은 썸네일 작업의 종류다.
+const thumbnailJob = "upload.thumbnail"
+
+// Module 은 업로드 모듈이다.
+type Module struct {
+	module.Base
+	dir       string
+	maxBytes  int64
+	thumbSize int
+	jobs      *jobs.Queue
+	publish   func(ctx context.Context, event string, data any)
+}
+
+func (m *Module) Name() string { return "upload" }
+
+func (m *Module) Routes(r *router.Router) {
+	r.POST("/upload", Handler(DirSink{Dir: m.dir}, m.saved), middleware.BodyLimit(m.maxBytes))
+}
+
+// saved 는 저장된 파일의 이벤트를 보내고 이미지라면 썸네일 작업을 넣는다.
+func (m *Module) saved(ctx context.Context, f File) {
+	if m.publish != nil {
+		m.publish(ctx, "upload.saved", f)
+	}
+	if m.thumbSize <= 0 || m.jobs == nil || !strings.HasPrefix(f.ContentType, "image/") {
+		return
+	}
+	if _, err := m.jobs.Enqueue(ctx, thumbnailJob, f); err != nil {
+		logging.From(ctx).Warn("thumbnail job not queued", "location", f.Location, "err", err)
+	}
+}
+
+// thumbnail 은 썸네일 작업 처리기다. 디코딩할 수 없는 이미지는 다시 시도하지 않는다.
+func (m *Module) thumbnail(ctx context.Context, payload json.RawMessage) error {
+	var f File
+	if err := json.Unmarshal(payload, &f); err != nil {
+		return jobs.Permanent(err)
+	}
+	name := filepath.Base(f.Location)
+	err := Thumbnail(filepath.Join(m.dir, name), filepath.Join(m.dir, "thumbs", name+".png"), m.thumbSize)
+	if errors.Is(err, image.ErrFormat) {
+		return jobs.Permanent(err)
+	}
+	return err
+}
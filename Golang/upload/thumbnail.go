@@ -0,0 +1,64 @@
+package upload
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif" // Thumbnail 이 읽을 수 있는 형식
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// Thumbnail 은 src 이미지(JPEG, PNG, GIF)를 size×size 안에 들어가도록 줄여 dst 에 PNG 로 저장한다.
+// 이미 작은 이미지는 크기를 바꾸지 않는다. 이미지가 아니면 image.ErrFormat 을 감싼 에러를 돌려준다.
+func Thumbnail(src, dst string, size int) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	img, _, err := image.Decode(in)
+	if err != nil {
+		return fmt.Errorf("upload: thumbnail %s: %w", filepath.Base(src), err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	// 같은 디렉터리의 임시 파일에 쓴 뒤 이름을 바꿔, 읽는 쪽이 반쯤 쓰인 파일을 보지 않게 한다.
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".thumb-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := png.Encode(tmp, scale(img, size)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// scale 은 가로세로 비율을 유지하며 긴 변이 size 가 되도록 최근접 이웃 방식으로 줄인다.
+func scale(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+	tw, th := size, h*size/w
+	if h > w {
+		tw, th = w*size/h, size
+	}
+	tw, th = max(tw, 1), max(th, 1)
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := range th {
+		sy := b.Min.Y + y*h/th
+		for x := range tw {
+			dst.Set(x, y, src.At(b.Min.X+x*w/tw, sy))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,161 @@
+// Package upload 는 multipart 파일 업로드를 메모리에 모으지 않고 스트리밍으로 저장하는 핸들러를 제공한다.
+package upload
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+)
+
+// Sink 는 업로드된 파일 내용을 받을 곳이다.
+type Sink interface {
+	// Create 는 파일 하나를 쓸 Writer 를 연다. location 은 응답에 포함될 저장 위치다.
+	Create(ctx context.Context, filename string) (w io.WriteCloser, location string, err error)
+	// Abort 는 업로드가 실패했을 때 쓰다 만 파일을 제거한다.
+	Abort(ctx context.Context, location string)
+}
+
+// DirSink 는 디렉터리에 임의 이름으로 파일을 저장한다.
+type DirSink struct{ Dir string }
+
+// Create 는 Sink 구현이다. 클라이언트가 보낸 파일 이름은 확장자만 사용한다.
+func (s DirSink) Create(_ context.Context, filename string) (io.WriteCloser, string, error) {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return nil, "", err
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	name := hex.EncodeToString(b) + strings.ToLower(filepath.Ext(filepath.Base(filename)))
+	f, err := os.OpenFile(filepath.Join(s.Dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, "", err
+	}
+	return f, name, nil
+}
+
+// Abort 는 Sink 구현이다.
+func (s DirSink) Abort(_ context.Context, location string) {
+	os.Remove(filepath.Join(s.Dir, filepath.Base(location)))
+}
+
+// WriterSink 는 모든 파일을 하나의 io.Writer 로 보낸다. (예: 외부 스토리지 스트림)
+type WriterSink struct{ W io.Writer }
+
+// Create 는 Sink 구현이다.
+func (s WriterSink) Create(_ context.Context, filename string) (io.WriteCloser, string, error) {
+	return nopCloser{s.W}, filename, nil
+}
+
+// Abort 는 Sink 구현이다. 이미 쓴 내용은 되돌릴 수 없다.
+func (WriterSink) Abort(context.Context, string) {}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// File 은 저장된 파일의 메타데이터다.
+type File struct {
+	Field       string `json:"field"`
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	ContentType string `json:"content_type,omitempty"`
+	Location    string `json:"location"`
+}
+
+// Handler 는 multipart/form-data 요청의 파일들을 차례로 sink 에 스트리밍하고 메타데이터를 JSON 으로 돌려준다.
+// 본문 크기 제한은 middleware.BodyLimit 로 라우트에 건다.
+// onSaved 는 요청의 모든 파일을 저장한 뒤 파일마다 호출된다. (예: 후처리 작업을 큐에 넣기)
+func Handler(sink Sink, onSaved ...func(context.Context, File)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mr, err := r.MultipartReader()
+		if err != nil {
+			api.WriteError(w, api.NewError(http.StatusUnsupportedMediaType, "unsupported_media_type",
+				"Content-Type must be multipart/form-data"))
+			return
+		}
+		files := []File{}
+		for {
+			part, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				writeReadError(w, r, err)
+				return
+			}
+			if part.FileName() == "" {
+				// 일반 폼 필드는 무시한다.
+				part.Close()
+				continue
+			}
+			f, err := save(r.Context(), sink, part.FormName(), part.FileName(), part.Header.Get("Content-Type"), part)
+			part.Close()
+			if err != nil {
+				writeReadError(w, r, err)
+				return
+			}
+			files = append(files, f)
+		}
+		if len(files) == 0 {
+			api.WriteError(w, api.BadRequest("no file parts in request"))
+			return
+		}
+		for _, f := range files {
+			for _, fn := range onSaved {
+				fn(r.Context(), f)
+			}
+		}
+		api.WriteJSON(w, http.StatusCreated, map[string]any{"files": files})
+	}
+}
+
+// save 는 파일 하나를 sink 로 복사하면서 크기와 SHA-256 을 계산한다.
+func save(ctx context.Context, sink Sink, field, name, ctype string, src io.Reader) (File, error) {
+	dst, loc, err := sink.Create(ctx, name)
+	if err != nil {
+		return File{}, err
+	}
+	sum := sha256.New()
+	n, err := io.Copy(io.MultiWriter(dst, sum), src)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		sink.Abort(ctx, loc)
+		return File{}, err
+	}
+	if mt, _, perr := mime.ParseMediaType(ctype); perr == nil {
+		ctype = mt
+	}
+	return File{
+		Field: field, Name: filepath.Base(name), Size: n,
+		SHA256: hex.EncodeToString(sum.Sum(nil)), ContentType: ctype, Location: loc,
+	}, nil
+}
+
+func writeReadError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		api.WriteError(w, api.NewError(http.StatusRequestEntityTooLarge, "body_too_large", "upload is too large"))
+		return
+	}
+	var apiErr *api.Error
+	if errors.As(err, &apiErr) {
+		api.WriteError(w, err)
+		return
+	}
+	logging.From(r.Context()).Warn("upload: malformed body", "err", err)
+	api.WriteError(w, api.BadRequest("malformed multipart body"))
+}
@@ -0,0 +1,4 @@
+package usersv1
+
+// users.proto 를 고치면 이 디렉터리에서 go generate 로 *.pb.go, *_grpc.pb.go, *.pb.gw.go 를 다시 만든다.
+//go:generate buf generate
@@ -0,0 +1,249 @@
+// Package validate 는 구조체 태그로 값을 검사한다.
+//
+//	type Req struct {
+//		Name  string `json:"name" validate:"required,max=64"`
+//		Email string `json:"email" validate:"omitempty,email"`
+//	}
+//
+// 규칙:
+//
+//	required    값이 0 이 아니어야 한다. 문자열은 공백만 있어도 비어 있는 것으로 본다.
+//	omitempty   값이 비어 있으면 나머지 규칙을 건너뛴다.
+//	min=N max=N 문자열·슬라이스·맵은 길이, 숫자는 값의 범위다.
+//	len=N       문자열·슬라이스·맵의 길이가 정확히 N 이다.
+//	email       이메일 주소 형식이다.
+//	url         http 또는 https 절대 URL 이다.
+//	oneof=a b   값이 나열된 것 중 하나다.
+//
+// 중첩 구조체와 구조체 슬라이스도 검사하며, 필드 이름은 json 태그를 따른다. (예: "items[0].name")
+// 포인터 필드가 nil(JSON null 이거나 없음)이면 required 만 검사한다.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError 는 필드 하나의 검사 실패다.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors 는 검사에 실패한 필드 목록이다.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, f := range e {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Struct 는 v(구조체 또는 구조체 포인터)를 검사한다. 실패하면 Errors 를 돌려준다.
+func Struct(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs Errors
+	walk(rv, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// walk 는 구조체의 필드를 차례로 검사한다.
+func walk(v reflect.Value, prefix string, errs *Errors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		name := fieldName(sf)
+		if name == "-" {
+			continue
+		}
+		if sf.Anonymous && sf.Tag.Get("json") == "" {
+			// 임베드된 구조체의 필드는 바깥 구조체의 필드로 취급한다.
+			dive(fv, strings.TrimSuffix(prefix, "."), errs)
+			continue
+		}
+		path := prefix + name
+		if tag := sf.Tag.Get("validate"); tag != "" && tag != "-" {
+			check(fv, path, tag, errs)
+		}
+		dive(fv, path, errs)
+	}
+}
+
+// dive 는 중첩 구조체와 구조체 슬라이스 안으로 들어간다.
+func dive(v reflect.Value, path string, errs *Errors) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if path != "" {
+			path += "."
+		}
+		walk(v, path, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			dive(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+// check 는 필드 하나에 태그의 규칙들을 적용한다. 첫 번째 실패한 규칙만 보고한다.
+func check(v reflect.Value, path, tag string, errs *Errors) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			break
+		}
+		v = v.Elem()
+	}
+	// 값이 없는(null) 포인터와 인터페이스는 required 만 검사한다.
+	missing := (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil()
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		if name == "omitempty" {
+			if empty(v) {
+				return
+			}
+			continue
+		}
+		if missing && name != "required" {
+			continue
+		}
+		if msg := apply(v, name, arg); msg != "" {
+			*errs = append(*errs, FieldError{Field: path, Rule: name, Message: msg})
+			return
+		}
+	}
+}
+
+// apply 는 규칙 하나를 검사하고 실패 메시지를 돌려준다. 통과하면 "" 이다.
+func apply(v reflect.Value, rule, arg string) string {
+	switch rule {
+	case "required":
+		if empty(v) {
+			return "is required"
+		}
+	case "min", "max", "len":
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validate: bad %s argument %q", rule, arg))
+		}
+		size, isLen := measure(v)
+		if ok := compare(rule, size, n); !ok {
+			return sizeMessage(rule, arg, isLen)
+		}
+	case "email":
+		s := v.String()
+		if a, err := mail.ParseAddress(s); err != nil || a.Address != s {
+			return "must be a valid email address"
+		}
+	case "url":
+		u, err := url.Parse(v.String())
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "must be an http or https URL"
+		}
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, opt := range strings.Fields(arg) {
+			if s == opt {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(strings.Fields(arg), ", ")
+	default:
+		panic("validate: unknown rule " + strconv.Quote(rule))
+	}
+	return ""
+}
+
+// measure 는 길이(문자열·컬렉션) 또는 숫자 값을 돌려준다.
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	}
+	return 0, false
+}
+
+func compare(rule string, size, n float64) bool {
+	switch rule {
+	case "min":
+		return size >= n
+	case "max":
+		return size <= n
+	}
+	return size == n
+}
+
+func sizeMessage(rule, arg string, isLen bool) string {
+	if isLen {
+		switch rule {
+		case "min":
+			return "must be at least " + arg + " characters or items long"
+		case "max":
+			return "must be at most " + arg + " characters or items long"
+		}
+		return "must be exactly " + arg + " characters or items long"
+	}
+	switch rule {
+	case "min":
+		return "must be at least " + arg
+	case "max":
+		return "must be at most " + arg
+	}
+	return "must be exactly " + arg
+}
+
+// empty 는 값이 비어 있는지 확인한다. 문자열은 공백만 있어도 비어 있다.
+func empty(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	if v.Kind() == reflect.String {
+		return strings.TrimSpace(v.String()) == ""
+	}
+	return v.IsZero()
+}
+
+// fieldName 은 json 태그의 이름을 돌려준다. 없으면 Go 필드 이름이다.
+func fieldName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" {
+		return sf.Name
+	}
+	return name
+}
@@ -1,32 +1,2605 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"embed"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
 	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"math"
 	"net/http"
-	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/hgsong234/_stack/Golang/acl"
+	"github.com/hgsong234/_stack/Golang/admin"
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/apikey"
+	"github.com/hgsong234/_stack/Golang/audit"
+	"github.com/hgsong234/_stack/Golang/auth"
+	"github.com/hgsong234/_stack/Golang/backup"
+	"github.com/hgsong234/_stack/Golang/bench"
+	"github.com/hgsong234/_stack/Golang/botguard"
+	"github.com/hgsong234/_stack/Golang/cache"
+	"github.com/hgsong234/_stack/Golang/canary"
+	"github.com/hgsong234/_stack/Golang/capture"
+	"github.com/hgsong234/_stack/Golang/chaos"
+	"github.com/hgsong234/_stack/Golang/coalesce"
+	"github.com/hgsong234/_stack/Golang/config"
+	"github.com/hgsong234/_stack/Golang/cookies"
+	"github.com/hgsong234/_stack/Golang/cors"
+	"github.com/hgsong234/_stack/Golang/cron"
+	"github.com/hgsong234/_stack/Golang/csrf"
+	"github.com/hgsong234/_stack/Golang/deadline"
+	"github.com/hgsong234/_stack/Golang/debug"
+	"github.com/hgsong234/_stack/Golang/devwatch"
+	"github.com/hgsong234/_stack/Golang/download"
+	"github.com/hgsong234/_stack/Golang/feed"
+	"github.com/hgsong234/_stack/Golang/flags"
+	"github.com/hgsong234/_stack/Golang/graphql"
+	"github.com/hgsong234/_stack/Golang/health"
+	"github.com/hgsong234/_stack/Golang/httpclient"
+	"github.com/hgsong234/_stack/Golang/i18n"
+	"github.com/hgsong234/_stack/Golang/idempotency"
+	"github.com/hgsong234/_stack/Golang/jobs"
+	"github.com/hgsong234/_stack/Golang/lifecycle"
+	"github.com/hgsong234/_stack/Golang/listquery"
+	"github.com/hgsong234/_stack/Golang/localauth"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/mailer"
+	"github.com/hgsong234/_stack/Golang/maintenance"
+	"github.com/hgsong234/_stack/Golang/markdown"
+	"github.com/hgsong234/_stack/Golang/metrics"
+	"github.com/hgsong234/_stack/Golang/middleware"
+	"github.com/hgsong234/_stack/Golang/migrate"
+	"github.com/hgsong234/_stack/Golang/mock"
+	"github.com/hgsong234/_stack/Golang/module"
+	"github.com/hgsong234/_stack/Golang/oauth"
+	"github.com/hgsong234/_stack/Golang/openapi"
+	"github.com/hgsong234/_stack/Golang/pages"
+	"github.com/hgsong234/_stack/Golang/proxy"
+	"github.com/hgsong234/_stack/Golang/quota"
+	"github.com/hgsong234/_stack/Golang/ratelimit"
+	"github.com/hgsong234/_stack/Golang/rbac"
+	"github.com/hgsong234/_stack/Golang/realip"
+	"github.com/hgsong234/_stack/Golang/reload"
+	"github.com/hgsong234/_stack/Golang/render"
+	"github.com/hgsong234/_stack/Golang/resource"
+	"github.com/hgsong234/_stack/Golang/respcache"
+	"github.com/hgsong234/_stack/Golang/router"
+	"github.com/hgsong234/_stack/Golang/rpc"
+	"github.com/hgsong234/_stack/Golang/search"
+	"github.com/hgsong234/_stack/Golang/server"
+	"github.com/hgsong234/_stack/Golang/session"
+	"github.com/hgsong234/_stack/Golang/shed"
+	"github.com/hgsong234/_stack/Golang/sse"
+	"github.com/hgsong234/_stack/Golang/static"
+	"github.com/hgsong234/_stack/Golang/store"
+	"github.com/hgsong234/_stack/Golang/tenant"
+	"github.com/hgsong234/_stack/Golang/timing"
+	"github.com/hgsong234/_stack/Golang/tracing"
+	_ "github.com/hgsong234/_stack/Golang/upload" // 업로드 모듈
+	"github.com/hgsong234/_stack/Golang/usersv1"
+	"github.com/hgsong234/_stack/Golang/webhook"
+	"github.com/hgsong234/_stack/Golang/wellknown"
+	"github.com/hgsong234/_stack/Golang/ws"
 )
 
+// 기본 템플릿 (templates.dir 설정이 없을 때 사용)
+//
+//go:embed templates
+var embeddedTemplates embed.FS
+
+//go:embed assets
+var embeddedAssets embed.FS
+
 // 루트 경로 ("/") 핸들러 함수
 func homeHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintf(w, "Welcome to the home page!")
+	defer timing.Start(r.Context(), "render")()
+	render.Render(w, "home.html", map[string]string{"Lang": i18n.Lang(r.Context())})
 }
 
-// "/hello" 경로 핸들러 함수
+// "/hello" 이름 입력값
+type helloForm struct {
+	Name string `form:"name" validate:"max=64"`
+}
+
+// "/hello", "/hello/{name}" 경로 핸들러 함수 (POST "/hello" 는 이름 입력 폼)
 func helloHandler(w http.ResponseWriter, r *http.Request) {
-	// URL 쿼리 파라미터에서 'name' 값을 가져온다.
-	name := r.URL.Query().Get("name")
+	// 경로 파라미터가 없으면 URL 쿼리 또는 폼의 'name' 값을 가져온다.
+	form := helloForm{Name: router.Param(r, "name")}
+	var err error
+	if form.Name == "" {
+		err = api.BindForm(r, &form)
+	} else {
+		err = api.Validate(&form)
+	}
+	var bad *api.Error
+	if err != nil && api.FieldErrors(err) == nil && errors.As(err, &bad) {
+		// 폼으로 읽을 수 없는 본문이다. (Content-Type, 크기)
+		http.Error(w, bad.Message, bad.Status)
+		return
+	}
+	if err != nil {
+		view := newHelloView(r, i18n.T(r.Context(), "guest"))
+		view.Error = i18n.T(r.Context(), "hello.name_too_long")
+		view.Errors = api.FieldErrors(err)
+		render.NegotiateStatus(w, r, http.StatusUnprocessableEntity, view)
+		return
+	}
+	name := form.Name
+	// 로그인한 사용자는 실제 이름으로 맞이한다.
+	// 그 외에는 이름을 받으면 세션에 기억하고, 없으면 세션에 저장된 이름을 사용한다.
+	if u := oauth.User(r); u != nil {
+		name = u.Name
+	} else if name != "" {
+		session.Set(r, "name", name)
+	} else {
+		name = session.GetString(r, "name")
+	}
 	if name == "" {
-		name = "Guest"
+		name = i18n.T(r.Context(), "guest")
+	}
+	render.Negotiate(w, r, newHelloView(r, name))
+}
+
+// helloView 는 "/hello" 응답이다. 브라우저에는 hello.html, API 클라이언트에는 JSON/XML/텍스트로 응답한다.
+type helloView struct {
+	XMLName xml.Name `json:"-" xml:"greeting"`
+	Name    string   `json:"name" xml:"name"`
+	Message string   `json:"message" xml:"message"`
+	Error   string   `json:"error,omitempty" xml:"error,omitempty"`
+	// Errors 는 폼 필드별 검사 메시지다.
+	Errors map[string]string `json:"errors,omitempty" xml:"-"`
+	CSRF   string            `json:"-" xml:"-"`
+	Lang   string            `json:"-" xml:"-"`
+}
+
+func newHelloView(r *http.Request, name string) helloView {
+	ctx := r.Context()
+	return helloView{Name: name, Message: i18n.T(ctx, "greeting", name), CSRF: csrf.Token(r), Lang: i18n.Lang(ctx)}
+}
+
+func (helloView) Template() string { return "hello.html" }
+func (v helloView) String() string { return v.Message }
+
+// errorHandler 는 404/405 응답 핸들러를 만든다.
+// /api/ 경로와 JSON 을 원하는 클라이언트에는 JSON 에러를, 그 외에는 error.html 페이지로 응답한다.
+func errorHandler(status int, code string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		msg := i18n.T(ctx, "error."+strconv.Itoa(status))
+		if strings.HasPrefix(r.URL.Path, "/api/") ||
+			render.PreferredType(r.Header.Get("Accept"), "text/html", "application/json") == "application/json" {
+			api.WriteError(w, api.NewError(status, code, http.StatusText(status)))
+			return
+		}
+		defer timing.Start(ctx, "render")()
+		render.Default().RenderStatus(w, status, "error.html", map[string]any{
+			"Status": status, "Title": http.StatusText(status), "Message": msg, "Lang": i18n.Lang(ctx),
+		})
+	}
+}
+
+// 인사말 JSON 응답
+type greeting struct {
+	ID      int64  `json:"id,omitempty"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+func newGreeting(ctx context.Context, id int64, name string) greeting {
+	key := "greeting"
+	if flags.Enabled(ctx, "new-greeting") {
+		key = "greeting.new"
+	}
+	return greeting{ID: id, Name: name, Message: i18n.T(ctx, key, name)}
+}
+
+// "/api/hello", "/api/hello/{name}" JSON 핸들러를 만든다.
+// users 가 있으면 "?id=" 로 저장된 사용자에게 인사한다.
+func helloAPIHandler(users *store.Users) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if id := r.URL.Query().Get("id"); id != "" && users != nil {
+			n, err := strconv.ParseInt(id, 10, 64)
+			if err != nil {
+				api.WriteError(w, api.BadRequest("id must be an integer"))
+				return
+			}
+			u, err := users.Get(r.Context(), n)
+			if errors.Is(err, store.ErrNotFound) {
+				api.WriteError(w, api.NotFound("user not found"))
+				return
+			}
+			if err != nil {
+				logging.From(r.Context()).Error("hello: store user", "err", err)
+				api.WriteError(w, err)
+				return
+			}
+			api.WriteJSON(w, http.StatusOK, newGreeting(r.Context(), u.ID, u.Name))
+			return
+		}
+		name := router.Param(r, "name")
+		if name == "" {
+			name = r.URL.Query().Get("name")
+		}
+		if name == "" {
+			name = i18n.T(r.Context(), "guest")
+		}
+		api.WriteJSON(w, http.StatusOK, newGreeting(r.Context(), 0, name))
+	}
+}
+
+// POST "/api/hello" 요청 본문
+type helloRequest struct {
+	Name  string `json:"name" validate:"required,max=64"`
+	Email string `json:"email,omitempty" validate:"omitempty,email"`
+}
+
+// POST "/api/hello" 핸들러를 만든다: {"name": "...", "email": "..."} 본문을 받는다.
+// users 가 있으면 사용자를 저장하고 201 과 함께 id 를 돌려준다.
+func helloAPIPostHandler(users *store.Users) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req helloRequest
+		if err := api.ReadJSON(r, &req); err != nil {
+			api.WriteError(w, err)
+			return
+		}
+		if users == nil {
+			api.WriteJSON(w, http.StatusOK, newGreeting(r.Context(), 0, req.Name))
+			return
+		}
+		u := &store.User{Name: req.Name, Email: req.Email}
+		if err := users.Create(r.Context(), u); err != nil {
+			logging.From(r.Context()).Error("hello: store user", "err", err)
+			api.WriteError(w, err)
+			return
+		}
+		api.WriteJSON(w, http.StatusCreated, newGreeting(r.Context(), u.ID, u.Name))
+	}
+}
+
+// newUserResource 는 /api/v1/users CRUD 리소스를 만든다. 본문 검사는 store.User 의 validate 태그를 따른다.
+func newUserResource(users *store.Users) *resource.Resource[store.User] {
+	return &resource.Resource[store.User]{
+		Name: "user",
+		Repo: users,
+		ID:   func(u *store.User) int64 { return u.ID },
+		Query: &listquery.Spec{
+			Key: "id",
+			Fields: map[string]listquery.Field{
+				"id":         {Type: listquery.Int, Filter: []string{"eq", "in", "gt", "lt"}, Sort: true},
+				"name":       {Filter: []string{"eq", "ne", "like", "in"}, Sort: true},
+				"email":      {Filter: []string{"eq", "like"}},
+				"created_at": {Type: listquery.Time, Filter: []string{"gte", "lt"}, Sort: true},
+			},
+			DefaultLimit: resource.DefaultLimit, MaxLimit: resource.MaxLimit,
+		},
+	}
+}
+
+// newGraphQLSchema 는 /graphql 의 사용자 스키마를 만든다. 뮤테이션은 REST 리소스처럼 users:write 권한이
+// 필요하고, 바뀐 사용자는 onChange 로 알린다. user(id:) 는 한 실행 안의 조회를 GetMany 한 번으로 묶는다.
+func newGraphQLSchema(users *store.Users, policy *rbac.Policy, onChange func(context.Context, string, int64, *store.User)) (*graphql.Schema, error) {
+	user := &graphql.Object{Name: "User", Description: "A stored user.", Fields: []*graphql.Field{
+		{Name: "id", Type: graphql.NonNull(graphql.ID)},
+		{Name: "name", Type: graphql.NonNull(graphql.String)},
+		{Name: "email", Type: graphql.String, Resolve: func(p graphql.Params) (any, error) {
+			if e := p.Source.(*store.User).Email; e != "" {
+				return e, nil
+			}
+			return nil, nil
+		}},
+		{Name: "createdAt", Type: graphql.NonNull(graphql.DateTime), Resolve: func(p graphql.Params) (any, error) {
+			return p.Source.(*store.User).CreatedAt, nil
+		}},
+	}}
+	input := &graphql.InputObject{Name: "UserInput", Fields: []*graphql.Arg{
+		{Name: "name", Type: graphql.NonNull(graphql.String)},
+		{Name: "email", Type: graphql.String},
+	}}
+	idArg := &graphql.Arg{Name: "id", Type: graphql.NonNull(graphql.ID)}
+	inputArg := &graphql.Arg{Name: "input", Type: graphql.NonNull(input)}
+
+	query := &graphql.Object{Name: "Query", Fields: []*graphql.Field{
+		{Name: "user", Type: user, Args: []*graphql.Arg{idArg}, Resolve: func(p graphql.Params) (any, error) {
+			id, err := graphQLUserID(p.Args["id"])
+			if err != nil {
+				return nil, err
+			}
+			u, err := graphql.LoaderFor(p.Context, "users", func(ctx context.Context, ids []int64) (map[int64]*store.User, error) {
+				list, err := users.GetMany(ctx, ids)
+				if err != nil {
+					return nil, err
+				}
+				found := make(map[int64]*store.User, len(list))
+				for i := range list {
+					found[list[i].ID] = &list[i]
+				}
+				return found, nil
+			}).Load(p.Context, id)
+			if errors.Is(err, graphql.ErrNotFound) {
+				return nil, nil
+			}
+			return u, graphQLError(p.Context, err)
+		}},
+		{Name: "users", Type: graphql.NonNull(graphql.NewList(graphql.NonNull(user))),
+			Args: []*graphql.Arg{
+				{Name: "limit", Type: graphql.Int, Default: resource.DefaultLimit},
+				{Name: "offset", Type: graphql.Int, Default: 0},
+			},
+			Resolve: func(p graphql.Params) (any, error) {
+				limit, offset := p.Args["limit"].(int), p.Args["offset"].(int)
+				if limit < 1 || limit > resource.MaxLimit || offset < 0 {
+					return nil, &graphql.Error{Message: fmt.Sprintf("limit must be 1..%d and offset must not be negative", resource.MaxLimit),
+						Extensions: map[string]any{"code": "bad_request"}}
+				}
+				list, err := users.List(p.Context, limit, offset)
+				if err != nil {
+					return nil, graphQLError(p.Context, err)
+				}
+				out := make([]*store.User, len(list))
+				for i := range list {
+					out[i] = &list[i]
+				}
+				return out, nil
+			}},
+		{Name: "usersCount", Type: graphql.NonNull(graphql.Int), Resolve: func(p graphql.Params) (any, error) {
+			n, err := users.Count(p.Context, &listquery.Query{})
+			return n, graphQLError(p.Context, err)
+		}},
+	}}
+
+	write := func(p graphql.Params) error {
+		pr := rbac.From(p.Context)
+		if pr == nil {
+			pr = policy.Resolve(p.Context)
+		}
+		switch {
+		case pr == nil:
+			return graphQLError(p.Context, api.NewError(http.StatusUnauthorized, "unauthorized", "authentication required"))
+		case !pr.Can("users:write"):
+			return graphQLError(p.Context, api.NewError(http.StatusForbidden, "forbidden", `permission "users:write" required`))
+		}
+		return nil
+	}
+	userInput := func(p graphql.Params) (*store.User, error) {
+		in := p.Args["input"].(map[string]any)
+		u := &store.User{Name: in["name"].(string)}
+		u.Email, _ = in["email"].(string)
+		return u, graphQLError(p.Context, api.Validate(u))
+	}
+	mutation := &graphql.Object{Name: "Mutation", Fields: []*graphql.Field{
+		{Name: "createUser", Type: graphql.NonNull(user), Args: []*graphql.Arg{inputArg}, Resolve: func(p graphql.Params) (any, error) {
+			if err := write(p); err != nil {
+				return nil, err
+			}
+			u, err := userInput(p)
+			if err != nil {
+				return nil, err
+			}
+			if err := users.Create(p.Context, u); err != nil {
+				return nil, graphQLError(p.Context, err)
+			}
+			onChange(p.Context, "created", u.ID, u)
+			return u, nil
+		}},
+		{Name: "updateUser", Type: user, Args: []*graphql.Arg{idArg, inputArg}, Resolve: func(p graphql.Params) (any, error) {
+			if err := write(p); err != nil {
+				return nil, err
+			}
+			id, err := graphQLUserID(p.Args["id"])
+			if err != nil {
+				return nil, err
+			}
+			u, err := userInput(p)
+			if err != nil {
+				return nil, err
+			}
+			if err := users.Update(p.Context, id, u); err != nil {
+				if errors.Is(err, store.ErrNotFound) {
+					return nil, nil
+				}
+				return nil, graphQLError(p.Context, err)
+			}
+			onChange(p.Context, "updated", id, u)
+			return u, nil
+		}},
+		{Name: "deleteUser", Type: graphql.NonNull(graphql.Boolean), Args: []*graphql.Arg{idArg},
+			Description: "Deletes the user. False when there is no such user.",
+			Resolve: func(p graphql.Params) (any, error) {
+				if err := write(p); err != nil {
+					return nil, err
+				}
+				id, err := graphQLUserID(p.Args["id"])
+				if err != nil {
+					return nil, err
+				}
+				if err := users.Delete(p.Context, id); err != nil {
+					if errors.Is(err, store.ErrNotFound) {
+						return false, nil
+					}
+					return nil, graphQLError(p.Context, err)
+				}
+				onChange(p.Context, "deleted", id, nil)
+				return true, nil
+			}},
+	}}
+	return graphql.NewSchema(query, mutation)
+}
+
+func graphQLUserID(v any) (int64, error) {
+	id, err := strconv.ParseInt(v.(string), 10, 64)
+	if err != nil || id < 1 {
+		return 0, &graphql.Error{Message: "id must be a positive integer", Extensions: map[string]any{"code": "bad_request"}}
+	}
+	return id, nil
+}
+
+// graphQLError 는 api.Error 를 extensions.code 가 있는 GraphQL 에러로 바꾼다. 다른 에러는 기록하고
+// 내용을 감춘다.
+func graphQLError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *api.Error
+	if errors.As(err, &e) {
+		ext := map[string]any{"code": e.Code}
+		if e.Details != nil {
+			ext["details"] = e.Details
+		}
+		return &graphql.Error{Message: e.Message, Extensions: ext}
+	}
+	logging.From(ctx).Error("graphql: resolver failed", "err", err)
+	return &graphql.Error{Message: "internal server error", Extensions: map[string]any{"code": "internal"}}
+}
+
+// describeAPI 는 직접 등록한 API 라우트의 문서 정보를 선언한다.
+func describeAPI(doc *openapi.Document) {
+	tags := []string{"hello"}
+	nameQuery := []openapi.Param{{Name: "name"}, {Name: "id", Type: "integer", Description: "greet a stored user"}}
+	doc.Describe(http.MethodGet, "/api/hello", openapi.Operation{
+		Summary: "Greet by name or stored user id", Tags: tags, Query: nameQuery, Response: greeting{},
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound},
+	})
+	doc.Describe(http.MethodGet, "/api/hello/{name}", openapi.Operation{
+		Summary: "Greet by path name", Tags: tags, Response: greeting{},
+	})
+	doc.Describe(http.MethodPost, "/api/hello", openapi.Operation{
+		Summary: "Greet and store a user", Tags: tags,
+		Request:  helloRequest{},
+		Response: greeting{}, Status: http.StatusCreated,
+		Errors: []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+	})
+	doc.Describe(http.MethodPost, "/api/markdown", openapi.Operation{
+		Summary: "Render Markdown to sanitized HTML", Tags: []string{"markdown"},
+		Request: markdown.Request{}, Response: markdown.Response{},
+		Errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType},
+	})
+	doc.Describe(http.MethodGet, "/api/tenant", openapi.Operation{
+		Summary: "Tenant of the request", Tags: []string{"tenancy"}, Response: tenant.Tenant{},
+		Errors: []int{http.StatusNotFound},
+	})
+	doc.Describe(http.MethodGet, "/api/me", openapi.Operation{
+		Summary: "Claims of the authenticated caller", Tags: []string{"auth"}, Response: auth.Claims{}, Auth: true,
+	})
+}
+
+// GET "/api/me" 핸들러 함수: 인증된 사용자의 클레임을 돌려준다.
+func meHandler(w http.ResponseWriter, r *http.Request) {
+	api.WriteJSON(w, http.StatusOK, auth.ClaimsFrom(r.Context()))
+}
+
+// newKeySet 은 설정의 "kid=값" 키들로 JWT KeySet 을 만든다.
+func newKeySet(cfg config.AuthConfig) (*auth.KeySet, error) {
+	var keys []auth.Key
+	if cfg.RSAPrivateKey != "" {
+		kid, path, _ := strings.Cut(cfg.RSAPrivateKey, "=")
+		priv, err := auth.LoadRSAPrivateKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, auth.RSAPrivateKey(kid, priv))
+	}
+	for _, kv := range cfg.HMACKeys {
+		kid, secret, _ := strings.Cut(kv, "=")
+		keys = append(keys, auth.HMACKey(kid, []byte(secret)))
+	}
+	for _, kv := range cfg.RSAPublicKeys {
+		kid, path, _ := strings.Cut(kv, "=")
+		pub, err := auth.LoadRSAPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, auth.RSAPublicKey(kid, pub))
+	}
+	ks := auth.NewKeySet(keys...)
+	ks.Issuer, ks.Audience = cfg.Issuer, cfg.Audience
+	return ks, nil
+}
+
+// newOutbound 는 외부 호출용 Transport 를 만든다. 시도마다 추적 span 이 생긴다.
+// base 가 nil 이면 http.DefaultTransport 를 쓴다.
+func newOutbound(cfg config.ClientConfig, base http.RoundTripper) *httpclient.Transport {
+	// 설정에서는 0 이 "사용 안 함" 이고, httpclient 에서는 음수가 "사용 안 함" 이다.
+	retries, failures := cfg.RetryMax, cfg.BreakerFailures
+	if retries == 0 {
+		retries = -1
+	}
+	if failures == 0 {
+		failures = -1
+	}
+	return &httpclient.Transport{
+		// 재시도마다 남은 시간을 다시 계산하도록 재시도 안쪽에서 기한을 전달한다.
+		Base: &deadline.Transport{Base: &tracing.Transport{Base: base}},
+		Retry: httpclient.RetryPolicy{
+			Max:       retries,
+			BaseDelay: cfg.RetryBaseDelay.D(),
+			MaxDelay:  cfg.RetryMaxDelay.D(),
+		},
+		Breaker: httpclient.BreakerConfig{
+			Failures:         failures,
+			OpenTimeout:      cfg.BreakerOpenTimeout.D(),
+			HalfOpenRequests: cfg.BreakerHalfOpenRequests,
+		},
+	}
+}
+
+// newOAuth 는 설정된 공급자들로 로그인 핸들러를 만든다.
+func newOAuth(cfg config.OAuthConfig, client *http.Client) *oauth.Handler {
+	var ps []*oauth.Provider
+	if cfg.GoogleClientID != "" {
+		ps = append(ps, oauth.Google(cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.RedirectURL))
+	}
+	if cfg.GitHubClientID != "" {
+		ps = append(ps, oauth.GitHub(cfg.GitHubClientID, cfg.GitHubClientSecret, cfg.RedirectURL))
+	}
+	h := oauth.New(ps...)
+	h.Client = client
+	return h
+}
+
+// newLocalAuth 는 설정으로 이메일·비밀번호 로그인 핸들러를 만든다.
+func newLocalAuth(cfg config.LocalAuthConfig, accounts *store.Accounts, mail *mailer.Mailer) *localauth.Handler {
+	return &localauth.Handler{
+		Accounts:        accounts,
+		Mail:            mail,
+		Hash:            cfg.Hash,
+		MinPassword:     cfg.MinPasswordLength,
+		RequireVerified: cfg.RequireVerified,
+		LockAfter:       cfg.LockAfter,
+		LockFor:         cfg.LockFor.D(),
+		VerifyTTL:       cfg.VerifyTTL.D(),
+		ResetTTL:        cfg.ResetTTL.D(),
+		BaseURL:         cfg.BaseURL,
+		Issuer:          cfg.TOTPIssuer,
+	}
+}
+
+// newProxy 는 설정의 프록시 규칙으로 Proxy 를 만든다. 백엔드 호출에도 재시도와 차단기가 적용된다.
+// 카나리 백엔드가 있는 규칙의 분할은 splits 에 등록한다.
+func newProxy(cfg config.ProxyConfig, client config.ClientConfig, splits *canary.Set) (*proxy.Proxy, error) {
+	routes := make([]proxy.Route, 0, len(cfg.Routes))
+	for _, rt := range cfg.Routes {
+		var cn *proxy.Canary
+		if c := rt.Canary; c != nil {
+			name := c.Split
+			if name == "" {
+				name = rt.Host + rt.Prefix
+			}
+			split, err := canary.New(name, c.Weight)
+			if err != nil {
+				return nil, err
+			}
+			if err := splits.Add(split); err != nil {
+				return nil, err
+			}
+			cn = &proxy.Canary{Upstreams: c.Upstreams, Split: split}
+		}
+		var tf *proxy.Transform
+		if t := rt.Transform; t != nil {
+			tf = &proxy.Transform{}
+			for _, h := range t.Headers {
+				tf.Headers = append(tf.Headers, proxy.HeaderRewrite{Header: h.Header, From: h.From, To: h.To})
+			}
+			if len(t.Links) > 0 {
+				links := make([]proxy.Rewrite, len(t.Links))
+				for i, l := range t.Links {
+					links[i] = proxy.Rewrite{From: l.From, To: l.To}
+				}
+				tf.Body = append(tf.Body, proxy.RewriteLinks(links))
+			}
+			if len(t.RedactFields) > 0 {
+				tf.Body = append(tf.Body, proxy.RedactJSON(t.RedactFields))
+			}
+		}
+		var hc *proxy.HealthCheck
+		if c := rt.HealthCheck; c != nil {
+			hc = &proxy.HealthCheck{
+				Path:               c.Path,
+				Interval:           c.Interval.D(),
+				Timeout:            c.Timeout.D(),
+				UnhealthyThreshold: c.UnhealthyThreshold,
+				HealthyThreshold:   c.HealthyThreshold,
+			}
+		}
+		routes = append(routes, proxy.Route{
+			Host:            rt.Host,
+			Prefix:          rt.Prefix,
+			Upstream:        rt.Upstream,
+			Upstreams:       rt.Upstreams,
+			Balance:         rt.Balance,
+			HealthCheck:     hc,
+			StripPrefix:     rt.StripPrefix,
+			SetHeaders:      rt.SetHeaders,
+			RemoveHeaders:   rt.RemoveHeaders,
+			ResponseHeaders: rt.ResponseHeaders,
+			MaxBodyBytes:    rt.MaxBodyBytes,
+			Canary:          cn,
+			Transform:       tf,
+		})
+	}
+	opts := proxy.Options{
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout.D(),
+		DialTimeout:           cfg.DialTimeout.D(),
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout.D(),
+	}
+	opts.Transport = newOutbound(client, proxy.NewTransport(opts))
+	return proxy.New(routes, opts)
+}
+
+// newSplits 는 설정의 핸들러 분할을 등록한 canary.Set 을 만든다.
+func newSplits(cfg config.CanaryConfig) (*canary.Set, error) {
+	set := canary.NewSet()
+	for _, sp := range cfg.Splits {
+		s, err := canary.New(sp.Name, sp.Weight)
+		if err != nil {
+			return nil, err
+		}
+		s.Cookie, s.Header = sp.Cookie, sp.Header
+		if err := set.Add(s); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// newTracingConfig 는 추적 설정의 "이름=값" 헤더 목록을 맵으로 바꾼다.
+func newTracingConfig(cfg config.TracingConfig) tracing.Config {
+	headers := map[string]string{}
+	for _, kv := range cfg.Headers {
+		k, v, _ := strings.Cut(kv, "=")
+		headers[k] = v
+	}
+	return tracing.Config{
+		Exporter:    cfg.Exporter,
+		Endpoint:    cfg.Endpoint,
+		Headers:     headers,
+		ServiceName: cfg.ServiceName,
+		SampleRatio: cfg.SampleRatio,
+	}
+}
+
+// newShedder 는 전역 한도와 라우트별 한도로 부하 차단기를 만든다. 라우트 한도도 같은 대기열 설정을 쓴다.
+func newShedder(cfg config.ShedConfig) *shed.Shedder {
+	lc := shed.Config{
+		Max:          cfg.MaxInFlight,
+		Min:          cfg.MinInFlight,
+		Queue:        cfg.Queue,
+		QueueTimeout: cfg.QueueTimeout.D(),
+		Target:       cfg.TargetLatency.D(),
+	}
+	s := &shed.Shedder{Routes: map[string]*shed.Limiter{}, Exempt: cfg.ExemptPaths}
+	if cfg.MaxInFlight > 0 {
+		s.Global = shed.New("global", lc)
+	}
+	for _, rt := range cfg.Routes {
+		pattern, n, _ := strings.Cut(rt, "=")
+		pattern = strings.Join(strings.Fields(pattern), " ")
+		rc := lc
+		rc.Max, _ = strconv.Atoi(strings.TrimSpace(n))
+		rc.Min = min(rc.Min, rc.Max)
+		s.Routes[pattern] = shed.New(pattern, rc)
+	}
+	return s
+}
+
+// newRateLimiter 는 설정에 맞는 백엔드로 요청 제한기를 만든다. key_header 로 구분할 때는 keys 로 확인한
+// API 키의 ID 를 쓴다. keys 가 nil 이면(DB 없음) IP 로 구분한다.
+func newRateLimiter(cfg config.RateLimitConfig, pool *cache.Pool, keys *store.APIKeys) (*ratelimit.Limiter, error) {
+	l := &ratelimit.Limiter{Rate: cfg.Rate, Burst: cfg.Burst}
+	switch cfg.Backend {
+	case "redis":
+		client, err := pool.Client(cfg.RedisAddr)
+		if err != nil {
+			return nil, err
+		}
+		l.Backend = ratelimit.NewRedisBackend(client)
+	default:
+		l.Backend = ratelimit.NewMemoryBackend()
+	}
+	if cfg.KeyHeader != "" {
+		var verify func(*http.Request, string) (string, bool)
+		if keys != nil {
+			// 전역 제한은 인증보다 바깥에서 실행되므로 키를 여기서도 확인한다.
+			auth := &apikey.Authenticator{Keys: keys}
+			verify = func(r *http.Request, v string) (string, bool) {
+				k, err := auth.Verify(r.Context(), v)
+				if err != nil {
+					return "", false
+				}
+				return strconv.FormatInt(k.ID, 10), true
+			}
+		}
+		l.Key = ratelimit.ByHeader(cfg.KeyHeader, verify)
+	}
+	return l, nil
+}
+
+// newQuotas 는 설정에 맞는 백엔드로 API 키 할당량 추적기를 만든다. 꺼져 있으면 nil 이다.
+// db 백엔드이면 사용량 저장소도 돌려준다. (오래된 사용량 정리용)
+func newQuotas(cfg config.QuotaConfig, pool *cache.Pool, db store.DB) (*quota.Tracker, *store.QuotaUsage, error) {
+	if !cfg.Enabled {
+		return nil, nil, nil
+	}
+	t := &quota.Tracker{Default: quota.Limits{Daily: cfg.Daily, Monthly: cfg.Monthly}}
+	var usage *store.QuotaUsage
+	switch cfg.Backend {
+	case "redis":
+		client, err := pool.Client(cfg.RedisAddr)
+		if err != nil {
+			return nil, nil, err
+		}
+		t.Counter = quota.NewRedisCounter(client)
+	case "db":
+		usage = store.NewQuotaUsage(db)
+		t.Counter = quota.DBCounter{Usage: usage}
+	default:
+		t.Counter = quota.NewMemoryCounter()
+	}
+	return t, usage, nil
+}
+
+// newResponseCache 는 설정에 맞는 백엔드로 응답 캐시를 만든다. 꺼져 있으면 nil 이다.
+func newResponseCache(cfg config.ResponseCacheConfig, pool *cache.Pool) (*respcache.Cache, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	c := &respcache.Cache{MaxEntryBytes: cfg.MaxEntryBytes}
+	switch cfg.Backend {
+	case "redis":
+		client, err := pool.Client(cfg.RedisAddr)
+		if err != nil {
+			return nil, err
+		}
+		c.Store = respcache.NewRedisStore(client)
+	default:
+		c.Store = respcache.NewMemoryStore(cfg.MaxBytes)
+	}
+	return c, nil
+}
+
+// newIdempotency 는 설정에 맞는 저장소로 Idempotency-Key 미들웨어를 만든다. 꺼져 있으면 아무것도 하지 않는다.
+// 키는 인증된 주체마다 따로 관리한다.
+func newIdempotency(cfg config.IdempotencyConfig, policy *rbac.Policy, pool *cache.Pool) (router.Middleware, error) {
+	if !cfg.Enabled {
+		return func(next http.Handler) http.Handler { return next }, nil
+	}
+	ic := idempotency.Config{TTL: cfg.TTL.D(), MaxBody: cfg.MaxBody}
+	switch cfg.Backend {
+	case "redis":
+		client, err := pool.Client(cfg.RedisAddr)
+		if err != nil {
+			return nil, err
+		}
+		ic.Store = idempotency.NewRedisStore(client)
+	default:
+		ic.Store = idempotency.NewMemoryStore(time.Minute)
+	}
+	ic.Scope = func(r *http.Request) string {
+		if pr := policy.Resolve(r.Context()); pr != nil {
+			return tenant.Key(r.Context(), pr.Subject)
+		}
+		return tenant.Key(r.Context(), r.Header.Get("Authorization")+"\n"+r.Header.Get("X-API-Key"))
+	}
+	return idempotency.Middleware(ic), nil
+}
+
+// newCORS 는 전역 CORS 정책을 만든다. /api/ 그룹은 쿠키 없이 Authorization 헤더를 허용한다.
+func newCORS(cfg config.CORSConfig) (*cors.CORS, error) {
+	c, err := cors.New(cors.Policy{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   cfg.ExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge.D(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Route("/api/", cors.Policy{
+		AllowedOrigins: cfg.AllowedOrigins,
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowedHeaders: append([]string{"Authorization", "Content-Type"}, cfg.AllowedHeaders...),
+		ExposedHeaders: []string{"X-Request-ID", "Retry-After"},
+		MaxAge:         cfg.MaxAge.D(),
+	})
+	return c, err
+}
+
+// newTenants 는 tenancy 설정으로 테넌트 목록을 만든다. 꺼져 있으면 nil 이다.
+func newTenants(cfg config.TenancyConfig) (*tenant.Registry, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	tc := tenant.Config{
+		Sources: cfg.Sources, Domain: cfg.Domain, Header: cfg.Header, PathPrefix: cfg.PathPrefix,
+		Default: cfg.Default, Required: cfg.Required,
+	}
+	for _, t := range cfg.Tenants {
+		tc.Tenants = append(tc.Tenants, tenant.Tenant{
+			ID: t.ID, Name: t.Name, Hosts: t.Hosts, Schema: t.Schema, DSN: t.DSN, Settings: t.Settings,
+		})
+	}
+	return tenant.New(tc)
+}
+
+// newRedisPool 은 redis 백엔드들이 함께 쓰는 연결 풀을 만든다. 클라이언트는 백엔드가 요청할 때 만든다.
+func newRedisPool(cfg config.RedisConfig) (*cache.Pool, error) {
+	cc := cache.Config{
+		Addrs: cfg.Addrs, MasterName: cfg.MasterName, SentinelPassword: cfg.SentinelPassword, Cluster: cfg.Cluster,
+		Username: cfg.Username, Password: cfg.Password, DB: cfg.DB,
+		PoolSize: cfg.PoolSize, MinIdleConns: cfg.MinIdleConns, ConnMaxIdleTime: cfg.ConnMaxIdleTime.D(),
+		DialTimeout: cfg.DialTimeout.D(), ReadTimeout: cfg.ReadTimeout.D(), WriteTimeout: cfg.WriteTimeout.D(),
+	}
+	if cfg.TLS {
+		tc, err := cache.TLSConfig(cfg.TLSCAFile, cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSServerName)
+		if err != nil {
+			return nil, err
+		}
+		cc.TLS = tc
+	}
+	return cache.NewPool(cc), nil
+}
+
+// newSessions 는 설정에 맞는 세션 저장소와 Manager 를 만든다. tenants 이면 세션을 테넌트별로 나눈다.
+// sweep 이 0 이면 메모리 저장소는 스스로 만료된 세션을 지우지 않으므로 Manager.Cleanup 을 주기적으로 불러야 한다.
+func newSessions(cfg config.SessionConfig, secret []byte, sweep time.Duration, tenants bool, pool *cache.Pool) (*session.Manager, error) {
+	var store session.Store
+	switch cfg.Store {
+	case "redis":
+		client, err := pool.Client(cfg.RedisAddr)
+		if err != nil {
+			return nil, err
+		}
+		store = session.NewRedisStore(client)
+	default:
+		store = session.NewMemoryStore(sweep)
+	}
+	if tenants {
+		store = tenant.Sessions(store)
+	}
+	var old [][]byte
+	for _, s := range cfg.OldSecrets {
+		old = append(old, []byte(s))
+	}
+	return session.NewManager(store, session.Options{
+		CookieName: cfg.CookieName,
+		TTL:        cfg.TTL.D(),
+		Secure:     cfg.Secure,
+		Secret:     secret,
+		OldSecrets: old,
+	})
+}
+
+// cookieSecret 은 세션과 CSRF 쿠키의 키다. session.secret 이 없으면 시작할 때마다 새로 만든다.
+func cookieSecret(cfg config.SessionConfig) []byte {
+	secret := []byte(cfg.Secret)
+	if len(secret) == 0 {
+		logging.Default().Warn("session.secret is not set; sessions will not survive a restart")
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+	return secret
+}
+
+// newBotGuard 는 세션과 같은 키로 문제와 통행증을 서명하는 봇 확인을 만든다.
+func newBotGuard(cfg *config.Config, secret []byte) (*botguard.Guard, error) {
+	secrets := [][]byte{secret}
+	for _, s := range cfg.Session.OldSecrets {
+		secrets = append(secrets, []byte(s))
+	}
+	return botguard.New(botguard.Options{
+		Secrets:         secrets,
+		Insecure:        !cfg.Session.Secure,
+		Paths:           cfg.Bot.Paths,
+		Threshold:       cfg.Bot.Threshold,
+		Difficulty:      cfg.Bot.Difficulty,
+		PassTTL:         cfg.Bot.PassTTL.D(),
+		RateWindow:      cfg.Bot.RateWindow.D(),
+		RateLimit:       cfg.Bot.RateLimit,
+		VerifyPath:      cfg.Bot.VerifyPath,
+		HCaptchaSiteKey: cfg.Bot.HCaptchaSiteKey,
+		HCaptchaSecret:  cfg.Bot.HCaptchaSecret,
+		Client:          httpclient.New(newOutbound(cfg.Client, nil), cfg.Client.Timeout.D()),
+	})
+}
+
+// newBackups 는 내보내고 들여올 수 있는 데이터 묶음이다. users, sessions 가 nil 이거나 pagesDir 가 비어 있으면 뺀다.
+func newBackups(users *store.Users, sessions *session.Manager, pagesDir string) *backup.Registry {
+	reg := &backup.Registry{}
+	if users != nil {
+		reg.Add(backup.Users(users))
+	}
+	if pagesDir != "" {
+		reg.Add(backup.Pages(pagesDir))
+	}
+	if sessions != nil {
+		reg.Add(backup.Sessions(sessions))
+	}
+	return reg
+}
+
+// newSearchIndex 는 search.backend 의 색인을 만든다. sqlite 색인은 search.dsn 파일에, 없으면 앱 DB 에 둔다.
+// 돌려준 close 는 따로 연 파일만 닫는다.
+func newSearchIndex(cfg *config.Config, db store.DB) (search.Index, func() error, error) {
+	noop := func() error { return nil }
+	if cfg.Search.Backend == "memory" {
+		return search.NewMemory(), noop, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	closeDB := noop
+	if cfg.Search.DSN != "" {
+		var err error
+		if db, err = store.Open(ctx, store.Config{Driver: "sqlite", DSN: cfg.Search.DSN}); err != nil {
+			return nil, nil, err
+		}
+		closeDB = db.Close
+	}
+	idx, err := search.NewSQLite(ctx, db)
+	if err != nil {
+		closeDB()
+		return nil, nil, err
+	}
+	return idx, closeDB, nil
+}
+
+// userDocument 는 사용자의 검색 문서다. 이름을 제목으로, 이메일을 본문으로 색인한다.
+func userDocument(u *store.User) search.Document {
+	id := strconv.FormatInt(u.ID, 10)
+	return search.Document{Kind: "user", ID: id, Title: u.Name, Body: u.Email, URL: "/api/v1/users/" + id}
+}
+
+// userSource 는 모든 사용자를 id 순서로 나눠 읽는다.
+func userSource(users *store.Users) search.Source {
+	return func(ctx context.Context, put func(search.Document) error) error {
+		const batch = 500
+		for offset := 0; ; offset += batch {
+			list, err := users.List(ctx, batch, offset)
+			if err != nil {
+				return err
+			}
+			for i := range list {
+				if err := put(userDocument(&list[i])); err != nil {
+					return err
+				}
+			}
+			if len(list) < batch {
+				return nil
+			}
+		}
+	}
+}
+
+// indexUser 는 REST, GraphQL, gRPC 로 바뀐 사용자를 색인에 반영한다. 실패해도 요청은 성공으로 두고 기록만 한다.
+func indexUser(ctx context.Context, index search.Index, action string, id int64, u *store.User) {
+	var err error
+	if action == "deleted" {
+		err = index.Delete(ctx, "user", strconv.FormatInt(id, 10))
+	} else {
+		err = index.Put(ctx, userDocument(u))
+	}
+	if err != nil {
+		logging.From(ctx).Warn("search index not updated", "user_id", id, "action", action, "err", err)
+	}
+}
+
+// pageSource 는 draft 가 아닌 파일 기반 페이지를 내보낸다.
+func pageSource(site *pages.Site) search.Source {
+	return func(_ context.Context, put func(search.Document) error) error {
+		for _, p := range site.Pages() {
+			if p.Draft {
+				continue
+			}
+			doc := search.Document{Kind: "page", ID: p.Path, Title: p.Title, Body: p.Description + "\n" + p.Text(), URL: p.Path}
+			if err := put(doc); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
-	fmt.Fprintf(w, "Hello, %s! How are you?", name)
+}
+
+// newCSRF 는 CSRF 미들웨어를 만든다. storage 가 "cookie" 이면 세션과 같은 키로 서명한 쿠키에 토큰을 둔다.
+func newCSRF(cfg *config.Config, secret []byte) (router.Middleware, error) {
+	opts := csrf.Options{ExemptPaths: cfg.CSRF.ExemptPaths}
+	if cfg.CSRF.Storage == "cookie" {
+		secrets := [][]byte{secret}
+		for _, s := range cfg.Session.OldSecrets {
+			secrets = append(secrets, []byte(s))
+		}
+		jar, err := cookies.New(cookies.Options{Secrets: secrets, Insecure: !cfg.Session.Secure})
+		if err != nil {
+			return nil, err
+		}
+		opts.Cookie = jar
+	}
+	return csrf.Middleware(opts), nil
+}
+
+// publishStats 는 interval 마다 서버 상태를 "stats" 이벤트로 발행한다.
+func publishStats(ctx context.Context, b *sse.Broker, hub *ws.Hub, interval time.Duration) {
+	start := time.Now()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		data, _ := json.Marshal(map[string]any{
+			"uptime_seconds": int(time.Since(start).Seconds()),
+			"goroutines":     runtime.NumGoroutine(),
+			"heap_bytes":     ms.HeapAlloc,
+			"ws_clients":     hub.Len(),
+		})
+		b.Publish(sse.Event{Event: "stats", Data: string(data)})
+	}
+}
+
+// openStore 는 데이터베이스에 연결하고, auto_migrate 설정이 켜져 있으면 마이그레이션을 적용한다.
+// 드라이버가 비어 있으면 nil 이다.
+func openStore(cfg config.DatabaseConfig) (store.DB, error) {
+	if cfg.Driver == "" {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	db, err := store.Open(ctx, store.Config{
+		Driver:          cfg.Driver,
+		DSN:             cfg.DSN,
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime.D(),
+		ConnMaxIdleTime: cfg.ConnMaxIdleTime.D(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AutoMigrate {
+		if err := migrateUp(ctx, db); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// openAudit 는 감사 로그 저장소를 연다. 꺼져 있으면 nil 이고, 파일 저장소는 돌려준 closeFn 으로 닫는다.
+func openAudit(cfg config.AuditConfig, db store.DB) (s audit.Store, closeFn func() error, err error) {
+	switch {
+	case !cfg.Enabled:
+		return nil, func() error { return nil }, nil
+	case cfg.Store == "db":
+		return audit.NewDBStore(db), func() error { return nil }, nil
+	}
+	fileStore, err := audit.OpenFile(cfg.File)
+	if err != nil {
+		return nil, nil, err
+	}
+	fileStore.Sync = cfg.Sync
+	return fileStore, fileStore.Close, nil
+}
+
+// migrateUp 은 적용하지 않은 마이그레이션을 모두 적용하고 기록한다.
+func migrateUp(ctx context.Context, db store.DB) error {
+	m, err := migrate.New(db)
+	if err != nil {
+		return err
+	}
+	done, err := m.Up(ctx)
+	for _, mg := range done {
+		logging.From(ctx).Info("migrate: applied", "migration", mg.String())
+	}
+	return err
+}
+
+// openTenantStores 는 schema 나 dsn 이 있는 테넌트의 데이터베이스를 연다. 스키마는 없으면 만들고,
+// auto_migrate 가 켜져 있으면 테넌트마다 마이그레이션을 적용한다.
+func openTenantStores(cfg *config.Config) (map[string]store.DB, error) {
+	dbs := map[string]store.DB{}
+	closeAll := func() {
+		for _, db := range dbs {
+			db.Close()
+		}
+	}
+	for _, t := range cfg.Tenancy.Tenants {
+		if t.Schema == "" && t.DSN == "" {
+			continue
+		}
+		dc := cfg.Database
+		dc.DSN = cmp.Or(t.DSN, dc.DSN)
+		dc.AutoMigrate = false
+		if t.Schema != "" {
+			dc.DSN = withSearchPath(dc.DSN, t.Schema)
+		}
+		db, err := openStore(dc)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("tenant %s: %w", t.ID, err)
+		}
+		dbs[t.ID] = db
+		ctx := logging.WithContext(context.Background(), logging.Default().With("tenant", t.ID))
+		if t.Schema != "" {
+			// 스키마 이름은 설정 검사에서 소문자, 숫자, _ 로 제한했다.
+			_, err = db.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+t.Schema)
+		}
+		if err == nil && cfg.Database.AutoMigrate {
+			err = migrateUp(ctx, db)
+		}
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("tenant %s: %w", t.ID, err)
+		}
+	}
+	return dbs, nil
+}
+
+// withSearchPath 는 Postgres DSN(URL 또는 key=value)에 search_path 를 더한다.
+func withSearchPath(dsn, schema string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return strings.TrimSpace(dsn + " search_path=" + schema)
+}
+
+// splitCommand 는 앞쪽의 하위 명령 단어들(예: "migrate up")과 나머지 플래그를 나눈다.
+func splitCommand(args []string) (cmd, rest []string) {
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = append(cmd, args[0]), args[1:]
+	}
+	return cmd, args
+}
+
+// runCommand 는 하위 명령을 실행한다.
+func runCommand(cfg *config.Config, cmd []string) error {
+	switch cmd[0] {
+	case "migrate":
+		if cfg.Database.Driver == "" {
+			return errors.New("migrate: database.driver is not set")
+		}
+		cfg.Database.AutoMigrate = false
+		db, err := openStore(cfg.Database)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if err := migrate.Run(context.Background(), db, cmd[1:], os.Stdout); err != nil || !cfg.Tenancy.Enabled {
+			return err
+		}
+		// 자기 데이터베이스가 있는 테넌트마다 같은 명령을 실행한다.
+		dbs, err := openTenantStores(cfg)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			for _, db := range dbs {
+				db.Close()
+			}
+		}()
+		for _, t := range cfg.Tenancy.Tenants {
+			if tdb, ok := dbs[t.ID]; ok {
+				fmt.Fprintf(os.Stdout, "tenant %s:\n", t.ID)
+				if err := migrate.Run(context.Background(), tdb, cmd[1:], os.Stdout); err != nil {
+					return fmt.Errorf("tenant %s: %w", t.ID, err)
+				}
+			}
+		}
+		return nil
+	case "replay":
+		return replay(cmd[1:], os.Stdout)
+	case "bench":
+		// "bench compare OLD NEW" 는 저장한 두 결과를 비교만 한다.
+		if len(cmd) != 4 || cmd[1] != "compare" {
+			return errors.New("usage: bench | bench compare OLD NEW")
+		}
+		base, err := bench.Load(cmd[2])
+		if err != nil {
+			return err
+		}
+		res, err := bench.Load(cmd[3])
+		if err != nil {
+			return err
+		}
+		return benchReport(cfg.Bench, res, base, os.Stdout)
+	case "export", "import":
+		var users *store.Users
+		if cfg.Database.Driver != "" {
+			cfg.Database.AutoMigrate = false
+			db, err := openStore(cfg.Database)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			users = store.NewUsers(db)
+		}
+		// 메모리 세션은 서버 프로세스 안에만 있으므로 관리 API(/api/admin/data/sessions)로만 옮길 수 있다.
+		var sessions *session.Manager
+		if cfg.Session.Store == "redis" {
+			pool, err := newRedisPool(cfg.Redis)
+			if err != nil {
+				return err
+			}
+			defer pool.Close()
+			if sessions, err = newSessions(cfg.Session, cookieSecret(cfg.Session), 0, cfg.Tenancy.Enabled, pool); err != nil {
+				return err
+			}
+		}
+		return backup.Run(context.Background(), newBackups(users, sessions, cfg.Pages.Dir), cmd, os.Stdout)
+	}
+	return fmt.Errorf("unknown command %q", cmd[0])
+}
+
+// runBench 는 "bench" 명령이다. 서브시스템을 시작하고 리스너 대신 app 에 직접 부하를 준 뒤 결과를 쓰고 종료 훅을 실행한다.
+// bench.output 이 있으면 결과를 저장하고, bench.baseline 이 있으면 그 결과와 비교한다.
+func runBench(cfg config.BenchConfig, app http.Handler, lc *lifecycle.Manager, out io.Writer) error {
+	opts := bench.Options{Concurrency: cfg.Concurrency, Duration: cfg.Duration.D(), Warmup: cfg.Warmup.D(), Header: http.Header{}}
+	for _, s := range cfg.Routes {
+		rt, err := bench.ParseRoute(s)
+		if err != nil {
+			return err
+		}
+		opts.Routes = append(opts.Routes, rt)
+	}
+	for _, h := range cfg.Headers {
+		name, value, _ := strings.Cut(h, ":")
+		opts.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	var base *bench.Result
+	if cfg.Baseline != "" {
+		var err error
+		if base, err = bench.Load(cfg.Baseline); err != nil {
+			return err
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := lc.Start(ctx); err != nil {
+		return err
+	}
+	lc.Ready(ctx)
+	logging.Default().Info("bench: running", "routes", len(opts.Routes), "concurrency", opts.Concurrency,
+		"duration", opts.Duration.String(), "warmup", opts.Warmup.String())
+	res, err := bench.Run(ctx, app, opts)
+	if err := errors.Join(err, lc.Shutdown(context.Background())); err != nil {
+		return err
+	}
+	if cfg.Output != "" {
+		if err := bench.Save(cfg.Output, res); err != nil {
+			return err
+		}
+	}
+	return benchReport(cfg, res, base, out)
+}
+
+// benchReport 는 결과 표를 쓰고, base 가 있고 bench.max_regression 을 넘은 값이 있으면 에러를 돌려준다.
+func benchReport(cfg config.BenchConfig, res, base *bench.Result, out io.Writer) error {
+	bench.Write(out, res, base)
+	if base == nil || cfg.MaxRegression == 0 {
+		return nil
+	}
+	regs := bench.Compare(base, res, cfg.MaxRegression)
+	for _, r := range regs {
+		fmt.Fprintln(out, "regression:", r)
+	}
+	if len(regs) > 0 {
+		return fmt.Errorf("bench: %d value(s) regressed more than %g%%", len(regs), cfg.MaxRegression)
+	}
+	return nil
+}
+
+// replay 는 "replay FILE TARGET [ID...]" 명령이다. FILE 의 기록(capture.file 또는 GET /api/admin/captures 응답)을
+// 오래된 것부터 TARGET 에 다시 보내고 기록한 응답과 비교한다. ID 를 주면 그 기록만 보낸다.
+func replay(args []string, out io.Writer) error {
+	if len(args) < 2 {
+		return errors.New("usage: replay FILE TARGET [ID...]")
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	exchanges, err := capture.Load(f)
+	if err != nil {
+		return err
+	}
+	only := map[int64]bool{}
+	for _, a := range args[2:] {
+		id, err := strconv.ParseInt(a, 10, 64)
+		if err != nil {
+			return fmt.Errorf("replay: invalid id %q", a)
+		}
+		only[id] = true
+	}
+	slices.SortStableFunc(exchanges, func(a, b capture.Exchange) int { return a.Time.Compare(b.Time) })
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		// 리다이렉트도 기록한 응답 그대로 비교한다.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	failed := 0
+	for _, e := range exchanges {
+		if len(only) > 0 && !only[e.ID] {
+			continue
+		}
+		res, err := capture.Replay(context.Background(), client, args[1], e)
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "#%d %s %s: %v\n", e.ID, e.Method, e.URL, err)
+			continue
+		}
+		note := ""
+		switch {
+		case res.Status != e.Status:
+			failed++
+			note = "  status differs"
+		case !res.SameBody:
+			note = "  body differs"
+		}
+		fmt.Fprintf(out, "#%d %s %s: %d -> %d (%.1fms)%s\n", e.ID, e.Method, e.URL, e.Status, res.Status, float64(res.Duration.Microseconds())/1000, note)
+	}
+	if failed > 0 {
+		return fmt.Errorf("replay: %d request(s) failed or changed status", failed)
+	}
+	return nil
+}
+
+// fatal 은 err 를 기록하고 프로세스를 끝낸다.
+func fatal(err error) {
+	logging.Default().Error(err.Error())
+	os.Exit(1)
+}
+
+// aclConfig 는 설정의 접근 제어 목록을 acl.Config 로 바꾼다.
+func aclConfig(cfg config.ACLConfig) acl.Config {
+	return acl.Config{Allow: cfg.Allow, Deny: cfg.Deny}
+}
+
+// realIPConfig 는 real_ip 설정을 realip.Config 로 바꾼다. 신뢰 프록시가 없으면 예전 acl 설정을 쓴다.
+func realIPConfig(cfg *config.Config) realip.Config {
+	rc := realip.Config{TrustedProxies: cfg.RealIP.TrustedProxies, Depth: cfg.RealIP.ProxyDepth, Headers: cfg.RealIP.Headers}
+	if len(rc.TrustedProxies) == 0 && len(cfg.ACL.TrustedProxies) > 0 {
+		rc.TrustedProxies, rc.Depth = cfg.ACL.TrustedProxies, cfg.ACL.ProxyDepth
+	}
+	return rc
+}
+
+// newAdminRouter 는 관리 전용 리스너의 라우터다. 관리 화면과 디버그 경로 외에 메트릭과 상태 검사를 둔다.
+// 세션, CSRF, 요청 제한처럼 공개 사이트용 미들웨어는 걸지 않는다.
+func newAdminRouter(cfg *config.Config, accessLog router.Middleware) *router.Router {
+	ar := router.New()
+	ar.NotFound = errorHandler(http.StatusNotFound, "not_found")
+	ar.MethodNotAllowed = errorHandler(http.StatusMethodNotAllowed, "method_not_allowed")
+	ar.Use(baseMiddleware(cfg, accessLog)...)
+	ar.Handle(http.MethodGet, "/metrics", metrics.Handler())
+	health.Default.Mount(ar)
+	return ar
+}
+
+// baseMiddleware 는 주 라우터가 아닌 라우트 트리에도 거는 최소한의 전역 미들웨어다.
+func baseMiddleware(cfg *config.Config, accessLog router.Middleware) []router.Middleware {
+	return []router.Middleware{
+		middleware.RequestID(),
+		realip.Default.Middleware(),
+		tracing.Middleware(),
+		timing.Middleware(cfg.Server.TimingHeader),
+		accessLog,
+		middleware.Recover(middleware.RecoverConfig{ShowStack: cfg.Dev.Enabled}),
+		middleware.SecureHeaders(middleware.SecureConfig(cfg.Security)),
+		i18n.Default.Middleware(),
+		middleware.MaxBodyBytes(cfg.Server.MaxBodyBytes),
+	}
+}
+
+// newVHosts 는 vhosts 설정으로 Host 헤더별 라우트 트리를 만든다. app 은 주 라우터, adminTree 는 관리 라우터다.
+func newVHosts(cfg *config.Config, app, adminTree http.Handler, accessLog router.Middleware) (*router.Hosts, error) {
+	hosts := router.NewHosts()
+	hosts.Default = app
+	for _, vh := range cfg.VHosts.Hosts {
+		var h http.Handler
+		switch vh.Handler {
+		case "admin":
+			h = adminTree
+		case "static":
+			h = router.Chain(static.Handler(os.DirFS(vh.Dir), static.Options{MaxAge: cfg.Static.MaxAge.D(), Index: "index.html"}), baseMiddleware(cfg, accessLog)...)
+		case "redirect":
+			to := strings.TrimSuffix(vh.RedirectTo, "/")
+			h = router.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, to+r.URL.RequestURI(), http.StatusMovedPermanently)
+			}), baseMiddleware(cfg, accessLog)...)
+		default:
+			h = app
+		}
+		if err := hosts.Handle(vh.Host, h); err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(vh.Host, cfg.VHosts.Default) {
+			hosts.Default = h
+		}
+	}
+	return hosts, nil
+}
+
+// newJobQueue 는 설정으로 작업 큐를 만들고 작업 종류별 처리기를 등록한다.
+func newJobQueue(cfg *config.Config) *jobs.Queue {
+	q := jobs.New(jobs.Options{
+		Concurrency: cfg.Jobs.Concurrency,
+		QueueSize:   cfg.Jobs.QueueSize,
+		MaxAttempts: cfg.Jobs.MaxAttempts,
+		BaseDelay:   cfg.Jobs.RetryBaseDelay.D(),
+		MaxDelay:    cfg.Jobs.RetryMaxDelay.D(),
+		DeadLetters: cfg.Jobs.DeadLetters,
+		SpoolFile:   cfg.Jobs.SpoolFile,
+	})
+	return q
+}
+
+// addCronTasks 는 설정에서 켠 주기 작업을 s 에 등록한다. app 은 캐시 예열 요청을 받을 핸들러다.
+// accounts 가 있으면 만료된 인증 토큰도, usage 가 있으면 지난 할당량 사용량도 지운다.
+func addCronTasks(s *cron.Scheduler, cfg config.CronConfig, sessions *session.Manager, app http.Handler, rec *admin.Recorder, accounts *store.Accounts, usage *store.QuotaUsage) error {
+	add := func(name, spec string, fn func(context.Context) error) error {
+		if spec == "" {
+			return nil
+		}
+		return s.Add(cron.Task{Name: name, Spec: spec, Func: fn, Timeout: cfg.Timeout.D(), Jitter: cfg.Jitter.D()})
+	}
+	err := errors.Join(
+		add("sessions.cleanup", cfg.SessionCleanup, func(ctx context.Context) error {
+			if n := sessions.Cleanup(); n > 0 {
+				logging.Default().Info("expired sessions removed", "count", n)
+			}
+			return nil
+		}),
+		add("cache.warmup", cfg.CacheWarmup, func(ctx context.Context) error {
+			return warmCache(ctx, app, cfg.WarmupPaths)
+		}),
+	)
+	if rec != nil {
+		err = errors.Join(err, add("stats.rollup", cfg.StatsRollup, statsRollup(rec)))
+	}
+	if accounts != nil {
+		err = errors.Join(err, add("auth.tokens.cleanup", cfg.TokenCleanup, func(ctx context.Context) error {
+			n, err := accounts.DeleteExpiredTokens(ctx)
+			if n > 0 {
+				logging.Default().Info("expired auth tokens removed", "count", n)
+			}
+			return err
+		}))
+	}
+	if usage != nil {
+		err = errors.Join(err, add("quota.usage.cleanup", cfg.QuotaCleanup, func(ctx context.Context) error {
+			// 이번 달 사용량은 남겨야 하므로 지난달 1일보다 먼저 시작한 기간만 지운다.
+			start, _ := quota.Month.Window(time.Now())
+			n, err := usage.DeleteBefore(ctx, start.AddDate(0, -1, 0))
+			if n > 0 {
+				logging.Default().Info("old quota usage removed", "count", n)
+			}
+			return err
+		}))
+	}
+	return err
+}
+
+// warmCache 는 paths 를 내부에서 GET 요청해 응답 캐시를 채운다. 실패한 경로가 있으면 모아서 돌려준다.
+func warmCache(ctx context.Context, h http.Handler, paths []string) error {
+	var errs []error
+	for _, p := range paths {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, p, nil)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		req.Host = "localhost"
+		req.RemoteAddr = "127.0.0.1:0"
+		w := &discardWriter{header: http.Header{}, status: http.StatusOK}
+		h.ServeHTTP(w, req)
+		if w.status >= 400 {
+			errs = append(errs, fmt.Errorf("warm %s: status %d", p, w.status))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// discardWriter 는 상태 코드만 기억하고 본문은 버리는 ResponseWriter 다.
+type discardWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) WriteHeader(code int)        { w.status = code }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+
+// statsRollup 은 호출할 때마다 직전 호출 이후의 요청 수, 에러 수, 평균 지연을 로그로 남기는 작업을 만든다.
+func statsRollup(rec *admin.Recorder) func(context.Context) error {
+	prev := rec.Stats()
+	return func(context.Context) error {
+		cur := rec.Stats()
+		n := cur.Requests - prev.Requests
+		avg := 0.0
+		if n > 0 {
+			avg = (cur.AvgLatencyMS*float64(cur.Requests) - prev.AvgLatencyMS*float64(prev.Requests)) / float64(n)
+		}
+		logging.Default().Info("request stats",
+			"requests", n,
+			"client_errors", cur.ClientErrors-prev.ClientErrors,
+			"server_errors", cur.ServerErrors-prev.ServerErrors,
+			"avg_latency_ms", math.Round(avg*100)/100)
+		prev = cur
+		return nil
+	}
+}
+
+// newWebhooks 는 설정의 구독자로 웹훅 발송기를 만들고 q 에 발송 처리기를 등록한다.
+// 재시도는 큐가 맡으므로 outbound 클라이언트의 재시도와 회로 차단기는 거치지 않는다.
+func newWebhooks(cfg config.WebhooksConfig, q *jobs.Queue) *webhook.Dispatcher {
+	subs := make([]webhook.Subscriber, 0, len(cfg.Subscribers))
+	for _, s := range cfg.Subscribers {
+		subs = append(subs, webhook.Subscriber{ID: s.ID, URL: s.URL, Secret: s.Secret, Events: s.Events})
+	}
+	d := webhook.New(q, cfg.History, subs...)
+	d.Client = &http.Client{Transport: &tracing.Transport{}, Timeout: cfg.Timeout.D()}
+	return d
+}
+
+// githubPush 는 GitHub push 이벤트 본문에서 쓰는 필드다.
+type githubPush struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Commits []json.RawMessage `json:"commits"`
+}
+
+// newReceiver 는 설정의 공급자로 수신 웹훅 Receiver 를 만들고 처리기를 등록한다.
+// GitHub push 는 저장소와 커밋 수를, 다른 이벤트는 이름과 ID 만 로그로 남긴다.
+func newReceiver(cfg config.WebhooksConfig) *webhook.Receiver {
+	rc := webhook.NewReceiver()
+	rc.ReplayWindow = 2 * cfg.Tolerance.D()
+	for _, p := range cfg.Receivers {
+		secret := []byte(p.Secret)
+		switch p.Type {
+		case "github":
+			rc.Provider(p.Name, webhook.GitHub{Secret: secret})
+			webhook.On(rc, p.Name, "push", func(ctx context.Context, m webhook.Message, ev githubPush) error {
+				logging.From(ctx).Info("github push received", "repository", ev.Repository.FullName, "ref", ev.Ref, "head", ev.After, "commits", len(ev.Commits))
+				return nil
+			})
+		case "stripe":
+			rc.Provider(p.Name, webhook.Stripe{Secret: secret, Tolerance: cfg.Tolerance.D()})
+		case "signed":
+			rc.Provider(p.Name, webhook.Signed{Secret: secret, Tolerance: cfg.Tolerance.D()})
+		}
+		rc.Handle(p.Name, "*", func(ctx context.Context, m webhook.Message) error {
+			logging.From(ctx).Info("webhook received", "provider", m.Provider, "event", m.Event, "delivery_id", m.ID, "bytes", len(m.Body))
+			return nil
+		})
+	}
+	return rc
+}
+
+// newMailer 는 설정의 백엔드로 Mailer 를 만들고 q 에 발송 처리기를 등록한다.
+func newMailer(cfg config.MailConfig, client config.ClientConfig, views *render.Engine, q *jobs.Queue) *mailer.Mailer {
+	hc := &http.Client{Transport: &tracing.Transport{}, Timeout: client.Timeout.D()}
+	var sender mailer.Sender
+	switch cfg.Backend {
+	case "smtp":
+		sender = mailer.SMTP{Addr: cfg.SMTPAddr, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword}
+	case "sendgrid":
+		sender = mailer.SendGrid{APIKey: cfg.SendGridKey, Client: hc}
+	case "ses":
+		ses := mailer.SES{Region: cfg.SESRegion, AccessKeyID: cfg.SESAccessKey, SecretAccessKey: cfg.SESSecretKey, Client: hc}
+		if ses.AccessKeyID == "" {
+			ses.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+			ses.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+			ses.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+		sender = ses
+	default:
+		sender = mailer.Log{}
+	}
+	return mailer.New(sender, cfg.From, views, q)
+}
+
+// welcomeMail 은 email_welcome.html 템플릿 데이터다.
+type welcomeMail struct {
+	Name string
+	Lang string
+}
+
+// publish 는 웹훅 이벤트를 보내고, 큐에 넣지 못하면 경고만 남긴다.
+func publish(ctx context.Context, hooks *webhook.Dispatcher, event string, data any) {
+	if _, err := hooks.Publish(ctx, event, data); err != nil {
+		logging.From(ctx).Warn("webhook event not queued", "event", event, "err", err)
+	}
+}
+
+// newFlagSources 는 설정에 있는 기능 플래그 Source 를 파일, 원격, 환경 변수 순서로 만든다.
+func newFlagSources(cfg config.FlagsConfig, client config.ClientConfig) []flags.Source {
+	var sources []flags.Source
+	if cfg.File != "" {
+		sources = append(sources, flags.File(cfg.File))
+	}
+	if cfg.RemoteURL != "" {
+		sources = append(sources, flags.Remote{URL: cfg.RemoteURL, Client: httpclient.New(newOutbound(client, nil), client.Timeout.D())})
+	}
+	if cfg.Env {
+		sources = append(sources, flags.Env{Prefix: config.EnvPrefix + "FLAG_"})
+	}
+	return sources
+}
+
+// maintenanceState 는 설정의 점검 모드를 maintenance.State 로 바꾼다.
+func maintenanceState(cfg config.MaintenanceConfig) maintenance.State {
+	return maintenance.State{Enabled: cfg.Enabled, Message: cfg.Message, RetryAfter: int(cfg.RetryAfter.D().Seconds())}
+}
+
+func chaosRules(rules []config.ChaosRule) []chaos.Rule {
+	out := make([]chaos.Rule, len(rules))
+	for i, c := range rules {
+		out[i] = chaos.Rule{Method: c.Method, Path: c.Path, Percent: c.Percent, Status: c.Status, Drop: c.Drop, BytesPerSecond: c.BytesPerSecond}
+		if c.Latency > 0 {
+			out[i].Latency = c.Latency.D().String()
+		}
+		if c.Jitter > 0 {
+			out[i].Jitter = c.Jitter.D().String()
+		}
+	}
+	return out
+}
+
+// rateLimits 는 요청 제한이 꺼져 있으면 0 을 돌려준다. (제한 없음)
+func rateLimits(cfg config.RateLimitConfig) (float64, int) {
+	if !cfg.Enabled {
+		return 0, 0
+	}
+	return cfg.Rate, cfg.Burst
+}
+
+// newWellKnown 은 /robots.txt, /favicon.ico, /.well-known/ 의 내용을 설정에서 읽는다.
+func newWellKnown(cfg *config.Config) (wellknown.Config, error) {
+	c := cfg.WellKnown
+	wk := wellknown.Config{
+		Disallow:       c.RobotsDisallow,
+		ACMEDir:        c.ACMEChallengeDir,
+		ChangePassword: c.ChangePasswordURL,
+		MaxAge:         c.MaxAge.D(),
+		Security:       wellknown.Security{Contact: c.SecurityContact, Policy: c.SecurityPolicy, Languages: c.SecurityLanguages},
+	}
+	if wk.ChangePassword == "" && cfg.LocalAuth.Enabled {
+		wk.ChangePassword = "/auth/forgot"
+	}
+	if cfg.Pages.Dir != "" {
+		wk.Sitemap = strings.TrimSuffix(cfg.Feed.BaseURL, "/") + "/sitemap.xml"
+	}
+	if c.SecurityExpires != "" {
+		wk.Security.Expires, _ = time.Parse(time.RFC3339, c.SecurityExpires)
+	}
+	var err error
+	if c.RobotsFile != "" {
+		if wk.Robots, err = os.ReadFile(c.RobotsFile); err != nil {
+			return wk, err
+		}
+	}
+	if c.FaviconFile != "" {
+		if wk.Favicon, err = os.ReadFile(c.FaviconFile); err != nil {
+			return wk, err
+		}
+	}
+	return wk, nil
+}
+
+// templateFuncs 는 템플릿에서 쓰는 함수들이다. (csrfField, t, markdown, asset, fieldError 등 폼 함수)
+func templateFuncs(md config.MarkdownConfig, assets *static.Assets) template.FuncMap {
+	fm := assets.FuncMap()
+	for k, v := range csrf.FuncMap() {
+		fm[k] = v
+	}
+	for k, v := range i18n.Default.FuncMap() {
+		fm[k] = v
+	}
+	for k, v := range api.FormFuncMap() {
+		fm[k] = v
+	}
+	for k, v := range markdown.FuncMap(markdownOptions(md)) {
+		fm[k] = v
+	}
+	return fm
+}
+
+// markdownOptions 는 사용자가 쓴 Markdown 에 쓸 확장 문법이다.
+func markdownOptions(cfg config.MarkdownConfig) markdown.Options {
+	return markdown.Options{Tables: cfg.Tables, Strikethrough: cfg.Strikethrough, Highlight: cfg.Highlight, HTML: cfg.HTML}
+}
+
+// newAssets 는 /static/ 아래의 정적 자산을 지문과 함께 준비한다. 디렉터리가 없으면 내장 자산(assets/)이다.
+func newAssets(cfg config.StaticConfig, reload bool) (*static.Assets, error) {
+	var fsys fs.FS
+	if cfg.Dir != "" {
+		fsys = os.DirFS(cfg.Dir)
+	} else {
+		fsys, _ = fs.Sub(embeddedAssets, "assets")
+		reload = false
+	}
+	return static.NewAssets(fsys, static.AssetOptions{
+		Prefix:  "/static/",
+		Options: static.Options{MaxAge: cfg.MaxAge.D()},
+		Reload:  reload,
+	})
+}
+
+// preloadLinks 는 Link 헤더 값의 /static/ 경로를 지문이 붙은 경로로 바꾼다.
+func preloadLinks(links []string, assets *static.Assets) []string {
+	out := make([]string, len(links))
+	for i, l := range links {
+		if rest, ok := strings.CutPrefix(l, "</static/"); ok {
+			if name, params, ok := strings.Cut(rest, ">"); ok {
+				l = "<" + assets.Path(name) + ">" + params
+			}
+		}
+		out[i] = l
+	}
+	return out
+}
+
+// templateFS 는 설정된 템플릿 디렉터리 또는 내장 템플릿을 돌려준다.
+func templateFS(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+	sub, _ := fs.Sub(embeddedTemplates, "templates")
+	return sub
 }
 
 func main() {
+	// 설정 로드 (기본값 → 설정 파일 → 환경 변수 → 플래그)
+	cmd, args := splitCommand(os.Args[1:])
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal(err)
+	}
+
+	sink, err := logging.OpenSink(logging.SinkConfig{
+		Outputs:        cfg.Log.Outputs,
+		File:           cfg.Log.File,
+		FileMaxSize:    int64(cfg.Log.FileMaxSizeMB) << 20,
+		FileMaxAge:     cfg.Log.FileMaxAge.D(),
+		FileMaxBackups: cfg.Log.FileMaxBackups,
+		SyslogNetwork:  cfg.Log.SyslogNetwork,
+		SyslogAddr:     cfg.Log.SyslogAddr,
+		SyslogTag:      cfg.Log.SyslogTag,
+	})
+	if err != nil {
+		fatal(err)
+	}
+	defer sink.Close()
+	logger, err := logging.New(logging.Options{Format: cfg.Log.Format, Output: sink})
+	if err != nil {
+		fatal(err)
+	}
+	logging.SetLevel(cfg.Log.Level)
+	logging.SetDefault(logger)
+	// SIGHUP 또는 POST /admin/reload 로 다시 적용할 부분은 아래에서 등록한다.
+	reloader := reload.New(cfg, func() (*config.Config, error) { return config.Load(args) })
+	reloader.Register("log", func(c *config.Config) (func(), error) {
+		if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+			return nil, err
+		}
+		return func() { logging.SetLevel(c.Log.Level) }, nil
+	})
+	if err := realip.Default.Update(realIPConfig(cfg)); err != nil {
+		fatal(err)
+	}
+	reloader.Register("real_ip", func(c *config.Config) (func(), error) {
+		if _, err := realip.New(realIPConfig(c)); err != nil {
+			return nil, err
+		}
+		return func() { realip.Default.Update(realIPConfig(c)) }, nil
+	})
+
+	// 하위 명령 (예: "migrate up") 은 서버를 띄우지 않고 실행만 한다. "bench" 만은 서버를 다 조립한 뒤 리스너 대신 부하를 준다.
+	benchMode := len(cmd) == 1 && cmd[0] == "bench"
+	if len(cmd) > 0 && !benchMode {
+		if err := runCommand(cfg, cmd); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	// -dev.watch 이면 이 프로세스는 감독만 하고, 빌드한 서버를 자식으로 띄워 소스가 바뀔 때마다 재시작한다.
+	if cfg.Dev.Watch {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		err := devwatch.Run(ctx, devwatch.Config{
+			Build:       cfg.Dev.Build,
+			Binary:      cfg.Dev.Binary,
+			Args:        append(slices.Clip(args), "-dev.watch=false"),
+			Dirs:        cfg.Dev.Dirs,
+			Exts:        cfg.Dev.Exts,
+			Interval:    cfg.Dev.Interval.D(),
+			StopTimeout: cfg.Server.DrainTimeout.D() + cfg.Server.HookTimeout.D(),
+		})
+		if err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if cfg.Dev.Enabled {
+		logger.Warn("development mode: templates and config reload on change, panics show stack traces")
+	}
+
+	// 템플릿 로드
+	assets, err := newAssets(cfg.Static, cfg.Templates.Reload)
+	if err != nil {
+		fatal(err)
+	}
+	views, err := render.New(templateFS(cfg.Templates.Dir), render.Options{Reload: cfg.Templates.Reload, Funcs: templateFuncs(cfg.Markdown, assets)})
+	if err != nil {
+		fatal(err)
+	}
+	render.SetDefault(views)
+
+	// 만료된 세션 정리는 스케줄러가 맡는다. 작업이 꺼져 있으면 저장소가 1분마다 직접 정리한다.
+	sweep := time.Duration(0)
+	if cfg.Cron.SessionCleanup == "" {
+		sweep = time.Minute
+	}
+	tenants, err := newTenants(cfg.Tenancy)
+	if err != nil {
+		fatal(err)
+	}
+	// 서브시스템은 만들 때 스스로 시작·종료 훅을 등록한다. 종료 훅은 등록의 역순으로 실행되므로
+	// 다른 서브시스템이 쓰는 Redis 와 DB 를 먼저 등록해 가장 늦게 닫는다.
+	lc := lifecycle.New()
+	lc.Timeout = cfg.Server.HookTimeout.D()
+	redisPool, err := newRedisPool(cfg.Redis)
+	if err != nil {
+		fatal(err)
+	}
+	if redisPool.Len() > 0 {
+		health.Register("redis", redisPool.Ping)
+		lc.OnShutdown("redis", 0, func(context.Context) error { return redisPool.Close() })
+	}
+	secret := cookieSecret(cfg.Session)
+	sessions, err := newSessions(cfg.Session, secret, sweep, cfg.Tenancy.Enabled, redisPool)
+	if err != nil {
+		fatal(err)
+	}
+
+	keys, err := newKeySet(cfg.Auth)
+	if err != nil {
+		fatal(err)
+	}
+
+	db, err := openStore(cfg.Database)
+	if err != nil {
+		fatal(err)
+	}
+	if db != nil && cfg.Tenancy.Enabled {
+		dbs, err := openTenantStores(cfg)
+		if err != nil {
+			db.Close()
+			fatal(err)
+		}
+		db = tenant.NewDB(db, dbs)
+	}
+	var (
+		users    *store.Users
+		apiKeys  *store.APIKeys
+		accounts *store.Accounts
+	)
+	if db != nil {
+		lc.OnShutdown("db", 0, func(context.Context) error { return db.Close() })
+		health.Register("db", db.PingContext)
+		users = store.NewUsers(db)
+		apiKeys = store.NewAPIKeys(db)
+		if cfg.LocalAuth.Enabled {
+			accounts = store.NewAccounts(db)
+		}
+	}
+
+	auditStore, closeAudit, err := openAudit(cfg.Audit, db)
+	if err != nil {
+		fatal(err)
+	}
+	lc.OnShutdown("audit", 0, func(context.Context) error { return closeAudit() })
+	audit.Default.Store = auditStore
+
+	shutdownTracing, err := tracing.Setup(context.Background(), newTracingConfig(cfg.Tracing))
+	if err != nil {
+		fatal(err)
+	}
+	lc.OnShutdown("tracing", 0, shutdownTracing)
+
+	// 백그라운드 작업 큐. 처리기를 모두 등록한 뒤 서버가 시작할 때 워커를 띄우고, HTTP 요청 드레인이
+	// 끝난 뒤 남은 작업을 처리한다.
+	queue := newJobQueue(cfg)
+	hooks := newWebhooks(cfg.Webhooks, queue)
+	mail := newMailer(cfg.Mail, cfg.Client, views, queue)
+	queue.RegisterLifecycle(lc)
+
+	// 실시간 인사말: 클라이언트가 보낸 메시지를 모든 연결에 전달한다.
+	hub := ws.NewHub()
+	hub.RegisterLifecycle(lc)
+	hub.OnMessage = func(m ws.Message) {
+		hub.Broadcast([]byte(fmt.Sprintf("%s: %s", m.Client.ID, m.Data)))
+	}
+
+	// 서버 상태 이벤트 스트림
+	events := sse.NewBroker(100)
+	events.RegisterLifecycle(lc)
+	statsCtx, stopStats := context.WithCancel(context.Background())
+	defer stopStats()
+	go publishStats(statsCtx, events, hub, 5*time.Second)
+
 	// 라우터 등록
-	http.HandleFunc("/", homeHandler)
-	http.HandleFunc("/hello", helloHandler)
+	r := router.New()
+	r.NotFound = errorHandler(http.StatusNotFound, "not_found")
+	r.MethodNotAllowed = errorHandler(http.StatusMethodNotAllowed, "method_not_allowed")
+	accessCfg := middleware.AccessLogConfig{
+		Out:           sink,
+		SampleRate:    cfg.Log.AccessSample,
+		SlowThreshold: cfg.Log.SlowRequest.D(),
+	}
+	// pretty 형식(개발 모드)이면 접근 로그도 같은 로거로 쓴다.
+	if cfg.Log.Format == "pretty" {
+		accessCfg.Out, accessCfg.Logger = nil, logger
+	}
+	if benchMode {
+		// 접근 로그를 만드는 비용은 재되 출력은 버린다.
+		accessCfg.Out, accessCfg.Logger = io.Discard, nil
+	}
+	accessLog := middleware.AccessLog(accessCfg)
+	r.Use(
+		// 103 응답은 ResponseWriter 래퍼를 거치지 않도록 가장 먼저 보낸다.
+		middleware.EarlyHints(preloadLinks(cfg.Static.Preload, assets)...),
+		middleware.RequestID(),
+		realip.Default.Middleware(),
+		tracing.Middleware(),
+		timing.Middleware(cfg.Server.TimingHeader),
+		accessLog,
+	)
+	// 관리 화면의 통계와 최근 에러는 패닉으로 끝난 요청까지 보도록 Recover 바깥에서 모은다.
+	var recorder *admin.Recorder
+	if cfg.Admin.Enabled {
+		recorder = admin.NewRecorder(cfg.Admin.RecentErrors)
+		r.Use(recorder.Middleware())
+	}
+	r.Use(
+		middleware.Recover(middleware.RecoverConfig{ShowStack: cfg.Dev.Enabled}),
+		middleware.SecureHeaders(middleware.SecureConfig(cfg.Security)),
+		i18n.Default.Middleware(),
+		metrics.Middleware(),
+		middleware.MaxBodyBytes(cfg.Server.MaxBodyBytes),
+	)
+	// 점검 모드도 꺼져 있을 때 등록해 두어야 실행 중에 켤 수 있다.
+	maint := maintenance.New(maintenanceState(cfg.Maintenance), cfg.Maintenance.ExemptPaths...)
+	r.Use(maint.Middleware())
+	reloader.Register("maintenance", func(c *config.Config) (func(), error) {
+		// 관리 API 로 바꾼 상태를 덮어쓰지 않도록 설정 파일의 값이 바뀐 경우에만 적용한다.
+		if c.Maintenance.Enabled == reloader.Current().Maintenance.Enabled {
+			return func() {}, nil
+		}
+		return func() { maint.Set(maintenanceState(c.Maintenance)) }, nil
+	})
+	// 기한은 프록시와 API 핸들러보다 먼저 걸어야 백엔드 호출과 DB 조회가 물려받는다.
+	if cfg.Deadline.Enabled {
+		r.Use(deadline.Middleware(deadline.Config{Header: cfg.Deadline.Header, Default: cfg.Deadline.Default.D(), Max: cfg.Deadline.Max.D()}))
+	}
+	// 장애 주입은 개발·스테이징에서 켰을 때만 등록한다.
+	var faults *chaos.Injector
+	if cfg.Chaos.Enabled {
+		faults, err = chaos.New(chaosRules(cfg.Chaos.Rules), cfg.Chaos.ExemptPaths...)
+		if err != nil {
+			fatal(err)
+		}
+		r.Use(faults.Middleware())
+		logger.Warn("chaos fault injection enabled", "rules", len(cfg.Chaos.Rules))
+		reloader.Register("chaos", func(c *config.Config) (func(), error) {
+			// 관리 API 로 바꾼 규칙을 덮어쓰지 않도록 설정 파일의 규칙이 바뀐 경우에만 적용한다.
+			if slices.Equal(c.Chaos.Rules, reloader.Current().Chaos.Rules) {
+				return func() {}, nil
+			}
+			rules := chaosRules(c.Chaos.Rules)
+			if _, err := chaos.New(rules); err != nil {
+				return nil, err
+			}
+			return func() { faults.SetRules(rules) }, nil
+		})
+	}
+	// 접근 제어 목록은 비어 있어도 등록해 두어야 다시 읽을 때 켤 수 있다.
+	guard, err := acl.New(aclConfig(cfg.ACL))
+	if err != nil {
+		fatal(err)
+	}
+	r.Use(guard.Middleware())
+	reloader.Register("acl", func(c *config.Config) (func(), error) {
+		if _, err := acl.New(aclConfig(c.ACL)); err != nil {
+			return nil, err
+		}
+		return func() { guard.Update(aclConfig(c.ACL)) }, nil
+	})
+	if cfg.Compress.Enabled {
+		r.Use(middleware.Compress(middleware.CompressConfig{
+			MinSize:      cfg.Compress.MinSize,
+			ContentTypes: cfg.Compress.ContentTypes,
+		}))
+	}
+	// 요청·응답 기록은 압축하기 전의 본문을 남기도록 압축 안쪽에 건다.
+	var captures *capture.Recorder
+	if cfg.Capture.Enabled {
+		cc := capture.Config{
+			Paths:         cfg.Capture.Paths,
+			MaxBody:       cfg.Capture.MaxBody,
+			Buffer:        cfg.Capture.Buffer,
+			RedactHeaders: cfg.Capture.RedactHeaders,
+			RedactFields:  cfg.Capture.RedactFields,
+		}
+		if cfg.Capture.File != "" {
+			f, err := os.OpenFile(cfg.Capture.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+			if err != nil {
+				fatal(err)
+			}
+			defer f.Close()
+			cc.Output = f
+		}
+		captures = capture.New(cc)
+		r.Use(captures.Middleware())
+		logging.Default().Warn("request capture is enabled; captured payloads may contain personal data", "paths", cfg.Capture.Paths)
+	}
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		c, err := newCORS(cfg.CORS)
+		if err != nil {
+			fatal(err)
+		}
+		r.Use(c.Middleware())
+	}
+	// 요청 제한도 꺼져 있을 때는 제한 없이(rate 0) 등록해 두고, 다시 읽을 때 한도만 바꾼다.
+	// 백엔드나 키 헤더를 바꾸려면 재시작해야 한다.
+	limiter, err := newRateLimiter(cfg.RateLimit, redisPool, apiKeys)
+	if err != nil {
+		fatal(err)
+	}
+	if cfg.Tenancy.Enabled {
+		limiter.Backend = tenant.RateLimits(limiter.Backend)
+	}
+	limiter.SetLimits(rateLimits(cfg.RateLimit))
+	r.Use(limiter.Middleware())
+	reloader.Register("rate_limit", func(c *config.Config) (func(), error) {
+		rate, burst := rateLimits(c.RateLimit)
+		return func() { limiter.SetLimits(rate, burst) }, nil
+	})
+	// 봇 확인은 요청 제한 안쪽, 프록시와 CSRF 바깥에 건다. 답을 받는 경로는 폼으로 바로 오기 때문이다.
+	if cfg.Bot.Enabled {
+		bots, err := newBotGuard(cfg, secret)
+		if err != nil {
+			fatal(err)
+		}
+		r.Use(bots.Middleware())
+	}
+	// 동시 처리 제한은 요청 제한을 통과한 요청에만 자리를 내준다.
+	if cfg.Shed.Enabled {
+		r.Use(newShedder(cfg.Shed).Middleware())
+	}
+	// 가짜 응답은 프록시와 실제 라우트보다 먼저 응답하고, 세션과 CSRF 도 거치지 않는다.
+	if cfg.Mock.File != "" {
+		mocks, err := mock.Open(cfg.Mock.File, cfg.Mock.Latency.D())
+		if err != nil {
+			fatal(err)
+		}
+		r.Use(mocks.Middleware())
+		reloader.Register("mock", func(c *config.Config) (func(), error) {
+			if c.Mock.File == "" {
+				return nil, errors.New("mock.file cannot be cleared without a restart")
+			}
+			return mocks.Prepare(c.Mock.File, c.Mock.Latency.D())
+		})
+		logging.Default().Warn("mock mode is enabled; fixture routes answer before real handlers", "file", cfg.Mock.File)
+	}
+	splits, err := newSplits(cfg.Canary)
+	if err != nil {
+		fatal(err)
+	}
+	// 프록시로 보내는 요청은 세션과 CSRF 를 거치지 않는다. 인증은 백엔드가 한다.
+	if len(cfg.Proxy.Routes) > 0 {
+		px, err := newProxy(cfg.Proxy, cfg.Client, splits)
+		if err != nil {
+			fatal(err)
+		}
+		r.Use(px.Middleware())
+		proxyCtx, stopProxy := context.WithCancel(context.Background())
+		defer stopProxy()
+		go px.CheckHealth(proxyCtx)
+	}
+	r.Use(sessions.Middleware())
+	if cfg.CSRF.Enabled {
+		mw, err := newCSRF(cfg, secret)
+		if err != nil {
+			fatal(err)
+		}
+		r.Use(mw)
+	}
+	pageCache, err := newResponseCache(cfg.ResponseCache, redisPool)
+	if err != nil {
+		fatal(err)
+	}
+	if pageCache != nil && cfg.Tenancy.Enabled {
+		pageCache.Store = tenant.Cache(pageCache.Store)
+	}
+	// 캐시가 비었을 때 몰린 같은 요청은 핸들러를 한 번만 실행하고 응답을 나눠 받는다.
+	// 테넌트 미들웨어는 라우터 바깥에서 먼저 실행되므로 키를 만들 때는 테넌트가 정해져 있다.
+	coalesceCfg := coalesce.Config{}
+	if cfg.Tenancy.Enabled {
+		coalesceCfg.Key = tenant.CoalesceKey(coalesce.DefaultKey)
+	}
+	coalesced := coalesce.Requests(coalesceCfg)
+	r.GET("/", homeHandler, middleware.ETag(), pageCache.For(time.Minute), coalesced)
+	wk, err := newWellKnown(cfg)
+	if err != nil {
+		fatal(err)
+	}
+	wellknown.Mount(r, wk)
+	r.GET("/hello", helloHandler)
+	r.POST("/hello", helloHandler)
+	r.GET("/hello/{name}", helloHandler)
+	apiTimeout := middleware.WithTimeout(cfg.Server.HandlerTimeout.D())
+	policy, err := rbac.Parse(cfg.RBAC.Roles)
+	if err != nil {
+		fatal(err)
+	}
+	if err := policy.RequireMFA(cfg.RBAC.RequireMFA...); err != nil {
+		fatal(err)
+	}
+	// 기능 플래그의 비율 배포와 사용자 지정은 인증된 주체의 subject 를 기준으로 한다.
+	flags.Default.Sources = newFlagSources(cfg.Flags, cfg.Client)
+	flags.Default.Subject = func(ctx context.Context) string {
+		if pr := policy.Resolve(ctx); pr != nil {
+			return pr.Subject
+		}
+		return ""
+	}
+	flags.Default.Refresh(context.Background())
+	if cfg.Flags.File != "" || cfg.Flags.RemoteURL != "" {
+		flagsCtx, stopFlags := context.WithCancel(context.Background())
+		defer stopFlags()
+		go flags.Default.Watch(flagsCtx, cfg.Flags.RefreshInterval.D())
+	}
+	// 감사 로그의 행위자는 인증된 주체, 없으면 로그인 세션의 사용자다.
+	audit.Default.Actor = func(r *http.Request) string {
+		if pr := policy.Resolve(r.Context()); pr != nil {
+			return pr.Subject
+		}
+		if u := oauth.User(r); u != nil {
+			return u.Provider + ":" + u.ID
+		}
+		return "anonymous"
+	}
+	// /api 와 /admin 아래에서는 Bearer 토큰과 X-API-Key 가 있으면 검증한다. 없으면 익명 요청이다.
+	authn := []router.Middleware{keys.Authenticate()}
+	var (
+		quotas     *quota.Tracker
+		quotaUsage *store.QuotaUsage
+	)
+	if apiKeys != nil {
+		quotas, quotaUsage, err = newQuotas(cfg.Quota, redisPool, db)
+		if err != nil {
+			fatal(err)
+		}
+		// 키별 요청 제한은 전역 제한과 같은 백엔드를 쓴다.
+		authn = append(authn, (&apikey.Authenticator{Keys: apiKeys, Limits: limiter.Backend, Quotas: quotas}).Middleware())
+	}
+	apiGroup := r.Group("/api", authn...)
+	// 관리 API 의 변경 요청은 인가된 주체와 함께 감사 로그에 남긴다.
+	requireAdmin := router.Middleware(func(next http.Handler) http.Handler {
+		return router.Chain(next, policy.RequireRole("admin"), audit.Changes())
+	})
+	adminOnly := append(authn, requireAdmin)
+	if apiKeys != nil {
+		(&apikey.Admin{Keys: apiKeys, Quotas: quotas}).Mount(apiGroup, "/admin", requireAdmin, apiTimeout)
+	}
+	if quotas != nil {
+		apiGroup.GET("/usage", apikey.UsageHandler(quotas), apikey.Require(), apiTimeout)
+	}
+	apiGroup.GET("/admin/loglevel", logging.LevelHandler(), requireAdmin)
+	if pageCache != nil {
+		apiGroup.DELETE("/admin/cache", pageCache.PurgeHandler(), requireAdmin)
+	}
+	apiGroup.PUT("/admin/loglevel", logging.LevelHandler(), requireAdmin)
+	flags.Default.Mount(apiGroup, "/admin/flags", requireAdmin)
+	splits.Mount(apiGroup, "/admin/canary", requireAdmin)
+	queue.Mount(apiGroup, "/admin/jobs", requireAdmin)
+	hooks.Mount(apiGroup, "/admin/webhooks", requireAdmin)
+	audit.Default.Mount(apiGroup, "/admin/audit", requireAdmin)
+	if captures != nil {
+		captures.Mount(apiGroup, "/admin/captures", requireAdmin)
+	}
+	// 주기 작업은 아래에서 app 이 만들어진 뒤 등록하지만 상태 라우트는 다른 관리 API 와 함께 둔다.
+	sched := cron.New()
+	sched.Mount(apiGroup, "/admin/cron", requireAdmin)
+	newBackups(users, sessions, cfg.Pages.Dir).Mount(apiGroup, "/admin/data", requireAdmin)
+	apiGroup.GET("/admin/maintenance", maint.Handler(), requireAdmin)
+	apiGroup.PUT("/admin/maintenance", maint.Handler(), requireAdmin)
+	if faults != nil {
+		apiGroup.GET("/admin/chaos", faults.Handler(), requireAdmin)
+		apiGroup.PUT("/admin/chaos", faults.Handler(), requireAdmin)
+		apiGroup.DELETE("/admin/chaos", faults.Handler(), requireAdmin)
+	}
+	apiGroup.GET("/hello", helloAPIHandler(users), coalesced, apiTimeout)
+	apiGroup.GET("/hello/{name}", helloAPIHandler(users), coalesced, apiTimeout)
+	idem, err := newIdempotency(cfg.Idempotency, policy, redisPool)
+	if err != nil {
+		fatal(err)
+	}
+	apiGroup.POST("/hello", helloAPIPostHandler(users), idem, apiTimeout)
+	apiGroup.GET("/me", meHandler, keys.Require(), apiTimeout)
+	apiGroup.POST("/markdown", markdown.Handler(markdownOptions(cfg.Markdown), cfg.Markdown.MaxBytes), apiTimeout)
+	if tenants != nil {
+		apiGroup.GET("/tenant", tenant.Handler())
+	}
+	v1 := apiGroup.Group("/v1", apiTimeout)
+	if cfg.API.V1Deprecated != "" {
+		since, sunset, _ := cfg.API.V1Deprecation()
+		v1.Use(middleware.Deprecation(middleware.DeprecationConfig{
+			Since: since, Sunset: sunset, Successor: cfg.API.V1Successor,
+		}))
+	}
+	docs := openapi.New("hello server", "1.0.0")
+	docs.Include = []string{"/api/", "/auth/token"}
+	describeAPI(docs)
+	// 검색 색인은 사용자와 페이지 라우트를 등록하면서 Source 를 모으고, 라우트를 모두 등록한 뒤 채운다.
+	var index search.Index
+	sources := search.Sources{}
+	if cfg.Search.Enabled {
+		var closeIndex func() error
+		index, closeIndex, err = newSearchIndex(cfg, db)
+		if err != nil {
+			fatal(err)
+		}
+		lc.OnShutdown("search", 0, func(context.Context) error { return closeIndex() })
+		v1.GET("/search", search.Handler(index))
+		apiGroup.POST("/admin/search/reindex", search.ReindexHandler(index, sources), requireAdmin)
+		docs.Describe(http.MethodGet, "/api/v1/search", openapi.Operation{
+			Summary: "Full-text search over users and pages", Tags: []string{"search"}, Response: search.Page{},
+			Query: []openapi.Param{
+				{Name: "q", Description: `words to match; "quoted phrase", prefix*`},
+				{Name: "kind", Description: "comma-separated kinds (user, page)"},
+				{Name: "limit", Type: "integer"}, {Name: "offset", Type: "integer"},
+			},
+			Errors: []int{http.StatusBadRequest},
+		})
+	}
+	var grpcSrv *grpc.Server
+	if users != nil {
+		res := newUserResource(users)
+		res.Read = []router.Middleware{middleware.ETag()}
+		res.Write = []router.Middleware{policy.RequirePermission("users:write"), idem}
+		res.OnChange = func(ctx context.Context, action string, id int64, u *store.User) {
+			var data any = u
+			if u == nil {
+				data = map[string]int64{"id": id}
+			}
+			publish(ctx, hooks, "user."+action, data)
+			if action == "created" && cfg.Mail.Welcome && u.Email != "" {
+				if err := mail.SendTemplate(ctx, "email_welcome.html", welcomeMail{Name: u.Name, Lang: i18n.Lang(ctx)}, u.Email); err != nil {
+					logging.From(ctx).Warn("welcome mail not queued", "user_id", id, "err", err)
+				}
+			}
+		}
+		if index != nil {
+			sources["user"] = userSource(users)
+			notify := res.OnChange
+			res.OnChange = func(ctx context.Context, action string, id int64, u *store.User) {
+				notify(ctx, action, id, u)
+				indexUser(ctx, index, action, id, u)
+			}
+		}
+		res.Mount(v1, "/users")
+		res.Describe(docs, "/api/v1/users")
+		if cfg.GraphQL.Enabled {
+			schema, err := newGraphQLSchema(users, policy, res.OnChange)
+			if err != nil {
+				fatal(err)
+			}
+			schema.MaxDepth = cfg.GraphQL.MaxDepth
+			gql := graphql.Handler(schema, graphql.Options{Playground: cfg.GraphQL.Playground, MaxBytes: cfg.GraphQL.MaxBytes})
+			gqlMW := append(slices.Clip(authn), apiTimeout)
+			r.GET("/graphql", gql, gqlMW...)
+			r.POST("/graphql", gql, gqlMW...)
+			r.GET("/graphql/schema.graphql", graphql.SDLHandler(schema), middleware.ETag())
+		}
+		if cfg.GRPC.Enabled {
+			svc := &rpc.Users{Store: users, Policy: policy, OnChange: res.OnChange}
+			grpcSrv = rpc.NewServer(grpc.MaxRecvMsgSize(int(cfg.Server.MaxBodyBytes)))
+			usersv1.RegisterUserServiceServer(grpcSrv, svc)
+			if cfg.GRPC.Gateway {
+				gw := rpc.Gateway()
+				if err := usersv1.RegisterUserServiceHandlerServer(context.Background(), gw, svc); err != nil {
+					fatal(err)
+				}
+				v2 := apiGroup.Group("/v2", apiTimeout)
+				v2.Handle(http.MethodGet, "/users", gw)
+				v2.Handle(http.MethodPost, "/users", gw, idem)
+				v2.Handle(http.MethodGet, "/users/{id}", gw)
+				v2.Handle(http.MethodPut, "/users/{id}", gw)
+				v2.Handle(http.MethodDelete, "/users/{id}", gw)
+			}
+			if !cfg.TLS.Enabled() && !cfg.TLS.ACME() && !cfg.Server.H2C {
+				logger.Warn("grpc needs HTTP/2; enable server.h2c or TLS to accept gRPC clients")
+			}
+		}
+	}
+	if cfg.OAuth.Enabled() {
+		newOAuth(cfg.OAuth, httpclient.New(newOutbound(cfg.Client, nil), cfg.Client.Timeout.D())).Mount(r)
+	}
+	if accounts != nil {
+		la := newLocalAuth(cfg.LocalAuth, accounts, mail)
+		la.Mount(r)
+		// OAuth 가 꺼져 있으면 로그아웃 경로도 여기서 등록한다.
+		if !cfg.OAuth.Enabled() {
+			r.POST("/auth/logout", la.Logout)
+		}
+	}
+	if cfg.Auth.TokenEndpoint {
+		r.POST("/auth/token", keys.TokenHandler(cfg.Auth.TokenTTL.D()))
+	}
+	r.Handle(http.MethodGet, "/metrics", metrics.Handler())
+	r.GET("/openapi.json", docs.Handler(r), middleware.ETag(), pageCache.For(5*time.Minute), coalesced)
+	r.GET("/docs", openapi.UIHandler("hello server API", "/openapi.json"), middleware.ETag(), pageCache.For(5*time.Minute))
+	// 기능 모듈(업로드 등)은 각자의 패키지가 등록한다. 외부 모듈은 그 패키지를 import 하면 더해진다.
+	mods, err := module.Load(module.Env{
+		Config: cfg,
+		DB:     db,
+		Jobs:   queue,
+		Publish: func(ctx context.Context, event string, data any) {
+			publish(ctx, hooks, event, data)
+		},
+		Logger:   logger,
+		Canaries: splits,
+	})
+	if err != nil {
+		fatal(err)
+	}
+	module.Mount(r, lc, mods)
+	for _, m := range mods {
+		logger.Debug("module loaded", "module", m.Name())
+	}
+	downloads := slices.Clone(cfg.Download.Routes)
+	if cfg.Download.Dir != "" {
+		downloads = append(downloads, config.DownloadRoute{Prefix: "/downloads/", Dir: cfg.Download.Dir, BytesPerSecond: cfg.Download.BytesPerSecond})
+	}
+	for _, d := range downloads {
+		files := download.Handler(os.DirFS(d.Dir), download.Options{BytesPerSecond: d.BytesPerSecond, Inline: d.Inline})
+		r.Handle(http.MethodGet, d.Prefix, http.StripPrefix(d.Prefix, files))
+	}
+	if len(cfg.Webhooks.Receivers) > 0 {
+		r.POST("/webhooks/{provider}", newReceiver(cfg.Webhooks).ServeHTTP)
+	}
+	r.GET("/ws", hub.Handler())
+	r.GET("/events", events.Handler())
+	health.Default.Mount(r)
+	// 관리 전용 리스너가 있으면 관리 화면과 디버그 경로는 공개 라우터에 두지 않는다.
+	mountAdmin := func(rt *router.Router) {
+		if cfg.Admin.Enabled {
+			dash := &admin.Dashboard{Routes: r.Routes, Config: reloader.Current, Sessions: sessions, Recorder: recorder}
+			dash.Mount(rt, "/admin", adminOnly...)
+			rt.POST("/admin/reload", reloader.Handler(), adminOnly...)
+		}
+		if cfg.Debug.Enabled && cfg.Debug.Addr == "" {
+			debug.Mount(rt, adminOnly...)
+		}
+	}
+	var adminRouter *router.Router
+	if cfg.Server.AdminListener() || cfg.VHosts.Uses("admin") {
+		adminRouter = newAdminRouter(cfg, accessLog)
+		mountAdmin(adminRouter)
+	} else {
+		mountAdmin(r)
+	}
+	// 파일 기반 페이지는 직접 등록한 라우트 뒤에 등록하므로 같은 경로면 직접 등록한 라우트가 이긴다.
+	if cfg.Pages.Dir != "" {
+		// 페이지 파일은 운영자가 쓰므로 HTML 을 그대로 둔다.
+		md := markdownOptions(cfg.Markdown)
+		md.HTML = true
+		site, err := pages.New(os.DirFS(cfg.Pages.Dir), pages.Options{Views: views, Funcs: templateFuncs(cfg.Markdown, assets), Markdown: &md, Live: cfg.Pages.Live})
+		if err != nil {
+			fatal(err)
+		}
+		site.Mount(r, middleware.ETag())
+		if index != nil {
+			sources["page"] = pageSource(site)
+		}
+		feed.New(feed.Config{
+			Title: cfg.Feed.Title, Description: cfg.Feed.Description, Author: cfg.Feed.Author, BaseURL: cfg.Feed.BaseURL,
+			Format: cfg.Feed.Format, Limit: cfg.Feed.Limit, MaxAge: cfg.Feed.MaxAge.D(),
+		}, site).Mount(r, middleware.ETag())
+		if cfg.Pages.Live {
+			// 실행 중에 새로 만든 파일도 재시작 없이 보이도록 404 전에 페이지를 찾아본다.
+			r.NotFound = site.Fallback(r.NotFound)
+			pagesCtx, stopPages := context.WithCancel(context.Background())
+			defer stopPages()
+			go site.Watch(pagesCtx, 500*time.Millisecond, templateFS(cfg.Templates.Dir))
+		}
+	}
+	r.Handle(http.MethodGet, "/static/", http.StripPrefix("/static/", assets))
+	if index != nil {
+		go sources.ReindexAll(context.Background(), index)
+	}
+
+	// 호스트별 라우트 트리가 있으면 주 라우터 앞에서 Host 헤더로 나눈다.
+	app := http.Handler(r)
+	if len(cfg.VHosts.Hosts) > 0 {
+		if app, err = newVHosts(cfg, r, adminRouter, accessLog); err != nil {
+			fatal(err)
+		}
+	}
+	// gRPC 요청은 라우터의 전역 미들웨어(압축, 타임아웃, 세션 등)를 거치지 않도록 앞에서 나누고,
+	// 로깅, 접근 제어, 요청 제한, 인증만 건다.
+	if grpcSrv != nil {
+		grpcMW := []router.Middleware{
+			middleware.RequestID(),
+			realip.Default.Middleware(),
+			tracing.Middleware(),
+			timing.Middleware(false),
+			accessLog,
+			guard.Middleware(),
+			limiter.Middleware(),
+		}
+		app = rpc.Split(router.Chain(grpcSrv, append(grpcMW, authn...)...), app)
+	}
+	// 테넌트는 경로 접두사를 떼어야 하므로 라우팅보다 먼저 찾는다.
+	if tenants != nil {
+		app = router.Chain(app, tenants.Middleware())
+	}
 
-	// 서버 시작
-	fmt.Println("Server is listening on :8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
-}
\ No newline at end of file
+	if benchMode {
+		if err := runBench(cfg.Bench, app, lc, os.Stdout); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	// 서버 시작 (SIGINT/SIGTERM 을 받으면 진행 중인 요청을 마무리하고 종료)
+	srv := server.New(cfg.Server.Addr, app)
+	srv.DrainTimeout = cfg.Server.DrainTimeout.D()
+	srv.RealtimeDrainTimeout = cfg.Server.RealtimeDrainTimeout.D()
+	srv.SetProtocols(cfg.Server.HTTP2, cfg.Server.H2C)
+	srv.SetTimeouts(server.Timeouts{
+		ReadHeader: cfg.Server.ReadHeaderTimeout.D(),
+		Read:       cfg.Server.ReadTimeout.D(),
+		Write:      cfg.Server.WriteTimeout.D(),
+		Idle:       cfg.Server.IdleTimeout.D(),
+	})
+	srv.SetSocketOptions(server.SocketOptions{
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+		DisableKeepAlives: !cfg.Server.KeepAlives,
+		KeepAlivePeriod:   cfg.Server.TCPKeepAlive.D(),
+		DisableNoDelay:    !cfg.Server.TCPNoDelay,
+		ReadBuffer:        cfg.Server.SocketReadBuffer,
+		WriteBuffer:       cfg.Server.SocketWriteBuffer,
+		Backlog:           cfg.Server.ListenBacklog,
+		MaxConns:          cfg.Server.MaxConnections,
+	})
+	srv.Lifecycle = lc
+	if err := addCronTasks(sched, cfg.Cron, sessions, app, recorder, accounts, quotaUsage); err != nil {
+		fatal(err)
+	}
+	// 주기 작업이 큐에 넣는 작업도 처리되도록 스케줄러를 큐보다 나중에 등록해 먼저 멈춘다.
+	sched.RegisterLifecycle(lc)
+	if grpcSrv != nil {
+		// gRPC 호출도 HTTP 요청이므로 드레인에서 이미 끝났다. 남은 자원만 정리한다.
+		lc.OnShutdown("grpc", 0, func(context.Context) error {
+			grpcSrv.Stop()
+			return nil
+		})
+	}
+	if cfg.Debug.Enabled && cfg.Debug.Addr != "" {
+		// 별도 리스너에도 전역 미들웨어 없이 인증·인가만 건다.
+		srv.Listen(cfg.Debug.Addr, router.Chain(debug.Handler(), adminOnly...))
+		logger.Info("debug endpoints listening", "addr", cfg.Debug.Addr)
+	}
+	switch {
+	case cfg.TLS.ACME():
+		srv.UseAutocert(cfg.TLS.ACMEHosts, cfg.TLS.ACMECacheDir, cfg.TLS.ACMEEmail)
+	case cfg.TLS.Enabled():
+		if err := srv.UseTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile); err != nil {
+			fatal(err)
+		}
+		reloader.Register("tls", func(c *config.Config) (func(), error) {
+			if !c.TLS.Enabled() {
+				return nil, errors.New("tls.cert_file cannot be removed without a restart")
+			}
+			cert, err := tls.LoadX509KeyPair(c.TLS.CertFile, c.TLS.KeyFile)
+			if err != nil {
+				return nil, err
+			}
+			return func() { srv.SetCert(&cert) }, nil
+		})
+	}
+	if cfg.TLS.RedirectAddr != "" {
+		srv.RedirectHTTP(cfg.TLS.RedirectAddr)
+	}
+	if cfg.TLS.HTTP3Addr != "" {
+		if err := srv.UseHTTP3(cfg.TLS.HTTP3Addr); err != nil {
+			fatal(err)
+		}
+	}
+	for _, l := range cfg.Server.Listeners {
+		h := app
+		if l.Handler == "admin" {
+			h = adminRouter
+		}
+		if len(l.Allow) > 0 {
+			// 리스너 단위의 허용 목록은 프록시 헤더를 믿지 않고 연결 주소만 본다.
+			allow, err := acl.New(acl.Config{Allow: l.Allow})
+			if err != nil {
+				fatal(fmt.Errorf("server.listeners %s: %w", l.Addr, err))
+			}
+			h = router.Chain(h, allow.Middleware())
+		}
+		if err := srv.AddListener(l.Addr, h, l.TLS); err != nil {
+			fatal(err)
+		}
+		logger.Info("listener added", "name", cmp.Or(l.Name, l.Addr), "addr", l.Addr, "tls", l.TLS, "handler", cmp.Or(l.Handler, "app"))
+	}
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	defer stopReload()
+	go reloader.WatchSignal(reloadCtx)
+	if cfg.Dev.Enabled && cfg.File() != "" {
+		go reloader.WatchFile(reloadCtx, cfg.File(), cfg.Dev.Interval.D())
+	}
+	logger.Info("server listening", "addr", cfg.Server.Addr)
+	if err := srv.Run(context.Background()); !server.IsClosed(err) {
+		fatal(err)
+	}
+	logger.Info("server stopped")
+}
@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Mount 는 r 의 prefix 아래에 구독자 관리와 발송 기록 라우트를 등록한다. mws 로 관리자 인증을 건다.
+//
+//	GET    prefix                       구독자 목록 (비밀 값 제외)
+//	POST   prefix                       구독자 추가. secret 을 비우면 만들어서 응답에 한 번만 돌려줌
+//	DELETE prefix/{id}                  구독자 삭제
+//	POST   prefix/{id}/ping             구독자에게 ping 이벤트 발송
+//	GET    prefix/deliveries?subscriber= 최근 발송 기록
+func (d *Dispatcher) Mount(r router.Routes, prefix string, mws ...router.Middleware) {
+	g := r.Group(prefix, mws...)
+	g.GET("", d.list)
+	g.POST("", d.create)
+	g.GET("/deliveries", d.deliveries)
+	g.DELETE("/{id}", d.remove)
+	g.POST("/{id}/ping", d.ping)
+}
+
+func (d *Dispatcher) list(w http.ResponseWriter, r *http.Request) {
+	api.WriteJSON(w, http.StatusOK, map[string][]Subscriber{"subscribers": d.Subscribers()})
+}
+
+func (d *Dispatcher) create(w http.ResponseWriter, r *http.Request) {
+	var s Subscriber
+	if err := api.ReadJSON(r, &s); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+	if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		api.WriteError(w, api.BadRequest("url must be an absolute http or https URL"))
+		return
+	}
+	if s.Secret == "" {
+		s.Secret = randomHex(32)
+	}
+	s = d.Subscribe(s)
+	logging.From(r.Context()).Warn("webhook subscriber added", "subscriber", s.ID, "url", s.URL)
+	api.WriteJSON(w, http.StatusCreated, s)
+}
+
+func (d *Dispatcher) remove(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+	if !d.Unsubscribe(id) {
+		api.WriteError(w, api.NewError(http.StatusNotFound, "not_found", "no subscriber with this id"))
+		return
+	}
+	logging.From(r.Context()).Warn("webhook subscriber removed", "subscriber", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (d *Dispatcher) ping(w http.ResponseWriter, r *http.Request) {
+	evID, ok, err := d.Ping(r.Context(), router.Param(r, "id"))
+	switch {
+	case !ok:
+		api.WriteError(w, api.NewError(http.StatusNotFound, "not_found", "no subscriber with this id"))
+	case err != nil:
+		api.WriteError(w, api.NewError(http.StatusServiceUnavailable, "queue_unavailable", err.Error()))
+	default:
+		api.WriteJSON(w, http.StatusAccepted, map[string]string{"event_id": evID})
+	}
+}
+
+func (d *Dispatcher) deliveries(w http.ResponseWriter, r *http.Request) {
+	api.WriteJSON(w, http.StatusOK, map[string][]Delivery{"deliveries": d.History(r.URL.Query().Get("subscriber"))})
+}
@@ -0,0 +1,281 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/metrics"
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+var received = metrics.NewCounterVec("webhook_received_total",
+	"Inbound webhooks by provider and result (ok, ignored, duplicate, rejected, error).", "provider", "result")
+
+var (
+	// ErrSignature 는 서명이 없거나 맞지 않는 요청이다.
+	ErrSignature = errors.New("webhook: invalid signature")
+	// ErrTimestamp 는 서명 시각이 허용 범위를 벗어난 요청이다. 재전송 공격을 막는다.
+	ErrTimestamp = errors.New("webhook: timestamp outside tolerance")
+)
+
+// Message 는 서명을 확인한 수신 웹훅이다.
+type Message struct {
+	Provider string
+	// ID 는 공급자가 붙인 발송 ID 다. 같은 ID 는 한 번만 처리한다.
+	ID    string
+	Event string
+	Body  []byte
+}
+
+// Provider 는 공급자별 서명 형식이다.
+type Provider interface {
+	// Verify 는 서명을 확인하고 발송 ID 와 이벤트 이름을 읽는다.
+	Verify(h http.Header, body []byte, now time.Time) (id, event string, err error)
+}
+
+// GitHub 은 X-Hub-Signature-256 헤더로 서명하는 GitHub 형식이다. 서명에 시각이 없으므로
+// 재전송은 X-GitHub-Delivery ID 로만 막는다.
+type GitHub struct {
+	Secret []byte
+}
+
+func (g GitHub) Verify(h http.Header, body []byte, _ time.Time) (string, string, error) {
+	if !validHMAC(g.Secret, []byte(strings.TrimPrefix(h.Get("X-Hub-Signature-256"), "sha256=")), body) {
+		return "", "", ErrSignature
+	}
+	return h.Get("X-GitHub-Delivery"), h.Get("X-GitHub-Event"), nil
+}
+
+// Stripe 는 "Stripe-Signature: t=시각,v1=서명" 헤더로 "시각.본문" 을 서명하는 형식이다.
+// 이벤트 이름과 ID 는 본문의 "type", "id" 에서 읽는다.
+type Stripe struct {
+	Secret    []byte
+	Tolerance time.Duration
+}
+
+func (s Stripe) Verify(h http.Header, body []byte, now time.Time) (string, string, error) {
+	var ts string
+	var sigs [][]byte
+	for _, part := range strings.Split(h.Get("Stripe-Signature"), ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, []byte(v))
+		}
+	}
+	if err := checkTimestamp(ts, now, s.Tolerance); err != nil {
+		return "", "", err
+	}
+	signed := append([]byte(ts+"."), body...)
+	ok := false
+	for _, sig := range sigs {
+		ok = ok || validHMAC(s.Secret, sig, signed)
+	}
+	if !ok {
+		return "", "", ErrSignature
+	}
+	var ev struct{ ID, Type string }
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return "", "", fmt.Errorf("webhook: %w", err)
+	}
+	return ev.ID, ev.Type, nil
+}
+
+// Signed 는 이 패키지의 Dispatcher 가 보내는 형식이다. (X-Webhook-Timestamp, X-Webhook-Signature)
+// 이 서버끼리 이벤트를 주고받을 때 쓴다.
+type Signed struct {
+	Secret    []byte
+	Tolerance time.Duration
+}
+
+func (s Signed) Verify(h http.Header, body []byte, now time.Time) (string, string, error) {
+	ts := h.Get("X-Webhook-Timestamp")
+	if err := checkTimestamp(ts, now, s.Tolerance); err != nil {
+		return "", "", err
+	}
+	if !hmac.Equal([]byte(h.Get("X-Webhook-Signature")), []byte(Sign(s.Secret, ts, body))) {
+		return "", "", ErrSignature
+	}
+	return h.Get("X-Webhook-ID"), h.Get("X-Webhook-Event"), nil
+}
+
+// validHMAC 는 hexSig 가 body 의 HMAC-SHA256 인지 상수 시간에 비교한다.
+func validHMAC(secret, hexSig, body []byte) bool {
+	sig := make([]byte, hex.DecodedLen(len(hexSig)))
+	if _, err := hex.Decode(sig, hexSig); err != nil || len(sig) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+// checkTimestamp 는 유닉스 초 ts 가 now 에서 tolerance 안쪽인지 확인한다. tolerance 가 0 이면 5분이다.
+func checkTimestamp(ts string, now time.Time, tolerance time.Duration) error {
+	if tolerance <= 0 {
+		tolerance = 5 * time.Minute
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrSignature
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > tolerance || d < -tolerance {
+		return ErrTimestamp
+	}
+	return nil
+}
+
+// Receiver 는 /webhooks/{provider} 로 들어온 웹훅의 서명을 확인하고 등록된 처리기로 보낸다.
+//
+// 처리기가 에러를 돌려주면 500 으로 응답해 공급자가 다시 보내게 한다. 처리기가 없는 이벤트는 202 로 받고 버린다.
+type Receiver struct {
+	// MaxBytes 는 본문 크기 제한이다. 0 이면 1MiB.
+	MaxBytes int64
+	// ReplayWindow 동안 같은 발송 ID 는 다시 처리하지 않는다. 0 이면 10분.
+	ReplayWindow time.Duration
+
+	mu        sync.Mutex
+	providers map[string]Provider
+	handlers  map[string]func(context.Context, Message) error // "공급자 이벤트"
+	seen      map[string]time.Time
+}
+
+// NewReceiver 는 비어 있는 Receiver 를 만든다.
+func NewReceiver() *Receiver {
+	return &Receiver{providers: map[string]Provider{}, handlers: map[string]func(context.Context, Message) error{}, seen: map[string]time.Time{}}
+}
+
+// Provider 는 경로 이름 name 의 서명 형식을 등록한다.
+func (rc *Receiver) Provider(name string, p Provider) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.providers[name] = p
+}
+
+// Handle 은 provider 의 event 처리기를 등록한다. event 가 "*" 이면 따로 등록하지 않은 모든 이벤트를 받는다.
+func (rc *Receiver) Handle(provider, event string, h func(context.Context, Message) error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.handlers[provider+" "+event] = h
+}
+
+// On 은 본문을 T 로 읽는 처리기를 등록한다. 본문을 읽지 못하면 400 으로 응답한다.
+func On[T any](rc *Receiver, provider, event string, fn func(ctx context.Context, m Message, payload T) error) {
+	rc.Handle(provider, event, func(ctx context.Context, m Message) error {
+		var v T
+		if err := json.Unmarshal(m.Body, &v); err != nil {
+			return api.BadRequest("payload does not match the event type")
+		}
+		return fn(ctx, m, v)
+	})
+}
+
+// ServeHTTP 는 Receiver 를 POST /webhooks/{provider} 라우트로 쓴다.
+func (rc *Receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := router.Param(r, "provider")
+	rc.mu.Lock()
+	p, ok := rc.providers[name]
+	rc.mu.Unlock()
+	if !ok {
+		api.WriteError(w, api.NewError(http.StatusNotFound, "not_found", "unknown webhook provider"))
+		return
+	}
+	limit := rc.MaxBytes
+	if limit <= 0 {
+		limit = 1 << 20
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil || int64(len(body)) > limit {
+		api.WriteError(w, api.NewError(http.StatusRequestEntityTooLarge, "payload_too_large", "webhook body is too large"))
+		return
+	}
+	log := logging.From(r.Context()).With("provider", name)
+	id, event, err := p.Verify(r.Header, body, time.Now())
+	switch {
+	case errors.Is(err, ErrTimestamp):
+		received.Inc(name, "rejected")
+		log.Warn("webhook rejected", "err", err)
+		api.WriteError(w, api.NewError(http.StatusUnauthorized, "stale_timestamp", "signature timestamp is outside the allowed tolerance"))
+		return
+	case err != nil:
+		received.Inc(name, "rejected")
+		log.Warn("webhook rejected", "err", err)
+		api.WriteError(w, api.NewError(http.StatusUnauthorized, "invalid_signature", "webhook signature does not match"))
+		return
+	}
+	log = log.With("event", event, "delivery_id", id)
+	if id != "" && !rc.first(name+" "+id) {
+		received.Inc(name, "duplicate")
+		log.Info("duplicate webhook ignored")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	h := rc.handler(name, event)
+	if h == nil {
+		received.Inc(name, "ignored")
+		log.Debug("webhook has no handler")
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err := h(r.Context(), Message{Provider: name, ID: id, Event: event, Body: body}); err != nil {
+		// 다시 보내면 처리할 수 있도록 ID 기록을 지운다.
+		rc.forget(name + " " + id)
+		received.Inc(name, "error")
+		log.Error("webhook handler failed", "err", err)
+		api.WriteError(w, err)
+		return
+	}
+	received.Inc(name, "ok")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (rc *Receiver) handler(provider, event string) func(context.Context, Message) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if h, ok := rc.handlers[provider+" "+event]; ok {
+		return h
+	}
+	return rc.handlers[provider+" *"]
+}
+
+// first 는 key 를 처음 보면 기록하고 true 를 돌려준다. ReplayWindow 가 지난 기록은 함께 지운다.
+func (rc *Receiver) first(key string) bool {
+	window := rc.ReplayWindow
+	if window <= 0 {
+		window = 10 * time.Minute
+	}
+	now := time.Now()
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for k, t := range rc.seen {
+		if now.Sub(t) > window {
+			delete(rc.seen, k)
+		}
+	}
+	if _, ok := rc.seen[key]; ok {
+		return false
+	}
+	rc.seen[key] = now
+	return true
+}
+
+func (rc *Receiver) forget(key string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	delete(rc.seen, key)
+}
@@ -0,0 +1,328 @@
+// Package webhook 은 서버에서 일어난 이벤트를 구독자 URL 로 보내는 웹훅 발송기다.
+//
+// Publish 는 구독자마다 jobs 큐에 작업을 넣고 바로 돌아온다. 보내기에 실패하면 큐의 지수 백오프로 다시 시도하며,
+// 모든 시도는 발송 기록(History)에 남는다.
+//
+// 본문은 구독자의 비밀 값으로 서명한다. 수신 측은 X-Webhook-Timestamp 값과 본문을 "." 으로 이어
+// HMAC-SHA256 을 계산하고 X-Webhook-Signature ("sha256=<hex>") 와 비교한다.
+//
+// 반대 방향으로, Receiver 는 외부 공급자(GitHub, Stripe, 다른 이 서버)가 보낸 웹훅의 서명과 시각을 확인한 뒤
+// 공급자·이벤트별로 등록한 처리기에 넘긴다.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/jobs"
+	"github.com/hgsong234/_stack/Golang/logging"
+	"github.com/hgsong234/_stack/Golang/metrics"
+)
+
+// JobKind 는 발송 작업의 jobs 종류다.
+const JobKind = "webhook.deliver"
+
+var deliveries = metrics.NewCounterVec("webhook_deliveries_total",
+	"Outbound webhook delivery attempts by event and result (ok, retry, failed).", "event", "result")
+
+// Subscriber 는 이벤트를 받을 URL 이다.
+type Subscriber struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+	// Events 는 받을 이벤트다. 비어 있으면 모든 이벤트이고, "user.*" 는 "user." 으로 시작하는 이벤트다.
+	Events []string `json:"events,omitempty"`
+}
+
+// Wants 는 구독자가 event 를 받는지 확인한다.
+func (s Subscriber) Wants(event string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == event || e == "*" {
+			return true
+		}
+		if p, ok := strings.CutSuffix(e, "*"); ok && strings.HasPrefix(event, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Event 는 구독자에게 보내는 본문이다.
+type Event struct {
+	ID   string    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data,omitempty"`
+}
+
+// Delivery 는 발송 시도 한 번의 기록이다.
+type Delivery struct {
+	EventID    string    `json:"event_id"`
+	Event      string    `json:"event"`
+	Subscriber string    `json:"subscriber"`
+	Attempt    int       `json:"attempt"`
+	Time       time.Time `json:"time"`
+	Status     int       `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs float64   `json:"duration_ms"`
+	// Response 는 응답 본문의 앞부분이다.
+	Response string `json:"response,omitempty"`
+}
+
+// task 는 큐에 넣는 발송 작업이다. 재시도 때 같은 본문을 보내도록 직렬화한 본문을 담는다.
+type task struct {
+	Subscriber string          `json:"subscriber"`
+	EventID    string          `json:"event_id"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// Dispatcher 는 구독자 목록과 발송 기록이다.
+type Dispatcher struct {
+	// Client 가 nil 이면 http.DefaultClient 를 쓴다.
+	Client *http.Client
+
+	queue *jobs.Queue
+
+	mu      sync.RWMutex
+	subs    []Subscriber
+	history []Delivery // 순환 버퍼
+	next    int
+	full    bool
+}
+
+// New 는 q 로 발송하는 Dispatcher 를 만들고 q 에 발송 처리기를 등록한다. q.Start 전에 호출한다.
+// history 는 보관할 발송 기록 수다.
+func New(q *jobs.Queue, history int, subs ...Subscriber) *Dispatcher {
+	if history <= 0 {
+		history = 200
+	}
+	d := &Dispatcher{queue: q, subs: slices.Clone(subs), history: make([]Delivery, history)}
+	q.Register(JobKind, d.deliver)
+	return d
+}
+
+// Subscribers 는 등록된 구독자다. 비밀 값은 지워서 돌려준다.
+func (d *Dispatcher) Subscribers() []Subscriber {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	out := slices.Clone(d.subs)
+	for i := range out {
+		out[i].Secret = ""
+	}
+	return out
+}
+
+// Subscribe 는 구독자를 추가한다. ID 가 비어 있으면 만들어 넣고, 같은 ID 가 있으면 바꾼다.
+func (d *Dispatcher) Subscribe(s Subscriber) Subscriber {
+	if s.ID == "" {
+		s.ID = randomHex(8)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if i := d.index(s.ID); i >= 0 {
+		d.subs[i] = s
+	} else {
+		d.subs = append(d.subs, s)
+	}
+	return s
+}
+
+// Unsubscribe 는 구독자를 지운다. 지운 것이 있으면 true 다. 이미 큐에 들어간 발송은 실패로 끝난다.
+func (d *Dispatcher) Unsubscribe(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i := d.index(id)
+	if i < 0 {
+		return false
+	}
+	d.subs = slices.Delete(d.subs, i, i+1)
+	return true
+}
+
+func (d *Dispatcher) index(id string) int {
+	return slices.IndexFunc(d.subs, func(s Subscriber) bool { return s.ID == id })
+}
+
+func (d *Dispatcher) subscriber(id string) (Subscriber, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if i := d.index(id); i >= 0 {
+		return d.subs[i], true
+	}
+	return Subscriber{}, false
+}
+
+// Publish 는 event 를 받는 구독자마다 발송 작업을 넣고 이벤트 ID 를 돌려준다.
+// 큐에 넣지 못한 구독자가 있으면 에러를 모아서 돌려준다.
+func (d *Dispatcher) Publish(ctx context.Context, event string, data any) (string, error) {
+	d.mu.RLock()
+	var targets []string
+	for _, s := range d.subs {
+		if s.Wants(event) {
+			targets = append(targets, s.ID)
+		}
+	}
+	d.mu.RUnlock()
+	return d.enqueue(ctx, event, data, targets)
+}
+
+// Ping 은 구독자의 Events 와 관계없이 id 구독자에게 "ping" 이벤트를 보낸다. 없는 구독자이면 false 다.
+func (d *Dispatcher) Ping(ctx context.Context, id string) (string, bool, error) {
+	if _, ok := d.subscriber(id); !ok {
+		return "", false, nil
+	}
+	evID, err := d.enqueue(ctx, "ping", nil, []string{id})
+	return evID, true, err
+}
+
+func (d *Dispatcher) enqueue(ctx context.Context, event string, data any, targets []string) (string, error) {
+	ev := Event{ID: randomHex(16), Type: event, Time: time.Now().UTC(), Data: data}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return "", err
+	}
+	var errs []error
+	for _, id := range targets {
+		if _, err := d.queue.Enqueue(ctx, JobKind, task{Subscriber: id, EventID: ev.ID, Event: event, Body: body}); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", id, err))
+		}
+	}
+	return ev.ID, errors.Join(errs...)
+}
+
+// deliver 는 발송 작업 하나를 처리한다. 재시도해도 소용없는 응답(408, 429 를 뺀 4xx)은 바로 실패로 끝낸다.
+func (d *Dispatcher) deliver(ctx context.Context, payload json.RawMessage) error {
+	var t task
+	if err := json.Unmarshal(payload, &t); err != nil {
+		return jobs.Permanent(err)
+	}
+	s, ok := d.subscriber(t.Subscriber)
+	if !ok {
+		return jobs.Permanent(fmt.Errorf("webhook: subscriber %q no longer exists", t.Subscriber))
+	}
+	rec := Delivery{EventID: t.EventID, Event: t.Event, Subscriber: s.ID, Time: time.Now()}
+	status, resp, err := d.post(ctx, s, t)
+	rec.DurationMs = float64(time.Since(rec.Time).Microseconds()) / 1000
+	rec.Status, rec.Response = status, resp
+	switch {
+	case err != nil:
+	case status >= 200 && status < 300:
+	case status >= 400 && status < 500 && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests:
+		err = jobs.Permanent(fmt.Errorf("webhook: %s returned %d", s.URL, status))
+	default:
+		err = fmt.Errorf("webhook: %s returned %d", s.URL, status)
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	d.record(&rec)
+
+	log := logging.From(ctx).With("subscriber", s.ID, "event", t.Event, "event_id", t.EventID, "attempt", rec.Attempt)
+	switch {
+	case err == nil:
+		deliveries.Inc(t.Event, "ok")
+		log.Debug("webhook delivered", "status", status)
+	case jobs.IsPermanent(err):
+		deliveries.Inc(t.Event, "failed")
+		log.Warn("webhook delivery failed", "status", status, "err", err)
+	default:
+		deliveries.Inc(t.Event, "retry")
+		log.Warn("webhook delivery will be retried", "status", status, "err", err)
+	}
+	return err
+}
+
+// post 는 서명한 본문을 보내고 상태 코드와 응답 본문의 앞부분을 돌려준다.
+func (d *Dispatcher) post(ctx context.Context, s Subscriber, t task) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(t.Body))
+	if err != nil {
+		return 0, "", jobs.Permanent(err)
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "hello-server-webhook/1")
+	req.Header.Set("X-Webhook-ID", t.EventID)
+	req.Header.Set("X-Webhook-Event", t.Event)
+	req.Header.Set("X-Webhook-Timestamp", ts)
+	req.Header.Set("X-Webhook-Signature", Sign([]byte(s.Secret), ts, t.Body))
+	c := d.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	head, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, string(head), nil
+}
+
+// Sign 은 timestamp 와 body 에 대한 서명 헤더 값 "sha256=<hex>" 를 만든다.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// record 는 발송 기록을 남긴다. 같은 이벤트·구독자의 이전 기록 수로 시도 번호를 채운다.
+func (d *Dispatcher) record(rec *Delivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	rec.Attempt = 1
+	for _, h := range d.history {
+		if h.EventID == rec.EventID && h.Subscriber == rec.Subscriber {
+			rec.Attempt++
+		}
+	}
+	d.history[d.next] = *rec
+	d.next = (d.next + 1) % len(d.history)
+	if d.next == 0 {
+		d.full = true
+	}
+}
+
+// History 는 최근 발송 기록을 최신 순으로 돌려준다. subscriber 가 있으면 그 구독자의 기록만 돌려준다.
+func (d *Dispatcher) History(subscriber string) []Delivery {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	n := d.next
+	if d.full {
+		n = len(d.history)
+	}
+	out := make([]Delivery, 0, n)
+	for i := 1; i <= n; i++ {
+		h := d.history[(d.next-i+len(d.history))%len(d.history)]
+		if subscriber == "" || h.Subscriber == subscriber {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,165 @@
+// Package wellknown 은 /robots.txt, /favicon.ico 와 /.well-known/ 아래의 정해진 주소를 처리한다.
+//
+//	/robots.txt                             Robots 또는 Disallow, Sitemap 으로 만든 내용
+//	/favicon.ico                            Favicon, 없으면 204
+//	/.well-known/security.txt               RFC 9116 보안 연락처 (Security.Contact 가 있을 때)
+//	/.well-known/acme-challenge/{token}     ACMEDir 의 http-01 챌린지 파일 (certbot --webroot 등)
+//	/.well-known/change-password            ChangePassword 로 리다이렉트
+//
+// 그 밖의 /.well-known/ 경로는 홈이나 페이지로 넘어가지 않고 404 다.
+package wellknown
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hgsong234/_stack/Golang/router"
+)
+
+// Security 는 security.txt 의 필드다. Contact 는 mailto:, https: 주소 또는 tel: 이다.
+type Security struct {
+	Contact   []string
+	Expires   time.Time
+	Policy    string
+	Languages []string
+	// Encryption 은 공개 키의 주소, Canonical 은 이 파일의 정식 주소다.
+	Encryption string
+	Canonical  string
+}
+
+// Config 는 처리할 내용이다.
+type Config struct {
+	// Robots 가 있으면 /robots.txt 로 그대로 내보낸다. 없으면 Disallow 와 Sitemap 으로 만든다.
+	Robots   []byte
+	Disallow []string
+	// Sitemap 은 robots.txt 에 적을 사이트맵 주소다. /로 시작하면 요청의 Host 를 앞에 붙인다.
+	Sitemap string
+	// Favicon 은 /favicon.ico 의 내용이다. 형식은 내용으로 알아낸다.
+	Favicon  []byte
+	Security Security
+	// ACMEDir 은 외부 ACME 클라이언트가 챌린지 파일을 쓰는 디렉터리다.
+	ACMEDir string
+	// ChangePassword 는 비밀번호 변경 페이지의 주소다.
+	ChangePassword string
+	// MaxAge 는 robots.txt, favicon, security.txt 의 Cache-Control max-age 다. 0 이면 하루.
+	MaxAge time.Duration
+}
+
+// Mount 는 cfg 의 라우트를 등록한다.
+func Mount(r router.Routes, cfg Config) {
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = 24 * time.Hour
+	}
+	started := time.Now()
+	r.GET("/robots.txt", func(w http.ResponseWriter, req *http.Request) {
+		body := cfg.Robots
+		if body == nil {
+			body = robots(cfg, req)
+		}
+		serve(w, req, "text/plain; charset=utf-8", body, cfg.MaxAge, started)
+	})
+	r.GET("/favicon.ico", func(w http.ResponseWriter, req *http.Request) {
+		if len(cfg.Favicon) == 0 {
+			// 아이콘이 없다는 것도 캐시하게 해서 브라우저가 매번 묻지 않게 한다.
+			w.Header().Set("Cache-Control", cacheControl(cfg.MaxAge))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		serve(w, req, http.DetectContentType(cfg.Favicon), cfg.Favicon, cfg.MaxAge, started)
+	})
+	if len(cfg.Security.Contact) > 0 {
+		txt := securityTxt(cfg.Security)
+		r.GET("/.well-known/security.txt", func(w http.ResponseWriter, req *http.Request) {
+			serve(w, req, "text/plain; charset=utf-8", txt, cfg.MaxAge, started)
+		})
+	}
+	if cfg.ACMEDir != "" {
+		r.GET("/.well-known/acme-challenge/{token}", acmeHandler(cfg.ACMEDir))
+	}
+	if cfg.ChangePassword != "" {
+		r.GET("/.well-known/change-password", func(w http.ResponseWriter, req *http.Request) {
+			http.Redirect(w, req, cfg.ChangePassword, http.StatusFound)
+		})
+	}
+	r.GET("/.well-known/", http.NotFound)
+}
+
+func robots(cfg Config, r *http.Request) []byte {
+	var b bytes.Buffer
+	b.WriteString("User-agent: *\n")
+	if len(cfg.Disallow) == 0 {
+		b.WriteString("Disallow:\n")
+	}
+	for _, p := range cfg.Disallow {
+		b.WriteString("Disallow: " + p + "\n")
+	}
+	if sm := cfg.Sitemap; sm != "" {
+		if strings.HasPrefix(sm, "/") {
+			scheme := "http"
+			if r.TLS != nil {
+				scheme = "https"
+			}
+			sm = scheme + "://" + r.Host + sm
+		}
+		b.WriteString("\nSitemap: " + sm + "\n")
+	}
+	return b.Bytes()
+}
+
+// securityTxt 는 RFC 9116 형식의 내용이다. Expires 가 없으면 1년 뒤로 적는다.
+func securityTxt(s Security) []byte {
+	var b bytes.Buffer
+	for _, c := range s.Contact {
+		b.WriteString("Contact: " + c + "\n")
+	}
+	expires := s.Expires
+	if expires.IsZero() {
+		expires = time.Now().AddDate(1, 0, 0).Truncate(24 * time.Hour)
+	}
+	b.WriteString("Expires: " + expires.UTC().Format(time.RFC3339) + "\n")
+	field := func(name, v string) {
+		if v != "" {
+			b.WriteString(name + ": " + v + "\n")
+		}
+	}
+	field("Encryption", s.Encryption)
+	field("Policy", s.Policy)
+	field("Preferred-Languages", strings.Join(s.Languages, ", "))
+	field("Canonical", s.Canonical)
+	return b.Bytes()
+}
+
+// acmeHandler 는 dir 의 챌린지 파일을 내보낸다. 토큰은 base64url 글자만 받는다.
+func acmeHandler(dir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := router.Param(r, "token")
+		if token == "" || strings.Trim(token, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_") != "" {
+			http.NotFound(w, r)
+			return
+		}
+		b, err := os.ReadFile(filepath.Join(dir, path.Base(token)))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(b)
+	}
+}
+
+func serve(w http.ResponseWriter, r *http.Request, contentType string, body []byte, maxAge time.Duration, modified time.Time) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", cacheControl(maxAge))
+	http.ServeContent(w, r, "", modified, bytes.NewReader(body))
+}
+
+func cacheControl(maxAge time.Duration) string {
+	return "public, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+}
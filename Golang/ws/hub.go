@@ -0,0 +1,233 @@
+// Package ws 는 WebSocket 업그레이드 핸들러와 연결을 관리하는 허브를 제공한다.
+package ws
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/hgsong234/_stack/Golang/api"
+	"github.com/hgsong234/_stack/Golang/lifecycle"
+	"github.com/hgsong234/_stack/Golang/logging"
+)
+
+// 연결 유지 관련 시간
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+	maxMessage = 64 << 10
+	sendBuffer = 64
+)
+
+// ErrUnknownClient 는 Send 대상 클라이언트가 없을 때의 에러다.
+var ErrUnknownClient = errors.New("ws: unknown client")
+
+// Message 는 클라이언트에서 받은 메시지다.
+type Message struct {
+	Client *Client
+	Data   []byte
+}
+
+// Client 는 허브에 연결된 WebSocket 연결 하나다.
+type Client struct {
+	ID   string
+	hub  *Hub
+	conn *websocket.Conn
+	send chan []byte
+	once sync.Once
+}
+
+// Hub 는 연결된 클라이언트들을 추적하고 메시지를 전달한다.
+type Hub struct {
+	// OnMessage 는 클라이언트가 보낸 메시지마다 호출된다. nil 이면 무시한다.
+	OnMessage func(Message)
+	// CheckOrigin 이 nil 이면 같은 호스트의 출처만 허용한다.
+	CheckOrigin func(r *http.Request) bool
+
+	mu      sync.RWMutex
+	clients map[string]*Client
+	closing bool
+}
+
+// NewHub 는 비어 있는 Hub 를 만든다.
+func NewHub() *Hub {
+	return &Hub{clients: map[string]*Client{}}
+}
+
+// Handler 는 요청을 WebSocket 으로 업그레이드하고 허브에 등록한다.
+func (h *Hub) Handler() http.HandlerFunc {
+	up := websocket.Upgrader{ReadBufferSize: 4096, WriteBufferSize: 4096, CheckOrigin: h.CheckOrigin}
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.mu.RLock()
+		closing := h.closing
+		h.mu.RUnlock()
+		if closing {
+			api.WriteError(w, api.NewError(http.StatusServiceUnavailable, "shutting_down", "server is shutting down"))
+			return
+		}
+		conn, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade 가 이미 에러 응답을 보냈다.
+			return
+		}
+		c := &Client{ID: newID(), hub: h, conn: conn, send: make(chan []byte, sendBuffer)}
+		h.mu.Lock()
+		h.clients[c.ID] = c
+		h.mu.Unlock()
+		go c.writeLoop()
+		c.readLoop()
+	}
+}
+
+// Broadcast 는 모든 클라이언트에게 메시지를 보낸다. 송신 버퍼가 가득 찬 느린 클라이언트는 끊는다.
+func (h *Hub) Broadcast(data []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, c := range h.clients {
+		c.enqueue(data)
+	}
+}
+
+// Send 는 클라이언트 한 명에게 메시지를 보낸다.
+func (h *Hub) Send(id string, data []byte) error {
+	h.mu.RLock()
+	c, ok := h.clients[id]
+	h.mu.RUnlock()
+	if !ok {
+		return ErrUnknownClient
+	}
+	c.enqueue(data)
+	return nil
+}
+
+// Len 은 연결된 클라이언트 수다.
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// RegisterLifecycle 은 lc 의 드레인 훅으로 Shutdown 을 등록한다. 요청 드레인을 시작하자마자 클라이언트에게
+// 종료를 알리므로 배포 중에도 클라이언트가 close 프레임을 받고 다시 연결할 수 있다.
+func (h *Hub) RegisterLifecycle(lc *lifecycle.Manager) {
+	lc.OnDrain("ws", 0, h.Shutdown)
+}
+
+// Shutdown 은 새 연결을 거부하고 모든 클라이언트에게 close 프레임을 보낸 뒤
+// 연결이 끊길 때까지(또는 ctx 가 끝날 때까지) 기다린다.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	clients := make([]*Client, 0, len(h.clients))
+	for _, c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+
+	if len(clients) > 0 {
+		logging.Default().Info("ws: closing connections", "clients", len(clients))
+	}
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	for _, c := range clients {
+		c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+	}
+	t := time.NewTicker(50 * time.Millisecond)
+	defer t.Stop()
+	for h.Len() > 0 {
+		select {
+		case <-ctx.Done():
+			logging.Default().Warn("ws: clients did not close in time, closing them", "clients", h.Len())
+			for _, c := range clients {
+				c.conn.Close()
+			}
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	return nil
+}
+
+func (h *Hub) remove(c *Client) {
+	h.mu.Lock()
+	delete(h.clients, c.ID)
+	h.mu.Unlock()
+}
+
+// enqueue 는 송신 큐에 메시지를 넣는다. 큐가 가득 차면 연결을 끊는다.
+func (c *Client) enqueue(data []byte) {
+	select {
+	case c.send <- data:
+	default:
+		logging.Default().Warn("ws: client is too slow, disconnecting", "client", c.ID)
+		c.close()
+	}
+}
+
+func (c *Client) close() {
+	c.once.Do(func() {
+		close(c.send)
+	})
+}
+
+// readLoop 는 메시지를 읽어 OnMessage 로 넘긴다. pong 을 받을 때마다 읽기 기한을 연장한다.
+func (c *Client) readLoop() {
+	defer func() {
+		c.hub.remove(c)
+		c.close()
+		c.conn.Close()
+	}()
+	c.conn.SetReadLimit(maxMessage)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if c.hub.OnMessage != nil {
+			c.hub.OnMessage(Message{Client: c, Data: data})
+		}
+	}
+}
+
+// writeLoop 는 송신 큐의 메시지를 쓰고 주기적으로 ping 을 보낸다.
+func (c *Client) writeLoop() {
+	t := time.NewTicker(pingPeriod)
+	defer func() {
+		t.Stop()
+		c.conn.Close()
+	}()
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, nil)
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-t.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}